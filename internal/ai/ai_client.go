@@ -1,438 +1,267 @@
 package ai
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
-	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
-	"jump-challenge/internal/config"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/prompt"
+	"jump-challenge/internal/repository"
 	"jump-challenge/internal/service"
 )
 
 type aiClient struct {
-	provider   string
-	apiKey     string
-	baseURL    string
+	settings   atomic.Pointer[aiSettings]
+	baseURL    string // replaces the provider's API URL when set
 	httpClient *http.Client
 	logger     *logger.Logger
-}
-
-const (
-	ProviderOpenAI   = "openai"
-	ProviderDeepSeek = "deepseek"
-	ProviderGemini   = "gemini"
-)
-
-func NewAIClient(apiKey string, logger *logger.Logger) service.AIClient {
-	provider := getEnv("AI_PROVIDER", "openai")
-	baseURL := getBaseURL(provider)
 
-	client := &aiClient{
-		provider:   provider,
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		httpClient: &http.Client{},
-		logger:     logger,
-	}
-
-	return client
+	classifyPrompt func(emailBody string, categories []*model.Category) string // one of classifyPrompts
+	calls          repository.AICallRepository                                 // records every request in debug mode; nil otherwise
 }
 
-// getBaseURL returns the appropriate API base URL based on the provider
-func getBaseURL(provider string) string {
-	switch provider {
-	case ProviderDeepSeek:
-		return "https://api.deepseek.com" // DeepSeek API endpoint
-	case ProviderGemini:
-		return "https://generativelanguage.googleapis.com/v1beta" // Gemini API endpoint
-	default:
-		return "https://api.openai.com/v1" // OpenAI default endpoint
-	}
-}
-
-// getModel returns the appropriate model based on the provider
-func getModel(provider string) string {
-	switch provider {
-	case ProviderDeepSeek:
-		return "deepseek-chat" // DeepSeek's chat model
-	case ProviderGemini:
-		return config.GetEnv("DEFAULT_MODEL", "gemini-2.0-flash-lite") // Gemini's model
-	default:
-		return "gpt-4o" // OpenAI fallback
-	}
+// aiSettings is the part of the client a config reload can change. Each request reads a
+// single snapshot so it never mixes one provider's URL with another's key.
+type aiSettings struct {
+	name     string
+	apiKey   string
+	model    string
+	provider ChatProvider
 }
 
-// OpenAI/DeepSeek API request/response structures
-type chatCompletionRequest struct {
-	Model     string    `json:"model"`
-	Messages  []message `json:"messages"`
-	MaxTokens int       `json:"max_tokens,omitempty"`
+// Client is an AIClient whose provider can be switched while it is in use
+type Client interface {
+	service.AIClient
+	service.ConfidenceClassifier
+	Reconfigure(provider, apiKey, model string)
+	// UseDebugStore records every prompt and answer, redacted, in calls
+	UseDebugStore(calls repository.AICallRepository)
+	// UseClassifyPrompt classifies with one of ClassifyPrompts instead of the default prompt
+	UseClassifyPrompt(name string) error
 }
 
-type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type chatCompletionResponse struct {
-	ID      string   `json:"id"`
-	Object  string   `json:"object"`
-	Created int64    `json:"created"`
-	Model   string   `json:"model"`
-	Choices []choice `json:"choices"`
-	Usage   usage    `json:"usage"`
-}
+var (
+	// ErrRateLimited is returned when the provider answers 429 Too Many Requests
+	ErrRateLimited = errors.New("AI provider rate limit exceeded")
+	// ErrRefused is returned when the provider declines to answer, e.g. on safety grounds
+	ErrRefused = errors.New("AI provider refused the request")
+	// ErrUnavailable is returned when the provider can't be reached or fails with a 5xx status
+	ErrUnavailable = errors.New("AI provider unavailable")
 
-type choice struct {
-	Index        int     `json:"index"`
-	Message      message `json:"message"`
-	FinishReason string  `json:"finish_reason"`
-}
-
-type usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
-}
-
-// Gemini API request/response structures
-type geminiContent struct {
-	Role  string       `json:"role"`
-	Parts []geminiPart `json:"parts"`
-}
+	// errNoAPIKey lets requests fail over to the local model while no key is configured
+	errNoAPIKey = fmt.Errorf("%w: no API key configured", ErrUnavailable)
+)
 
-type geminiPart struct {
-	Text string `json:"text"`
+// NewAIClient creates a client for provider, one of Providers(); model overrides the provider's
+// default model and may be empty
+func NewAIClient(provider, apiKey, model string, logger *logger.Logger) Client {
+	return NewAIClientWithBaseURL(provider, apiKey, model, "", logger)
 }
 
-type geminiRequest struct {
-	Contents []geminiContent `json:"contents"`
-}
+// NewAIClientWithBaseURL sends every provider's requests to baseURL instead of its API, e.g. a
+// fake server in tests or a compatible proxy; an empty baseURL uses the provider's
+func NewAIClientWithBaseURL(provider, apiKey, model, baseURL string, logger *logger.Logger) Client {
+	client := &aiClient{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		httpClient:     &http.Client{},
+		classifyPrompt: defaultClassifyPrompt,
+		logger:         logger,
+	}
+	client.Reconfigure(provider, apiKey, model)
 
-type geminiResponse struct {
-	Candidates []geminiCandidate `json:"candidates"`
+	return client
 }
 
-type geminiCandidate struct {
-	Content       geminiContentForResponse `json:"content"`
-	FinishReason  string                   `json:"finishReason"`
-	SafetyRatings []interface{}            `json:"safetyRatings"`
+// Reconfigure switches provider, key and model; requests already in flight finish with the old
+// ones. An unknown provider is logged and replaced by OpenAI.
+func (a *aiClient) Reconfigure(provider, apiKey, model string) {
+	if provider == "" {
+		provider = ProviderOpenAI
+	}
+	factory, ok := providerFactory(provider)
+	if !ok {
+		a.logger.Warn("Unknown AI provider", provider+", using", ProviderOpenAI+"; registered providers:", strings.Join(Providers(), ", "))
+		provider = ProviderOpenAI
+		factory, _ = providerFactory(provider)
+	}
+
+	a.settings.Store(&aiSettings{
+		name:   provider,
+		apiKey: apiKey,
+		model:  model,
+		provider: factory(ProviderConfig{
+			APIKey:     apiKey,
+			Model:      model,
+			BaseURL:    a.baseURL,
+			HTTPClient: a.httpClient,
+		}),
+	})
 }
 
-type geminiContentForResponse struct {
-	Parts []geminiPart `json:"parts"`
+func (a *aiClient) UseDebugStore(calls repository.AICallRepository) {
+	a.calls = calls
 }
 
-func (a *aiClient) ClassifyEmail(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
-	var classification string
-	var err error
-
-	switch a.provider {
-	case ProviderGemini:
-		classification, err = a.classifyEmailWithGemini(ctx, emailBody, categories)
-	default:
-		classification, err = a.classifyEmailWithOpenAIStyle(ctx, emailBody, categories)
+func (a *aiClient) UseClassifyPrompt(name string) error {
+	build, ok := classifyPrompts[name]
+	if !ok {
+		return fmt.Errorf("unknown classification prompt %q, available: %s", name, strings.Join(ClassifyPrompts(), ", "))
 	}
-
-	if err != nil {
-		return "", fmt.Errorf("failed to classify email: %w", err)
-	}
-
-	a.logger.Info("Classified email as:", classification)
-
-	// Find the most similar category
-	categoryNames := make([]string, len(categories))
-	for i, cat := range categories {
-		categoryNames[i] = cat.Name
-	}
-	return findBestCategoryMatch(classification, categoryNames), nil
+	a.classifyPrompt = build
+	return nil
 }
 
-func (a *aiClient) SummarizeEmail(ctx context.Context, emailBody string) (string, error) {
-	var summary string
-	var err error
-
-	switch a.provider {
-	case ProviderGemini:
-		summary, err = a.summarizeEmailWithGemini(ctx, emailBody)
-	default:
-		summary, err = a.summarizeEmailWithOpenAIStyle(ctx, emailBody)
+// chat sends the prompt after the system prompt ranking instructions above email content;
+// operation names what it is for in debug mode
+func (a *aiClient) chat(ctx context.Context, operation, userPrompt string, maxTokens int) (string, error) {
+	settings := a.settings.Load()
+	if settings.apiKey == "" {
+		return "", errNoAPIKey
 	}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to summarize email: %w", err)
+	started := time.Now()
+	answer, err := settings.provider.Chat(ctx, prompt.System, userPrompt, maxTokens)
+	if a.calls != nil {
+		a.record(ctx, settings, operation, userPrompt, maxTokens, answer, err, time.Since(started))
 	}
-
-	a.logger.Info("Summarized email")
-
-	return summary, nil
+	return answer, err
 }
 
-// classifyEmailWithOpenAIStyle handles email classification using OpenAI/DeepSeek style API
-func (a *aiClient) classifyEmailWithOpenAIStyle(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
-	// Create a prompt to classify the email with more detailed context
-	var categoryList string
-	if len(categories) > 0 {
-		// Format categories with clear labels for better understanding by OpenAI
-		categoryDetails := make([]string, len(categories))
-		for i, cat := range categories {
-			categoryDetails[i] = fmt.Sprintf("Category: %s\nCategory Description: %s", cat.Name, cat.Description)
-		}
-		categoryList = strings.Join(categoryDetails, "\n\n")
-	} else {
-		categoryList = "No categories provided"
-	}
-
-	prompt := fmt.Sprintf(`Classify the following email into one of these categories:
-
-%s
-
-Email content:
-%s
-
-Please respond with only the exact category name that best fits the email or return a  empty string if don't find one that fits.`,
-		categoryList,
-		emailBody)
-
-	maxFetchEmails := config.GetEnv("MAX_FETCH_EMAILS", "3")
-	maxFetch, _ := strconv.Atoi(maxFetchEmails)
-	maxResults := int(maxFetch)
-
-	request := chatCompletionRequest{
-		Model: getModel(a.provider),
-		Messages: []message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		MaxTokens: maxResults,
-	}
-
-	resp, err := a.makeRequest(ctx, request)
+// record saves the request for debugging; failing to doesn't fail the request
+func (a *aiClient) record(ctx context.Context, settings *aiSettings, operation, userPrompt string, maxTokens int, answer string, err error, duration time.Duration) {
+	call := model.NewAICall(settings.name, settings.model, operation, prompt.System, redact(userPrompt), maxTokens)
+	call.Response = redact(answer)
 	if err != nil {
-		return "", fmt.Errorf("failed to classify email: %w", err)
+		call.Error = redact(err.Error())
 	}
+	call.DurationMS = duration.Milliseconds()
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no choices returned from AI")
+	if err := a.calls.Create(context.WithoutCancel(ctx), call); err != nil {
+		a.logger.Error("Failed to record AI call:", err)
 	}
-
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
 }
 
-// summarizeEmailWithOpenAIStyle handles email summarization using OpenAI/DeepSeek style API
-func (a *aiClient) summarizeEmailWithOpenAIStyle(ctx context.Context, emailBody string) (string, error) {
-	// Create a prompt to summarize the email
-	prompt := fmt.Sprintf(`Summarize the following email in 2-3 sentences: %s`, emailBody)
-
-	request := chatCompletionRequest{
-		Model: getModel(a.provider),
-		Messages: []message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		MaxTokens: 150,
-	}
-
-	resp, err := a.makeRequest(ctx, request)
+func (a *aiClient) ClassifyEmail(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+	userPrompt := a.classifyPrompt(emailBody, categories)
+	classification, err := a.chat(ctx, model.AIOperationClassify, userPrompt, 20) // enough for a category name
 	if err != nil {
-		return "", fmt.Errorf("failed to summarize email: %w", err)
+		return "", fmt.Errorf("failed to classify email: %w", err)
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no choices returned from AI")
-	}
+	a.logger.Info("Classified email as:", classification)
 
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	// Find the most similar category
+	return findBestCategoryMatch(classification, categoryNames(categories)), nil
 }
 
-// classifyEmailWithGemini handles email classification using Google Gemini API
-func (a *aiClient) classifyEmailWithGemini(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
-	// Create a prompt to classify the email with more detailed context
-	var categoryList string
-	if len(categories) > 0 {
-		// Format categories with clear labels for better understanding by Gemini
-		categoryDetails := make([]string, len(categories))
-		for i, cat := range categories {
-			categoryDetails[i] = fmt.Sprintf("Category: %s\nCategory Description: %s", cat.Name, cat.Description)
-		}
-		categoryList = strings.Join(categoryDetails, "\n\n")
-	} else {
-		categoryList = "No categories provided"
-	}
-
-	prompt := fmt.Sprintf(`Classify the following email into one of these categories:
-
-%s
-
-Email content:
-%s
-
-Please respond with only the exact category name that best fits the email and it must be classified into one of the categories mentioned above.`,
-		categoryList,
-		emailBody)
+// confidenceInstruction asks for the model's confidence on the line after the category name
+const confidenceInstruction = "On a second line, write how confident you are in that category, as a number from 0 to 100."
 
-	request := geminiRequest{
-		Contents: []geminiContent{
-			{
-				Role: "user",
-				Parts: []geminiPart{
-					{
-						Text: prompt,
-					},
-				},
-			},
-		},
-	}
-
-	resp, err := a.makeGeminiRequest(ctx, request)
+// ClassifyEmailWithConfidence is ClassifyEmail with the model's own estimate of how likely the
+// category is right; an answer without one is taken as certain
+func (a *aiClient) ClassifyEmailWithConfidence(ctx context.Context, emailBody string, categories []*model.Category) (string, float64, error) {
+	userPrompt := a.classifyPrompt(emailBody, categories) + "\n\n" + confidenceInstruction
+	answer, err := a.chat(ctx, model.AIOperationClassify, userPrompt, 30) // a category name and a number
 	if err != nil {
-		return "", fmt.Errorf("failed to classify email with gemini: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates returned from Gemini")
+		return "", 0, fmt.Errorf("failed to classify email: %w", err)
 	}
 
-	if len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no content parts in Gemini response")
-	}
-
-	cat := strings.TrimSpace(resp.Candidates[0].Content.Parts[0].Text)
-	return strings.TrimSpace(cat), nil
+	classification, confidence := parseConfidence(answer)
+	a.logger.Info("Classified email as:", classification, "with confidence", confidence)
+	return findBestCategoryMatch(classification, categoryNames(categories)), confidence, nil
 }
 
-// summarizeEmailWithGemini handles email summarization using Google Gemini API
-func (a *aiClient) summarizeEmailWithGemini(ctx context.Context, emailBody string) (string, error) {
-	// Create a prompt to summarize the email
-	prompt := fmt.Sprintf(`Summarize the following email in 2-3 sentences: %s`, emailBody)
-
-	request := geminiRequest{
-		Contents: []geminiContent{
-			{
-				Role: "user",
-				Parts: []geminiPart{
-					{
-						Text: prompt,
-					},
-				},
-			},
-		},
-	}
+func (a *aiClient) SummarizeEmail(ctx context.Context, emailBody, language string) (string, error) {
+	userPrompt := fmt.Sprintf("%s\n\n%s", summaryInstruction(language), prompt.Untrusted("email", emailBody))
 
-	resp, err := a.makeGeminiRequest(ctx, request)
+	summary, err := a.chat(ctx, model.AIOperationSummarize, userPrompt, 150)
 	if err != nil {
-		return "", fmt.Errorf("failed to summarize email with gemini: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates returned from Gemini")
+		return "", fmt.Errorf("failed to summarize email: %w", err)
 	}
 
-	if len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no content parts in Gemini response")
-	}
+	a.logger.Info("Summarized email")
 
-	return strings.TrimSpace(resp.Candidates[0].Content.Parts[0].Text), nil
+	return summary, nil
 }
 
-// makeRequest makes an HTTP request to the OpenAI/DeepSeek AI API
-func (a *aiClient) makeRequest(ctx context.Context, request chatCompletionRequest) (*chatCompletionResponse, error) {
-	// Marshal the request to JSON
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create the HTTP request
-	url := a.baseURL + "/chat/completions"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+// Analyze carries out task, written by the caller, on content from a third party such as a web
+// page, which the model is told never to take instructions from
+func (a *aiClient) Analyze(ctx context.Context, task, content string) (string, error) {
+	answer, err := a.chat(ctx, model.AIOperationAnalyze, task+"\n\n"+prompt.Untrusted("content", content), 300)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to analyze content: %w", err)
 	}
+	return answer, nil
+}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.apiKey)
-
-	// Make the request
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+// summaryInstruction asks for a short summary, in language when one is given
+func summaryInstruction(language string) string {
+	instruction := "Summarize the following email in 2-3 sentences."
+	if language != "" {
+		instruction += " " + prompt.Language(language)
 	}
-	defer resp.Body.Close()
+	return instruction
+}
 
-	// Check status code
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+// describeCategories formats the categories with clear labels for the classification prompt,
+// along with the hints users give for telling them apart
+func describeCategories(categories []*model.Category) string {
+	if len(categories) == 0 {
+		return "No categories provided"
 	}
-
-	// Decode the response
-	var chatResp chatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	details := make([]string, len(categories))
+	for i, cat := range categories {
+		details[i] = fmt.Sprintf("Category: %s\nCategory Description: %s", cat.Name, cat.Description)
+		if hints := strings.TrimSpace(cat.Hints); hints != "" {
+			details[i] += "\nCategory Hints: " + prompt.Sanitize(hints)
+		}
 	}
-
-	return &chatResp, nil
+	return strings.Join(details, "\n\n")
 }
 
-// makeGeminiRequest makes an HTTP request to the Google Gemini API
-func (a *aiClient) makeGeminiRequest(ctx context.Context, request geminiRequest) (*geminiResponse, error) {
-	// Marshal the request to JSON
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+func categoryNames(categories []*model.Category) []string {
+	names := make([]string, len(categories))
+	for i, cat := range categories {
+		names[i] = cat.Name
 	}
+	return names
+}
 
-	// Create the HTTP request - Gemini uses a different endpoint format
-	modelName := getModel(a.provider)
-	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", a.baseURL, modelName, a.apiKey)
+// confidencePattern finds the confidence in the answer's second line, e.g. "85" or "85%"
+var confidencePattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// parseConfidence splits an answer into the category name on its first line and the confidence
+// after it, between 0 and 1; without a number the answer is taken as certain
+func parseConfidence(answer string) (string, float64) {
+	classification, rest, _ := strings.Cut(strings.TrimSpace(answer), "\n")
+	number := confidencePattern.FindString(rest)
+	if number == "" {
+		return classification, 1
 	}
-
-	// Set headers for Gemini
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make the request
-	resp, err := a.httpClient.Do(req)
+	percent, err := strconv.ParseFloat(number, 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Gemini API request failed with status %d: %s", resp.StatusCode, string(body))
+		return classification, 1
 	}
-
-	// Decode the response
-	var geminiResp geminiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	return &geminiResp, nil
+	return classification, math.Min(percent, 100) / 100
 }
 
-// findBestCategoryMatch finds the best matching category from the AI response
+// maxCategoryAnswerLength is the longest answer still read as naming a category; a longer
+// one is the model chatting or repeating text injected into the email
+const maxCategoryAnswerLength = 100
+
+// findBestCategoryMatch maps the AI response onto one of the categories, so whatever the model
+// was talked into answering, only a category name comes out of it
 func findBestCategoryMatch(response string, categories []string) string {
-	responseLower := strings.ToLower(strings.TrimSpace(response))
+	responseLower := strings.ToLower(strings.Trim(strings.TrimSpace(response), "\"'`.*"))
 
 	// First, try exact matches (case-insensitive)
 	for _, category := range categories {
@@ -441,11 +270,23 @@ func findBestCategoryMatch(response string, categories []string) string {
 		}
 	}
 
-	// If no exact match, try partial matches
-	for _, category := range categories {
-		categoryLower := strings.ToLower(strings.TrimSpace(category))
-		if strings.Contains(responseLower, categoryLower) || strings.Contains(categoryLower, responseLower) {
-			return category
+	// If no exact match, try partial matches, preferring the longest category name so that
+	// "Work Travel" isn't taken for "Work"
+	if responseLower != "" && len(responseLower) <= maxCategoryAnswerLength {
+		best := ""
+		for _, category := range categories {
+			categoryLower := strings.ToLower(strings.TrimSpace(category))
+			if categoryLower == "" {
+				continue
+			}
+			if strings.Contains(responseLower, categoryLower) || strings.Contains(categoryLower, responseLower) {
+				if len(category) > len(best) {
+					best = category
+				}
+			}
+		}
+		if best != "" {
+			return best
 		}
 	}
 
@@ -457,11 +298,3 @@ func findBestCategoryMatch(response string, categories []string) string {
 	// This shouldn't happen in practice since we check for categories in the service
 	return ""
 }
-
-// getEnv retrieves environment variable or returns default value
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultValue
-}
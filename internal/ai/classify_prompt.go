@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/prompt"
+)
+
+// DefaultClassifyPrompt is the classification prompt used unless an experiment tries another
+const DefaultClassifyPrompt = "default"
+
+// classifyPrompts are the variants of the classification prompt, by name. A new variant is
+// added here and tried on a share of emails with AI_EXPERIMENT_PROMPT before replacing the default.
+var classifyPrompts = map[string]func(emailBody string, categories []*model.Category) string{
+	DefaultClassifyPrompt: defaultClassifyPrompt,
+	"compact":             compactClassifyPrompt,
+}
+
+// ClassifyPrompts lists the names of the classification prompt variants, sorted
+func ClassifyPrompts() []string {
+	names := make([]string, 0, len(classifyPrompts))
+	for name := range classifyPrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func defaultClassifyPrompt(emailBody string, categories []*model.Category) string {
+	return fmt.Sprintf(`Classify the following email into one of these categories:
+
+%s
+
+Email content:
+%s
+
+Please respond with only the exact category name that best fits the email; it must be one of the categories mentioned above.`,
+		describeCategories(categories),
+		prompt.Untrusted("email", emailBody))
+}
+
+// compactClassifyPrompt lists each category on a single line, for fewer tokens per request
+func compactClassifyPrompt(emailBody string, categories []*model.Category) string {
+	lines := make([]string, len(categories))
+	for i, cat := range categories {
+		lines[i] = "- " + cat.Name + ": " + cat.Description
+		if hints := strings.TrimSpace(cat.Hints); hints != "" {
+			lines[i] += " (" + prompt.Sanitize(hints) + ")"
+		}
+	}
+	return fmt.Sprintf("Categories:\n%s\n\n%s\n\nAnswer with the name of the one category above the email belongs in, and nothing else.",
+		strings.Join(lines, "\n"),
+		prompt.Untrusted("email", emailBody))
+}
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+var (
+	emailAddressPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Query strings of links carry tokens, e.g. of unsubscribe and password reset links
+	urlQueryPattern = regexp.MustCompile(`(https?://[^\s?#"'<>]+)\?[^\s"'<>]*`)
+	// Six digits or more, spaces and dashes allowed, are account, card, phone or verification numbers
+	longNumberPattern = regexp.MustCompile(`\d(?:[ -]?\d){5,}`)
+)
+
+// redact masks what identifies people or grants access in text kept for debugging: email
+// addresses, link query strings and long numbers
+func redact(text string) string {
+	text = emailAddressPattern.ReplaceAllString(text, "[email]")
+	text = urlQueryPattern.ReplaceAllString(text, "$1?[redacted]")
+	return longNumberPattern.ReplaceAllString(text, "[number]")
+}
+
+// DebugService lists the AI calls recorded in debug mode and replays their prompts against
+// another provider or model, for comparing answers
+type DebugService struct {
+	calls      repository.AICallRepository
+	apiKey     func(provider string) string
+	baseURL    string // replaces the providers' API URL when set
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewDebugService creates the service over the recorded calls; apiKey returns the configured key
+// of a provider, empty when there is none
+func NewDebugService(calls repository.AICallRepository, apiKey func(provider string) string, logger *logger.Logger) *DebugService {
+	return NewDebugServiceWithBaseURL(calls, apiKey, "", logger)
+}
+
+// NewDebugServiceWithBaseURL replays prompts against baseURL instead of the providers' APIs,
+// like NewAIClientWithBaseURL
+func NewDebugServiceWithBaseURL(calls repository.AICallRepository, apiKey func(provider string) string, baseURL string, logger *logger.Logger) *DebugService {
+	return &DebugService{
+		calls:      calls,
+		apiKey:     apiKey,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{},
+		logger:     logger,
+	}
+}
+
+func (d *DebugService) GetCalls(ctx context.Context, limit int) ([]*model.AICall, error) {
+	return d.calls.FindRecent(ctx, limit)
+}
+
+func (d *DebugService) GetCall(ctx context.Context, id string) (*model.AICall, error) {
+	return d.calls.FindByID(ctx, id)
+}
+
+// Replay sends the call's prompt, as it was recorded, to provider, the call's own when empty,
+// with modelName or the provider's default model. The provider failing is part of the
+// comparison and is reported in the replay, not as an error.
+func (d *DebugService) Replay(ctx context.Context, id, provider, modelName string) (*model.AICallReplay, error) {
+	call, err := d.calls.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if provider == "" {
+		provider = call.Provider
+	}
+	factory, ok := providerFactory(provider)
+	if !ok {
+		return nil, apierror.InvalidFields([]apierror.FieldError{
+			{Field: "provider", Message: "must be one of " + strings.Join(Providers(), ", ")},
+		})
+	}
+	apiKey := d.apiKey(provider)
+	if apiKey == "" {
+		return nil, apierror.Validation("no API key configured for provider " + provider)
+	}
+
+	chat := factory(ProviderConfig{
+		APIKey:     apiKey,
+		Model:      modelName,
+		BaseURL:    d.baseURL,
+		HTTPClient: d.httpClient,
+	})
+	started := time.Now()
+	answer, err := chat.Chat(ctx, call.System, call.Prompt, call.MaxTokens)
+	replay := &model.AICallReplay{
+		Call:       call,
+		Provider:   provider,
+		Model:      modelName,
+		Response:   redact(answer),
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		replay.Error = redact(err.Error())
+	}
+
+	d.logger.Info("Replayed AI call", call.ID, "against", provider)
+	return replay, nil
+}
+
+// PurgeCalls deletes the calls recorded longer than retention ago
+func (d *DebugService) PurgeCalls(ctx context.Context, retention time.Duration) (int, error) {
+	return d.calls.DeleteBefore(ctx, time.Now().Add(-retention))
+}
@@ -0,0 +1,9 @@
+package ai
+
+const ProviderDeepSeek = "deepseek"
+
+func init() {
+	Register(ProviderDeepSeek, func(config ProviderConfig) ChatProvider {
+		return newChatCompletionsProvider(config, "https://api.deepseek.com", "deepseek-chat")
+	})
+}
@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+)
+
+// ProviderHealth is what a FailoverClient knows of one of its providers
+type ProviderHealth struct {
+	Name           string     `json:"name"`
+	Healthy        bool       `json:"healthy"`
+	UnhealthyUntil *time.Time `json:"unhealthy_until,omitempty"` // when it is tried first again
+	LastError      string     `json:"last_error,omitempty"`
+	Requests       int64      `json:"requests"`
+	Failures       int64      `json:"failures"` // rate limits and outages
+}
+
+type failoverProvider struct {
+	name   string
+	client service.AIClient
+
+	unhealthyUntil time.Time
+	lastError      string
+	requests       int64
+	failures       int64
+}
+
+// FailoverClient sends each request to the first healthy of its providers, in the order they
+// were added. A provider that is rate limited or unavailable is skipped for the cooldown, and
+// its request goes on to the next one; other errors, such as a refusal, are returned as they are.
+type FailoverClient struct {
+	mutex     sync.Mutex
+	providers []*failoverProvider
+	cooldown  time.Duration
+	logger    *logger.Logger
+}
+
+// NewFailoverClient creates a client without providers; add them with AddProvider
+func NewFailoverClient(cooldown time.Duration, logger *logger.Logger) *FailoverClient {
+	return &FailoverClient{cooldown: cooldown, logger: logger}
+}
+
+// AddProvider appends a provider to the chain, after those already added
+func (f *FailoverClient) AddProvider(name string, client service.AIClient) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.providers = append(f.providers, &failoverProvider{name: name, client: client})
+}
+
+// SetCooldown changes how long a failing provider is skipped
+func (f *FailoverClient) SetCooldown(cooldown time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.cooldown = cooldown
+}
+
+// Health reports each provider, in the order they are tried
+func (f *FailoverClient) Health() []ProviderHealth {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	health := make([]ProviderHealth, len(f.providers))
+	for i, provider := range f.providers {
+		health[i] = ProviderHealth{
+			Name:      provider.name,
+			Healthy:   !now.Before(provider.unhealthyUntil),
+			LastError: provider.lastError,
+			Requests:  provider.requests,
+			Failures:  provider.failures,
+		}
+		if !health[i].Healthy {
+			until := provider.unhealthyUntil
+			health[i].UnhealthyUntil = &until
+		}
+	}
+	return health
+}
+
+func (f *FailoverClient) ClassifyEmail(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+	return f.call(ctx, func(client service.AIClient) (string, error) {
+		return client.ClassifyEmail(ctx, emailBody, categories)
+	})
+}
+
+// ClassifyEmailWithConfidence asks the provider answering for its confidence when it can tell
+func (f *FailoverClient) ClassifyEmailWithConfidence(ctx context.Context, emailBody string, categories []*model.Category) (string, float64, error) {
+	var confidence float64
+	name, err := f.call(ctx, func(client service.AIClient) (string, error) {
+		classifier, ok := client.(service.ConfidenceClassifier)
+		if !ok {
+			confidence = 1
+			return client.ClassifyEmail(ctx, emailBody, categories)
+		}
+		name, score, err := classifier.ClassifyEmailWithConfidence(ctx, emailBody, categories)
+		confidence = score
+		return name, err
+	})
+	return name, confidence, err
+}
+
+func (f *FailoverClient) SummarizeEmail(ctx context.Context, emailBody, language string) (string, error) {
+	return f.call(ctx, func(client service.AIClient) (string, error) {
+		return client.SummarizeEmail(ctx, emailBody, language)
+	})
+}
+
+func (f *FailoverClient) Analyze(ctx context.Context, task, content string) (string, error) {
+	return f.call(ctx, func(client service.AIClient) (string, error) {
+		return client.Analyze(ctx, task, content)
+	})
+}
+
+func (f *FailoverClient) call(ctx context.Context, request func(service.AIClient) (string, error)) (string, error) {
+	err := errors.New("no AI provider configured")
+	for _, provider := range f.order() {
+		var answer string
+		answer, err = request(provider.client)
+		f.record(provider, err)
+		if err == nil || ctx.Err() != nil || !shouldFailOver(err) {
+			return answer, err
+		}
+	}
+	return "", err
+}
+
+// order lists the healthy providers first, so requests still go out when all of them failed
+// recently; among the unhealthy ones, the one recovering first comes first
+func (f *FailoverClient) order() []*failoverProvider {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	var healthy, unhealthy []*failoverProvider
+	for _, provider := range f.providers {
+		if now.Before(provider.unhealthyUntil) {
+			unhealthy = append(unhealthy, provider)
+		} else {
+			healthy = append(healthy, provider)
+		}
+	}
+	for i := 1; i < len(unhealthy); i++ {
+		for j := i; j > 0 && unhealthy[j].unhealthyUntil.Before(unhealthy[j-1].unhealthyUntil); j-- {
+			unhealthy[j], unhealthy[j-1] = unhealthy[j-1], unhealthy[j]
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (f *FailoverClient) record(provider *failoverProvider, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	provider.requests++
+	if err == nil {
+		if !provider.unhealthyUntil.IsZero() {
+			f.logger.Info("AI provider", provider.name, "recovered")
+		}
+		provider.unhealthyUntil = time.Time{}
+		provider.lastError = ""
+		return
+	}
+	if !shouldFailOver(err) {
+		return
+	}
+
+	provider.failures++
+	provider.lastError = err.Error()
+	provider.unhealthyUntil = time.Now().Add(f.cooldown)
+	if len(f.providers) > 1 {
+		f.logger.Warn("AI provider", provider.name, "failed, skipping it for", f.cooldown.String(), ":", err)
+	}
+}
+
+// shouldFailOver tells the errors another provider may not run into
+func shouldFailOver(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable)
+}
@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const ProviderGemini = "gemini"
+
+func init() {
+	Register(ProviderGemini, func(config ProviderConfig) ChatProvider {
+		model := config.Model
+		if model == "" {
+			model = "gemini-2.0-flash-lite"
+		}
+		return &geminiProvider{
+			baseURL:    config.baseURLOr("https://generativelanguage.googleapis.com/v1beta"),
+			apiKey:     config.APIKey,
+			model:      model,
+			httpClient: config.HTTPClient,
+		}
+	})
+}
+
+// geminiProvider speaks the Google Gemini API, which takes the system prompt on its own and has
+// no token limit per request
+type geminiProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func (p *geminiProvider) Chat(ctx context.Context, system, user string, maxTokens int) (string, error) {
+	request := geminiRequest{
+		SystemInstruction: &geminiContent{Parts: []geminiPart{{Text: system}}},
+		Contents: []geminiContent{
+			{Role: "user", Parts: []geminiPart{{Text: user}}},
+		},
+	}
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, p.model, p.apiKey)
+
+	var response geminiResponse
+	if err := postJSON(ctx, p.httpClient, url, nil, "Gemini API", request, &response); err != nil {
+		return "", err
+	}
+	return response.text()
+}
+
+// Gemini API request/response structures
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate    `json:"candidates"`
+	PromptFeedback geminiPromptFeedback `json:"promptFeedback"`
+}
+
+// geminiPromptFeedback tells why a prompt was blocked before any candidate was generated
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
+type geminiCandidate struct {
+	Content       geminiContentForResponse `json:"content"`
+	FinishReason  string                   `json:"finishReason"`
+	SafetyRatings []interface{}            `json:"safetyRatings"`
+}
+
+type geminiContentForResponse struct {
+	Parts []geminiPart `json:"parts"`
+}
+
+// text returns the first candidate's answer, or ErrRefused when Gemini blocked the prompt or the answer
+func (r *geminiResponse) text() (string, error) {
+	if r.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("%w: prompt blocked for %s", ErrRefused, r.PromptFeedback.BlockReason)
+	}
+	if len(r.Candidates) == 0 {
+		return "", fmt.Errorf("no candidates returned from Gemini")
+	}
+
+	candidate := r.Candidates[0]
+	if len(candidate.Content.Parts) == 0 {
+		switch candidate.FinishReason {
+		case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+			return "", fmt.Errorf("%w: answer blocked for %s", ErrRefused, candidate.FinishReason)
+		}
+		return "", fmt.Errorf("no content parts in Gemini response")
+	}
+	return strings.TrimSpace(candidate.Content.Parts[0].Text), nil
+}
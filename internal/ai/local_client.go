@@ -0,0 +1,216 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+)
+
+const (
+	// keywordWeight counts each word of a category's name, description and hints as that many
+	// occurrences, so the descriptions steer classification until users have filed emails
+	keywordWeight = 5
+	// maxLearnedWords bounds the words learned from a single email
+	maxLearnedWords = 500
+	// A summary is made of the email's first sentences, as many as fit
+	maxLocalSummarySentences = 2
+	maxLocalSummaryLength    = 240
+)
+
+// stopWords are too common to tell categories apart
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "you": true, "your": true, "are": true, "with": true,
+	"this": true, "that": true, "from": true, "have": true, "has": true, "was": true, "were": true,
+	"will": true, "not": true, "but": true, "all": true, "our": true, "can": true, "any": true,
+	"its": true, "into": true, "about": true, "more": true, "been": true, "they": true, "their": true,
+	"them": true, "there": true, "what": true, "when": true, "which": true, "who": true, "would": true,
+	"emails": true, "email": true,
+}
+
+// wordCounts tallies the words of the emails filed in one category
+type wordCounts struct {
+	words     map[string]int
+	total     int
+	documents int
+}
+
+func (w *wordCounts) add(words []string, weight int) {
+	for _, word := range words {
+		w.words[word] += weight
+		w.total += weight
+	}
+}
+
+// LocalClient classifies emails without a provider, with a naive Bayes model over words. It
+// starts from the names, descriptions and hints of the categories and learns from the emails
+// users file themselves. Summaries are the email's first sentences, in its own language, and
+// other analysis isn't supported.
+type LocalClient struct {
+	mutex   sync.RWMutex
+	learned map[string]*wordCounts // by category ID
+	logger  *logger.Logger
+}
+
+// NewLocalClient creates a local model that hasn't learned from any email yet
+func NewLocalClient(logger *logger.Logger) *LocalClient {
+	return &LocalClient{learned: make(map[string]*wordCounts), logger: logger}
+}
+
+// Learn teaches the model that an email with this text belongs in the category
+func (l *LocalClient) Learn(text, categoryID string) {
+	words := tokenize(text)
+	if len(words) > maxLearnedWords {
+		words = words[:maxLearnedWords]
+	}
+	if len(words) == 0 || categoryID == "" {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counts, ok := l.learned[categoryID]
+	if !ok {
+		counts = &wordCounts{words: make(map[string]int)}
+		l.learned[categoryID] = counts
+	}
+	counts.add(words, 1)
+	counts.documents++
+}
+
+func (l *LocalClient) ClassifyEmail(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+	name, _, err := l.ClassifyEmailWithConfidence(ctx, emailBody, categories)
+	return name, err
+}
+
+// ClassifyEmailWithConfidence takes the probability the model gives the best category as its
+// confidence
+func (l *LocalClient) ClassifyEmailWithConfidence(ctx context.Context, emailBody string, categories []*model.Category) (string, float64, error) {
+	if len(categories) == 0 {
+		return "", 0, errors.New("no categories to classify into")
+	}
+
+	l.mutex.RLock()
+	models := make([]*wordCounts, len(categories))
+	vocabulary := make(map[string]bool)
+	documents := 0
+	for i, category := range categories {
+		counts := &wordCounts{words: make(map[string]int)}
+		counts.add(tokenize(category.Name+" "+category.Description+" "+category.Hints), keywordWeight)
+		if learned, ok := l.learned[category.ID]; ok {
+			for word, count := range learned.words {
+				counts.words[word] += count
+			}
+			counts.total += learned.total
+			counts.documents = learned.documents
+		}
+		for word := range counts.words {
+			vocabulary[word] = true
+		}
+		documents += counts.documents
+		models[i] = counts
+	}
+	l.mutex.RUnlock()
+
+	// Words no category has seen say nothing about which one the email belongs in
+	var words []string
+	for _, word := range tokenize(emailBody) {
+		if vocabulary[word] {
+			words = append(words, word)
+		}
+	}
+
+	best, scores := 0, make([]float64, len(models))
+	for i, counts := range models {
+		scores[i] = math.Log(float64(counts.documents+1) / float64(documents+len(models)))
+		for _, word := range words {
+			scores[i] += math.Log(float64(counts.words[word]+1) / float64(counts.total+len(vocabulary)))
+		}
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+
+	// The scores are logarithms; taken relative to the best one, their exponentials stay in range
+	total := 0.0
+	for _, score := range scores {
+		total += math.Exp(score - scores[best])
+	}
+	confidence := 1 / total
+
+	l.logger.Info("Classified email locally as:", categories[best].Name, "with confidence", confidence)
+	return categories[best].Name, confidence, nil
+}
+
+// SummarizeEmail takes the first sentences of the email; it can't write them in another language
+func (l *LocalClient) SummarizeEmail(ctx context.Context, emailBody, language string) (string, error) {
+	var taken []string
+	length := 0
+	for _, sentence := range sentences(emailBody) {
+		if len(taken) == maxLocalSummarySentences || (len(taken) > 0 && length+utf8.RuneCountInString(sentence) > maxLocalSummaryLength) {
+			break
+		}
+		taken = append(taken, sentence)
+		length += utf8.RuneCountInString(sentence) + 1
+	}
+
+	summary := strings.Join(taken, " ")
+	if utf8.RuneCountInString(summary) > maxLocalSummaryLength {
+		summary = string([]rune(summary)[:maxLocalSummaryLength-3]) + "..."
+	}
+	return summary, nil
+}
+
+func (l *LocalClient) Analyze(ctx context.Context, task, content string) (string, error) {
+	return "", service.ErrAnalysisUnsupported
+}
+
+// tokenize lower-cases the text and splits it into words, leaving out short and common ones;
+// plurals count as their singular, so "orders" in a description matches "order" in an email
+func tokenize(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) < 3 || stopWords[word] {
+			continue
+		}
+		switch {
+		case len(word) > 4 && strings.HasSuffix(word, "ies"):
+			word = strings.TrimSuffix(word, "ies") + "y"
+		case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+			word = strings.TrimSuffix(word, "s")
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// sentences splits the text at sentence ends and line breaks
+func sentences(text string) []string {
+	var result []string
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		end := r == '\n' || ((r == '.' || r == '!' || r == '?') && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])))
+		if !end {
+			continue
+		}
+		if sentence := strings.TrimSpace(string(runes[start : i+1])); strings.ContainsFunc(sentence, unicode.IsLetter) {
+			result = append(result, sentence)
+		}
+		start = i + 1
+	}
+	if sentence := strings.TrimSpace(string(runes[start:])); strings.ContainsFunc(sentence, unicode.IsLetter) {
+		result = append(result, sentence)
+	}
+	return result
+}
@@ -2,17 +2,21 @@ package ai
 
 import (
 	"context"
-	"strconv"
 	"strings"
 
-	"jump-challenge/internal/config"
 	"jump-challenge/internal/model"
 )
 
+// mockSummaryLength is how much of the body default mock summaries keep
+const mockSummaryLength = 3
+
 // MockAIClient is a mock implementation of AIClient for testing
 type MockAIClient struct {
 	ClassifyEmailFunc  func(ctx context.Context, emailBody string, categories []*model.Category) (string, error)
-	SummarizeEmailFunc func(ctx context.Context, emailBody string) (string, error)
+	SummarizeEmailFunc func(ctx context.Context, emailBody, language string) (string, error)
+	AnalyzeFunc        func(ctx context.Context, task, content string) (string, error)
+	// ClassifyEmailWithConfidenceFunc defaults to ClassifyEmail with full confidence
+	ClassifyEmailWithConfidenceFunc func(ctx context.Context, emailBody string, categories []*model.Category) (string, float64, error)
 }
 
 func NewMockAIClient() *MockAIClient {
@@ -31,18 +35,31 @@ func (m *MockAIClient) ClassifyEmail(ctx context.Context, emailBody string, cate
 	return "", nil
 }
 
-func (m *MockAIClient) SummarizeEmail(ctx context.Context, emailBody string) (string, error) {
-	if m.SummarizeEmailFunc != nil {
-		return m.SummarizeEmailFunc(ctx, emailBody)
+func (m *MockAIClient) ClassifyEmailWithConfidence(ctx context.Context, emailBody string, categories []*model.Category) (string, float64, error) {
+	if m.ClassifyEmailWithConfidenceFunc != nil {
+		return m.ClassifyEmailWithConfidenceFunc(ctx, emailBody, categories)
 	}
+	name, err := m.ClassifyEmail(ctx, emailBody, categories)
+	return name, 1, err
+}
 
-	maxFetchEmails := config.GetEnv("MAX_FETCH_EMAILS", "3")
-	maxFetch, _ := strconv.Atoi(maxFetchEmails)
-	maxResults := int(maxFetch)
+func (m *MockAIClient) SummarizeEmail(ctx context.Context, emailBody, language string) (string, error) {
+	if m.SummarizeEmailFunc != nil {
+		return m.SummarizeEmailFunc(ctx, emailBody, language)
+	}
 
-	// Default mock behavior: return a summary based on first few words
-	if len(emailBody) > maxResults {
-		return strings.TrimSpace(emailBody[:maxResults]) + "... (summary)", nil
+	// Default mock behavior: return a summary based on first few characters
+	if len(emailBody) > mockSummaryLength {
+		return strings.TrimSpace(emailBody[:mockSummaryLength]) + "... (summary)", nil
 	}
 	return strings.TrimSpace(emailBody) + " (summary)", nil
 }
+
+func (m *MockAIClient) Analyze(ctx context.Context, task, content string) (string, error) {
+	if m.AnalyzeFunc != nil {
+		return m.AnalyzeFunc(ctx, task, content)
+	}
+
+	// Default mock behavior: no answer
+	return "", nil
+}
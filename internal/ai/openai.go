@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const ProviderOpenAI = "openai"
+
+func init() {
+	Register(ProviderOpenAI, func(config ProviderConfig) ChatProvider {
+		return newChatCompletionsProvider(config, "https://api.openai.com/v1", "gpt-4o")
+	})
+}
+
+// chatCompletionsProvider speaks the OpenAI chat completions API, which other providers, such as
+// DeepSeek, offer as well. The model is the provider's own; AI_MODEL doesn't change it.
+type chatCompletionsProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func newChatCompletionsProvider(config ProviderConfig, baseURL, model string) *chatCompletionsProvider {
+	return &chatCompletionsProvider{
+		baseURL:    config.baseURLOr(baseURL),
+		apiKey:     config.APIKey,
+		model:      model,
+		httpClient: config.HTTPClient,
+	}
+}
+
+func (p *chatCompletionsProvider) Chat(ctx context.Context, system, user string, maxTokens int) (string, error) {
+	request := chatCompletionRequest{
+		Model: p.model,
+		Messages: []message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		MaxTokens: maxTokens,
+	}
+	header := http.Header{"Authorization": {"Bearer " + p.apiKey}}
+
+	var response chatCompletionResponse
+	if err := postJSON(ctx, p.httpClient, p.baseURL+"/chat/completions", header, "API", request, &response); err != nil {
+		return "", err
+	}
+	return response.text()
+}
+
+// OpenAI/DeepSeek API request/response structures
+type chatCompletionRequest struct {
+	Model     string    `json:"model"`
+	Messages  []message `json:"messages"`
+	MaxTokens int       `json:"max_tokens,omitempty"`
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	Refusal string `json:"refusal,omitempty"` // set instead of content when the model declines
+}
+
+type chatCompletionResponse struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   usage    `json:"usage"`
+}
+
+type choice struct {
+	Index        int     `json:"index"`
+	Message      message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// text returns the first choice's answer, or ErrRefused when the model declined to give one
+func (r *chatCompletionResponse) text() (string, error) {
+	if len(r.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned from AI")
+	}
+
+	choice := r.Choices[0]
+	if choice.Message.Refusal != "" {
+		return "", fmt.Errorf("%w: %s", ErrRefused, choice.Message.Refusal)
+	}
+	if choice.FinishReason == "content_filter" {
+		return "", fmt.Errorf("%w: content filter", ErrRefused)
+	}
+	return strings.TrimSpace(choice.Message.Content), nil
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ChatProvider sends prompts to one provider's API. Each provider lives in its own file and
+// registers a factory under its name from init.
+type ChatProvider interface {
+	// Chat sends the system prompt and the user's prompt and returns the answer, kept within
+	// maxTokens where the API allows it. Errors wrap ErrRateLimited, ErrUnavailable or ErrRefused
+	// when they are one of those, which decide whether another provider is tried.
+	Chat(ctx context.Context, system, user string, maxTokens int) (string, error)
+}
+
+// ProviderConfig is what a provider is created with
+type ProviderConfig struct {
+	APIKey     string
+	Model      string // overrides the provider's default model, where the provider supports it
+	BaseURL    string // replaces the provider's API URL when set, e.g. a fake server in tests
+	HTTPClient *http.Client
+}
+
+// baseURLOr returns the configured base URL, or the provider's own when none is
+func (c ProviderConfig) baseURLOr(defaultURL string) string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return defaultURL
+}
+
+// ProviderFactory creates a provider from its configuration
+type ProviderFactory func(config ProviderConfig) ChatProvider
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]ProviderFactory)
+)
+
+// Register makes a provider available under name, the value of AI_PROVIDER that selects it.
+// Registering the same name twice panics, as it is a programming error.
+func Register(name string, factory ProviderFactory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic("ai: provider " + name + " registered twice")
+	}
+	registry[name] = factory
+}
+
+// Providers lists the names of the registered providers, sorted
+func Providers() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func providerFactory(name string) (ProviderFactory, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// postJSON sends request as JSON to url and decodes the answer into response; api names the
+// API in errors
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, api string, request, response any) error {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return statusError(api, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// statusError describes a failed API response, wrapping ErrRateLimited for 429s and
+// ErrUnavailable for 5xx
+func statusError(api string, resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w (%s returned status %d): %s", ErrRateLimited, api, resp.StatusCode, string(body))
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%w: %s request failed with status %d: %s", ErrUnavailable, api, resp.StatusCode, string(body))
+	}
+	return fmt.Errorf("%s request failed with status %d: %s", api, resp.StatusCode, string(body))
+}
+
+// requestError describes a request that got no response, wrapping ErrUnavailable unless the
+// caller gave up on it
+func requestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	return fmt.Errorf("%w: failed to make request: %w", ErrUnavailable, err)
+}
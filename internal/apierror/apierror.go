@@ -0,0 +1,120 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Machine-readable error codes returned to API clients
+const (
+	CodeNotFound       = "not_found"
+	CodeUnauthorized   = "unauthorized"
+	CodeForbidden      = "forbidden"
+	CodeValidation     = "validation_failed"
+	CodeUpstream       = "upstream_error"
+	CodeInternal       = "internal_error"
+	CodeUnavailable    = "unavailable"
+	CodeReauthRequired = "reauth_required"
+	CodeQuotaExceeded  = "quota_exceeded"
+	CodeConflict       = "conflict"
+)
+
+// Error is an error that knows how it should be reported over HTTP
+type Error struct {
+	Status  int
+	Code    string
+	Message string       // safe to show to clients
+	Fields  []FieldError // per-field problems of a validation error
+	Current any          // the existing resource a conflict is with, returned to the client
+	Err     error        // underlying cause, logged but never exposed
+}
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any error with the same code, so errors.Is(err, apierror.ErrNotFound) works for every not found error
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Sentinels for errors.Is comparisons
+var (
+	ErrNotFound     = &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: "not found"}
+	ErrUnauthorized = &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "unauthorized"}
+	ErrForbidden    = &Error{Status: http.StatusForbidden, Code: CodeForbidden, Message: "forbidden"}
+	ErrValidation   = &Error{Status: http.StatusBadRequest, Code: CodeValidation, Message: "validation failed"}
+	ErrUpstream     = &Error{Status: http.StatusBadGateway, Code: CodeUpstream, Message: "upstream service failed"}
+	ErrInternal     = &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error"}
+	ErrUnavailable  = &Error{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: "service unavailable"}
+	ErrConflict     = &Error{Status: http.StatusConflict, Code: CodeConflict, Message: "conflict"}
+)
+
+// NotFound reports a missing resource
+func NotFound(message string) *Error {
+	return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: message}
+}
+
+// Unauthorized reports a missing or expired session
+func Unauthorized(message string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: message}
+}
+
+// Forbidden reports an action the user is not allowed to perform
+func Forbidden(message string) *Error {
+	return &Error{Status: http.StatusForbidden, Code: CodeForbidden, Message: message}
+}
+
+// Validation reports a malformed or invalid request
+func Validation(message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeValidation, Message: message}
+}
+
+// InvalidFields reports a request whose fields failed validation
+func InvalidFields(fields []FieldError) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeValidation, Message: "Invalid request", Fields: fields}
+}
+
+// Conflict reports a request that collides with an existing resource; current, when not nil,
+// is returned so the client can use it instead
+func Conflict(message string, current any) *Error {
+	return &Error{Status: http.StatusConflict, Code: CodeConflict, Message: message, Current: current}
+}
+
+// Upstream reports a failure of an external service such as Gmail or the AI provider
+func Upstream(message string, err error) *Error {
+	return &Error{Status: http.StatusBadGateway, Code: CodeUpstream, Message: message, Err: err}
+}
+
+// Internal reports an unexpected failure
+func Internal(message string, err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: message, Err: err}
+}
+
+// Unavailable reports a temporary capacity problem; the client may retry later
+func Unavailable(message string) *Error {
+	return &Error{Status: http.StatusServiceUnavailable, Code: CodeUnavailable, Message: message}
+}
+
+// From returns err as an *Error, treating untyped errors as internal failures described by fallback
+func From(err error, fallback string) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal(fallback, err)
+}
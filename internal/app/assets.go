@@ -0,0 +1,93 @@
+package app
+
+import (
+	_ "embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"jump-challenge/internal/static"
+	"jump-challenge/internal/templates"
+)
+
+// defaultCategories is the category set seeded into an empty database
+//
+//go:embed categories.json
+var defaultCategories []byte
+
+// overlayFS serves files from override when present and falls back to base
+type overlayFS struct {
+	override fs.FS
+	base     fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.override.Open(name)
+	if err == nil {
+		return file, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir lists the files of both, so that globs and walks see the files that aren't overridden
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(o.base, name)
+	overrides, overrideErr := fs.ReadDir(o.override, name)
+	if err != nil && overrideErr != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]fs.DirEntry, len(entries)+len(overrides))
+	for _, entry := range append(entries, overrides...) {
+		byName[entry.Name()] = entry
+	}
+	merged := make([]fs.DirEntry, 0, len(byName))
+	for _, entry := range byName {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
+
+// withOverride layers dir over the embedded files; an empty or missing dir leaves them untouched
+func withOverride(base fs.FS, dir string) fs.FS {
+	if dir == "" {
+		return base
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return base
+	}
+	return overlayFS{override: os.DirFS(dir), base: base}
+}
+
+// templatesFS returns the HTML pages, optionally customized via <AssetsDir>/templates
+func (c *Container) templatesFS() fs.FS {
+	return withOverride(templates.FS, c.assetPath("templates"))
+}
+
+// staticFS returns the static assets, optionally customized via <AssetsDir>/static
+func (c *Container) staticFS() fs.FS {
+	return withOverride(static.FS, c.assetPath("static"))
+}
+
+// categoriesJSON returns <AssetsDir>/categories.json when present and the embedded defaults otherwise
+func (c *Container) categoriesJSON() []byte {
+	if path := c.assetPath("categories.json"); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			return data
+		}
+	}
+	return defaultCategories
+}
+
+func (c *Container) assetPath(name string) string {
+	if c.Config.AssetsDir == "" {
+		return ""
+	}
+	return filepath.Join(c.Config.AssetsDir, name)
+}
@@ -0,0 +1,712 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/billing"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/mailer"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/push"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/repository/postgres"
+	"jump-challenge/internal/router"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
+	"jump-challenge/internal/static"
+	"jump-challenge/internal/telegram"
+	"jump-challenge/internal/validation"
+	"jump-challenge/internal/view"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	_ "github.com/lib/pq"
+)
+
+// Job is a background worker managed by the container
+type Job interface {
+	Start()
+	Stop()
+}
+
+// Container owns every application component and its lifecycle
+type Container struct {
+	Config    *config.Config // snapshot the container was built from
+	Logger    *logger.Logger
+	DB        *sql.DB // nil when running on in-memory repositories
+	ReplicaDB *sql.DB // nil without DATABASE_REPLICA_URL
+
+	// Current configuration; the sync interval and AI provider follow reloads
+	ConfigStore *config.Store
+
+	// Repositories
+	UserRepo               repository.UserRepository
+	CategoryRepo           repository.CategoryRepository
+	EmailRepo              repository.EmailRepository
+	RetentionRepo          repository.RetentionPolicyRepository
+	AutomationRepo         repository.AutomationRepository
+	PreferenceRepo         repository.NotificationPreferencesRepository
+	PushRepo               repository.PushSubscriptionRepository
+	TelegramRepo           repository.TelegramLinkRepository
+	WebhookRepo            repository.WebhookRepository
+	ShareRepo              repository.EmailShareRepository
+	OrgRepo                repository.OrganizationRepository
+	InvitationRepo         repository.InvitationRepository
+	BillingRepo            repository.BillingAccountRepository
+	UsageRepo              repository.UsageRepository
+	ViewRepo               repository.SavedViewRepository
+	ProfileRepo            repository.SenderProfileRepository
+	ShipmentRepo           repository.ShipmentRepository
+	VIPRepo                repository.VIPSenderRepository
+	ReportRepo             repository.ReportRepository
+	UnsubscribeAttemptRepo repository.UnsubscribeAttemptRepository
+	PendingEventRepo       repository.PendingEventRepository
+	GmailActionRepo        repository.GmailActionRepository
+	MergeCodeRepo          repository.AccountMergeCodeRepository
+	CleanupPolicyRepo      repository.CleanupPolicyRepository
+	CleanupRunRepo         repository.CleanupRunRepository
+	AICallRepo             repository.AICallRepository
+	ExperimentRepo         repository.ExperimentResultRepository
+
+	// External clients
+	GmailClient    service.MailClient
+	GmailMonitor   *service.GmailMonitor // wraps GmailClient, recording whether Gmail answers each user and pausing failing ones
+	AIClient       service.AIClient
+	LocalAI        *ai.LocalClient        // last resort of the AI client; nil when the AI client was replaced
+	PushClient     service.PushClient     // nil when no VAPID keys are configured
+	TelegramClient service.TelegramClient // nil when no bot token is configured
+	TrackingClient service.TrackingClient // nil unless a carrier tracking API is plugged in
+	FetchClient    *http.Client           // fetches images and links of email bodies and posts webhooks; nil for one refusing private addresses
+	MailSender     service.MailSender     // SMTP when SMTP_HOST is set, otherwise each user's own Gmail
+
+	// Services
+	AuthService         service.AuthService
+	AccountMerge        service.AccountMergeService
+	CategoryService     service.CategoryService
+	EmailService        service.EmailService
+	UnsubscribeService  service.UnsubscribeService
+	RetentionService    service.RetentionService
+	AutomationService   service.AutomationService
+	NotificationService service.NotificationService
+	PushService         service.PushService
+	TelegramService     service.TelegramService
+	WebhookService      service.WebhookService
+	ShareService        service.ShareService
+	OrgService          service.OrganizationService
+	BillingService      service.BillingService
+	ViewService         service.SavedViewService
+	TriageService       service.TriageService
+	ShipmentService     service.ShipmentService
+	Recommendations     service.RecommendationService
+	Onboarding          service.OnboardingService
+	ReportService       service.ReportService
+	ImageProxy          service.ImageProxyService
+	LinkService         service.LinkService
+	CleanupService      service.CleanupService
+	AIDebug             service.AIDebugService
+	Experiments         service.ExperimentService
+	SelfCheck           service.SelfCheckService
+	Workspace           service.WorkspaceService // disabled unless GMAIL_AUTH is delegation
+
+	// Real-time updates and background jobs
+	SSEManager    *sse.SSEManager
+	BulkJobs      *sse.BulkJobQueue
+	EmailSyncJob  *sse.EmailSyncJob
+	CleanupJob    *sse.CleanupJob
+	AutomationJob *sse.AutomationJob
+	TelegramJob   *sse.TelegramBotJob
+	TrackingJob   *sse.ShipmentTrackingJob
+	ReplayJob     *sse.GmailReplayJob
+	ReportJob     *sse.ReportJob
+
+	// HTTP server with all routes registered
+	Echo *echo.Echo
+
+	jobs []Job
+}
+
+// Option customizes the container before components are built
+type Option func(*Container)
+
+// WithGmailClient replaces the per-user Gmail client, e.g. with a mock in tests
+func WithGmailClient(client service.MailClient) Option {
+	return func(c *Container) {
+		c.GmailClient = client
+	}
+}
+
+// WithAIClient replaces the AI client, e.g. with a mock in tests
+func WithAIClient(client service.AIClient) Option {
+	return func(c *Container) {
+		c.AIClient = client
+	}
+}
+
+// WithPushClient replaces the Web Push client, e.g. with a mock in tests
+func WithPushClient(client service.PushClient) Option {
+	return func(c *Container) {
+		c.PushClient = client
+	}
+}
+
+// WithTelegramClient replaces the Telegram Bot API client, e.g. with a mock in tests
+func WithTelegramClient(client service.TelegramClient) Option {
+	return func(c *Container) {
+		c.TelegramClient = client
+	}
+}
+
+// WithMailSender replaces how the app sends emails, e.g. with a fake in tests
+func WithMailSender(sender service.MailSender) Option {
+	return func(c *Container) {
+		c.MailSender = sender
+	}
+}
+
+// WithTrackingClient sets the client packages are tracked with; without one shipments are only
+// updated from the shipping notifications
+func WithTrackingClient(client service.TrackingClient) Option {
+	return func(c *Container) {
+		c.TrackingClient = client
+	}
+}
+
+// WithFetchClient replaces the HTTP client images and tracked links of email bodies are fetched
+// and webhooks posted with, e.g. to reach a test server on a private address
+func WithFetchClient(client *http.Client) Option {
+	return func(c *Container) {
+		c.FetchClient = client
+	}
+}
+
+// New builds the whole application from config; background jobs are not started until Start
+func New(cfg *config.Config, opts ...Option) (*Container, error) {
+	c := &Container{
+		Config:      cfg,
+		Logger:      logger.New(),
+		ConfigStore: config.NewStore(cfg, config.LoadConfig),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := c.initRepositories(); err != nil {
+		return nil, err
+	}
+
+	// Load default categories if none exist
+	loadDefaultCategories(c.CategoryRepo, c.categoriesJSON(), c.Logger)
+
+	c.initServices()
+	c.initJobs()
+	if err := c.initHTTP(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// initRepositories uses postgres when DATABASE_URL is set and in-memory storage otherwise
+func (c *Container) initRepositories() error {
+	if c.Config.DatabaseURL == "" {
+		orgRepo := memory.NewInMemoryOrganizationRepository()
+		categoryRepo := memory.NewInMemoryCategoryRepository()
+		categoryRepo.UseOrganizations(orgRepo)
+
+		c.UserRepo = memory.NewInMemoryUserRepository()
+		c.CategoryRepo = categoryRepo
+		c.EmailRepo = memory.NewInMemoryEmailRepository()
+		c.RetentionRepo = memory.NewInMemoryRetentionPolicyRepository()
+		c.AutomationRepo = memory.NewInMemoryAutomationRepository()
+		c.PreferenceRepo = memory.NewInMemoryNotificationPreferencesRepository()
+		c.PushRepo = memory.NewInMemoryPushSubscriptionRepository()
+		c.TelegramRepo = memory.NewInMemoryTelegramLinkRepository()
+		c.WebhookRepo = memory.NewInMemoryWebhookRepository()
+		c.ShareRepo = memory.NewInMemoryEmailShareRepository()
+		c.OrgRepo = orgRepo
+		c.InvitationRepo = memory.NewInMemoryInvitationRepository()
+		c.BillingRepo = memory.NewInMemoryBillingAccountRepository()
+		c.UsageRepo = memory.NewInMemoryUsageRepository()
+		c.ViewRepo = memory.NewInMemorySavedViewRepository()
+		c.ProfileRepo = memory.NewInMemorySenderProfileRepository()
+		c.ShipmentRepo = memory.NewInMemoryShipmentRepository()
+		c.VIPRepo = memory.NewInMemoryVIPSenderRepository()
+		c.ReportRepo = memory.NewInMemoryReportRepository()
+		c.UnsubscribeAttemptRepo = memory.NewInMemoryUnsubscribeAttemptRepository()
+		c.PendingEventRepo = memory.NewInMemoryPendingEventRepository()
+		c.GmailActionRepo = memory.NewInMemoryGmailActionRepository()
+		c.MergeCodeRepo = memory.NewInMemoryAccountMergeCodeRepository()
+		c.CleanupPolicyRepo = memory.NewInMemoryCleanupPolicyRepository()
+		c.CleanupRunRepo = memory.NewInMemoryCleanupRunRepository()
+		c.AICallRepo = memory.NewInMemoryAICallRepository()
+		c.ExperimentRepo = memory.NewInMemoryExperimentResultRepository()
+
+		c.Logger.Info("Using in-memory repositories")
+		return nil
+	}
+
+	db, err := sql.Open("postgres", c.Config.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Initialize database tables
+	if err := postgres.InitializeDatabase(db); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	c.DB = db
+	emailRepo := postgres.NewPostgresEmailRepository(db)
+	if c.Config.DatabaseReplicaURL != "" {
+		replica, err := sql.Open("postgres", c.Config.DatabaseReplicaURL)
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("failed to connect to database replica: %w", err)
+		}
+		// An unreachable replica isn't fatal, its reads go to the primary until it's back
+		if err := replica.Ping(); err != nil {
+			c.Logger.Warn("Database replica unavailable, reading from the primary:", err)
+		}
+		c.ReplicaDB = replica
+		emailRepo.UseReplica(replica)
+	}
+	c.UserRepo = postgres.NewPostgresUserRepository(db)
+	c.CategoryRepo = postgres.NewPostgresCategoryRepository(db)
+	c.EmailRepo = emailRepo
+	c.RetentionRepo = postgres.NewPostgresRetentionPolicyRepository(db)
+	c.AutomationRepo = postgres.NewPostgresAutomationRepository(db)
+	c.PreferenceRepo = postgres.NewPostgresNotificationPreferencesRepository(db)
+	c.PushRepo = postgres.NewPostgresPushSubscriptionRepository(db)
+	c.TelegramRepo = postgres.NewPostgresTelegramLinkRepository(db)
+	c.WebhookRepo = postgres.NewPostgresWebhookRepository(db)
+	c.ShareRepo = postgres.NewPostgresEmailShareRepository(db)
+	c.OrgRepo = postgres.NewPostgresOrganizationRepository(db)
+	c.InvitationRepo = postgres.NewPostgresInvitationRepository(db)
+	c.BillingRepo = postgres.NewPostgresBillingAccountRepository(db)
+	c.UsageRepo = postgres.NewPostgresUsageRepository(db)
+	c.ViewRepo = postgres.NewPostgresSavedViewRepository(db)
+	c.ProfileRepo = postgres.NewPostgresSenderProfileRepository(db)
+	c.ShipmentRepo = postgres.NewPostgresShipmentRepository(db)
+	c.VIPRepo = postgres.NewPostgresVIPSenderRepository(db)
+	c.ReportRepo = postgres.NewPostgresReportRepository(db)
+	c.UnsubscribeAttemptRepo = postgres.NewPostgresUnsubscribeAttemptRepository(db)
+	c.PendingEventRepo = postgres.NewPostgresPendingEventRepository(db)
+	c.GmailActionRepo = postgres.NewPostgresGmailActionRepository(db)
+	c.MergeCodeRepo = postgres.NewPostgresAccountMergeCodeRepository(db)
+	c.CleanupPolicyRepo = postgres.NewPostgresCleanupPolicyRepository(db)
+	c.CleanupRunRepo = postgres.NewPostgresCleanupRunRepository(db)
+	c.AICallRepo = postgres.NewPostgresAICallRepository(db)
+	c.ExperimentRepo = postgres.NewPostgresExperimentResultRepository(db)
+
+	c.Logger.Info("Using PostgreSQL repositories")
+	return nil
+}
+
+func (c *Container) initServices() {
+	primaryAI := c.AIClient
+	if c.AIClient == nil {
+		// Requests the primary provider can't serve, when rate limited or down, go to the fallback,
+		// and when no provider can, or no API key is configured, to the local model
+		aiClient := ai.NewFailoverClient(c.Config.AIFailoverCooldown, c.Logger)
+		primary := ai.NewAIClient(c.Config.AIProvider, c.Config.AIKey, c.Config.AIModel, c.Logger)
+		aiClient.AddProvider("primary", primary)
+		primaryAI = primary
+		var fallback ai.Client
+		if c.Config.AIFallbackProvider != "" {
+			fallback = ai.NewAIClient(c.Config.AIFallbackProvider, c.Config.AIFallbackKey, c.Config.AIFallbackModel, c.Logger)
+			aiClient.AddProvider("fallback", fallback)
+		}
+		if c.Config.AIDebug {
+			c.Logger.Warn("AI debug mode is on, prompts and answers are recorded")
+			primary.UseDebugStore(c.AICallRepo)
+			if fallback != nil {
+				fallback.UseDebugStore(c.AICallRepo)
+			}
+		}
+		c.LocalAI = ai.NewLocalClient(c.Logger)
+		aiClient.AddProvider("local", c.LocalAI)
+		if c.Config.AIKey == "" {
+			c.Logger.Warn("No AI_API_KEY configured, emails are classified by the local model")
+		}
+		c.ConfigStore.Subscribe(func(cfg *config.Config) {
+			primary.Reconfigure(cfg.AIProvider, cfg.AIKey, cfg.AIModel)
+			if fallback != nil && cfg.AIFallbackProvider != "" {
+				fallback.Reconfigure(cfg.AIFallbackProvider, cfg.AIFallbackKey, cfg.AIFallbackModel)
+			}
+			aiClient.SetCooldown(cfg.AIFailoverCooldown)
+		})
+		c.AIClient = aiClient
+	}
+	var workspaceDomain, workspaceClientID string
+	if c.Config.GmailAuth == config.GmailAuthDelegation {
+		workspaceDomain = c.Config.WorkspaceDomain
+	}
+	var userClient *gmail.UserClient
+	if c.GmailClient == nil {
+		// Gmail client that looks up user-specific access tokens
+		userClient = gmail.NewUserClient(c.UserRepo, c.Config.MaxFetchEmails, c.Logger)
+		if c.Config.GoogleClientID != "" {
+			userClient.UseTokenRefresh(gmail.NewTokenRefresher(c.Config.GoogleClientID, c.Config.GoogleClientSecret, googleTokenURL))
+		}
+		c.GmailClient = userClient
+
+		// The Workspace domain's mailboxes are called as their users with the service account
+		if workspaceDomain != "" {
+			delegated, err := c.delegatedClient(userClient)
+			if err != nil {
+				c.Logger.Error("Workspace delegation disabled:", err)
+				workspaceDomain = ""
+			} else {
+				workspaceClientID = delegated.ClientID()
+				c.GmailClient = delegated
+			}
+		}
+	}
+	c.GmailMonitor = service.NewGmailMonitor(c.GmailClient, c.Config.GmailBreakerFailures, c.Config.GmailBreakerCooldown, c.Logger)
+	c.GmailClient = c.GmailMonitor
+	c.ConfigStore.Subscribe(func(cfg *config.Config) {
+		c.GmailMonitor.SetBreaker(cfg.GmailBreakerFailures, cfg.GmailBreakerCooldown)
+	})
+	if c.PushClient == nil && c.Config.VAPIDPrivateKey != "" {
+		pushClient, err := push.NewPushClient(c.Config.VAPIDPublicKey, c.Config.VAPIDPrivateKey, c.Config.VAPIDSubject, c.Logger)
+		if err != nil {
+			c.Logger.Warn("Push notifications disabled:", err)
+		} else {
+			c.PushClient = pushClient
+		}
+	}
+	if c.TelegramClient == nil && c.Config.TelegramBotToken != "" {
+		c.TelegramClient = telegram.NewTelegramClient(c.Config.TelegramBotToken, c.Logger)
+	}
+	if c.MailSender == nil {
+		if c.Config.SMTPHost != "" {
+			c.MailSender = mailer.NewSMTPSender(c.Config.SMTPHost, c.Config.SMTPPort, c.Config.SMTPUsername, c.Config.SMTPPassword, c.Config.SMTPFrom)
+		} else {
+			c.MailSender = mailer.NewGmailSender(c.GmailClient)
+		}
+	}
+
+	var billingProviders []service.BillingProvider
+	if c.Config.StripeWebhookKey != "" {
+		billingProviders = append(billingProviders, billing.NewStripeProvider(c.Config.StripeWebhookKey))
+	}
+	c.BillingService = service.NewBillingService(c.BillingRepo, c.UsageRepo, c.Config.DefaultPlan, c.Logger, billingProviders...)
+
+	c.AuthService = service.NewAuthService(c.UserRepo, c.Logger)
+	c.AuthService.UseWorkspaceDomain(workspaceDomain)
+	c.Workspace = service.NewWorkspaceService(c.UserRepo, c.GmailClient, workspaceDomain, workspaceClientID, c.Logger)
+	c.AccountMerge = service.NewAccountMergeService(c.MergeCodeRepo, c.UserRepo, c.CategoryRepo, c.EmailRepo, c.OrgRepo, c.AutomationRepo,
+		c.CleanupPolicyRepo, c.ViewRepo, c.VIPRepo, c.RetentionRepo, c.PreferenceRepo, c.Logger)
+	if userClient != nil {
+		c.AuthService.OnTokenRefresh(func(ctx context.Context, user *model.User) {
+			userClient.Invalidate(user.ID)
+		})
+	}
+	// Users whose grant Google refused must sign in again, which gives their calls a fresh start
+	c.GmailMonitor.OnReauthRequired(c.AuthService.MarkNeedsReauth)
+	c.AuthService.OnTokenRefresh(func(ctx context.Context, user *model.User) {
+		c.GmailMonitor.Reset(user.Email)
+	})
+	c.CategoryService = service.NewCategoryService(c.CategoryRepo, c.OrgRepo, c.Logger)
+	c.EmailService = service.NewEmailService(c.EmailRepo, c.CategoryRepo, c.UserRepo, c.GmailClient, c.AIClient, c.Logger)
+	c.EmailService.SetMaxBodyBytes(c.Config.MaxEmailBodyBytes)
+	c.EmailService.SetAIInputLimits(c.Config.AIChunkTokens, c.Config.AIMaxChunks)
+	c.EmailService.UseSenderProfiles(c.ProfileRepo)
+	c.EmailService.UseVIPSenders(c.VIPRepo)
+	c.EmailService.UseGmailQueue(c.GmailMonitor, c.GmailActionRepo)
+	c.UnsubscribeService = service.NewUnsubscribeService(c.EmailRepo, c.UserRepo, c.GmailClient, c.AIClient, c.Logger)
+	c.RetentionService = service.NewRetentionService(c.RetentionRepo, c.EmailRepo, c.Logger)
+	c.NotificationService = service.NewNotificationService(c.PreferenceRepo, c.Logger)
+	c.NotificationService.UseUserTimeZones(c.UserRepo)
+	c.AutomationService = service.NewAutomationService(c.AutomationRepo, c.CategoryRepo, c.EmailRepo, c.OrgRepo, c.EmailService, c.Logger)
+	c.OrgService = service.NewOrganizationService(c.OrgRepo, c.InvitationRepo, c.UserRepo, c.CategoryRepo, c.AutomationRepo, c.EmailRepo, c.Logger)
+	c.TriageService = service.NewTriageService(c.EmailRepo, c.EmailService, c.AutomationService, c.Logger)
+	c.ViewService = service.NewSavedViewService(c.ViewRepo, c.CategoryRepo, c.EmailService, c.Logger)
+	c.ShareService = service.NewShareService(c.ShareRepo, c.EmailRepo, c.CategoryRepo, c.Config.SessionSecret, c.Config.BaseURL, c.Logger)
+	c.PushService = service.NewPushService(c.PushRepo, c.PushClient, c.NotificationService, c.Logger)
+	c.TelegramService = service.NewTelegramService(c.TelegramRepo, c.TelegramClient, c.Config.TelegramBotUser,
+		c.EmailService, c.CategoryService, c.UnsubscribeService, c.NotificationService, c.Logger)
+	c.WebhookService = service.NewWebhookService(c.WebhookRepo, c.FetchClient, c.NotificationService, c.Logger)
+	c.ShipmentService = service.NewShipmentService(c.ShipmentRepo, c.AIClient, c.TrackingClient, c.Logger)
+	c.Recommendations = service.NewRecommendationService(c.EmailRepo, c.CategoryRepo, c.Logger)
+	c.Onboarding = service.NewOnboardingService(c.UserRepo, c.CategoryService, c.EmailService, c.Recommendations, c.Config.OnboardingBackfill, c.Logger)
+	c.ReportService = service.NewReportService(c.ReportRepo, c.EmailRepo, c.CategoryRepo, c.UserRepo, c.Logger)
+	if c.Config.ReportEmails {
+		c.ReportService.UseMailSender(c.MailSender, c.Config.BaseURL+"/app")
+	}
+	c.ImageProxy = service.NewImageProxyService(c.FetchClient, c.Logger)
+	c.LinkService = service.NewLinkService(c.FetchClient, c.Logger)
+	c.CleanupService = service.NewCleanupService(c.CleanupPolicyRepo, c.CleanupRunRepo, c.CategoryRepo, c.EmailRepo, c.UserRepo, c.EmailService, c.Logger)
+	c.AIDebug = ai.NewDebugService(c.AICallRepo, c.aiKey, c.Logger)
+	c.Experiments = service.NewExperimentService(c.ExperimentRepo, c.Logger)
+	c.startExperiment()
+	c.SelfCheck = NewSelfCheck(c.Config, c.DB, c.ReplicaDB, primaryAI, c.Logger)
+
+	c.EmailService.UseQuotas(c.BillingService)
+	c.EmailService.UseExperiment(c.Experiments)
+	c.EmailService.OnCategorized(c.Experiments.RecordCorrection)
+	if c.LocalAI != nil {
+		// The local model learns from the emails users file themselves
+		c.EmailService.OnCategorized(func(ctx context.Context, email *model.Email) {
+			c.LocalAI.Learn(service.PlainText(email.Body), email.CategoryID)
+		})
+	}
+	c.EmailService.SetSummaryBudgetReserve(c.Config.SummaryBudgetReserve)
+	c.EmailService.SetReviewThreshold(c.Config.ReviewConfidence)
+	c.CategoryService.UseEmailCounts(c.EmailService)
+	c.UnsubscribeService.UseQuotas(c.BillingService)
+	c.UnsubscribeService.UseAttempts(c.UnsubscribeAttemptRepo)
+	c.UnsubscribeService.UseSpamReports(c.EmailService)
+	c.UnsubscribeService.SetGracePeriod(c.Config.UnsubscribeGrace)
+	c.UnsubscribeService.SetCrawlLimits(service.CrawlLimits{
+		HostConcurrency: c.Config.UnsubscribeHostConcurrency,
+		HostInterval:    c.Config.UnsubscribeHostInterval,
+		Budget:          c.Config.UnsubscribeBudget,
+	})
+
+	// Immediate automations run on every email a sync classifies, then important ones are pushed
+	c.EmailService.OnClassified(c.AutomationService.ApplyToNewEmail)
+	c.EmailService.OnClassified(c.PushService.NotifyNewEmail)
+	c.EmailService.OnClassified(c.TelegramService.NotifyNewEmail)
+	c.EmailService.OnClassified(c.WebhookService.NotifyNewEmail)
+	c.ShipmentService.UseSensitiveCheck(c.EmailService.IsSensitive)
+	c.EmailService.OnClassified(c.ShipmentService.DetectShipment)
+	c.EmailService.OnClassified(c.UnsubscribeService.CheckEffectiveness)
+}
+
+// delegatedClient calls Gmail for the users of the Workspace domain with the service account,
+// and for the others with fallback
+func (c *Container) delegatedClient(fallback service.MailClient) (*gmail.DelegatedClient, error) {
+	key, err := os.ReadFile(c.Config.GoogleServiceAccount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account key: %w", err)
+	}
+	return gmail.NewDelegatedClient(key, c.Config.WorkspaceDomain, c.Config.MaxFetchEmails, fallback, c.Logger)
+}
+
+// aiKey returns the configured API key of the primary or fallback AI provider, for replaying
+// debug calls against them
+func (c *Container) aiKey(provider string) string {
+	cfg := c.ConfigStore.Get()
+	switch {
+	case provider == cfg.AIProvider:
+		return cfg.AIKey
+	case provider == cfg.AIFallbackProvider:
+		return cfg.AIFallbackKey
+	}
+	return ""
+}
+
+// startExperiment routes AI_EXPERIMENT_PERCENT of the classifications to the experiment's
+// provider, model and prompt; the control is whatever classifies the other emails
+func (c *Container) startExperiment() {
+	cfg := c.Config
+	if cfg.AIExperimentPercent == 0 {
+		return
+	}
+
+	provider := cfg.AIExperimentProvider
+	if provider == "" {
+		provider = cfg.AIProvider
+	}
+	apiKey := cfg.AIExperimentKey
+	if apiKey == "" {
+		apiKey = c.aiKey(provider)
+	}
+	prompt := cfg.AIExperimentPrompt
+	if prompt == "" {
+		prompt = ai.DefaultClassifyPrompt
+	}
+	client := ai.NewAIClient(provider, apiKey, cfg.AIExperimentModel, c.Logger)
+	if err := client.UseClassifyPrompt(prompt); err != nil {
+		c.Logger.Warn("Classification experiment not started:", err)
+		return
+	}
+	if cfg.AIDebug {
+		client.UseDebugStore(c.AICallRepo)
+	}
+
+	control := model.ExperimentArm{Provider: cfg.AIProvider, Model: cfg.AIModel, Prompt: ai.DefaultClassifyPrompt}
+	treatment := model.ExperimentArm{Provider: provider, Model: cfg.AIExperimentModel, Prompt: prompt}
+	c.Experiments.StartExperiment(cfg.AIExperimentName, cfg.AIExperimentPercent, control, treatment, client)
+}
+
+func (c *Container) initJobs() {
+	c.SSEManager = sse.NewSSEManager(c.Logger)
+	c.SSEManager.UseNotificationPreferences(c.NotificationService)
+	if c.Config.SSEQueueSize > 0 {
+		c.SSEManager.UseOfflineQueue(c.PendingEventRepo, c.Config.SSEQueueSize, c.Config.SSEQueueTTL)
+	}
+	c.AuthService.OnNeedsReauth(func(ctx context.Context, user *model.User) {
+		c.SSEManager.Publish(user.ID, sse.ReauthRequired{
+			Message: "Google access was revoked. Sign in again to keep syncing your emails.",
+			URL:     handler.SignInAgainURL(user),
+		})
+	})
+	c.AuthService.OnNeedsReauth(c.PushService.NotifyReauthRequired)
+	c.BulkJobs = sse.NewBulkJobQueue(c.EmailService, c.UnsubscribeService, c.SSEManager, c.Config.BulkBatchSize, c.Logger)
+	c.BulkJobs.UsePush(c.PushService)
+	c.EmailSyncJob = sse.NewEmailSyncJob(c.EmailService, c.UserRepo, c.SSEManager, c.Config.SyncInterval, c.Config.MaxFetchEmails, c.Logger)
+	c.EmailSyncJob.AddOfflineChannel(c.PushService.HasSubscriptions)
+	c.EmailSyncJob.AddOfflineChannel(c.TelegramService.IsLinked)
+	c.EmailSyncJob.AddOfflineChannel(c.WebhookService.HasWebhook)
+	c.EmailSyncJob.AddOfflineChannel(c.SSEManager.AwaitsReplay)
+	if c.Config.GmailAuth == config.GmailAuthDelegation {
+		// Designated mailboxes are synced for the company whether or not anyone watches them
+		c.EmailSyncJob.SyncAlways(func(user *model.User) bool {
+			return user.WorkspaceMailbox
+		})
+	}
+	c.ConfigStore.Subscribe(func(cfg *config.Config) {
+		c.EmailSyncJob.Reconfigure(cfg.SyncInterval, cfg.MaxFetchEmails)
+	})
+	c.CleanupJob = sse.NewCleanupJob(c.EmailService, c.RetentionService, c.Config.TrashRetention, c.Config.OTPRetention, c.Logger)
+	c.CleanupJob.UseAIDebug(c.AIDebug, c.Config.AIDebugRetention)
+	c.AutomationJob = sse.NewAutomationJob(c.AutomationService, c.Config.AutomationInterval, c.Logger)
+	c.AutomationJob.UseCleanupPolicies(c.CleanupService)
+	c.TelegramJob = sse.NewTelegramBotJob(c.TelegramClient, c.TelegramService, c.Logger)
+	c.TrackingJob = sse.NewShipmentTrackingJob(c.TrackingClient, c.ShipmentService, c.Config.TrackingInterval, c.Logger)
+	c.ReportJob = sse.NewReportJob(c.ReportService, c.Logger)
+	c.ReplayJob = sse.NewGmailReplayJob(c.EmailService, c.Config.GmailReplayInterval, c.Logger)
+
+	// Deliveries are pushed whether a notice or the carrier reported them
+	c.ShipmentService.OnDelivered(func(ctx context.Context, shipment *model.Shipment) {
+		c.SSEManager.Publish(shipment.UserID, sse.ShipmentDelivered{Shipment: shipment})
+	})
+	c.UnsubscribeService.OnIneffective(func(ctx context.Context, attempt *model.UnsubscribeAttempt) {
+		c.SSEManager.Publish(attempt.UserID, sse.UnsubscribeIneffective(attempt))
+	})
+	c.UnsubscribeService.OnManualAction(func(ctx context.Context, attempt *model.UnsubscribeAttempt) {
+		c.SSEManager.Publish(attempt.UserID, sse.UnsubscribeManualAction(attempt))
+	})
+	c.CleanupService.OnCleaned(func(ctx context.Context, run *model.CleanupRun) {
+		c.SSEManager.Publish(run.UserID, sse.CleanupCompleted{CleanupRun: run})
+	})
+
+	c.jobs = []Job{c.EmailSyncJob, c.CleanupJob, c.AutomationJob, c.TelegramJob, c.TrackingJob, c.ReportJob, c.ReplayJob, c.BulkJobs}
+}
+
+func (c *Container) initHTTP() error {
+	assets, err := static.NewAssets(c.staticFS())
+	if err != nil {
+		return fmt.Errorf("failed to read static assets: %w", err)
+	}
+	// Pages and partials are parsed up front so a broken template override fails at startup
+	renderer, err := view.New(c.templatesFS(), assets)
+	if err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	e := echo.New()
+	e.HideBanner = true
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	e.Validator = validation.New()
+	e.Renderer = renderer
+
+	// Middleware
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+	e.Use(middleware.CORS())
+
+	authHandler := handler.NewAuthHandler(c.AuthService, c.Config, e.Logger)
+	authHandler.UseGmailHealth(c.EmailService)
+	mergeHandler := handler.NewAccountMergeHandler(c.AccountMerge, authHandler, e.Logger)
+	categoryHandler := handler.NewCategoryHandler(c.CategoryService, authHandler, e.Logger)
+	emailHandler := handler.NewEmailHandler(c.EmailService, c.CategoryService, authHandler, c.SSEManager, c.BulkJobs, c.ImageProxy, c.LinkService, e.Logger)
+	unsubscribeHandler := handler.NewUnsubscribeHandler(c.UnsubscribeService, authHandler, c.BulkJobs, e.Logger)
+	retentionHandler := handler.NewRetentionHandler(c.RetentionService, authHandler, e.Logger)
+	automationHandler := handler.NewAutomationHandler(c.AutomationService, authHandler, e.Logger)
+	notificationHandler := handler.NewNotificationHandler(c.NotificationService, authHandler, e.Logger)
+	pushHandler := handler.NewPushHandler(c.PushService, authHandler, e.Logger)
+	telegramHandler := handler.NewTelegramHandler(c.TelegramService, authHandler, e.Logger)
+	webhookHandler := handler.NewWebhookHandler(c.WebhookService, authHandler, e.Logger)
+	shareHandler := handler.NewShareHandler(c.ShareService, authHandler, e.Logger)
+	orgHandler := handler.NewOrganizationHandler(c.OrgService, authHandler, e.Logger)
+	billingHandler := handler.NewBillingHandler(c.BillingService, authHandler, e.Logger)
+	viewHandler := handler.NewSavedViewHandler(c.ViewService, authHandler, e.Logger)
+	triageHandler := handler.NewTriageHandler(c.TriageService, authHandler, e.Logger)
+	shipmentHandler := handler.NewShipmentHandler(c.ShipmentService, authHandler, e.Logger)
+	reportHandler := handler.NewReportHandler(c.ReportService, authHandler, e.Logger)
+	imageProxyHandler := handler.NewImageProxyHandler(c.ImageProxy, authHandler, e.Logger)
+	recommendationHandler := handler.NewRecommendationHandler(c.Recommendations, authHandler, c.BulkJobs, e.Logger)
+	onboardingHandler := handler.NewOnboardingHandler(c.Onboarding, c.Recommendations, authHandler, c.BulkJobs, e.Logger)
+	cleanupHandler := handler.NewCleanupHandler(c.CleanupService, authHandler, e.Logger)
+	aiDebugHandler := handler.NewAIDebugHandler(c.AIDebug, c.Experiments, authHandler, e.Logger)
+	selfCheckHandler := handler.NewSelfCheckHandler(c.SelfCheck, authHandler, e.Logger)
+	workspaceHandler := handler.NewWorkspaceHandler(c.Workspace, authHandler, e.Logger)
+	pageHandler := handler.NewPageHandler(c.CategoryService, authHandler, map[string]bool{
+		"push":     c.PushClient != nil,
+		"telegram": c.TelegramClient != nil,
+	}, e.Logger)
+
+	// Pages and static files are embedded in the binary
+	err = router.SetupRoutes(e, authHandler, mergeHandler, categoryHandler, emailHandler, unsubscribeHandler, retentionHandler, automationHandler, notificationHandler, pushHandler, telegramHandler, webhookHandler, shareHandler, orgHandler, billingHandler, viewHandler, triageHandler, shipmentHandler, reportHandler, imageProxyHandler, recommendationHandler, onboardingHandler, cleanupHandler, aiDebugHandler, selfCheckHandler, workspaceHandler, pageHandler, c.templatesFS(), assets)
+	if err != nil {
+		return fmt.Errorf("failed to set up routes: %w", err)
+	}
+
+	c.Echo = e
+	return nil
+}
+
+// Start launches every background job in its own goroutine
+func (c *Container) Start() {
+	for _, job := range c.jobs {
+		go job.Start()
+	}
+}
+
+// Serve starts background jobs and blocks serving HTTP until the server is shut down
+func (c *Container) Serve() error {
+	c.Start()
+
+	c.Logger.Info("Starting server on port", c.Config.Port)
+	if err := c.Echo.Start(":" + c.Config.Port); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	return nil
+}
+
+// ReloadConfig re-reads the environment and .env file and applies what can change at runtime;
+// other settings, such as the port or database, still need a restart
+func (c *Container) ReloadConfig() error {
+	cfg, err := c.ConfigStore.Reload()
+	if err != nil {
+		return err
+	}
+	c.Logger.Info("Configuration reloaded, email sync interval:", cfg.SyncInterval.String(), "AI provider:", cfg.AIProvider)
+	return nil
+}
+
+// Stop shuts down the HTTP server, background jobs, SSE connections and the database
+func (c *Container) Stop(ctx context.Context) error {
+	err := c.Echo.Shutdown(ctx)
+
+	for _, job := range c.jobs {
+		job.Stop()
+	}
+	c.SSEManager.Close()
+
+	if c.DB != nil {
+		if dbErr := c.DB.Close(); dbErr != nil && err == nil {
+			err = dbErr
+		}
+	}
+	if c.ReplicaDB != nil {
+		if dbErr := c.ReplicaDB.Close(); dbErr != nil && err == nil {
+			err = dbErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// Category represents a category from the JSON file
+type CategoryJSON struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// loadDefaultCategories loads the default categories if none exist for the "default" user
+func loadDefaultCategories(categoryRepo repository.CategoryRepository, data []byte, logger *logger.Logger) {
+	ctx := context.Background()
+
+	// Try to find existing categories
+	categories, err := categoryRepo.FindAll(ctx)
+	if err != nil {
+		// If there's an error, we might not have any categories yet
+		logger.Info("Error checking for existing categories:", err.Error())
+	}
+
+	// If we already have categories, don't load again
+	if len(categories) > 0 {
+		logger.Info("Categories already exist, skipping loading")
+		return
+	}
+
+	// Parse the JSON
+	var categoriesJSON []CategoryJSON
+	if err := json.Unmarshal(data, &categoriesJSON); err != nil {
+		logger.Error("Failed to parse categories.json:", err)
+		return
+	}
+
+	// Create default categories
+	logger.Info("Loading", len(categoriesJSON), "default categories")
+
+	for _, cat := range categoriesJSON {
+		// Create a new category model with the default user ID
+		category := model.NewCategory(cat.Name, cat.Description)
+
+		// Add to repository
+		if err := categoryRepo.Create(ctx, category); err != nil {
+			logger.Error("Failed to create default category:", cat.Name, err)
+		} else {
+			logger.Info("Created default category:", cat.Name)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/mailer"
+	"jump-challenge/internal/repository/postgres"
+	"jump-challenge/internal/service"
+)
+
+// googleTokenURL is where the self check tries the Google OAuth client's credentials
+const googleTokenURL = "https://oauth2.googleapis.com/token"
+
+// NewSelfCheck checks the configuration, the Google OAuth client, a completion by the AI
+// provider, the database and the SMTP server. aiClient is the primary provider's client,
+// without the fallbacks that would hide its errors; db and replica are nil when not configured.
+func NewSelfCheck(cfg *config.Config, db, replica *sql.DB, aiClient service.AIClient, logger *logger.Logger) service.SelfCheckService {
+	checks := service.NewSelfCheckService(logger)
+
+	checks.AddCheck("config", func(ctx context.Context) (string, error) {
+		if err := cfg.Validate(); err != nil {
+			return "", err
+		}
+		return "the required settings are present", nil
+	})
+
+	checks.AddCheck("google_oauth", service.GoogleOAuthCheck(cfg.GoogleClientID, cfg.GoogleClientSecret,
+		cfg.BaseURL+"/auth/google/callback", googleTokenURL, &http.Client{Timeout: 10 * time.Second}))
+
+	if cfg.AIKey == "" {
+		checks.AddCheck("ai", func(ctx context.Context) (string, error) {
+			return "no AI_API_KEY, emails are classified by the local model", nil
+		})
+	} else {
+		checks.AddCheck("ai", service.AICheck(aiClient))
+	}
+
+	checks.AddCheck("database", func(ctx context.Context) (string, error) {
+		if db == nil {
+			return "no DATABASE_URL, data is kept in memory and lost on restart", nil
+		}
+		err := postgres.CheckSchema(ctx, db)
+		if errors.Is(err, postgres.ErrSchemaOutdated) {
+			return "", fmt.Errorf("%w, run `go run . migrate`", err)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w, check DATABASE_URL", err)
+		}
+		return "connected and migrated", nil
+	})
+	if replica != nil {
+		checks.AddCheck("database_replica", func(ctx context.Context) (string, error) {
+			if err := replica.PingContext(ctx); err != nil {
+				return "", fmt.Errorf("failed to connect to the replica, reads go to the primary meanwhile; check DATABASE_REPLICA_URL: %w", err)
+			}
+			return "connected", nil
+		})
+	}
+
+	if cfg.SMTPHost != "" {
+		sender := mailer.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		checks.AddCheck("smtp", func(ctx context.Context) (string, error) {
+			if err := sender.Check(ctx); err != nil {
+				return "", fmt.Errorf("%w, check SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD", err)
+			}
+			return "connected and authenticated", nil
+		})
+	}
+
+	return checks
+}
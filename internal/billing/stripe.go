@@ -0,0 +1,119 @@
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+)
+
+// signatureTolerance is how old a signed webhook may be, guarding against replays
+const signatureTolerance = 5 * time.Minute
+
+var errInvalidSignature = apierror.Validation("invalid webhook signature")
+
+// stripeProvider is a minimal Stripe webhook handler. It verifies signatures and reads the
+// plan from the subscription's metadata (user_id and plan), which the checkout flow sets;
+// mapping Stripe prices to plans is left to the deployment.
+type stripeProvider struct {
+	webhookSecret string
+}
+
+func NewStripeProvider(webhookSecret string) service.BillingProvider {
+	return &stripeProvider{webhookSecret: webhookSecret}
+}
+
+func (p *stripeProvider) Name() string {
+	return "stripe"
+}
+
+// Stripe event structures, limited to the fields plans depend on
+type stripeEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Object stripeSubscription `json:"object"`
+	} `json:"data"`
+}
+
+type stripeSubscription struct {
+	Customer string            `json:"customer"`
+	Status   string            `json:"status"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+func (p *stripeProvider) ParseWebhook(payload []byte, header http.Header) (*model.BillingEvent, error) {
+	if err := p.verify(payload, header.Get("Stripe-Signature")); err != nil {
+		return nil, err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, apierror.Validation("invalid webhook payload")
+	}
+
+	subscription := event.Data.Object
+	billingEvent := &model.BillingEvent{
+		UserID:     subscription.Metadata["user_id"],
+		CustomerID: subscription.Customer,
+		Plan:       subscription.Metadata["plan"],
+		Status:     subscription.Status,
+	}
+
+	switch event.Type {
+	case "customer.subscription.created", "customer.subscription.updated":
+		// Past due subscriptions keep their plan while Stripe retries the payment
+		switch subscription.Status {
+		case "active", "trialing", "past_due":
+		default:
+			billingEvent.Plan = ""
+		}
+	case "customer.subscription.deleted":
+		billingEvent.Plan = ""
+	default:
+		return nil, nil
+	}
+	return billingEvent, nil
+}
+
+// verify checks a Stripe-Signature header of the form t=<unix>,v1=<hex hmac>[,v1=...]
+func (p *stripeProvider) verify(payload []byte, signatureHeader string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return errInvalidSignature
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return errInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return errInvalidSignature
+}
@@ -0,0 +1,417 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/jsonstream"
+	"jump-challenge/internal/loadtest"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/push"
+	"jump-challenge/internal/repository/postgres"
+	"jump-challenge/internal/service"
+
+	_ "github.com/lib/pq"
+)
+
+// command is a single CLI subcommand
+type command struct {
+	name  string
+	usage string
+	run   func(cfg *config.Config, args []string, out io.Writer) error
+}
+
+var commands = []command{
+	{"serve", "serve                         run the web server and background jobs (default)", runServe},
+	{"migrate", "migrate                       create or upgrade the database schema", runMigrate},
+	{"check", "check                         check the configuration, Google OAuth client, AI provider and database (also --check)", runCheck},
+	{"sync", "sync --user <id|email> [--max N]   fetch and process new emails for a user", runSync},
+	{"reclassify", "reclassify --category <id>    re-run AI classification for a category", runReclassify},
+	{"export", "export --user <id|email> [--out file]   write a user's emails as JSON", runExport},
+	{"prune", "prune                         purge expired trash and enforce retention policies", runPrune},
+	{"replay-ai", "replay-ai --call <id> [--provider p] [--model m]   replay a prompt recorded in AI debug mode", runReplayAI},
+	{"vapid-keys", "vapid-keys                    generate a key pair for Web Push notifications", runVAPIDKeys},
+	{"loadtest", "loadtest [--emails N] [--batch N] [--ai-latency D]   sync synthetic emails and report throughput", runLoadTest},
+}
+
+// Run dispatches args to a subcommand and returns the process exit code
+func Run(args []string, out, errOut io.Writer) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(out)
+		return 0
+	}
+	if name == "--check" {
+		name = "check"
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Fprintln(errOut, "Failed to load config:", err)
+			return 1
+		}
+
+		if err := cmd.run(cfg, args, out); err != nil {
+			fmt.Fprintf(errOut, "%s: %v\n", name, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(errOut, "unknown command: %s\n\n", name)
+	printUsage(errOut)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: app <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintln(w, "  "+cmd.usage)
+	}
+}
+
+func runServe(cfg *config.Config, args []string, out io.Writer) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+
+	container, err := app.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+
+	// Shut down gracefully on SIGINT/SIGTERM
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := container.Stop(ctx); err != nil {
+			container.Logger.Error("Failed to shut down cleanly:", err)
+		}
+	}()
+
+	// Reload the configuration on SIGHUP
+	go func() {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		for range reload {
+			if err := container.ReloadConfig(); err != nil {
+				container.Logger.Error("Failed to reload configuration:", err)
+			}
+		}
+	}()
+
+	if err := container.Serve(); err != nil {
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+func runMigrate(cfg *config.Config, args []string, out io.Writer) error {
+	if cfg.DatabaseURL == "" {
+		return errors.New("DATABASE_URL is required")
+	}
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := postgres.InitializeDatabase(db); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Database schema is up to date")
+	return nil
+}
+
+// runCheck runs the self check without starting the app, so that bad credentials show before
+// users run into them. The database isn't migrated, so a schema behind this build is reported.
+func runCheck(cfg *config.Config, args []string, out io.Writer) error {
+	log := logger.NewWithWriter(io.Discard)
+	var db, replica *sql.DB
+	if cfg.DatabaseURL != "" {
+		var err error
+		if db, err = sql.Open("postgres", cfg.DatabaseURL); err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer db.Close()
+	}
+	if cfg.DatabaseReplicaURL != "" {
+		var err error
+		if replica, err = sql.Open("postgres", cfg.DatabaseReplicaURL); err != nil {
+			return fmt.Errorf("failed to connect to database replica: %w", err)
+		}
+		defer replica.Close()
+	}
+
+	aiClient := ai.NewAIClient(cfg.AIProvider, cfg.AIKey, cfg.AIModel, log)
+	report := app.NewSelfCheck(cfg, db, replica, aiClient, log).Run(context.Background())
+
+	failed := 0
+	for _, check := range report.Checks {
+		mark := "ok"
+		if !check.OK {
+			mark = "FAILED"
+			failed++
+		}
+		fmt.Fprintf(out, "%-17s %-6s %s (%dms)\n", check.Name, mark, check.Message, check.DurationMS)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+	}
+	return nil
+}
+
+func runSync(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	userRef := flags.String("user", "", "user ID or email address")
+	maxResults := flags.Int64("max", 0, "maximum emails to fetch (default MAX_FETCH_EMAILS)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *userRef == "" {
+		return errors.New("--user is required")
+	}
+
+	return withContainer(cfg, func(ctx context.Context, container *app.Container) error {
+		user, err := findUser(ctx, container, *userRef)
+		if err != nil {
+			return err
+		}
+
+		result, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: *maxResults})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, "Synced emails for", user.Email+":", len(result.New), "new,", len(result.Updated), "updated,", result.Skipped, "skipped")
+		return nil
+	})
+}
+
+func runReclassify(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("reclassify", flag.ContinueOnError)
+	categoryID := flags.String("category", "", "category ID")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *categoryID == "" {
+		return errors.New("--category is required")
+	}
+
+	return withContainer(cfg, func(ctx context.Context, container *app.Container) error {
+		if _, err := container.CategoryRepo.FindByID(ctx, *categoryID); err != nil {
+			return err
+		}
+		total, err := container.EmailRepo.CountByCategoryID(ctx, *categoryID)
+		if err != nil {
+			return err
+		}
+
+		updated, err := container.EmailService.ReclassifyCategory(ctx, *categoryID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, "Reclassified", updated, "of", total, "emails")
+		return nil
+	})
+}
+
+func runExport(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	userRef := flags.String("user", "", "user ID or email address")
+	outPath := flags.String("out", "", "output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *userRef == "" {
+		return errors.New("--user is required")
+	}
+
+	return withContainer(cfg, func(ctx context.Context, container *app.Container) error {
+		user, err := findUser(ctx, container, *userRef)
+		if err != nil {
+			return err
+		}
+
+		// Locally archived emails are part of the export too; sensitive ones lose their content
+		// in privacy mode
+		emails, err := container.EmailService.ExportEmails(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		w := out
+		if *outPath != "" {
+			file, err := os.Create(*outPath)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+
+		return jsonstream.WriteArray(w, emails, "  ")
+	})
+}
+
+func runPrune(cfg *config.Config, args []string, out io.Writer) error {
+	return withContainer(cfg, func(ctx context.Context, container *app.Container) error {
+		retentionDays := container.CleanupJob.TrashRetention()
+		purged, err := container.EmailService.PurgeTrash(ctx, retentionDays)
+		if err != nil {
+			return err
+		}
+
+		stats, err := container.RetentionService.EnforcePolicies(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, "Purged", purged, "emails from trash")
+		fmt.Fprintln(out, "Retention pruned", stats.BodiesPruned, "bodies and deleted", stats.EmailsDeleted, "emails")
+		return nil
+	})
+}
+
+func runVAPIDKeys(cfg *config.Config, args []string, out io.Writer) error {
+	publicKey, privateKey, err := push.GenerateVAPIDKeys()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "VAPID_PUBLIC_KEY="+publicKey)
+	fmt.Fprintln(out, "VAPID_PRIVATE_KEY="+privateKey)
+	return nil
+}
+
+func runLoadTest(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	emails := flags.Int("emails", 1000, "synthetic emails to sync")
+	batch := flags.Int("batch", 50, "emails per Gmail fetch")
+	aiLatency := flags.Duration("ai-latency", 0, "simulated latency of each AI call, e.g. 200ms")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	return withContainer(cfg, func(ctx context.Context, container *app.Container) error {
+		storage := "memory"
+		if cfg.DatabaseURL != "" {
+			storage = "postgres"
+		}
+		fmt.Fprintf(out, "Syncing %d synthetic emails in batches of %d into %s storage\n", *emails, *batch, storage)
+
+		// The pipeline logs every email; that would drown the report and skew the timing
+		report, err := loadtest.Run(ctx, container.UserRepo, container.CategoryRepo, container.EmailRepo,
+			loadtest.Options{Emails: *emails, BatchSize: *batch, AILatency: *aiLatency}, logger.NewWithWriter(io.Discard))
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Synced %d emails in %d syncs in %s\n", report.Emails, report.Syncs, report.Duration.Round(time.Millisecond))
+		fmt.Fprintf(out, "Throughput:  %.1f emails/s\n", report.EmailsPerSec)
+		fmt.Fprintf(out, "DB writes:   %d (%.1f writes/s of write time)\n", report.Writes, report.WritesPerSec)
+		fmt.Fprintf(out, "Allocations: %d (%.1f MiB, %d per email)\n", report.Allocs, float64(report.AllocBytes)/(1<<20), report.Allocs/uint64(report.Emails))
+		return nil
+	})
+}
+
+// withContainer builds the app without starting background jobs and tears it down afterwards
+func runReplayAI(cfg *config.Config, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("replay-ai", flag.ContinueOnError)
+	callID := flags.String("call", "", "ID of a call recorded with AI_DEBUG")
+	provider := flags.String("provider", "", "AI provider to replay against (default the call's)")
+	modelName := flags.String("model", "", "model of the provider (default the provider's)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *callID == "" {
+		return errors.New("--call is required")
+	}
+	// Calls recorded by the server are only visible to another process through the database
+	if cfg.DatabaseURL == "" {
+		return errors.New("DATABASE_URL is required")
+	}
+
+	return withContainer(cfg, func(ctx context.Context, container *app.Container) error {
+		replay, err := container.AIDebug.Replay(ctx, *callID, *provider, *modelName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Prompt (%s):\n%s\n\n", replay.Call.Operation, replay.Call.Prompt)
+		fmt.Fprintf(out, "%s:\n%s\n\n", describeAnswer(replay.Call.Provider, replay.Call.Model, replay.Call.DurationMS), answerOrError(replay.Call.Response, replay.Call.Error))
+		fmt.Fprintf(out, "%s:\n%s\n", describeAnswer(replay.Provider, replay.Model, replay.DurationMS), answerOrError(replay.Response, replay.Error))
+		return nil
+	})
+}
+
+// describeAnswer heads an AI answer with where it came from
+func describeAnswer(provider, modelName string, durationMS int64) string {
+	if modelName == "" {
+		modelName = "default model"
+	}
+	return fmt.Sprintf("%s, %s, %dms", provider, modelName, durationMS)
+}
+
+func answerOrError(answer, errMessage string) string {
+	if errMessage != "" {
+		return "error: " + errMessage
+	}
+	return answer
+}
+
+func withContainer(cfg *config.Config, fn func(ctx context.Context, container *app.Container) error) error {
+	container, err := app.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+	defer container.Stop(context.Background())
+
+	return fn(context.Background(), container)
+}
+
+// findUser resolves a user by ID, falling back to email address
+func findUser(ctx context.Context, container *app.Container, ref string) (*model.User, error) {
+	if user, err := container.UserRepo.FindByID(ctx, ref); err == nil {
+		return user, nil
+	}
+	user, err := container.UserRepo.FindByEmail(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %s", ref)
+	}
+	return user, nil
+}
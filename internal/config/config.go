@@ -1,39 +1,372 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Defaults for the tunable settings; components fall back to them when given a zero value
+const (
+	DefaultSessionTTL         = 7 * 24 * time.Hour
+	DefaultSyncInterval       = 30 * time.Second
+	DefaultMaxFetchEmails     = 3
+	DefaultMaxEmailBodyBytes  = 256 * 1024 // some HTML newsletters run to several megabytes
+	DefaultTrashRetention     = 30 * 24 * time.Hour
+	DefaultOTPRetention       = 24 * time.Hour // verification codes are useless within minutes
+	DefaultBulkBatchSize      = 50
+	DefaultOnboardingBackfill = 50        // enough emails for the first recommendations without a long wait
+	DefaultAutomationInterval = time.Hour // delays are counted in days, so hourly is precise enough
+	DefaultTrackingInterval   = 2 * time.Hour
+	DefaultGmailReplay        = time.Minute         // how often changes queued while Gmail was unreachable are retried
+	DefaultUnsubscribeGrace   = 10 * 24 * time.Hour // senders are allowed 10 business days to honor an opt-out
+	DefaultSSEQueueSize       = 100
+	DefaultSSEQueueTTL        = 24 * time.Hour
+
+	// Politeness towards the servers of unsubscribe pages
+	DefaultUnsubscribeHostConcurrency = 2
+	DefaultUnsubscribeHostInterval    = 500 * time.Millisecond
+	DefaultUnsubscribeBudget          = 5 * time.Minute // per batch of emails unsubscribed from
+
+	// Circuit breaker pausing a user's Gmail calls
+	DefaultGmailBreakerFailures = 3 // outages or refused credentials in a row
+	DefaultGmailBreakerCooldown = time.Minute
+
+	DefaultAIFailoverCooldown = time.Minute // how long a rate limited or failing AI provider is skipped
+	DefaultAIChunkTokens      = 3000        // well inside every supported model's context window
+	DefaultAIMaxChunks        = 4
+
+	DefaultSummaryBudgetReserve = 20 // percent of the month's summaries kept from low priority categories
+
+	DefaultAIDebugRetention = 24 * time.Hour // debug mode records every prompt, so they aren't kept long
+
+	DefaultSMTPPort = 587 // submission, upgraded to TLS with STARTTLS
+)
+
+// How the app is authorized to call Gmail
+const (
+	GmailAuthOAuth      = "oauth"      // with the tokens each user grants when signing in
+	GmailAuthDelegation = "delegation" // as the users of a Workspace domain, with a service account
+)
+
 type Config struct {
-	Port               string
-	BaseURL            string
+	Port          string
+	BaseURL       string
+	Env           string
+	AssetsDir     string // optional directory overriding the embedded templates, static files and categories.json
+	SessionSecret string
+	SessionTTL    time.Duration // idle time after which a session expires
+	DatabaseURL   string
+
+	// Optional read-only replica of the database, for email listings and counts
+	DatabaseReplicaURL string
+
+	// Google OAuth
 	GoogleClientID     string
 	GoogleClientSecret string
-	SessionSecret      string
-	DatabaseURL        string
-	AIProvider         string
-	AIKey              string
-	Env                string
+
+	// Gmail authorization, GmailAuthOAuth or GmailAuthDelegation. With delegation, the mailboxes
+	// of WorkspaceDomain are called as their users with the service account, which the domain's
+	// admin granted domain-wide delegation; other users keep their own OAuth tokens.
+	GmailAuth            string
+	GoogleServiceAccount string // path to the JSON key of the service account
+	WorkspaceDomain      string
+
+	// AI provider
+	AIProvider string
+	AIKey      string
+	AIModel    string // overrides the provider's default model when set, for the providers that allow it
+
+	// Optional AI provider taking requests the primary can't serve, e.g. when rate limited
+	AIFallbackProvider string
+	AIFallbackKey      string
+	AIFallbackModel    string
+	AIFailoverCooldown time.Duration // how long a failing provider is skipped
+
+	// Size of the email text sent to the AI
+	AIChunkTokens int // estimated tokens per request; longer emails are summarized in chunks
+	AIMaxChunks   int // chunks summarized per email, the rest is left out
+
+	// Debug mode records every AI prompt and answer, redacted, so they can be inspected and replayed
+	AIDebug          bool // takes a restart to change
+	AIDebugRetention time.Duration
+
+	// Classification experiment routing a share of emails to another prompt or model; it is
+	// set at startup and off while AIExperimentPercent is 0
+	AIExperimentName     string // results are counted per name
+	AIExperimentPercent  int
+	AIExperimentProvider string // the primary provider when empty
+	AIExperimentKey      string // the configured key of the provider when empty
+	AIExperimentModel    string
+	AIExperimentPrompt   string // name of a classification prompt variant, the default one when empty
+
+	// Percent of a user's monthly summaries left under which low priority categories aren't summarized
+	SummaryBudgetReserve int
+	// Percent of confidence under which a classification waits in the review queue, 0 disables it
+	ReviewConfidence int
+
+	// Email sync and storage
+	SyncInterval      time.Duration
+	MaxFetchEmails    int64 // emails fetched per sync when the caller doesn't say
+	MaxEmailBodyBytes int   // stored body size cap, 0 disables it
+	TrashRetention    time.Duration
+	OTPRetention      time.Duration // emails holding a verification code are trashed once this old
+	BulkBatchSize     int
+	// Emails imported by the backfill step of a new user's onboarding
+	OnboardingBackfill int
+
+	// Unsubscribes
+	UnsubscribeGrace           time.Duration // emails from a sender this long after unsubscribing flag the unsubscribe as ineffective
+	UnsubscribeHostConcurrency int           // requests in flight to one host
+	UnsubscribeHostInterval    time.Duration // minimum time between requests to one host
+	UnsubscribeBudget          time.Duration // time one batch of unsubscribes may take
+
+	// Automations
+	AutomationInterval time.Duration
+
+	// Package tracking
+	TrackingInterval time.Duration // how often carriers are asked about packages on their way
+
+	// Changes made while Gmail was unreachable
+	GmailReplayInterval time.Duration // how often they are retried in Gmail
+
+	// Circuit breaker pausing a user's Gmail calls after repeated outages or refused credentials
+	GmailBreakerFailures int           // failures in a row that open it
+	GmailBreakerCooldown time.Duration // how long it stays open at first, doubled by each failed probe
+
+	// Notifications
+	VAPIDPublicKey   string // Web Push keys; push notifications are disabled without a private key
+	VAPIDPrivateKey  string
+	VAPIDSubject     string // contact push services can reach, a mailto: or https: URL
+	TelegramBotToken string // the Telegram bot is disabled without a token
+	TelegramBotUser  string // bot username used to build t.me link URLs
+
+	// Emails the app sends on its own, through an SMTP server or, without one, from each user's
+	// own Gmail
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string // no authentication when empty
+	SMTPPassword string
+	SMTPFrom     string // sender of the emails, e.g. "Jump Challenge <noreply@example.com>"
+	ReportEmails bool   // email users their weekly report
+
+	// Events broadcast to users without an SSE connection, sent once they reconnect
+	SSEQueueSize int           // events kept per user, 0 disables the queue
+	SSEQueueTTL  time.Duration // how long they are kept
+
+	// Operators, by the email of their Google account, allowed to see across users
+	AdminEmails []string
+
+	// Billing
+	DefaultPlan      string // plan of users without a billing account; unlimited disables quotas
+	StripeWebhookKey string // signing secret of the Stripe webhook endpoint; the webhook is disabled without it
 }
 
+var (
+	envMutex    sync.Mutex
+	processEnv  map[string]bool // variables set before .env was first read; .env never overrides them
+	envFileKeys map[string]bool // variables currently taken from .env
+)
+
+// loadEnvFile applies .env on top of the process environment. Calling it again picks up
+// edits to the file, including variables removed from it.
+func loadEnvFile() {
+	envMutex.Lock()
+	defer envMutex.Unlock()
+
+	if processEnv == nil {
+		processEnv = make(map[string]bool)
+		for _, variable := range os.Environ() {
+			key, _, _ := strings.Cut(variable, "=")
+			processEnv[key] = true
+		}
+	}
+
+	// A missing .env file leaves only the process environment
+	values, err := godotenv.Read()
+	if err != nil {
+		values = nil
+	}
+
+	for key := range envFileKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	envFileKeys = make(map[string]bool)
+	for key, value := range values {
+		if processEnv[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		envFileKeys[key] = true
+	}
+}
+
+// LoadConfig reads the configuration from the environment and the .env file, if it exists.
+// Every malformed variable is reported, not just the first one.
 func LoadConfig() (*Config, error) {
-	// Load .env file if it exists
-	_ = godotenv.Load()
+	loadEnvFile()
+
+	var env envReader
+	cfg := &Config{
+		Port:          GetEnv("PORT", "8080"),
+		BaseURL:       GetEnv("BASE_URL", "http://localhost:8080"),
+		Env:           GetEnv("ENV", "development"),
+		AssetsDir:     GetEnv("ASSETS_DIR", ""),
+		SessionSecret: GetEnv("SESSION_SECRET", "175cd51c-b5e7-4218-81ed-e6832c8b53f1"),
+		SessionTTL:    env.duration("SESSION_TTL_HOURS", time.Hour, DefaultSessionTTL),
+		DatabaseURL:   GetEnv("DATABASE_URL", ""),
+
+		DatabaseReplicaURL: GetEnv("DATABASE_REPLICA_URL", ""),
 
-	return &Config{
-		Port:               GetEnv("PORT", "8080"),
-		BaseURL:            GetEnv("BASE_URL", "http://localhost:8080"),
 		GoogleClientID:     GetEnv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: GetEnv("GOOGLE_CLIENT_SECRET", ""),
-		SessionSecret:      GetEnv("SESSION_SECRET", "175cd51c-b5e7-4218-81ed-e6832c8b53f1"),
-		DatabaseURL:        GetEnv("DATABASE_URL", ""),
-		AIProvider:         GetEnv("AI_PROVIDER", "gemini"),
-		AIKey:              GetEnv("AI_API_KEY", ""),
-		Env:                GetEnv("ENV", "development"),
-	}, nil
+
+		GmailAuth:            GetEnv("GMAIL_AUTH", GmailAuthOAuth),
+		GoogleServiceAccount: GetEnv("GOOGLE_SERVICE_ACCOUNT_FILE", ""),
+		WorkspaceDomain:      strings.ToLower(GetEnv("WORKSPACE_DOMAIN", "")),
+
+		AIProvider: GetEnv("AI_PROVIDER", "gemini"),
+		AIKey:      GetEnv("AI_API_KEY", ""),
+		AIModel:    GetEnv("DEFAULT_MODEL", ""),
+
+		AIFallbackProvider: GetEnv("AI_FALLBACK_PROVIDER", ""),
+		AIFallbackKey:      GetEnv("AI_FALLBACK_API_KEY", ""),
+		AIFallbackModel:    GetEnv("AI_FALLBACK_MODEL", ""),
+		AIFailoverCooldown: env.duration("AI_FAILOVER_COOLDOWN_SECONDS", time.Second, DefaultAIFailoverCooldown),
+
+		AIChunkTokens: env.int("AI_CHUNK_TOKENS", DefaultAIChunkTokens, 100),
+		AIMaxChunks:   env.int("AI_MAX_CHUNKS", DefaultAIMaxChunks, 1),
+
+		AIDebug:          env.bool("AI_DEBUG", false),
+		AIDebugRetention: env.duration("AI_DEBUG_RETENTION_HOURS", time.Hour, DefaultAIDebugRetention),
+
+		AIExperimentName:     GetEnv("AI_EXPERIMENT_NAME", ""),
+		AIExperimentPercent:  env.int("AI_EXPERIMENT_PERCENT", 0, 0),
+		AIExperimentProvider: GetEnv("AI_EXPERIMENT_PROVIDER", ""),
+		AIExperimentKey:      GetEnv("AI_EXPERIMENT_API_KEY", ""),
+		AIExperimentModel:    GetEnv("AI_EXPERIMENT_MODEL", ""),
+		AIExperimentPrompt:   GetEnv("AI_EXPERIMENT_PROMPT", ""),
+
+		SummaryBudgetReserve: env.int("SUMMARY_BUDGET_RESERVE_PERCENT", DefaultSummaryBudgetReserve, 0),
+		ReviewConfidence:     env.int("REVIEW_CONFIDENCE_PERCENT", 0, 0),
+
+		SyncInterval:      env.duration("EMAIL_SYNC_INTERVAL_SECONDS", time.Second, DefaultSyncInterval),
+		MaxFetchEmails:    int64(env.int("MAX_FETCH_EMAILS", DefaultMaxFetchEmails, 1)),
+		MaxEmailBodyBytes: env.int("MAX_EMAIL_BODY_BYTES", DefaultMaxEmailBodyBytes, 0),
+		TrashRetention:    env.duration("TRASH_RETENTION_DAYS", 24*time.Hour, DefaultTrashRetention),
+		OTPRetention:      env.duration("OTP_RETENTION_HOURS", time.Hour, DefaultOTPRetention),
+		BulkBatchSize:     env.int("BULK_ACTION_BATCH_SIZE", DefaultBulkBatchSize, 1),
+
+		OnboardingBackfill: env.int("ONBOARDING_BACKFILL_EMAILS", DefaultOnboardingBackfill, 1),
+
+		UnsubscribeGrace:           env.duration("UNSUBSCRIBE_GRACE_DAYS", 24*time.Hour, DefaultUnsubscribeGrace),
+		UnsubscribeHostConcurrency: env.int("UNSUBSCRIBE_HOST_CONCURRENCY", DefaultUnsubscribeHostConcurrency, 1),
+		UnsubscribeHostInterval:    env.duration("UNSUBSCRIBE_HOST_INTERVAL_MS", time.Millisecond, DefaultUnsubscribeHostInterval),
+		UnsubscribeBudget:          env.duration("UNSUBSCRIBE_BUDGET_SECONDS", time.Second, DefaultUnsubscribeBudget),
+
+		AutomationInterval: env.duration("AUTOMATION_INTERVAL_MINUTES", time.Minute, DefaultAutomationInterval),
+
+		TrackingInterval: env.duration("TRACKING_INTERVAL_MINUTES", time.Minute, DefaultTrackingInterval),
+
+		GmailReplayInterval: env.duration("GMAIL_REPLAY_INTERVAL_SECONDS", time.Second, DefaultGmailReplay),
+
+		GmailBreakerFailures: env.int("GMAIL_BREAKER_FAILURES", DefaultGmailBreakerFailures, 1),
+		GmailBreakerCooldown: env.duration("GMAIL_BREAKER_COOLDOWN_SECONDS", time.Second, DefaultGmailBreakerCooldown),
+
+		VAPIDPublicKey:   GetEnv("VAPID_PUBLIC_KEY", ""),
+		VAPIDPrivateKey:  GetEnv("VAPID_PRIVATE_KEY", ""),
+		VAPIDSubject:     GetEnv("VAPID_SUBJECT", "mailto:admin@example.com"),
+		TelegramBotToken: GetEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramBotUser:  GetEnv("TELEGRAM_BOT_USERNAME", ""),
+
+		SMTPHost:     GetEnv("SMTP_HOST", ""),
+		SMTPPort:     env.int("SMTP_PORT", DefaultSMTPPort, 1),
+		SMTPUsername: GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword: GetEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     GetEnv("SMTP_FROM", ""),
+		ReportEmails: env.bool("REPORT_EMAILS", false),
+
+		SSEQueueSize: env.int("SSE_QUEUE_SIZE", DefaultSSEQueueSize, 0),
+		SSEQueueTTL:  env.duration("SSE_QUEUE_TTL_HOURS", time.Hour, DefaultSSEQueueTTL),
+
+		AdminEmails: env.list("ADMIN_EMAILS"),
+
+		DefaultPlan:      GetEnv("DEFAULT_PLAN", "unlimited"),
+		StripeWebhookKey: GetEnv("STRIPE_WEBHOOK_SECRET", ""),
+	}
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// envReader parses typed variables, collecting an error for each malformed one
+type envReader struct {
+	errs []error
+}
+
+// int reads an integer of at least minValue, defaultValue when unset
+func (r *envReader) int(key string, defaultValue, minValue int) int {
+	raw, ok := os.LookupEnv(key)
+	if !ok || raw == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || value < minValue {
+		r.errs = append(r.errs, fmt.Errorf("%s must be an integer of at least %d, got %q", key, minValue, raw))
+		return defaultValue
+	}
+	return value
+}
+
+// duration reads a positive whole number of units, e.g. hours for SESSION_TTL_HOURS
+func (r *envReader) duration(key string, unit, defaultValue time.Duration) time.Duration {
+	return time.Duration(r.int(key, int(defaultValue/unit), 1)) * unit
+}
+
+// bool reads true or false, as strconv.ParseBool spells them, defaultValue when unset
+func (r *envReader) bool(key string, defaultValue bool) bool {
+	raw, ok := os.LookupEnv(key)
+	if !ok || raw == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be true or false, got %q", key, raw))
+		return defaultValue
+	}
+	return value
+}
+
+// list reads comma-separated values, skipping empty ones
+func (r *envReader) list(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// IsAdmin reports whether the email belongs to an operator
+func (c *Config) IsAdmin(email string) bool {
+	for _, admin := range c.AdminEmails {
+		if strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
 }
 
 func GetEnv(key, defaultValue string) string {
@@ -43,18 +376,56 @@ func GetEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// Validate checks what the server needs to run and reports every problem at once
 func (c *Config) Validate() error {
+	var errs []error
 	if c.GoogleClientID == "" {
-		return fmt.Errorf("GOOGLE_CLIENT_ID is required")
+		errs = append(errs, errors.New("GOOGLE_CLIENT_ID is required"))
 	}
 	if c.GoogleClientSecret == "" {
-		return fmt.Errorf("GOOGLE_CLIENT_SECRET is required")
+		errs = append(errs, errors.New("GOOGLE_CLIENT_SECRET is required"))
 	}
 	if c.SessionSecret == "" {
-		return fmt.Errorf("SESSION_SECRET is required")
+		errs = append(errs, errors.New("SESSION_SECRET is required"))
+	}
+	switch c.GmailAuth {
+	case "", GmailAuthOAuth:
+	case GmailAuthDelegation:
+		if c.GoogleServiceAccount == "" {
+			errs = append(errs, errors.New("GOOGLE_SERVICE_ACCOUNT_FILE is required with GMAIL_AUTH=delegation"))
+		}
+		if c.WorkspaceDomain == "" {
+			errs = append(errs, errors.New("WORKSPACE_DOMAIN is required with GMAIL_AUTH=delegation"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("GMAIL_AUTH must be %s or %s, got %q", GmailAuthOAuth, GmailAuthDelegation, c.GmailAuth))
+	}
+	if c.AIFallbackProvider != "" && c.AIFallbackKey == "" {
+		errs = append(errs, errors.New("AI_FALLBACK_API_KEY is required with AI_FALLBACK_PROVIDER"))
+	}
+	if c.AIExperimentPercent > 100 {
+		errs = append(errs, errors.New("AI_EXPERIMENT_PERCENT must be at most 100"))
+	}
+	if c.AIExperimentPercent > 0 && c.AIExperimentName == "" {
+		errs = append(errs, errors.New("AI_EXPERIMENT_NAME is required with AI_EXPERIMENT_PERCENT"))
+	}
+	if c.SummaryBudgetReserve > 100 {
+		errs = append(errs, errors.New("SUMMARY_BUDGET_RESERVE_PERCENT must be at most 100"))
+	}
+	if c.ReviewConfidence > 100 {
+		errs = append(errs, errors.New("REVIEW_CONFIDENCE_PERCENT must be at most 100"))
+	}
+	if c.VAPIDPrivateKey != "" && c.VAPIDPublicKey == "" {
+		errs = append(errs, errors.New("VAPID_PUBLIC_KEY is required with VAPID_PRIVATE_KEY"))
+	}
+	if c.OnboardingBackfill > 500 {
+		errs = append(errs, errors.New("ONBOARDING_BACKFILL_EMAILS must be at most 500"))
+	}
+	if c.SMTPHost != "" && c.SMTPFrom == "" {
+		errs = append(errs, errors.New("SMTP_FROM is required with SMTP_HOST"))
 	}
-	if c.AIKey == "" {
-		return fmt.Errorf("AI_API_KEY is required")
+	if c.SMTPPort > 65535 {
+		errs = append(errs, errors.New("SMTP_PORT must be at most 65535"))
 	}
-	return nil
+	return errors.Join(errs...)
 }
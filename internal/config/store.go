@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the current configuration snapshot. Components that can follow a change
+// without a restart subscribe to it; everything else keeps the snapshot it started with.
+type Store struct {
+	current     atomic.Pointer[Config]
+	load        func() (*Config, error)
+	subscribers []func(cfg *Config)
+
+	// Serializes reloads so subscribers see snapshots in order
+	mutex sync.Mutex
+}
+
+// NewStore starts from cfg; load builds a fresh snapshot on Reload, usually LoadConfig
+func NewStore(cfg *Config, load func() (*Config, error)) *Store {
+	s := &Store{load: load}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get returns the current snapshot, which must not be modified
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Subscribe calls fn with every snapshot set after this call
+func (s *Store) Subscribe(fn func(cfg *Config)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Reload loads a new snapshot and hands it to subscribers; on error the current one stays
+func (s *Store) Reload() (*Config, error) {
+	cfg, err := s.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload config: %w", err)
+	}
+	s.Set(cfg)
+	return cfg, nil
+}
+
+// Set swaps in cfg and notifies subscribers
+func (s *Store) Set(cfg *Config) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.current.Store(cfg)
+	for _, fn := range s.subscribers {
+		fn(cfg)
+	}
+}
@@ -0,0 +1,181 @@
+// Package emailtemplate renders the emails the app sends on its own, such as digests and weekly
+// reports. Email clients ignore most stylesheets, so the HTML is laid out with tables and styled
+// inline, narrowing to the screen on phones; every message comes with a plain-text alternative.
+package emailtemplate
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"jump-challenge/internal/model"
+)
+
+//go:embed templates
+var files embed.FS
+
+// Message is a rendered email, ready to be sent as multipart/alternative
+type Message struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Digest lists the emails received since the previous digest, grouped by category
+type Digest struct {
+	UserName string // greets the user, left out when empty
+	Since    time.Time
+	Sections []DigestSection // in the order shown
+	AppURL   string          // where to read the emails
+}
+
+// DigestSection holds a digest's emails of one category
+type DigestSection struct {
+	Category string
+	Emails   []DigestEmail
+}
+
+// DigestEmail is an email as a digest shows it
+type DigestEmail struct {
+	From       string
+	Subject    string
+	Summary    string // the email's snippet when it has no summary
+	ReceivedAt time.Time
+}
+
+// Count is how many emails the digest lists
+func (d Digest) Count() int {
+	count := 0
+	for _, section := range d.Sections {
+		count += len(section.Emails)
+	}
+	return count
+}
+
+// Report is a user's weekly inbox report as sent by email
+type Report struct {
+	UserName string
+	Report   *model.InboxReport
+	AppURL   string
+}
+
+// Busiest is the count of the busiest category, the full width of the report's bars
+func (r Report) Busiest() int {
+	busiest := 0
+	for _, category := range r.Report.Stats.Categories {
+		if category.Count > busiest {
+			busiest = category.Count
+		}
+	}
+	return busiest
+}
+
+// CategoryColor is how the app colors a category's badge
+type CategoryColor struct {
+	Background string
+	Text       string
+}
+
+// ColorOf picks the colors of a category by its name, as the app does
+func ColorOf(category string) CategoryColor {
+	normalized := strings.ToLower(category)
+	switch {
+	case strings.Contains(normalized, "work"):
+		return CategoryColor{Background: "#e3f2fd", Text: "#1976d2"}
+	case strings.Contains(normalized, "personal"):
+		return CategoryColor{Background: "#fce4ec", Text: "#c2185b"}
+	case strings.Contains(normalized, "financ"):
+		return CategoryColor{Background: "#e8f5e9", Text: "#388e3c"}
+	case strings.Contains(normalized, "sales"):
+		return CategoryColor{Background: "#fff3e0", Text: "#ef6c00"}
+	case strings.Contains(normalized, "newsletter"):
+		return CategoryColor{Background: "#f3e5f5", Text: "#7b1fa2"}
+	default:
+		return CategoryColor{Background: "#eeeeee", Text: "#616161"}
+	}
+}
+
+var funcs = map[string]interface{}{
+	"color":      ColorOf,
+	"formatDate": formatDate,
+	"formatTime": formatTime,
+	"duration":   formatMinutes,
+	"percent":    percent,
+	"plural":     plural,
+}
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").Funcs(funcs).ParseFS(files, "templates/*.html"))
+	textTemplates = texttemplate.Must(texttemplate.New("").Funcs(funcs).ParseFS(files, "templates/*.txt"))
+)
+
+// RenderDigest renders the digest
+func RenderDigest(digest Digest) (*Message, error) {
+	subject := fmt.Sprintf("Your digest: %d new %s", digest.Count(), plural(digest.Count(), "email", "emails"))
+	return render("digest", subject, digest)
+}
+
+// RenderReport renders the weekly report
+func RenderReport(report Report) (*Message, error) {
+	end := report.Report.PeriodEnd.AddDate(0, 0, -1)
+	subject := fmt.Sprintf("Your week in email: %s - %s", formatDate(report.Report.PeriodStart), formatDate(end))
+	return render("report", subject, report)
+}
+
+// render executes the name.html and name.txt templates with the data
+func render(name, subject string, data interface{}) (*Message, error) {
+	page := struct {
+		Subject string
+		Data    interface{}
+	}{subject, data}
+
+	var html, text bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", page); err != nil {
+		return nil, fmt.Errorf("failed to render %s as HTML: %w", name, err)
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt", page); err != nil {
+		return nil, fmt.Errorf("failed to render %s as text: %w", name, err)
+	}
+	return &Message{Subject: subject, HTML: html.String(), Text: text.String()}, nil
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("Jan 2, 2006")
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("Jan 2, 15:04")
+}
+
+// formatMinutes writes a duration for people, e.g. "1 h 25 min"
+func formatMinutes(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%d min", minutes)
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%d h", minutes/60)
+	}
+	return fmt.Sprintf("%d h %d min", minutes/60, minutes%60)
+}
+
+// percent is part of whole as a whole percentage, at least 1 so a small bar never vanishes
+func percent(part, whole int) int {
+	if part <= 0 || whole <= 0 {
+		return 0
+	}
+	if p := part * 100 / whole; p > 0 {
+		return p
+	}
+	return 1
+}
+
+func plural(count int, one, many string) string {
+	if count == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,228 @@
+package gmail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+)
+
+// DelegatedClient calls Gmail as the users of a Google Workspace domain with a service account
+// the domain's admin granted domain-wide delegation, so their mailboxes are synced without each
+// of them signing in with OAuth. Addresses outside the domain are left to the fallback client,
+// usually a UserClient calling Gmail with the users' own tokens.
+//
+// When Google refuses to delegate access, because the admin hasn't authorized the service
+// account's client ID for the scopes or the address isn't a user of the domain, calls fail with
+// service.ErrGmailUnauthorized; the users can't fix it by signing in again.
+type DelegatedClient struct {
+	domain         string
+	clientID       string
+	config         *jwt.Config // of the service account; each user's copy sets Subject
+	maxFetchEmails int64       // used when List isn't told how many messages to list
+	endpoint       string      // the Gmail API's, empty for Google's
+	fallback       service.MailClient
+	logger         *logger.Logger
+
+	// The users' token sources fetch an access token when the previous one expires; the
+	// Gmail client is rebuilt each time. A domain's mailboxes are few enough to keep them all.
+	mutex     sync.Mutex
+	mailboxes map[string]*delegatedMailbox // by address
+}
+
+var _ service.MailClient = (*DelegatedClient)(nil)
+
+// delegatedMailbox is the service account's access to one user's mailbox
+type delegatedMailbox struct {
+	tokens      oauth2.TokenSource
+	accessToken string // the client was built with
+	client      service.MailClient
+}
+
+// NewDelegatedClient impersonates the users of domain with the service account whose JSON key
+// is given; fallback, which may be nil, serves the other addresses
+func NewDelegatedClient(key []byte, domain string, maxFetchEmails int64, fallback service.MailClient, logger *logger.Logger) (*DelegatedClient, error) {
+	return NewDelegatedClientWithEndpoint(key, domain, maxFetchEmails, "", fallback, logger)
+}
+
+// NewDelegatedClientWithEndpoint talks to the Gmail API at endpoint instead of Google's, e.g. a
+// fake server in tests; an empty endpoint uses the default. Tokens are fetched from the key's
+// token_uri.
+func NewDelegatedClientWithEndpoint(key []byte, domain string, maxFetchEmails int64, endpoint string, fallback service.MailClient, logger *logger.Logger) (*DelegatedClient, error) {
+	config, err := google.JWTConfigFromJSON(key, model.WorkspaceScopes...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+	var account struct {
+		ClientID string `json:"client_id"`
+	}
+	if err := json.Unmarshal(key, &account); err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+
+	return &DelegatedClient{
+		domain:         strings.ToLower(domain),
+		clientID:       account.ClientID,
+		config:         config,
+		maxFetchEmails: maxFetchEmails,
+		endpoint:       endpoint,
+		fallback:       fallback,
+		logger:         logger,
+		mailboxes:      make(map[string]*delegatedMailbox),
+	}, nil
+}
+
+// ClientID is what the domain's admin authorizes for domain-wide delegation in the Admin console
+func (d *DelegatedClient) ClientID() string {
+	return d.clientID
+}
+
+// InDomain reports whether the address belongs to the Workspace domain
+func (d *DelegatedClient) InDomain(userEmail string) bool {
+	return model.InDomain(userEmail, d.domain)
+}
+
+// do makes the call with a client impersonating the user, or with the fallback client for
+// addresses outside the domain
+func (d *DelegatedClient) do(userEmail string, call func(gmailClient service.MailClient) error) error {
+	if !d.InDomain(userEmail) {
+		if d.fallback == nil {
+			return fmt.Errorf("%s is outside the Workspace domain %s", userEmail, d.domain)
+		}
+		return call(d.fallback)
+	}
+
+	gmailClient, err := d.clientFor(userEmail)
+	if err != nil {
+		return err
+	}
+	return call(gmailClient)
+}
+
+// clientFor returns a Gmail client authorized with the service account's current access token
+// for the user
+func (d *DelegatedClient) clientFor(userEmail string) (service.MailClient, error) {
+	userEmail = strings.ToLower(userEmail)
+	d.mutex.Lock()
+	mailbox, ok := d.mailboxes[userEmail]
+	if !ok {
+		config := *d.config
+		config.Subject = userEmail
+		// The token source outlives the call that creates it, so it doesn't take its context
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 10 * time.Second})
+		mailbox = &delegatedMailbox{tokens: config.TokenSource(ctx)}
+		d.mailboxes[userEmail] = mailbox
+	}
+	d.mutex.Unlock()
+
+	token, err := mailbox.tokens.Token()
+	if err != nil {
+		return nil, delegationError(userEmail, err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if mailbox.client == nil || mailbox.accessToken != token.AccessToken {
+		gmailClient, err := NewGmailClientWithEndpoint(token.AccessToken, d.endpoint, d.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Gmail client: %w", err)
+		}
+		mailbox.accessToken = token.AccessToken
+		mailbox.client = gmailClient
+	}
+	return mailbox.client, nil
+}
+
+// delegationError tells a refused delegation, as service.ErrGmailUnauthorized, from Google's
+// token endpoint being unreachable
+func delegationError(userEmail string, err error) error {
+	var refused *oauth2.RetrieveError
+	if !errors.As(err, &refused) || refused.Response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("failed to get an access token for %s: %w: %v", userEmail, service.ErrGmailUnreachable, err)
+	}
+
+	var answer struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if json.Unmarshal(refused.Body, &answer) != nil || answer.Error == "" {
+		answer.Error = refused.Response.Status
+	}
+	return fmt.Errorf("%w: Google refused to delegate access to %s: %s", service.ErrGmailUnauthorized, userEmail,
+		strings.TrimSpace(answer.Error+" "+answer.Description))
+}
+
+func (d *DelegatedClient) List(ctx context.Context, userEmail string, maxResults int64) ([]string, error) {
+	if maxResults <= 0 && d.InDomain(userEmail) {
+		maxResults = d.maxFetchEmails
+	}
+	var messageIDs []string
+	err := d.do(userEmail, func(gmailClient service.MailClient) (err error) {
+		messageIDs, err = gmailClient.List(ctx, userEmail, maxResults)
+		return err
+	})
+	return messageIDs, err
+}
+
+func (d *DelegatedClient) Get(ctx context.Context, userEmail, messageID string) (*model.Email, error) {
+	var email *model.Email
+	err := d.do(userEmail, func(gmailClient service.MailClient) (err error) {
+		email, err = gmailClient.Get(ctx, userEmail, messageID)
+		return err
+	})
+	return email, err
+}
+
+func (d *DelegatedClient) Modify(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+	return d.do(userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Modify(ctx, userEmail, messageID, add, remove)
+	})
+}
+
+func (d *DelegatedClient) Trash(ctx context.Context, userEmail, messageID string) error {
+	return d.do(userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Trash(ctx, userEmail, messageID)
+	})
+}
+
+func (d *DelegatedClient) Delete(ctx context.Context, userEmail string, messageIDs []string) error {
+	return d.do(userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Delete(ctx, userEmail, messageIDs)
+	})
+}
+
+func (d *DelegatedClient) Send(ctx context.Context, userEmail string, raw []byte) error {
+	return d.do(userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Send(ctx, userEmail, raw)
+	})
+}
+
+func (d *DelegatedClient) Watch(ctx context.Context, userEmail, topic string) (*model.MailWatch, error) {
+	var watch *model.MailWatch
+	err := d.do(userEmail, func(gmailClient service.MailClient) (err error) {
+		watch, err = gmailClient.Watch(ctx, userEmail, topic)
+		return err
+	})
+	return watch, err
+}
+
+func (d *DelegatedClient) GetOrCreateLabel(ctx context.Context, userEmail, name string) (string, error) {
+	var labelID string
+	err := d.do(userEmail, func(gmailClient service.MailClient) (err error) {
+		labelID, err = gmailClient.GetOrCreateLabel(ctx, userEmail, name)
+		return err
+	})
+	return labelID, err
+}
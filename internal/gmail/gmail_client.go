@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"html"
 	"net/http"
-	"strconv"
+	"net/mail"
 	"strings"
 	"time"
 
@@ -24,12 +24,24 @@ type gmailClient struct {
 	logger *logger.Logger
 }
 
-func NewGmailClient(accessToken string, logger *logger.Logger) (service.GmailClient, error) {
+var _ service.MailClient = (*gmailClient)(nil)
+
+func NewGmailClient(accessToken string, logger *logger.Logger) (service.MailClient, error) {
+	return NewGmailClientWithEndpoint(accessToken, "", logger)
+}
+
+// NewGmailClientWithEndpoint talks to the Gmail API at endpoint instead of Google's, e.g. a fake
+// server in tests; an empty endpoint uses the default
+func NewGmailClientWithEndpoint(accessToken, endpoint string, logger *logger.Logger) (service.MailClient, error) {
 	httpClient := &http.Client{
 		Transport: &oauth2Transport{token: accessToken},
 	}
 
-	gmailService, err := gmail.NewService(context.Background(), option.WithHTTPClient(httpClient))
+	options := []option.ClientOption{option.WithHTTPClient(httpClient)}
+	if endpoint != "" {
+		options = append(options, option.WithEndpoint(endpoint))
+	}
+	gmailService, err := gmail.NewService(context.Background(), options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gmail service: %w", err)
 	}
@@ -44,90 +56,113 @@ type oauth2Transport struct {
 	token string
 }
 
+// RoundTrip fails with service.ErrGmailUnreachable when Gmail can't be reached or answers with a
+// server error, and with service.ErrGmailUnauthorized when it refuses the token, so callers can
+// tell those from a refused request
 func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Set("Authorization", "Bearer "+t.token)
-	return http.DefaultTransport.RoundTrip(req)
-}
-
-func (g *gmailClient) SyncEmails(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
-	// List messages with a query to fetch emails
-	user := "me" // Use 'me' to refer to the authenticated user
-
-	// Build the query to filter emails - using a more general query since we're not just getting unread emails
-	var query string
-	if afterEmailID != "" {
-		// If afterEmailID is provided, we'll filter after finding emails
-		query = "" // Empty query fetches all emails
-	} else {
-		query = "" // Empty query fetches all emails
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", service.ErrGmailUnreachable, err)
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", service.ErrGmailUnreachable, resp.Status)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", service.ErrGmailUnauthorized, resp.Status)
 	}
+	return resp, nil
+}
 
-	// Use provided maxResults, or fall back to the environment variable, or default to 3
+func (g *gmailClient) List(ctx context.Context, userEmail string, maxResults int64) ([]string, error) {
 	if maxResults <= 0 {
-		maxFetchEmails := config.GetEnv("MAX_FETCH_EMAILS", "3")
-		maxFetch, _ := strconv.Atoi(maxFetchEmails)
-		maxResults = int64(maxFetch)
+		maxResults = config.DefaultMaxFetchEmails
 	}
 
-	req := g.client.Users.Messages.List(user).MaxResults(maxResults).Q(query)
-
-	// If afterEmailID is provided, we might need to handle pagination to find emails after it
-	list, err := req.Do()
+	// 'me' refers to the authenticated user
+	list, err := g.client.Users.Messages.List("me").MaxResults(maxResults).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list messages: %w", err)
 	}
 
-	var emails []*model.Email
-
-	// If afterEmailID is provided, we need to filter the results to exclude emails up to and including afterEmailID
-	// This is a simplified approach - in real usage, we'd need to check timestamps or position
-	shouldStartCollecting := afterEmailID == ""
-
-	for _, msg := range list.Messages {
-		// If we're looking for emails after a specific email ID, skip until we find it
-		if afterEmailID != "" && msg.Id == afterEmailID {
-			shouldStartCollecting = true
-			continue
-		}
-
-		// If we haven't found the afterEmailID yet, skip this email
-		if afterEmailID != "" && !shouldStartCollecting {
-			continue
-		}
+	messageIDs := make([]string, len(list.Messages))
+	for i, message := range list.Messages {
+		messageIDs[i] = message.Id
+	}
+	return messageIDs, nil
+}
 
-		// Get the full message
-		message, err := g.client.Users.Messages.Get(user, msg.Id).Format("full").Do()
-		if err != nil {
-			g.logger.Error("Failed to get message:", err)
-			continue
-		}
+func (g *gmailClient) Get(ctx context.Context, userEmail, messageID string) (*model.Email, error) {
+	message, err := g.client.Users.Messages.Get("me", messageID).Format("full").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
 
-		// Extract subject and body
-		subject := message.Snippet
-		from := ""
-		body := ""
-
-		// Extract headers
-		for _, header := range message.Payload.Headers {
-			if header.Name == "Subject" {
-				subject = header.Value
-			} else if header.Name == "From" {
-				from = header.Value
+	// Extract subject and body
+	subject := message.Snippet
+	from := ""
+	var to, cc, replyTo, rfcMessageID string
+	var sentAt *time.Time
+	systemHeaders := make(map[string]string)
+
+	// Extract headers (names are case-insensitive per RFC 5322)
+	for _, header := range message.Payload.Headers {
+		name := strings.ToLower(header.Name)
+		switch name {
+		case "subject":
+			subject = header.Value
+		case "from":
+			from = header.Value
+		case "to":
+			to = header.Value
+		case "cc":
+			cc = header.Value
+		case "reply-to":
+			replyTo = header.Value
+		case "message-id":
+			rfcMessageID = strings.TrimSpace(header.Value)
+		case "date":
+			if parsed, err := mail.ParseDate(header.Value); err == nil {
+				sentAt = &parsed
+			} else {
+				g.logger.Warn("Failed to parse Date header:", header.Value, err)
 			}
+		case "x-failed-recipients", "content-type", "auto-submitted", "x-autoreply", "x-autorespond", "precedence":
+			systemHeaders[name] = header.Value
 		}
-
-		// Extract body
-		body = g.extractBody(message.Payload)
-
-		// Convert Gmail timestamp to time.Time
-		receivedAt := time.Unix(message.InternalDate/1000, 0)
-
-		email := model.NewEmail("", msg.Id, from, subject, body, receivedAt)
-		emails = append(emails, email)
 	}
 
-	g.logger.Info("Fetched", len(emails), "emails from Gmail")
-	return emails, nil
+	// Extract body
+	body := g.extractBody(message.Payload)
+
+	// Convert Gmail timestamp to time.Time
+	receivedAt := time.Unix(message.InternalDate/1000, 0)
+
+	email := model.NewEmail("", message.Id, from, subject, body, receivedAt)
+	email.To = to
+	email.Cc = cc
+	email.ReplyTo = replyTo
+	email.MessageID = rfcMessageID
+	email.SentAt = sentAt
+	email.SystemFlag = model.SystemFlagFromHeaders(systemHeaders)
+	// Gmail escapes the snippet as HTML
+	email.SetSnippet(html.UnescapeString(message.Snippet))
+	for _, label := range message.LabelIds {
+		switch label {
+		case model.LabelUnread:
+			email.Unread = true
+		case model.LabelStarred:
+			email.Starred = true
+		case model.LabelImportant:
+			email.Important = true
+		}
+	}
+	return email, nil
 }
 
 func (g *gmailClient) extractBody(payload *gmail.MessagePart) string {
@@ -137,8 +172,8 @@ func (g *gmailClient) extractBody(payload *gmail.MessagePart) string {
 	}
 
 	// If it's not multipart, try to get HTML content directly
-	if payload.MimeType == "text/html" && payload.Body.Data != "" {
-		decoded, err := base64.URLEncoding.DecodeString(payload.Body.Data)
+	if payload.MimeType == "text/html" && hasData(payload) {
+		decoded, err := decodeBodyData(payload.Body.Data)
 		if err != nil {
 			g.logger.Error("Failed to decode email body:", err)
 			return g.extractBodyAsText(payload) // fallback to text
@@ -152,53 +187,64 @@ func (g *gmailClient) extractBody(payload *gmail.MessagePart) string {
 
 // extractMultipartBody handles multipart messages to prioritize HTML content
 func (g *gmailClient) extractMultipartBody(parts []*gmail.MessagePart) string {
-	var htmlBody string
-	var textBody string
+	htmlBody, textBody := g.findBodies(parts)
+
+	// Prioritize HTML over text if both are available
+	if htmlBody != "" {
+		return htmlBody
+	}
+
+	if textBody != "" {
+		// Convert text to basic HTML if no HTML is available
+		return g.textToHtml(textBody)
+	}
+
+	// If we still don't have content, return fallback
+	return g.extractBodyAsText(&gmail.MessagePart{Parts: parts})
+}
 
+// findBodies walks the MIME tree depth first and returns the first HTML and the first plain
+// text body, wherever they are nested; attachments are skipped even when they are text
+func (g *gmailClient) findBodies(parts []*gmail.MessagePart) (htmlBody, textBody string) {
 	for _, part := range parts {
-		if part.MimeType == "text/html" && part.Body.Data != "" {
-			decoded, err := base64.URLEncoding.DecodeString(part.Body.Data)
+		if part.Filename != "" {
+			continue
+		}
+
+		switch {
+		case len(part.Parts) > 0:
+			nestedHTML, nestedText := g.findBodies(part.Parts)
+			if htmlBody == "" {
+				htmlBody = nestedHTML
+			}
+			if textBody == "" {
+				textBody = nestedText
+			}
+		case !hasData(part):
+			continue
+		case part.MimeType == "text/html" && htmlBody == "":
+			decoded, err := decodeBodyData(part.Body.Data)
 			if err != nil {
 				g.logger.Error("Failed to decode HTML email body:", err)
 				continue
 			}
 			htmlBody = string(decoded)
-			// Continue to check for other parts that might be needed
-		} else if part.MimeType == "text/plain" && part.Body.Data != "" {
-			decoded, err := base64.URLEncoding.DecodeString(part.Body.Data)
+		case part.MimeType == "text/plain" && textBody == "":
+			decoded, err := decodeBodyData(part.Body.Data)
 			if err != nil {
 				g.logger.Error("Failed to decode text email body:", err)
 				continue
 			}
 			textBody = string(decoded)
-		} else if len(part.Parts) > 0 {
-			// Handle nested multipart content
-			nestedBody := g.extractMultipartBody(part.Parts)
-			if nestedBody != "" && htmlBody == "" {
-				// If we haven't found HTML yet but found content in nested parts
-				return nestedBody
-			}
 		}
 	}
-
-	// Prioritize HTML over text if both are available
-	if htmlBody != "" {
-		return htmlBody
-	}
-
-	if textBody != "" {
-		// Convert text to basic HTML if no HTML is available
-		return g.textToHtml(textBody)
-	}
-
-	// If we still don't have content, return fallback
-	return g.extractBodyAsText(&gmail.MessagePart{Parts: parts})
+	return htmlBody, textBody
 }
 
 // extractBodyAsText extracts text content following the original logic
 func (g *gmailClient) extractBodyAsText(payload *gmail.MessagePart) string {
-	if payload.Body.Data != "" {
-		decoded, err := base64.URLEncoding.DecodeString(payload.Body.Data)
+	if hasData(payload) {
+		decoded, err := decodeBodyData(payload.Body.Data)
 		if err != nil {
 			g.logger.Error("Failed to decode email body:", err)
 			return ""
@@ -208,8 +254,8 @@ func (g *gmailClient) extractBodyAsText(payload *gmail.MessagePart) string {
 
 	// If it's a multipart message, look for the text/plain part
 	for _, part := range payload.Parts {
-		if part.MimeType == "text/plain" && part.Body.Data != "" {
-			decoded, err := base64.URLEncoding.DecodeString(part.Body.Data)
+		if part.MimeType == "text/plain" && part.Filename == "" && hasData(part) {
+			decoded, err := decodeBodyData(part.Body.Data)
 			if err != nil {
 				g.logger.Error("Failed to decode email body:", err)
 				continue
@@ -220,8 +266,8 @@ func (g *gmailClient) extractBodyAsText(payload *gmail.MessagePart) string {
 
 	// If no text/plain part found, return the first available body
 	for _, part := range payload.Parts {
-		if part.Body.Data != "" {
-			decoded, err := base64.URLEncoding.DecodeString(part.Body.Data)
+		if part.Filename == "" && hasData(part) {
+			decoded, err := decodeBodyData(part.Body.Data)
 			if err != nil {
 				g.logger.Error("Failed to decode email body:", err)
 				continue
@@ -233,6 +279,19 @@ func (g *gmailClient) extractBodyAsText(payload *gmail.MessagePart) string {
 	return ""
 }
 
+// hasData reports whether the part carries its body inline; container parts and large
+// attachments come without one
+func hasData(part *gmail.MessagePart) bool {
+	return part.Body != nil && part.Body.Data != ""
+}
+
+// decodeBodyData decodes a part body. Gmail sends base64url, usually padded, but unpadded
+// and line-wrapped data show up too, so both are accepted.
+func decodeBodyData(data string) ([]byte, error) {
+	data = strings.NewReplacer("\r", "", "\n", "", "=", "").Replace(data)
+	return base64.RawURLEncoding.DecodeString(data)
+}
+
 // textToHtml converts plain text to basic HTML formatting
 func (g *gmailClient) textToHtml(text string) string {
 	// Replace newlines with HTML paragraph breaks for basic formatting
@@ -253,49 +312,89 @@ func (g *gmailClient) textToHtml(text string) string {
 	return result
 }
 
-func (g *gmailClient) ArchiveEmail(ctx context.Context, userEmail, messageID string) error {
-	user := "me" // Use 'me' to refer to the authenticated user
-
-	// Modify the message to remove the 'INBOX' and 'UNREAD' labels (which archives it)
+func (g *gmailClient) Modify(ctx context.Context, userEmail, messageID string, add, remove []string) error {
 	modifyRequest := &gmail.ModifyMessageRequest{
-		RemoveLabelIds: []string{"INBOX", "UNREAD"},
-		AddLabelIds:    []string{}, // No additional labels to add
+		AddLabelIds:    add,
+		RemoveLabelIds: remove,
 	}
 
-	_, err := g.client.Users.Messages.Modify(user, messageID, modifyRequest).Do()
-	if err != nil {
-		return fmt.Errorf("failed to archive email: %w", err)
+	if _, err := g.client.Users.Messages.Modify("me", messageID, modifyRequest).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to modify the labels of email: %w", err)
 	}
 
-	g.logger.Info("Archived email:", messageID)
+	g.logger.Info("Modified the labels of email:", messageID, "added:", add, "removed:", remove)
 	return nil
 }
 
-func (g *gmailClient) MarkAsRead(ctx context.Context, userEmail, messageID string) error {
-	user := "me" // Use 'me' to refer to the authenticated user
+func (g *gmailClient) Trash(ctx context.Context, userEmail, messageID string) error {
+	if _, err := g.client.Users.Messages.Trash("me", messageID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to trash email: %w", err)
+	}
 
-	// Modify the message to remove the 'UNREAD' label
-	modifyRequest := &gmail.ModifyMessageRequest{
-		RemoveLabelIds: []string{"UNREAD"},
-		AddLabelIds:    []string{},
+	g.logger.Info("Trashed email:", messageID)
+	return nil
+}
+
+func (g *gmailClient) GetOrCreateLabel(ctx context.Context, userEmail, name string) (string, error) {
+	labels, err := g.client.Users.Labels.List("me").Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to list labels: %w", err)
+	}
+	for _, label := range labels.Labels {
+		if strings.EqualFold(label.Name, name) {
+			return label.Id, nil
+		}
+	}
+
+	created, err := g.client.Users.Labels.Create("me", &gmail.Label{
+		Name:                  name,
+		LabelListVisibility:   "labelShow",
+		MessageListVisibility: "show",
+	}).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create label: %w", err)
 	}
 
-	_, err := g.client.Users.Messages.Modify(user, messageID, modifyRequest).Do()
+	g.logger.Info("Created label:", name)
+	return created.Id, nil
+}
+
+func (g *gmailClient) Send(ctx context.Context, userEmail string, raw []byte) error {
+	message := &gmail.Message{Raw: base64.URLEncoding.EncodeToString(raw)}
+	sent, err := g.client.Users.Messages.Send("me", message).Context(ctx).Do()
 	if err != nil {
-		return fmt.Errorf("failed to mark email as read: %w", err)
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	g.logger.Info("Marked email as read:", messageID)
+	g.logger.Info("Sent email:", sent.Id)
 	return nil
 }
 
-func (g *gmailClient) DeleteEmails(ctx context.Context, userEmail string, messageIDs []string) error {
+func (g *gmailClient) Watch(ctx context.Context, userEmail, topic string) (*model.MailWatch, error) {
+	watchRequest := &gmail.WatchRequest{
+		TopicName: topic,
+		LabelIds:  []string{model.LabelInbox},
+	}
+
+	watched, err := g.client.Users.Watch("me", watchRequest).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch mailbox: %w", err)
+	}
+
+	g.logger.Info("Watching mailbox", userEmail, "on topic:", topic)
+	return &model.MailWatch{
+		HistoryID:  watched.HistoryId,
+		Expiration: time.UnixMilli(watched.Expiration),
+	}, nil
+}
+
+func (g *gmailClient) Delete(ctx context.Context, userEmail string, messageIDs []string) error {
 	user := "me" // Use 'me' to refer to the authenticated user
 
 	// Delete emails in batch to avoid making too many individual requests
 	for _, messageID := range messageIDs {
 		// Delete the email from Gmail
-		err := g.client.Users.Messages.Delete(user, messageID).Do()
+		err := g.client.Users.Messages.Delete(user, messageID).Context(ctx).Do()
 		if err != nil {
 			g.logger.Error("Failed to delete email from Gmail:", messageID, err)
 			// Continue with other emails even if one fails
@@ -2,54 +2,129 @@ package gmail
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
 )
 
-// MockGmailClient is a mock implementation of GmailClient for testing
+// MockGmailClient is a mock implementation of MailClient for testing
 type MockGmailClient struct {
-	SyncEmailsFunc       func(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error)
-	ArchiveEmailFunc     func(ctx context.Context, userEmail, messageID string) error
-	MarkAsReadFunc       func(ctx context.Context, userEmail, messageID string) error
-	DeleteEmailsFunc     func(ctx context.Context, userEmail string, messageIDs []string) error
+	// MessagesFunc stands for the user's mailbox when ListFunc and GetFunc aren't set: List
+	// returns the IDs of the emails it returns, and Get those emails
+	MessagesFunc         func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error)
+	ListFunc             func(ctx context.Context, userEmail string, maxResults int64) ([]string, error)
+	GetFunc              func(ctx context.Context, userEmail, messageID string) (*model.Email, error)
+	ModifyFunc           func(ctx context.Context, userEmail, messageID string, add, remove []string) error
+	TrashFunc            func(ctx context.Context, userEmail, messageID string) error
+	DeleteFunc           func(ctx context.Context, userEmail string, messageIDs []string) error
+	SendFunc             func(ctx context.Context, userEmail string, raw []byte) error
+	WatchFunc            func(ctx context.Context, userEmail, topic string) (*model.MailWatch, error)
+	GetOrCreateLabelFunc func(ctx context.Context, userEmail, name string) (string, error)
+
+	mutex  sync.Mutex
+	listed map[string]map[string]*model.Email // the emails MessagesFunc returned, by user and Gmail ID
 }
 
+var _ service.MailClient = (*MockGmailClient)(nil)
+
 func NewMockGmailClient() *MockGmailClient {
 	return &MockGmailClient{}
 }
 
-func (m *MockGmailClient) SyncEmails(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
-	if m.SyncEmailsFunc != nil {
-		return m.SyncEmailsFunc(ctx, userEmail, maxResults, afterEmailID)
+func (m *MockGmailClient) List(ctx context.Context, userEmail string, maxResults int64) ([]string, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, userEmail, maxResults)
+	}
+	if m.MessagesFunc == nil {
+		// Default mock behavior: an empty mailbox
+		return []string{}, nil
+	}
+
+	emails, err := m.MessagesFunc(ctx, userEmail, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.listed == nil {
+		m.listed = make(map[string]map[string]*model.Email)
+	}
+	m.listed[userEmail] = make(map[string]*model.Email, len(emails))
+	messageIDs := make([]string, len(emails))
+	for i, email := range emails {
+		m.listed[userEmail][email.GmailID] = email
+		messageIDs[i] = email.GmailID
+	}
+	return messageIDs, nil
+}
+
+func (m *MockGmailClient) Get(ctx context.Context, userEmail, messageID string) (*model.Email, error) {
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, userEmail, messageID)
+	}
+
+	// Default mock behavior: the email MessagesFunc listed
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if email, ok := m.listed[userEmail][messageID]; ok {
+		return email, nil
+	}
+	return nil, fmt.Errorf("message %s not found", messageID)
+}
+
+func (m *MockGmailClient) Modify(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+	if m.ModifyFunc != nil {
+		return m.ModifyFunc(ctx, userEmail, messageID, add, remove)
 	}
-	
-	// Default mock behavior: return an empty list
-	return []*model.Email{}, nil
+
+	// Default mock behavior: success
+	return nil
 }
 
-func (m *MockGmailClient) ArchiveEmail(ctx context.Context, userEmail, messageID string) error {
-	if m.ArchiveEmailFunc != nil {
-		return m.ArchiveEmailFunc(ctx, userEmail, messageID)
+func (m *MockGmailClient) Trash(ctx context.Context, userEmail, messageID string) error {
+	if m.TrashFunc != nil {
+		return m.TrashFunc(ctx, userEmail, messageID)
 	}
-	
+
 	// Default mock behavior: success
 	return nil
 }
 
-func (m *MockGmailClient) MarkAsRead(ctx context.Context, userEmail, messageID string) error {
-	if m.MarkAsReadFunc != nil {
-		return m.MarkAsReadFunc(ctx, userEmail, messageID)
+func (m *MockGmailClient) Delete(ctx context.Context, userEmail string, messageIDs []string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, userEmail, messageIDs)
 	}
-	
+
 	// Default mock behavior: success
 	return nil
 }
 
-func (m *MockGmailClient) DeleteEmails(ctx context.Context, userEmail string, messageIDs []string) error {
-	if m.DeleteEmailsFunc != nil {
-		return m.DeleteEmailsFunc(ctx, userEmail, messageIDs)
+func (m *MockGmailClient) Send(ctx context.Context, userEmail string, raw []byte) error {
+	if m.SendFunc != nil {
+		return m.SendFunc(ctx, userEmail, raw)
 	}
-	
+
 	// Default mock behavior: success
 	return nil
-}
\ No newline at end of file
+}
+
+func (m *MockGmailClient) Watch(ctx context.Context, userEmail, topic string) (*model.MailWatch, error) {
+	if m.WatchFunc != nil {
+		return m.WatchFunc(ctx, userEmail, topic)
+	}
+
+	// Default mock behavior: a watch of a week, Gmail's longest
+	return &model.MailWatch{HistoryID: 1, Expiration: time.Now().Add(7 * 24 * time.Hour)}, nil
+}
+
+func (m *MockGmailClient) GetOrCreateLabel(ctx context.Context, userEmail, name string) (string, error) {
+	if m.GetOrCreateLabelFunc != nil {
+		return m.GetOrCreateLabelFunc(ctx, userEmail, name)
+	}
+
+	// Default mock behavior: use the name as the label ID
+	return name, nil
+}
@@ -0,0 +1,81 @@
+package gmail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/service"
+)
+
+// TokenRefresher trades users' refresh tokens for new access tokens at Google's token endpoint
+type TokenRefresher struct {
+	clientID     string
+	clientSecret string
+	tokenURL     string
+	httpClient   *http.Client
+}
+
+func NewTokenRefresher(clientID, clientSecret, tokenURL string) *TokenRefresher {
+	return &TokenRefresher{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		tokenURL:     tokenURL,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Refresh returns a new access token and when it expires. It fails with
+// service.ErrGmailReauthRequired when Google answers invalid_grant, as it does once the user
+// revoked the app's access or the refresh token expired, and with service.ErrGmailUnreachable
+// when Google can't be reached.
+func (r *TokenRefresher) Refresh(ctx context.Context, refreshToken string) (string, time.Time, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {r.clientID},
+		"client_secret": {r.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", time.Time{}, err
+		}
+		return "", time.Time{}, fmt.Errorf("failed to refresh access token: %w: %v", service.ErrGmailUnreachable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return "", time.Time{}, fmt.Errorf("failed to refresh access token: %w: %s", service.ErrGmailUnreachable, resp.Status)
+	}
+
+	var answer struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"` // seconds
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return "", time.Time{}, fmt.Errorf("unexpected answer from Google, status %d", resp.StatusCode)
+	}
+	if answer.Error == "invalid_grant" {
+		return "", time.Time{}, fmt.Errorf("failed to refresh access token: %w: %s", service.ErrGmailReauthRequired, answer.Description)
+	}
+	if answer.Error != "" || answer.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("failed to refresh access token, status %d: %s %s", resp.StatusCode, answer.Error, answer.Description)
+	}
+
+	var expiry time.Time
+	if answer.ExpiresIn > 0 {
+		expiry = time.Now().Add(time.Duration(answer.ExpiresIn) * time.Second)
+	}
+	return answer.AccessToken, expiry, nil
+}
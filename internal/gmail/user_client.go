@@ -0,0 +1,269 @@
+package gmail
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/service"
+)
+
+// DefaultClientCacheSize is how many users' Gmail clients a UserClient keeps by default
+const DefaultClientCacheSize = 1000
+
+// tokenExpiryMargin refreshes access tokens a little before they expire, so a call made just
+// before doesn't reach Gmail with an expired one
+const tokenExpiryMargin = time.Minute
+
+// UserClient calls Gmail as the user whose address it is given, with their stored access token.
+// The Gmail clients of the most recently used users are kept, so a sync or bulk action doesn't
+// look the user up and build a client for every call.
+//
+// With a TokenRefresher, expired access tokens are refreshed and saved, and a call Gmail answers
+// with 401 is retried once with a refreshed token. When Google refuses the refresh, or the user
+// has no refresh token, the call fails with service.ErrGmailReauthRequired.
+type UserClient struct {
+	userRepo       repository.UserRepository
+	maxFetchEmails int64  // used when List isn't told how many messages to list
+	endpoint       string // the Gmail API's, empty for Google's
+	refresher      *TokenRefresher
+	logger         *logger.Logger
+
+	mutex    sync.Mutex
+	capacity int
+	recent   *list.List               // of *cachedClient, most recently used first
+	byUserID map[string]*list.Element // the cached clients by user ID
+	byEmail  map[string]*list.Element // and by the address they were looked up with
+}
+
+var _ service.MailClient = (*UserClient)(nil)
+
+// cachedClient is a Gmail client authorized with the access token the user had when it was built
+type cachedClient struct {
+	userID string
+	email  string
+	expiry time.Time // of the access token, zero when unknown
+	client service.MailClient
+}
+
+func NewUserClient(userRepo repository.UserRepository, maxFetchEmails int64, logger *logger.Logger) *UserClient {
+	return NewUserClientWithEndpoint(userRepo, maxFetchEmails, "", logger)
+}
+
+// NewUserClientWithEndpoint talks to the Gmail API at endpoint instead of Google's, e.g. a fake
+// server in tests; an empty endpoint uses the default
+func NewUserClientWithEndpoint(userRepo repository.UserRepository, maxFetchEmails int64, endpoint string, logger *logger.Logger) *UserClient {
+	return &UserClient{
+		userRepo:       userRepo,
+		maxFetchEmails: maxFetchEmails,
+		endpoint:       endpoint,
+		logger:         logger,
+		capacity:       DefaultClientCacheSize,
+		recent:         list.New(),
+		byUserID:       make(map[string]*list.Element),
+		byEmail:        make(map[string]*list.Element),
+	}
+}
+
+// SetCacheSize sets how many users' clients are kept, evicting the least recently used ones
+func (u *UserClient) SetCacheSize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.capacity = size
+	u.evict()
+}
+
+// UseTokenRefresh refreshes expired or refused access tokens with the refresher
+func (u *UserClient) UseTokenRefresh(refresher *TokenRefresher) {
+	u.refresher = refresher
+}
+
+// Invalidate drops the user's cached client, so the next call uses the access token stored then.
+// Called when the user's tokens are replaced.
+func (u *UserClient) Invalidate(userID string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if element, ok := u.byUserID[userID]; ok {
+		u.remove(element)
+	}
+}
+
+// do makes the call with a client authorized as the user. A call refused with 401 before the
+// token expired, e.g. because the user revoked the app's access, is retried once with a
+// refreshed token; the refresh tells whether the grant still holds.
+func (u *UserClient) do(ctx context.Context, userEmail string, call func(gmailClient service.MailClient) error) error {
+	gmailClient, err := u.clientFor(ctx, userEmail, false)
+	if err != nil {
+		return err
+	}
+	err = call(gmailClient)
+	if u.refresher == nil || !errors.Is(err, service.ErrGmailUnauthorized) {
+		return err
+	}
+
+	if gmailClient, err = u.clientFor(ctx, userEmail, true); err != nil {
+		return err
+	}
+	return call(gmailClient)
+}
+
+// clientFor returns a Gmail client authorized with the user's access token, the cached one
+// unless the token it was built with has expired since or refresh is set. The token is
+// refreshed first when it has expired, or when refresh is set.
+func (u *UserClient) clientFor(ctx context.Context, userEmail string, refresh bool) (service.MailClient, error) {
+	u.mutex.Lock()
+	if element, ok := u.byEmail[userEmail]; ok {
+		cached := element.Value.(*cachedClient)
+		if !refresh && (cached.expiry.IsZero() || time.Now().Add(tokenExpiryMargin).Before(cached.expiry)) {
+			u.recent.MoveToFront(element)
+			u.mutex.Unlock()
+			return cached.client, nil
+		}
+		u.remove(element)
+	}
+	u.mutex.Unlock()
+
+	// Find user by email to get their access token
+	user, err := u.userRepo.FindByEmail(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("user not found or access token not available for email: %s", userEmail)
+	}
+
+	expired := !user.TokenExpiry.IsZero() && time.Now().Add(tokenExpiryMargin).After(user.TokenExpiry)
+	if u.refresher != nil && (refresh || expired) {
+		if err := u.refresh(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.AccessToken == "" {
+		return nil, fmt.Errorf("access token not available for user: %s", userEmail)
+	}
+
+	// Create Gmail client with user's access token
+	gmailClient, err := NewGmailClientWithEndpoint(user.AccessToken, u.endpoint, u.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gmail client: %w", err)
+	}
+	u.store(user, userEmail, gmailClient)
+	return gmailClient, nil
+}
+
+// refresh trades the user's refresh token for a new access token and saves it
+func (u *UserClient) refresh(ctx context.Context, user *model.User) error {
+	if user.RefreshToken == "" {
+		return fmt.Errorf("%w: no refresh token stored for user %s", service.ErrGmailReauthRequired, user.ID)
+	}
+	accessToken, expiry, err := u.refresher.Refresh(ctx, user.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	user.AccessToken = accessToken
+	user.TokenExpiry = expiry
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to save refreshed access token: %w", err)
+	}
+	u.logger.Info("Refreshed the access token of user", user.ID)
+	return nil
+}
+
+func (u *UserClient) store(user *model.User, userEmail string, client service.MailClient) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if element, ok := u.byUserID[user.ID]; ok {
+		u.remove(element)
+	}
+	element := u.recent.PushFront(&cachedClient{userID: user.ID, email: userEmail, expiry: user.TokenExpiry, client: client})
+	u.byUserID[user.ID] = element
+	u.byEmail[userEmail] = element
+	u.evict()
+}
+
+// evict drops the least recently used clients past the capacity; the mutex must be held
+func (u *UserClient) evict() {
+	for u.recent.Len() > u.capacity {
+		u.remove(u.recent.Back())
+	}
+}
+
+// remove drops a cached client; the mutex must be held
+func (u *UserClient) remove(element *list.Element) {
+	cached := u.recent.Remove(element).(*cachedClient)
+	delete(u.byUserID, cached.userID)
+	if u.byEmail[cached.email] == element {
+		delete(u.byEmail, cached.email)
+	}
+}
+
+func (u *UserClient) List(ctx context.Context, userEmail string, maxResults int64) ([]string, error) {
+	if maxResults <= 0 {
+		maxResults = u.maxFetchEmails
+	}
+	var messageIDs []string
+	err := u.do(ctx, userEmail, func(gmailClient service.MailClient) (err error) {
+		messageIDs, err = gmailClient.List(ctx, userEmail, maxResults)
+		return err
+	})
+	return messageIDs, err
+}
+
+func (u *UserClient) Get(ctx context.Context, userEmail, messageID string) (*model.Email, error) {
+	var email *model.Email
+	err := u.do(ctx, userEmail, func(gmailClient service.MailClient) (err error) {
+		email, err = gmailClient.Get(ctx, userEmail, messageID)
+		return err
+	})
+	return email, err
+}
+
+func (u *UserClient) Modify(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+	return u.do(ctx, userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Modify(ctx, userEmail, messageID, add, remove)
+	})
+}
+
+func (u *UserClient) Trash(ctx context.Context, userEmail, messageID string) error {
+	return u.do(ctx, userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Trash(ctx, userEmail, messageID)
+	})
+}
+
+func (u *UserClient) Delete(ctx context.Context, userEmail string, messageIDs []string) error {
+	return u.do(ctx, userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Delete(ctx, userEmail, messageIDs)
+	})
+}
+
+func (u *UserClient) Send(ctx context.Context, userEmail string, raw []byte) error {
+	return u.do(ctx, userEmail, func(gmailClient service.MailClient) error {
+		return gmailClient.Send(ctx, userEmail, raw)
+	})
+}
+
+func (u *UserClient) Watch(ctx context.Context, userEmail, topic string) (*model.MailWatch, error) {
+	var watch *model.MailWatch
+	err := u.do(ctx, userEmail, func(gmailClient service.MailClient) (err error) {
+		watch, err = gmailClient.Watch(ctx, userEmail, topic)
+		return err
+	})
+	return watch, err
+}
+
+func (u *UserClient) GetOrCreateLabel(ctx context.Context, userEmail, name string) (string, error) {
+	var labelID string
+	err := u.do(ctx, userEmail, func(gmailClient service.MailClient) (err error) {
+		labelID, err = gmailClient.GetOrCreateLabel(ctx, userEmail, name)
+		return err
+	})
+	return labelID, err
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AccountMergeHandler lets a user fold a duplicate account into the one they keep
+type AccountMergeHandler struct {
+	mergeService service.AccountMergeService
+	authHandler  *AuthHandler
+	logger       echo.Logger
+}
+
+func NewAccountMergeHandler(mergeService service.AccountMergeService, authHandler *AuthHandler, logger echo.Logger) *AccountMergeHandler {
+	return &AccountMergeHandler{
+		mergeService: mergeService,
+		authHandler:  authHandler,
+		logger:       logger,
+	}
+}
+
+// CreateMergeCode returns a code, for the duplicate account the user is signed in to, which
+// they then redeem signed in to the account to keep
+func (h *AccountMergeHandler) CreateMergeCode(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	code, err := h.mergeService.CreateMergeCode(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to create merge code:", err)
+		return apierror.From(err, "Failed to create merge code")
+	}
+
+	return c.JSON(http.StatusCreated, code)
+}
+
+// Merge moves the data of the account the code was created in to the user's and deletes it
+func (h *AccountMergeHandler) Merge(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req AccountMergeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	merge, err := h.mergeService.Merge(c.Request().Context(), user.ID, req.Code)
+	if err != nil {
+		h.logger.Error("Failed to merge accounts:", err)
+		return apierror.From(err, "Failed to merge accounts")
+	}
+
+	return c.JSON(http.StatusOK, merge)
+}
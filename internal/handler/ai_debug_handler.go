@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AIDebugHandler lets operators look into how the AI classifies: they inspect and replay the
+// calls recorded in debug mode and compare the arms of classification experiments
+type AIDebugHandler struct {
+	aiDebug     service.AIDebugService
+	experiments service.ExperimentService
+	authHandler *AuthHandler
+	logger      echo.Logger
+}
+
+func NewAIDebugHandler(aiDebug service.AIDebugService, experiments service.ExperimentService, authHandler *AuthHandler, logger echo.Logger) *AIDebugHandler {
+	return &AIDebugHandler{
+		aiDebug:     aiDebug,
+		experiments: experiments,
+		authHandler: authHandler,
+		logger:      logger,
+	}
+}
+
+// requireOperator rejects users not listed in ADMIN_EMAILS, as recorded calls hold other
+// users' emails and experiments span every user
+func (h *AIDebugHandler) requireOperator(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	if !h.authHandler.IsAdmin(user) {
+		return apierror.Forbidden("Only operators can debug the AI")
+	}
+	return nil
+}
+
+// GetCalls lists the recorded AI calls, newest first
+func (h *AIDebugHandler) GetCalls(c echo.Context) error {
+	if err := h.requireOperator(c); err != nil {
+		return err
+	}
+
+	var query AICallsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	calls, err := h.aiDebug.GetCalls(c.Request().Context(), query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get AI calls:", err)
+		return apierror.From(err, "Failed to get AI calls")
+	}
+
+	return c.JSON(http.StatusOK, calls)
+}
+
+// GetCall returns a recorded AI call with its prompt and answer
+func (h *AIDebugHandler) GetCall(c echo.Context) error {
+	if err := h.requireOperator(c); err != nil {
+		return err
+	}
+
+	call, err := h.aiDebug.GetCall(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return apierror.From(err, "Failed to get AI call")
+	}
+
+	return c.JSON(http.StatusOK, call)
+}
+
+// ReplayCall sends a recorded prompt to another provider or model for comparison
+func (h *AIDebugHandler) ReplayCall(c echo.Context) error {
+	if err := h.requireOperator(c); err != nil {
+		return err
+	}
+
+	var req ReplayAICallRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	replay, err := h.aiDebug.Replay(c.Request().Context(), c.Param("id"), req.Provider, req.Model)
+	if err != nil {
+		h.logger.Error("Failed to replay AI call:", err)
+		return apierror.From(err, "Failed to replay AI call")
+	}
+
+	return c.JSON(http.StatusOK, replay)
+}
+
+// GetExperimentReport compares the arms of a classification experiment by how often users
+// corrected them
+func (h *AIDebugHandler) GetExperimentReport(c echo.Context) error {
+	if err := h.requireOperator(c); err != nil {
+		return err
+	}
+
+	var query ExperimentReportQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	report, err := h.experiments.Report(c.Request().Context(), query.Name)
+	if err != nil {
+		h.logger.Error("Failed to get experiment report:", err)
+		return apierror.From(err, "Failed to get experiment report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
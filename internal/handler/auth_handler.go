@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"jump-challenge/internal/apierror"
 	"jump-challenge/internal/config"
 	"jump-challenge/internal/model"
 	"jump-challenge/internal/service"
@@ -15,27 +18,74 @@ import (
 	"github.com/markbates/goth/providers/google"
 )
 
+const sessionName = "gothic_session"
+
+// sessionRefreshInterval bounds how often the sliding expiry is written back to the session
+const sessionRefreshInterval = time.Minute
+
+// Why a session doesn't authenticate anyone; other errors of GetCurrentUser, e.g. the database
+// being unreachable, say nothing about the session
+var (
+	errNotSignedIn    = errors.New("user not authenticated")
+	errSessionExpired = errors.New("session expired")
+	errUserGone       = errors.New("user no longer exists")
+	errGrantRevoked   = errors.New("google grant revoked")
+)
+
+// providerScopes lists the OAuth scopes requested by each goth provider
+var providerScopes = map[string][]string{
+	"google": {
+		model.ScopeGmailReadonly,
+		"https://www.googleapis.com/auth/userinfo.email",
+		"https://www.googleapis.com/auth/userinfo.profile",
+	},
+	"google-modify": {
+		model.ScopeGmailReadonly,
+		model.ScopeGmailModify,
+		"https://www.googleapis.com/auth/userinfo.email",
+		"https://www.googleapis.com/auth/userinfo.profile",
+	},
+}
+
+// ReauthURL starts the re-consent flow that grants Gmail modify access
+const ReauthURL = "/auth/google-modify"
+
+// LoginURL starts the sign-in with Google, granting Gmail read access
+const LoginURL = "/auth/google"
+
+// SignInAgainURL starts the sign-in of a user whose grant Google refused, asking again for
+// the access they had granted
+func SignInAgainURL(user *model.User) string {
+	if user.HasScope(model.ScopeGmailModify) {
+		return ReauthURL
+	}
+	return LoginURL
+}
+
 type AuthHandler struct {
-	authService service.AuthService
-	config      *config.Config
-	logger      echo.Logger
+	authService  service.AuthService
+	emailService service.EmailService // reports Gmail's health in /me; nil leaves it out
+	config       *config.Config
+	logger       echo.Logger
 }
 
 func NewAuthHandler(authService service.AuthService, config *config.Config, logger echo.Logger) *AuthHandler {
-	// Set up goth with Google provider
-	gothic.Store = sessions.NewFilesystemStore("", []byte(config.SessionSecret))
-
-	goth.UseProviders(
-		google.New(
-			config.GoogleClientID,
-			config.GoogleClientSecret,
-			config.BaseURL+"/auth/google/callback",
-			"https://www.googleapis.com/auth/gmail.readonly",
-			"https://www.googleapis.com/auth/gmail.modify",
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		),
-	)
+	// Set up goth with Google provider; the cookie lives as long as the session TTL
+	store := sessions.NewFilesystemStore("", []byte(config.SessionSecret))
+	store.MaxAge(int(config.SessionTTL.Seconds()))
+	gothic.Store = store
+
+	// Login asks for read access only; "google-modify" is the re-consent flow used
+	// the first time the user runs an action that changes Gmail
+	readonly := google.New(config.GoogleClientID, config.GoogleClientSecret,
+		config.BaseURL+"/auth/google/callback", providerScopes["google"]...)
+
+	modify := google.New(config.GoogleClientID, config.GoogleClientSecret,
+		config.BaseURL+"/auth/google-modify/callback", providerScopes["google-modify"]...)
+	modify.SetName("google-modify")
+	modify.SetPrompt("consent")
+
+	goth.UseProviders(readonly, modify)
 
 	return &AuthHandler{
 		authService: authService,
@@ -48,42 +98,53 @@ func NewAuthHandler(authService service.AuthService, config *config.Config, logg
 func (h *AuthHandler) BeginAuthHandler(c echo.Context) error {
 	// Manually handle the provider parameter for Goth
 	provider := c.Param("provider")
-	if provider != "google" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid provider",
-		})
+	if _, ok := providerScopes[provider]; !ok {
+		return apierror.Validation("Invalid provider")
 	}
 
 	// Set provider in the request URL so Goth can recognize it
 	req := c.Request()
 	q := req.URL.Query()
-	q.Set("provider", "google")
+	q.Set("provider", provider)
 	req.URL.RawQuery = q.Encode()
 
+	// The login page passes the browser's time zone, applied once the user is known
+	if tz := c.QueryParam("tz"); tz != "" {
+		session, _ := gothic.Store.Get(req, sessionName)
+		session.Values["time_zone"] = tz
+		if err := session.Save(req, c.Response()); err != nil {
+			h.logger.Error("Failed to save detected time zone:", err)
+		}
+	}
+
 	gothic.BeginAuthHandler(c.Response(), req)
 	return nil
 }
 
 // CallbackHandler handles the OAuth callback
 func (h *AuthHandler) CallbackHandler(c echo.Context) error {
+	provider := c.Param("provider")
+	scopes, ok := providerScopes[provider]
+	if !ok {
+		return apierror.Validation("Invalid provider")
+	}
+
 	// Set provider in the request URL so Goth can recognize it
 	req := c.Request()
 	q := req.URL.Query()
-	q.Set("provider", "google")
+	q.Set("provider", provider)
 	req.URL.RawQuery = q.Encode()
 
 	googleUser, err := gothic.CompleteUserAuth(c.Response(), req)
 	if err != nil {
 		h.logger.Error("Failed to complete user auth:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Authentication failed",
-		})
+		return apierror.From(err, "Authentication failed")
 	}
 
-	// Get or create user in our database
+	// Get or create user in our database; both providers map to the same Google account
 	user, err := h.authService.GetOrCreateUser(
 		c.Request().Context(),
-		googleUser.Provider+"_"+googleUser.UserID, // Creating a unique ID with provider prefix
+		"google_"+googleUser.UserID, // Creating a unique ID with provider prefix
 		googleUser.Email,
 		googleUser.Name,
 		googleUser.AccessToken,
@@ -92,19 +153,30 @@ func (h *AuthHandler) CallbackHandler(c echo.Context) error {
 	)
 	if err != nil {
 		h.logger.Error("Failed to get or create user:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to process user",
-		})
+		return apierror.From(err, "Failed to process user")
 	}
 
-	// Set user ID in session
-	session, _ := gothic.Store.Get(req, "gothic_session")
+	// The new token carries exactly the scopes this provider asked for
+	if user, err = h.authService.SetGrantedScopes(c.Request().Context(), user.ID, scopes); err != nil {
+		h.logger.Error("Failed to record granted scopes:", err)
+		return apierror.From(err, "Failed to process user")
+	}
+
+	// Set user ID and expiry in session
+	session, _ := gothic.Store.Get(req, sessionName)
+	if tz, ok := session.Values["time_zone"].(string); ok {
+		if err := h.authService.DetectTimeZone(c.Request().Context(), user.ID, tz); err != nil {
+			h.logger.Error("Failed to record detected time zone:", err)
+		}
+		delete(session.Values, "time_zone")
+	}
+	now := time.Now()
 	session.Values["user_id"] = user.ID
+	session.Values["issued_at"] = now.Unix()
+	session.Values["expires_at"] = now.Add(h.config.SessionTTL).Unix()
 	if err := session.Save(req, c.Response()); err != nil {
 		h.logger.Error("Failed to save session:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to save session",
-		})
+		return apierror.From(err, "Failed to save session")
 	}
 
 	// Redirect to the app page
@@ -127,20 +199,218 @@ func (h *AuthHandler) LogoutHandler(c echo.Context) error {
 
 // GetCurrentUser returns the current authenticated user
 func (h *AuthHandler) GetCurrentUser(c echo.Context) (*model.User, error) {
-	session, err := gothic.Store.Get(c.Request(), "gothic_session")
+	session, err := gothic.Store.Get(c.Request(), sessionName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		// The cookie can't be decoded, e.g. it was signed with another secret
+		return nil, fmt.Errorf("%w: failed to get session: %v", errNotSignedIn, err)
 	}
 
 	userID, ok := session.Values["user_id"].(string)
 	if !ok {
-		return nil, fmt.Errorf("user not authenticated")
+		return nil, errNotSignedIn
+	}
+
+	if expiresAt, ok := session.Values["expires_at"].(int64); ok && time.Now().Unix() >= expiresAt {
+		return nil, errSessionExpired
 	}
 
 	user, err := h.authService.GetUser(c.Request().Context(), userID)
+	if errors.Is(err, apierror.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", errUserGone, userID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user from database: %w", err)
 	}
 
+	if !user.HasGoogleGrant() {
+		return nil, fmt.Errorf("%w for user: %s", errGrantRevoked, userID)
+	}
+
+	return user, nil
+}
+
+// IsAdmin reports whether the user is an operator, listed in ADMIN_EMAILS
+func (h *AuthHandler) IsAdmin(user *model.User) bool {
+	return h.config.IsAdmin(user.Email)
+}
+
+// ValidateSession authenticates the request, clearing sessions that expired or whose user
+// is gone or revoked, and slides the expiry forward for active sessions. It fails with
+// apierror.Unauthorized when the session doesn't authenticate anyone; any other error, e.g.
+// the database timing out, is returned as it is and leaves the session in place.
+func (h *AuthHandler) ValidateSession(c echo.Context) (*model.User, error) {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		if !signedOut(err) {
+			return nil, err
+		}
+		h.invalidateSession(c)
+		return nil, apierror.Unauthorized("Unauthorized")
+	}
+
+	session, err := gothic.Store.Get(c.Request(), sessionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Sessions created before expiry tracking get one on first use
+	now := time.Now()
+	refreshedAt, _ := session.Values["refreshed_at"].(int64)
+	if now.Sub(time.Unix(refreshedAt, 0)) >= sessionRefreshInterval {
+		session.Values["refreshed_at"] = now.Unix()
+		session.Values["expires_at"] = now.Add(h.config.SessionTTL).Unix()
+		if err := session.Save(c.Request(), c.Response()); err != nil {
+			h.logger.Error("Failed to refresh session:", err)
+		}
+	}
+
 	return user, nil
 }
+
+// signedOut reports whether the error of GetCurrentUser means the session can't be used again
+func signedOut(err error) bool {
+	return errors.Is(err, errNotSignedIn) || errors.Is(err, errSessionExpired) ||
+		errors.Is(err, errUserGone) || errors.Is(err, errGrantRevoked)
+}
+
+// invalidateSession deletes the session so a stale cookie stops being presented
+func (h *AuthHandler) invalidateSession(c echo.Context) {
+	session, err := gothic.Store.Get(c.Request(), sessionName)
+	if err != nil || session.IsNew {
+		return
+	}
+
+	session.Options.MaxAge = -1
+	if err := session.Save(c.Request(), c.Response()); err != nil {
+		h.logger.Error("Failed to invalidate session:", err)
+	}
+}
+
+// UseGmailHealth adds to /me whether Gmail can be reached for the user
+func (h *AuthHandler) UseGmailHealth(emailService service.EmailService) {
+	h.emailService = emailService
+}
+
+// Me returns the authenticated user, the state of their session and of their Gmail
+func (h *AuthHandler) Me(c echo.Context) error {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	session, err := gothic.Store.Get(c.Request(), sessionName)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	state := SessionState{
+		TTLSeconds: int64(h.config.SessionTTL.Seconds()),
+	}
+	if issuedAt, ok := session.Values["issued_at"].(int64); ok {
+		t := time.Unix(issuedAt, 0)
+		state.IssuedAt = &t
+	}
+	if expiresAt, ok := session.Values["expires_at"].(int64); ok {
+		t := time.Unix(expiresAt, 0)
+		state.ExpiresAt = &t
+	}
+
+	var gmailHealth *model.GmailHealth
+	if h.emailService != nil {
+		if gmailHealth, err = h.emailService.GetGmailHealth(c.Request().Context(), user.ID); err != nil {
+			h.logger.Error("Failed to get Gmail health:", err)
+			return apierror.From(err, "Failed to get Gmail health")
+		}
+	}
+
+	// Only expose profile fields; the user model also carries OAuth tokens
+	return c.JSON(http.StatusOK, MeResponse{
+		User: CurrentUser{
+			ID:             user.ID,
+			Email:          user.Email,
+			Name:           user.Name,
+			CanModify:      user.HasScope(model.ScopeGmailModify),
+			TimeZone:       user.TimeZone,
+			Locale:         user.Locale,
+			OnboardingStep: user.Onboarding(),
+			NeedsReauth:    user.NeedsReauth,
+		},
+		Session: state,
+		Gmail:   gmailHealth,
+		Banner:  banner(user),
+	})
+}
+
+// banner returns the notice the frontend shows the user on every page, nil when there is none
+func banner(user *model.User) *Banner {
+	if !user.NeedsReauth {
+		return nil
+	}
+	return &Banner{
+		Type:      model.EventReauth,
+		Message:   "Google access was revoked. Sign in again to keep syncing your emails.",
+		ActionURL: SignInAgainURL(user),
+	}
+}
+
+// GetTimeZone returns the time zone the user's schedules follow
+func (h *AuthHandler) GetTimeZone(c echo.Context) error {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	return c.JSON(http.StatusOK, TimeZoneSettings{TimeZone: user.TimeZone})
+}
+
+// UpdateTimeZone sets the time zone the user's schedules follow, overriding the one detected at login
+func (h *AuthHandler) UpdateTimeZone(c echo.Context) error {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req TimeZoneSettings
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	user, err = h.authService.SetTimeZone(c.Request().Context(), user.ID, req.TimeZone)
+	if err != nil {
+		h.logger.Error("Failed to update time zone:", err)
+		return apierror.From(err, "Failed to update time zone")
+	}
+
+	return c.JSON(http.StatusOK, TimeZoneSettings{TimeZone: user.TimeZone})
+}
+
+// GetLocale returns the language of the user's API messages and AI summaries
+func (h *AuthHandler) GetLocale(c echo.Context) error {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	return c.JSON(http.StatusOK, LocaleSettings{Locale: user.Locale})
+}
+
+// UpdateLocale sets the language of the user's API messages and AI summaries
+func (h *AuthHandler) UpdateLocale(c echo.Context) error {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req LocaleSettings
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	user, err = h.authService.SetLocale(c.Request().Context(), user.ID, req.Locale)
+	if err != nil {
+		h.logger.Error("Failed to update locale:", err)
+		return apierror.From(err, "Failed to update locale")
+	}
+
+	return c.JSON(http.StatusOK, LocaleSettings{Locale: user.Locale})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AutomationHandler struct {
+	automationService service.AutomationService
+	authHandler       *AuthHandler
+	logger            echo.Logger
+}
+
+func NewAutomationHandler(automationService service.AutomationService, authHandler *AuthHandler, logger echo.Logger) *AutomationHandler {
+	return &AutomationHandler{
+		automationService: automationService,
+		authHandler:       authHandler,
+		logger:            logger,
+	}
+}
+
+// CreateAutomation attaches an automatic action to one of the user's categories
+func (h *AutomationHandler) CreateAutomation(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req AutomationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	create := h.automationService.CreateAutomation
+	if req.Team {
+		create = h.automationService.CreateTeamAutomation
+	}
+	automation, err := create(c.Request().Context(), user.ID, req.CategoryID, req.Action, req.Label, req.DelayDays)
+	if err != nil {
+		h.logger.Error("Failed to create automation:", err)
+		return apierror.From(err, "Failed to create automation")
+	}
+
+	return c.JSON(http.StatusCreated, automation)
+}
+
+// GetAutomations lists the user's automations along with their organization's
+func (h *AutomationHandler) GetAutomations(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	automations, err := h.automationService.GetAutomations(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get automations:", err)
+		return apierror.From(err, "Failed to get automations")
+	}
+
+	return c.JSON(http.StatusOK, automations)
+}
+
+// UpdateAutomation replaces one of the user's automations
+func (h *AutomationHandler) UpdateAutomation(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req UpdateAutomationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	// Automations are enabled unless the client says otherwise
+	enabled := req.Enabled == nil || *req.Enabled
+	automation, err := h.automationService.UpdateAutomation(c.Request().Context(), user.ID, c.Param("id"), req.CategoryID, req.Action, req.Label, req.DelayDays, enabled)
+	if err != nil {
+		h.logger.Error("Failed to update automation:", err)
+		return apierror.From(err, "Failed to update automation")
+	}
+
+	return c.JSON(http.StatusOK, automation)
+}
+
+// DeleteAutomation removes one of the user's automations
+func (h *AutomationHandler) DeleteAutomation(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.automationService.DeleteAutomation(c.Request().Context(), user.ID, c.Param("id")); err != nil {
+		h.logger.Error("Failed to delete automation:", err)
+		return apierror.From(err, "Failed to delete automation")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxWebhookBytes bounds webhook payloads; provider events are a few kilobytes
+const maxWebhookBytes = 1 << 20
+
+type BillingHandler struct {
+	billingService service.BillingService
+	authHandler    *AuthHandler
+	logger         echo.Logger
+}
+
+func NewBillingHandler(billingService service.BillingService, authHandler *AuthHandler, logger echo.Logger) *BillingHandler {
+	return &BillingHandler{
+		billingService: billingService,
+		authHandler:    authHandler,
+		logger:         logger,
+	}
+}
+
+// GetUsage returns the user's plan and this month's quota usage
+func (h *BillingHandler) GetUsage(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	usage, err := h.billingService.GetUsage(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get usage:", err)
+		return apierror.From(err, "Failed to get usage")
+	}
+
+	return c.JSON(http.StatusOK, usage)
+}
+
+// Webhook receives plan changes from a billing provider; it is authenticated by the provider's signature
+func (h *BillingHandler) Webhook(c echo.Context) error {
+	payload, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBytes))
+	if err != nil {
+		return apierror.Validation("failed to read webhook payload")
+	}
+
+	if err := h.billingService.HandleWebhook(c.Request().Context(), c.Param("provider"), payload, c.Request().Header); err != nil {
+		h.logger.Error("Failed to handle billing webhook:", err)
+		return apierror.From(err, "Failed to handle billing webhook")
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -2,8 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
 	"jump-challenge/internal/service"
+	"jump-challenge/internal/view"
 
 	"github.com/labstack/echo/v4"
 )
@@ -27,123 +31,158 @@ func (h *CategoryHandler) CreateCategory(c echo.Context) error {
 	// Get the authenticated user
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
 	}
 
 	// Parse the request body
-	var req struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-	}
+	var req CategoryRequest
 
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
 	}
 
-	// Validate input
-	if req.Name == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Name is required",
-		})
-	}
 
-	// Create the category
-	category, err := h.categoryService.CreateCategory(c.Request().Context(), user.ID, req.Name, req.Description)
+	// Create the category, in the organization's taxonomy when asked
+	create := h.categoryService.CreateCategory
+	if req.Team {
+		create = h.categoryService.CreateTeamCategory
+	}
+	category, err := create(c.Request().Context(), user.ID, req.Name, req.Description)
 	if err != nil {
 		h.logger.Error("Failed to create category:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create category",
-		})
+		return apierror.From(err, "Failed to create category")
+	}
+	if req.Hints != "" {
+		if category, err = h.categoryService.SetCategoryHints(c.Request().Context(), user.ID, category.ID, req.Hints); err != nil {
+			h.logger.Error("Failed to set category hints:", err)
+			return apierror.From(err, "Failed to set category hints")
+		}
+	}
+	if req.Summarize != "" {
+		if category, err = h.categoryService.SetCategorySummarize(c.Request().Context(), user.ID, category.ID, req.Summarize); err != nil {
+			h.logger.Error("Failed to set category summarize mode:", err)
+			return apierror.From(err, "Failed to set category summarize mode")
+		}
+	}
+	if req.Sensitive {
+		if category, err = h.categoryService.SetCategorySensitive(c.Request().Context(), user.ID, category.ID, true); err != nil {
+			h.logger.Error("Failed to mark category sensitive:", err)
+			return apierror.From(err, "Failed to mark category sensitive")
+		}
 	}
 
 	return c.JSON(http.StatusCreated, category)
 }
 
-// GetCategory retrieves a category by ID
+// GetCategory retrieves a shared category or one of the user's own
 func (h *CategoryHandler) GetCategory(c echo.Context) error {
-	categoryID := c.Param("id")
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
 
-	category, err := h.categoryService.GetCategory(c.Request().Context(), categoryID)
+	category, err := h.categoryService.GetCategory(c.Request().Context(), user.ID, c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Category not found",
-		})
+		return apierror.From(err, "Category not found")
 	}
 
-	// Return the category (shared among all users)
 	return c.JSON(http.StatusOK, category)
 }
 
-// GetCategories retrieves all categories for the authenticated user
+// GetCategories retrieves the shared categories and the authenticated user's own with their
+// email counts, as JSON or as the category list partial for HTMX
 func (h *CategoryHandler) GetCategories(c echo.Context) error {
-	// Get all categories (shared among all users)
-	categories, err := h.categoryService.GetAllCategories(c.Request().Context())
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	categories, err := h.categoryService.GetCategoriesWithCounts(replicaOK(c), user.ID)
 	if err != nil {
 		h.logger.Error("Failed to get categories:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get categories",
-		})
+		return apierror.From(err, "Failed to get categories")
+	}
+
+	// The counts change with the emails, not the categories, so they are part of the version
+	html := wantsHTML(c)
+	var modified time.Time
+	counts := make([]model.EmailCounts, len(categories))
+	for i, category := range categories {
+		if category.UpdatedAt.After(modified) {
+			modified = category.UpdatedAt
+		}
+		counts[i] = category.EmailCounts
+	}
+	if notModified(c, len(categories), modified, html, counts) {
+		return c.NoContent(http.StatusNotModified)
 	}
 
+	if html {
+		return c.Render(http.StatusOK, view.CategoryList, categories)
+	}
 	return c.JSON(http.StatusOK, categories)
 }
 
-// UpdateCategory updates an existing category
+// UpdateCategory updates one of the user's own categories
 func (h *CategoryHandler) UpdateCategory(c echo.Context) error {
-	categoryID := c.Param("id")
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
 
 	// Parse the request body
-	var req struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-	}
+	var req UpdateCategoryRequest
 
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
 	}
 
-	// Get the current category to check ownership
-	_, err := h.categoryService.GetCategory(c.Request().Context(), categoryID)
-	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Category not found",
-		})
-	}
-
-	// Update the category
+	// Update the category; the service rejects categories the user does not own
 	updatedCategory, err := h.categoryService.UpdateCategory(
 		c.Request().Context(),
-		categoryID,
+		user.ID,
+		c.Param("id"),
 		req.Name,
 		req.Description,
 	)
 	if err != nil {
 		h.logger.Error("Failed to update category:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to update category",
-		})
+		return apierror.From(err, "Failed to update category")
+	}
+	if req.Hints != nil {
+		if updatedCategory, err = h.categoryService.SetCategoryHints(c.Request().Context(), user.ID, updatedCategory.ID, *req.Hints); err != nil {
+			h.logger.Error("Failed to set category hints:", err)
+			return apierror.From(err, "Failed to set category hints")
+		}
+	}
+	if req.Summarize != "" {
+		if updatedCategory, err = h.categoryService.SetCategorySummarize(c.Request().Context(), user.ID, updatedCategory.ID, req.Summarize); err != nil {
+			h.logger.Error("Failed to set category summarize mode:", err)
+			return apierror.From(err, "Failed to set category summarize mode")
+		}
+	}
+	if req.Sensitive != nil {
+		if updatedCategory, err = h.categoryService.SetCategorySensitive(c.Request().Context(), user.ID, updatedCategory.ID, *req.Sensitive); err != nil {
+			h.logger.Error("Failed to mark category sensitive:", err)
+			return apierror.From(err, "Failed to mark category sensitive")
+		}
 	}
 
 	return c.JSON(http.StatusOK, updatedCategory)
 }
 
-// DeleteCategory deletes a category
+// DeleteCategory deletes one of the user's own categories
 func (h *CategoryHandler) DeleteCategory(c echo.Context) error {
-	categoryID := c.Param("id")
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
 
 	// Delete the category
-	err := h.categoryService.DeleteCategory(c.Request().Context(), categoryID)
+	err = h.categoryService.DeleteCategory(c.Request().Context(), user.ID, c.Param("id"))
 	if err != nil {
 		h.logger.Error("Failed to delete category:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to delete category",
-		})
+		return apierror.From(err, "Failed to delete category")
 	}
 
 	return c.NoContent(http.StatusNoContent)
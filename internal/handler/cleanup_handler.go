@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CleanupHandler struct {
+	cleanupService service.CleanupService
+	authHandler    *AuthHandler
+	logger         echo.Logger
+}
+
+func NewCleanupHandler(cleanupService service.CleanupService, authHandler *AuthHandler, logger echo.Logger) *CleanupHandler {
+	return &CleanupHandler{
+		cleanupService: cleanupService,
+		authHandler:    authHandler,
+		logger:         logger,
+	}
+}
+
+// CreatePolicy schedules a standing cleanup of one of the user's categories
+func (h *CleanupHandler) CreatePolicy(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req CleanupPolicyRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	enabled := req.Enabled == nil || *req.Enabled
+	policy, err := h.cleanupService.CreatePolicy(c.Request().Context(), user.ID, req.Name, req.CategoryID, req.Unread, req.OlderThanDays, req.Action, req.Schedule, enabled)
+	if err != nil {
+		h.logger.Error("Failed to create cleanup policy:", err)
+		return apierror.From(err, "Failed to create cleanup policy")
+	}
+
+	return c.JSON(http.StatusCreated, policy)
+}
+
+// GetPolicies lists the user's cleanup policies
+func (h *CleanupHandler) GetPolicies(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	policies, err := h.cleanupService.GetPolicies(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get cleanup policies:", err)
+		return apierror.From(err, "Failed to get cleanup policies")
+	}
+
+	return c.JSON(http.StatusOK, policies)
+}
+
+// UpdatePolicy replaces one of the user's cleanup policies
+func (h *CleanupHandler) UpdatePolicy(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req CleanupPolicyRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	enabled := req.Enabled == nil || *req.Enabled
+	policy, err := h.cleanupService.UpdatePolicy(c.Request().Context(), user.ID, c.Param("id"), req.Name, req.CategoryID, req.Unread, req.OlderThanDays, req.Action, req.Schedule, enabled)
+	if err != nil {
+		h.logger.Error("Failed to update cleanup policy:", err)
+		return apierror.From(err, "Failed to update cleanup policy")
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy removes one of the user's cleanup policies along with its history
+func (h *CleanupHandler) DeletePolicy(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.cleanupService.DeletePolicy(c.Request().Context(), user.ID, c.Param("id")); err != nil {
+		h.logger.Error("Failed to delete cleanup policy:", err)
+		return apierror.From(err, "Failed to delete cleanup policy")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PreviewPolicy tells what a cleanup policy would clean if it ran now, without running it
+func (h *CleanupHandler) PreviewPolicy(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	preview, err := h.cleanupService.PreviewPolicy(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to preview cleanup policy:", err)
+		return apierror.From(err, "Failed to preview cleanup policy")
+	}
+
+	return c.JSON(http.StatusOK, preview)
+}
+
+// GetRuns lists the past runs of a cleanup policy, newest first
+func (h *CleanupHandler) GetRuns(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query CleanupRunsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	runs, err := h.cleanupService.GetRuns(c.Request().Context(), user.ID, c.Param("id"), query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get cleanup runs:", err)
+		return apierror.From(err, "Failed to get cleanup runs")
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
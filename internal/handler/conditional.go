@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// notModified sets the ETag of a list response and reports whether the client's copy is still
+// current, in which case the handler answers 304 instead of the list.
+//
+// The ETag is derived from how many items the list holds and when the latest of them changed,
+// along with whatever else the response shows that doesn't bump updated_at (the representation,
+// counts). The lists carry no Last-Modified, and If-Modified-Since is ignored: the latest change
+// stays put when an item is removed or a count moves, so a date would call a stale copy current.
+func notModified(c echo.Context, count int, modified time.Time, extra ...any) bool {
+	hash := fnv.New64a()
+	fmt.Fprint(hash, count, modified.UnixNano(), extra)
+	etag := fmt.Sprintf(`W/"%x"`, hash.Sum64())
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, "private, no-cache")
+	header.Set("ETag", etag)
+
+	match := c.Request().Header.Get("If-None-Match")
+	return match != "" && etagMatches(match, etag)
+}
+
+// etagMatches compares an If-None-Match list to the current ETag, weakly as GET requires
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
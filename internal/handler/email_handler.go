@@ -1,32 +1,47 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"net/url"
 	"time"
 
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/jsonstream"
 	"jump-challenge/internal/model"
 	"jump-challenge/internal/service"
 	"jump-challenge/internal/sse"
+	"jump-challenge/internal/view"
 
 	"github.com/labstack/echo/v4"
 )
 
+// StaleHeader is set to "true" on email lists served while Gmail is unreachable for the user,
+// whose stored emails may be behind their mailbox
+const StaleHeader = "X-Data-Stale"
+
 type EmailHandler struct {
-	emailService service.EmailService
-	authHandler  *AuthHandler
-	sseManager   *sse.SSEManager
-	logger       echo.Logger
+	emailService    service.EmailService
+	categoryService service.CategoryService
+	authHandler     *AuthHandler
+	sseManager      *sse.SSEManager
+	bulkJobs        *sse.BulkJobQueue
+	imageProxy      service.ImageProxyService
+	links           service.LinkService
+	logger          echo.Logger
 }
 
-func NewEmailHandler(emailService service.EmailService, authHandler *AuthHandler, sseManager *sse.SSEManager, logger echo.Logger) *EmailHandler {
+func NewEmailHandler(emailService service.EmailService, categoryService service.CategoryService, authHandler *AuthHandler, sseManager *sse.SSEManager, bulkJobs *sse.BulkJobQueue, imageProxy service.ImageProxyService, links service.LinkService, logger echo.Logger) *EmailHandler {
 	return &EmailHandler{
-		emailService: emailService,
-		authHandler:  authHandler,
-		sseManager:   sseManager,
-		logger:       logger,
+		emailService:    emailService,
+		categoryService: categoryService,
+		authHandler:     authHandler,
+		sseManager:      sseManager,
+		bulkJobs:        bulkJobs,
+		imageProxy:      imageProxy,
+		links:           links,
+		logger:          logger,
 	}
 }
 
@@ -34,55 +49,70 @@ func NewEmailHandler(emailService service.EmailService, authHandler *AuthHandler
 func (h *EmailHandler) SyncEmails(c echo.Context) error {
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
 	}
 
-	// Get query parameters for email sync configuration
-	maxResultsStr := c.QueryParam("max_results")
-	afterEmailID := c.QueryParam("after_email_id")
-
-	// Parse max_results parameter, default to 3 if not provided or invalid
-	maxResults := int64(3)
-	if maxResultsStr != "" {
-		parsed, err := strconv.ParseInt(maxResultsStr, 3, 64)
-		if err == nil && parsed > 0 {
-			maxResults = parsed
-		}
+	// Without max_results the Gmail client falls back to MAX_FETCH_EMAILS
+	var query SyncEmailsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
 	}
 
-	err = h.emailService.SyncEmails(c.Request().Context(), user.ID, maxResults, afterEmailID)
+	result, err := h.emailService.SyncEmails(c.Request().Context(), user.ID, service.SyncOptions{
+		MaxResults:   query.MaxResults,
+		AfterEmailID: query.AfterEmailID,
+	})
+	if errors.Is(err, service.ErrGmailUnreachable) {
+		// The stored emails are still there to show
+		h.logger.Warn("Gmail is unreachable, sync skipped for user:", user.ID)
+		c.Response().Header().Set(StaleHeader, "true")
+		return c.JSON(http.StatusOK, SyncEmailsResponse{
+			Message: "Gmail is unreachable, showing the emails already stored",
+			Stale:   true,
+		})
+	}
 	if err != nil {
 		h.logger.Error("Failed to sync emails:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return apierror.From(err, "Failed to sync emails")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Emails synced successfully",
+	return c.JSON(http.StatusOK, SyncEmailsResponse{
+		Message: "Emails synced successfully",
+		Fetched: len(result.Fetched),
+		New:     len(result.New),
+		Updated: len(result.Updated),
+		Skipped: result.Skipped,
 	})
 }
 
-// GetEmailsByUser retrieves all emails for the authenticated user
+// GetEmailsByUser retrieves emails for the authenticated user, newest first
+// (optional ?limit=N, ?archived=true for locally archived only or ?archived=all)
 func (h *EmailHandler) GetEmailsByUser(c echo.Context) error {
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query ListEmailsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
 	}
 
-	emails, err := h.emailService.GetEmailsByUser(c.Request().Context(), user.ID)
+	filter := model.EmailFilter{Archive: parseArchiveFilter(query.Archived)}
+	emails, err := h.emailService.ListEmails(replicaOK(c), user.ID, filter, query.Limit)
 	if err != nil {
 		h.logger.Error("Failed to get emails:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get emails",
-		})
+		return apierror.From(err, "Failed to get emails")
 	}
+	h.markStale(c, user.ID)
 
-	return c.JSON(http.StatusOK, emails)
+	if wantsHTML(c) {
+		return h.renderEmails(c, user.ID, emails)
+	}
+	if notModified(c, len(emails), lastUpdated(emails), query.IncludeBody) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return writeEmails(c, listEmails(emails, query.IncludeBody))
 }
 
 // GetEmailsByCategory retrieves emails for a specific category
@@ -93,74 +123,582 @@ func (h *EmailHandler) GetEmailsByCategory(c echo.Context) error {
 	// will return only emails that belong to the authenticated user
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query ListEmailsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
 	}
 
-	emails, err := h.emailService.GetEmailsByCategory(c.Request().Context(), categoryID)
+	filter := model.EmailFilter{CategoryID: categoryID, Archive: parseArchiveFilter(query.Archived)}
+	emails, err := h.emailService.ListEmails(replicaOK(c), user.ID, filter, query.Limit)
 	if err != nil {
 		h.logger.Error("Failed to get emails by category:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to get emails by category",
-		})
+		return apierror.From(err, "Failed to get emails by category")
+	}
+	h.markStale(c, user.ID)
+
+	if wantsHTML(c) {
+		return h.renderEmails(c, user.ID, emails)
+	}
+	if notModified(c, len(emails), lastUpdated(emails), query.IncludeBody) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return writeEmails(c, listEmails(emails, query.IncludeBody))
+}
+
+// markStale sets StaleHeader while Gmail is unreachable for the user
+func (h *EmailHandler) markStale(c echo.Context, userID string) {
+	health, err := h.emailService.GetGmailHealth(c.Request().Context(), userID)
+	if err != nil {
+		h.logger.Warn("Failed to get Gmail health:", err)
+		return
+	}
+	if health.Status == model.GmailUnreachable {
+		c.Response().Header().Set(StaleHeader, "true")
+	}
+}
+
+// GetEmailWindow lists a window of the user's emails as small rows with the total, for lists
+// that only load the rows in view
+func (h *EmailHandler) GetEmailWindow(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query EmailWindowQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	filter := model.EmailFilter{CategoryID: query.CategoryID, Archive: parseArchiveFilter(query.Archived)}
+	window, err := h.emailService.ListEmailWindow(replicaOK(c), user.ID, filter, query.Offset, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get email window:", err)
+		return apierror.From(err, "Failed to get emails")
+	}
+
+	return c.JSON(http.StatusOK, window)
+}
+
+// GetEmailDelta lists the IDs of the user's emails changed since the cursor, for clients to
+// bring a local copy up to date
+func (h *EmailHandler) GetEmailDelta(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query EmailDeltaQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	delta, err := h.emailService.GetEmailDelta(c.Request().Context(), user.ID, query.Since, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get email delta:", err)
+		return apierror.From(err, "Failed to get email delta")
+	}
+
+	return c.JSON(http.StatusOK, delta)
+}
+
+// GetTrash lists the authenticated user's trashed emails
+func (h *EmailHandler) GetTrash(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query ListEmailsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	emails, err := h.emailService.GetTrash(replicaOK(c), user.ID, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get trash:", err)
+		return apierror.From(err, "Failed to get trash")
+	}
+
+	return writeEmails(c, listEmails(emails, false))
+}
+
+// GetVIPEmails lists the emails from the user's VIP senders, newest first
+func (h *EmailHandler) GetVIPEmails(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query ListEmailsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	emails, err := h.emailService.GetVIPEmails(replicaOK(c), user.ID, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get VIP emails:", err)
+		return apierror.From(err, "Failed to get VIP emails")
+	}
+
+	return writeEmails(c, listEmails(emails, query.IncludeBody))
+}
+
+// GetVIPSenders lists the senders the user marked as VIP
+func (h *EmailHandler) GetVIPSenders(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	vips, err := h.emailService.GetVIPSenders(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get VIP senders:", err)
+		return apierror.From(err, "Failed to get VIP senders")
+	}
+
+	return c.JSON(http.StatusOK, vips)
+}
+
+// AddVIPSender marks a sender as VIP
+func (h *EmailHandler) AddVIPSender(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
 	}
 
-	// Filter emails to only include ones owned by the current user
-	var userEmails []*model.Email
+	var req VIPSenderRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	vip, err := h.emailService.AddVIPSender(c.Request().Context(), user.ID, req.Address, req.Name)
+	if err != nil {
+		h.logger.Error("Failed to add VIP sender:", err)
+		return apierror.From(err, "Failed to add VIP sender")
+	}
+
+	return c.JSON(http.StatusCreated, vip)
+}
+
+// RemoveVIPSender turns a VIP sender back into a regular one
+func (h *EmailHandler) RemoveVIPSender(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	address, err := url.PathUnescape(c.Param("address"))
+	if err != nil {
+		return apierror.Validation("invalid sender address")
+	}
+	if err := h.emailService.RemoveVIPSender(c.Request().Context(), user.ID, address); err != nil {
+		h.logger.Error("Failed to remove VIP sender:", err)
+		return apierror.From(err, "Failed to remove VIP sender")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RestoreEmail moves an email out of the trash
+func (h *EmailHandler) RestoreEmail(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	email, err := h.emailService.RestoreEmail(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to restore email:", err)
+		return apierror.From(err, "Email not found in trash")
+	}
+
+	return c.JSON(http.StatusOK, email.WithoutBody())
+}
+
+// CategorizeEmail files an email under another of the user's categories, correcting its classification
+func (h *EmailHandler) CategorizeEmail(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req CategorizeEmailRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	email, err := h.emailService.CategorizeEmail(c.Request().Context(), user.ID, c.Param("id"), req.CategoryID)
+	if err != nil {
+		h.logger.Error("Failed to categorize email:", err)
+		return apierror.From(err, "Failed to categorize email")
+	}
+
+	return c.JSON(http.StatusOK, email.WithoutBody())
+}
+
+// GetReviewQueue lists the emails the AI wasn't confident enough to file, with its suggestion
+func (h *EmailHandler) GetReviewQueue(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query ListEmailsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	emails, err := h.emailService.GetReviewQueue(c.Request().Context(), user.ID, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get review queue:", err)
+		return apierror.From(err, "Failed to get review queue")
+	}
+
+	return writeEmails(c, listEmails(emails, query.IncludeBody))
+}
+
+// ResolveReview files an email of the review queue under the category the user picked
+func (h *EmailHandler) ResolveReview(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req ResolveReviewRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	email, err := h.emailService.ResolveReview(c.Request().Context(), user.ID, c.Param("id"), req.CategoryID)
+	if err != nil {
+		h.logger.Error("Failed to resolve review:", err)
+		return apierror.From(err, "Failed to resolve review")
+	}
+
+	return c.JSON(http.StatusOK, email.WithoutBody())
+}
+
+// GetEmailBody returns the full body of a single email, which list endpoints omit by default
+func (h *EmailHandler) GetEmailBody(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	email, err := h.emailService.GetEmail(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		return apierror.From(err, "Email not found")
+	}
+
+	body, textOnly := h.displayBody(c, user, email.Body)
+	return c.JSON(http.StatusOK, EmailBodyResponse{
+		ID:        email.ID,
+		Body:      body,
+		Truncated: email.BodyTruncated,
+		Pruned:    email.BodyPruned,
+		TextOnly:  textOnly,
+	})
+}
+
+// GetOTP returns the verification code found in an email so the UI can offer one-tap copy
+func (h *EmailHandler) GetOTP(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	otp, err := h.emailService.GetOTP(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		return apierror.From(err, "Failed to get verification code")
+	}
+
+	return c.JSON(http.StatusOK, otp)
+}
+
+// GetSecurityEvents lists the user's account-security emails, newest first
+func (h *EmailHandler) GetSecurityEvents(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query SecurityEventsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	events, err := h.emailService.GetSecurityEvents(c.Request().Context(), user.ID, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get security events:", err)
+		return apierror.From(err, "Failed to get security events")
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
+
+// GetEmail returns a full email with its category, sender history, unsubscribe availability
+// and the previous/next email IDs within the listing it was opened from
+func (h *EmailHandler) GetEmail(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query EmailDetailQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	filter := model.EmailFilter{
+		CategoryID: query.CategoryID,
+		Sender:     query.Sender,
+		Archive:    parseArchiveFilter(query.Archived),
+	}
+	detail, err := h.emailService.GetEmailDetail(c.Request().Context(), user.ID, c.Param("id"), filter)
+	if err != nil {
+		return apierror.From(err, "Failed to get email")
+	}
+	detail.Email.Body, detail.TextOnly = h.displayBody(c, user, detail.Email.Body)
+
+	return c.JSON(http.StatusOK, detail)
+}
+
+// displayBody prepares a body for display: tracked links point at their destination and
+// remote images load through the proxy. In privacy mode nothing remote is fetched and only
+// the text is shown, which it reports.
+func (h *EmailHandler) displayBody(c echo.Context, user *model.User, body string) (string, bool) {
+	if user.PrivacyMode {
+		return service.PlainText(body), true
+	}
+	return h.imageProxy.RewriteImages(h.links.ResolveLinks(c.Request().Context(), body)), false
+}
+
+// GetSenderHistory returns every stored email from a sender, oldest first, with how the user
+// engaged with them
+func (h *EmailHandler) GetSenderHistory(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	address, err := url.PathUnescape(c.Param("address"))
+	if err != nil {
+		return apierror.Validation("invalid sender address")
+	}
+	history, err := h.emailService.GetSenderHistory(c.Request().Context(), user.ID, address)
+	if err != nil {
+		h.logger.Error("Failed to get sender history:", err)
+		return apierror.From(err, "Failed to get sender history")
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+// GenerateSenderProfile has the AI characterize a sender from their recent emails; the profile
+// is cached and shown in the sender's history
+func (h *EmailHandler) GenerateSenderProfile(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query SenderProfileQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+	address, err := url.PathUnescape(c.Param("address"))
+	if err != nil {
+		return apierror.Validation("invalid sender address")
+	}
+	profile, err := h.emailService.GenerateSenderProfile(c.Request().Context(), user.ID, address, query.Refresh)
+	if err != nil {
+		h.logger.Error("Failed to generate sender profile:", err)
+		return apierror.From(err, "Failed to generate sender profile")
+	}
+
+	return c.JSON(http.StatusOK, profile)
+}
+
+// GetSystemEmailSettings returns how the user's bounces and auto-replies are handled
+func (h *EmailHandler) GetSystemEmailSettings(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	settings, err := h.emailService.GetSystemEmailSettings(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get system email settings:", err)
+		return apierror.From(err, "Failed to get system email settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSystemEmailSettings replaces how the user's bounces and auto-replies are handled
+func (h *EmailHandler) UpdateSystemEmailSettings(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req SystemEmailSettingsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	settings, err := h.emailService.UpdateSystemEmailSettings(c.Request().Context(), user.ID,
+		model.SystemEmailSettings{AutoArchive: req.AutoArchive})
+	if err != nil {
+		h.logger.Error("Failed to update system email settings:", err)
+		return apierror.From(err, "Failed to update system email settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// GetPrivacySettings returns whether the user is in privacy mode
+func (h *EmailHandler) GetPrivacySettings(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	settings, err := h.emailService.GetPrivacySettings(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get privacy settings:", err)
+		return apierror.From(err, "Failed to get privacy settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdatePrivacySettings turns privacy mode on or off
+func (h *EmailHandler) UpdatePrivacySettings(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req PrivacySettingsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	settings, err := h.emailService.UpdatePrivacySettings(c.Request().Context(), user.ID,
+		model.PrivacySettings{Enabled: req.Enabled})
+	if err != nil {
+		h.logger.Error("Failed to update privacy settings:", err)
+		return apierror.From(err, "Failed to update privacy settings")
+	}
+
+	return c.JSON(http.StatusOK, settings)
+}
+
+// parseArchiveFilter maps the ?archived query parameter onto a local archive filter
+func parseArchiveFilter(value string) model.ArchiveFilter {
+	switch value {
+	case "true":
+		return model.ArchiveFilterOnly
+	case "all":
+		return model.ArchiveFilterAll
+	default:
+		return model.ArchiveFilterExclude
+	}
+}
+
+// listEmails prepares emails for a list response, dropping bodies unless explicitly requested
+func listEmails(emails []*model.Email, includeBody bool) []*model.Email {
+	if includeBody {
+		return emails
+	}
+
+	result := make([]*model.Email, len(emails))
+	for i, email := range emails {
+		result[i] = email.WithoutBody()
+	}
+	return result
+}
+
+// writeEmails streams an email list as JSON, as it can hold thousands of emails
+func writeEmails(c echo.Context, emails []*model.Email) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c.Response().WriteHeader(http.StatusOK)
+	return jsonstream.WriteArray(c.Response(), emails, "")
+}
+
+// lastUpdated returns when the most recently changed of the emails changed
+func lastUpdated(emails []*model.Email) time.Time {
+	var latest time.Time
 	for _, email := range emails {
-		if email.UserID == user.ID {
-			userEmails = append(userEmails, email)
+		if email.UpdatedAt.After(latest) {
+			latest = email.UpdatedAt
 		}
 	}
+	return latest
+}
 
-	return c.JSON(http.StatusOK, userEmails)
+// renderEmails writes the email list partial, labelling emails with the categories the user can see
+func (h *EmailHandler) renderEmails(c echo.Context, userID string, emails []*model.Email) error {
+	categories, err := h.categoryService.GetAllCategories(c.Request().Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get categories:", err)
+		return apierror.From(err, "Failed to get categories")
+	}
+
+	return c.Render(http.StatusOK, view.EmailList, view.NewEmails(emails, categories))
 }
 
 // PerformBulkAction performs an action on multiple emails
 func (h *EmailHandler) PerformBulkAction(c echo.Context) error {
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
 	}
 
-	// Parse the request body
-	var req struct {
-		EmailIDs []string `json:"email_ids"`
-		Action   string   `json:"action"` // "archive", "read", "delete"
+	// Parse the request body; either email_ids or filter selects the emails
+	var req BulkActionRequest
+
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
 	}
 
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+
+	if req.Action == "move" && req.Label == "" {
+		return apierror.InvalidFields([]apierror.FieldError{{Field: "label", Message: "is required for the move action"}})
 	}
 
-	if len(req.EmailIDs) == 0 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Email IDs are required",
-		})
+	if req.Filter != nil {
+		job := model.NewBulkJob(user.ID, req.Action, *req.Filter)
+		job.Label = req.Label
+		job.BlockSender = req.BlockSender
+		return h.enqueueBulkJob(c, user, job)
 	}
 
-	if req.Action == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Action is required",
-		})
+	if len(req.EmailIDs) == 0 {
+		return apierror.Validation("Email IDs are required")
 	}
 
-	// Perform the bulk action
-	err = h.emailService.PerformBulkAction(c.Request().Context(), req.EmailIDs, req.Action, user.ID)
+	// Perform the bulk action; actions with parameters have their own service methods
+	switch req.Action {
+	case "move":
+		err = h.emailService.MoveEmails(c.Request().Context(), req.EmailIDs, req.Label, user.ID)
+	case "spam":
+		err = h.emailService.ReportSpam(c.Request().Context(), req.EmailIDs, user.ID, req.BlockSender)
+	default:
+		err = h.emailService.PerformBulkAction(c.Request().Context(), req.EmailIDs, req.Action, user.ID)
+	}
 	if err != nil {
 		h.logger.Error("Failed to perform bulk action:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to perform bulk action",
-		})
+		return apierror.From(err, "Failed to perform bulk action")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Bulk action performed successfully",
+	return c.JSON(http.StatusOK, MessageResponse{
+		Message: "Bulk action performed successfully",
 	})
 }
 
@@ -168,68 +706,80 @@ func (h *EmailHandler) PerformBulkAction(c echo.Context) error {
 func (h *EmailHandler) DeleteEmails(c echo.Context) error {
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
 	}
 
-	// Parse the request body
-	var req struct {
-		EmailIDs []string `json:"email_ids"`
+	// Parse the request body; either email_ids or filter selects the emails
+	var req EmailSelectionRequest
+
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
 	}
 
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+	if req.Filter != nil {
+		return h.enqueueBulkJob(c, user, model.NewBulkJob(user.ID, "delete", *req.Filter))
 	}
 
 	if len(req.EmailIDs) == 0 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Email IDs are required",
-		})
+		return apierror.Validation("Email IDs are required")
 	}
 
 	// Perform the bulk deletion
 	err = h.emailService.DeleteEmails(c.Request().Context(), req.EmailIDs, user.ID)
 	if err != nil {
 		h.logger.Error("Failed to delete emails:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to delete emails",
-		})
+		return apierror.From(err, "Failed to delete emails")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Emails deleted successfully",
+	return c.JSON(http.StatusOK, MessageResponse{
+		Message: "Emails deleted successfully",
 	})
 }
 
+// enqueueBulkJob schedules a filter-based bulk action and responds with the queued job
+func (h *EmailHandler) enqueueBulkJob(c echo.Context, user *model.User, job *model.BulkJob) error {
+	// Fail fast instead of letting every batch hit the missing permission
+	if service.RequiresGmailModify(job.Action) && !user.HasScope(model.ScopeGmailModify) {
+		return service.ErrGmailModifyScopeRequired
+	}
+
+	job, err := h.bulkJobs.Submit(job)
+	if err != nil {
+		return apierror.From(err, "Failed to enqueue bulk job")
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GetBulkJob reports the progress of a filter-based bulk job
+func (h *EmailHandler) GetBulkJob(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	job, err := h.bulkJobs.GetJob(user.ID, c.Param("id"))
+	if err != nil {
+		return apierror.From(err, "Bulk job not found")
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
 // ClassifyEmail receives an email subject and body and classifies it
 func (h *EmailHandler) ClassifyEmail(c echo.Context) error {
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
 	}
 
 	// Parse the request body
-	var req struct {
-		Subject string `json:"subject"`
-		Body    string `json:"body"`
-	}
+	var req ClassifyRequest
 
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
 	}
 
-	if req.Body == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Email body is required",
-		})
-	}
 
 	// Log the classification request for the authenticated user
 	h.logger.Info("Classifying email for user:", user.ID)
@@ -238,14 +788,12 @@ func (h *EmailHandler) ClassifyEmail(c echo.Context) error {
 	classifiedCategory, err := h.emailService.ClassifyEmailByContent(c.Request().Context(), user.ID, req.Body)
 	if err != nil {
 		h.logger.Error("Failed to classify email for user:", user.ID, err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to classify email",
-		})
+		return apierror.From(err, "Failed to classify email")
 	}
 
 	h.logger.Info("Email classified as:", classifiedCategory, "for user:", user.ID)
-	return c.JSON(http.StatusOK, map[string]string{
-		"classification": classifiedCategory,
+	return c.JSON(http.StatusOK, ClassifyResponse{
+		Classification: classifiedCategory,
 	})
 }
 
@@ -253,9 +801,7 @@ func (h *EmailHandler) ClassifyEmail(c echo.Context) error {
 func (h *EmailHandler) SSEEmailUpdates(c echo.Context) error {
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
 	}
 
 	// Set response headers for SSE
@@ -273,23 +819,26 @@ func (h *EmailHandler) SSEEmailUpdates(c echo.Context) error {
 	}()
 
 	// Send initial connection confirmation
-	initEvent := map[string]interface{}{
-		"type": "connection",
-		"data": map[string]string{
-			"message": "Connected to email updates",
-			"userId":  user.ID,
-		},
-		"time": time.Now().Unix(),
-	}
-
-	initJSON, _ := json.Marshal(initEvent)
+	initJSON, _ := sse.Encode(sse.Connected{
+		Message: "Connected to email updates",
+		UserID:  user.ID,
+	})
 	fmt.Fprintf(c.Response(), "data: %s\n\n", initJSON)
+
+	// Then what was broadcast while the user had no connection, e.g. a laptop asleep
+	for _, eventData := range h.sseManager.TakePending(user.ID) {
+		fmt.Fprintf(c.Response(), "data: %s\n\n", eventData)
+	}
 	c.Response().Flush()
 
 	// Listen for messages on the client channel and send them to the client
 	for {
 		select {
-		case eventData := <-clientChannel:
+		case eventData, open := <-clientChannel:
+			if !open {
+				// The server is shutting down
+				return nil
+			}
 			// Send the event data to the client
 			fmt.Fprintf(c.Response(), "data: %s\n\n", eventData)
 			c.Response().Flush()
@@ -299,3 +848,49 @@ func (h *EmailHandler) SSEEmailUpdates(c echo.Context) error {
 		}
 	}
 }
+
+// SSEStats reports how many events reached the SSE connections and how many were dropped for
+// connections that fell behind, across all users
+func (h *EmailHandler) SSEStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.sseManager.Stats())
+}
+
+// SSEPresence tells the user whether they receive live updates, for the UI to show the state
+// of its connection
+func (h *EmailHandler) SSEPresence(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	return c.JSON(http.StatusOK, h.sseManager.Presence(user.ID))
+}
+
+// GmailBreakers lists the Gmail circuit breaker of every user, for operators
+func (h *EmailHandler) GmailBreakers(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	if !h.authHandler.IsAdmin(user) {
+		return apierror.Forbidden("Only operators can list Gmail circuit breakers")
+	}
+
+	return c.JSON(http.StatusOK, h.emailService.GetGmailBreakers())
+}
+
+// SSEConnections lists the SSE connections of every user, for operators
+func (h *EmailHandler) SSEConnections(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	if !h.authHandler.IsAdmin(user) {
+		return apierror.Forbidden("Only operators can list connections")
+	}
+
+	return c.JSON(http.StatusOK, ConnectionsResponse{
+		Users: h.sseManager.Presences(),
+		Stats: h.sseManager.Stats(),
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/i18n"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPErrorHandler renders every error returned by a handler as an ErrorResponse
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, response := errorResponse(err)
+	response = localize(response, requestLocale(c))
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, response)
+	}
+	if err != nil {
+		c.Logger().Error("Failed to write error response:", err)
+	}
+}
+
+// errorResponse maps typed API errors and echo's own errors onto a status and body
+func errorResponse(err error) (int, ErrorResponse) {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		response := ErrorResponse{Error: apiErr.Message, Code: apiErr.Code, Fields: apiErr.Fields, Current: apiErr.Current}
+		if apiErr.Code == apierror.CodeReauthRequired {
+			response.ReauthURL = ReauthURL
+		}
+		return apiErr.Status, response
+	}
+
+	// Routing errors (unknown path, wrong method) and middleware errors
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		message, ok := httpErr.Message.(string)
+		if !ok {
+			message = http.StatusText(httpErr.Code)
+		}
+		return httpErr.Code, ErrorResponse{Error: message, Code: codeForStatus(httpErr.Code)}
+	}
+
+	return http.StatusInternalServerError, ErrorResponse{Error: "Internal server error", Code: apierror.CodeInternal}
+}
+
+// localize translates the messages of the response; codes and field names stay as they are
+func localize(response ErrorResponse, locale string) ErrorResponse {
+	response.Error = i18n.Translate(locale, response.Error)
+	if len(response.Fields) > 0 {
+		// Fields may belong to a shared error, so translate a copy
+		fields := make([]apierror.FieldError, len(response.Fields))
+		for i, field := range response.Fields {
+			fields[i] = apierror.FieldError{Field: field.Field, Message: i18n.Translate(locale, field.Message)}
+		}
+		response.Fields = fields
+	}
+	return response
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return apierror.CodeNotFound
+	case http.StatusUnauthorized:
+		return apierror.CodeUnauthorized
+	case http.StatusForbidden:
+		return apierror.CodeForbidden
+	case http.StatusServiceUnavailable:
+		return apierror.CodeUnavailable
+	case http.StatusConflict:
+		return apierror.CodeConflict
+	}
+	if status >= 400 && status < 500 {
+		return apierror.CodeValidation
+	}
+	return apierror.CodeInternal
+}
+
+// bindAndValidate decodes the request into req and checks its validate tags
+func bindAndValidate(c echo.Context, req any) error {
+	if err := c.Bind(req); err != nil {
+		return apierror.Validation("Invalid request body")
+	}
+	return c.Validate(req)
+}
+
+// bindQuery decodes query parameters into req and checks its validate tags
+func bindQuery(c echo.Context, req any) error {
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, req); err != nil {
+		return apierror.Validation("Invalid query parameters")
+	}
+	return c.Validate(req)
+}
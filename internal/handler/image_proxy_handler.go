@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ImageProxyHandler struct {
+	imageProxy  service.ImageProxyService
+	authHandler *AuthHandler
+	logger      echo.Logger
+}
+
+func NewImageProxyHandler(imageProxy service.ImageProxyService, authHandler *AuthHandler, logger echo.Logger) *ImageProxyHandler {
+	return &ImageProxyHandler{
+		imageProxy:  imageProxy,
+		authHandler: authHandler,
+		logger:      logger,
+	}
+}
+
+// ProxyImage serves a remote image of an email body, fetched by the server instead of the browser
+func (h *ImageProxyHandler) ProxyImage(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	if user.PrivacyMode {
+		return apierror.Forbidden("remote content is blocked in privacy mode")
+	}
+
+	var query ImageProxyQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	image, err := h.imageProxy.FetchImage(c.Request().Context(), query.URL)
+	if err != nil {
+		h.logger.Warn("Failed to proxy image:", err)
+		return apierror.From(err, "Failed to load image")
+	}
+
+	header := c.Response().Header()
+	header.Set("Cache-Control", "private, max-age=86400")
+	header.Set("X-Content-Type-Options", "nosniff")
+	return c.Blob(http.StatusOK, image.ContentType, image.Data)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"jump-challenge/internal/i18n"
+	"jump-challenge/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// localeKey holds the authenticated user's locale in the echo context
+const localeKey = "locale"
+
+// SetUserLocale makes the rest of the request answer in the user's language
+func SetUserLocale(c echo.Context, user *model.User) {
+	c.Set(localeKey, user.Locale)
+}
+
+// requestLocale is the locale of the request's messages: the user's own, else the first
+// supported language of Accept-Language, else empty for English
+func requestLocale(c echo.Context) string {
+	if locale, ok := c.Get(localeKey).(string); ok && locale != "" {
+		return locale
+	}
+	return i18n.FromAcceptLanguage(c.Request().Header.Get("Accept-Language"))
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// wantsHTML reports whether the client asked for an HTML partial instead of JSON:
+// HTMX requests always do, other clients do when Accept lists text/html first
+func wantsHTML(c echo.Context) bool {
+	// The same URL serves both representations, so caches must key on these headers
+	c.Response().Header().Add(echo.HeaderVary, "HX-Request")
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+
+	req := c.Request()
+	if req.Header.Get("HX-Request") == "true" {
+		return true
+	}
+
+	preferred, _, _ := strings.Cut(req.Header.Get(echo.HeaderAccept), ",")
+	preferred, _, _ = strings.Cut(preferred, ";")
+	return strings.TrimSpace(preferred) == echo.MIMETextHTML
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type NotificationHandler struct {
+	notificationService service.NotificationService
+	authHandler         *AuthHandler
+	logger              echo.Logger
+}
+
+func NewNotificationHandler(notificationService service.NotificationService, authHandler *AuthHandler, logger echo.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		authHandler:         authHandler,
+		logger:              logger,
+	}
+}
+
+// GetNotificationSettings returns the user's notification preferences
+func (h *NotificationHandler) GetNotificationSettings(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	preferences, err := h.notificationService.GetPreferences(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get notification preferences:", err)
+		return apierror.From(err, "Failed to get notification preferences")
+	}
+
+	return c.JSON(http.StatusOK, preferences)
+}
+
+// UpdateNotificationSettings replaces the user's notification preferences
+func (h *NotificationHandler) UpdateNotificationSettings(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req NotificationSettingsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	preferences, err := h.notificationService.UpdatePreferences(c.Request().Context(), user.ID,
+		req.QuietHoursStart, req.QuietHoursEnd, req.TimeZone, req.EventTypes, req.MinPriority)
+	if err != nil {
+		h.logger.Error("Failed to update notification preferences:", err)
+		return apierror.From(err, "Failed to update notification preferences")
+	}
+
+	return c.JSON(http.StatusOK, preferences)
+}
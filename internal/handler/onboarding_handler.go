@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OnboardingHandler lets the frontend drive the onboarding wizard of new users
+type OnboardingHandler struct {
+	onboardingService     service.OnboardingService
+	recommendationService service.RecommendationService
+	authHandler           *AuthHandler
+	bulkJobs              *sse.BulkJobQueue
+	logger                echo.Logger
+}
+
+func NewOnboardingHandler(onboardingService service.OnboardingService, recommendationService service.RecommendationService, authHandler *AuthHandler, bulkJobs *sse.BulkJobQueue, logger echo.Logger) *OnboardingHandler {
+	return &OnboardingHandler{
+		onboardingService:     onboardingService,
+		recommendationService: recommendationService,
+		authHandler:           authHandler,
+		bulkJobs:              bulkJobs,
+		logger:                logger,
+	}
+}
+
+// GetOnboarding returns the user's current step and what it offers
+func (h *OnboardingHandler) GetOnboarding(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	onboarding, err := h.onboardingService.GetOnboarding(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get onboarding:", err)
+		return apierror.From(err, "Failed to get onboarding")
+	}
+
+	return c.JSON(http.StatusOK, onboarding)
+}
+
+// CompleteOnboardingStep does the user's current step and responds with the next one
+func (h *OnboardingHandler) CompleteOnboardingStep(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req OnboardingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	var response OnboardingResponse
+	switch {
+	case req.Skip:
+		response.Onboarding, err = h.onboardingService.CompleteStep(ctx, user.ID, req.Step)
+	case req.Step == model.OnboardingCategories:
+		response.Onboarding, err = h.onboardingService.ChooseCategories(ctx, user.ID, req.Categories)
+	case req.Step == model.OnboardingBackfill:
+		var result *model.SyncResult
+		response.Onboarding, result, err = h.onboardingService.Backfill(ctx, user.ID)
+		if err == nil {
+			response.Backfill = &SyncEmailsResponse{
+				Message: "Emails synced successfully",
+				Fetched: len(result.Fetched),
+				New:     len(result.New),
+				Updated: len(result.Updated),
+				Skipped: result.Skipped,
+			}
+		}
+	case req.Step == model.OnboardingUnsubscribe:
+		response.Jobs, err = h.unsubscribe(c, user, req.RecommendationIDs)
+		if err == nil {
+			response.Onboarding, err = h.onboardingService.CompleteStep(ctx, user.ID, req.Step)
+		}
+	}
+	if err != nil {
+		h.logger.Error("Failed to complete onboarding step:", err)
+		return apierror.From(err, "Failed to complete onboarding step")
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// unsubscribe queues the bulk jobs carrying out the unsubscribe recommendations, once the user
+// is known to be at the unsubscribe step
+func (h *OnboardingHandler) unsubscribe(c echo.Context, user *model.User, ids []string) ([]*model.BulkJob, error) {
+	ctx := c.Request().Context()
+	onboarding, err := h.onboardingService.GetOnboarding(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if onboarding.Step != model.OnboardingUnsubscribe {
+		return nil, apierror.Conflict(fmt.Sprintf("The onboarding is at the %s step", onboarding.Step), onboarding)
+	}
+
+	var recommendations []*model.Recommendation
+	for _, id := range ids {
+		recommendation, err := h.recommendationService.GetRecommendation(ctx, user.ID, id)
+		if err != nil {
+			return nil, err
+		}
+		if recommendation.Kind != model.RecommendUnsubscribe {
+			return nil, apierror.Validation(fmt.Sprintf("%q is not an unsubscribe recommendation", id))
+		}
+		if service.RequiresGmailModify(recommendation.Action) && !user.HasScope(model.ScopeGmailModify) {
+			return nil, service.ErrGmailModifyScopeRequired
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+
+	var jobs []*model.BulkJob
+	for _, recommendation := range recommendations {
+		job, err := h.bulkJobs.Submit(model.NewBulkJob(user.ID, recommendation.Action, recommendation.Filter))
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OrganizationHandler struct {
+	orgService  service.OrganizationService
+	authHandler *AuthHandler
+	logger      echo.Logger
+}
+
+func NewOrganizationHandler(orgService service.OrganizationService, authHandler *AuthHandler, logger echo.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService:  orgService,
+		authHandler: authHandler,
+		logger:      logger,
+	}
+}
+
+// CreateOrganization starts an organization with the user as its admin
+func (h *OrganizationHandler) CreateOrganization(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req OrganizationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	detail, err := h.orgService.CreateOrganization(c.Request().Context(), user.ID, req.Name)
+	if err != nil {
+		h.logger.Error("Failed to create organization:", err)
+		return apierror.From(err, "Failed to create organization")
+	}
+
+	return c.JSON(http.StatusCreated, detail)
+}
+
+// GetOrganization returns the user's organization with its members
+func (h *OrganizationHandler) GetOrganization(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	detail, err := h.orgService.GetOrganization(c.Request().Context(), user.ID)
+	if err != nil {
+		return apierror.From(err, "Failed to get organization")
+	}
+
+	return c.JSON(http.StatusOK, detail)
+}
+
+// RenameOrganization changes the organization's name
+func (h *OrganizationHandler) RenameOrganization(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req OrganizationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	org, err := h.orgService.RenameOrganization(c.Request().Context(), user.ID, req.Name)
+	if err != nil {
+		h.logger.Error("Failed to rename organization:", err)
+		return apierror.From(err, "Failed to rename organization")
+	}
+
+	return c.JSON(http.StatusOK, org)
+}
+
+// LeaveOrganization removes the user from their organization
+func (h *OrganizationHandler) LeaveOrganization(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.orgService.LeaveOrganization(c.Request().Context(), user.ID); err != nil {
+		h.logger.Error("Failed to leave organization:", err)
+		return apierror.From(err, "Failed to leave organization")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UpdateMemberRole promotes or demotes a member
+func (h *OrganizationHandler) UpdateMemberRole(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req MemberRoleRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	member, err := h.orgService.UpdateMemberRole(c.Request().Context(), user.ID, c.Param("user_id"), req.Role)
+	if err != nil {
+		h.logger.Error("Failed to update member role:", err)
+		return apierror.From(err, "Failed to update member role")
+	}
+
+	return c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember removes another member from the organization
+func (h *OrganizationHandler) RemoveMember(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.orgService.RemoveMember(c.Request().Context(), user.ID, c.Param("user_id")); err != nil {
+		h.logger.Error("Failed to remove member:", err)
+		return apierror.From(err, "Failed to remove member")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetStats returns aggregate mailbox stats across the organization's members
+func (h *OrganizationHandler) GetStats(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	stats, err := h.orgService.GetStats(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get organization stats:", err)
+		return apierror.From(err, "Failed to get organization stats")
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// Invite sends an invitation to an email address
+func (h *OrganizationHandler) Invite(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req InvitationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	invitation, err := h.orgService.Invite(c.Request().Context(), user.ID, req.Email, req.Role)
+	if err != nil {
+		h.logger.Error("Failed to invite member:", err)
+		return apierror.From(err, "Failed to invite member")
+	}
+
+	return c.JSON(http.StatusCreated, invitation)
+}
+
+// GetInvitations lists the organization's pending invitations
+func (h *OrganizationHandler) GetInvitations(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	invitations, err := h.orgService.ListInvitations(c.Request().Context(), user.ID)
+	if err != nil {
+		return apierror.From(err, "Failed to get invitations")
+	}
+
+	return c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation withdraws a pending invitation
+func (h *OrganizationHandler) RevokeInvitation(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.orgService.RevokeInvitation(c.Request().Context(), user.ID, c.Param("id")); err != nil {
+		h.logger.Error("Failed to revoke invitation:", err)
+		return apierror.From(err, "Failed to revoke invitation")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetReceivedInvitations lists the pending invitations addressed to the user
+func (h *OrganizationHandler) GetReceivedInvitations(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	invitations, err := h.orgService.GetPendingInvitations(c.Request().Context(), user.ID)
+	if err != nil {
+		return apierror.From(err, "Failed to get invitations")
+	}
+
+	return c.JSON(http.StatusOK, invitations)
+}
+
+// AcceptInvitation joins the organization the user was invited to
+func (h *OrganizationHandler) AcceptInvitation(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	detail, err := h.orgService.AcceptInvitation(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to accept invitation:", err)
+		return apierror.From(err, "Failed to accept invitation")
+	}
+
+	return c.JSON(http.StatusOK, detail)
+}
+
+// DeclineInvitation discards an invitation addressed to the user
+func (h *OrganizationHandler) DeclineInvitation(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.orgService.DeclineInvitation(c.Request().Context(), user.ID, c.Param("id")); err != nil {
+		h.logger.Error("Failed to decline invitation:", err)
+		return apierror.From(err, "Failed to decline invitation")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
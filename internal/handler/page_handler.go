@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/view"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PageHandler renders the HTML pages with what their scripts would otherwise fetch on load
+type PageHandler struct {
+	categoryService service.CategoryService
+	authHandler     *AuthHandler
+	features        map[string]bool
+	logger          echo.Logger
+}
+
+// NewPageHandler takes the optional features the deployment has set up, e.g. {"push": true},
+// which the pages use to hide what isn't
+func NewPageHandler(categoryService service.CategoryService, authHandler *AuthHandler, features map[string]bool, logger echo.Logger) *PageHandler {
+	return &PageHandler{
+		categoryService: categoryService,
+		authHandler:     authHandler,
+		features:        features,
+		logger:          logger,
+	}
+}
+
+// Home serves the landing page
+func (h *PageHandler) Home(c echo.Context) error {
+	return h.render(c, view.HomePage)
+}
+
+// App serves the main app page; it is public, signed out it asks the user to log in
+func (h *PageHandler) App(c echo.Context) error {
+	return h.render(c, view.AppPage)
+}
+
+// Docs serves the API reference
+func (h *PageHandler) Docs(c echo.Context) error {
+	return h.render(c, view.DocsPage)
+}
+
+func (h *PageHandler) render(c echo.Context, name string) error {
+	page := view.Page{Features: h.features}
+	if user, err := h.authHandler.GetCurrentUser(c); err == nil {
+		page.User = user
+		categories, err := h.categoryService.GetAllCategories(c.Request().Context(), user.ID)
+		if err != nil {
+			// The page fetches them again on load
+			h.logger.Error("Failed to get categories for the page:", err)
+		}
+		page.Categories = categories
+	}
+	return c.Render(http.StatusOK, name, page)
+}
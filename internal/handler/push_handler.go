@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PushHandler struct {
+	pushService service.PushService
+	authHandler *AuthHandler
+	logger      echo.Logger
+}
+
+func NewPushHandler(pushService service.PushService, authHandler *AuthHandler, logger echo.Logger) *PushHandler {
+	return &PushHandler{
+		pushService: pushService,
+		authHandler: authHandler,
+		logger:      logger,
+	}
+}
+
+// GetVAPIDPublicKey returns the key the browser needs to subscribe to push notifications
+func (h *PushHandler) GetVAPIDPublicKey(c echo.Context) error {
+	if _, err := h.authHandler.GetCurrentUser(c); err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	publicKey, err := h.pushService.PublicKey()
+	if err != nil {
+		return apierror.From(err, "Failed to get VAPID public key")
+	}
+
+	return c.JSON(http.StatusOK, VAPIDKeyResponse{PublicKey: publicKey})
+}
+
+// Subscribe registers the browser's push subscription for the user
+func (h *PushHandler) Subscribe(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req PushSubscriptionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	subscription, err := h.pushService.Subscribe(c.Request().Context(), user.ID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth)
+	if err != nil {
+		h.logger.Error("Failed to register push subscription:", err)
+		return apierror.From(err, "Failed to register push subscription")
+	}
+
+	return c.JSON(http.StatusCreated, subscription)
+}
+
+// Unsubscribe removes the browser's push subscription
+func (h *PushHandler) Unsubscribe(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req PushUnsubscribeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.pushService.Unsubscribe(c.Request().Context(), user.ID, req.Endpoint); err != nil {
+		h.logger.Error("Failed to remove push subscription:", err)
+		return apierror.From(err, "Failed to remove push subscription")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RecommendationHandler struct {
+	recommendationService service.RecommendationService
+	authHandler           *AuthHandler
+	bulkJobs              *sse.BulkJobQueue
+	logger                echo.Logger
+}
+
+func NewRecommendationHandler(recommendationService service.RecommendationService, authHandler *AuthHandler, bulkJobs *sse.BulkJobQueue, logger echo.Logger) *RecommendationHandler {
+	return &RecommendationHandler{
+		recommendationService: recommendationService,
+		authHandler:           authHandler,
+		bulkJobs:              bulkJobs,
+		logger:                logger,
+	}
+}
+
+// GetRecommendations lists the cleanups proposed from the user's emails
+func (h *RecommendationHandler) GetRecommendations(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	recommendations, err := h.recommendationService.GetRecommendations(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get recommendations:", err)
+		return apierror.From(err, "Failed to get recommendations")
+	}
+
+	return c.JSON(http.StatusOK, recommendations)
+}
+
+// ApplyRecommendation queues the bulk job carrying out a recommendation and responds with it
+func (h *RecommendationHandler) ApplyRecommendation(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	recommendation, err := h.recommendationService.GetRecommendation(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		return apierror.From(err, "Recommendation not found")
+	}
+	if service.RequiresGmailModify(recommendation.Action) && !user.HasScope(model.ScopeGmailModify) {
+		return service.ErrGmailModifyScopeRequired
+	}
+
+	job, err := h.bulkJobs.Submit(model.NewBulkJob(user.ID, recommendation.Action, recommendation.Filter))
+	if err != nil {
+		return apierror.From(err, "Failed to enqueue bulk job")
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"context"
+
+	"jump-challenge/internal/repository"
+
+	"github.com/labstack/echo/v4"
+)
+
+// replicaOK is the request's context for the listings and counts shown as they are, which may
+// be read from the database replica
+func replicaOK(c echo.Context) context.Context {
+	return repository.ReplicaOK(c.Request().Context())
+}
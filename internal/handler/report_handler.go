@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReportHandler struct {
+	reportService service.ReportService
+	authHandler   *AuthHandler
+	logger        echo.Logger
+}
+
+func NewReportHandler(reportService service.ReportService, authHandler *AuthHandler, logger echo.Logger) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		authHandler:   authHandler,
+		logger:        logger,
+	}
+}
+
+// GetReports lists the user's weekly inbox reports, latest week first
+func (h *ReportHandler) GetReports(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query ReportsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	reports, err := h.reportService.GetReports(c.Request().Context(), user.ID, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get reports:", err)
+		return apierror.From(err, "Failed to get reports")
+	}
+
+	return c.JSON(http.StatusOK, reports)
+}
+
+// GetReport returns one of the user's weekly inbox reports
+func (h *ReportHandler) GetReport(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	report, err := h.reportService.GetReport(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to get report:", err)
+		return apierror.From(err, "Failed to get report")
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
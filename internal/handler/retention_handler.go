@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type RetentionHandler struct {
+	retentionService service.RetentionService
+	authHandler      *AuthHandler
+	logger           echo.Logger
+}
+
+func NewRetentionHandler(retentionService service.RetentionService, authHandler *AuthHandler, logger echo.Logger) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+		authHandler:      authHandler,
+		logger:           logger,
+	}
+}
+
+// GetRetention returns the user's retention policy along with what it has pruned so far
+func (h *RetentionHandler) GetRetention(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	policy, err := h.retentionService.GetPolicy(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get retention policy:", err)
+		return apierror.From(err, "Failed to get retention policy")
+	}
+
+	return c.JSON(http.StatusOK, RetentionResponse{
+		Policy: policy,
+		Stats:  h.retentionService.GetStats(user.ID),
+	})
+}
+
+// UpdateRetention replaces the user's retention policy; zero disables a rule
+func (h *RetentionHandler) UpdateRetention(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req RetentionRequest
+
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+
+	policy, err := h.retentionService.UpdatePolicy(c.Request().Context(), user.ID, req.BodyRetentionDays, req.RetentionDays, req.MaxEmailsPerCategory)
+	if err != nil {
+		h.logger.Error("Failed to update retention policy:", err)
+		return apierror.From(err, "Failed to update retention policy")
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
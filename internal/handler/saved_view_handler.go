@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SavedViewHandler struct {
+	viewService service.SavedViewService
+	authHandler *AuthHandler
+	logger      echo.Logger
+}
+
+func NewSavedViewHandler(viewService service.SavedViewService, authHandler *AuthHandler, logger echo.Logger) *SavedViewHandler {
+	return &SavedViewHandler{
+		viewService: viewService,
+		authHandler: authHandler,
+		logger:      logger,
+	}
+}
+
+// CreateView saves a named email filter for the user
+func (h *SavedViewHandler) CreateView(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req SavedViewRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	view, err := h.viewService.CreateView(c.Request().Context(), user.ID, req.Name, req.Filter)
+	if err != nil {
+		h.logger.Error("Failed to create view:", err)
+		return apierror.From(err, "Failed to create view")
+	}
+
+	return c.JSON(http.StatusCreated, view)
+}
+
+// GetViews lists the user's saved views
+func (h *SavedViewHandler) GetViews(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	views, err := h.viewService.GetViews(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get views:", err)
+		return apierror.From(err, "Failed to get views")
+	}
+
+	return c.JSON(http.StatusOK, views)
+}
+
+// GetView retrieves one of the user's saved views
+func (h *SavedViewHandler) GetView(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	view, err := h.viewService.GetView(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		return apierror.From(err, "Failed to get view")
+	}
+
+	return c.JSON(http.StatusOK, view)
+}
+
+// UpdateView replaces the name and filter of one of the user's saved views
+func (h *SavedViewHandler) UpdateView(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req SavedViewRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	view, err := h.viewService.UpdateView(c.Request().Context(), user.ID, c.Param("id"), req.Name, req.Filter)
+	if err != nil {
+		h.logger.Error("Failed to update view:", err)
+		return apierror.From(err, "Failed to update view")
+	}
+
+	return c.JSON(http.StatusOK, view)
+}
+
+// DeleteView removes one of the user's saved views; the emails it matched are untouched
+func (h *SavedViewHandler) DeleteView(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.viewService.DeleteView(c.Request().Context(), user.ID, c.Param("id")); err != nil {
+		h.logger.Error("Failed to delete view:", err)
+		return apierror.From(err, "Failed to delete view")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetViewEmails lists the emails matching a saved view, newest first
+// (optional ?limit=N and ?include_body=true; the archive state comes from the view's filter)
+func (h *SavedViewHandler) GetViewEmails(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query ListEmailsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	emails, err := h.viewService.ListEmails(c.Request().Context(), user.ID, c.Param("id"), query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get view emails:", err)
+		return apierror.From(err, "Failed to get view emails")
+	}
+
+	return writeEmails(c, listEmails(emails, query.IncludeBody))
+}
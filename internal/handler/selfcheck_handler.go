@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SelfCheckHandler lets operators check the app's credentials and services while it runs
+type SelfCheckHandler struct {
+	selfCheck   service.SelfCheckService
+	authHandler *AuthHandler
+	logger      echo.Logger
+}
+
+func NewSelfCheckHandler(selfCheck service.SelfCheckService, authHandler *AuthHandler, logger echo.Logger) *SelfCheckHandler {
+	return &SelfCheckHandler{
+		selfCheck:   selfCheck,
+		authHandler: authHandler,
+		logger:      logger,
+	}
+}
+
+// SelfCheck runs every check; it answers 503 when one fails, so monitoring can watch it
+func (h *SelfCheckHandler) SelfCheck(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	// The checks spend an AI completion and describe the deployment's setup
+	if !h.authHandler.IsAdmin(user) {
+		return apierror.Forbidden("Only operators can run the self check")
+	}
+
+	report := h.selfCheck.Run(c.Request().Context())
+	if !report.OK {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
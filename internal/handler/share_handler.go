@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/view"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ShareHandler struct {
+	shareService service.ShareService
+	authHandler  *AuthHandler
+	logger       echo.Logger
+}
+
+func NewShareHandler(shareService service.ShareService, authHandler *AuthHandler, logger echo.Logger) *ShareHandler {
+	return &ShareHandler{
+		shareService: shareService,
+		authHandler:  authHandler,
+		logger:       logger,
+	}
+}
+
+// CreateShare creates a public read-only link to an email's summary
+func (h *ShareHandler) CreateShare(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req ShareEmailRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	share, err := h.shareService.CreateShare(c.Request().Context(), user.ID, c.Param("id"),
+		time.Duration(req.ExpiresInHours)*time.Hour, req.IncludeBody)
+	if err != nil {
+		h.logger.Error("Failed to create share:", err)
+		return apierror.From(err, "Failed to create share")
+	}
+
+	return c.JSON(http.StatusCreated, share)
+}
+
+// GetShares lists the share links of an email, including expired and revoked ones
+func (h *ShareHandler) GetShares(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	shares, err := h.shareService.GetShares(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		return apierror.From(err, "Failed to get shares")
+	}
+	if shares == nil {
+		shares = []*model.EmailShare{}
+	}
+
+	return c.JSON(http.StatusOK, shares)
+}
+
+// RevokeShare disables a share link
+func (h *ShareHandler) RevokeShare(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.shareService.RevokeShare(c.Request().Context(), user.ID, c.Param("id"), c.Param("share_id")); err != nil {
+		h.logger.Error("Failed to revoke share:", err)
+		return apierror.From(err, "Failed to revoke share")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ViewShare renders the public page behind a share link; it needs no session
+func (h *ShareHandler) ViewShare(c echo.Context) error {
+	// Shared pages must not linger in caches, search engines or referrers
+	header := c.Response().Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Referrer-Policy", "no-referrer")
+	header.Set("X-Robots-Tag", "noindex")
+
+	shared, err := h.shareService.OpenShare(c.Request().Context(), c.Param("token"))
+	if err != nil {
+		if !errors.Is(err, apierror.ErrNotFound) {
+			h.logger.Error("Failed to open share:", err)
+		}
+		return c.Render(http.StatusNotFound, view.SharedEmail, nil)
+	}
+
+	return c.Render(http.StatusOK, view.SharedEmail, shared)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ShipmentHandler struct {
+	shipmentService service.ShipmentService
+	authHandler     *AuthHandler
+	logger          echo.Logger
+}
+
+func NewShipmentHandler(shipmentService service.ShipmentService, authHandler *AuthHandler, logger echo.Logger) *ShipmentHandler {
+	return &ShipmentHandler{
+		shipmentService: shipmentService,
+		authHandler:     authHandler,
+		logger:          logger,
+	}
+}
+
+// GetShipments lists the packages found in the user's shipping notifications
+func (h *ShipmentHandler) GetShipments(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	shipments, err := h.shipmentService.GetShipments(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get shipments:", err)
+		return apierror.From(err, "Failed to get shipments")
+	}
+
+	return c.JSON(http.StatusOK, shipments)
+}
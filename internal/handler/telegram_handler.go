@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TelegramHandler struct {
+	telegramService service.TelegramService
+	authHandler     *AuthHandler
+	logger          echo.Logger
+}
+
+func NewTelegramHandler(telegramService service.TelegramService, authHandler *AuthHandler, logger echo.Logger) *TelegramHandler {
+	return &TelegramHandler{
+		telegramService: telegramService,
+		authHandler:     authHandler,
+		logger:          logger,
+	}
+}
+
+// GetLink returns the Telegram chat linked to the user
+func (h *TelegramHandler) GetLink(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	link, err := h.telegramService.GetLink(c.Request().Context(), user.ID)
+	if err != nil {
+		return apierror.From(err, "Failed to get telegram link")
+	}
+
+	return c.JSON(http.StatusOK, link)
+}
+
+// CreateLinkCode returns a code the user sends to the bot to link their chat
+func (h *TelegramHandler) CreateLinkCode(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	code, err := h.telegramService.CreateLinkCode(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to create telegram link code:", err)
+		return apierror.From(err, "Failed to create telegram link code")
+	}
+
+	return c.JSON(http.StatusCreated, code)
+}
+
+// Unlink stops the bot from messaging the user's chat
+func (h *TelegramHandler) Unlink(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.telegramService.Unlink(c.Request().Context(), user.ID); err != nil {
+		h.logger.Error("Failed to unlink telegram chat:", err)
+		return apierror.From(err, "Failed to unlink telegram chat")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
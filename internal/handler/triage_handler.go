@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TriageHandler struct {
+	triageService service.TriageService
+	authHandler   *AuthHandler
+	logger        echo.Logger
+}
+
+func NewTriageHandler(triageService service.TriageService, authHandler *AuthHandler, logger echo.Logger) *TriageHandler {
+	return &TriageHandler{
+		triageService: triageService,
+		authHandler:   authHandler,
+		logger:        logger,
+	}
+}
+
+// GetNext returns the oldest email the user hasn't triaged yet with a suggested action
+func (h *TriageHandler) GetNext(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	item, err := h.triageService.Next(c.Request().Context(), user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get next email to triage:", err)
+		return apierror.From(err, "Failed to get next email to triage")
+	}
+
+	return c.JSON(http.StatusOK, item)
+}
+
+// Decide applies the user's decision on an email and returns the next one to triage
+func (h *TriageHandler) Decide(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req TriageDecisionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	item, err := h.triageService.Decide(c.Request().Context(), user.ID, c.Param("id"), req.Action)
+	if err != nil {
+		h.logger.Error("Failed to triage email:", err)
+		return apierror.From(err, "Failed to triage email")
+	}
+
+	return c.JSON(http.StatusOK, item)
+}
@@ -0,0 +1,391 @@
+package handler
+
+import (
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/sse"
+)
+
+// ErrorResponse is returned by every endpoint on failure
+type ErrorResponse struct {
+	Error     string                `json:"error"`
+	Code      string                `json:"code"`                 // machine-readable, see the apierror package
+	ReauthURL string                `json:"reauth_url,omitempty"` // set when the user must grant Gmail modify access
+	Fields    []apierror.FieldError `json:"fields,omitempty"`     // set when request fields failed validation
+	Current   any                   `json:"current,omitempty"`    // set on conflicts to the existing resource
+}
+
+// MessageResponse acknowledges an action that has no other result
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// CategoryRequest creates a category; team categories join the organization's taxonomy
+type CategoryRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=1000"`
+	Team        bool   `json:"team,omitempty"`
+	Hints       string `json:"hints,omitempty" validate:"max=2000"` // examples for the AI to classify by
+	Summarize   string `json:"summarize,omitempty" validate:"omitempty,oneof=on low_priority off"`
+	Sensitive   bool   `json:"sensitive,omitempty"` // kept away from the AI in privacy mode
+}
+
+// UpdateCategoryRequest changes a category; empty fields are left unchanged, except hints,
+// which are removed when empty. Hints and sensitive are left unchanged when missing.
+type UpdateCategoryRequest struct {
+	Name        string  `json:"name" validate:"max=100"`
+	Description string  `json:"description" validate:"max=1000"`
+	Hints       *string `json:"hints,omitempty" validate:"omitempty,max=2000"`
+	Summarize   string  `json:"summarize,omitempty" validate:"omitempty,oneof=on low_priority off"`
+	Sensitive   *bool   `json:"sensitive,omitempty"`
+}
+
+// EmailSelectionRequest selects emails either by ID or, for a background job, by filter
+type EmailSelectionRequest struct {
+	EmailIDs []string           `json:"email_ids,omitempty" validate:"max=1000,dive,required"`
+	Filter   *model.EmailFilter `json:"filter,omitempty"`
+}
+
+// BulkActionRequest applies an action to the selected emails
+type BulkActionRequest struct {
+	EmailIDs    []string           `json:"email_ids,omitempty" validate:"max=1000,dive,required"`
+	Filter      *model.EmailFilter `json:"filter,omitempty"`
+	Action      string             `json:"action" validate:"required,oneof=archive read unread star unstar move spam delete local_archive local_unarchive unsubscribe"`
+	Label       string             `json:"label,omitempty" validate:"max=225"` // Gmail label for the move action
+	BlockSender bool               `json:"block_sender,omitempty"`             // the spam action also blocks the senders
+}
+
+// AutomationRequest attaches an action to a category; a zero delay runs it as soon as emails are classified
+type AutomationRequest struct {
+	CategoryID string `json:"category_id" validate:"required,max=100"`
+	Action     string `json:"action" validate:"required,oneof=archive read star move local_archive delete"`
+	Label      string `json:"label,omitempty" validate:"max=225"` // Gmail label for the move action
+	DelayDays  int    `json:"delay_days" validate:"min=0,max=36500"`
+	Team       bool   `json:"team,omitempty"` // run on every organization member's emails
+}
+
+// UpdateAutomationRequest replaces an automation; omitting enabled keeps it enabled
+type UpdateAutomationRequest struct {
+	CategoryID string `json:"category_id" validate:"required,max=100"`
+	Action     string `json:"action" validate:"required,oneof=archive read star move local_archive delete"`
+	Label      string `json:"label,omitempty" validate:"max=225"`
+	DelayDays  int    `json:"delay_days" validate:"min=0,max=36500"`
+	Enabled    *bool  `json:"enabled,omitempty"`
+}
+
+// CleanupPolicyRequest schedules a cleanup of a category's emails older than a number of days;
+// omitting enabled creates or keeps the policy enabled
+type CleanupPolicyRequest struct {
+	Name          string `json:"name" validate:"required,max=100"`
+	CategoryID    string `json:"category_id" validate:"required,max=100"`
+	Unread        *bool  `json:"unread,omitempty"` // only unread or only read emails
+	OlderThanDays int    `json:"older_than_days" validate:"min=0,max=36500"`
+	Action        string `json:"action" validate:"required,oneof=archive read local_archive delete"`
+	Schedule      string `json:"schedule" validate:"required,oneof=daily weekly"`
+	Enabled       *bool  `json:"enabled,omitempty"`
+}
+
+// CleanupRunsQuery limits the cleanup runs listing
+type CleanupRunsQuery struct {
+	Limit int `query:"limit" validate:"min=0" doc:"Maximum number of results, 0 for no limit"`
+}
+
+// AICallsQuery limits the AI debug calls listing
+type AICallsQuery struct {
+	Limit int `query:"limit" validate:"min=0" doc:"Maximum number of results, 0 for no limit"`
+}
+
+// ReplayAICallRequest picks what a recorded prompt is replayed against
+type ReplayAICallRequest struct {
+	Provider string `json:"provider,omitempty" validate:"max=50"` // the recorded call's provider when empty
+	Model    string `json:"model,omitempty" validate:"max=100"`   // the provider's default model when empty
+}
+
+// ExperimentReportQuery picks the experiment to report on
+type ExperimentReportQuery struct {
+	Name string `query:"name" validate:"max=100" doc:"Experiment name, the running experiment when empty"`
+}
+
+// SavedViewRequest names an email filter to save as a smart view
+type SavedViewRequest struct {
+	Name   string            `json:"name" validate:"required,max=100"`
+	Filter model.EmailFilter `json:"filter"`
+}
+
+// TriageDecisionRequest is the user's decision on an email in triage; keep leaves it as it is
+type TriageDecisionRequest struct {
+	Action string `json:"action" validate:"required,oneof=keep archive read star local_archive delete spam"`
+}
+
+// ClassifyRequest is an ad-hoc email to classify against the user's categories
+type ClassifyRequest struct {
+	Subject string `json:"subject,omitempty" validate:"max=1000"`
+	Body    string `json:"body" validate:"required,max=100000"`
+}
+
+// ClassifyResponse names the category the email was classified into
+type ClassifyResponse struct {
+	Classification string `json:"classification"`
+}
+
+// EmailBodyResponse carries the full body that list endpoints omit
+type EmailBodyResponse struct {
+	ID        string `json:"id"`
+	Body      string `json:"body"`
+	Truncated bool   `json:"truncated"`
+	Pruned    bool   `json:"pruned"`
+	TextOnly  bool   `json:"text_only"` // the body is plain text, as privacy mode shows it
+}
+
+// RetentionRequest replaces a user's retention policy; zero disables a rule
+type RetentionRequest struct {
+	BodyRetentionDays    int `json:"body_retention_days" validate:"min=0,max=36500"`
+	RetentionDays        int `json:"retention_days" validate:"min=0,max=36500"`
+	MaxEmailsPerCategory int `json:"max_emails_per_category" validate:"min=0"`
+}
+
+// NotificationSettingsRequest replaces a user's notification preferences; empty quiet hours disable them
+type NotificationSettingsRequest struct {
+	QuietHoursStart string   `json:"quiet_hours_start" validate:"max=5"` // HH:MM
+	QuietHoursEnd   string   `json:"quiet_hours_end" validate:"max=5"`   // HH:MM
+	TimeZone        string   `json:"time_zone" validate:"max=100"`       // IANA name, empty for UTC
+	EventTypes      []string `json:"event_types" validate:"max=10,dive,oneof=new_email email_summary bulk_job otp shipment_delivered security_alert vip_email unsubscribe_ineffective unsubscribe_manual_action cleanup_completed"`
+	MinPriority     string   `json:"min_priority" validate:"omitempty,oneof=low normal high"`
+}
+
+// CategorizeEmailRequest names the category an email is filed under instead of the one it was classified in
+type CategorizeEmailRequest struct {
+	CategoryID string `json:"category_id" validate:"required"`
+}
+
+// ResolveReviewRequest names the category an email of the review queue is filed under, the
+// suggested one or another
+type ResolveReviewRequest struct {
+	CategoryID string `json:"category_id" validate:"required"`
+}
+
+// PrivacySettingsRequest turns privacy mode on or off
+type PrivacySettingsRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// SystemEmailSettingsRequest replaces how bounces and auto-replies are handled
+type SystemEmailSettingsRequest struct {
+	AutoArchive bool `json:"auto_archive"`
+}
+
+// ShareEmailRequest creates a public share link; the body is left out unless asked for
+type ShareEmailRequest struct {
+	ExpiresInHours int  `json:"expires_in_hours" validate:"min=0,max=720"` // 0 for the 72 hour default
+	IncludeBody    bool `json:"include_body"`
+}
+
+// PushSubscriptionRequest is the PushSubscription a browser returns from pushManager.subscribe
+type PushSubscriptionRequest struct {
+	Endpoint string               `json:"endpoint" validate:"required,max=1000"`
+	Keys     PushSubscriptionKeys `json:"keys"`
+}
+
+// PushSubscriptionKeys are the browser's encryption keys, base64url encoded
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh" validate:"required,max=200"`
+	Auth   string `json:"auth" validate:"required,max=100"`
+}
+
+// PushUnsubscribeRequest removes the subscription for a browser endpoint
+type PushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" validate:"required,max=1000"`
+}
+
+// WebhookRequest sets the URL the user's VIP emails are posted to
+type WebhookRequest struct {
+	URL string `json:"url" validate:"required,max=2000"`
+}
+
+// ListEmailsQuery holds the query parameters of email listings
+type ListEmailsQuery struct {
+	Limit       int    `query:"limit" validate:"min=0" doc:"Maximum number of results, 0 for no limit"`
+	Archived    string `query:"archived" validate:"omitempty,oneof=true false all" doc:"true for locally archived emails only, all to include them"`
+	IncludeBody bool   `query:"include_body" doc:"Include email bodies"`
+}
+
+// EmailWindowQuery holds the position and size of a window of the email list
+type EmailWindowQuery struct {
+	Offset     int    `query:"offset" validate:"min=0" doc:"Number of emails before the window"`
+	Limit      int    `query:"limit" validate:"min=0,max=200" doc:"Maximum number of emails in the window, 0 for 50"`
+	CategoryID string `query:"category_id" validate:"max=100" doc:"Only list emails in this category"`
+	Archived   string `query:"archived" validate:"omitempty,oneof=true false all" doc:"true for locally archived emails only, all to include them"`
+}
+
+// EmailDeltaQuery holds the cursor of the email changes a client already has
+type EmailDeltaQuery struct {
+	Since string `query:"since" validate:"max=200" doc:"Cursor of the previous delta, empty for every email"`
+	Limit int    `query:"limit" validate:"min=0,max=1000" doc:"Maximum number of changes, 0 for 500"`
+}
+
+// EmailDetailQuery holds the listing filter an email was opened from, for previous/next navigation
+type EmailDetailQuery struct {
+	CategoryID string `query:"category_id" validate:"max=100" doc:"Navigate within this category"`
+	Sender     string `query:"sender" validate:"max=320" doc:"Navigate within emails from this sender address"`
+	Archived   string `query:"archived" validate:"omitempty,oneof=true false all" doc:"true to navigate locally archived emails only, all to include them"`
+}
+
+// ImageProxyQuery holds the address of an image to load through the proxy
+type ImageProxyQuery struct {
+	URL string `query:"url" validate:"required,max=2048" doc:"Address of the remote image"`
+}
+
+// SyncEmailsQuery holds the query parameters of a Gmail sync
+type SyncEmailsQuery struct {
+	MaxResults   int64  `query:"max_results" validate:"min=0,max=500" doc:"Maximum number of emails to fetch, 0 for MAX_FETCH_EMAILS"`
+	AfterEmailID string `query:"after_email_id" validate:"max=100" doc:"Only fetch emails newer than this Gmail ID"`
+}
+
+// SyncEmailsResponse accounts for the emails a sync fetched from Gmail
+type SyncEmailsResponse struct {
+	Message string `json:"message"`
+	Fetched int    `json:"fetched"`
+	New     int    `json:"new"`             // stored, classified and summarized
+	Updated int    `json:"updated"`         // stored before, with the read or starred state changed in Gmail
+	Skipped int    `json:"skipped"`         // stored before and unchanged, trashed, blocked or over the quota
+	Stale   bool   `json:"stale,omitempty"` // Gmail was unreachable, nothing was synced
+}
+
+// OnboardingRequest completes the user's current onboarding step, named so that a repeated
+// request can't complete the next one; skip moves on without doing it
+type OnboardingRequest struct {
+	Step              string   `json:"step" validate:"required,oneof=categories backfill unsubscribe"`
+	Categories        []string `json:"categories,omitempty" validate:"max=20,dive,required,max=100"`         // suggested categories to create
+	RecommendationIDs []string `json:"recommendation_ids,omitempty" validate:"max=20,dive,required,max=400"` // unsubscribe recommendations to carry out
+	Skip              bool     `json:"skip,omitempty"`
+}
+
+// OnboardingResponse is the user's next onboarding step, with what the completed one did
+type OnboardingResponse struct {
+	*model.Onboarding
+	Backfill *SyncEmailsResponse `json:"backfill,omitempty"`
+	Jobs     []*model.BulkJob    `json:"jobs,omitempty"` // the queued unsubscribes
+}
+
+// VIPSenderRequest marks a sender as VIP
+type VIPSenderRequest struct {
+	Address string `json:"address" validate:"required,max=320"`
+	Name    string `json:"name" validate:"max=255"`
+}
+
+// SecurityEventsQuery limits the security events listing
+type SecurityEventsQuery struct {
+	Limit int `query:"limit" validate:"min=0" doc:"Maximum number of results, 0 for no limit"`
+}
+
+// EscalateUnsubscribeRequest is what to do about a sender that ignored an unsubscribe
+type EscalateUnsubscribeRequest struct {
+	Action string `json:"action" validate:"required,oneof=block spam"`
+}
+
+// UnsubscribeAttemptsQuery limits the unsubscribe attempts listing
+type UnsubscribeAttemptsQuery struct {
+	Limit int `query:"limit" validate:"min=0" doc:"Maximum number of results, 0 for no limit"`
+}
+
+// ReportsQuery limits the reports listing
+type ReportsQuery struct {
+	Limit int `query:"limit" validate:"min=0" doc:"Maximum number of results, 0 for no limit"`
+}
+
+// SenderProfileQuery holds the query parameters of a sender profile request
+type SenderProfileQuery struct {
+	Refresh bool `query:"refresh" doc:"Regenerate the profile even if the sender's emails are unchanged"`
+}
+
+// RetentionResponse is the retention policy along with what it has pruned so far
+type RetentionResponse struct {
+	Policy *model.RetentionPolicy `json:"policy"`
+	Stats  model.PruneStats       `json:"stats"`
+}
+
+// OrganizationRequest creates or renames the user's organization
+type OrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// InvitationRequest invites an email address to the organization
+type InvitationRequest struct {
+	Email string `json:"email" validate:"required,max=320"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// MemberRoleRequest changes a member's role
+type MemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// ConnectionsResponse lists every user's SSE connections, for operators
+type ConnectionsResponse struct {
+	Users []sse.Presence    `json:"users"`
+	Stats sse.DeliveryStats `json:"stats"`
+}
+
+// VAPIDKeyResponse is the application server key passed to pushManager.subscribe
+type VAPIDKeyResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+// MeResponse describes the authenticated user and their session
+type MeResponse struct {
+	User    CurrentUser        `json:"user"`
+	Session SessionState       `json:"session"`
+	Gmail   *model.GmailHealth `json:"gmail,omitempty"`
+	Banner  *Banner            `json:"banner,omitempty"`
+}
+
+// Banner is a notice the frontend shows on every page until the user deals with its cause
+type Banner struct {
+	Type      string `json:"type"` // reauth_required
+	Message   string `json:"message"`
+	ActionURL string `json:"action_url"` // where the user deals with it
+}
+
+// AccountMergeRequest redeems a merge code created signed in to the duplicate account
+type AccountMergeRequest struct {
+	Code string `json:"code" validate:"required,max=64"`
+}
+
+// WorkspaceMailboxRequest designates a mailbox of the Workspace domain to be synced
+type WorkspaceMailboxRequest struct {
+	Email string `json:"email" validate:"required,max=254"`
+	Name  string `json:"name" validate:"max=255"` // the part before the @ when empty; replaced when its user signs in
+}
+
+// CurrentUser exposes only profile fields; the user model also carries OAuth tokens
+type CurrentUser struct {
+	ID             string `json:"id"`
+	Email          string `json:"email"`
+	Name           string `json:"name"`
+	CanModify      bool   `json:"can_modify"`
+	TimeZone       string `json:"time_zone"`
+	Locale         string `json:"locale"`
+	OnboardingStep string `json:"onboarding_step"` // "done" once through the onboarding wizard
+	NeedsReauth    bool   `json:"needs_reauth"`    // Google refused the user's grant, see the banner
+}
+
+// LocaleSettings is the language of API messages and AI summaries; empty follows the
+// browser's Accept-Language and leaves summaries in each email's language
+type LocaleSettings struct {
+	Locale string `json:"locale" validate:"omitempty,oneof=en es pt"`
+}
+
+// TimeZoneSettings is the IANA time zone reports, cleanup schedules and quiet hours follow;
+// empty means UTC
+type TimeZoneSettings struct {
+	TimeZone string `json:"time_zone" validate:"max=100"`
+}
+
+// SessionState reports when the current session was issued and when it expires
+type SessionState struct {
+	TTLSeconds int64      `json:"ttl_seconds"`
+	IssuedAt   *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
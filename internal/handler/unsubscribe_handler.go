@@ -3,7 +3,9 @@ package handler
 import (
 	"net/http"
 
+	"jump-challenge/internal/apierror"
 	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
 
 	"github.com/labstack/echo/v4"
 )
@@ -11,13 +13,15 @@ import (
 type UnsubscribeHandler struct {
 	unsubscribeService service.UnsubscribeService
 	authHandler        *AuthHandler
+	bulkJobs           *sse.BulkJobQueue
 	logger             echo.Logger
 }
 
-func NewUnsubscribeHandler(unsubscribeService service.UnsubscribeService, authHandler *AuthHandler, logger echo.Logger) *UnsubscribeHandler {
+func NewUnsubscribeHandler(unsubscribeService service.UnsubscribeService, authHandler *AuthHandler, bulkJobs *sse.BulkJobQueue, logger echo.Logger) *UnsubscribeHandler {
 	return &UnsubscribeHandler{
 		unsubscribeService: unsubscribeService,
 		authHandler:        authHandler,
+		bulkJobs:           bulkJobs,
 		logger:             logger,
 	}
 }
@@ -26,38 +30,95 @@ func NewUnsubscribeHandler(unsubscribeService service.UnsubscribeService, authHa
 func (h *UnsubscribeHandler) UnsubscribeEmails(c echo.Context) error {
 	user, err := h.authHandler.GetCurrentUser(c)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
-		})
+		return apierror.Unauthorized("Unauthorized")
 	}
 
-	// Parse the request body
-	var req struct {
-		EmailIDs []string `json:"email_ids"`
+	// Parse the request body; either email_ids or filter selects the emails
+	var req EmailSelectionRequest
+
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
 	}
 
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
+	if req.Filter != nil {
+		job, err := h.bulkJobs.Enqueue(user.ID, "unsubscribe", *req.Filter)
+		if err != nil {
+			return apierror.From(err, "Failed to enqueue bulk job")
+		}
+		return c.JSON(http.StatusAccepted, job)
 	}
 
 	if len(req.EmailIDs) == 0 {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Email IDs are required",
-		})
+		return apierror.Validation("Email IDs are required")
 	}
 
 	// Perform the unsubscribe action
 	err = h.unsubscribeService.UnsubscribeEmails(c.Request().Context(), req.EmailIDs, user.ID)
 	if err != nil {
 		h.logger.Error("Failed to unsubscribe emails:", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to unsubscribe from emails",
-		})
+		return apierror.From(err, "Failed to unsubscribe from emails")
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Unsubscribe process completed",
+	return c.JSON(http.StatusOK, MessageResponse{
+		Message: "Unsubscribe process completed",
 	})
-}
\ No newline at end of file
+}
+
+// GetAttempts lists the user's unsubscribe attempts, newest first, with the dark patterns found
+// on each page and what was selected there
+func (h *UnsubscribeHandler) GetAttempts(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var query UnsubscribeAttemptsQuery
+	if err := bindQuery(c, &query); err != nil {
+		return err
+	}
+
+	attempts, err := h.unsubscribeService.GetAttempts(c.Request().Context(), user.ID, query.Limit)
+	if err != nil {
+		h.logger.Error("Failed to get unsubscribe attempts:", err)
+		return apierror.From(err, "Failed to get unsubscribe attempts")
+	}
+
+	return c.JSON(http.StatusOK, attempts)
+}
+
+// Escalate blocks the sender of an ineffective unsubscribe, or also reports their emails as spam
+func (h *UnsubscribeHandler) Escalate(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req EscalateUnsubscribeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	attempt, err := h.unsubscribeService.Escalate(c.Request().Context(), user.ID, c.Param("id"), req.Action)
+	if err != nil {
+		h.logger.Error("Failed to escalate unsubscribe:", err)
+		return apierror.From(err, "Failed to escalate unsubscribe")
+	}
+
+	return c.JSON(http.StatusOK, attempt)
+}
+
+// CompleteManually records that the user finished an unsubscribe the service had to leave to them
+func (h *UnsubscribeHandler) CompleteManually(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	attempt, err := h.unsubscribeService.CompleteManually(c.Request().Context(), user.ID, c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to complete unsubscribe:", err)
+		return apierror.From(err, "Failed to complete unsubscribe")
+	}
+
+	return c.JSON(http.StatusOK, attempt)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+	authHandler    *AuthHandler
+	logger         echo.Logger
+}
+
+func NewWebhookHandler(webhookService service.WebhookService, authHandler *AuthHandler, logger echo.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		authHandler:    authHandler,
+		logger:         logger,
+	}
+}
+
+// GetWebhook returns the URL the user's VIP emails are posted to
+func (h *WebhookHandler) GetWebhook(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	webhook, err := h.webhookService.GetWebhook(c.Request().Context(), user.ID)
+	if err != nil {
+		return apierror.From(err, "Failed to get webhook")
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// SetWebhook posts the user's VIP emails to the URL, with a new signing secret
+func (h *WebhookHandler) SetWebhook(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	var req WebhookRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	webhook, err := h.webhookService.SetWebhook(c.Request().Context(), user.ID, req.URL)
+	if err != nil {
+		h.logger.Error("Failed to set webhook:", err)
+		return apierror.From(err, "Failed to set webhook")
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook stops posting the user's VIP emails
+func (h *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request().Context(), user.ID); err != nil {
+		h.logger.Error("Failed to delete webhook:", err)
+		return apierror.From(err, "Failed to delete webhook")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
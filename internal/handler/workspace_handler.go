@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WorkspaceHandler lets operators designate the mailboxes of the Workspace domain synced with
+// domain-wide delegation
+type WorkspaceHandler struct {
+	workspaceService service.WorkspaceService
+	authHandler      *AuthHandler
+	logger           echo.Logger
+}
+
+func NewWorkspaceHandler(workspaceService service.WorkspaceService, authHandler *AuthHandler, logger echo.Logger) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+		authHandler:      authHandler,
+		logger:           logger,
+	}
+}
+
+// GetConnection returns the Workspace domain, what its admin authorizes for the service account
+// and the designated mailboxes
+func (h *WorkspaceHandler) GetConnection(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	if !h.authHandler.IsAdmin(user) {
+		return apierror.Forbidden("Only operators can manage the Workspace domain")
+	}
+
+	connection, err := h.workspaceService.Connection(c.Request().Context())
+	if err != nil {
+		h.logger.Error("Failed to get Workspace connection:", err)
+		return apierror.From(err, "Failed to get Workspace connection")
+	}
+
+	return c.JSON(http.StatusOK, connection)
+}
+
+// AddMailbox designates a mailbox of the domain to be synced
+func (h *WorkspaceHandler) AddMailbox(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	if !h.authHandler.IsAdmin(user) {
+		return apierror.Forbidden("Only operators can manage the Workspace domain")
+	}
+
+	var req WorkspaceMailboxRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	mailbox, err := h.workspaceService.AddMailbox(c.Request().Context(), req.Email, req.Name)
+	if err != nil {
+		h.logger.Error("Failed to add Workspace mailbox:", err)
+		return apierror.From(err, "Failed to add Workspace mailbox")
+	}
+
+	return c.JSON(http.StatusCreated, mailbox)
+}
+
+// RemoveMailbox stops syncing a mailbox while nobody watches it
+func (h *WorkspaceHandler) RemoveMailbox(c echo.Context) error {
+	user, err := h.authHandler.GetCurrentUser(c)
+	if err != nil {
+		return apierror.Unauthorized("Unauthorized")
+	}
+	if !h.authHandler.IsAdmin(user) {
+		return apierror.Forbidden("Only operators can manage the Workspace domain")
+	}
+
+	if err := h.workspaceService.RemoveMailbox(c.Request().Context(), c.Param("email")); err != nil {
+		h.logger.Error("Failed to remove Workspace mailbox:", err)
+		return apierror.From(err, "Failed to remove Workspace mailbox")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,104 @@
+package i18n
+
+// The catalogs translate the messages API clients see most: session and validation errors,
+// missing resources and quota errors. Server failures keep their English message.
+
+var portuguese = map[string]string{
+	"Unauthorized":             "Não autorizado",
+	"unauthorized":             "não autorizado",
+	"forbidden":                "proibido",
+	"not found":                "não encontrado",
+	"conflict":                 "conflito",
+	"validation failed":        "validação falhou",
+	"service unavailable":      "serviço indisponível",
+	"upstream service failed":  "serviço externo falhou",
+	"internal error":           "erro interno",
+	"Internal server error":    "Erro interno do servidor",
+	"Invalid request":          "Requisição inválida",
+	"Invalid request body":     "Corpo da requisição inválido",
+	"Invalid query parameters": "Parâmetros de consulta inválidos",
+
+	"is required":                      "é obrigatório",
+	"does not exist":                   "não existe",
+	"must not be negative":             "não pode ser negativo",
+	"must be an email address":         "deve ser um endereço de email",
+	"must be an https URL":             "deve ser uma URL https",
+	"must be a time as HH:MM":          "deve ser um horário no formato HH:MM",
+	"must be an IANA time zone":        "deve ser um fuso horário IANA",
+	"must be a supported locale":       "deve ser um idioma suportado",
+	"must be daily or weekly":          "deve ser daily ou weekly",
+	"must be one of: %s":               "deve ser um de: %s",
+	"must be at least %s characters":   "deve ter pelo menos %s caracteres",
+	"must be at most %s characters":    "deve ter no máximo %s caracteres",
+	"must be at least %s items":        "deve ter pelo menos %s itens",
+	"must be at most %s items":         "deve ter no máximo %s itens",
+	"must be at least %s":              "deve ser pelo menos %s",
+	"must be at most %s":               "deve ser no máximo %s",
+	"unsupported bulk action: %s":      "ação em massa não suportada: %s",
+	"Email IDs are required":           "Os IDs dos emails são obrigatórios",
+	"category name already exists":     "já existe uma categoria com esse nome",
+	"Gmail modify permission required": "É necessária a permissão para modificar o Gmail",
+
+	"Monthly quota exceeded, upgrade your plan to continue": "Cota mensal excedida, mude de plano para continuar",
+
+	"email not found":          "email não encontrado",
+	"category not found":       "categoria não encontrada",
+	"user not found":           "usuário não encontrado",
+	"automation not found":     "automação não encontrada",
+	"cleanup policy not found": "política de limpeza não encontrada",
+	"report not found":         "relatório não encontrado",
+	"view not found":           "visualização não encontrada",
+	"share not found":          "compartilhamento não encontrado",
+
+	"Uncategorized": "Sem categoria",
+}
+
+var spanish = map[string]string{
+	"Unauthorized":             "No autorizado",
+	"unauthorized":             "no autorizado",
+	"forbidden":                "prohibido",
+	"not found":                "no encontrado",
+	"conflict":                 "conflicto",
+	"validation failed":        "la validación falló",
+	"service unavailable":      "servicio no disponible",
+	"upstream service failed":  "el servicio externo falló",
+	"internal error":           "error interno",
+	"Internal server error":    "Error interno del servidor",
+	"Invalid request":          "Solicitud no válida",
+	"Invalid request body":     "Cuerpo de la solicitud no válido",
+	"Invalid query parameters": "Parámetros de consulta no válidos",
+
+	"is required":                      "es obligatorio",
+	"does not exist":                   "no existe",
+	"must not be negative":             "no puede ser negativo",
+	"must be an email address":         "debe ser una dirección de correo",
+	"must be an https URL":             "debe ser una URL https",
+	"must be a time as HH:MM":          "debe ser una hora con formato HH:MM",
+	"must be an IANA time zone":        "debe ser una zona horaria IANA",
+	"must be a supported locale":       "debe ser un idioma admitido",
+	"must be daily or weekly":          "debe ser daily o weekly",
+	"must be one of: %s":               "debe ser uno de: %s",
+	"must be at least %s characters":   "debe tener al menos %s caracteres",
+	"must be at most %s characters":    "debe tener como máximo %s caracteres",
+	"must be at least %s items":        "debe tener al menos %s elementos",
+	"must be at most %s items":         "debe tener como máximo %s elementos",
+	"must be at least %s":              "debe ser al menos %s",
+	"must be at most %s":               "debe ser como máximo %s",
+	"unsupported bulk action: %s":      "acción masiva no admitida: %s",
+	"Email IDs are required":           "Los IDs de los correos son obligatorios",
+	"category name already exists":     "ya existe una categoría con ese nombre",
+	"Gmail modify permission required": "Se necesita permiso para modificar Gmail",
+
+	"Monthly quota exceeded, upgrade your plan to continue": "Cuota mensual superada, mejora tu plan para continuar",
+
+	"email not found":          "correo no encontrado",
+	"category not found":       "categoría no encontrada",
+	"user not found":           "usuario no encontrado",
+	"automation not found":     "automatización no encontrada",
+	"cleanup policy not found": "política de limpieza no encontrada",
+	"report not found":         "informe no encontrado",
+	"view not found":           "vista no encontrada",
+	"share not found":          "enlace compartido no encontrado",
+
+	"Uncategorized": "Sin categoría",
+}
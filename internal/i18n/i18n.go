@@ -0,0 +1,102 @@
+// Package i18n translates the messages users see, such as API errors, into their language.
+// Messages are looked up by their English text, so code keeps writing English and anything
+// missing from a catalog is served as is.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default is the locale messages are written in
+const Default = "en"
+
+// language is a supported locale: its English name, which AI prompts ask for, and its catalog
+type language struct {
+	name     string
+	messages map[string]string
+	// patterns are the messages with a %s placeholder, longest first so the most specific matches
+	patterns []string
+}
+
+var languages = map[string]*language{
+	"en": {name: "English"},
+	"es": {name: "Spanish", messages: spanish},
+	"pt": {name: "Portuguese", messages: portuguese},
+}
+
+func init() {
+	for _, lang := range languages {
+		for message := range lang.messages {
+			if strings.Contains(message, "%s") {
+				lang.patterns = append(lang.patterns, message)
+			}
+		}
+		sort.Slice(lang.patterns, func(i, j int) bool {
+			return len(lang.patterns[i]) > len(lang.patterns[j])
+		})
+	}
+}
+
+// Locales lists the supported locales in alphabetical order
+func Locales() []string {
+	locales := make([]string, 0, len(languages))
+	for locale := range languages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported reports whether there is a catalog for the locale
+func Supported(locale string) bool {
+	_, ok := languages[locale]
+	return ok
+}
+
+// LanguageName is the English name of the locale's language, e.g. "Portuguese" for "pt", or
+// empty for an unsupported locale
+func LanguageName(locale string) string {
+	if lang, ok := languages[locale]; ok {
+		return lang.name
+	}
+	return ""
+}
+
+// Translate returns the message in the locale's language, or the message itself when the
+// locale or the message isn't in the catalog. Messages ending in a value, such as
+// "must be at most 100 characters", match the catalog entry with %s in its place.
+func Translate(locale, message string) string {
+	lang, ok := languages[locale]
+	if !ok || lang.messages == nil {
+		return message
+	}
+	if translated, ok := lang.messages[message]; ok {
+		return translated
+	}
+
+	for _, pattern := range lang.patterns {
+		prefix, suffix, _ := strings.Cut(pattern, "%s")
+		if len(message) > len(prefix)+len(suffix) && strings.HasPrefix(message, prefix) && strings.HasSuffix(message, suffix) {
+			return fmt.Sprintf(lang.messages[pattern], message[len(prefix):len(message)-len(suffix)])
+		}
+	}
+	return message
+}
+
+// FromAcceptLanguage picks the first supported locale of an Accept-Language header, matching
+// regional tags such as pt-BR by their language; it returns empty when none is supported
+func FromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		if locale := strings.ToLower(strings.TrimSpace(base)); Supported(locale) {
+			return locale
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+// Package jsonstream writes long JSON arrays one element at a time, so the encoding of the whole
+// array is never held in memory
+package jsonstream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// WriteArray writes items to w as a JSON array ending in a newline, like a json.Encoder would.
+// A non-empty indent puts each element on its own lines, indented by it.
+func WriteArray[T any](w io.Writer, items []T, indent string) error {
+	buffered := bufio.NewWriter(w)
+	var element bytes.Buffer
+	encoder := json.NewEncoder(&element)
+	separator := ""
+	if indent != "" {
+		encoder.SetIndent(indent, indent)
+		separator = "\n" + indent
+	}
+
+	buffered.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			buffered.WriteByte(',')
+		}
+		buffered.WriteString(separator)
+
+		element.Reset()
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+		// The encoder ends every value with a newline, which belongs after the array only
+		if _, err := buffered.Write(bytes.TrimSuffix(element.Bytes(), []byte("\n"))); err != nil {
+			return err
+		}
+	}
+	if indent != "" && len(items) > 0 {
+		buffered.WriteByte('\n')
+	}
+	buffered.WriteString("]\n")
+	return buffered.Flush()
+}
@@ -0,0 +1,157 @@
+// Package loadtest pushes synthetic emails through the sync pipeline, with the mock Gmail and AI
+// clients standing in for the real ones, to measure its throughput
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/service"
+)
+
+// Options sizes a load test run
+type Options struct {
+	Emails    int           // emails to sync in total
+	BatchSize int           // emails each Gmail fetch returns
+	AILatency time.Duration // simulated provider latency of every AI call
+}
+
+// Report is what a run measured
+type Report struct {
+	Emails       int           `json:"emails"`
+	Syncs        int           `json:"syncs"`
+	Duration     time.Duration `json:"duration"`
+	EmailsPerSec float64       `json:"emails_per_sec"`
+	Writes       int64         `json:"writes"`     // email creates and updates, a batch of creates counting once
+	WriteTime    time.Duration `json:"write_time"` // spent in those writes, summed over goroutines
+	WritesPerSec float64       `json:"writes_per_sec"`
+	Allocs       uint64        `json:"allocs"` // heap allocations during the run
+	AllocBytes   uint64        `json:"alloc_bytes"`
+}
+
+// Run syncs opts.Emails synthetic emails for a new user, stored in the given repositories, and
+// reports the throughput. The user, its categories and the emails are left in the repositories.
+func Run(ctx context.Context, userRepo repository.UserRepository, categoryRepo repository.CategoryRepository,
+	emailRepo repository.EmailRepository, opts Options, logger *logger.Logger) (*Report, error) {
+	if opts.Emails <= 0 {
+		return nil, errors.New("emails must be positive")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	runID := uuid.New().String()[:8]
+	user := model.NewUser("loadtest-"+runID, "loadtest-"+runID+"@example.com", "Load Test", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	if err := userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create load test user: %w", err)
+	}
+	for _, name := range []string{"Work", "Shopping", "Newsletters"} {
+		category := model.NewCategory(name, name+" emails")
+		category.UserID = user.ID
+		if err := categoryRepo.Create(ctx, category); err != nil {
+			return nil, fmt.Errorf("failed to create load test category: %w", err)
+		}
+	}
+
+	generated := 0
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		var emails []*model.Email
+		for len(emails) < int(maxResults) && generated < opts.Emails {
+			generated++
+			emails = append(emails, syntheticEmail(runID, generated))
+		}
+		return emails, nil
+	}
+
+	aiClient := ai.NewMockAIClient()
+	if opts.AILatency > 0 {
+		aiClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+			time.Sleep(opts.AILatency)
+			return categories[0].Name, nil
+		}
+		aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+			time.Sleep(opts.AILatency)
+			return "Synthetic summary", nil
+		}
+	}
+
+	writes := &countingEmailRepository{EmailRepository: emailRepo}
+	emailService := service.NewEmailService(writes, categoryRepo, userRepo, gmailClient, aiClient, logger)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	report := &Report{}
+	for generated < opts.Emails {
+		if _, err := emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: int64(opts.BatchSize)}); err != nil {
+			return nil, err
+		}
+		report.Syncs++
+	}
+
+	report.Duration = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	report.Emails = generated
+	report.EmailsPerSec = float64(generated) / report.Duration.Seconds()
+	report.Writes = writes.count.Load()
+	report.WriteTime = time.Duration(writes.nanos.Load())
+	if report.WriteTime > 0 {
+		report.WritesPerSec = float64(report.Writes) / report.WriteTime.Seconds()
+	}
+	report.Allocs = after.Mallocs - before.Mallocs
+	report.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	return report, nil
+}
+
+func syntheticEmail(runID string, n int) *model.Email {
+	from := fmt.Sprintf("Sender %d <sender%d@example.com>", n%50, n%50)
+	subject := fmt.Sprintf("Synthetic email %d", n)
+	body := fmt.Sprintf("<p>Hello,</p><p>This is synthetic email %d of a load test. It stands in for a typical message body.</p>", n)
+	// Received times go back in time so every email is older than the last
+	return model.NewEmail("", fmt.Sprintf("loadtest-%s-%d", runID, n), from, subject, body, time.Now().Add(-time.Duration(n)*time.Second))
+}
+
+// countingEmailRepository counts email writes and the time they take
+type countingEmailRepository struct {
+	repository.EmailRepository
+	count atomic.Int64
+	nanos atomic.Int64
+}
+
+func (r *countingEmailRepository) Create(ctx context.Context, email *model.Email) error {
+	defer r.track(time.Now())
+	return r.EmailRepository.Create(ctx, email)
+}
+
+func (r *countingEmailRepository) CreateBatch(ctx context.Context, emails []*model.Email) error {
+	defer r.track(time.Now())
+	return r.EmailRepository.CreateBatch(ctx, emails)
+}
+
+func (r *countingEmailRepository) Update(ctx context.Context, email *model.Email) error {
+	defer r.track(time.Now())
+	return r.EmailRepository.Update(ctx, email)
+}
+
+func (r *countingEmailRepository) track(start time.Time) {
+	r.count.Add(1)
+	r.nanos.Add(int64(time.Since(start)))
+}
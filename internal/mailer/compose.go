@@ -0,0 +1,78 @@
+// Package mailer delivers the emails the app sends on its own, through an SMTP server or the
+// recipient's own Gmail account
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/emailtemplate"
+)
+
+// Compose writes the message as a multipart/alternative RFC 5322 email. The plain-text part
+// comes first, so clients able to show HTML pick the HTML one.
+func Compose(from, to string, message *emailtemplate.Message, date time.Time) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+	headers := []string{
+		"From: " + sender.String(),
+		"To: " + recipient.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date: " + date.Format(time.RFC1123Z),
+		"Message-ID: " + messageID(sender.Address),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+		"Auto-Submitted: auto-generated", // keeps auto-replies from answering
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// messageID makes a unique Message-ID in the sender's domain
+func messageID(address string) string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	domain := address[strings.LastIndex(address, "@")+1:]
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">"
+}
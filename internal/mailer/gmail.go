@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"time"
+
+	"jump-challenge/internal/emailtemplate"
+	"jump-challenge/internal/service"
+)
+
+// GmailSender sends each email from the recipient's own Gmail account to themselves, for
+// deployments without an SMTP server. It only reaches the app's users, and only those who let it
+// modify their Gmail.
+type GmailSender struct {
+	client service.MailClient
+}
+
+var _ service.MailSender = (*GmailSender)(nil)
+
+func NewGmailSender(client service.MailClient) *GmailSender {
+	return &GmailSender{client: client}
+}
+
+func (g *GmailSender) Send(ctx context.Context, to string, message *emailtemplate.Message) error {
+	raw, err := Compose(to, to, message, time.Now())
+	if err != nil {
+		return err
+	}
+	return g.client.Send(ctx, to, raw)
+}
@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"jump-challenge/internal/emailtemplate"
+	"jump-challenge/internal/service"
+)
+
+// smtpTimeout bounds a delivery whose context has no deadline
+const smtpTimeout = time.Minute
+
+// SMTPSender delivers email through an SMTP server, from the app's own address
+type SMTPSender struct {
+	host        string
+	addr        string
+	auth        smtp.Auth // nil without credentials
+	from        string
+	implicitTLS bool // port 465 speaks TLS from the start, the others are upgraded with STARTTLS
+}
+
+var _ service.MailSender = (*SMTPSender)(nil)
+
+// NewSMTPSender sends as from, e.g. "Jump Challenge <noreply@example.com>", authenticating with
+// the username and password unless the username is empty. Credentials are only ever sent over
+// TLS, or to a server on localhost.
+func NewSMTPSender(host string, port int, username, password, from string) *SMTPSender {
+	sender := &SMTPSender{
+		host:        host,
+		addr:        net.JoinHostPort(host, strconv.Itoa(port)),
+		from:        from,
+		implicitTLS: port == 465,
+	}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender
+}
+
+func (s *SMTPSender) Send(ctx context.Context, to string, message *emailtemplate.Message) error {
+	raw, err := Compose(s.from, to, message, time.Now())
+	if err != nil {
+		return err
+	}
+	// Compose checked both addresses
+	sender, _ := mail.ParseAddress(s.from)
+	recipient, _ := mail.ParseAddress(to)
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return fmt.Errorf("failed to authenticate with the SMTP server: %w", err)
+		}
+	}
+	if err := client.Mail(sender.Address); err != nil {
+		return fmt.Errorf("the SMTP server refused the sender: %w", err)
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return fmt.Errorf("the SMTP server refused the recipient: %w", err)
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(raw); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("the SMTP server refused the email: %w", err)
+	}
+	return client.Quit()
+}
+
+// Check connects and authenticates without sending anything
+func (s *SMTPSender) Check(ctx context.Context) error {
+	client, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return fmt.Errorf("failed to authenticate with the SMTP server: %w", err)
+		}
+	}
+	return client.Quit()
+}
+
+// dial connects and says hello, switching to TLS when the server offers it
+func (s *SMTPSender) dial(ctx context.Context) (*smtp.Client, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(deadline)
+	if s.implicitTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.host})
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !s.implicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+	}
+	return client, nil
+}
@@ -1,8 +1,6 @@
 package middleware
 
 import (
-	"net/http"
-
 	"jump-challenge/internal/handler"
 
 	"github.com/labstack/echo/v4"
@@ -12,13 +10,13 @@ import (
 func AuthMiddleware(authHandler *handler.AuthHandler) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Check if the user is authenticated by trying to get the current user
-			_, err := authHandler.GetCurrentUser(c)
+			// Check the session is still valid and slide its expiry forward; failing to check
+			// it, e.g. with the database down, isn't answered as signed out
+			user, err := authHandler.ValidateSession(c)
 			if err != nil {
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error": "Unauthorized",
-				})
+				return err
 			}
+			handler.SetUserLocale(c, user)
 
 			return next(c)
 		}
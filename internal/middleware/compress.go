@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+// compressMinLength is the smallest response worth gzipping, below it the gzip framing outweighs the savings
+const compressMinLength = 1024
+
+// Compress gzips responses for clients accepting it. Event streams are skipped, as gzip would hold
+// events back until enough of them filled a block, and so are proxied images, which are compressed already.
+func Compress() echo.MiddlewareFunc {
+	return echomiddleware.GzipWithConfig(echomiddleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			path := c.Request().URL.Path
+			return strings.HasSuffix(path, "/sse") || strings.HasSuffix(path, "/proxy/image")
+		},
+		MinLength: compressMinLength,
+	})
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// AccountMergeCode is a one-time code, created signed in to a duplicate account, with which
+// the account the user keeps takes the duplicate's data over
+type AccountMergeCode struct {
+	Code      string    `json:"code"`
+	UserID    string    `json:"-"` // of the duplicate
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AccountMerge accounts for what a merge moved to the kept account
+type AccountMerge struct {
+	MergedUserID string `json:"merged_user_id"` // the duplicate, deleted once merged
+	MergedEmail  string `json:"merged_email"`
+	Emails       int    `json:"emails"`     // the duplicate's copies of messages the kept account stores are dropped
+	Categories   int    `json:"categories"` // those named like one of the kept account's are merged into it
+	Automations  int    `json:"automations"`
+	Cleanups     int    `json:"cleanup_policies"`
+	SavedViews   int    `json:"saved_views"` // those named like one of the kept account's are dropped
+	VIPSenders   int    `json:"vip_senders"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Operations an AI call is made for
+const (
+	AIOperationClassify  = "classify"
+	AIOperationSummarize = "summarize"
+	AIOperationAnalyze   = "analyze"
+)
+
+// AICall is a prompt sent to an AI provider and its raw answer, kept in debug mode with email
+// addresses, links and numbers redacted
+type AICall struct {
+	ID         string    `json:"id"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model,omitempty"` // empty for the provider's default model
+	Operation  string    `json:"operation"`
+	System     string    `json:"system"`
+	Prompt     string    `json:"prompt"`
+	MaxTokens  int       `json:"max_tokens"`
+	Response   string    `json:"response"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewAICall(provider, model, operation, system, prompt string, maxTokens int) *AICall {
+	return &AICall{
+		ID:        uuid.New().String(),
+		Provider:  provider,
+		Model:     model,
+		Operation: operation,
+		System:    system,
+		Prompt:    prompt,
+		MaxTokens: maxTokens,
+		CreatedAt: time.Now(),
+	}
+}
+
+// AICallReplay is the answer of another provider or model to a stored call's prompt
+type AICallReplay struct {
+	Call       *AICall `json:"call"`
+	Provider   string  `json:"provider"`
+	Model      string  `json:"model,omitempty"`
+	Response   string  `json:"response"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS int64   `json:"duration_ms"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AutomationActions lists the bulk actions an automation may run
+var AutomationActions = []string{"archive", "read", "star", "move", "local_archive", "delete"}
+
+// Automation runs an action on the emails classified into a category, either as soon as
+// they are synced or once they are older than DelayDays
+type Automation struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	OrgID      string     `json:"org_id,omitempty"` // team automations run on every member's emails
+	CategoryID string     `json:"category_id"`
+	Action     string     `json:"action"`
+	Label      string     `json:"label,omitempty"` // Gmail label for the move action
+	DelayDays  int        `json:"delay_days"`      // 0 runs the action right after classification
+	Enabled    bool       `json:"enabled"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"` // when the last scheduled pass applied the automation
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func NewAutomation(userID, categoryID, action, label string, delayDays int) *Automation {
+	now := time.Now()
+	return &Automation{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		CategoryID: categoryID,
+		Action:     action,
+		Label:      label,
+		DelayDays:  delayDays,
+		Enabled:    true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// IsScheduled reports whether the automation waits for emails to age instead of running on sync
+func (a *Automation) IsScheduled() bool {
+	return a.DelayDays > 0
+}
+
+// Cutoff returns the receive time before which emails are due for a scheduled automation
+func (a *Automation) Cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -a.DelayDays)
+}
+
+// AlreadyApplied reports whether the last scheduled pass handled the email: it was stored
+// before that pass and already due at the time. Emails synced later are picked up even when old.
+func (a *Automation) AlreadyApplied(email *Email) bool {
+	if a.LastRunAt == nil {
+		return false
+	}
+	return email.CreatedAt.Before(*a.LastRunAt) && email.ReceivedAt.Before(a.Cutoff(*a.LastRunAt))
+}
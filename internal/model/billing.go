@@ -0,0 +1,82 @@
+package model
+
+import (
+	"time"
+)
+
+// Quota metrics, counted per user and calendar month (UTC)
+const (
+	MetricEmails       = "emails"       // new emails processed by sync
+	MetricSummaries    = "summaries"    // AI summaries generated
+	MetricUnsubscribes = "unsubscribes" // unsubscribe attempts, one per email
+)
+
+// QuotaMetrics lists the metrics in the order usage reports show them
+var QuotaMetrics = []string{MetricEmails, MetricSummaries, MetricUnsubscribes}
+
+// Plan names
+const (
+	PlanFree      = "free"
+	PlanPro       = "pro"
+	PlanUnlimited = "unlimited"
+)
+
+// Plans maps each plan to its monthly limits; a metric without a limit is unlimited
+var Plans = map[string]map[string]int{
+	PlanFree:      {MetricEmails: 500, MetricSummaries: 500, MetricUnsubscribes: 20},
+	PlanPro:       {MetricEmails: 20000, MetricSummaries: 20000, MetricUnsubscribes: 1000},
+	PlanUnlimited: {},
+}
+
+// IsPlan reports whether name is a known plan
+func IsPlan(name string) bool {
+	_, ok := Plans[name]
+	return ok
+}
+
+// BillingAccount ties a user to their plan and the payment provider's customer
+type BillingAccount struct {
+	UserID     string    `json:"user_id"`
+	Plan       string    `json:"plan"`
+	Provider   string    `json:"provider,omitempty"`
+	CustomerID string    `json:"customer_id,omitempty"`
+	Status     string    `json:"status,omitempty"` // provider subscription status, e.g. active or past_due
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BillingEvent is a plan change reported by a payment provider's webhook
+type BillingEvent struct {
+	UserID     string // set when the provider carries our user ID, e.g. in metadata
+	CustomerID string
+	Plan       string // empty when the subscription ended
+	Status     string
+}
+
+// Usage counts one metric for a user in a period
+type Usage struct {
+	UserID string
+	Period string // YYYY-MM
+	Metric string
+	Count  int
+}
+
+// UsagePeriod returns the period t falls in
+func UsagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// UsageReport is the user's plan and how much of each quota they have used this period
+type UsageReport struct {
+	Plan     string        `json:"plan"`
+	Period   string        `json:"period"`
+	ResetsAt time.Time     `json:"resets_at"`
+	Metrics  []MetricUsage `json:"metrics"`
+}
+
+// MetricUsage is the use of a single quota; Limit is 0 when unlimited
+type MetricUsage struct {
+	Metric    string `json:"metric"`
+	Used      int    `json:"used"`
+	Limit     int    `json:"limit"`
+	Remaining *int   `json:"remaining,omitempty"` // omitted when unlimited
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bulk job statuses
+const (
+	BulkJobPending   = "pending"
+	BulkJobRunning   = "running"
+	BulkJobCompleted = "completed"
+	BulkJobFailed    = "failed"
+)
+
+// BulkJob is a filter-based bulk action processed in the background
+type BulkJob struct {
+	ID          string      `json:"id"`
+	UserID      string      `json:"user_id"`
+	Action      string      `json:"action"`
+	Filter      EmailFilter `json:"filter"`
+	Label       string      `json:"label,omitempty"`        // Gmail label the move action files emails under
+	BlockSender bool        `json:"block_sender,omitempty"` // the spam action also blocks the senders
+	Status      string      `json:"status"`
+	Total       int         `json:"total"`     // emails matched by the filter
+	Processed   int         `json:"processed"` // emails handed to the action successfully
+	Failed      int         `json:"failed"`
+	Error       string      `json:"error,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func NewBulkJob(userID, action string, filter EmailFilter) *BulkJob {
+	now := time.Now()
+	return &BulkJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Action:    action,
+		Filter:    filter,
+		Status:    BulkJobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
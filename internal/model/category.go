@@ -6,12 +6,32 @@ import (
 	"github.com/google/uuid"
 )
 
+// How the emails of a category are summarized
+const (
+	SummarizeOn          = "on"           // every email, while the month's summaries last
+	SummarizeLowPriority = "low_priority" // only while the month's summaries aren't running low
+	SummarizeOff         = "off"          // classified only
+)
+
 type Category struct {
 	ID          string    `json:"id"`
+	UserID      string    `json:"user_id,omitempty"` // owner; empty for the shared default categories
+	OrgID       string    `json:"org_id,omitempty"`  // team categories are visible to every member of the organization
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Examples and counter-examples given to the AI when classifying, e.g. "order confirmations,
+	// invoices, payment notifications; not bank statements"
+	Hints string `json:"hints,omitempty"`
+
+	// One of the Summarize modes; empty means on
+	Summarize string `json:"summarize,omitempty"`
+
+	// Sensitive categories, e.g. Medical, are kept away from the AI and out of exports while
+	// the user is in privacy mode
+	Sensitive bool `json:"sensitive,omitempty"`
 }
 
 func NewCategory(name, description string) *Category {
@@ -23,4 +43,52 @@ func NewCategory(name, description string) *Category {
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
-}
\ No newline at end of file
+}
+
+// EmailCounts tallies the emails of a category outside the trash
+type EmailCounts struct {
+	Total  int `json:"email_count"`
+	Unread int `json:"unread_count"`
+}
+
+// CategoryWithCounts is a category listed along with how many of the user's emails it holds
+type CategoryWithCounts struct {
+	*Category
+	EmailCounts
+}
+
+// IsSummarizeMode reports whether mode is one of the Summarize modes
+func IsSummarizeMode(mode string) bool {
+	switch mode {
+	case SummarizeOn, SummarizeLowPriority, SummarizeOff:
+		return true
+	default:
+		return false
+	}
+}
+
+// SummarizeMode returns how the category's emails are summarized
+func (c *Category) SummarizeMode() string {
+	if c.Summarize == "" {
+		return SummarizeOn
+	}
+	return c.Summarize
+}
+
+// IsShared reports whether the category is a default visible to every user
+func (c *Category) IsShared() bool {
+	return c.UserID == ""
+}
+
+// IsTeam reports whether the category belongs to an organization's taxonomy
+func (c *Category) IsTeam() bool {
+	return c.OrgID != ""
+}
+
+// VisibleTo reports whether the user, a member of orgID ("" for none), may see the category
+func (c *Category) VisibleTo(userID, orgID string) bool {
+	if c.IsTeam() {
+		return c.OrgID == orgID
+	}
+	return c.IsShared() || c.UserID == userID
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CleanupActions lists the bulk actions a cleanup policy may run
+var CleanupActions = []string{"archive", "read", "local_archive", "delete"}
+
+// How often a cleanup policy runs
+const (
+	CleanupDaily  = "daily"
+	CleanupWeekly = "weekly"
+)
+
+// cleanupPreviewSize caps the emails listed by a cleanup preview
+const cleanupPreviewSize = 20
+
+// CleanupPolicy is a standing cleanup of the user's mailbox, e.g. deleting Social emails older
+// than 14 days every day, or archiving read Newsletters weekly
+type CleanupPolicy struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	Name          string     `json:"name"`
+	CategoryID    string     `json:"category_id"`
+	Unread        *bool      `json:"unread,omitempty"` // only unread or only read emails, both when nil
+	OlderThanDays int        `json:"older_than_days"`
+	Action        string     `json:"action"`
+	Schedule      string     `json:"schedule"` // daily or weekly
+	Enabled       bool       `json:"enabled"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func NewCleanupPolicy(userID, name, categoryID string, unread *bool, olderThanDays int, action, schedule string) *CleanupPolicy {
+	now := time.Now()
+	return &CleanupPolicy{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Name:          name,
+		CategoryID:    categoryID,
+		Unread:        unread,
+		OlderThanDays: olderThanDays,
+		Action:        action,
+		Schedule:      schedule,
+		Enabled:       true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// IsDue reports whether the scheduler should run the policy at now, given in the user's time zone:
+// daily policies run once per calendar day there, and weekly ones once per week starting Monday
+func (p *CleanupPolicy) IsDue(now time.Time) bool {
+	if !p.Enabled {
+		return false
+	}
+	if p.LastRunAt == nil {
+		return true
+	}
+	period := StartOfDay
+	if p.Schedule == CleanupWeekly {
+		period = StartOfWeek
+	}
+	return period(p.LastRunAt.In(now.Location())).Before(period(now))
+}
+
+// Filter selects the emails the policy cleans as of now. Emails the action would leave unchanged,
+// such as read ones for the read action, are left out so they aren't counted as cleaned.
+func (p *CleanupPolicy) Filter(now time.Time) EmailFilter {
+	before := now.AddDate(0, 0, -p.OlderThanDays)
+	filter := EmailFilter{CategoryID: p.CategoryID, Before: &before, Unread: p.Unread, Archive: ArchiveFilterAll}
+	switch p.Action {
+	case "read":
+		unread := true
+		filter.Unread = &unread
+	case "local_archive":
+		filter.Archive = ArchiveFilterExclude
+	}
+	return filter
+}
+
+// CleanupRun records one run of a cleanup policy
+type CleanupRun struct {
+	ID         string    `json:"id"`
+	PolicyID   string    `json:"policy_id"`
+	UserID     string    `json:"user_id"`
+	PolicyName string    `json:"policy_name"`
+	Action     string    `json:"action"`
+	Matched    int       `json:"matched"` // emails the policy selected
+	Cleaned    int       `json:"cleaned"` // emails the action was applied to
+	Error      string    `json:"error,omitempty"`
+	RanAt      time.Time `json:"ran_at"`
+}
+
+func NewCleanupRun(policy *CleanupPolicy, ranAt time.Time) *CleanupRun {
+	return &CleanupRun{
+		ID:         uuid.New().String(),
+		PolicyID:   policy.ID,
+		UserID:     policy.UserID,
+		PolicyName: policy.Name,
+		Action:     policy.Action,
+		RanAt:      ranAt,
+	}
+}
+
+// CleanupPreview is what a cleanup policy would do if it ran now, without doing it
+type CleanupPreview struct {
+	Count  int      `json:"count"`
+	Emails []*Email `json:"emails"` // the newest of them, without bodies
+}
+
+// NewCleanupPreview counts the matched emails and keeps the first of them, dropping their bodies
+func NewCleanupPreview(emails []*Email) *CleanupPreview {
+	count := len(emails)
+	if len(emails) > cleanupPreviewSize {
+		emails = emails[:cleanupPreviewSize]
+	}
+	preview := &CleanupPreview{Count: count, Emails: make([]*Email, len(emails))}
+	for i, email := range emails {
+		preview.Emails[i] = email.WithoutBody()
+	}
+	return preview
+}
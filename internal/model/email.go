@@ -1,37 +1,154 @@
 package model
 
 import (
+	"net/mail"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
 type Email struct {
-	ID         string    `json:"id"`
-	UserID     string    `json:"user_id"`
-	GmailID    string    `json:"gmail_id"`
-	From       string    `json:"from"`
-	Subject    string    `json:"subject"`
-	Body       string    `json:"body"`
-	Summary    string    `json:"summary"`
-	CategoryID string    `json:"category_id"`
-	ReceivedAt time.Time `json:"received_at"`
-	Archived   bool      `json:"archived"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID              string     `json:"id"`
+	UserID          string     `json:"user_id"`
+	GmailID         string     `json:"gmail_id"`
+	From            string     `json:"from"`
+	FromName        string     `json:"from_name"`
+	FromAddress     string     `json:"from_address"`
+	To              string     `json:"to"`
+	Cc              string     `json:"cc"`
+	ReplyTo         string     `json:"reply_to"`
+	MessageID       string     `json:"message_id"`
+	Subject         string     `json:"subject"`
+	Body            string     `json:"body,omitempty"`
+	BodyTruncated   bool       `json:"body_truncated"` // Body was cut down to the storage limit
+	BodyPruned      bool       `json:"body_pruned"`    // Body was dropped by the retention policy
+	Snippet         string     `json:"snippet"`        // plain text preview for lists, kept when Body is dropped
+	Summary         string     `json:"summary"`
+	CategoryID      string     `json:"category_id"`
+	ReceivedAt      time.Time  `json:"received_at"`
+	SentAt          *time.Time `json:"sent_at,omitempty"` // parsed Date header, nil when missing or unparseable
+	Archived        bool       `json:"archived"`
+	Unread          bool       `json:"unread"`           // mirrors Gmail's UNREAD label as of the last sync or action
+	Starred         bool       `json:"starred"`          // mirrors Gmail's STARRED label as of the last sync or action
+	Important       bool       `json:"important"`        // Gmail marked the email IMPORTANT when it was synced
+	LocallyArchived bool       `json:"locally_archived"` // hidden in the app only, Gmail is untouched
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"` // set while the email sits in the trash
+	TriagedAt       *time.Time `json:"triaged_at,omitempty"` // set once the user decided on the email in triage
+	TriageAction    string     `json:"triage_action,omitempty"`
+	UnsubscribedAt  *time.Time `json:"unsubscribed_at,omitempty"` // set once unsubscribing through the email succeeded
+	SystemFlag      string     `json:"system_flag,omitempty"`     // bounce or auto_reply for machine-generated emails
+	OTPCode         string     `json:"-"`                         // verification code found at sync, served by the otp endpoint
+	OTPExpiresAt    *time.Time `json:"otp_expires_at,omitempty"`  // set along with OTPCode
+	SecurityFlag    string     `json:"security_flag,omitempty"`   // kind of account-security email, see SecurityEvent
+	Priority        string     `json:"priority,omitempty"`        // urgent for emails from VIP senders
+
+	// The AI answered with something unfit to store as the summary, e.g. a refusal; a later
+	// sync asks again
+	NeedsReprocessing bool `json:"needs_reprocessing"`
+
+	// Classified with too little confidence, the email waits in the review queue with the AI's
+	// suggestion instead of a category
+	ReviewCategoryID string  `json:"review_category_id,omitempty"`
+	Confidence       float64 `json:"confidence,omitempty"` // of the AI in its classification, from 0 to 1
+
+	// Version counts the saves of the email; an update only succeeds on the version it read
+	Version int `json:"-"`
 }
 
 func NewEmail(userID, gmailID, from, subject, body string, receivedAt time.Time) *Email {
 	now := time.Now()
+	fromName, fromAddress := ParseFrom(from)
 	return &Email{
-		ID:         uuid.New().String(),
-		UserID:     userID,
-		GmailID:    gmailID,
-		From:       from,
-		Subject:    subject,
-		Body:       body,
-		ReceivedAt: receivedAt,
-		CreatedAt:  now,
-		UpdatedAt:  now,
-	}
-}
\ No newline at end of file
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		GmailID:     gmailID,
+		From:        from,
+		FromName:    fromName,
+		FromAddress: fromAddress,
+		Subject:     subject,
+		Body:        body,
+		ReceivedAt:  receivedAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// InReview reports whether the email waits for the user to confirm or correct its category
+func (e *Email) InReview() bool {
+	return e.ReviewCategoryID != ""
+}
+
+// ParseFrom splits a raw From header ("Jane Doe <jane@x.com>") into its display name
+// and bare, lower-cased address. Headers that net/mail can't parse fall back to a best
+// effort split so a malformed sender never drops the email.
+func ParseFrom(raw string) (name, address string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ""
+	}
+
+	if parsed, err := mail.ParseAddress(raw); err == nil {
+		return parsed.Name, strings.ToLower(parsed.Address)
+	}
+
+	// Fallback for headers like `"Broken <name" <a@b.com>` or a bare address
+	if start, end := strings.LastIndex(raw, "<"), strings.LastIndex(raw, ">"); start >= 0 && end > start {
+		name = strings.Trim(strings.TrimSpace(raw[:start]), `"`)
+		return name, strings.ToLower(strings.TrimSpace(raw[start+1 : end]))
+	}
+	if strings.Contains(raw, "@") {
+		return "", strings.ToLower(raw)
+	}
+	return raw, ""
+}
+
+// SnippetLength is the most characters of a preview snippet
+const SnippetLength = 140
+
+// SetSnippet stores the start of the text as the preview, with its whitespace collapsed and cut
+// on a word boundary when longer than SnippetLength
+func (e *Email) SetSnippet(text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= SnippetLength {
+		e.Snippet = text
+		return
+	}
+
+	runes := []rune(text)[:SnippetLength-1]
+	cut := len(runes)
+	for i := len(runes) - 1; i > SnippetLength/2; i-- {
+		if runes[i] == ' ' {
+			cut = i
+			break
+		}
+	}
+	e.Snippet = strings.TrimRight(string(runes[:cut]), " ") + "…"
+}
+
+// TruncateBody cuts Body down to at most limit bytes without splitting a UTF-8 rune.
+// It reports whether the body was truncated; a non-positive limit disables truncation.
+func (e *Email) TruncateBody(limit int) bool {
+	if limit <= 0 || len(e.Body) <= limit {
+		return false
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(e.Body[cut]) {
+		cut--
+	}
+	e.Body = e.Body[:cut]
+	e.BodyTruncated = true
+	return true
+}
+
+// WithoutBody returns a shallow copy of the email with Body cleared, for list responses
+// that shouldn't ship full message bodies. The receiver is left untouched.
+func (e *Email) WithoutBody() *Email {
+	clone := *e
+	clone.Body = ""
+	return &clone
+}
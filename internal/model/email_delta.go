@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// EmailDelta lists the IDs of a user's emails that changed since a cursor, for clients to
+// reconcile a local copy instead of reloading every email
+type EmailDelta struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Deleted []string `json:"deleted"`  // moved to the trash
+	Cursor  string   `json:"cursor"`   // passed as since, gets the changes after these
+	HasMore bool     `json:"has_more"` // more changes follow, ask again with the cursor right away
+}
+
+// ChangedAt returns when the email was last created, updated or trashed
+func (e *Email) ChangedAt() time.Time {
+	changed := e.CreatedAt
+	if e.UpdatedAt.After(changed) {
+		changed = e.UpdatedAt
+	}
+	if e.DeletedAt != nil && e.DeletedAt.After(changed) {
+		changed = *e.DeletedAt
+	}
+	return changed
+}
@@ -0,0 +1,12 @@
+package model
+
+// EmailDetail is a single email with the context shown in the detail view
+type EmailDetail struct {
+	Email            *Email    `json:"email"`
+	Category         *Category `json:"category,omitempty"` // nil when uncategorized or the category was deleted
+	SenderEmailCount int       `json:"sender_email_count"` // emails received from the same address, this one included
+	CanUnsubscribe   bool      `json:"can_unsubscribe"`    // the body contains an unsubscribe link
+	PreviousID       string    `json:"previous_id,omitempty"`
+	NextID           string    `json:"next_id,omitempty"`
+	TextOnly         bool      `json:"text_only,omitempty"` // the body is plain text, as privacy mode shows it
+}
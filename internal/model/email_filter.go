@@ -0,0 +1,77 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// ArchiveFilter selects emails by their local archive state
+type ArchiveFilter string
+
+const (
+	ArchiveFilterExclude ArchiveFilter = ""     // default: hide locally archived emails
+	ArchiveFilterOnly    ArchiveFilter = "only" // only locally archived emails
+	ArchiveFilterAll     ArchiveFilter = "all"  // archived or not
+)
+
+// EmailFilter narrows an email listing; zero values match everything except locally archived emails
+type EmailFilter struct {
+	CategoryID string        `json:"category_id,omitempty" validate:"max=100"`
+	Sender     string        `json:"sender,omitempty" validate:"max=320"` // sender address, compared case-insensitively
+	After      *time.Time    `json:"after,omitempty"`                     // received at or after this time
+	Before     *time.Time    `json:"before,omitempty"`                    // received strictly before this time
+	Unread     *bool         `json:"unread,omitempty"`
+	Query      string        `json:"query,omitempty" validate:"max=200"` // text in the subject, sender or summary, ignoring case
+	Archive    ArchiveFilter `json:"archive,omitempty" validate:"omitempty,oneof=only all"`
+}
+
+// HasCriteria reports whether the filter narrows anything beyond the archive state.
+// Bulk actions require criteria so an empty filter can't hit the whole mailbox.
+func (f EmailFilter) HasCriteria() bool {
+	return f.CategoryID != "" || f.Sender != "" || f.After != nil || f.Before != nil || f.Unread != nil || f.Query != ""
+}
+
+// Matches reports whether an active (non-trashed) email passes the filter
+func (f EmailFilter) Matches(email *Email) bool {
+	if email.DeletedAt != nil {
+		return false
+	}
+	if f.CategoryID != "" && email.CategoryID != f.CategoryID {
+		return false
+	}
+	if f.Sender != "" && email.FromAddress != strings.ToLower(f.Sender) {
+		return false
+	}
+	if f.After != nil && email.ReceivedAt.Before(*f.After) {
+		return false
+	}
+	if f.Before != nil && !email.ReceivedAt.Before(*f.Before) {
+		return false
+	}
+	if f.Unread != nil && email.Unread != *f.Unread {
+		return false
+	}
+	if f.Query != "" && !matchesQuery(email, f.Query) {
+		return false
+	}
+
+	switch f.Archive {
+	case ArchiveFilterOnly:
+		return email.LocallyArchived
+	case ArchiveFilterAll:
+		return true
+	default:
+		return !email.LocallyArchived
+	}
+}
+
+// matchesQuery reports whether the query appears in the email's subject, sender or summary
+func matchesQuery(email *Email, query string) bool {
+	query = strings.ToLower(query)
+	for _, field := range []string{email.Subject, email.From, email.Summary} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// EmailRow is the part of an email a list row shows, small enough for clients to load
+// thousands of them
+type EmailRow struct {
+	ID         string    `json:"id"`
+	From       string    `json:"from"` // the sender's display name, or their address without one
+	Subject    string    `json:"subject"`
+	Snippet    string    `json:"snippet"`
+	CategoryID string    `json:"category_id"`
+	ReceivedAt time.Time `json:"received_at"`
+	Unread     bool      `json:"unread,omitempty"`
+	Starred    bool      `json:"starred,omitempty"`
+	Important  bool      `json:"important,omitempty"`
+	Archived   bool      `json:"archived,omitempty"` // locally archived
+}
+
+// NewEmailRow trims the email down to its row
+func NewEmailRow(email *Email) *EmailRow {
+	from := email.FromName
+	if from == "" {
+		from = email.FromAddress
+	}
+	return &EmailRow{
+		ID:         email.ID,
+		From:       from,
+		Subject:    email.Subject,
+		Snippet:    email.Snippet,
+		CategoryID: email.CategoryID,
+		ReceivedAt: email.ReceivedAt,
+		Unread:     email.Unread,
+		Starred:    email.Starred,
+		Important:  email.Important,
+		Archived:   email.LocallyArchived,
+	}
+}
+
+// EmailWindow is a slice of an email listing at any position, for virtualized lists that only
+// load the rows in view. Emails are listed newest first, then by ID, so windows don't overlap.
+type EmailWindow struct {
+	Offset int         `json:"offset"`
+	Total  int         `json:"total"` // emails in the whole listing
+	Emails []*EmailRow `json:"emails"`
+}
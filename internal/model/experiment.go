@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Arms of a classification experiment
+const (
+	ArmControl   = "control"   // the configured provider, model and default prompt
+	ArmTreatment = "treatment" // the alternative being tried
+)
+
+// ExperimentArm describes what one arm of an experiment classifies with
+type ExperimentArm struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"` // empty for the provider's default model
+	Prompt   string `json:"prompt"`
+}
+
+// ExperimentResult records the arm an email was classified by in an experiment, and the
+// category its owner moved it to if they disagreed
+type ExperimentResult struct {
+	ID                  string     `json:"id"`
+	Experiment          string     `json:"experiment"`
+	Arm                 string     `json:"arm"`
+	EmailID             string     `json:"email_id"`
+	UserID              string     `json:"user_id"`
+	CategoryID          string     `json:"category_id"`
+	CorrectedCategoryID string     `json:"corrected_category_id,omitempty"`
+	ClassifiedAt        time.Time  `json:"classified_at"`
+	CorrectedAt         *time.Time `json:"corrected_at,omitempty"`
+}
+
+func NewExperimentResult(experiment, arm string, email *Email) *ExperimentResult {
+	return &ExperimentResult{
+		ID:           uuid.New().String(),
+		Experiment:   experiment,
+		Arm:          arm,
+		EmailID:      email.ID,
+		UserID:       email.UserID,
+		CategoryID:   email.CategoryID,
+		ClassifiedAt: time.Now(),
+	}
+}
+
+// ExperimentArmStats counts the classifications of one arm and how many users corrected
+type ExperimentArmStats struct {
+	ExperimentArm
+	Classified int     `json:"classified"`
+	Corrected  int     `json:"corrected"`
+	Accuracy   float64 `json:"accuracy"` // share of the classifications left uncorrected, 0 without any
+}
+
+// ExperimentReport compares the arms of a classification experiment
+type ExperimentReport struct {
+	Experiment string                `json:"experiment"`
+	Running    bool                  `json:"running"` // whether emails are still being routed to it
+	Percent    int                   `json:"percent"` // of classifications routed to the treatment
+	Arms       []*ExperimentArmStats `json:"arms"`
+}
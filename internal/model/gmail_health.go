@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Gmail statuses of a user
+const (
+	GmailReachable   = "ok"
+	GmailUnreachable = "unreachable" // the user's latest Gmail call failed to reach it
+)
+
+// States of the circuit breaker guarding a user's Gmail calls
+const (
+	BreakerClosed   = "closed"    // calls go through
+	BreakerOpen     = "open"      // calls fail without reaching Gmail until RetryAt
+	BreakerHalfOpen = "half_open" // the cooldown is over, the next call probes whether Gmail works again
+)
+
+// GmailHealth tells the frontend whether what it shows may be stale because Gmail can't be
+// reached for the user
+type GmailHealth struct {
+	Status        string     `json:"status"`
+	Since         *time.Time `json:"since,omitempty"`      // when Gmail stopped answering
+	LastError     string     `json:"last_error,omitempty"` // of the latest failed call
+	QueuedActions int        `json:"queued_actions"`       // changes waiting to be replayed in Gmail
+	Breaker       string     `json:"breaker"`              // one of the Breaker states
+	RetryAt       *time.Time `json:"retry_at,omitempty"`   // when an open breaker lets a call through again
+}
+
+// GmailBreaker is what the Gmail client knows of one user's calls since the server started, for
+// operators
+type GmailBreaker struct {
+	UserEmail           string     `json:"user_email"`
+	State               string     `json:"state"`                // one of the Breaker states
+	ConsecutiveFailures int        `json:"consecutive_failures"` // outages and refused credentials in a row
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	Requests            int64      `json:"requests"` // calls that reached the Gmail client
+	Failures            int64      `json:"failures"` // outages and refused credentials
+	Rejected            int64      `json:"rejected"` // calls failed by the open breaker
+}
+
+// GmailAction is a change made to an email while Gmail was unreachable, replayed there once
+// it answers again
+type GmailAction struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	EmailID   string    `json:"email_id"`
+	GmailID   string    `json:"gmail_id"`
+	Action    string    `json:"action"` // a bulk action, one of QueueableGmailActions
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// QueueableGmailActions lists the bulk actions that are queued rather than failed while Gmail
+// is unreachable; replaying them in order leaves Gmail as the app shows it
+var QueueableGmailActions = []string{"archive", "read", "unread", "star", "unstar"}
+
+func NewGmailAction(userID, emailID, gmailID, action string) *GmailAction {
+	return &GmailAction{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		EmailID:   emailID,
+		GmailID:   gmailID,
+		Action:    action,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,7 @@
+package model
+
+// ProxiedImage is a remote image fetched on the user's behalf by the image proxy
+type ProxiedImage struct {
+	ContentType string
+	Data        []byte
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Gmail's system labels the app reads and changes
+const (
+	LabelInbox     = "INBOX"
+	LabelUnread    = "UNREAD"
+	LabelStarred   = "STARRED"
+	LabelImportant = "IMPORTANT"
+	LabelSpam      = "SPAM"
+)
+
+// MailWatch is Gmail publishing the changes of a user's inbox to a Pub/Sub topic
+type MailWatch struct {
+	HistoryID  uint64    `json:"history_id"` // of the mailbox when the watch started
+	Expiration time.Time `json:"expiration"` // renew the watch before then
+}
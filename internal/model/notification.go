@@ -0,0 +1,144 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types pushed to clients over SSE
+const (
+	EventNewEmail     = "new_email"
+	EventEmailSummary = "email_summary"
+	EventBulkJob      = "bulk_job"
+	EventOTP          = "otp"                // a verification code arrived
+	EventShipment     = "shipment_delivered" // a tracked package arrived
+	EventSecurity     = "security_alert"     // an account-security email arrived
+	EventVIPEmail     = "vip_email"          // an email from a VIP sender arrived
+	EventCleanup      = "cleanup_completed"  // a scheduled cleanup policy ran
+	EventReauth       = "reauth_required"    // Google refused the user's grant, they must sign in again
+
+	EventUnsubscribeIneffective  = "unsubscribe_ineffective"   // a sender emailed again after an unsubscribe
+	EventUnsubscribeManualAction = "unsubscribe_manual_action" // an unsubscribe page needs the user, e.g. for a CAPTCHA
+
+	// Sent to a single connection whatever the preferences: first when it opens, and when it
+	// fell behind and missed events
+	EventConnection = "connection"
+	EventDropped    = "events_dropped"
+)
+
+// Notification priorities, lowest first
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// EventPriority ranks an event type. Bulk job progress, verification codes and unsubscribes left to
+// the user answer something the user just did and security alerts, VIP emails and revoked access may need acting on at once, so they are high;
+// other new mail is normal and sync summaries are low.
+func EventPriority(eventType string) string {
+	switch eventType {
+	case EventBulkJob, EventOTP, EventSecurity, EventVIPEmail, EventUnsubscribeManualAction, EventReauth:
+		return PriorityHigh
+	case EventEmailSummary:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+func priorityRank(priority string) int {
+	switch priority {
+	case PriorityHigh:
+		return 2
+	case PriorityNormal:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// NotificationPreferences controls which SSE events reach a user. During quiet hours only
+// high priority events are delivered.
+type NotificationPreferences struct {
+	UserID          string    `json:"user_id"`
+	QuietHoursStart string    `json:"quiet_hours_start"` // HH:MM in TimeZone; empty disables quiet hours
+	QuietHoursEnd   string    `json:"quiet_hours_end"`   // HH:MM, before the start when quiet hours span midnight
+	TimeZone        string    `json:"time_zone"`         // IANA name such as Europe/Lisbon; empty means UTC
+	EventTypes      []string  `json:"event_types"`       // events to receive; empty receives all
+	MinPriority     string    `json:"min_priority"`      // events below this priority are never delivered
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func NewNotificationPreferences(userID, quietHoursStart, quietHoursEnd, timeZone string, eventTypes []string, minPriority string) *NotificationPreferences {
+	if minPriority == "" {
+		minPriority = PriorityLow
+	}
+	return &NotificationPreferences{
+		UserID:          userID,
+		QuietHoursStart: quietHoursStart,
+		QuietHoursEnd:   quietHoursEnd,
+		TimeZone:        timeZone,
+		EventTypes:      eventTypes,
+		MinPriority:     minPriority,
+		UpdatedAt:       time.Now(),
+	}
+}
+
+// Allows reports whether an event of the given type may be delivered at now
+func (p *NotificationPreferences) Allows(eventType string, now time.Time) bool {
+	if len(p.EventTypes) > 0 && !containsString(p.EventTypes, eventType) {
+		return false
+	}
+
+	priority := EventPriority(eventType)
+	if priorityRank(priority) < priorityRank(p.MinPriority) {
+		return false
+	}
+	return priority == PriorityHigh || !p.InQuietHours(now)
+}
+
+// InQuietHours reports whether now falls within the user's quiet hours
+func (p *NotificationPreferences) InQuietHours(now time.Time) bool {
+	start, okStart := ParseClock(p.QuietHoursStart)
+	end, okEnd := ParseClock(p.QuietHoursEnd)
+	if !okStart || !okEnd || start == end {
+		return false
+	}
+
+	local := now.In(LoadLocation(p.TimeZone))
+	minute := local.Hour()*60 + local.Minute()
+
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// Quiet hours span midnight, e.g. 22:00 to 07:00
+	return minute >= start || minute < end
+}
+
+// ParseClock parses an HH:MM time of day into minutes after midnight
+func ParseClock(value string) (int, bool) {
+	hours, minutes, found := strings.Cut(value, ":")
+	if !found || len(hours) != 2 || len(minutes) != 2 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, false
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
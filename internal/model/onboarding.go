@@ -0,0 +1,40 @@
+package model
+
+// Onboarding steps, in the order a new user goes through them
+const (
+	OnboardingCategories  = "categories"  // pick categories among the suggested ones
+	OnboardingBackfill    = "backfill"    // import a first batch of recent emails
+	OnboardingUnsubscribe = "unsubscribe" // unsubscribe from the senders the backfill shows are ignored
+	OnboardingDone        = "done"
+)
+
+// OnboardingSteps lists the steps in order
+var OnboardingSteps = []string{OnboardingCategories, OnboardingBackfill, OnboardingUnsubscribe, OnboardingDone}
+
+// NextOnboardingStep returns the step after step, OnboardingDone after the last one
+func NextOnboardingStep(step string) string {
+	for i, s := range OnboardingSteps[:len(OnboardingSteps)-1] {
+		if s == step {
+			return OnboardingSteps[i+1]
+		}
+	}
+	return OnboardingDone
+}
+
+// CategorySuggestion is a category offered to a new user
+type CategorySuggestion struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Onboarding is where a user stands in the onboarding wizard and what the current step offers
+type Onboarding struct {
+	Step  string   `json:"step"`
+	Steps []string `json:"steps"`
+	// At the categories step, the suggestions the user doesn't have a category of that name for
+	SuggestedCategories []*CategorySuggestion `json:"suggested_categories,omitempty"`
+	// At the backfill step, how many emails it imports
+	BackfillEmails int `json:"backfill_emails,omitempty"`
+	// At the unsubscribe step, the senders worth unsubscribing from
+	Recommendations []*Recommendation `json:"recommendations,omitempty"`
+}
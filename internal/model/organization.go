@@ -0,0 +1,116 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization roles
+const (
+	RoleAdmin  = "admin"  // manages members, invitations and the team's categories and automations
+	RoleMember = "member" // uses the team's categories and automations
+)
+
+// InvitationTTL is how long an invitation can be accepted
+const InvitationTTL = 7 * 24 * time.Hour
+
+// Organization is a team whose members share categories and automations
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewOrganization(name string) *Organization {
+	now := time.Now()
+	return &Organization{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Membership places a user in an organization; a user belongs to at most one
+type Membership struct {
+	OrgID     string    `json:"org_id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"` // copied from the user when listing members
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewMembership(orgID, userID, role string) *Membership {
+	return &Membership{
+		OrgID:     orgID,
+		UserID:    userID,
+		Role:      role,
+		CreatedAt: time.Now(),
+	}
+}
+
+// IsAdmin reports whether the member manages the organization
+func (m *Membership) IsAdmin() bool {
+	return m.Role == RoleAdmin
+}
+
+// Invitation asks the user with an email address to join an organization
+type Invitation struct {
+	ID         string     `json:"id"`
+	OrgID      string     `json:"org_id"`
+	OrgName    string     `json:"org_name,omitempty"` // filled in for the invitee
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	InvitedBy  string     `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func NewInvitation(orgID, email, role, invitedBy string) *Invitation {
+	now := time.Now()
+	return &Invitation{
+		ID:        uuid.New().String(),
+		OrgID:     orgID,
+		Email:     strings.ToLower(email),
+		Role:      role,
+		InvitedBy: invitedBy,
+		ExpiresAt: now.Add(InvitationTTL),
+		CreatedAt: now,
+	}
+}
+
+// IsPending reports whether the invitation can still be accepted
+func (i *Invitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// OrganizationDetail is the user's organization along with their role and the members
+type OrganizationDetail struct {
+	Organization *Organization `json:"organization"`
+	Role         string        `json:"role"`
+	Members      []*Membership `json:"members"`
+}
+
+// OrganizationStats aggregates the members' mailboxes for admins
+type OrganizationStats struct {
+	Members          int            `json:"members"`
+	Emails           int            `json:"emails"`
+	Unread           int            `json:"unread"`
+	TeamCategories   int            `json:"team_categories"`
+	TeamAutomations  int            `json:"team_automations"`
+	EmailsByCategory map[string]int `json:"emails_by_category"` // category name -> emails across members
+	EmailsByMember   []MemberStats  `json:"emails_by_member"`
+}
+
+// MemberStats counts one member's emails
+type MemberStats struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Emails int    `json:"emails"`
+	Unread int    `json:"unread"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"cmp"
+	"time"
+)
+
+// OTP is a one-time verification code found in an email, for one-tap copying
+type OTP struct {
+	EmailID   string    `json:"email_id"`
+	Code      string    `json:"code"`
+	From      string    `json:"from"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Expired   bool      `json:"expired"`
+}
+
+// NewOTP returns the email's verification code, nil when it has none
+func NewOTP(email *Email, now time.Time) *OTP {
+	if email.OTPCode == "" || email.OTPExpiresAt == nil {
+		return nil
+	}
+	return &OTP{
+		EmailID:   email.ID,
+		Code:      email.OTPCode,
+		From:      cmp.Or(email.FromName, email.FromAddress, email.From),
+		ExpiresAt: *email.OTPExpiresAt,
+		Expired:   !now.Before(*email.OTPExpiresAt),
+	}
+}
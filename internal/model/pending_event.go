@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PendingEvent is an SSE event broadcast while the user had no connection, kept to be sent once
+// they reconnect
+type PendingEvent struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Type      string    `json:"type"`
+	Payload   []byte    `json:"-"` // the SSE message as it would have been sent
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewPendingEvent(userID, eventType string, payload []byte, ttl time.Duration) *PendingEvent {
+	now := time.Now()
+	return &PendingEvent{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Type:      eventType,
+		Payload:   payload,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// Expired reports whether the event is too old to be worth sending at now
+func (e *PendingEvent) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package model
+
+// PrivacySettings is whether the user reads email in privacy mode: bodies are shown as text
+// only, remote content such as images and tracked links is never fetched, and emails in the
+// categories marked sensitive are kept away from the AI and out of exports
+type PrivacySettings struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Redacted returns a copy of the email without its body and anything derived from it, for
+// sensitive emails leaving the app
+func (e *Email) Redacted() *Email {
+	clone := *e
+	clone.Body = ""
+	clone.Snippet = ""
+	clone.Summary = ""
+	clone.OTPCode = ""
+	return &clone
+}
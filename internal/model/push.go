@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PushSubscription is a browser's Web Push endpoint along with the keys to encrypt messages for it
+type PushSubscription struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"-"` // browser public key, base64url
+	Auth      string    `json:"-"` // browser auth secret, base64url
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewPushSubscription(userID, endpoint, p256dh, auth string) *PushSubscription {
+	return &PushSubscription{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Endpoint:  endpoint,
+		P256dh:    p256dh,
+		Auth:      auth,
+		CreatedAt: time.Now(),
+	}
+}
+
+// PushNotification is the payload the service worker turns into a desktop notification
+type PushNotification struct {
+	Type    string `json:"type"` // the SSE event type it stands for
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	URL     string `json:"url"`           // opened when the notification is clicked
+	Tag     string `json:"tag,omitempty"` // notifications with the same tag replace each other
+	Urgency string `json:"-"`             // Web Push urgency: very-low, low, normal or high
+}
+
+// TTL is how many seconds a push service keeps the message for an offline browser;
+// job results are stale after an hour, email alerts stay relevant for a day
+func (n *PushNotification) TTL() int {
+	if n.Type == EventBulkJob {
+		return 60 * 60
+	}
+	return 24 * 60 * 60
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Recommendation kinds
+const (
+	RecommendArchiveStale = "archive_stale" // unread emails of a category left for weeks
+	RecommendUnsubscribe  = "unsubscribe"   // a sender whose emails are never opened
+)
+
+// Recommendation is a cleanup proposed from the user's stored emails, applied as a bulk job
+type Recommendation struct {
+	ID      string      `json:"id"` // stable while the emails it's based on are, e.g. archive_stale:<category id>
+	Kind    string      `json:"kind"`
+	Message string      `json:"message"`
+	Count   int         `json:"count"` // emails concerned
+	Action  string      `json:"action"`
+	Filter  EmailFilter `json:"filter"` // the emails the action applies to
+}
+
+// SenderActivity tallies the user's emails from one sender outside the trash
+type SenderActivity struct {
+	Address        string
+	Total          int
+	Unread         int
+	Unsubscribed   int // emails unsubscribed through
+	LastReceivedAt time.Time
+}
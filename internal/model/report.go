@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InboxReport holds a user's inbox statistics for one week, generated once the week is over
+type InboxReport struct {
+	ID          string      `json:"id"`
+	UserID      string      `json:"-"`
+	PeriodStart time.Time   `json:"period_start"` // Monday 00:00 in the user's time zone, stored as UTC
+	PeriodEnd   time.Time   `json:"period_end"`   // exclusive, the following Monday
+	Stats       ReportStats `json:"stats"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// ReportStats are the figures of an InboxReport
+type ReportStats struct {
+	TotalEmails      int              `json:"total_emails"` // received during the week, trashed ones included
+	Categories       []CategoryVolume `json:"categories"`   // busiest first
+	TopSenders       []SenderVolume   `json:"top_senders"`
+	Summarized       int              `json:"summarized"`
+	Archived         int              `json:"archived"`     // archived in Gmail or in the app
+	Unsubscribes     int              `json:"unsubscribes"` // senders unsubscribed from during the week
+	TimeSavedMinutes int              `json:"time_saved_minutes"`
+}
+
+// CategoryVolume counts a week's emails in one category; CategoryID is "" for unclassified ones
+type CategoryVolume struct {
+	CategoryID string `json:"category_id"`
+	Name       string `json:"name"`
+	Count      int    `json:"count"`
+}
+
+// SenderVolume counts a week's emails from one sender
+type SenderVolume struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+}
+
+// NewInboxReport creates the report of the week starting at periodStart, whose location decides
+// where the week ends when clocks change during it
+func NewInboxReport(userID string, periodStart time.Time, stats ReportStats) *InboxReport {
+	return &InboxReport{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		PeriodStart: periodStart.UTC(),
+		PeriodEnd:   periodStart.AddDate(0, 0, 7).UTC(),
+		Stats:       stats,
+		CreatedAt:   time.Now(),
+	}
+}
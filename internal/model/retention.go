@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// RetentionPolicy controls how long a user's emails are kept locally.
+// A zero value for any limit disables that rule.
+type RetentionPolicy struct {
+	UserID               string    `json:"user_id"`
+	BodyRetentionDays    int       `json:"body_retention_days"`     // drop bodies of emails older than this
+	RetentionDays        int       `json:"retention_days"`          // delete emails older than this
+	MaxEmailsPerCategory int       `json:"max_emails_per_category"` // keep only the newest N emails per category
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+func NewRetentionPolicy(userID string, bodyRetentionDays, retentionDays, maxEmailsPerCategory int) *RetentionPolicy {
+	return &RetentionPolicy{
+		UserID:               userID,
+		BodyRetentionDays:    bodyRetentionDays,
+		RetentionDays:        retentionDays,
+		MaxEmailsPerCategory: maxEmailsPerCategory,
+		UpdatedAt:            time.Now(),
+	}
+}
+
+// IsEnabled reports whether the policy has at least one active rule
+func (p *RetentionPolicy) IsEnabled() bool {
+	return p.BodyRetentionDays > 0 || p.RetentionDays > 0 || p.MaxEmailsPerCategory > 0
+}
+
+// PruneStats records what a retention pass removed
+type PruneStats struct {
+	BodiesPruned  int       `json:"bodies_pruned"`
+	EmailsDeleted int       `json:"emails_deleted"`
+	LastRunAt     time.Time `json:"last_run_at,omitempty"`
+}
+
+// Add accumulates another pass into the stats
+func (s *PruneStats) Add(other PruneStats) {
+	s.BodiesPruned += other.BodiesPruned
+	s.EmailsDeleted += other.EmailsDeleted
+	if other.LastRunAt.After(s.LastRunAt) {
+		s.LastRunAt = other.LastRunAt
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedView is a named email filter a user keeps around as a smart view
+type SavedView struct {
+	ID        string      `json:"id"`
+	UserID    string      `json:"user_id"`
+	Name      string      `json:"name"`
+	Filter    EmailFilter `json:"filter"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func NewSavedView(userID, name string, filter EmailFilter) *SavedView {
+	now := time.Now()
+	return &SavedView{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Filter:    filter,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"cmp"
+	"time"
+)
+
+// Security flags mark account-security emails, whatever category they were classified under
+const (
+	SecurityPasswordReset = "password_reset"      // reset link or code the user may not have asked for
+	SecurityNewSignIn     = "new_sign_in"         // sign-in from a new device, browser or location
+	SecurityAccountChange = "account_change"      // password, recovery details or two-factor settings changed
+	SecuritySuspicious    = "suspicious_activity" // blocked attempt or other unusual activity
+)
+
+// SecurityEvent is an account-security email, listed across providers and categories
+type SecurityEvent struct {
+	EmailID    string    `json:"email_id"`
+	Kind       string    `json:"kind"`
+	From       string    `json:"from"`
+	Subject    string    `json:"subject"`
+	Summary    string    `json:"summary,omitempty"`
+	Unread     bool      `json:"unread"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// NewSecurityEvent returns the email as a security event, nil when it isn't flagged
+func NewSecurityEvent(email *Email) *SecurityEvent {
+	if email.SecurityFlag == "" {
+		return nil
+	}
+	return &SecurityEvent{
+		EmailID:    email.ID,
+		Kind:       email.SecurityFlag,
+		From:       cmp.Or(email.FromName, email.FromAddress, email.From),
+		Subject:    email.Subject,
+		Summary:    email.Summary,
+		Unread:     email.Unread,
+		ReceivedAt: email.ReceivedAt,
+	}
+}
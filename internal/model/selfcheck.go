@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// SelfCheck is the outcome of checking one of the services the app depends on
+type SelfCheck struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Message    string `json:"message"` // what was found, or what to fix when the check failed
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// SelfCheckReport gathers the outcomes of every check, in the order they were registered
+type SelfCheckReport struct {
+	OK        bool         `json:"ok"` // every check passed
+	Checks    []*SelfCheck `json:"checks"`
+	CheckedAt time.Time    `json:"checked_at"`
+}
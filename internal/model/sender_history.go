@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// SenderHistory is every stored email from one sender with how the user engaged with them
+type SenderHistory struct {
+	Address string         `json:"address"`
+	Name    string         `json:"name,omitempty"` // display name of the latest email
+	Blocked bool           `json:"blocked"`        // sync skips the sender's new emails
+	Stats   SenderStats    `json:"stats"`
+	Profile *SenderProfile `json:"profile,omitempty"` // the last generated profile, if any
+	Emails  []*Email       `json:"emails"`            // oldest first, without bodies
+}
+
+// SenderStats counts a sender's emails by what happened to them; an email may count towards several
+type SenderStats struct {
+	Total           int        `json:"total"`
+	Unread          int        `json:"unread"`
+	Archived        int        `json:"archived"` // in Gmail or locally
+	Deleted         int        `json:"deleted"`  // in the trash
+	Unsubscribed    int        `json:"unsubscribed"`
+	FirstReceivedAt *time.Time `json:"first_received_at,omitempty"`
+	LastReceivedAt  *time.Time `json:"last_received_at,omitempty"`
+}
+
+// NewSenderHistory tallies the sender's emails, given oldest first
+func NewSenderHistory(address string, emails []*Email) *SenderHistory {
+	history := &SenderHistory{Address: address, Emails: make([]*Email, 0, len(emails))}
+	for _, email := range emails {
+		history.Emails = append(history.Emails, email.WithoutBody())
+		if email.FromName != "" {
+			history.Name = email.FromName
+		}
+
+		stats := &history.Stats
+		stats.Total++
+		if email.Unread {
+			stats.Unread++
+		}
+		if email.Archived || email.LocallyArchived {
+			stats.Archived++
+		}
+		if email.DeletedAt != nil {
+			stats.Deleted++
+		}
+		if email.UnsubscribedAt != nil {
+			stats.Unsubscribed++
+		}
+		receivedAt := email.ReceivedAt
+		if stats.FirstReceivedAt == nil {
+			stats.FirstReceivedAt = &receivedAt
+		}
+		stats.LastReceivedAt = &receivedAt
+	}
+	return history
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// SenderProfile is the AI's short characterization of a sender, such as "weekly marketing
+// newsletter, ~3 emails/week, rarely important"
+type SenderProfile struct {
+	UserID      string    `json:"-"`
+	Address     string    `json:"address"`
+	Description string    `json:"description"`
+	EmailCount  int       `json:"email_count"` // stored emails from the sender when it was generated
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// IsCurrent reports whether the profile was generated from the sender's current emails
+func (p *SenderProfile) IsCurrent(history *SenderHistory) bool {
+	return p.EmailCount == history.Stats.Total
+}
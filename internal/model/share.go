@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailShare is a public, read-only link to an email's summary
+type EmailShare struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	EmailID     string     `json:"email_id"`
+	IncludeBody bool       `json:"include_body"` // the page also shows the email body as plain text
+	URL         string     `json:"url"`          // signed public URL; built from the ID, not stored
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func NewEmailShare(userID, emailID string, includeBody bool, expiresAt time.Time) *EmailShare {
+	return &EmailShare{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		EmailID:     emailID,
+		IncludeBody: includeBody,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// IsActive reports whether the link can still be opened
+func (s *EmailShare) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// SharedEmail is what a share link shows to people without an account
+type SharedEmail struct {
+	Subject    string
+	From       string
+	ReceivedAt time.Time
+	Category   string
+	Summary    string
+	Body       string // plain text, empty unless the share includes the body
+	ExpiresAt  time.Time
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Carriers shipments are recognized for
+const (
+	CarrierUPS   = "ups"
+	CarrierFedEx = "fedex"
+	CarrierUSPS  = "usps"
+	CarrierDHL   = "dhl"
+)
+
+// Shipment statuses, from the shipping notice or the carrier's tracking
+const (
+	ShipmentInTransit      = "in_transit"
+	ShipmentOutForDelivery = "out_for_delivery"
+	ShipmentDelivered      = "delivered"
+	ShipmentException      = "exception" // delayed, returned or failed delivery
+)
+
+// Shipment is a package found in a user's shipping notifications
+type Shipment struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"user_id"`
+	EmailID        string     `json:"email_id"` // the latest notification about the package
+	Carrier        string     `json:"carrier"`
+	TrackingNumber string     `json:"tracking_number"`
+	TrackingURL    string     `json:"tracking_url,omitempty"`
+	Status         string     `json:"status"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CheckedAt      *time.Time `json:"checked_at,omitempty"` // last status poll of the carrier
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func NewShipment(userID, emailID, carrier, trackingNumber, status string) *Shipment {
+	now := time.Now()
+	shipment := &Shipment{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		EmailID:        emailID,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		TrackingURL:    TrackingURL(carrier, trackingNumber),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	shipment.SetStatus(status, now)
+	return shipment
+}
+
+// SetStatus records a status change, reporting whether the status is new
+func (s *Shipment) SetStatus(status string, at time.Time) bool {
+	if status == "" || status == s.Status {
+		return false
+	}
+	s.Status = status
+	if status == ShipmentDelivered {
+		s.DeliveredAt = &at
+	}
+	s.UpdatedAt = at
+	return true
+}
+
+// IsDelivered reports whether the package arrived; delivered shipments are no longer tracked
+func (s *Shipment) IsDelivered() bool {
+	return s.Status == ShipmentDelivered
+}
+
+// TrackingURL links to the carrier's public tracking page, "" for unknown carriers
+func TrackingURL(carrier, trackingNumber string) string {
+	switch carrier {
+	case CarrierUPS:
+		return "https://www.ups.com/track?tracknum=" + trackingNumber
+	case CarrierFedEx:
+		return "https://www.fedex.com/fedextrack/?trknbr=" + trackingNumber
+	case CarrierUSPS:
+		return "https://tools.usps.com/go/TrackConfirmAction?tLabels=" + trackingNumber
+	case CarrierDHL:
+		return "https://www.dhl.com/en/express/tracking.html?AWB=" + trackingNumber
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,10 @@
+package model
+
+// SyncResult accounts for every email a sync fetched from Gmail: each one was stored as new,
+// updated, or skipped
+type SyncResult struct {
+	Fetched []*Email // as Gmail returned them
+	New     []*Email // stored for the first time, classified and summarized
+	Updated []*Email // already stored, with the read or starred state Gmail had changed
+	Skipped int      // already stored and unchanged, in the trash, from a blocked sender or over the quota
+}
@@ -0,0 +1,94 @@
+package model
+
+import "strings"
+
+// System flags mark machine-generated emails, which are kept out of AI classification and summaries
+const (
+	SystemFlagBounce    = "bounce"     // delivery failure notice
+	SystemFlagAutoReply = "auto_reply" // out-of-office or other automatic reply
+)
+
+// SystemEmailSettings is how the user wants bounces and auto-replies handled
+type SystemEmailSettings struct {
+	AutoArchive bool `json:"auto_archive"` // locally archive them as they are synced
+}
+
+// bounceSenders are the local parts mail servers send delivery failure notices from
+var bounceSenders = []string{"mailer-daemon", "postmaster"}
+
+// Subject prefixes of the notices the common mail servers and clients send, lower-cased
+var (
+	bounceSubjects = []string{
+		"delivery status notification (failure)",
+		"delivery status notification (delay)",
+		"undeliverable:",
+		"undelivered mail returned to sender",
+		"mail delivery failed",
+		"mail delivery failure",
+		"delivery failure",
+		"returned mail:",
+		"failure notice",
+	}
+	autoReplySubjects = []string{
+		"automatic reply:",
+		"auto-reply:",
+		"autoreply:",
+		"auto reply:",
+		"out of office",
+		"out of the office",
+	}
+)
+
+// SystemFlagFromHeaders detects machine-generated emails from the headers the sending server
+// added, keyed by lower-cased name. Auto-Submitted: auto-generated alone isn't enough, since
+// many notifications people want to read carry it too.
+func SystemFlagFromHeaders(headers map[string]string) string {
+	if _, ok := headers["x-failed-recipients"]; ok {
+		return SystemFlagBounce
+	}
+	contentType := strings.ToLower(headers["content-type"])
+	if strings.HasPrefix(contentType, "multipart/report") && strings.Contains(contentType, "delivery-status") {
+		return SystemFlagBounce
+	}
+
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(headers["auto-submitted"])), "auto-replied") {
+		return SystemFlagAutoReply
+	}
+	if _, ok := headers["x-autoreply"]; ok {
+		return SystemFlagAutoReply
+	}
+	if _, ok := headers["x-autorespond"]; ok {
+		return SystemFlagAutoReply
+	}
+	if strings.EqualFold(strings.TrimSpace(headers["precedence"]), "auto_reply") {
+		return SystemFlagAutoReply
+	}
+	return ""
+}
+
+// DetectSystemFlag falls back on the sender and subject when the headers didn't flag the email,
+// setting SystemFlag on a match, and reports whether the email is machine-generated
+func (e *Email) DetectSystemFlag() bool {
+	if e.SystemFlag != "" {
+		return true
+	}
+
+	localPart, _, _ := strings.Cut(e.FromAddress, "@")
+	subject := strings.ToLower(strings.TrimSpace(e.Subject))
+	switch {
+	case containsString(bounceSenders, localPart) || hasAnyPrefix(subject, bounceSubjects):
+		e.SystemFlag = SystemFlagBounce
+	case hasAnyPrefix(subject, autoReplySubjects):
+		e.SystemFlag = SystemFlagAutoReply
+	}
+	return e.SystemFlag != ""
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
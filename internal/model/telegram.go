@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// TelegramLink connects a user to the Telegram chat the bot talks to them in
+type TelegramLink struct {
+	UserID      string    `json:"user_id"`
+	ChatID      int64     `json:"chat_id"`
+	LastEmailID string    `json:"-"` // the email most recently sent to the chat, the default target of commands
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func NewTelegramLink(userID string, chatID int64) *TelegramLink {
+	return &TelegramLink{
+		UserID:    userID,
+		ChatID:    chatID,
+		CreatedAt: time.Now(),
+	}
+}
+
+// TelegramLinkCode is a one-time code the user sends to the bot as /start <code> to link their chat
+type TelegramLinkCode struct {
+	Code      string    `json:"code"`
+	URL       string    `json:"url,omitempty"` // t.me deep link sending the code, when the bot username is configured
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TelegramMessage is a text message the bot received
+type TelegramMessage struct {
+	UpdateID    int64
+	ChatID      int64
+	Text        string
+	ReplyToText string // text of the bot message being replied to, if any
+}
@@ -0,0 +1,31 @@
+package model
+
+// TriageActions lists the decisions triage accepts; keep leaves the email as it is
+var TriageActions = []string{"keep", "archive", "read", "star", "local_archive", "delete", "spam"}
+
+// Why an action was suggested, from the strongest signal to the fallback
+const (
+	TriageReasonAutomation = "automation"     // an automation on the email's category runs the action
+	TriageReasonSender     = "sender_history" // the user mostly decided this on the sender's earlier emails
+	TriageReasonImportant  = "important"      // Gmail marked the email important
+	TriageReasonDefault    = "default"
+)
+
+// TriageSuggestion is the action triage proposes for an email
+type TriageSuggestion struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// TriageItem is the next email to triage; Email is nil once the queue is empty
+type TriageItem struct {
+	Email      *Email            `json:"email"`
+	Suggestion *TriageSuggestion `json:"suggestion,omitempty"`
+	Remaining  int               `json:"remaining"` // emails left to triage, this one included
+}
+
+// NeedsTriage reports whether the email waits in the triage queue: it is in the inbox, neither
+// archived nor trashed, and the user hasn't decided on it yet
+func (e *Email) NeedsTriage() bool {
+	return e.TriagedAt == nil && e.DeletedAt == nil && !e.Archived && !e.LocallyArchived
+}
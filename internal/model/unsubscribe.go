@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dark patterns found on unsubscribe pages
+const (
+	DarkPatternPrecheckedOptIn = "prechecked_opt_in" // a box keeping the user on a list is checked by default
+	DarkPatternPauseInstead    = "pause_instead"     // pausing or fewer emails offered in place of opting out
+	DarkPatternConfirmshaming  = "confirmshaming"    // the opt-out is worded to guilt the user, reported by the AI
+)
+
+// Escalations the user can choose when a sender keeps emailing after an unsubscribe
+const (
+	EscalationBlock = "block" // later emails from the sender are skipped by sync
+	EscalationSpam  = "spam"  // the emails since the unsubscribe are reported as spam, and the sender blocked
+)
+
+// UnsubscribeAttempt records how unsubscribing through an email went, with what was chosen on
+// the sender's page so the user can see it was done in their interest
+type UnsubscribeAttempt struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"-"`
+	EmailID       string    `json:"email_id"`
+	SenderAddress string    `json:"sender_address"`
+	URL           string    `json:"url,omitempty"` // the unsubscribe page that succeeded, or was tried last
+	Succeeded     bool      `json:"succeeded"`
+	Error         string    `json:"error,omitempty"`
+	DarkPatterns  []string  `json:"dark_patterns"`
+	Selections    []string  `json:"selections"` // what was checked, unchecked, chosen or clicked, in order
+	CreatedAt     time.Time `json:"created_at"`
+
+	// A page the user has to finish unsubscribing on themselves, e.g. one behind a CAPTCHA
+	ManualActionURL string `json:"manual_action_url,omitempty"`
+
+	// Set once the sender emailed again after the grace period, with the emails synced since
+	IneffectiveAt      *time.Time `json:"ineffective_at,omitempty"`
+	ResurfacedEmailIDs []string   `json:"resurfaced_email_ids"`
+	Escalation         string     `json:"escalation,omitempty"` // block or spam, once the user escalated
+}
+
+func NewUnsubscribeAttempt(email *Email) *UnsubscribeAttempt {
+	return &UnsubscribeAttempt{
+		ID:            uuid.New().String(),
+		UserID:        email.UserID,
+		EmailID:       email.ID,
+		SenderAddress: email.FromAddress,
+		DarkPatterns:  []string{},
+		Selections:    []string{},
+		CreatedAt:     time.Now(),
+
+		ResurfacedEmailIDs: []string{},
+	}
+}
+
+// StartPage resets what was found on a previous page before trying the next one
+func (a *UnsubscribeAttempt) StartPage(pageURL string) {
+	a.URL = pageURL
+	a.DarkPatterns = []string{}
+	a.Selections = []string{}
+}
+
+// AddDarkPattern records a dark pattern once per page
+func (a *UnsubscribeAttempt) AddDarkPattern(kind string) {
+	if !slices.Contains(a.DarkPatterns, kind) {
+		a.DarkPatterns = append(a.DarkPatterns, kind)
+	}
+}
+
+// Select records an action taken on the page, e.g. `unchecked "Keep me on the newsletter"`
+func (a *UnsubscribeAttempt) Select(format string, args ...any) {
+	a.Selections = append(a.Selections, fmt.Sprintf(format, args...))
+}
+
+// RequiresManualAction reports whether the user has yet to finish the unsubscribe on the sender's page
+func (a *UnsubscribeAttempt) RequiresManualAction() bool {
+	return a.ManualActionURL != "" && !a.Succeeded
+}
+
+// Resurfaced records an email from the sender received after the grace period following a
+// successful unsubscribe, reporting whether it counts against the unsubscribe; the first one
+// makes the unsubscribe ineffective
+func (a *UnsubscribeAttempt) Resurfaced(email *Email, grace time.Duration, now time.Time) bool {
+	if !a.Succeeded || email.ReceivedAt.Before(a.CreatedAt.Add(grace)) || slices.Contains(a.ResurfacedEmailIDs, email.ID) {
+		return false
+	}
+	a.ResurfacedEmailIDs = append(a.ResurfacedEmailIDs, email.ID)
+	if a.IneffectiveAt == nil {
+		a.IneffectiveAt = &now
+	}
+	return true
+}
+
+// IsIneffective reports whether the sender kept emailing after the unsubscribe
+func (a *UnsubscribeAttempt) IsIneffective() bool {
+	return a.IneffectiveAt != nil
+}
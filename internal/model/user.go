@@ -1,34 +1,100 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Gmail OAuth scopes; readonly is requested at login and modify only once an action needs it
+const (
+	ScopeGmailReadonly = "https://www.googleapis.com/auth/gmail.readonly"
+	ScopeGmailModify   = "https://www.googleapis.com/auth/gmail.modify"
+)
+
 type User struct {
-	ID            string    `json:"id"`
-	GoogleID      string    `json:"google_id"`
-	Email         string    `json:"email"`
-	Name          string    `json:"name"`
-	AccessToken   string    `json:"access_token"`
-	RefreshToken  string    `json:"refresh_token"`
-	TokenExpiry   time.Time `json:"token_expiry"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	GoogleID       string    `json:"google_id"`
+	Email          string    `json:"email"`
+	Name           string    `json:"name"`
+	AccessToken    string    `json:"access_token"`
+	RefreshToken   string    `json:"refresh_token"`
+	TokenExpiry    time.Time `json:"token_expiry"`
+	GrantedScopes  string    `json:"granted_scopes"`  // space-separated OAuth scopes from the last consent
+	BlockedSenders string    `json:"blocked_senders"` // space-separated sender addresses whose emails sync skips
+	// ArchiveSystemEmails locally archives bounces and auto-replies as they are synced
+	ArchiveSystemEmails bool      `json:"archive_system_emails"`
+	TimeZone            string    `json:"time_zone"` // IANA name schedules and quiet hours follow; empty means UTC
+	Locale              string    `json:"locale"`    // language of API messages and AI summaries; empty follows the browser
+	PrivacyMode         bool      `json:"privacy_mode"`
+	OnboardingStep      string    `json:"onboarding_step"`   // empty for users who signed up before onboarding, who count as done
+	NeedsReauth         bool      `json:"needs_reauth"`      // Google refused the user's grant; cleared when they sign in again
+	WorkspaceMailbox    bool      `json:"workspace_mailbox"` // designated by an operator, synced with the Workspace service account
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
 }
 
 func NewUser(googleID, email, name, accessToken, refreshToken string, tokenExpiry time.Time) *User {
 	now := time.Now()
 	return &User{
-		ID:            uuid.New().String(),
-		GoogleID:      googleID,
-		Email:         email,
-		Name:          name,
-		AccessToken:   accessToken,
-		RefreshToken:  refreshToken,
-		TokenExpiry:   tokenExpiry,
-		CreatedAt:     now,
-		UpdatedAt:     now,
+		ID:           uuid.New().String(),
+		GoogleID:     googleID,
+		Email:        email,
+		Name:         name,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenExpiry:  tokenExpiry,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// Location returns the user's time zone, UTC when unset or unknown
+func (u *User) Location() *time.Location {
+	return LoadLocation(u.TimeZone)
+}
+
+// Onboarding returns the user's step of the onboarding wizard
+func (u *User) Onboarding() string {
+	if u.OnboardingStep == "" {
+		return OnboardingDone
 	}
-}
\ No newline at end of file
+	return u.OnboardingStep
+}
+
+// HasGoogleGrant reports whether the user still holds OAuth tokens; they are cleared when the grant is revoked
+func (u *User) HasGoogleGrant() bool {
+	return u.AccessToken != "" || u.RefreshToken != ""
+}
+
+// BlockSender adds the address to the user's blocked senders, reporting whether it was new
+func (u *User) BlockSender(address string) bool {
+	address = strings.ToLower(address)
+	if address == "" || u.IsSenderBlocked(address) {
+		return false
+	}
+	u.BlockedSenders = strings.TrimSpace(u.BlockedSenders + " " + address)
+	return true
+}
+
+// IsSenderBlocked reports whether emails from the address should be skipped
+func (u *User) IsSenderBlocked(address string) bool {
+	address = strings.ToLower(address)
+	for _, blocked := range strings.Fields(u.BlockedSenders) {
+		if blocked == address {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the user's last consent included the scope
+func (u *User) HasScope(scope string) bool {
+	for _, granted := range strings.Fields(u.GrantedScopes) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
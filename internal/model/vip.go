@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// EmailPriorityUrgent marks emails from VIP senders, which automations leave alone and which
+// are notified right away
+const EmailPriorityUrgent = "urgent"
+
+// VIPSender is a sender whose emails the user never wants to miss
+type VIPSender struct {
+	UserID    string    `json:"-"`
+	Address   string    `json:"address"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsUrgent reports whether the email came from one of the user's VIP senders
+func (e *Email) IsUrgent() bool {
+	return e.Priority == EmailPriorityUrgent
+}
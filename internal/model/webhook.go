@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Webhook is the URL VIP emails are posted to for a user, signed with Secret
+type Webhook struct {
+	UserID    string    `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"` // HMAC-SHA256 key of the X-Webhook-Signature header, hex
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebhook(userID, url, secret string) *Webhook {
+	return &Webhook{
+		UserID:    userID,
+		URL:       url,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// WorkspaceGoogleIDPrefix marks the Google ID of a mailbox an operator designated before its
+// user signed in; their first sign-in replaces it with their own
+const WorkspaceGoogleIDPrefix = "workspace_"
+
+// WorkspaceScopes are the scopes the Workspace domain's admin delegates to the service account
+var WorkspaceScopes = []string{ScopeGmailReadonly, ScopeGmailModify}
+
+// WorkspaceConnection is the Workspace domain the app syncs mailboxes of with domain-wide
+// delegation, and what its admin authorizes for it in the Admin console
+type WorkspaceConnection struct {
+	Domain    string             `json:"domain"`
+	ClientID  string             `json:"client_id"` // of the service account, empty when its key couldn't be read
+	Scopes    []string           `json:"scopes"`
+	Mailboxes []WorkspaceMailbox `json:"mailboxes"`
+}
+
+// WorkspaceMailbox is a mailbox of the domain an operator designated to be synced
+type WorkspaceMailbox struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	SignedIn  bool      `json:"signed_in"` // its user signed in to the app too
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewWorkspaceMailbox returns a user for a designated mailbox, without OAuth tokens since the
+// service account calls Gmail for it. Its user goes through onboarding when they first sign in.
+func NewWorkspaceMailbox(email, name string) *User {
+	email = strings.ToLower(email)
+	user := NewUser(WorkspaceGoogleIDPrefix+email, email, name, "", "", time.Time{})
+	user.GrantWorkspaceScopes()
+	user.OnboardingStep = OnboardingCategories
+	user.WorkspaceMailbox = true
+	return user
+}
+
+// InDomain reports whether the address belongs to the domain
+func InDomain(address, domain string) bool {
+	_, addressDomain, ok := strings.Cut(address, "@")
+	return ok && domain != "" && strings.EqualFold(addressDomain, domain)
+}
+
+// GrantWorkspaceScopes adds the scopes delegated to the service account to the user's, as its
+// calls for the user carry them whatever the user consented to
+func (u *User) GrantWorkspaceScopes() {
+	for _, scope := range WorkspaceScopes {
+		if !u.HasScope(scope) {
+			u.GrantedScopes = strings.TrimSpace(u.GrantedScopes + " " + scope)
+		}
+	}
+}
+
+// SignedIn reports whether the user ever signed in with Google, rather than only having their
+// mailbox designated
+func (u *User) SignedIn() bool {
+	return !strings.HasPrefix(u.GoogleID, WorkspaceGoogleIDPrefix)
+}
@@ -0,0 +1,331 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operation documents a single API endpoint
+type Operation struct {
+	Method      string
+	Path        string // echo-style path, e.g. /emails/:id
+	Summary     string
+	Tag         string
+	Public      bool   // no session required
+	Request     any    // request body value, nil when the endpoint takes no body
+	Response    any    // success response value, nil for empty responses
+	Status      int    // success status code, defaults to 200
+	ContentType string // success content type, defaults to application/json
+	HTML        bool   // also served as an HTML partial to HTMX clients and Accept: text/html
+	Query       any    // struct whose `query` tagged fields are the query parameters, described by `doc` tags
+
+	// For text/event-stream responses, the events the stream carries
+	Events []Event
+}
+
+// Event documents one type of event of a stream, sent as {type, version, data, time}
+type Event struct {
+	Name        string
+	Version     int
+	Description string
+	Data        any // data value
+}
+
+// Document builds an OpenAPI 3 specification from registered operations
+type Document struct {
+	title   string
+	version string
+	baseURL string
+
+	operations []Operation
+	schemas    map[string]*Schema
+	errorType  any
+}
+
+// Schema is the subset of JSON schema used by the generated document
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// NewDocument creates an empty document whose paths are relative to baseURL.
+// errorType is the body returned by every failing endpoint.
+func NewDocument(title, version, baseURL string, errorType any) *Document {
+	return &Document{
+		title:     title,
+		version:   version,
+		baseURL:   baseURL,
+		schemas:   make(map[string]*Schema),
+		errorType: errorType,
+	}
+}
+
+// Add registers an operation with the document
+func (d *Document) Add(op Operation) {
+	d.operations = append(d.operations, op)
+}
+
+var pathParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// Spec renders the document as an OpenAPI 3 JSON-compatible value
+func (d *Document) Spec() map[string]any {
+	errorSchema := d.schemaFor(reflect.TypeOf(d.errorType))
+	paths := make(map[string]map[string]any)
+
+	for _, op := range d.operations {
+		path := pathParam.ReplaceAllString(op.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		success := map[string]any{"description": http.StatusText(status)}
+		if op.Response != nil || op.ContentType != "" {
+			contentType := op.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			schema := &Schema{Type: "string"}
+			if op.Response != nil {
+				schema = d.schemaFor(reflect.TypeOf(op.Response))
+			}
+			if len(op.Events) > 0 {
+				schema = d.eventsSchema(op.Events)
+			}
+			content := map[string]any{contentType: map[string]any{"schema": schema}}
+			if op.HTML {
+				content["text/html"] = map[string]any{"schema": &Schema{Type: "string"}}
+			}
+			success["content"] = content
+		}
+
+		errorResponse := func(description string) map[string]any {
+			return map[string]any{
+				"description": description,
+				"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+			}
+		}
+
+		responses := map[string]any{
+			strconv.Itoa(status): success,
+			"default":            errorResponse("Error"),
+		}
+		if !op.Public {
+			responses["401"] = errorResponse("Unauthorized")
+		}
+
+		operation := map[string]any{
+			"summary":     op.Summary,
+			"operationId": operationID(op.Method, op.Path),
+			"responses":   responses,
+		}
+		if op.Tag != "" {
+			operation["tags"] = []string{op.Tag}
+		}
+		if op.Public {
+			operation["security"] = []any{}
+		}
+
+		var params []map[string]any
+		for _, match := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   &Schema{Type: "string"},
+			})
+		}
+		if op.Query != nil {
+			queryType := reflect.TypeOf(op.Query)
+			for i := 0; i < queryType.NumField(); i++ {
+				field := queryType.Field(i)
+				name, _, _ := strings.Cut(field.Tag.Get("query"), ",")
+				if name == "" {
+					continue
+				}
+				params = append(params, map[string]any{
+					"name":        name,
+					"in":          "query",
+					"description": field.Tag.Get("doc"),
+					"schema":      d.schemaFor(field.Type),
+				})
+			}
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": d.schemaFor(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+
+		paths[path][strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   d.title,
+			"version": d.version,
+		},
+		"servers": []map[string]any{{"url": d.baseURL}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": d.schemas,
+			"securitySchemes": map[string]any{
+				"session": map[string]any{"type": "apiKey", "in": "cookie", "name": "gothic_session"},
+			},
+		},
+		"security": []map[string]any{{"session": []string{}}},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor maps a Go type onto a schema, registering named structs as components
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Nullable: nullable}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Nullable: nullable}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaFor(t.Elem()), Nullable: nullable}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem()), Nullable: nullable}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := d.schemas[name]; !ok {
+			// Reserve the name first so self-referencing types terminate
+			d.schemas[name] = &Schema{}
+			*d.schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Embedded structs have their fields inlined, as encoding/json does
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			inlined := d.structSchema(embedded)
+			for property, propertySchema := range inlined.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, inlined.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = d.schemaFor(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// eventsSchema registers an envelope component per event, e.g. NewEmailEvent for new_email,
+// and returns a schema matching any of them
+func (d *Document) eventsSchema(events []Event) *Schema {
+	schema := &Schema{}
+	for _, event := range events {
+		var name strings.Builder
+		for _, word := range strings.Split(event.Name, "_") {
+			if word != "" {
+				name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+			}
+		}
+		name.WriteString("Event")
+
+		d.schemas[name.String()] = &Schema{
+			Type:        "object",
+			Description: event.Description,
+			Properties: map[string]*Schema{
+				"type":    {Type: "string", Enum: []any{event.Name}},
+				"version": {Type: "integer", Format: "int32", Enum: []any{event.Version}},
+				"data":    d.schemaFor(reflect.TypeOf(event.Data)),
+				"time":    {Type: "integer", Format: "int64"},
+			},
+			Required: []string{"type", "version", "data", "time"},
+		}
+		schema.OneOf = append(schema.OneOf, &Schema{Ref: "#/components/schemas/" + name.String()})
+	}
+	return schema
+}
+
+// operationID derives a stable identifier such as getEmailsById from the route
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' }) {
+		if strings.HasPrefix(part, ":") {
+			b.WriteString("By")
+			part = part[1:]
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
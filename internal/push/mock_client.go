@@ -0,0 +1,29 @@
+package push
+
+import (
+	"context"
+
+	"jump-challenge/internal/model"
+)
+
+// MockPushClient is a mock implementation of PushClient for testing
+type MockPushClient struct {
+	SendFunc func(ctx context.Context, subscription *model.PushSubscription, notification *model.PushNotification) error
+}
+
+func NewMockPushClient() *MockPushClient {
+	return &MockPushClient{}
+}
+
+func (m *MockPushClient) PublicKey() string {
+	return "mock-vapid-public-key"
+}
+
+func (m *MockPushClient) Send(ctx context.Context, subscription *model.PushSubscription, notification *model.PushNotification) error {
+	if m.SendFunc != nil {
+		return m.SendFunc(ctx, subscription, notification)
+	}
+
+	// Default mock behavior: success
+	return nil
+}
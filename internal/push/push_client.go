@@ -0,0 +1,233 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+)
+
+// pushClient sends Web Push messages signed with VAPID (RFC 8292) and encrypted
+// with aes128gcm (RFC 8291)
+type pushClient struct {
+	publicKey  []byte // uncompressed P-256 point
+	privateKey *ecdsa.PrivateKey
+	subject    string // contact for push services, a mailto: or https: URL
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// recordSize is the aes128gcm record size; messages are small enough to fit a single record
+const recordSize = 4096
+
+var b64 = base64.RawURLEncoding
+
+// NewPushClient builds a client from base64url-encoded VAPID keys as printed by GenerateVAPIDKeys
+func NewPushClient(publicKey, privateKey, subject string, logger *logger.Logger) (service.PushClient, error) {
+	rawPrivate, err := b64.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(rawPrivate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	public := ecdhKey.PublicKey().Bytes()
+	if publicKey != "" && publicKey != b64.EncodeToString(public) {
+		return nil, errors.New("VAPID public key does not match the private key")
+	}
+
+	return &pushClient{
+		publicKey: public,
+		privateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(public[1:33]),
+				Y:     new(big.Int).SetBytes(public[33:]),
+			},
+			D: new(big.Int).SetBytes(rawPrivate),
+		},
+		subject:    subject,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}, nil
+}
+
+// GenerateVAPIDKeys creates a new application server key pair, base64url-encoded
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return b64.EncodeToString(key.PublicKey().Bytes()), b64.EncodeToString(key.Bytes()), nil
+}
+
+func (p *pushClient) PublicKey() string {
+	return b64.EncodeToString(p.publicKey)
+}
+
+func (p *pushClient) Send(ctx context.Context, subscription *model.PushSubscription, notification *model.PushNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode push notification: %w", err)
+	}
+
+	body, err := encrypt(subscription, payload)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt push notification: %w", err)
+	}
+
+	token, err := p.vapidToken(subscription.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to sign push notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "vapid t="+token+", k="+p.PublicKey())
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(notification.TTL()))
+	req.Header.Set("Urgency", notification.Urgency)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach push service: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return service.ErrPushSubscriptionGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("push service responded with status %d", resp.StatusCode)
+	}
+
+	p.logger.Info("Delivered push notification to subscription:", subscription.ID)
+	return nil
+}
+
+// vapidToken signs the ES256 JWT that identifies this server to the endpoint's push service
+func (p *pushClient) vapidToken(endpoint string) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": p.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + b64.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, p.privateKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS wants the raw 64-byte r||s signature rather than ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return unsigned + "." + b64.EncodeToString(signature), nil
+}
+
+// encrypt produces an aes128gcm body readable only by the subscribed browser
+func encrypt(subscription *model.PushSubscription, payload []byte) ([]byte, error) {
+	userAgentKey, err := b64.DecodeString(subscription.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := b64.DecodeString(subscription.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+	userAgentPublic, err := ecdh.P256().NewPublicKey(userAgentKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	// A fresh key pair and salt per message
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	sharedSecret, err := serverKey.ECDH(userAgentPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(userAgentKey) + string(serverPublic)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	contentKey, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record
+	ciphertext := gcm.Seal(nil, nonce, append(payload, 0x02), nil)
+
+	// Header: salt, record size, key id length and the server public key as key id
+	body := make([]byte, 0, 16+4+1+len(serverPublic)+len(ciphertext))
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(serverPublic)))
+	body = append(body, serverPublic...)
+	return append(body, ciphertext...), nil
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"jump-challenge/internal/apierror"
 	"jump-challenge/internal/model"
 )
 
@@ -21,18 +23,337 @@ type UserRepository interface {
 type CategoryRepository interface {
 	Create(ctx context.Context, category *model.Category) error
 	FindByID(ctx context.Context, id string) (*model.Category, error)
+	// FindByIDAndUser returns the category only if it is shared, owned by the user or
+	// belongs to the user's organization
+	FindByIDAndUser(ctx context.Context, id, userID string) (*model.Category, error)
 	FindAll(ctx context.Context) ([]*model.Category, error)
+	// FindByUserID lists the shared categories along with the user's own and their organization's
+	FindByUserID(ctx context.Context, userID string) ([]*model.Category, error)
+	// FindByOrgID lists the organization's team categories
+	FindByOrgID(ctx context.Context, orgID string) ([]*model.Category, error)
 	Update(ctx context.Context, category *model.Category) error
 	Delete(ctx context.Context, id string) error
 }
 
+// ErrEmailChanged is returned by EmailRepository.Update when the email was saved since it was
+// read; read it again and reapply the change
+var ErrEmailChanged = apierror.Conflict("The email was changed by someone else, try again", nil)
+
 // EmailRepository defines the interface for email data operations
 type EmailRepository interface {
+	// Create stores the email, or overwrites the user's copy of the same Gmail message, keeping
+	// its ID; each user stores their own copy of a message sent to several of them
 	Create(ctx context.Context, email *model.Email) error
+	// CreateBatch creates the emails as Create does, with as few round trips as the store allows;
+	// either all of them are stored or none
+	CreateBatch(ctx context.Context, emails []*model.Email) error
 	FindByID(ctx context.Context, id string) (*model.Email, error)
-	FindByUserID(ctx context.Context, userID string) ([]*model.Email, error)
-	FindByCategoryID(ctx context.Context, categoryID string) ([]*model.Email, error)
+	// FindByIDAndUser returns the email only if it belongs to the user
+	FindByIDAndUser(ctx context.Context, id, userID string) (*model.Email, error)
+	// FindByUserID and FindByCategoryID return emails newest first; limit <= 0 means no limit
+	FindByUserID(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	FindByCategoryID(ctx context.Context, categoryID string, limit int) ([]*model.Email, error)
+	FindByCategoryIDAndUser(ctx context.Context, categoryID, userID string, limit int) ([]*model.Email, error)
+	// FindMostRecentByUserID returns the user's newest email outside the trash, the first FindByUserID lists
+	FindMostRecentByUserID(ctx context.Context, userID string) (*model.Email, error)
+	// CountByUserID and CountByCategoryID count emails outside the trash
+	CountByUserID(ctx context.Context, userID string) (int, error)
+	CountByCategoryID(ctx context.Context, categoryID string) (int, error)
+	// FindByFilter lists a user's active emails matching the filter, newest first
+	FindByFilter(ctx context.Context, userID string, filter model.EmailFilter, limit int) ([]*model.Email, error)
+	// FindWindowByFilter is FindByFilter skipping the first offset emails, for lists read a
+	// window at a time
+	FindWindowByFilter(ctx context.Context, userID string, filter model.EmailFilter, offset, limit int) ([]*model.Email, error)
+	CountByFilter(ctx context.Context, userID string, filter model.EmailFilter) (int, error)
+	// CountByCategory tallies the user's emails outside the trash by category ID, "" for unclassified ones
+	CountByCategory(ctx context.Context, userID string) (map[string]model.EmailCounts, error)
+	// CountBySender tallies the user's emails outside the trash received since the time, by
+	// sender, the senders with the most emails first
+	CountBySender(ctx context.Context, userID string, since time.Time) ([]*model.SenderActivity, error)
+	// FindAdjacent returns the IDs listed just before and just after email within the filter, "" at either end
+	FindAdjacent(ctx context.Context, userID string, filter model.EmailFilter, email *model.Email) (previousID, nextID string, err error)
 	FindByGmailID(ctx context.Context, userID, gmailID string) (*model.Email, error)
+	// ExistsByGmailID reports whether the user stores the Gmail message, in the trash or not
+	ExistsByGmailID(ctx context.Context, userID, gmailID string) (bool, error)
+	// FindByGmailIDs returns the user's stored copies of the Gmail messages, trashed ones
+	// included, in one lookup; messages the user doesn't store are left out
+	FindByGmailIDs(ctx context.Context, userID string, gmailIDs []string) ([]*model.Email, error)
+	// FindByPriority lists the user's emails of the priority outside the trash, newest first
+	FindByPriority(ctx context.Context, userID, priority string, limit int) ([]*model.Email, error)
+	// FindNeedingReprocessing lists the user's emails whose AI output was rejected, outside the
+	// trash and with their body, newest first
+	FindNeedingReprocessing(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	// FindInReview lists the user's emails waiting in the review queue, outside the trash,
+	// newest first
+	FindInReview(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	// SetPriorityBySender sets the priority of the user's emails from the address and returns how many changed
+	SetPriorityBySender(ctx context.Context, userID, address, priority string) (int, error)
+	// MoveToUser gives every email of fromUserID, trashed ones included, to toUserID, filing
+	// those of a category in categories under the category it maps to, and returns how many
+	// moved. The copies of messages toUserID already stores are deleted instead.
+	MoveToUser(ctx context.Context, fromUserID, toUserID string, categories map[string]string) (int, error)
+	// FindSecurityFlagged lists the user's account-security emails outside the trash, newest first
+	FindSecurityFlagged(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	// FindBySender lists every stored email from the address, trashed ones included, oldest first
+	FindBySender(ctx context.Context, userID, address string) ([]*model.Email, error)
+	// FindChangedSince lists the user's emails, trashed ones included, whose ChangedAt and ID sort
+	// after since and afterID, in that order; limit <= 0 means no limit
+	FindChangedSince(ctx context.Context, userID string, since time.Time, afterID string, limit int) ([]*model.Email, error)
+	// Update saves everything but the triage and unsubscribe state, which only the Mark methods
+	// change, and moves the email to its next Version. It fails with ErrEmailChanged when the
+	// stored email is no longer at the version the email was read at.
 	Update(ctx context.Context, email *model.Email) error
+	// MarkTriaged records the user's triage decision on the email
+	MarkTriaged(ctx context.Context, id, action string, at time.Time) error
+	// MarkUnsubscribed records that unsubscribing through the email succeeded
+	MarkUnsubscribed(ctx context.Context, id string, at time.Time) error
+	// FindNextUntriaged returns the user's oldest email that needs triage, or a not found error
+	FindNextUntriaged(ctx context.Context, userID string) (*model.Email, error)
+	CountUntriaged(ctx context.Context, userID string) (int, error)
+	// Delete moves the email to the trash; trashed emails are excluded from listings
+	Delete(ctx context.Context, id string) error
+	FindDeletedByUserID(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	Restore(ctx context.Context, id string) error
+	// TrashOTPReceivedBefore moves emails holding a verification code received before the cutoff to the trash
+	TrashOTPReceivedBefore(ctx context.Context, before time.Time) (int, error)
+	// PurgeDeletedBefore permanently removes emails trashed before the cutoff
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int, error)
+	// Retention helpers; each returns the number of emails affected
+	PruneBodiesBefore(ctx context.Context, userID string, before time.Time) (int, error)
+	DeleteReceivedBefore(ctx context.Context, userID string, before time.Time) (int, error)
+	DeleteExcessPerCategory(ctx context.Context, userID string, keep int) (int, error)
+}
+
+// RetentionPolicyRepository stores per-user retention policies
+type RetentionPolicyRepository interface {
+	FindByUserID(ctx context.Context, userID string) (*model.RetentionPolicy, error)
+	FindAll(ctx context.Context) ([]*model.RetentionPolicy, error)
+	// Save creates or replaces the user's policy
+	Save(ctx context.Context, policy *model.RetentionPolicy) error
+}
+
+// NotificationPreferencesRepository stores per-user notification preferences
+type NotificationPreferencesRepository interface {
+	FindByUserID(ctx context.Context, userID string) (*model.NotificationPreferences, error)
+	// Save creates or replaces the user's preferences
+	Save(ctx context.Context, preferences *model.NotificationPreferences) error
+}
+
+// PushSubscriptionRepository stores the Web Push subscriptions of each user's browsers
+type PushSubscriptionRepository interface {
+	// Save stores the subscription, replacing any existing one for the same endpoint
+	Save(ctx context.Context, subscription *model.PushSubscription) error
+	FindByUserID(ctx context.Context, userID string) ([]*model.PushSubscription, error)
+	// DeleteByEndpoint removes the user's subscription for the endpoint, if any
+	DeleteByEndpoint(ctx context.Context, userID, endpoint string) error
+}
+
+// PendingEventRepository keeps the SSE events of users without a connection until they reconnect
+type PendingEventRepository interface {
+	// Enqueue stores the event, dropping the user's expired events and, past limit, their oldest ones
+	Enqueue(ctx context.Context, event *model.PendingEvent, limit int) error
+	// TakeByUserID removes the user's events and returns those not expired at now, oldest first
+	TakeByUserID(ctx context.Context, userID string, now time.Time) ([]*model.PendingEvent, error)
+}
+
+// GmailActionRepository queues the changes made while Gmail was unreachable until they are replayed
+type GmailActionRepository interface {
+	Create(ctx context.Context, action *model.GmailAction) error
+	// FindAll lists every user's queued actions, oldest first
+	FindAll(ctx context.Context) ([]*model.GmailAction, error)
+	CountByUserID(ctx context.Context, userID string) (int, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// TelegramLinkRepository stores which Telegram chat each user linked
+type TelegramLinkRepository interface {
+	// Save stores the link, replacing the user's previous chat and any other user linked to the same chat
+	Save(ctx context.Context, link *model.TelegramLink) error
+	FindByUserID(ctx context.Context, userID string) (*model.TelegramLink, error)
+	FindByChatID(ctx context.Context, chatID int64) (*model.TelegramLink, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+// WebhookRepository stores the outgoing webhook of each user
+type WebhookRepository interface {
+	// Save stores the webhook, replacing the user's previous one
+	Save(ctx context.Context, webhook *model.Webhook) error
+	FindByUserID(ctx context.Context, userID string) (*model.Webhook, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+// OrganizationRepository stores organizations and their memberships
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *model.Organization) error
+	FindByID(ctx context.Context, id string) (*model.Organization, error)
+	Update(ctx context.Context, org *model.Organization) error
+	// Delete removes the organization along with its memberships
+	Delete(ctx context.Context, id string) error
+	// SaveMember adds the membership or changes its role
+	SaveMember(ctx context.Context, membership *model.Membership) error
+	// FindMembership returns the user's membership, not found when they are in no organization
+	FindMembership(ctx context.Context, userID string) (*model.Membership, error)
+	// FindMembers lists the organization's members, oldest first
+	FindMembers(ctx context.Context, orgID string) ([]*model.Membership, error)
+	RemoveMember(ctx context.Context, orgID, userID string) error
+}
+
+// InvitationRepository stores invitations to join organizations
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *model.Invitation) error
+	FindByID(ctx context.Context, id string) (*model.Invitation, error)
+	// FindByOrgID and FindByEmail list invitations newest first
+	FindByOrgID(ctx context.Context, orgID string) ([]*model.Invitation, error)
+	FindByEmail(ctx context.Context, email string) ([]*model.Invitation, error)
+	Update(ctx context.Context, invitation *model.Invitation) error
+	Delete(ctx context.Context, id string) error
+}
+
+// BillingAccountRepository stores users' plans
+type BillingAccountRepository interface {
+	Save(ctx context.Context, account *model.BillingAccount) error
+	FindByUserID(ctx context.Context, userID string) (*model.BillingAccount, error)
+	FindByCustomerID(ctx context.Context, provider, customerID string) (*model.BillingAccount, error)
+}
+
+// UsageRepository counts quota usage per user, period and metric
+type UsageRepository interface {
+	Add(ctx context.Context, userID, period, metric string, delta int) error
+	// FindByPeriod returns the user's count for each metric used in the period
+	FindByPeriod(ctx context.Context, userID, period string) (map[string]int, error)
+}
+
+// EmailShareRepository stores the public share links of emails
+type EmailShareRepository interface {
+	Create(ctx context.Context, share *model.EmailShare) error
+	FindByID(ctx context.Context, id string) (*model.EmailShare, error)
+	// FindByEmail lists the user's share links for an email, newest first
+	FindByEmail(ctx context.Context, userID, emailID string) ([]*model.EmailShare, error)
+	Update(ctx context.Context, share *model.EmailShare) error
+}
+
+// AutomationRepository stores per-user category automations
+type AutomationRepository interface {
+	Create(ctx context.Context, automation *model.Automation) error
+	FindByID(ctx context.Context, id string) (*model.Automation, error)
+	// FindByIDAndUser returns the automation only if it belongs to the user
+	FindByIDAndUser(ctx context.Context, id, userID string) (*model.Automation, error)
+	// FindByUserID lists the automations the user created, oldest first
+	FindByUserID(ctx context.Context, userID string) ([]*model.Automation, error)
+	// FindByOrgID lists the organization's team automations, oldest first
+	FindByOrgID(ctx context.Context, orgID string) ([]*model.Automation, error)
+	FindAll(ctx context.Context) ([]*model.Automation, error)
+	Update(ctx context.Context, automation *model.Automation) error
+	Delete(ctx context.Context, id string) error
+}
+
+// CleanupPolicyRepository stores the users' scheduled cleanup policies
+type CleanupPolicyRepository interface {
+	Create(ctx context.Context, policy *model.CleanupPolicy) error
+	// FindByIDAndUser returns the policy only if it belongs to the user
+	FindByIDAndUser(ctx context.Context, id, userID string) (*model.CleanupPolicy, error)
+	// FindByUserID lists the user's policies, oldest first
+	FindByUserID(ctx context.Context, userID string) ([]*model.CleanupPolicy, error)
+	FindAll(ctx context.Context) ([]*model.CleanupPolicy, error)
+	Update(ctx context.Context, policy *model.CleanupPolicy) error
+	Delete(ctx context.Context, id string) error
+}
+
+// CleanupRunRepository keeps the history of cleanup policy runs
+type CleanupRunRepository interface {
+	Create(ctx context.Context, run *model.CleanupRun) error
+	// FindByPolicyID lists the policy's runs, newest first, at most limit of them when limit is positive
+	FindByPolicyID(ctx context.Context, policyID string, limit int) ([]*model.CleanupRun, error)
+	DeleteByPolicyID(ctx context.Context, policyID string) error
+}
+
+// AICallRepository keeps the AI prompts and answers recorded in debug mode
+type AICallRepository interface {
+	Create(ctx context.Context, call *model.AICall) error
+	FindByID(ctx context.Context, id string) (*model.AICall, error)
+	// FindRecent lists the calls newest first, at most limit of them when limit is positive
+	FindRecent(ctx context.Context, limit int) ([]*model.AICall, error)
+	// DeleteBefore removes the calls made before the time and returns how many were removed
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// ExperimentResultRepository keeps the classifications made during prompt and model experiments
+type ExperimentResultRepository interface {
+	// Save creates or replaces the result of the email in its experiment
+	Save(ctx context.Context, result *model.ExperimentResult) error
+	FindByEmailID(ctx context.Context, experiment, emailID string) (*model.ExperimentResult, error)
+	// CountByArm counts the experiment's classifications and corrections of each arm; only
+	// Name, Classified and Corrected are set
+	CountByArm(ctx context.Context, experiment string) ([]*model.ExperimentArmStats, error)
+}
+
+// SenderProfileRepository caches the AI-generated profile of each of a user's senders
+type SenderProfileRepository interface {
+	FindByAddress(ctx context.Context, userID, address string) (*model.SenderProfile, error)
+	// Save creates or replaces the profile of the user's sender
+	Save(ctx context.Context, profile *model.SenderProfile) error
+}
+
+// VIPSenderRepository stores the senders each user marked as VIP
+type VIPSenderRepository interface {
+	// Save adds the sender to the user's VIPs, or renames it
+	Save(ctx context.Context, vip *model.VIPSender) error
+	Delete(ctx context.Context, userID, address string) error
+	// FindByUserID lists the user's VIP senders by address
+	FindByUserID(ctx context.Context, userID string) ([]*model.VIPSender, error)
+}
+
+// ReportRepository stores the users' weekly inbox reports
+type ReportRepository interface {
+	// Save creates the report, or replaces the user's report for the same week
+	Save(ctx context.Context, report *model.InboxReport) error
+	// FindByIDAndUser returns the report only if it belongs to the user
+	FindByIDAndUser(ctx context.Context, id, userID string) (*model.InboxReport, error)
+	FindByPeriod(ctx context.Context, userID string, periodStart time.Time) (*model.InboxReport, error)
+	// FindByUserID lists the user's reports, latest week first; limit <= 0 means no limit
+	FindByUserID(ctx context.Context, userID string, limit int) ([]*model.InboxReport, error)
+}
+
+// UnsubscribeAttemptRepository stores the outcome of each unsubscribe, for the user to review
+type UnsubscribeAttemptRepository interface {
+	Create(ctx context.Context, attempt *model.UnsubscribeAttempt) error
+	// Update saves what happened after the unsubscribe: its manual completion, the resurfaced
+	// emails and the escalation
+	Update(ctx context.Context, attempt *model.UnsubscribeAttempt) error
+	// FindByIDAndUser returns the attempt only if it belongs to the user
+	FindByIDAndUser(ctx context.Context, id, userID string) (*model.UnsubscribeAttempt, error)
+	// FindLatestSucceeded returns the user's latest successful unsubscribe from the sender, or a not found error
+	FindLatestSucceeded(ctx context.Context, userID, senderAddress string) (*model.UnsubscribeAttempt, error)
+	// FindByUserID lists the user's attempts, newest first; limit <= 0 means no limit
+	FindByUserID(ctx context.Context, userID string, limit int) ([]*model.UnsubscribeAttempt, error)
+}
+
+// ShipmentRepository stores the packages found in users' shipping notifications
+type ShipmentRepository interface {
+	Create(ctx context.Context, shipment *model.Shipment) error
+	Update(ctx context.Context, shipment *model.Shipment) error
+	FindByTrackingNumber(ctx context.Context, userID, trackingNumber string) (*model.Shipment, error)
+	// FindByUserID lists the user's shipments, most recently updated first
+	FindByUserID(ctx context.Context, userID string) ([]*model.Shipment, error)
+	// FindUndelivered lists every user's shipments still on their way, for tracking
+	FindUndelivered(ctx context.Context) ([]*model.Shipment, error)
+}
+
+// AccountMergeCodeRepository keeps the account merge codes until they are redeemed
+type AccountMergeCodeRepository interface {
+	// Create stores the code, dropping the codes that expired before it was created
+	Create(ctx context.Context, code *model.AccountMergeCode, now time.Time) error
+	// Take removes the code and returns it, unless it had expired at now
+	Take(ctx context.Context, code string, now time.Time) (*model.AccountMergeCode, error)
+}
+
+// SavedViewRepository stores the users' saved email filters
+type SavedViewRepository interface {
+	Create(ctx context.Context, view *model.SavedView) error
+	// FindByIDAndUser returns the view only if it belongs to the user
+	FindByIDAndUser(ctx context.Context, id, userID string) (*model.SavedView, error)
+	// FindByUserID lists the user's views, oldest first
+	FindByUserID(ctx context.Context, userID string) ([]*model.SavedView, error)
+	Update(ctx context.Context, view *model.SavedView) error
 	Delete(ctx context.Context, id string) error
-}
\ No newline at end of file
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	"jump-challenge/internal/model"
+)
+
+// clone copies a value on its way in or out of a repository. Stored values are never shared
+// with callers, so a sync goroutine updating an email can't race a handler encoding it, and
+// changes only take effect through Update or Save, as with the postgres repositories.
+func clone[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+
+	copied := *value
+	// Copy what the struct copy would still share
+	switch v := any(&copied).(type) {
+	case *model.Email:
+		v.SentAt = cloneTime(v.SentAt)
+		v.DeletedAt = cloneTime(v.DeletedAt)
+		v.TriagedAt = cloneTime(v.TriagedAt)
+		v.UnsubscribedAt = cloneTime(v.UnsubscribedAt)
+		v.OTPExpiresAt = cloneTime(v.OTPExpiresAt)
+	case *model.NotificationPreferences:
+		v.EventTypes = slices.Clone(v.EventTypes)
+	case *model.Invitation:
+		v.AcceptedAt = cloneTime(v.AcceptedAt)
+	case *model.EmailShare:
+		v.RevokedAt = cloneTime(v.RevokedAt)
+	case *model.Shipment:
+		v.DeliveredAt = cloneTime(v.DeliveredAt)
+		v.CheckedAt = cloneTime(v.CheckedAt)
+	case *model.InboxReport:
+		v.Stats.Categories = slices.Clone(v.Stats.Categories)
+		v.Stats.TopSenders = slices.Clone(v.Stats.TopSenders)
+	case *model.UnsubscribeAttempt:
+		v.DarkPatterns = slices.Clone(v.DarkPatterns)
+		v.Selections = slices.Clone(v.Selections)
+		v.IneffectiveAt = cloneTime(v.IneffectiveAt)
+		v.ResurfacedEmailIDs = slices.Clone(v.ResurfacedEmailIDs)
+	case *model.PendingEvent:
+		v.Payload = slices.Clone(v.Payload)
+	case *model.SavedView:
+		v.Filter.After = cloneTime(v.Filter.After)
+		v.Filter.Before = cloneTime(v.Filter.Before)
+		if v.Filter.Unread != nil {
+			unread := *v.Filter.Unread
+			v.Filter.Unread = &unread
+		}
+	case *model.CleanupPolicy:
+		v.LastRunAt = cloneTime(v.LastRunAt)
+		if v.Unread != nil {
+			unread := *v.Unread
+			v.Unread = &unread
+		}
+	}
+	return &copied
+}
+
+// cloneAll clones every value of a result list
+func cloneAll[T any](values []*T) []*T {
+	if values == nil {
+		return nil
+	}
+
+	result := make([]*T, len(values))
+	for i, value := range values {
+		result[i] = clone(value)
+	}
+	return result
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
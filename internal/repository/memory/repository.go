@@ -2,11 +2,14 @@ package memory
 
 import (
 	"context"
-	"errors"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"jump-challenge/internal/apierror"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
 )
 
 type InMemoryUserRepository struct {
@@ -24,7 +27,21 @@ func (r *InMemoryUserRepository) Create(ctx context.Context, user *model.User) e
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
-	r.users[user.ID] = user
+	// Signing in again with the same Google account refreshes the existing user, as in postgres
+	for _, existing := range r.users {
+		if existing.GoogleID == user.GoogleID && existing.ID != user.ID {
+			existing.Email = user.Email
+			existing.Name = user.Name
+			existing.AccessToken = user.AccessToken
+			existing.RefreshToken = user.RefreshToken
+			existing.TokenExpiry = user.TokenExpiry
+			existing.GrantedScopes = user.GrantedScopes
+			existing.NeedsReauth = false
+			existing.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	r.users[user.ID] = clone(user)
 	return nil
 }
 
@@ -34,9 +51,9 @@ func (r *InMemoryUserRepository) FindByID(ctx context.Context, id string) (*mode
 	
 	user, exists := r.users[id]
 	if !exists {
-		return nil, errors.New("user not found")
+		return nil, apierror.NotFound("user not found")
 	}
-	return user, nil
+	return clone(user), nil
 }
 
 func (r *InMemoryUserRepository) FindByGoogleID(ctx context.Context, googleID string) (*model.User, error) {
@@ -45,10 +62,10 @@ func (r *InMemoryUserRepository) FindByGoogleID(ctx context.Context, googleID st
 	
 	for _, user := range r.users {
 		if user.GoogleID == googleID {
-			return user, nil
+			return clone(user), nil
 		}
 	}
-	return nil, errors.New("user not found")
+	return nil, apierror.NotFound("user not found")
 }
 
 func (r *InMemoryUserRepository) Update(ctx context.Context, user *model.User) error {
@@ -57,9 +74,9 @@ func (r *InMemoryUserRepository) Update(ctx context.Context, user *model.User) e
 	
 	_, exists := r.users[user.ID]
 	if !exists {
-		return errors.New("user not found")
+		return apierror.NotFound("user not found")
 	}
-	r.users[user.ID] = user
+	r.users[user.ID] = clone(user)
 	return nil
 }
 
@@ -69,10 +86,10 @@ func (r *InMemoryUserRepository) FindByEmail(ctx context.Context, email string)
 	
 	for _, user := range r.users {
 		if user.Email == email {
-			return user, nil
+			return clone(user), nil
 		}
 	}
-	return nil, errors.New("user not found")
+	return nil, apierror.NotFound("user not found")
 }
 
 func (r *InMemoryUserRepository) Delete(ctx context.Context, id string) error {
@@ -91,7 +108,7 @@ func (r *InMemoryUserRepository) FindAll(ctx context.Context) ([]*model.User, er
 	for _, user := range r.users {
 		users = append(users, user)
 	}
-	return users, nil
+	return cloneAll(users), nil
 }
 
 // GetAllUsers returns all users (needed for the Gmail client to find users by email)
@@ -103,13 +120,16 @@ func (r *InMemoryUserRepository) GetAllUsers() []*model.User {
 	for _, user := range r.users {
 		users = append(users, user)
 	}
-	return users
+	return cloneAll(users)
 }
 
 // Category repository implementation
 type InMemoryCategoryRepository struct {
 	categories map[string]*model.Category
 	mutex      sync.RWMutex
+
+	// Resolves users' organizations so team categories are visible to members; optional
+	orgs repository.OrganizationRepository
 }
 
 func NewInMemoryCategoryRepository() *InMemoryCategoryRepository {
@@ -122,7 +142,22 @@ func (r *InMemoryCategoryRepository) Create(ctx context.Context, category *model
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
-	r.categories[category.ID] = category
+	if err := r.checkNameUnique(category); err != nil {
+		return err
+	}
+	r.categories[category.ID] = clone(category)
+	return nil
+}
+
+// checkNameUnique enforces what the postgres unique index does: an owner's category names
+// differ regardless of case. Callers hold the lock.
+func (r *InMemoryCategoryRepository) checkNameUnique(category *model.Category) error {
+	for _, existing := range r.categories {
+		if existing.ID != category.ID && existing.UserID == category.UserID && existing.OrgID == category.OrgID &&
+			strings.EqualFold(existing.Name, category.Name) {
+			return apierror.Conflict("category name already exists", clone(existing))
+		}
+	}
 	return nil
 }
 
@@ -132,9 +167,38 @@ func (r *InMemoryCategoryRepository) FindByID(ctx context.Context, id string) (*
 	
 	category, exists := r.categories[id]
 	if !exists {
-		return nil, errors.New("category not found")
+		return nil, apierror.NotFound("category not found")
+	}
+	return clone(category), nil
+}
+
+// UseOrganizations makes team categories visible to the members of their organization
+func (r *InMemoryCategoryRepository) UseOrganizations(orgs repository.OrganizationRepository) {
+	r.orgs = orgs
+}
+
+// orgOf returns the user's organization ID, "" when they are in none
+func (r *InMemoryCategoryRepository) orgOf(ctx context.Context, userID string) string {
+	if r.orgs == nil {
+		return ""
+	}
+	if membership, err := r.orgs.FindMembership(ctx, userID); err == nil {
+		return membership.OrgID
+	}
+	return ""
+}
+
+func (r *InMemoryCategoryRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.Category, error) {
+	orgID := r.orgOf(ctx, userID)
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	category, exists := r.categories[id]
+	if !exists || !category.VisibleTo(userID, orgID) {
+		return nil, apierror.NotFound("category not found")
 	}
-	return category, nil
+	return clone(category), nil
 }
 
 func (r *InMemoryCategoryRepository) FindAll(ctx context.Context) ([]*model.Category, error) {
@@ -145,7 +209,38 @@ func (r *InMemoryCategoryRepository) FindAll(ctx context.Context) ([]*model.Cate
 	for _, category := range r.categories {
 		result = append(result, category)
 	}
-	return result, nil
+	sortCategories(result)
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryCategoryRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Category, error) {
+	orgID := r.orgOf(ctx, userID)
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Category
+	for _, category := range r.categories {
+		if category.VisibleTo(userID, orgID) {
+			result = append(result, category)
+		}
+	}
+	sortCategories(result)
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryCategoryRepository) FindByOrgID(ctx context.Context, orgID string) ([]*model.Category, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Category
+	for _, category := range r.categories {
+		if category.OrgID == orgID {
+			result = append(result, category)
+		}
+	}
+	sortCategories(result)
+	return cloneAll(result), nil
 }
 
 func (r *InMemoryCategoryRepository) Update(ctx context.Context, category *model.Category) error {
@@ -154,9 +249,12 @@ func (r *InMemoryCategoryRepository) Update(ctx context.Context, category *model
 	
 	_, exists := r.categories[category.ID]
 	if !exists {
-		return errors.New("category not found")
+		return apierror.NotFound("category not found")
+	}
+	if err := r.checkNameUnique(category); err != nil {
+		return err
 	}
-	r.categories[category.ID] = category
+	r.categories[category.ID] = clone(category)
 	return nil
 }
 
@@ -168,6 +266,16 @@ func (r *InMemoryCategoryRepository) Delete(ctx context.Context, id string) erro
 	return nil
 }
 
+// sortCategories orders categories oldest first, matching the postgres repository
+func sortCategories(categories []*model.Category) {
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].CreatedAt.Equal(categories[j].CreatedAt) {
+			return categories[i].ID < categories[j].ID
+		}
+		return categories[i].CreatedAt.Before(categories[j].CreatedAt)
+	})
+}
+
 // Email repository implementation
 type InMemoryEmailRepository struct {
 	emails map[string]*model.Email
@@ -184,87 +292,2105 @@ func (r *InMemoryEmailRepository) Create(ctx context.Context, email *model.Email
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
-	r.emails[email.ID] = email
+	r.create(email)
 	return nil
 }
 
+func (r *InMemoryEmailRepository) CreateBatch(ctx context.Context, emails []*model.Email) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, email := range emails {
+		r.create(email)
+	}
+	return nil
+}
+
+// create stores the email; the mutex must be held
+func (r *InMemoryEmailRepository) create(email *model.Email) {
+	// A Gmail message is stored once per user; creating it again overwrites the user's copy, as in postgres
+	for id, existing := range r.emails {
+		if existing.UserID == email.UserID && existing.GmailID == email.GmailID && id != email.ID {
+			updated := clone(email)
+			updated.ID = existing.ID
+			updated.CreatedAt = existing.CreatedAt
+			updated.UpdatedAt = time.Now()
+			updated.Version = existing.Version + 1
+			keepMarks(updated, existing)
+			r.emails[id] = updated
+			return
+		}
+	}
+	r.emails[email.ID] = clone(email)
+}
+
 func (r *InMemoryEmailRepository) FindByID(ctx context.Context, id string) (*model.Email, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	
 	email, exists := r.emails[id]
 	if !exists {
-		return nil, errors.New("email not found")
+		return nil, apierror.NotFound("email not found")
+	}
+	return clone(email), nil
+}
+
+func (r *InMemoryEmailRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	email, exists := r.emails[id]
+	if !exists || email.UserID != userID {
+		return nil, apierror.NotFound("email not found")
 	}
-	return email, nil
+	return clone(email), nil
 }
 
-func (r *InMemoryEmailRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Email, error) {
+func (r *InMemoryEmailRepository) FindByUserID(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	
 	var result []*model.Email
 	for _, email := range r.emails {
-		if email.UserID == userID {
+		if email.UserID == userID && email.DeletedAt == nil {
 			result = append(result, email)
 		}
 	}
 	
-	// Sort emails by received_at in descending order (most recent first)
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].ReceivedAt.After(result[j].ReceivedAt)
-	})
-	
-	return result, nil
+	return cloneAll(sortAndLimit(result, limit)), nil
 }
 
-func (r *InMemoryEmailRepository) FindByCategoryID(ctx context.Context, categoryID string) ([]*model.Email, error) {
+func (r *InMemoryEmailRepository) FindByCategoryID(ctx context.Context, categoryID string, limit int) ([]*model.Email, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	
 	var result []*model.Email
 	for _, email := range r.emails {
-		if email.CategoryID == categoryID {
+		if email.CategoryID == categoryID && email.DeletedAt == nil {
 			result = append(result, email)
 		}
 	}
 	
-	// Sort emails by received_at in descending order (most recent first)
+	return cloneAll(sortAndLimit(result, limit)), nil
+}
+
+func (r *InMemoryEmailRepository) FindByCategoryIDAndUser(ctx context.Context, categoryID, userID string, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.CategoryID == categoryID && email.UserID == userID && email.DeletedAt == nil {
+			result = append(result, email)
+		}
+	}
+
+	return cloneAll(sortAndLimit(result, limit)), nil
+}
+
+func (r *InMemoryEmailRepository) FindMostRecentByUserID(ctx context.Context, userID string) (*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var latest *model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.DeletedAt == nil && (latest == nil || sortsBefore(email, latest)) {
+			latest = email
+		}
+	}
+	if latest == nil {
+		return nil, apierror.NotFound("email not found")
+	}
+	return clone(latest), nil
+}
+
+func (r *InMemoryEmailRepository) CountByUserID(ctx context.Context, userID string) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, email := range r.emails {
+		if email.UserID == userID && email.DeletedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryEmailRepository) CountByCategoryID(ctx context.Context, categoryID string) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, email := range r.emails {
+		if email.CategoryID == categoryID && email.DeletedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryEmailRepository) FindByFilter(ctx context.Context, userID string, filter model.EmailFilter, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && filter.Matches(email) {
+			result = append(result, email)
+		}
+	}
+
+	return cloneAll(sortAndLimit(result, limit)), nil
+}
+
+func (r *InMemoryEmailRepository) FindWindowByFilter(ctx context.Context, userID string, filter model.EmailFilter, offset, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && filter.Matches(email) {
+			result = append(result, email)
+		}
+	}
+
+	result = sortAndLimit(result, 0)
+	if offset > len(result) {
+		offset = len(result)
+	}
+	if offset > 0 {
+		result = result[offset:]
+	}
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryEmailRepository) CountByFilter(ctx context.Context, userID string, filter model.EmailFilter) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, email := range r.emails {
+		if email.UserID == userID && filter.Matches(email) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryEmailRepository) CountByCategory(ctx context.Context, userID string) (map[string]model.EmailCounts, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	counts := make(map[string]model.EmailCounts)
+	for _, email := range r.emails {
+		if email.UserID != userID || email.DeletedAt != nil {
+			continue
+		}
+		count := counts[email.CategoryID]
+		count.Total++
+		if email.Unread {
+			count.Unread++
+		}
+		counts[email.CategoryID] = count
+	}
+	return counts, nil
+}
+
+func (r *InMemoryEmailRepository) CountBySender(ctx context.Context, userID string, since time.Time) ([]*model.SenderActivity, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	bySender := make(map[string]*model.SenderActivity)
+	for _, email := range r.emails {
+		if email.UserID != userID || email.DeletedAt != nil || email.ReceivedAt.Before(since) {
+			continue
+		}
+		activity := bySender[email.FromAddress]
+		if activity == nil {
+			activity = &model.SenderActivity{Address: email.FromAddress}
+			bySender[email.FromAddress] = activity
+		}
+		activity.Total++
+		if email.Unread {
+			activity.Unread++
+		}
+		if email.UnsubscribedAt != nil {
+			activity.Unsubscribed++
+		}
+		if email.ReceivedAt.After(activity.LastReceivedAt) {
+			activity.LastReceivedAt = email.ReceivedAt
+		}
+	}
+
+	result := make([]*model.SenderActivity, 0, len(bySender))
+	for _, activity := range bySender {
+		result = append(result, activity)
+	}
 	sort.Slice(result, func(i, j int) bool {
-		return result[i].ReceivedAt.After(result[j].ReceivedAt)
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Address < result[j].Address
 	})
-	
 	return result, nil
 }
 
+func (r *InMemoryEmailRepository) FindAdjacent(ctx context.Context, userID string, filter model.EmailFilter, email *model.Email) (string, string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var previous, next *model.Email
+	for _, candidate := range r.emails {
+		if candidate.ID == email.ID || candidate.UserID != userID || !filter.Matches(candidate) {
+			continue
+		}
+		if sortsBefore(candidate, email) {
+			if previous == nil || sortsBefore(previous, candidate) {
+				previous = candidate
+			}
+		} else if next == nil || sortsBefore(candidate, next) {
+			next = candidate
+		}
+	}
+
+	var previousID, nextID string
+	if previous != nil {
+		previousID = previous.ID
+	}
+	if next != nil {
+		nextID = next.ID
+	}
+	return previousID, nextID, nil
+}
+
 func (r *InMemoryEmailRepository) FindByGmailID(ctx context.Context, userID, gmailID string) (*model.Email, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	
 	for _, email := range r.emails {
 		if email.UserID == userID && email.GmailID == gmailID {
-			return email, nil
+			return clone(email), nil
+		}
+	}
+	return nil, apierror.NotFound("email not found")
+}
+
+func (r *InMemoryEmailRepository) ExistsByGmailID(ctx context.Context, userID, gmailID string) (bool, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, email := range r.emails {
+		if email.UserID == userID && email.GmailID == gmailID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *InMemoryEmailRepository) FindByGmailIDs(ctx context.Context, userID string, gmailIDs []string) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	wanted := make(map[string]bool, len(gmailIDs))
+	for _, gmailID := range gmailIDs {
+		wanted[gmailID] = true
+	}
+	var emails []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && wanted[email.GmailID] {
+			emails = append(emails, clone(email))
 		}
 	}
-	return nil, errors.New("email not found")
+	return emails, nil
 }
 
 func (r *InMemoryEmailRepository) Update(ctx context.Context, email *model.Email) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
-	_, exists := r.emails[email.ID]
+	existing, exists := r.emails[email.ID]
+	if !exists {
+		return apierror.NotFound("email not found")
+	}
+	if existing.Version != email.Version {
+		return repository.ErrEmailChanged
+	}
+	updated := clone(email)
+	updated.UpdatedAt = time.Now()
+	updated.Version++
+	keepMarks(updated, existing)
+	r.emails[email.ID] = updated
+	email.Version = updated.Version
+	return nil
+}
+
+// keepMarks carries the state only the Mark methods change over to an updated email, as
+// the postgres repository leaves those columns out of its updates
+func keepMarks(updated, existing *model.Email) {
+	updated.TriagedAt, updated.TriageAction = existing.TriagedAt, existing.TriageAction
+	updated.UnsubscribedAt = existing.UnsubscribedAt
+}
+
+func (r *InMemoryEmailRepository) FindBySender(ctx context.Context, userID, address string) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	address = strings.ToLower(address)
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.FromAddress == address {
+			result = append(result, email)
+		}
+	}
+	// Oldest first, the reverse of the listing order
+	sort.Slice(result, func(i, j int) bool {
+		return sortsBefore(result[j], result[i])
+	})
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryEmailRepository) MarkTriaged(ctx context.Context, id, action string, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	email, exists := r.emails[id]
+	if !exists {
+		return apierror.NotFound("email not found")
+	}
+	email.TriagedAt = &at
+	email.TriageAction = action
+	email.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *InMemoryEmailRepository) MarkUnsubscribed(ctx context.Context, id string, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	email, exists := r.emails[id]
 	if !exists {
-		return errors.New("email not found")
+		return apierror.NotFound("email not found")
 	}
-	r.emails[email.ID] = email
+	email.UnsubscribedAt = &at
+	email.UpdatedAt = time.Now()
 	return nil
 }
 
+func (r *InMemoryEmailRepository) FindNextUntriaged(ctx context.Context, userID string) (*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var oldest *model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.NeedsTriage() && (oldest == nil || sortsBefore(oldest, email)) {
+			oldest = email
+		}
+	}
+	if oldest == nil {
+		return nil, apierror.NotFound("no email to triage")
+	}
+	return clone(oldest), nil
+}
+
+func (r *InMemoryEmailRepository) CountUntriaged(ctx context.Context, userID string) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, email := range r.emails {
+		if email.UserID == userID && email.NeedsTriage() {
+			count++
+		}
+	}
+	return count, nil
+}
+
 func (r *InMemoryEmailRepository) Delete(ctx context.Context, id string) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
-	delete(r.emails, id)
+	if email, exists := r.emails[id]; exists && email.DeletedAt == nil {
+		now := time.Now()
+		email.DeletedAt = &now
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+func (r *InMemoryEmailRepository) FindDeletedByUserID(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.DeletedAt != nil {
+			result = append(result, email)
+		}
+	}
+
+	// Most recently trashed first
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DeletedAt.After(*result[j].DeletedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryEmailRepository) Restore(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	email, exists := r.emails[id]
+	if !exists {
+		return apierror.NotFound("email not found")
+	}
+	email.DeletedAt = nil
+	email.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *InMemoryEmailRepository) FindByPriority(ctx context.Context, userID, priority string, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.Priority == priority && email.DeletedAt == nil {
+			result = append(result, email)
+		}
+	}
+	return cloneAll(sortAndLimit(result, limit)), nil
+}
+
+func (r *InMemoryEmailRepository) FindNeedingReprocessing(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.NeedsReprocessing && !email.BodyPruned && email.DeletedAt == nil {
+			result = append(result, email)
+		}
+	}
+	return cloneAll(sortAndLimit(result, limit)), nil
+}
+
+func (r *InMemoryEmailRepository) FindInReview(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.InReview() && email.DeletedAt == nil {
+			result = append(result, email)
+		}
+	}
+	return cloneAll(sortAndLimit(result, limit)), nil
+}
+
+func (r *InMemoryEmailRepository) SetPriorityBySender(ctx context.Context, userID, address, priority string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	changed := 0
+	for _, email := range r.emails {
+		if email.UserID == userID && email.FromAddress == address && email.Priority != priority {
+			email.Priority = priority
+			email.UpdatedAt = time.Now()
+			email.Version++
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (r *InMemoryEmailRepository) MoveToUser(ctx context.Context, fromUserID, toUserID string, categories map[string]string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kept := make(map[string]bool)
+	for _, email := range r.emails {
+		if email.UserID == toUserID {
+			kept[email.GmailID] = true
+		}
+	}
+	moved := 0
+	for id, email := range r.emails {
+		if email.UserID != fromUserID {
+			continue
+		}
+		if kept[email.GmailID] {
+			delete(r.emails, id)
+			continue
+		}
+		if to, ok := categories[email.CategoryID]; ok {
+			email.CategoryID = to
+		}
+		if to, ok := categories[email.ReviewCategoryID]; ok {
+			email.ReviewCategoryID = to
+		}
+		email.UserID = toUserID
+		email.UpdatedAt = time.Now()
+		email.Version++
+		moved++
+	}
+	return moved, nil
+}
+
+func (r *InMemoryEmailRepository) FindChangedSince(ctx context.Context, userID string, since time.Time, afterID string, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		changed := email.ChangedAt()
+		if email.UserID == userID && (changed.After(since) || (changed.Equal(since) && email.ID > afterID)) {
+			result = append(result, email)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].ChangedAt(), result[j].ChangedAt()
+		if a.Equal(b) {
+			return result[i].ID < result[j].ID
+		}
+		return a.Before(b)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryEmailRepository) FindSecurityFlagged(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Email
+	for _, email := range r.emails {
+		if email.UserID == userID && email.SecurityFlag != "" && email.DeletedAt == nil {
+			result = append(result, email)
+		}
+	}
+	return cloneAll(sortAndLimit(result, limit)), nil
+}
+
+func (r *InMemoryEmailRepository) TrashOTPReceivedBefore(ctx context.Context, before time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	trashed := 0
+	for _, email := range r.emails {
+		if email.OTPCode != "" && email.DeletedAt == nil && email.ReceivedAt.Before(before) {
+			email.DeletedAt = &now
+			trashed++
+		}
+	}
+	return trashed, nil
+}
+
+func (r *InMemoryEmailRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	purged := 0
+	for id, email := range r.emails {
+		if email.DeletedAt != nil && email.DeletedAt.Before(before) {
+			delete(r.emails, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *InMemoryEmailRepository) PruneBodiesBefore(ctx context.Context, userID string, before time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	pruned := 0
+	for _, email := range r.emails {
+		if email.UserID == userID && email.ReceivedAt.Before(before) && !email.BodyPruned {
+			email.Body = ""
+			email.BodyPruned = true
+			email.UpdatedAt = time.Now()
+			email.Version++
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (r *InMemoryEmailRepository) DeleteReceivedBefore(ctx context.Context, userID string, before time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deleted := 0
+	for id, email := range r.emails {
+		if email.UserID == userID && email.ReceivedAt.Before(before) {
+			delete(r.emails, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *InMemoryEmailRepository) DeleteExcessPerCategory(ctx context.Context, userID string, keep int) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	byCategory := make(map[string][]*model.Email)
+	for _, email := range r.emails {
+		if email.UserID == userID && email.DeletedAt == nil {
+			byCategory[email.CategoryID] = append(byCategory[email.CategoryID], email)
+		}
+	}
+
+	deleted := 0
+	for _, emails := range byCategory {
+		emails = sortAndLimit(emails, 0)
+		if len(emails) <= keep {
+			continue
+		}
+		for _, email := range emails[keep:] {
+			delete(r.emails, email.ID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// sortAndLimit orders emails by received_at descending (ID as tiebreaker, matching the
+// Postgres ORDER BY) and caps the result at limit when limit is positive
+func sortAndLimit(emails []*model.Email, limit int) []*model.Email {
+	sort.Slice(emails, func(i, j int) bool {
+		return sortsBefore(emails[i], emails[j])
+	})
+
+	if limit > 0 && len(emails) > limit {
+		emails = emails[:limit]
+	}
+	return emails
+}
+
+// sortsBefore reports whether a is listed before b: newer first, then by ID
+func sortsBefore(a, b *model.Email) bool {
+	if a.ReceivedAt.Equal(b.ReceivedAt) {
+		return a.ID < b.ID
+	}
+	return a.ReceivedAt.After(b.ReceivedAt)
+}
+
+type InMemoryRetentionPolicyRepository struct {
+	policies map[string]*model.RetentionPolicy
+	mutex    sync.RWMutex
+}
+
+func NewInMemoryRetentionPolicyRepository() *InMemoryRetentionPolicyRepository {
+	return &InMemoryRetentionPolicyRepository{
+		policies: make(map[string]*model.RetentionPolicy),
+	}
+}
+
+func (r *InMemoryRetentionPolicyRepository) FindByUserID(ctx context.Context, userID string) (*model.RetentionPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	policy, exists := r.policies[userID]
+	if !exists {
+		return nil, apierror.NotFound("retention policy not found")
+	}
+	return clone(policy), nil
+}
+
+func (r *InMemoryRetentionPolicyRepository) FindAll(ctx context.Context) ([]*model.RetentionPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var policies []*model.RetentionPolicy
+	for _, policy := range r.policies {
+		policies = append(policies, policy)
+	}
+	return cloneAll(policies), nil
+}
+
+func (r *InMemoryRetentionPolicyRepository) Save(ctx context.Context, policy *model.RetentionPolicy) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.policies[policy.UserID] = clone(policy)
+	return nil
+}
+
+type InMemoryNotificationPreferencesRepository struct {
+	preferences map[string]*model.NotificationPreferences
+	mutex       sync.RWMutex
+}
+
+func NewInMemoryNotificationPreferencesRepository() *InMemoryNotificationPreferencesRepository {
+	return &InMemoryNotificationPreferencesRepository{
+		preferences: make(map[string]*model.NotificationPreferences),
+	}
+}
+
+func (r *InMemoryNotificationPreferencesRepository) FindByUserID(ctx context.Context, userID string) (*model.NotificationPreferences, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	preferences, exists := r.preferences[userID]
+	if !exists {
+		return nil, apierror.NotFound("notification preferences not found")
+	}
+	return clone(preferences), nil
+}
+
+func (r *InMemoryNotificationPreferencesRepository) Save(ctx context.Context, preferences *model.NotificationPreferences) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.preferences[preferences.UserID] = clone(preferences)
+	return nil
+}
+
+type InMemoryPushSubscriptionRepository struct {
+	subscriptions map[string]*model.PushSubscription // endpoint -> subscription
+	mutex         sync.RWMutex
+}
+
+func NewInMemoryPushSubscriptionRepository() *InMemoryPushSubscriptionRepository {
+	return &InMemoryPushSubscriptionRepository{
+		subscriptions: make(map[string]*model.PushSubscription),
+	}
+}
+
+func (r *InMemoryPushSubscriptionRepository) Save(ctx context.Context, subscription *model.PushSubscription) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.subscriptions[subscription.Endpoint] = clone(subscription)
+	return nil
+}
+
+func (r *InMemoryPushSubscriptionRepository) FindByUserID(ctx context.Context, userID string) ([]*model.PushSubscription, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.PushSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.UserID == userID {
+			result = append(result, subscription)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryPushSubscriptionRepository) DeleteByEndpoint(ctx context.Context, userID, endpoint string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if subscription, exists := r.subscriptions[endpoint]; exists && subscription.UserID == userID {
+		delete(r.subscriptions, endpoint)
+	}
+	return nil
+}
+
+type InMemoryPendingEventRepository struct {
+	events map[string][]*model.PendingEvent // user ID -> events, oldest first
+	mutex  sync.Mutex
+}
+
+func NewInMemoryPendingEventRepository() *InMemoryPendingEventRepository {
+	return &InMemoryPendingEventRepository{
+		events: make(map[string][]*model.PendingEvent),
+	}
+}
+
+func (r *InMemoryPendingEventRepository) Enqueue(ctx context.Context, event *model.PendingEvent, limit int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var kept []*model.PendingEvent
+	for _, queued := range r.events[event.UserID] {
+		if !queued.Expired(event.CreatedAt) {
+			kept = append(kept, queued)
+		}
+	}
+	kept = append(kept, clone(event))
+	if limit > 0 && len(kept) > limit {
+		kept = kept[len(kept)-limit:]
+	}
+	r.events[event.UserID] = kept
+	return nil
+}
+
+func (r *InMemoryPendingEventRepository) TakeByUserID(ctx context.Context, userID string, now time.Time) ([]*model.PendingEvent, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var result []*model.PendingEvent
+	for _, event := range r.events[userID] {
+		if !event.Expired(now) {
+			result = append(result, event)
+		}
+	}
+	delete(r.events, userID)
+	return result, nil
+}
+
+type InMemoryAccountMergeCodeRepository struct {
+	codes map[string]*model.AccountMergeCode
+	mutex sync.Mutex
+}
+
+func NewInMemoryAccountMergeCodeRepository() *InMemoryAccountMergeCodeRepository {
+	return &InMemoryAccountMergeCodeRepository{
+		codes: make(map[string]*model.AccountMergeCode),
+	}
+}
+
+func (r *InMemoryAccountMergeCodeRepository) Create(ctx context.Context, code *model.AccountMergeCode, now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for existing, stored := range r.codes {
+		if !now.Before(stored.ExpiresAt) {
+			delete(r.codes, existing)
+		}
+	}
+	r.codes[code.Code] = clone(code)
+	return nil
+}
+
+func (r *InMemoryAccountMergeCodeRepository) Take(ctx context.Context, code string, now time.Time) (*model.AccountMergeCode, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, exists := r.codes[code]
+	delete(r.codes, code)
+	if !exists || !now.Before(stored.ExpiresAt) {
+		return nil, apierror.NotFound("merge code not found")
+	}
+	return stored, nil
+}
+
+type InMemoryGmailActionRepository struct {
+	actions []*model.GmailAction
+	mutex   sync.RWMutex
+}
+
+func NewInMemoryGmailActionRepository() *InMemoryGmailActionRepository {
+	return &InMemoryGmailActionRepository{}
+}
+
+func (r *InMemoryGmailActionRepository) Create(ctx context.Context, action *model.GmailAction) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.actions = append(r.actions, clone(action))
+	return nil
+}
+
+func (r *InMemoryGmailActionRepository) FindAll(ctx context.Context) ([]*model.GmailAction, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := cloneAll(r.actions)
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (r *InMemoryGmailActionRepository) CountByUserID(ctx context.Context, userID string) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, action := range r.actions {
+		if action.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *InMemoryGmailActionRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, action := range r.actions {
+		if action.ID == id {
+			r.actions = append(r.actions[:i], r.actions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+type InMemoryTelegramLinkRepository struct {
+	links map[string]*model.TelegramLink // user ID -> link
+	mutex sync.RWMutex
+}
+
+func NewInMemoryTelegramLinkRepository() *InMemoryTelegramLinkRepository {
+	return &InMemoryTelegramLinkRepository{
+		links: make(map[string]*model.TelegramLink),
+	}
+}
+
+func (r *InMemoryTelegramLinkRepository) Save(ctx context.Context, link *model.TelegramLink) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for userID, existing := range r.links {
+		if existing.ChatID == link.ChatID && userID != link.UserID {
+			delete(r.links, userID)
+		}
+	}
+	r.links[link.UserID] = clone(link)
+	return nil
+}
+
+func (r *InMemoryTelegramLinkRepository) FindByUserID(ctx context.Context, userID string) (*model.TelegramLink, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	link, exists := r.links[userID]
+	if !exists {
+		return nil, apierror.NotFound("telegram link not found")
+	}
+	return clone(link), nil
+}
+
+func (r *InMemoryTelegramLinkRepository) FindByChatID(ctx context.Context, chatID int64) (*model.TelegramLink, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, link := range r.links {
+		if link.ChatID == chatID {
+			return clone(link), nil
+		}
+	}
+	return nil, apierror.NotFound("telegram link not found")
+}
+
+func (r *InMemoryTelegramLinkRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.links, userID)
+	return nil
+}
+
+type InMemoryWebhookRepository struct {
+	webhooks map[string]*model.Webhook // user ID -> webhook
+	mutex    sync.RWMutex
+}
+
+func NewInMemoryWebhookRepository() *InMemoryWebhookRepository {
+	return &InMemoryWebhookRepository{
+		webhooks: make(map[string]*model.Webhook),
+	}
+}
+
+func (r *InMemoryWebhookRepository) Save(ctx context.Context, webhook *model.Webhook) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.webhooks[webhook.UserID] = clone(webhook)
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) FindByUserID(ctx context.Context, userID string) (*model.Webhook, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	webhook, exists := r.webhooks[userID]
+	if !exists {
+		return nil, apierror.NotFound("webhook not found")
+	}
+	return clone(webhook), nil
+}
+
+func (r *InMemoryWebhookRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.webhooks, userID)
+	return nil
+}
+
+type InMemoryOrganizationRepository struct {
+	orgs    map[string]*model.Organization
+	members map[string]*model.Membership // user ID -> membership
+	mutex   sync.RWMutex
+}
+
+func NewInMemoryOrganizationRepository() *InMemoryOrganizationRepository {
+	return &InMemoryOrganizationRepository{
+		orgs:    make(map[string]*model.Organization),
+		members: make(map[string]*model.Membership),
+	}
+}
+
+func (r *InMemoryOrganizationRepository) Create(ctx context.Context, org *model.Organization) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.orgs[org.ID] = clone(org)
+	return nil
+}
+
+func (r *InMemoryOrganizationRepository) FindByID(ctx context.Context, id string) (*model.Organization, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	org, exists := r.orgs[id]
+	if !exists {
+		return nil, apierror.NotFound("organization not found")
+	}
+	return clone(org), nil
+}
+
+func (r *InMemoryOrganizationRepository) Update(ctx context.Context, org *model.Organization) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.orgs[org.ID]; !exists {
+		return apierror.NotFound("organization not found")
+	}
+	r.orgs[org.ID] = clone(org)
+	return nil
+}
+
+func (r *InMemoryOrganizationRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.orgs, id)
+	for userID, membership := range r.members {
+		if membership.OrgID == id {
+			delete(r.members, userID)
+		}
+	}
+	return nil
+}
+
+func (r *InMemoryOrganizationRepository) SaveMember(ctx context.Context, membership *model.Membership) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.members[membership.UserID] = clone(membership)
+	return nil
+}
+
+func (r *InMemoryOrganizationRepository) FindMembership(ctx context.Context, userID string) (*model.Membership, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	membership, exists := r.members[userID]
+	if !exists {
+		return nil, apierror.NotFound("membership not found")
+	}
+	return clone(membership), nil
+}
+
+func (r *InMemoryOrganizationRepository) FindMembers(ctx context.Context, orgID string) ([]*model.Membership, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Membership
+	for _, membership := range r.members {
+		if membership.OrgID == orgID {
+			result = append(result, membership)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].UserID < result[j].UserID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if membership, exists := r.members[userID]; exists && membership.OrgID == orgID {
+		delete(r.members, userID)
+	}
+	return nil
+}
+
+type InMemoryInvitationRepository struct {
+	invitations map[string]*model.Invitation
+	mutex       sync.RWMutex
+}
+
+func NewInMemoryInvitationRepository() *InMemoryInvitationRepository {
+	return &InMemoryInvitationRepository{
+		invitations: make(map[string]*model.Invitation),
+	}
+}
+
+func (r *InMemoryInvitationRepository) Create(ctx context.Context, invitation *model.Invitation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.invitations[invitation.ID] = clone(invitation)
+	return nil
+}
+
+func (r *InMemoryInvitationRepository) FindByID(ctx context.Context, id string) (*model.Invitation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	invitation, exists := r.invitations[id]
+	if !exists {
+		return nil, apierror.NotFound("invitation not found")
+	}
+	return clone(invitation), nil
+}
+
+func (r *InMemoryInvitationRepository) FindByOrgID(ctx context.Context, orgID string) ([]*model.Invitation, error) {
+	return r.findWhere(func(invitation *model.Invitation) bool { return invitation.OrgID == orgID }), nil
+}
+
+func (r *InMemoryInvitationRepository) FindByEmail(ctx context.Context, email string) ([]*model.Invitation, error) {
+	return r.findWhere(func(invitation *model.Invitation) bool { return strings.EqualFold(invitation.Email, email) }), nil
+}
+
+func (r *InMemoryInvitationRepository) findWhere(match func(*model.Invitation) bool) []*model.Invitation {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Invitation
+	for _, invitation := range r.invitations {
+		if match(invitation) {
+			result = append(result, invitation)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return cloneAll(result)
+}
+
+func (r *InMemoryInvitationRepository) Update(ctx context.Context, invitation *model.Invitation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.invitations[invitation.ID]; !exists {
+		return apierror.NotFound("invitation not found")
+	}
+	r.invitations[invitation.ID] = clone(invitation)
+	return nil
+}
+
+func (r *InMemoryInvitationRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.invitations, id)
+	return nil
+}
+
+type InMemoryBillingAccountRepository struct {
+	accounts map[string]*model.BillingAccount
+	mutex    sync.RWMutex
+}
+
+func NewInMemoryBillingAccountRepository() *InMemoryBillingAccountRepository {
+	return &InMemoryBillingAccountRepository{
+		accounts: make(map[string]*model.BillingAccount),
+	}
+}
+
+func (r *InMemoryBillingAccountRepository) Save(ctx context.Context, account *model.BillingAccount) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.accounts[account.UserID] = clone(account)
+	return nil
+}
+
+func (r *InMemoryBillingAccountRepository) FindByUserID(ctx context.Context, userID string) (*model.BillingAccount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	account, exists := r.accounts[userID]
+	if !exists {
+		return nil, apierror.NotFound("billing account not found")
+	}
+	return clone(account), nil
+}
+
+func (r *InMemoryBillingAccountRepository) FindByCustomerID(ctx context.Context, provider, customerID string) (*model.BillingAccount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, account := range r.accounts {
+		if account.Provider == provider && account.CustomerID == customerID {
+			return clone(account), nil
+		}
+	}
+	return nil, apierror.NotFound("billing account not found")
+}
+
+type InMemoryUsageRepository struct {
+	counts map[string]map[string]int // user ID + "/" + period -> metric -> count
+	mutex  sync.RWMutex
+}
+
+func NewInMemoryUsageRepository() *InMemoryUsageRepository {
+	return &InMemoryUsageRepository{
+		counts: make(map[string]map[string]int),
+	}
+}
+
+func (r *InMemoryUsageRepository) Add(ctx context.Context, userID, period, metric string, delta int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := userID + "/" + period
+	if r.counts[key] == nil {
+		r.counts[key] = make(map[string]int)
+	}
+	r.counts[key][metric] += delta
+	return nil
+}
+
+func (r *InMemoryUsageRepository) FindByPeriod(ctx context.Context, userID, period string) (map[string]int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(map[string]int)
+	for metric, count := range r.counts[userID+"/"+period] {
+		result[metric] = count
+	}
+	return result, nil
+}
+
+type InMemoryEmailShareRepository struct {
+	shares map[string]*model.EmailShare
+	mutex  sync.RWMutex
+}
+
+func NewInMemoryEmailShareRepository() *InMemoryEmailShareRepository {
+	return &InMemoryEmailShareRepository{
+		shares: make(map[string]*model.EmailShare),
+	}
+}
+
+func (r *InMemoryEmailShareRepository) Create(ctx context.Context, share *model.EmailShare) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.shares[share.ID] = clone(share)
+	return nil
+}
+
+func (r *InMemoryEmailShareRepository) FindByID(ctx context.Context, id string) (*model.EmailShare, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	share, exists := r.shares[id]
+	if !exists {
+		return nil, apierror.NotFound("share not found")
+	}
+	return clone(share), nil
+}
+
+func (r *InMemoryEmailShareRepository) FindByEmail(ctx context.Context, userID, emailID string) ([]*model.EmailShare, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.EmailShare
+	for _, share := range r.shares {
+		if share.UserID == userID && share.EmailID == emailID {
+			result = append(result, share)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryEmailShareRepository) Update(ctx context.Context, share *model.EmailShare) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.shares[share.ID]; !exists {
+		return apierror.NotFound("share not found")
+	}
+	r.shares[share.ID] = clone(share)
+	return nil
+}
+
+type InMemoryAutomationRepository struct {
+	automations map[string]*model.Automation
+	mutex       sync.RWMutex
+}
+
+func NewInMemoryAutomationRepository() *InMemoryAutomationRepository {
+	return &InMemoryAutomationRepository{
+		automations: make(map[string]*model.Automation),
+	}
+}
+
+func (r *InMemoryAutomationRepository) Create(ctx context.Context, automation *model.Automation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.automations[automation.ID] = clone(automation)
+	return nil
+}
+
+func (r *InMemoryAutomationRepository) FindByID(ctx context.Context, id string) (*model.Automation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	automation, exists := r.automations[id]
+	if !exists {
+		return nil, apierror.NotFound("automation not found")
+	}
+	return clone(automation), nil
+}
+
+func (r *InMemoryAutomationRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.Automation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	automation, exists := r.automations[id]
+	if !exists || automation.UserID != userID {
+		return nil, apierror.NotFound("automation not found")
+	}
+	return clone(automation), nil
+}
+
+func (r *InMemoryAutomationRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Automation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Automation
+	for _, automation := range r.automations {
+		if automation.UserID == userID {
+			result = append(result, automation)
+		}
+	}
+	sortAutomations(result)
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryAutomationRepository) FindByOrgID(ctx context.Context, orgID string) ([]*model.Automation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Automation
+	for _, automation := range r.automations {
+		if automation.OrgID == orgID {
+			result = append(result, automation)
+		}
+	}
+	sortAutomations(result)
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryAutomationRepository) FindAll(ctx context.Context) ([]*model.Automation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Automation
+	for _, automation := range r.automations {
+		result = append(result, automation)
+	}
+	sortAutomations(result)
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryAutomationRepository) Update(ctx context.Context, automation *model.Automation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.automations[automation.ID]; !exists {
+		return apierror.NotFound("automation not found")
+	}
+	r.automations[automation.ID] = clone(automation)
+	return nil
+}
+
+func (r *InMemoryAutomationRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.automations, id)
+	return nil
+}
+
+// sortAutomations orders automations oldest first, matching the postgres repository
+func sortAutomations(automations []*model.Automation) {
+	sort.Slice(automations, func(i, j int) bool {
+		if automations[i].CreatedAt.Equal(automations[j].CreatedAt) {
+			return automations[i].ID < automations[j].ID
+		}
+		return automations[i].CreatedAt.Before(automations[j].CreatedAt)
+	})
+}
+
+type InMemoryCleanupPolicyRepository struct {
+	policies map[string]*model.CleanupPolicy
+	mutex    sync.RWMutex
+}
+
+func NewInMemoryCleanupPolicyRepository() *InMemoryCleanupPolicyRepository {
+	return &InMemoryCleanupPolicyRepository{
+		policies: make(map[string]*model.CleanupPolicy),
+	}
+}
+
+func (r *InMemoryCleanupPolicyRepository) Create(ctx context.Context, policy *model.CleanupPolicy) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.policies[policy.ID] = clone(policy)
+	return nil
+}
+
+func (r *InMemoryCleanupPolicyRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.CleanupPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	policy, exists := r.policies[id]
+	if !exists || policy.UserID != userID {
+		return nil, apierror.NotFound("cleanup policy not found")
+	}
+	return clone(policy), nil
+}
+
+func (r *InMemoryCleanupPolicyRepository) FindByUserID(ctx context.Context, userID string) ([]*model.CleanupPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.CleanupPolicy
+	for _, policy := range r.policies {
+		if policy.UserID == userID {
+			result = append(result, policy)
+		}
+	}
+	sortCleanupPolicies(result)
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryCleanupPolicyRepository) FindAll(ctx context.Context) ([]*model.CleanupPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.CleanupPolicy
+	for _, policy := range r.policies {
+		result = append(result, policy)
+	}
+	sortCleanupPolicies(result)
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryCleanupPolicyRepository) Update(ctx context.Context, policy *model.CleanupPolicy) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.policies[policy.ID]; !exists {
+		return apierror.NotFound("cleanup policy not found")
+	}
+	r.policies[policy.ID] = clone(policy)
+	return nil
+}
+
+func (r *InMemoryCleanupPolicyRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.policies, id)
+	return nil
+}
+
+// sortCleanupPolicies orders policies oldest first, matching the postgres repository
+func sortCleanupPolicies(policies []*model.CleanupPolicy) {
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].CreatedAt.Equal(policies[j].CreatedAt) {
+			return policies[i].ID < policies[j].ID
+		}
+		return policies[i].CreatedAt.Before(policies[j].CreatedAt)
+	})
+}
+
+type InMemoryCleanupRunRepository struct {
+	runs  []*model.CleanupRun
+	mutex sync.RWMutex
+}
+
+func NewInMemoryCleanupRunRepository() *InMemoryCleanupRunRepository {
+	return &InMemoryCleanupRunRepository{}
+}
+
+func (r *InMemoryCleanupRunRepository) Create(ctx context.Context, run *model.CleanupRun) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.runs = append(r.runs, clone(run))
+	return nil
+}
+
+func (r *InMemoryCleanupRunRepository) FindByPolicyID(ctx context.Context, policyID string, limit int) ([]*model.CleanupRun, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.CleanupRun
+	for _, run := range r.runs {
+		if run.PolicyID == policyID {
+			result = append(result, run)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].RanAt.Equal(result[j].RanAt) {
+			return result[i].ID > result[j].ID
+		}
+		return result[i].RanAt.After(result[j].RanAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryCleanupRunRepository) DeleteByPolicyID(ctx context.Context, policyID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kept := r.runs[:0]
+	for _, run := range r.runs {
+		if run.PolicyID != policyID {
+			kept = append(kept, run)
+		}
+	}
+	r.runs = kept
+	return nil
+}
+
+type InMemoryAICallRepository struct {
+	calls []*model.AICall
+	mutex sync.RWMutex
+}
+
+func NewInMemoryAICallRepository() *InMemoryAICallRepository {
+	return &InMemoryAICallRepository{}
+}
+
+func (r *InMemoryAICallRepository) Create(ctx context.Context, call *model.AICall) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.calls = append(r.calls, clone(call))
+	return nil
+}
+
+func (r *InMemoryAICallRepository) FindByID(ctx context.Context, id string) (*model.AICall, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, call := range r.calls {
+		if call.ID == id {
+			return clone(call), nil
+		}
+	}
+	return nil, apierror.NotFound("AI call not found")
+}
+
+func (r *InMemoryAICallRepository) FindRecent(ctx context.Context, limit int) ([]*model.AICall, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]*model.AICall, len(r.calls))
+	copy(result, r.calls)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID > result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryAICallRepository) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	kept := r.calls[:0]
+	for _, call := range r.calls {
+		if !call.CreatedAt.Before(before) {
+			kept = append(kept, call)
+		}
+	}
+	deleted := len(r.calls) - len(kept)
+	r.calls = kept
+	return deleted, nil
+}
+
+type InMemoryExperimentResultRepository struct {
+	results map[string]*model.ExperimentResult // by experiment and email ID
+	mutex   sync.RWMutex
+}
+
+func NewInMemoryExperimentResultRepository() *InMemoryExperimentResultRepository {
+	return &InMemoryExperimentResultRepository{
+		results: make(map[string]*model.ExperimentResult),
+	}
+}
+
+func experimentResultKey(experiment, emailID string) string {
+	return experiment + "|" + emailID
+}
+
+func (r *InMemoryExperimentResultRepository) Save(ctx context.Context, result *model.ExperimentResult) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.results[experimentResultKey(result.Experiment, result.EmailID)] = clone(result)
+	return nil
+}
+
+func (r *InMemoryExperimentResultRepository) FindByEmailID(ctx context.Context, experiment, emailID string) (*model.ExperimentResult, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result, exists := r.results[experimentResultKey(experiment, emailID)]
+	if !exists {
+		return nil, apierror.NotFound("experiment result not found")
+	}
+	return clone(result), nil
+}
+
+func (r *InMemoryExperimentResultRepository) CountByArm(ctx context.Context, experiment string) ([]*model.ExperimentArmStats, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	byArm := make(map[string]*model.ExperimentArmStats)
+	var stats []*model.ExperimentArmStats
+	for _, result := range r.results {
+		if result.Experiment != experiment {
+			continue
+		}
+		arm, exists := byArm[result.Arm]
+		if !exists {
+			arm = &model.ExperimentArmStats{ExperimentArm: model.ExperimentArm{Name: result.Arm}}
+			byArm[result.Arm] = arm
+			stats = append(stats, arm)
+		}
+		arm.Classified++
+		if result.CorrectedCategoryID != "" {
+			arm.Corrected++
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats, nil
+}
+
+type InMemorySavedViewRepository struct {
+	views map[string]*model.SavedView
+	mutex sync.RWMutex
+}
+
+func NewInMemorySavedViewRepository() *InMemorySavedViewRepository {
+	return &InMemorySavedViewRepository{
+		views: make(map[string]*model.SavedView),
+	}
+}
+
+func (r *InMemorySavedViewRepository) Create(ctx context.Context, view *model.SavedView) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.views[view.ID] = clone(view)
+	return nil
+}
+
+func (r *InMemorySavedViewRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.SavedView, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	view, exists := r.views[id]
+	if !exists || view.UserID != userID {
+		return nil, apierror.NotFound("view not found")
+	}
+	return clone(view), nil
+}
+
+func (r *InMemorySavedViewRepository) FindByUserID(ctx context.Context, userID string) ([]*model.SavedView, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.SavedView
+	for _, view := range r.views {
+		if view.UserID == userID {
+			result = append(result, view)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return cloneAll(result), nil
+}
+
+func (r *InMemorySavedViewRepository) Update(ctx context.Context, view *model.SavedView) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.views[view.ID]; !exists {
+		return apierror.NotFound("view not found")
+	}
+	r.views[view.ID] = clone(view)
+	return nil
+}
+
+func (r *InMemorySavedViewRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.views, id)
+	return nil
+}
+
+type InMemorySenderProfileRepository struct {
+	profiles map[senderKey]*model.SenderProfile
+	mutex    sync.RWMutex
+}
+
+type senderKey struct {
+	userID  string
+	address string
+}
+
+func NewInMemorySenderProfileRepository() *InMemorySenderProfileRepository {
+	return &InMemorySenderProfileRepository{
+		profiles: make(map[senderKey]*model.SenderProfile),
+	}
+}
+
+func (r *InMemorySenderProfileRepository) FindByAddress(ctx context.Context, userID, address string) (*model.SenderProfile, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	profile, exists := r.profiles[senderKey{userID, address}]
+	if !exists {
+		return nil, apierror.NotFound("sender profile not found")
+	}
+	return clone(profile), nil
+}
+
+func (r *InMemorySenderProfileRepository) Save(ctx context.Context, profile *model.SenderProfile) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.profiles[senderKey{profile.UserID, profile.Address}] = clone(profile)
+	return nil
+}
+
+type InMemoryVIPSenderRepository struct {
+	vips  map[senderKey]*model.VIPSender
+	mutex sync.RWMutex
+}
+
+func NewInMemoryVIPSenderRepository() *InMemoryVIPSenderRepository {
+	return &InMemoryVIPSenderRepository{
+		vips: make(map[senderKey]*model.VIPSender),
+	}
+}
+
+func (r *InMemoryVIPSenderRepository) Save(ctx context.Context, vip *model.VIPSender) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := senderKey{vip.UserID, vip.Address}
+	if existing, exists := r.vips[key]; exists {
+		existing.Name = vip.Name
+		vip.CreatedAt = existing.CreatedAt
+		return nil
+	}
+	r.vips[key] = clone(vip)
+	return nil
+}
+
+func (r *InMemoryVIPSenderRepository) Delete(ctx context.Context, userID, address string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := senderKey{userID, address}
+	if _, exists := r.vips[key]; !exists {
+		return apierror.NotFound("VIP sender not found")
+	}
+	delete(r.vips, key)
+	return nil
+}
+
+func (r *InMemoryVIPSenderRepository) FindByUserID(ctx context.Context, userID string) ([]*model.VIPSender, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.VIPSender
+	for key, vip := range r.vips {
+		if key.userID == userID {
+			result = append(result, vip)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+	return cloneAll(result), nil
+}
+
+type InMemoryShipmentRepository struct {
+	shipments map[string]*model.Shipment
+	mutex     sync.RWMutex
+}
+
+func NewInMemoryShipmentRepository() *InMemoryShipmentRepository {
+	return &InMemoryShipmentRepository{
+		shipments: make(map[string]*model.Shipment),
+	}
+}
+
+func (r *InMemoryShipmentRepository) Create(ctx context.Context, shipment *model.Shipment) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.shipments[shipment.ID] = clone(shipment)
+	return nil
+}
+
+func (r *InMemoryShipmentRepository) Update(ctx context.Context, shipment *model.Shipment) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.shipments[shipment.ID]; !exists {
+		return apierror.NotFound("shipment not found")
+	}
+	r.shipments[shipment.ID] = clone(shipment)
+	return nil
+}
+
+func (r *InMemoryShipmentRepository) FindByTrackingNumber(ctx context.Context, userID, trackingNumber string) (*model.Shipment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, shipment := range r.shipments {
+		if shipment.UserID == userID && shipment.TrackingNumber == trackingNumber {
+			return clone(shipment), nil
+		}
+	}
+	return nil, apierror.NotFound("shipment not found")
+}
+
+func (r *InMemoryShipmentRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Shipment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Shipment
+	for _, shipment := range r.shipments {
+		if shipment.UserID == userID {
+			result = append(result, shipment)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UpdatedAt.Equal(result[j].UpdatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].UpdatedAt.After(result[j].UpdatedAt)
+	})
+	return cloneAll(result), nil
+}
+
+func (r *InMemoryShipmentRepository) FindUndelivered(ctx context.Context) ([]*model.Shipment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.Shipment
+	for _, shipment := range r.shipments {
+		if !shipment.IsDelivered() {
+			result = append(result, shipment)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return cloneAll(result), nil
+}
+
+type InMemoryReportRepository struct {
+	reports map[string]*model.InboxReport
+	mutex   sync.RWMutex
+}
+
+func NewInMemoryReportRepository() *InMemoryReportRepository {
+	return &InMemoryReportRepository{
+		reports: make(map[string]*model.InboxReport),
+	}
+}
+
+func (r *InMemoryReportRepository) Save(ctx context.Context, report *model.InboxReport) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// A regenerated week keeps the ID it was first stored under
+	for id, existing := range r.reports {
+		if existing.UserID == report.UserID && existing.PeriodStart.Equal(report.PeriodStart) {
+			report.ID = id
+			break
+		}
+	}
+	r.reports[report.ID] = clone(report)
+	return nil
+}
+
+func (r *InMemoryReportRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.InboxReport, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	report, exists := r.reports[id]
+	if !exists || report.UserID != userID {
+		return nil, apierror.NotFound("report not found")
+	}
+	return clone(report), nil
+}
+
+func (r *InMemoryReportRepository) FindByPeriod(ctx context.Context, userID string, periodStart time.Time) (*model.InboxReport, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, report := range r.reports {
+		if report.UserID == userID && report.PeriodStart.Equal(periodStart) {
+			return clone(report), nil
+		}
+	}
+	return nil, apierror.NotFound("report not found")
+}
+
+func (r *InMemoryReportRepository) FindByUserID(ctx context.Context, userID string, limit int) ([]*model.InboxReport, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.InboxReport
+	for _, report := range r.reports {
+		if report.UserID == userID {
+			result = append(result, report)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PeriodStart.After(result[j].PeriodStart)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return cloneAll(result), nil
+}
+
+type InMemoryUnsubscribeAttemptRepository struct {
+	attempts map[string]*model.UnsubscribeAttempt
+	mutex    sync.RWMutex
+}
+
+func NewInMemoryUnsubscribeAttemptRepository() *InMemoryUnsubscribeAttemptRepository {
+	return &InMemoryUnsubscribeAttemptRepository{
+		attempts: make(map[string]*model.UnsubscribeAttempt),
+	}
+}
+
+func (r *InMemoryUnsubscribeAttemptRepository) Create(ctx context.Context, attempt *model.UnsubscribeAttempt) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.attempts[attempt.ID] = clone(attempt)
+	return nil
+}
+
+func (r *InMemoryUnsubscribeAttemptRepository) Update(ctx context.Context, attempt *model.UnsubscribeAttempt) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.attempts[attempt.ID]; !exists {
+		return apierror.NotFound("unsubscribe attempt not found")
+	}
+	r.attempts[attempt.ID] = clone(attempt)
+	return nil
+}
+
+func (r *InMemoryUnsubscribeAttemptRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.UnsubscribeAttempt, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	attempt, exists := r.attempts[id]
+	if !exists || attempt.UserID != userID {
+		return nil, apierror.NotFound("unsubscribe attempt not found")
+	}
+	return clone(attempt), nil
+}
+
+func (r *InMemoryUnsubscribeAttemptRepository) FindLatestSucceeded(ctx context.Context, userID, senderAddress string) (*model.UnsubscribeAttempt, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var latest *model.UnsubscribeAttempt
+	for _, attempt := range r.attempts {
+		if attempt.UserID != userID || attempt.SenderAddress != senderAddress || !attempt.Succeeded {
+			continue
+		}
+		if latest == nil || attempt.CreatedAt.After(latest.CreatedAt) ||
+			(attempt.CreatedAt.Equal(latest.CreatedAt) && attempt.ID < latest.ID) {
+			latest = attempt
+		}
+	}
+	if latest == nil {
+		return nil, apierror.NotFound("unsubscribe attempt not found")
+	}
+	return clone(latest), nil
+}
+
+func (r *InMemoryUnsubscribeAttemptRepository) FindByUserID(ctx context.Context, userID string, limit int) ([]*model.UnsubscribeAttempt, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*model.UnsubscribeAttempt
+	for _, attempt := range r.attempts {
+		if attempt.UserID == userID {
+			result = append(result, attempt)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return cloneAll(result), nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+// replicaRetryInterval is how long reads stay on the primary after the replica failed one
+const replicaRetryInterval = 30 * time.Second
+
+// readRouter runs the reads whose context allows it, see repository.ReplicaOK, on a read-only
+// replica, and the others on the primary, as it does when there is no replica or it can't be
+// reached
+type readRouter struct {
+	primary *sql.DB
+	replica *sql.DB // nil without a replica
+
+	mutex     sync.Mutex
+	downUntil time.Time // reads go to the primary until then
+}
+
+func newReadRouter(primary, replica *sql.DB) *readRouter {
+	return &readRouter{primary: primary, replica: replica}
+}
+
+// db returns the replica when the context allows it, unless it is missing or recently failed
+func (r *readRouter) db(ctx context.Context) *sql.DB {
+	if r.replica == nil || !repository.AllowsReplica(ctx) {
+		return r.primary
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if time.Now().Before(r.downUntil) {
+		return r.primary
+	}
+	return r.replica
+}
+
+func (r *readRouter) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	db := r.db(ctx)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err == nil || db == r.primary || ctx.Err() != nil || !unavailable(err) {
+		return rows, err
+	}
+
+	r.mutex.Lock()
+	r.downUntil = time.Now().Add(replicaRetryInterval)
+	r.mutex.Unlock()
+	return r.primary.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext is QueryContext for a single row. Unlike *sql.DB's, a replica that can't be
+// reached is noticed before the row is scanned, so the primary can answer instead.
+func (r *readRouter) QueryRowContext(ctx context.Context, query string, args ...interface{}) rowScanner {
+	rows, err := r.QueryContext(ctx, query, args...)
+	return &firstRow{rows: rows, err: err}
+}
+
+// unavailable tells a database that couldn't run the query from one that rejected it, e.g. for
+// a syntax error the primary would reject as well
+func unavailable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return true
+	}
+	// connection exceptions, and the server shutting down or still starting
+	class := pqErr.Code.Class()
+	return class == "08" || class == "57"
+}
+
+// firstRow scans the first row of a query, like *sql.Row
+type firstRow struct {
+	rows *sql.Rows
+	err  error
+}
+
+func (f *firstRow) Scan(dest ...interface{}) error {
+	if f.err != nil {
+		return f.err
+	}
+	defer f.rows.Close()
+	if !f.rows.Next() {
+		if err := f.rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := f.rows.Scan(dest...); err != nil {
+		return err
+	}
+	return f.rows.Close()
+}
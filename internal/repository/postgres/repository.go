@@ -3,12 +3,17 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"jump-challenge/internal/apierror"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresUserRepository struct {
@@ -19,91 +24,86 @@ func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
 	return &PostgresUserRepository{db: db}
 }
 
+// userColumns lists the users table columns in the order scanUser expects them
+const userColumns = `id, google_id, email, name, access_token, refresh_token, token_expiry, granted_scopes, blocked_senders, archive_system_emails, time_zone, locale, privacy_mode, onboarding_step, needs_reauth, workspace_mailbox, created_at, updated_at`
+
+func scanUser(row rowScanner) (*model.User, error) {
+	user := &model.User{}
+	err := row.Scan(
+		&user.ID, &user.GoogleID, &user.Email, &user.Name,
+		&user.AccessToken, &user.RefreshToken, &user.TokenExpiry, &user.GrantedScopes, &user.BlockedSenders, &user.ArchiveSystemEmails, &user.TimeZone, &user.Locale, &user.PrivacyMode, &user.OnboardingStep, &user.NeedsReauth, &user.WorkspaceMailbox,
+		&user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *PostgresUserRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 func (r *PostgresUserRepository) Create(ctx context.Context, user *model.User) error {
 	query := `
-		INSERT INTO users (id, google_id, email, name, access_token, refresh_token, token_expiry, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO users (` + userColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		ON CONFLICT (google_id) DO UPDATE SET
 			email = EXCLUDED.email,
 			name = EXCLUDED.name,
 			access_token = EXCLUDED.access_token,
 			refresh_token = EXCLUDED.refresh_token,
 			token_expiry = EXCLUDED.token_expiry,
+			granted_scopes = EXCLUDED.granted_scopes,
+			needs_reauth = FALSE,
 			updated_at = NOW()`
 	_, err := r.db.ExecContext(ctx, query,
 		user.ID, user.GoogleID, user.Email, user.Name,
-		user.AccessToken, user.RefreshToken, user.TokenExpiry,
+		user.AccessToken, user.RefreshToken, user.TokenExpiry, user.GrantedScopes, user.BlockedSenders, user.ArchiveSystemEmails, user.TimeZone, user.Locale, user.PrivacyMode, user.OnboardingStep, user.NeedsReauth, user.WorkspaceMailbox,
 		user.CreatedAt, user.UpdatedAt)
 	return err
 }
 
 func (r *PostgresUserRepository) FindByID(ctx context.Context, id string) (*model.User, error) {
-	query := `SELECT id, google_id, email, name, access_token, refresh_token, token_expiry, created_at, updated_at FROM users WHERE id = $1`
-	row := r.db.QueryRowContext(ctx, query, id)
-
-	user := &model.User{}
-	err := row.Scan(
-		&user.ID, &user.GoogleID, &user.Email, &user.Name,
-		&user.AccessToken, &user.RefreshToken, &user.TokenExpiry,
-		&user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-	return user, nil
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return r.findOne(ctx, query, id)
 }
 
 func (r *PostgresUserRepository) FindByGoogleID(ctx context.Context, googleID string) (*model.User, error) {
-	query := `SELECT id, google_id, email, name, access_token, refresh_token, token_expiry, created_at, updated_at FROM users WHERE google_id = $1`
-	row := r.db.QueryRowContext(ctx, query, googleID)
-
-	user := &model.User{}
-	err := row.Scan(
-		&user.ID, &user.GoogleID, &user.Email, &user.Name,
-		&user.AccessToken, &user.RefreshToken, &user.TokenExpiry,
-		&user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-	return user, nil
+	query := `SELECT ` + userColumns + ` FROM users WHERE google_id = $1`
+	return r.findOne(ctx, query, googleID)
 }
 
 func (r *PostgresUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, google_id, email, name, access_token, refresh_token, token_expiry, created_at, updated_at FROM users WHERE email = $1`
-	row := r.db.QueryRowContext(ctx, query, email)
-
-	user := &model.User{}
-	err := row.Scan(
-		&user.ID, &user.GoogleID, &user.Email, &user.Name,
-		&user.AccessToken, &user.RefreshToken, &user.TokenExpiry,
-		&user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-	return user, nil
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	return r.findOne(ctx, query, email)
 }
 
 func (r *PostgresUserRepository) Update(ctx context.Context, user *model.User) error {
 	query := `
-		UPDATE users SET google_id=$1, email=$2, name=$3, access_token=$4, 
-		refresh_token=$5, token_expiry=$6, updated_at=NOW() WHERE id=$7`
-	_, err := r.db.ExecContext(ctx, query,
+		UPDATE users SET google_id=$1, email=$2, name=$3, access_token=$4,
+		refresh_token=$5, token_expiry=$6, granted_scopes=$7, blocked_senders=$8, archive_system_emails=$9, time_zone=$10, locale=$11, privacy_mode=$12, onboarding_step=$13, needs_reauth=$14, workspace_mailbox=$15, updated_at=NOW() WHERE id=$16`
+	result, err := r.db.ExecContext(ctx, query,
 		user.GoogleID, user.Email, user.Name,
-		user.AccessToken, user.RefreshToken, user.TokenExpiry,
+		user.AccessToken, user.RefreshToken, user.TokenExpiry, user.GrantedScopes, user.BlockedSenders, user.ArchiveSystemEmails, user.TimeZone, user.Locale, user.PrivacyMode, user.OnboardingStep, user.NeedsReauth, user.WorkspaceMailbox,
 		user.ID)
-	return err
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("user not found")
+	}
+	return nil
 }
 
 func (r *PostgresUserRepository) FindAll(ctx context.Context) ([]*model.User, error) {
-	query := `SELECT id, google_id, email, name, access_token, refresh_token, token_expiry, created_at, updated_at FROM users`
+	query := `SELECT ` + userColumns + ` FROM users`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
@@ -112,11 +112,7 @@ func (r *PostgresUserRepository) FindAll(ctx context.Context) ([]*model.User, er
 
 	var users []*model.User
 	for rows.Next() {
-		user := &model.User{}
-		err := rows.Scan(
-			&user.ID, &user.GoogleID, &user.Email, &user.Name,
-			&user.AccessToken, &user.RefreshToken, &user.TokenExpiry,
-			&user.CreatedAt, &user.UpdatedAt)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -132,6 +128,12 @@ func (r *PostgresUserRepository) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+// isUniqueViolation reports whether err is postgres rejecting a duplicate key
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 // Postgres Category repository implementation
 type PostgresCategoryRepository struct {
 	db *sql.DB
@@ -141,40 +143,34 @@ func NewPostgresCategoryRepository(db *sql.DB) *PostgresCategoryRepository {
 	return &PostgresCategoryRepository{db: db}
 }
 
-func (r *PostgresCategoryRepository) Create(ctx context.Context, category *model.Category) error {
-	query := `
-		INSERT INTO categories (id, name, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (id) DO UPDATE SET
-			name = EXCLUDED.name,
-			description = EXCLUDED.description,
-			updated_at = NOW()`
-	_, err := r.db.ExecContext(ctx, query,
-		category.ID, category.Name, category.Description,
-		category.CreatedAt, category.UpdatedAt)
-	return err
-}
+// categoryColumns lists the categories table columns in the order scanCategory expects them
+const categoryColumns = `id, user_id, org_id, name, description, created_at, updated_at, hints, summarize, sensitive`
 
-func (r *PostgresCategoryRepository) FindByID(ctx context.Context, id string) (*model.Category, error) {
-	query := `SELECT id, name, description, created_at, updated_at FROM categories WHERE id = $1`
-	row := r.db.QueryRowContext(ctx, query, id)
+// visibleCategories matches the categories the user ($1) may see, mirroring Category.VisibleTo
+const visibleCategories = `(CASE WHEN org_id <> '' THEN org_id IN (SELECT org_id FROM organization_members WHERE user_id = $1)
+	ELSE user_id = '' OR user_id = $1 END)`
 
+func scanCategory(row rowScanner) (*model.Category, error) {
 	category := &model.Category{}
 	err := row.Scan(
-		&category.ID, &category.Name, &category.Description,
-		&category.CreatedAt, &category.UpdatedAt)
+		&category.ID, &category.UserID, &category.OrgID, &category.Name, &category.Description,
+		&category.CreatedAt, &category.UpdatedAt, &category.Hints, &category.Summarize, &category.Sensitive)
+	return category, err
+}
+
+func (r *PostgresCategoryRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.Category, error) {
+	category, err := scanCategory(r.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("category not found")
+			return nil, apierror.NotFound("category not found")
 		}
 		return nil, err
 	}
 	return category, nil
 }
 
-func (r *PostgresCategoryRepository) FindAll(ctx context.Context) ([]*model.Category, error) {
-	query := `SELECT id, name, description, created_at, updated_at FROM categories`
-	rows, err := r.db.QueryContext(ctx, query)
+func (r *PostgresCategoryRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*model.Category, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -182,10 +178,7 @@ func (r *PostgresCategoryRepository) FindAll(ctx context.Context) ([]*model.Cate
 
 	var categories []*model.Category
 	for rows.Next() {
-		category := &model.Category{}
-		err := rows.Scan(
-			&category.ID, &category.Name, &category.Description,
-			&category.CreatedAt, &category.UpdatedAt)
+		category, err := scanCategory(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -195,14 +188,68 @@ func (r *PostgresCategoryRepository) FindAll(ctx context.Context) ([]*model.Cate
 	return categories, rows.Err()
 }
 
-func (r *PostgresCategoryRepository) Update(ctx context.Context, category *model.Category) error {
+func (r *PostgresCategoryRepository) Create(ctx context.Context, category *model.Category) error {
 	query := `
-		UPDATE categories SET name=$1, description=$2, updated_at=NOW() WHERE id=$3`
+		INSERT INTO categories (` + categoryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			hints = EXCLUDED.hints,
+			summarize = EXCLUDED.summarize,
+			sensitive = EXCLUDED.sensitive,
+			updated_at = NOW()`
 	_, err := r.db.ExecContext(ctx, query,
-		category.Name, category.Description, category.ID)
+		category.ID, category.UserID, category.OrgID, category.Name, category.Description,
+		category.CreatedAt, category.UpdatedAt, category.Hints, category.Summarize, category.Sensitive)
+	if isUniqueViolation(err) {
+		return apierror.Conflict("category name already exists", nil)
+	}
 	return err
 }
 
+func (r *PostgresCategoryRepository) FindByID(ctx context.Context, id string) (*model.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = $1`
+	return r.findOne(ctx, query, id)
+}
+
+func (r *PostgresCategoryRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = $2 AND ` + visibleCategories
+	return r.findOne(ctx, query, userID, id)
+}
+
+func (r *PostgresCategoryRepository) FindAll(ctx context.Context) ([]*model.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories ORDER BY created_at, id`
+	return r.findMany(ctx, query)
+}
+
+func (r *PostgresCategoryRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE ` + visibleCategories + ` ORDER BY created_at, id`
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresCategoryRepository) FindByOrgID(ctx context.Context, orgID string) ([]*model.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE org_id = $1 ORDER BY created_at, id`
+	return r.findMany(ctx, query, orgID)
+}
+
+func (r *PostgresCategoryRepository) Update(ctx context.Context, category *model.Category) error {
+	query := `
+		UPDATE categories SET name=$1, description=$2, hints=$3, summarize=$4, sensitive=$5, updated_at=NOW() WHERE id=$6`
+	result, err := r.db.ExecContext(ctx, query,
+		category.Name, category.Description, category.Hints, category.Summarize, category.Sensitive, category.ID)
+	if isUniqueViolation(err) {
+		return apierror.Conflict("category name already exists", nil)
+	}
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("category not found")
+	}
+	return nil
+}
+
 func (r *PostgresCategoryRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM categories WHERE id = $1`
 	_, err := r.db.ExecContext(ctx, query, id)
@@ -211,137 +258,2280 @@ func (r *PostgresCategoryRepository) Delete(ctx context.Context, id string) erro
 
 // Postgres Email repository implementation
 type PostgresEmailRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	reads *readRouter // for listings and counts, see UseReplica
 }
 
 func NewPostgresEmailRepository(db *sql.DB) *PostgresEmailRepository {
-	return &PostgresEmailRepository{db: db}
+	return &PostgresEmailRepository{db: db, reads: newReadRouter(db, nil)}
 }
 
-func (r *PostgresEmailRepository) Create(ctx context.Context, email *model.Email) error {
-	query := `
-		INSERT INTO emails (id, user_id, gmail_id, from_email, subject, body, summary, category_id, received_at, archived, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		ON CONFLICT (gmail_id) DO UPDATE SET
-			user_id = EXCLUDED.user_id,
-			from_email = EXCLUDED.from_email,
-			subject = EXCLUDED.subject,
-			body = EXCLUDED.body,
-			summary = EXCLUDED.summary,
-			category_id = EXCLUDED.category_id,
-			received_at = EXCLUDED.received_at,
-			archived = EXCLUDED.archived,
-			updated_at = NOW()`
-	_, err := r.db.ExecContext(ctx, query,
-		email.ID, email.UserID, email.GmailID, email.From, email.Subject, email.Body,
-		email.Summary, email.CategoryID, email.ReceivedAt, email.Archived,
-		email.CreatedAt, email.UpdatedAt)
-	return err
+// UseReplica runs listings and counts on a read-only replica, which may lag a moment behind,
+// when their context is marked with repository.ReplicaOK. Writes, every other read, which may
+// decide what is written, and all reads while the replica can't be reached stay on the primary.
+func (r *PostgresEmailRepository) UseReplica(replica *sql.DB) {
+	r.reads = newReadRouter(r.db, replica)
 }
 
-func (r *PostgresEmailRepository) FindByID(ctx context.Context, id string) (*model.Email, error) {
-	query := `SELECT id, user_id, gmail_id, from_email, subject, body, summary, category_id, received_at, archived, created_at, updated_at FROM emails WHERE id = $1`
-	row := r.db.QueryRowContext(ctx, query, id)
+// emailColumns lists the emails table columns in the order scanEmail expects them
+const emailColumns = `id, user_id, gmail_id, from_email, from_name, from_address, to_addresses, cc_addresses, reply_to, message_id, subject, body, body_truncated, body_pruned, summary, category_id, received_at, sent_at, archived, unread, starred, important, locally_archived, created_at, updated_at, deleted_at, triaged_at, triage_action, unsubscribed_at, system_flag, otp_code, otp_expires_at, security_flag, priority, snippet, needs_reprocessing, review_category_id, confidence, version`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+func scanEmail(row rowScanner) (*model.Email, error) {
 	email := &model.Email{}
 	err := row.Scan(
-		&email.ID, &email.UserID, &email.GmailID, &email.From, &email.Subject, &email.Body,
-		&email.Summary, &email.CategoryID, &email.ReceivedAt, &email.Archived,
-		&email.CreatedAt, &email.UpdatedAt)
+		&email.ID, &email.UserID, &email.GmailID, &email.From, &email.FromName, &email.FromAddress,
+		&email.To, &email.Cc, &email.ReplyTo, &email.MessageID,
+		&email.Subject, &email.Body, &email.BodyTruncated, &email.BodyPruned, &email.Summary, &email.CategoryID, &email.ReceivedAt, &email.SentAt, &email.Archived, &email.Unread, &email.Starred, &email.Important, &email.LocallyArchived,
+		&email.CreatedAt, &email.UpdatedAt, &email.DeletedAt, &email.TriagedAt, &email.TriageAction, &email.UnsubscribedAt, &email.SystemFlag, &email.OTPCode, &email.OTPExpiresAt, &email.SecurityFlag, &email.Priority, &email.Snippet, &email.NeedsReprocessing,
+		&email.ReviewCategoryID, &email.Confidence, &email.Version)
+	if err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+func (r *PostgresEmailRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.Email, error) {
+	email, err := scanEmail(r.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("email not found")
+			return nil, apierror.NotFound("email not found")
 		}
 		return nil, err
 	}
 	return email, nil
 }
 
-func (r *PostgresEmailRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Email, error) {
-	query := `SELECT id, user_id, gmail_id, from_email, subject, body, summary, category_id, received_at, archived, created_at, updated_at FROM emails WHERE user_id = $1 ORDER BY received_at DESC`
-	rows, err := r.db.QueryContext(ctx, query, userID)
+func (r *PostgresEmailRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*model.Email, error) {
+	rows, err := r.reads.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	return scanEmails(rows)
+}
+
+// scanEmails reads every row as an email and closes the rows
+func scanEmails(rows *sql.Rows) ([]*model.Email, error) {
 	defer rows.Close()
 
 	var emails []*model.Email
 	for rows.Next() {
-		email := &model.Email{}
-		err := rows.Scan(
-			&email.ID, &email.UserID, &email.GmailID, &email.From, &email.Subject, &email.Body,
-			&email.Summary, &email.CategoryID, &email.ReceivedAt, &email.Archived,
-			&email.CreatedAt, &email.UpdatedAt)
+		email, err := scanEmail(rows)
 		if err != nil {
 			return nil, err
 		}
 		emails = append(emails, email)
 	}
 
-	return emails, nil
+	return emails, rows.Err()
+}
+
+// emailUpsert overwrites the user's copy of a Gmail message that is already stored
+const emailUpsert = `
+		ON CONFLICT (user_id, gmail_id) DO UPDATE SET
+			from_email = EXCLUDED.from_email,
+			from_name = EXCLUDED.from_name,
+			from_address = EXCLUDED.from_address,
+			to_addresses = EXCLUDED.to_addresses,
+			cc_addresses = EXCLUDED.cc_addresses,
+			reply_to = EXCLUDED.reply_to,
+			message_id = EXCLUDED.message_id,
+			subject = EXCLUDED.subject,
+			body = EXCLUDED.body,
+			body_truncated = EXCLUDED.body_truncated,
+			body_pruned = EXCLUDED.body_pruned,
+			summary = EXCLUDED.summary,
+			category_id = EXCLUDED.category_id,
+			received_at = EXCLUDED.received_at,
+			sent_at = EXCLUDED.sent_at,
+			archived = EXCLUDED.archived,
+			unread = EXCLUDED.unread,
+			starred = EXCLUDED.starred,
+			important = EXCLUDED.important,
+			locally_archived = EXCLUDED.locally_archived,
+			system_flag = EXCLUDED.system_flag,
+			otp_code = EXCLUDED.otp_code,
+			otp_expires_at = EXCLUDED.otp_expires_at,
+			security_flag = EXCLUDED.security_flag,
+			priority = EXCLUDED.priority,
+			snippet = EXCLUDED.snippet,
+			needs_reprocessing = EXCLUDED.needs_reprocessing,
+			review_category_id = EXCLUDED.review_category_id,
+			confidence = EXCLUDED.confidence,
+			deleted_at = EXCLUDED.deleted_at,
+			version = emails.version + 1,
+			updated_at = NOW()`
+
+// emailBatchSize keeps a multi-row insert under postgres' 65535 bind parameters
+const emailBatchSize = 500
+
+func emailValues(email *model.Email) []any {
+	return []any{
+		email.ID, email.UserID, email.GmailID, email.From, email.FromName, email.FromAddress,
+		email.To, email.Cc, email.ReplyTo, email.MessageID,
+		email.Subject, email.Body, email.BodyTruncated, email.BodyPruned, email.Summary, email.CategoryID, email.ReceivedAt, email.SentAt, email.Archived, email.Unread, email.Starred, email.Important, email.LocallyArchived,
+		email.CreatedAt, email.UpdatedAt, email.DeletedAt, email.TriagedAt, email.TriageAction, email.UnsubscribedAt, email.SystemFlag,
+		email.OTPCode, email.OTPExpiresAt, email.SecurityFlag, email.Priority, email.Snippet, email.NeedsReprocessing,
+		email.ReviewCategoryID, email.Confidence, email.Version,
+	}
+}
+
+func (r *PostgresEmailRepository) Create(ctx context.Context, email *model.Email) error {
+	query := `
+		INSERT INTO emails (` + emailColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39)` + emailUpsert
+	_, err := r.db.ExecContext(ctx, query, emailValues(email)...)
+	return err
+}
+
+// CreateBatch stores the emails with a multi-row insert per emailBatchSize emails, in a single
+// transaction
+func (r *PostgresEmailRepository) CreateBatch(ctx context.Context, emails []*model.Email) error {
+	// A statement can't upsert the same row twice, so only the last copy of a message is kept
+	last := make(map[string]int, len(emails))
+	for i, email := range emails {
+		last[email.UserID+"/"+email.GmailID] = i
+	}
+	unique := make([]*model.Email, 0, len(last))
+	for i, email := range emails {
+		if last[email.UserID+"/"+email.GmailID] == i {
+			unique = append(unique, email)
+		}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	columns := strings.Count(emailColumns, ",") + 1
+	for start := 0; start < len(unique); start += emailBatchSize {
+		batch := unique[start:min(start+emailBatchSize, len(unique))]
+		rows := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*columns)
+		for i, email := range batch {
+			placeholders := make([]string, columns)
+			for j := range placeholders {
+				placeholders[j] = fmt.Sprintf("$%d", i*columns+j+1)
+			}
+			rows[i] = "(" + strings.Join(placeholders, ", ") + ")"
+			args = append(args, emailValues(email)...)
+		}
+		query := `INSERT INTO emails (` + emailColumns + `) VALUES ` + strings.Join(rows, ", ") + emailUpsert
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresEmailRepository) FindByID(ctx context.Context, id string) (*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE id = $1`
+	return r.findOne(ctx, query, id)
+}
+
+func (r *PostgresEmailRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE id = $1 AND user_id = $2`
+	return r.findOne(ctx, query, id, userID)
+}
+
+func (r *PostgresEmailRepository) FindByUserID(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	// Served by idx_emails_user_received; id breaks ties so pages are stable
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND deleted_at IS NULL ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresEmailRepository) FindByCategoryID(ctx context.Context, categoryID string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE category_id = $1 AND deleted_at IS NULL ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, categoryID)
+}
+
+func (r *PostgresEmailRepository) FindByCategoryIDAndUser(ctx context.Context, categoryID, userID string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE category_id = $1 AND user_id = $2 AND deleted_at IS NULL ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, categoryID, userID)
+}
+
+func (r *PostgresEmailRepository) FindByFilter(ctx context.Context, userID string, filter model.EmailFilter, limit int) ([]*model.Email, error) {
+	conditions, args := filterConditions(userID, filter)
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, args...)
+}
+
+func (r *PostgresEmailRepository) FindWindowByFilter(ctx context.Context, userID string, filter model.EmailFilter, offset, limit int) ([]*model.Email, error) {
+	conditions, args := filterConditions(userID, filter)
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY received_at DESC, id` + limitClause(limit) + offsetClause(offset)
+	return r.findMany(ctx, query, args...)
 }
 
-func (r *PostgresEmailRepository) FindByCategoryID(ctx context.Context, categoryID string) ([]*model.Email, error) {
-	query := `SELECT id, user_id, gmail_id, from_email, subject, body, summary, category_id, received_at, archived, created_at, updated_at FROM emails WHERE category_id = $1 ORDER BY received_at DESC`
-	rows, err := r.db.QueryContext(ctx, query, categoryID)
+func (r *PostgresEmailRepository) CountByFilter(ctx context.Context, userID string, filter model.EmailFilter) (int, error) {
+	conditions, args := filterConditions(userID, filter)
+	query := `SELECT COUNT(*) FROM emails WHERE ` + strings.Join(conditions, " AND ")
+
+	var count int
+	err := r.reads.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresEmailRepository) FindMostRecentByUserID(ctx context.Context, userID string) (*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND deleted_at IS NULL ORDER BY received_at DESC, id LIMIT 1`
+	return r.findOne(ctx, query, userID)
+}
+
+func (r *PostgresEmailRepository) CountByUserID(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.reads.QueryRowContext(ctx, `SELECT COUNT(*) FROM emails WHERE user_id = $1 AND deleted_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresEmailRepository) CountByCategoryID(ctx context.Context, categoryID string) (int, error) {
+	var count int
+	err := r.reads.QueryRowContext(ctx, `SELECT COUNT(*) FROM emails WHERE category_id = $1 AND deleted_at IS NULL`, categoryID).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresEmailRepository) CountByCategory(ctx context.Context, userID string) (map[string]model.EmailCounts, error) {
+	query := `SELECT COALESCE(category_id, ''), COUNT(*), COUNT(*) FILTER (WHERE unread)
+		FROM emails WHERE user_id = $1 AND deleted_at IS NULL GROUP BY 1`
+	rows, err := r.reads.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var emails []*model.Email
+	counts := make(map[string]model.EmailCounts)
 	for rows.Next() {
-		email := &model.Email{}
-		err := rows.Scan(
-			&email.ID, &email.UserID, &email.GmailID, &email.From, &email.Subject, &email.Body,
-			&email.Summary, &email.CategoryID, &email.ReceivedAt, &email.Archived,
-			&email.CreatedAt, &email.UpdatedAt)
-		if err != nil {
+		var categoryID string
+		var count model.EmailCounts
+		if err := rows.Scan(&categoryID, &count.Total, &count.Unread); err != nil {
 			return nil, err
 		}
-		emails = append(emails, email)
+		counts[categoryID] = count
+	}
+	return counts, rows.Err()
+}
+
+func (r *PostgresEmailRepository) CountBySender(ctx context.Context, userID string, since time.Time) ([]*model.SenderActivity, error) {
+	query := `SELECT from_address, COUNT(*), COUNT(*) FILTER (WHERE unread),
+			COUNT(*) FILTER (WHERE unsubscribed_at IS NOT NULL), MAX(received_at)
+		FROM emails WHERE user_id = $1 AND deleted_at IS NULL AND received_at >= $2
+		GROUP BY from_address ORDER BY COUNT(*) DESC, from_address`
+	rows, err := r.reads.QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*model.SenderActivity
+	for rows.Next() {
+		activity := &model.SenderActivity{}
+		if err := rows.Scan(&activity.Address, &activity.Total, &activity.Unread, &activity.Unsubscribed, &activity.LastReceivedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, activity)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresEmailRepository) FindAdjacent(ctx context.Context, userID string, filter model.EmailFilter, email *model.Email) (string, string, error) {
+	conditions, args := filterConditions(userID, filter)
+	args = append(args, email.ReceivedAt, email.ID)
+	at, id := len(args)-1, len(args)
+
+	// Listings run received_at DESC, id; the previous email sorts just before this one, the next just after
+	newer := fmt.Sprintf("(received_at > $%d OR (received_at = $%d AND id < $%d))", at, at, id)
+	older := fmt.Sprintf("(received_at < $%d OR (received_at = $%d AND id > $%d))", at, at, id)
+	where := strings.Join(conditions, " AND ")
+
+	previousID, err := r.findID(ctx, `SELECT id FROM emails WHERE `+where+` AND `+newer+` ORDER BY received_at ASC, id DESC LIMIT 1`, args...)
+	if err != nil {
+		return "", "", err
+	}
+	nextID, err := r.findID(ctx, `SELECT id FROM emails WHERE `+where+` AND `+older+` ORDER BY received_at DESC, id LIMIT 1`, args...)
+	if err != nil {
+		return "", "", err
+	}
+	return previousID, nextID, nil
+}
+
+// findID returns the id selected by query, or "" when there is no row
+func (r *PostgresEmailRepository) findID(ctx context.Context, query string, args ...interface{}) (string, error) {
+	var id string
+	err := r.reads.QueryRowContext(ctx, query, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// filterConditions renders an email filter as WHERE conditions over a user's active emails
+func filterConditions(userID string, filter model.EmailFilter) ([]string, []interface{}) {
+	conditions := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{userID}
+
+	if filter.CategoryID != "" {
+		args = append(args, filter.CategoryID)
+		conditions = append(conditions, fmt.Sprintf("category_id = $%d", len(args)))
+	}
+	if filter.Sender != "" {
+		args = append(args, strings.ToLower(filter.Sender))
+		conditions = append(conditions, fmt.Sprintf("from_address = $%d", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, *filter.After)
+		conditions = append(conditions, fmt.Sprintf("received_at >= $%d", len(args)))
+	}
+	if filter.Before != nil {
+		args = append(args, *filter.Before)
+		conditions = append(conditions, fmt.Sprintf("received_at < $%d", len(args)))
+	}
+	if filter.Unread != nil {
+		args = append(args, *filter.Unread)
+		conditions = append(conditions, fmt.Sprintf("unread = $%d", len(args)))
+	}
+	if filter.Query != "" {
+		args = append(args, "%"+likeEscaper.Replace(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(subject ILIKE $%d OR from_email ILIKE $%d OR summary ILIKE $%d)", len(args), len(args), len(args)))
+	}
+
+	switch filter.Archive {
+	case model.ArchiveFilterOnly:
+		conditions = append(conditions, "locally_archived = TRUE")
+	case model.ArchiveFilterAll:
+	default:
+		conditions = append(conditions, "locally_archived = FALSE")
+	}
+
+	return conditions, args
+}
+
+// likeEscaper escapes the LIKE wildcards so a query matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// limitClause renders a LIMIT for positive limits; the value is an int so it is safe to inline
+func limitClause(limit int) string {
+	if limit <= 0 {
+		return ""
 	}
+	return fmt.Sprintf(" LIMIT %d", limit)
+}
 
-	return emails, nil
+func offsetClause(offset int) string {
+	if offset <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" OFFSET %d", offset)
 }
 
 func (r *PostgresEmailRepository) Update(ctx context.Context, email *model.Email) error {
 	query := `
-		UPDATE emails SET from_email=$1, subject=$2, body=$3, summary=$4, category_id=$5, archived=$6, updated_at=NOW() WHERE id=$7`
-	_, err := r.db.ExecContext(ctx, query,
-		email.From, email.Subject, email.Body, email.Summary, email.CategoryID, email.Archived,
-		email.ID)
-	return err
+		UPDATE emails SET from_email=$1, from_name=$2, from_address=$3, to_addresses=$4, cc_addresses=$5,
+		reply_to=$6, message_id=$7, subject=$8, body=$9, body_truncated=$10, body_pruned=$11, summary=$12, category_id=$13,
+		sent_at=$14, archived=$15, unread=$16, starred=$17, important=$18, locally_archived=$19, system_flag=$20,
+		otp_code=$21, otp_expires_at=$22, security_flag=$23, priority=$24, snippet=$25, needs_reprocessing=$26,
+		review_category_id=$27, confidence=$28, version=version+1, updated_at=NOW() WHERE id=$29 AND version=$30`
+	result, err := r.db.ExecContext(ctx, query,
+		email.From, email.FromName, email.FromAddress, email.To, email.Cc,
+		email.ReplyTo, email.MessageID, email.Subject, email.Body, email.BodyTruncated, email.BodyPruned, email.Summary, email.CategoryID, email.SentAt,
+		email.Archived, email.Unread, email.Starred, email.Important, email.LocallyArchived, email.SystemFlag,
+		email.OTPCode, email.OTPExpiresAt, email.SecurityFlag, email.Priority, email.Snippet, email.NeedsReprocessing,
+		email.ReviewCategoryID, email.Confidence, email.ID, email.Version)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		// Either the email is gone or it was saved since it was read
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM emails WHERE id = $1)`, email.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return apierror.NotFound("email not found")
+		}
+		return repository.ErrEmailChanged
+	}
+	email.Version++
+	return nil
 }
 
-func (r *PostgresEmailRepository) FindByGmailID(ctx context.Context, userID, gmailID string) (*model.Email, error) {
-	query := `SELECT id, user_id, gmail_id, from_email, subject, body, summary, category_id, received_at, archived, created_at, updated_at FROM emails WHERE user_id = $1 AND gmail_id = $2`
-	row := r.db.QueryRowContext(ctx, query, userID, gmailID)
+func (r *PostgresEmailRepository) MarkTriaged(ctx context.Context, id, action string, at time.Time) error {
+	query := `UPDATE emails SET triaged_at = $1, triage_action = $2, updated_at = NOW() WHERE id = $3`
+	result, err := r.db.ExecContext(ctx, query, at, action, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("email not found")
+	}
+	return nil
+}
 
-	email := &model.Email{}
-	err := row.Scan(
-		&email.ID, &email.UserID, &email.GmailID, &email.From, &email.Subject, &email.Body,
-		&email.Summary, &email.CategoryID, &email.ReceivedAt, &email.Archived,
-		&email.CreatedAt, &email.UpdatedAt)
+func (r *PostgresEmailRepository) MarkUnsubscribed(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE emails SET unsubscribed_at = $1, updated_at = NOW() WHERE id = $2`
+	result, err := r.db.ExecContext(ctx, query, at, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("email not found")
-		}
-		return nil, err
+		return err
 	}
-	return email, nil
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("email not found")
+	}
+	return nil
 }
 
-func (r *PostgresEmailRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM emails WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+func (r *PostgresEmailRepository) FindBySender(ctx context.Context, userID, address string) ([]*model.Email, error) {
+	// Served by idx_emails_user_sender; oldest first, the reverse of the listing order
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND from_address = $2 ORDER BY received_at, id DESC`
+	return r.findMany(ctx, query, userID, strings.ToLower(address))
 }
 
-// InitializeDatabase creates the necessary tables
-func InitializeDatabase(db *sql.DB) error {
-	tables := []string{
-		`CREATE TABLE IF NOT EXISTS users (
+// untriagedConditions matches the emails model.Email.NeedsTriage accepts
+const untriagedConditions = `user_id = $1 AND triaged_at IS NULL AND deleted_at IS NULL AND archived = FALSE AND locally_archived = FALSE`
+
+func (r *PostgresEmailRepository) FindNextUntriaged(ctx context.Context, userID string) (*model.Email, error) {
+	// The reverse of the listing order, served by idx_emails_user_untriaged
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE ` + untriagedConditions + ` ORDER BY received_at, id DESC LIMIT 1`
+	email, err := r.findOne(ctx, query, userID)
+	if errors.Is(err, apierror.ErrNotFound) {
+		return nil, apierror.NotFound("no email to triage")
+	}
+	return email, err
+}
+
+func (r *PostgresEmailRepository) CountUntriaged(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.reads.QueryRowContext(ctx, `SELECT COUNT(*) FROM emails WHERE `+untriagedConditions, userID).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresEmailRepository) FindByGmailID(ctx context.Context, userID, gmailID string) (*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND gmail_id = $2`
+	return r.findOne(ctx, query, userID, gmailID)
+}
+
+func (r *PostgresEmailRepository) ExistsByGmailID(ctx context.Context, userID, gmailID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM emails WHERE user_id = $1 AND gmail_id = $2)`, userID, gmailID).Scan(&exists)
+	return exists, err
+}
+
+func (r *PostgresEmailRepository) FindByGmailIDs(ctx context.Context, userID string, gmailIDs []string) ([]*model.Email, error) {
+	if len(gmailIDs) == 0 {
+		return nil, nil
+	}
+	// Sync decides from this which emails are new, so it reads the primary: on a lagging
+	// replica stored emails would look new and be saved over. Served by idx_emails_user_gmail_unique.
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND gmail_id = ANY($2)`
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(gmailIDs))
+	if err != nil {
+		return nil, err
+	}
+	return scanEmails(rows)
+}
+
+func (r *PostgresEmailRepository) Delete(ctx context.Context, id string) error {
+	query := `UPDATE emails SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *PostgresEmailRepository) FindDeletedByUserID(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresEmailRepository) Restore(ctx context.Context, id string) error {
+	query := `UPDATE emails SET deleted_at = NULL, updated_at = NOW() WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("email not found")
+	}
+	return nil
+}
+
+func (r *PostgresEmailRepository) FindByPriority(ctx context.Context, userID, priority string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND priority = $2 AND deleted_at IS NULL ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, userID, priority)
+}
+
+func (r *PostgresEmailRepository) FindNeedingReprocessing(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND needs_reprocessing AND NOT body_pruned AND deleted_at IS NULL
+		ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresEmailRepository) FindInReview(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND review_category_id <> '' AND deleted_at IS NULL
+		ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresEmailRepository) SetPriorityBySender(ctx context.Context, userID, address, priority string) (int, error) {
+	query := `UPDATE emails SET priority = $1, version = version + 1, updated_at = NOW() WHERE user_id = $2 AND from_address = $3 AND priority <> $1`
+	result, err := r.db.ExecContext(ctx, query, priority, userID, address)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *PostgresEmailRepository) MoveToUser(ctx context.Context, fromUserID, toUserID string, categories map[string]string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for from, to := range categories {
+		query := `UPDATE emails SET
+			category_id = CASE WHEN category_id = $2 THEN $3 ELSE category_id END,
+			review_category_id = CASE WHEN review_category_id = $2 THEN $3 ELSE review_category_id END
+			WHERE user_id = $1 AND (category_id = $2 OR review_category_id = $2)`
+		if _, err := tx.ExecContext(ctx, query, fromUserID, from, to); err != nil {
+			return 0, err
+		}
+	}
+	query := `UPDATE emails SET user_id = $2, version = version + 1, updated_at = NOW()
+		WHERE user_id = $1 AND gmail_id NOT IN (SELECT gmail_id FROM emails WHERE user_id = $2)`
+	result, err := tx.ExecContext(ctx, query, fromUserID, toUserID)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM emails WHERE user_id = $1`, fromUserID); err != nil {
+		return 0, err
+	}
+	return int(moved), tx.Commit()
+}
+
+// emailChangedAt is the SQL of model.Email.ChangedAt
+const emailChangedAt = `GREATEST(created_at, updated_at, COALESCE(deleted_at, updated_at))`
+
+func (r *PostgresEmailRepository) FindChangedSince(ctx context.Context, userID string, since time.Time, afterID string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND (` + emailChangedAt + `, id) > ($2, $3)
+		ORDER BY ` + emailChangedAt + `, id` + limitClause(limit)
+	return r.findMany(ctx, query, userID, since, afterID)
+}
+
+func (r *PostgresEmailRepository) FindSecurityFlagged(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	query := `SELECT ` + emailColumns + ` FROM emails WHERE user_id = $1 AND security_flag <> '' AND deleted_at IS NULL ORDER BY received_at DESC, id` + limitClause(limit)
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresEmailRepository) TrashOTPReceivedBefore(ctx context.Context, before time.Time) (int, error) {
+	query := `UPDATE emails SET deleted_at = NOW() WHERE otp_code <> '' AND deleted_at IS NULL AND received_at < $1`
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *PostgresEmailRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM emails WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *PostgresEmailRepository) PruneBodiesBefore(ctx context.Context, userID string, before time.Time) (int, error) {
+	query := `
+		UPDATE emails SET body = '', body_pruned = TRUE, version = version + 1, updated_at = NOW()
+		WHERE user_id = $1 AND received_at < $2 AND body_pruned = FALSE`
+	result, err := r.db.ExecContext(ctx, query, userID, before)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *PostgresEmailRepository) DeleteReceivedBefore(ctx context.Context, userID string, before time.Time) (int, error) {
+	query := `DELETE FROM emails WHERE user_id = $1 AND received_at < $2`
+	result, err := r.db.ExecContext(ctx, query, userID, before)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *PostgresEmailRepository) DeleteExcessPerCategory(ctx context.Context, userID string, keep int) (int, error) {
+	// Rank each category's emails newest first and drop everything past the cap
+	query := `
+		DELETE FROM emails WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY category_id ORDER BY received_at DESC, id) AS rank
+				FROM emails WHERE user_id = $1 AND deleted_at IS NULL
+			) ranked WHERE rank > $2
+		)`
+	result, err := r.db.ExecContext(ctx, query, userID, keep)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// Postgres RetentionPolicy repository implementation
+type PostgresRetentionPolicyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRetentionPolicyRepository(db *sql.DB) *PostgresRetentionPolicyRepository {
+	return &PostgresRetentionPolicyRepository{db: db}
+}
+
+func (r *PostgresRetentionPolicyRepository) FindByUserID(ctx context.Context, userID string) (*model.RetentionPolicy, error) {
+	query := `SELECT user_id, body_retention_days, retention_days, max_emails_per_category, updated_at FROM retention_policies WHERE user_id = $1`
+	row := r.db.QueryRowContext(ctx, query, userID)
+
+	policy := &model.RetentionPolicy{}
+	err := row.Scan(&policy.UserID, &policy.BodyRetentionDays, &policy.RetentionDays, &policy.MaxEmailsPerCategory, &policy.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("retention policy not found")
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (r *PostgresRetentionPolicyRepository) FindAll(ctx context.Context) ([]*model.RetentionPolicy, error) {
+	query := `SELECT user_id, body_retention_days, retention_days, max_emails_per_category, updated_at FROM retention_policies`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []*model.RetentionPolicy
+	for rows.Next() {
+		policy := &model.RetentionPolicy{}
+		err := rows.Scan(&policy.UserID, &policy.BodyRetentionDays, &policy.RetentionDays, &policy.MaxEmailsPerCategory, &policy.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+func (r *PostgresRetentionPolicyRepository) Save(ctx context.Context, policy *model.RetentionPolicy) error {
+	query := `
+		INSERT INTO retention_policies (user_id, body_retention_days, retention_days, max_emails_per_category, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			body_retention_days = EXCLUDED.body_retention_days,
+			retention_days = EXCLUDED.retention_days,
+			max_emails_per_category = EXCLUDED.max_emails_per_category,
+			updated_at = EXCLUDED.updated_at`
+	_, err := r.db.ExecContext(ctx, query,
+		policy.UserID, policy.BodyRetentionDays, policy.RetentionDays, policy.MaxEmailsPerCategory, policy.UpdatedAt)
+	return err
+}
+
+// Postgres NotificationPreferences repository implementation
+type PostgresNotificationPreferencesRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresNotificationPreferencesRepository(db *sql.DB) *PostgresNotificationPreferencesRepository {
+	return &PostgresNotificationPreferencesRepository{db: db}
+}
+
+func (r *PostgresNotificationPreferencesRepository) FindByUserID(ctx context.Context, userID string) (*model.NotificationPreferences, error) {
+	query := `SELECT user_id, quiet_hours_start, quiet_hours_end, time_zone, event_types, min_priority, updated_at FROM notification_preferences WHERE user_id = $1`
+	row := r.db.QueryRowContext(ctx, query, userID)
+
+	preferences := &model.NotificationPreferences{}
+	var eventTypes string
+	err := row.Scan(&preferences.UserID, &preferences.QuietHoursStart, &preferences.QuietHoursEnd, &preferences.TimeZone, &eventTypes, &preferences.MinPriority, &preferences.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("notification preferences not found")
+		}
+		return nil, err
+	}
+	// Event types are stored space-separated, like granted scopes
+	preferences.EventTypes = strings.Fields(eventTypes)
+	return preferences, nil
+}
+
+func (r *PostgresNotificationPreferencesRepository) Save(ctx context.Context, preferences *model.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, quiet_hours_start, quiet_hours_end, time_zone, event_types, min_priority, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			quiet_hours_start = EXCLUDED.quiet_hours_start,
+			quiet_hours_end = EXCLUDED.quiet_hours_end,
+			time_zone = EXCLUDED.time_zone,
+			event_types = EXCLUDED.event_types,
+			min_priority = EXCLUDED.min_priority,
+			updated_at = EXCLUDED.updated_at`
+	_, err := r.db.ExecContext(ctx, query,
+		preferences.UserID, preferences.QuietHoursStart, preferences.QuietHoursEnd, preferences.TimeZone,
+		strings.Join(preferences.EventTypes, " "), preferences.MinPriority, preferences.UpdatedAt)
+	return err
+}
+
+// Postgres PushSubscription repository implementation
+type PostgresPushSubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPushSubscriptionRepository(db *sql.DB) *PostgresPushSubscriptionRepository {
+	return &PostgresPushSubscriptionRepository{db: db}
+}
+
+func (r *PostgresPushSubscriptionRepository) Save(ctx context.Context, subscription *model.PushSubscription) error {
+	// A browser re-subscribing, possibly after another user logged in, takes over the endpoint
+	query := `
+		INSERT INTO push_subscriptions (id, user_id, endpoint, p256dh, auth, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (endpoint) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth`
+	_, err := r.db.ExecContext(ctx, query,
+		subscription.ID, subscription.UserID, subscription.Endpoint, subscription.P256dh, subscription.Auth, subscription.CreatedAt)
+	return err
+}
+
+func (r *PostgresPushSubscriptionRepository) FindByUserID(ctx context.Context, userID string) ([]*model.PushSubscription, error) {
+	query := `SELECT id, user_id, endpoint, p256dh, auth, created_at FROM push_subscriptions WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*model.PushSubscription
+	for rows.Next() {
+		subscription := &model.PushSubscription{}
+		if err := rows.Scan(&subscription.ID, &subscription.UserID, &subscription.Endpoint, &subscription.P256dh, &subscription.Auth, &subscription.CreatedAt); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func (r *PostgresPushSubscriptionRepository) DeleteByEndpoint(ctx context.Context, userID, endpoint string) error {
+	query := `DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, endpoint)
+	return err
+}
+
+// Postgres PendingEvent repository implementation
+type PostgresPendingEventRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPendingEventRepository(db *sql.DB) *PostgresPendingEventRepository {
+	return &PostgresPendingEventRepository{db: db}
+}
+
+func (r *PostgresPendingEventRepository) Enqueue(ctx context.Context, event *model.PendingEvent, limit int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO pending_events (id, user_id, event_type, payload, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.ExecContext(ctx, query,
+		event.ID, event.UserID, event.Type, string(event.Payload), event.CreatedAt, event.ExpiresAt); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pending_events WHERE user_id = $1 AND expires_at <= $2`, event.UserID, event.CreatedAt); err != nil {
+		return err
+	}
+	if limit > 0 {
+		query = `
+			DELETE FROM pending_events WHERE user_id = $1 AND id NOT IN (
+				SELECT id FROM pending_events WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+			)`
+		if _, err := tx.ExecContext(ctx, query, event.UserID, limit); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresPendingEventRepository) TakeByUserID(ctx context.Context, userID string, now time.Time) ([]*model.PendingEvent, error) {
+	// Taken in one statement, so a second connection of the user can't receive the events again
+	query := `
+		WITH taken AS (
+			DELETE FROM pending_events WHERE user_id = $1
+			RETURNING id, user_id, event_type, payload, created_at, expires_at
+		)
+		SELECT id, user_id, event_type, payload, created_at, expires_at FROM taken
+		WHERE expires_at > $2 ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.PendingEvent
+	for rows.Next() {
+		event := &model.PendingEvent{}
+		var payload string
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Type, &payload, &event.CreatedAt, &event.ExpiresAt); err != nil {
+			return nil, err
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// Postgres AccountMergeCode repository implementation
+type PostgresAccountMergeCodeRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAccountMergeCodeRepository(db *sql.DB) *PostgresAccountMergeCodeRepository {
+	return &PostgresAccountMergeCodeRepository{db: db}
+}
+
+func (r *PostgresAccountMergeCodeRepository) Create(ctx context.Context, code *model.AccountMergeCode, now time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM account_merge_codes WHERE expires_at <= $1`, now); err != nil {
+		return err
+	}
+	query := `INSERT INTO account_merge_codes (code, user_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, code.Code, code.UserID, code.ExpiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresAccountMergeCodeRepository) Take(ctx context.Context, code string, now time.Time) (*model.AccountMergeCode, error) {
+	// Taken in one statement, so two requests can't both redeem the code
+	query := `DELETE FROM account_merge_codes WHERE code = $1 RETURNING code, user_id, expires_at`
+	taken := &model.AccountMergeCode{}
+	err := r.db.QueryRowContext(ctx, query, code).Scan(&taken.Code, &taken.UserID, &taken.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !now.Before(taken.ExpiresAt)) {
+		return nil, apierror.NotFound("merge code not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return taken, nil
+}
+
+// Postgres GmailAction repository implementation
+type PostgresGmailActionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresGmailActionRepository(db *sql.DB) *PostgresGmailActionRepository {
+	return &PostgresGmailActionRepository{db: db}
+}
+
+func (r *PostgresGmailActionRepository) Create(ctx context.Context, action *model.GmailAction) error {
+	query := `
+		INSERT INTO gmail_actions (id, user_id, email_id, gmail_id, action, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.ExecContext(ctx, query, action.ID, action.UserID, action.EmailID, action.GmailID, action.Action, action.CreatedAt)
+	return err
+}
+
+func (r *PostgresGmailActionRepository) FindAll(ctx context.Context) ([]*model.GmailAction, error) {
+	query := `SELECT id, user_id, email_id, gmail_id, action, created_at FROM gmail_actions ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []*model.GmailAction
+	for rows.Next() {
+		action := &model.GmailAction{}
+		if err := rows.Scan(&action.ID, &action.UserID, &action.EmailID, &action.GmailID, &action.Action, &action.CreatedAt); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, rows.Err()
+}
+
+func (r *PostgresGmailActionRepository) CountByUserID(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM gmail_actions WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresGmailActionRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM gmail_actions WHERE id = $1`, id)
+	return err
+}
+
+// Postgres TelegramLink repository implementation
+type PostgresTelegramLinkRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTelegramLinkRepository(db *sql.DB) *PostgresTelegramLinkRepository {
+	return &PostgresTelegramLinkRepository{db: db}
+}
+
+func (r *PostgresTelegramLinkRepository) Save(ctx context.Context, link *model.TelegramLink) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A chat belongs to a single user; linking it again moves it over
+	if _, err := tx.ExecContext(ctx, `DELETE FROM telegram_links WHERE chat_id = $1 AND user_id <> $2`, link.ChatID, link.UserID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO telegram_links (user_id, chat_id, last_email_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			chat_id = EXCLUDED.chat_id,
+			last_email_id = EXCLUDED.last_email_id`
+	if _, err := tx.ExecContext(ctx, query, link.UserID, link.ChatID, link.LastEmailID, link.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresTelegramLinkRepository) FindByUserID(ctx context.Context, userID string) (*model.TelegramLink, error) {
+	return r.findOne(ctx, `SELECT user_id, chat_id, last_email_id, created_at FROM telegram_links WHERE user_id = $1`, userID)
+}
+
+func (r *PostgresTelegramLinkRepository) FindByChatID(ctx context.Context, chatID int64) (*model.TelegramLink, error) {
+	return r.findOne(ctx, `SELECT user_id, chat_id, last_email_id, created_at FROM telegram_links WHERE chat_id = $1`, chatID)
+}
+
+func (r *PostgresTelegramLinkRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.TelegramLink, error) {
+	link := &model.TelegramLink{}
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&link.UserID, &link.ChatID, &link.LastEmailID, &link.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("telegram link not found")
+		}
+		return nil, err
+	}
+	return link, nil
+}
+
+func (r *PostgresTelegramLinkRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM telegram_links WHERE user_id = $1`, userID)
+	return err
+}
+
+// Postgres Webhook repository implementation
+type PostgresWebhookRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresWebhookRepository(db *sql.DB) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{db: db}
+}
+
+func (r *PostgresWebhookRepository) Save(ctx context.Context, webhook *model.Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			url = EXCLUDED.url,
+			secret = EXCLUDED.secret,
+			created_at = EXCLUDED.created_at`
+	_, err := r.db.ExecContext(ctx, query, webhook.UserID, webhook.URL, webhook.Secret, webhook.CreatedAt)
+	return err
+}
+
+func (r *PostgresWebhookRepository) FindByUserID(ctx context.Context, userID string) (*model.Webhook, error) {
+	webhook := &model.Webhook{}
+	err := r.db.QueryRowContext(ctx, `SELECT user_id, url, secret, created_at FROM webhooks WHERE user_id = $1`, userID).
+		Scan(&webhook.UserID, &webhook.URL, &webhook.Secret, &webhook.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("webhook not found")
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *PostgresWebhookRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE user_id = $1`, userID)
+	return err
+}
+
+// Postgres Organization repository implementation
+type PostgresOrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresOrganizationRepository(db *sql.DB) *PostgresOrganizationRepository {
+	return &PostgresOrganizationRepository{db: db}
+}
+
+func (r *PostgresOrganizationRepository) Create(ctx context.Context, org *model.Organization) error {
+	query := `INSERT INTO organizations (id, name, created_at, updated_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, org.ID, org.Name, org.CreatedAt, org.UpdatedAt)
+	return err
+}
+
+func (r *PostgresOrganizationRepository) FindByID(ctx context.Context, id string) (*model.Organization, error) {
+	org := &model.Organization{}
+	row := r.db.QueryRowContext(ctx, `SELECT id, name, created_at, updated_at FROM organizations WHERE id = $1`, id)
+	if err := row.Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("organization not found")
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+func (r *PostgresOrganizationRepository) Update(ctx context.Context, org *model.Organization) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE organizations SET name = $1, updated_at = $2 WHERE id = $3`, org.Name, org.UpdatedAt, org.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("organization not found")
+	}
+	return nil
+}
+
+func (r *PostgresOrganizationRepository) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM organization_members WHERE org_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresOrganizationRepository) SaveMember(ctx context.Context, membership *model.Membership) error {
+	query := `
+		INSERT INTO organization_members (user_id, org_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			org_id = EXCLUDED.org_id,
+			role = EXCLUDED.role`
+	_, err := r.db.ExecContext(ctx, query, membership.UserID, membership.OrgID, membership.Role, membership.CreatedAt)
+	return err
+}
+
+func scanMembership(row rowScanner) (*model.Membership, error) {
+	membership := &model.Membership{}
+	err := row.Scan(&membership.UserID, &membership.OrgID, &membership.Role, &membership.CreatedAt)
+	return membership, err
+}
+
+func (r *PostgresOrganizationRepository) FindMembership(ctx context.Context, userID string) (*model.Membership, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT user_id, org_id, role, created_at FROM organization_members WHERE user_id = $1`, userID)
+	membership, err := scanMembership(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("membership not found")
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+func (r *PostgresOrganizationRepository) FindMembers(ctx context.Context, orgID string) ([]*model.Membership, error) {
+	query := `SELECT user_id, org_id, role, created_at FROM organization_members WHERE org_id = $1 ORDER BY created_at, user_id`
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*model.Membership
+	for rows.Next() {
+		membership, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, membership)
+	}
+	return members, rows.Err()
+}
+
+func (r *PostgresOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	return err
+}
+
+// Postgres Invitation repository implementation
+type PostgresInvitationRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresInvitationRepository(db *sql.DB) *PostgresInvitationRepository {
+	return &PostgresInvitationRepository{db: db}
+}
+
+// invitationColumns lists the organization_invitations table columns in the order scanInvitation expects them
+const invitationColumns = `id, org_id, email, role, invited_by, expires_at, accepted_at, created_at`
+
+func scanInvitation(row rowScanner) (*model.Invitation, error) {
+	invitation := &model.Invitation{}
+	err := row.Scan(&invitation.ID, &invitation.OrgID, &invitation.Email, &invitation.Role, &invitation.InvitedBy,
+		&invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.CreatedAt)
+	return invitation, err
+}
+
+func (r *PostgresInvitationRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*model.Invitation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*model.Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (r *PostgresInvitationRepository) Create(ctx context.Context, invitation *model.Invitation) error {
+	query := `INSERT INTO organization_invitations (` + invitationColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query,
+		invitation.ID, invitation.OrgID, invitation.Email, invitation.Role, invitation.InvitedBy,
+		invitation.ExpiresAt, invitation.AcceptedAt, invitation.CreatedAt)
+	return err
+}
+
+func (r *PostgresInvitationRepository) FindByID(ctx context.Context, id string) (*model.Invitation, error) {
+	invitation, err := scanInvitation(r.db.QueryRowContext(ctx, `SELECT `+invitationColumns+` FROM organization_invitations WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("invitation not found")
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (r *PostgresInvitationRepository) FindByOrgID(ctx context.Context, orgID string) ([]*model.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM organization_invitations WHERE org_id = $1 ORDER BY created_at DESC`
+	return r.findMany(ctx, query, orgID)
+}
+
+func (r *PostgresInvitationRepository) FindByEmail(ctx context.Context, email string) ([]*model.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM organization_invitations WHERE email = LOWER($1) ORDER BY created_at DESC`
+	return r.findMany(ctx, query, email)
+}
+
+func (r *PostgresInvitationRepository) Update(ctx context.Context, invitation *model.Invitation) error {
+	query := `UPDATE organization_invitations SET role = $1, expires_at = $2, accepted_at = $3 WHERE id = $4`
+	result, err := r.db.ExecContext(ctx, query, invitation.Role, invitation.ExpiresAt, invitation.AcceptedAt, invitation.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("invitation not found")
+	}
+	return nil
+}
+
+func (r *PostgresInvitationRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM organization_invitations WHERE id = $1`, id)
+	return err
+}
+
+// Postgres BillingAccount repository implementation
+type PostgresBillingAccountRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresBillingAccountRepository(db *sql.DB) *PostgresBillingAccountRepository {
+	return &PostgresBillingAccountRepository{db: db}
+}
+
+// billingAccountColumns lists the billing_accounts table columns in the order findOne expects them
+const billingAccountColumns = `user_id, plan, provider, customer_id, status, updated_at`
+
+func (r *PostgresBillingAccountRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.BillingAccount, error) {
+	account := &model.BillingAccount{}
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&account.UserID, &account.Plan, &account.Provider, &account.CustomerID, &account.Status, &account.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("billing account not found")
+		}
+		return nil, err
+	}
+	return account, nil
+}
+
+func (r *PostgresBillingAccountRepository) Save(ctx context.Context, account *model.BillingAccount) error {
+	query := `
+		INSERT INTO billing_accounts (` + billingAccountColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			plan = EXCLUDED.plan,
+			provider = EXCLUDED.provider,
+			customer_id = EXCLUDED.customer_id,
+			status = EXCLUDED.status,
+			updated_at = EXCLUDED.updated_at`
+	_, err := r.db.ExecContext(ctx, query,
+		account.UserID, account.Plan, account.Provider, account.CustomerID, account.Status, account.UpdatedAt)
+	return err
+}
+
+func (r *PostgresBillingAccountRepository) FindByUserID(ctx context.Context, userID string) (*model.BillingAccount, error) {
+	return r.findOne(ctx, `SELECT `+billingAccountColumns+` FROM billing_accounts WHERE user_id = $1`, userID)
+}
+
+func (r *PostgresBillingAccountRepository) FindByCustomerID(ctx context.Context, provider, customerID string) (*model.BillingAccount, error) {
+	return r.findOne(ctx, `SELECT `+billingAccountColumns+` FROM billing_accounts WHERE provider = $1 AND customer_id = $2`, provider, customerID)
+}
+
+// Postgres Usage repository implementation
+type PostgresUsageRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUsageRepository(db *sql.DB) *PostgresUsageRepository {
+	return &PostgresUsageRepository{db: db}
+}
+
+func (r *PostgresUsageRepository) Add(ctx context.Context, userID, period, metric string, delta int) error {
+	query := `
+		INSERT INTO usage_counters (user_id, period, metric, count)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, period, metric) DO UPDATE SET
+			count = usage_counters.count + EXCLUDED.count`
+	_, err := r.db.ExecContext(ctx, query, userID, period, metric, delta)
+	return err
+}
+
+func (r *PostgresUsageRepository) FindByPeriod(ctx context.Context, userID, period string) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT metric, count FROM usage_counters WHERE user_id = $1 AND period = $2`, userID, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var metric string
+		var count int
+		if err := rows.Scan(&metric, &count); err != nil {
+			return nil, err
+		}
+		counts[metric] = count
+	}
+	return counts, rows.Err()
+}
+
+// Postgres EmailShare repository implementation
+type PostgresEmailShareRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresEmailShareRepository(db *sql.DB) *PostgresEmailShareRepository {
+	return &PostgresEmailShareRepository{db: db}
+}
+
+// shareColumns lists the email_shares table columns in the order scanShare expects them
+const shareColumns = `id, user_id, email_id, include_body, expires_at, revoked_at, created_at`
+
+func scanShare(row rowScanner) (*model.EmailShare, error) {
+	share := &model.EmailShare{}
+	err := row.Scan(&share.ID, &share.UserID, &share.EmailID, &share.IncludeBody, &share.ExpiresAt, &share.RevokedAt, &share.CreatedAt)
+	return share, err
+}
+
+func (r *PostgresEmailShareRepository) Create(ctx context.Context, share *model.EmailShare) error {
+	query := `INSERT INTO email_shares (` + shareColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.ExecContext(ctx, query,
+		share.ID, share.UserID, share.EmailID, share.IncludeBody, share.ExpiresAt, share.RevokedAt, share.CreatedAt)
+	return err
+}
+
+func (r *PostgresEmailShareRepository) FindByID(ctx context.Context, id string) (*model.EmailShare, error) {
+	share, err := scanShare(r.db.QueryRowContext(ctx, `SELECT `+shareColumns+` FROM email_shares WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("share not found")
+		}
+		return nil, err
+	}
+	return share, nil
+}
+
+func (r *PostgresEmailShareRepository) FindByEmail(ctx context.Context, userID, emailID string) ([]*model.EmailShare, error) {
+	query := `SELECT ` + shareColumns + ` FROM email_shares WHERE user_id = $1 AND email_id = $2 ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []*model.EmailShare
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (r *PostgresEmailShareRepository) Update(ctx context.Context, share *model.EmailShare) error {
+	query := `UPDATE email_shares SET include_body = $1, expires_at = $2, revoked_at = $3 WHERE id = $4`
+	result, err := r.db.ExecContext(ctx, query, share.IncludeBody, share.ExpiresAt, share.RevokedAt, share.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("share not found")
+	}
+	return nil
+}
+
+// Postgres Automation repository implementation
+type PostgresAutomationRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAutomationRepository(db *sql.DB) *PostgresAutomationRepository {
+	return &PostgresAutomationRepository{db: db}
+}
+
+// automationColumns lists the automations table columns in the order scanAutomation expects them
+const automationColumns = `id, user_id, org_id, category_id, action, label, delay_days, enabled, last_run_at, created_at, updated_at`
+
+func scanAutomation(row rowScanner) (*model.Automation, error) {
+	automation := &model.Automation{}
+	err := row.Scan(
+		&automation.ID, &automation.UserID, &automation.OrgID, &automation.CategoryID, &automation.Action, &automation.Label,
+		&automation.DelayDays, &automation.Enabled, &automation.LastRunAt,
+		&automation.CreatedAt, &automation.UpdatedAt)
+	return automation, err
+}
+
+func (r *PostgresAutomationRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*model.Automation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var automations []*model.Automation
+	for rows.Next() {
+		automation, err := scanAutomation(rows)
+		if err != nil {
+			return nil, err
+		}
+		automations = append(automations, automation)
+	}
+
+	return automations, rows.Err()
+}
+
+func (r *PostgresAutomationRepository) Create(ctx context.Context, automation *model.Automation) error {
+	query := `
+		INSERT INTO automations (` + automationColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := r.db.ExecContext(ctx, query,
+		automation.ID, automation.UserID, automation.OrgID, automation.CategoryID, automation.Action, automation.Label,
+		automation.DelayDays, automation.Enabled, automation.LastRunAt,
+		automation.CreatedAt, automation.UpdatedAt)
+	return err
+}
+
+func (r *PostgresAutomationRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.Automation, error) {
+	automation, err := scanAutomation(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("automation not found")
+		}
+		return nil, err
+	}
+	return automation, nil
+}
+
+func (r *PostgresAutomationRepository) FindByID(ctx context.Context, id string) (*model.Automation, error) {
+	query := `SELECT ` + automationColumns + ` FROM automations WHERE id = $1`
+	return r.findOne(ctx, query, id)
+}
+
+func (r *PostgresAutomationRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.Automation, error) {
+	query := `SELECT ` + automationColumns + ` FROM automations WHERE id = $1 AND user_id = $2`
+	return r.findOne(ctx, query, id, userID)
+}
+
+func (r *PostgresAutomationRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Automation, error) {
+	query := `SELECT ` + automationColumns + ` FROM automations WHERE user_id = $1 ORDER BY created_at, id`
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresAutomationRepository) FindByOrgID(ctx context.Context, orgID string) ([]*model.Automation, error) {
+	query := `SELECT ` + automationColumns + ` FROM automations WHERE org_id = $1 ORDER BY created_at, id`
+	return r.findMany(ctx, query, orgID)
+}
+
+func (r *PostgresAutomationRepository) FindAll(ctx context.Context) ([]*model.Automation, error) {
+	query := `SELECT ` + automationColumns + ` FROM automations ORDER BY created_at, id`
+	return r.findMany(ctx, query)
+}
+
+func (r *PostgresAutomationRepository) Update(ctx context.Context, automation *model.Automation) error {
+	query := `
+		UPDATE automations SET category_id=$1, action=$2, label=$3, delay_days=$4, enabled=$5,
+		last_run_at=$6, updated_at=$7 WHERE id=$8`
+	result, err := r.db.ExecContext(ctx, query,
+		automation.CategoryID, automation.Action, automation.Label, automation.DelayDays, automation.Enabled,
+		automation.LastRunAt, automation.UpdatedAt, automation.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("automation not found")
+	}
+	return nil
+}
+
+func (r *PostgresAutomationRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM automations WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Postgres CleanupPolicy repository implementation
+type PostgresCleanupPolicyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCleanupPolicyRepository(db *sql.DB) *PostgresCleanupPolicyRepository {
+	return &PostgresCleanupPolicyRepository{db: db}
+}
+
+// cleanupPolicyColumns lists the cleanup_policies table columns in the order scanCleanupPolicy expects them
+const cleanupPolicyColumns = `id, user_id, name, category_id, unread, older_than_days, action, schedule, enabled, last_run_at, created_at, updated_at`
+
+func scanCleanupPolicy(row rowScanner) (*model.CleanupPolicy, error) {
+	policy := &model.CleanupPolicy{}
+	err := row.Scan(
+		&policy.ID, &policy.UserID, &policy.Name, &policy.CategoryID, &policy.Unread, &policy.OlderThanDays,
+		&policy.Action, &policy.Schedule, &policy.Enabled, &policy.LastRunAt,
+		&policy.CreatedAt, &policy.UpdatedAt)
+	return policy, err
+}
+
+func (r *PostgresCleanupPolicyRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*model.CleanupPolicy, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []*model.CleanupPolicy
+	for rows.Next() {
+		policy, err := scanCleanupPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+func (r *PostgresCleanupPolicyRepository) Create(ctx context.Context, policy *model.CleanupPolicy) error {
+	query := `
+		INSERT INTO cleanup_policies (` + cleanupPolicyColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err := r.db.ExecContext(ctx, query,
+		policy.ID, policy.UserID, policy.Name, policy.CategoryID, policy.Unread, policy.OlderThanDays,
+		policy.Action, policy.Schedule, policy.Enabled, policy.LastRunAt,
+		policy.CreatedAt, policy.UpdatedAt)
+	return err
+}
+
+func (r *PostgresCleanupPolicyRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.CleanupPolicy, error) {
+	query := `SELECT ` + cleanupPolicyColumns + ` FROM cleanup_policies WHERE id = $1 AND user_id = $2`
+	policy, err := scanCleanupPolicy(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("cleanup policy not found")
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (r *PostgresCleanupPolicyRepository) FindByUserID(ctx context.Context, userID string) ([]*model.CleanupPolicy, error) {
+	query := `SELECT ` + cleanupPolicyColumns + ` FROM cleanup_policies WHERE user_id = $1 ORDER BY created_at, id`
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresCleanupPolicyRepository) FindAll(ctx context.Context) ([]*model.CleanupPolicy, error) {
+	query := `SELECT ` + cleanupPolicyColumns + ` FROM cleanup_policies ORDER BY created_at, id`
+	return r.findMany(ctx, query)
+}
+
+func (r *PostgresCleanupPolicyRepository) Update(ctx context.Context, policy *model.CleanupPolicy) error {
+	query := `
+		UPDATE cleanup_policies SET name=$1, category_id=$2, unread=$3, older_than_days=$4, action=$5,
+		schedule=$6, enabled=$7, last_run_at=$8, updated_at=$9 WHERE id=$10`
+	result, err := r.db.ExecContext(ctx, query,
+		policy.Name, policy.CategoryID, policy.Unread, policy.OlderThanDays, policy.Action,
+		policy.Schedule, policy.Enabled, policy.LastRunAt, policy.UpdatedAt, policy.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("cleanup policy not found")
+	}
+	return nil
+}
+
+func (r *PostgresCleanupPolicyRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM cleanup_policies WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Postgres CleanupRun repository implementation
+type PostgresCleanupRunRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCleanupRunRepository(db *sql.DB) *PostgresCleanupRunRepository {
+	return &PostgresCleanupRunRepository{db: db}
+}
+
+func (r *PostgresCleanupRunRepository) Create(ctx context.Context, run *model.CleanupRun) error {
+	query := `
+		INSERT INTO cleanup_runs (id, policy_id, user_id, policy_name, action, matched, cleaned, error, ran_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.ExecContext(ctx, query,
+		run.ID, run.PolicyID, run.UserID, run.PolicyName, run.Action, run.Matched, run.Cleaned, run.Error, run.RanAt)
+	return err
+}
+
+func (r *PostgresCleanupRunRepository) FindByPolicyID(ctx context.Context, policyID string, limit int) ([]*model.CleanupRun, error) {
+	query := `
+		SELECT id, policy_id, user_id, policy_name, action, matched, cleaned, error, ran_at
+		FROM cleanup_runs WHERE policy_id = $1 ORDER BY ran_at DESC, id DESC`
+	args := []interface{}{policyID}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*model.CleanupRun
+	for rows.Next() {
+		run := &model.CleanupRun{}
+		if err := rows.Scan(&run.ID, &run.PolicyID, &run.UserID, &run.PolicyName, &run.Action,
+			&run.Matched, &run.Cleaned, &run.Error, &run.RanAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+func (r *PostgresCleanupRunRepository) DeleteByPolicyID(ctx context.Context, policyID string) error {
+	query := `DELETE FROM cleanup_runs WHERE policy_id = $1`
+	_, err := r.db.ExecContext(ctx, query, policyID)
+	return err
+}
+
+// Postgres AICall repository implementation
+type PostgresAICallRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAICallRepository(db *sql.DB) *PostgresAICallRepository {
+	return &PostgresAICallRepository{db: db}
+}
+
+// aiCallColumns lists the ai_calls table columns in the order scanAICall expects them
+const aiCallColumns = `id, provider, model, operation, system, prompt, max_tokens, response, error, duration_ms, created_at`
+
+func scanAICall(row rowScanner) (*model.AICall, error) {
+	call := &model.AICall{}
+	err := row.Scan(&call.ID, &call.Provider, &call.Model, &call.Operation, &call.System, &call.Prompt,
+		&call.MaxTokens, &call.Response, &call.Error, &call.DurationMS, &call.CreatedAt)
+	return call, err
+}
+
+func (r *PostgresAICallRepository) Create(ctx context.Context, call *model.AICall) error {
+	query := `
+		INSERT INTO ai_calls (` + aiCallColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := r.db.ExecContext(ctx, query,
+		call.ID, call.Provider, call.Model, call.Operation, call.System, call.Prompt,
+		call.MaxTokens, call.Response, call.Error, call.DurationMS, call.CreatedAt)
+	return err
+}
+
+func (r *PostgresAICallRepository) FindByID(ctx context.Context, id string) (*model.AICall, error) {
+	query := `SELECT ` + aiCallColumns + ` FROM ai_calls WHERE id = $1`
+	call, err := scanAICall(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("AI call not found")
+		}
+		return nil, err
+	}
+	return call, nil
+}
+
+func (r *PostgresAICallRepository) FindRecent(ctx context.Context, limit int) ([]*model.AICall, error) {
+	query := `SELECT ` + aiCallColumns + ` FROM ai_calls ORDER BY created_at DESC, id DESC`
+	var args []interface{}
+	if limit > 0 {
+		query += ` LIMIT $1`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []*model.AICall
+	for rows.Next() {
+		call, err := scanAICall(rows)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, rows.Err()
+}
+
+func (r *PostgresAICallRepository) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM ai_calls WHERE created_at < $1`
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// Postgres ExperimentResult repository implementation
+type PostgresExperimentResultRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresExperimentResultRepository(db *sql.DB) *PostgresExperimentResultRepository {
+	return &PostgresExperimentResultRepository{db: db}
+}
+
+// experimentResultColumns lists the experiment_results table columns in the order scanExperimentResult expects them
+const experimentResultColumns = `id, experiment, arm, email_id, user_id, category_id, corrected_category_id, classified_at, corrected_at`
+
+func scanExperimentResult(row rowScanner) (*model.ExperimentResult, error) {
+	result := &model.ExperimentResult{}
+	err := row.Scan(&result.ID, &result.Experiment, &result.Arm, &result.EmailID, &result.UserID,
+		&result.CategoryID, &result.CorrectedCategoryID, &result.ClassifiedAt, &result.CorrectedAt)
+	return result, err
+}
+
+func (r *PostgresExperimentResultRepository) Save(ctx context.Context, result *model.ExperimentResult) error {
+	query := `
+		INSERT INTO experiment_results (` + experimentResultColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (experiment, email_id) DO UPDATE SET
+			id = EXCLUDED.id, arm = EXCLUDED.arm, user_id = EXCLUDED.user_id, category_id = EXCLUDED.category_id,
+			corrected_category_id = EXCLUDED.corrected_category_id, classified_at = EXCLUDED.classified_at,
+			corrected_at = EXCLUDED.corrected_at`
+	_, err := r.db.ExecContext(ctx, query,
+		result.ID, result.Experiment, result.Arm, result.EmailID, result.UserID,
+		result.CategoryID, result.CorrectedCategoryID, result.ClassifiedAt, result.CorrectedAt)
+	return err
+}
+
+func (r *PostgresExperimentResultRepository) FindByEmailID(ctx context.Context, experiment, emailID string) (*model.ExperimentResult, error) {
+	query := `SELECT ` + experimentResultColumns + ` FROM experiment_results WHERE experiment = $1 AND email_id = $2`
+	result, err := scanExperimentResult(r.db.QueryRowContext(ctx, query, experiment, emailID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("experiment result not found")
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *PostgresExperimentResultRepository) CountByArm(ctx context.Context, experiment string) ([]*model.ExperimentArmStats, error) {
+	query := `
+		SELECT arm, COUNT(*), COUNT(*) FILTER (WHERE corrected_category_id <> '')
+		FROM experiment_results WHERE experiment = $1 GROUP BY arm ORDER BY arm`
+	rows, err := r.db.QueryContext(ctx, query, experiment)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*model.ExperimentArmStats
+	for rows.Next() {
+		arm := &model.ExperimentArmStats{}
+		if err := rows.Scan(&arm.Name, &arm.Classified, &arm.Corrected); err != nil {
+			return nil, err
+		}
+		stats = append(stats, arm)
+	}
+
+	return stats, rows.Err()
+}
+
+// Postgres SavedView repository implementation
+type PostgresSavedViewRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSavedViewRepository(db *sql.DB) *PostgresSavedViewRepository {
+	return &PostgresSavedViewRepository{db: db}
+}
+
+// savedViewColumns lists the saved_views table columns in the order scanSavedView expects them
+const savedViewColumns = `id, user_id, name, category_id, sender, received_after, received_before, unread, query, archive, created_at, updated_at`
+
+func scanSavedView(row rowScanner) (*model.SavedView, error) {
+	view := &model.SavedView{}
+	err := row.Scan(
+		&view.ID, &view.UserID, &view.Name, &view.Filter.CategoryID, &view.Filter.Sender,
+		&view.Filter.After, &view.Filter.Before, &view.Filter.Unread, &view.Filter.Query, &view.Filter.Archive,
+		&view.CreatedAt, &view.UpdatedAt)
+	return view, err
+}
+
+func (r *PostgresSavedViewRepository) Create(ctx context.Context, view *model.SavedView) error {
+	query := `
+		INSERT INTO saved_views (` + savedViewColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err := r.db.ExecContext(ctx, query,
+		view.ID, view.UserID, view.Name, view.Filter.CategoryID, view.Filter.Sender,
+		view.Filter.After, view.Filter.Before, view.Filter.Unread, view.Filter.Query, string(view.Filter.Archive),
+		view.CreatedAt, view.UpdatedAt)
+	return err
+}
+
+func (r *PostgresSavedViewRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.SavedView, error) {
+	query := `SELECT ` + savedViewColumns + ` FROM saved_views WHERE id = $1 AND user_id = $2`
+	view, err := scanSavedView(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("view not found")
+		}
+		return nil, err
+	}
+	return view, nil
+}
+
+func (r *PostgresSavedViewRepository) FindByUserID(ctx context.Context, userID string) ([]*model.SavedView, error) {
+	query := `SELECT ` + savedViewColumns + ` FROM saved_views WHERE user_id = $1 ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []*model.SavedView
+	for rows.Next() {
+		view, err := scanSavedView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+func (r *PostgresSavedViewRepository) Update(ctx context.Context, view *model.SavedView) error {
+	query := `
+		UPDATE saved_views SET name=$1, category_id=$2, sender=$3, received_after=$4, received_before=$5,
+		unread=$6, query=$7, archive=$8, updated_at=$9 WHERE id=$10`
+	result, err := r.db.ExecContext(ctx, query,
+		view.Name, view.Filter.CategoryID, view.Filter.Sender, view.Filter.After, view.Filter.Before,
+		view.Filter.Unread, view.Filter.Query, string(view.Filter.Archive), view.UpdatedAt, view.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("view not found")
+	}
+	return nil
+}
+
+func (r *PostgresSavedViewRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM saved_views WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Postgres Shipment repository implementation
+type PostgresShipmentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresShipmentRepository(db *sql.DB) *PostgresShipmentRepository {
+	return &PostgresShipmentRepository{db: db}
+}
+
+// shipmentColumns lists the shipments table columns in the order scanShipment expects them
+const shipmentColumns = `id, user_id, email_id, carrier, tracking_number, tracking_url, status, delivered_at, checked_at, created_at, updated_at`
+
+func scanShipment(row rowScanner) (*model.Shipment, error) {
+	shipment := &model.Shipment{}
+	err := row.Scan(
+		&shipment.ID, &shipment.UserID, &shipment.EmailID, &shipment.Carrier, &shipment.TrackingNumber, &shipment.TrackingURL,
+		&shipment.Status, &shipment.DeliveredAt, &shipment.CheckedAt, &shipment.CreatedAt, &shipment.UpdatedAt)
+	return shipment, err
+}
+
+func (r *PostgresShipmentRepository) findMany(ctx context.Context, query string, args ...interface{}) ([]*model.Shipment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []*model.Shipment
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, rows.Err()
+}
+
+func (r *PostgresShipmentRepository) Create(ctx context.Context, shipment *model.Shipment) error {
+	query := `
+		INSERT INTO shipments (` + shipmentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := r.db.ExecContext(ctx, query,
+		shipment.ID, shipment.UserID, shipment.EmailID, shipment.Carrier, shipment.TrackingNumber, shipment.TrackingURL,
+		shipment.Status, shipment.DeliveredAt, shipment.CheckedAt, shipment.CreatedAt, shipment.UpdatedAt)
+	return err
+}
+
+func (r *PostgresShipmentRepository) Update(ctx context.Context, shipment *model.Shipment) error {
+	query := `
+		UPDATE shipments SET email_id = $1, carrier = $2, tracking_url = $3, status = $4,
+		delivered_at = $5, checked_at = $6, updated_at = $7 WHERE id = $8`
+	result, err := r.db.ExecContext(ctx, query,
+		shipment.EmailID, shipment.Carrier, shipment.TrackingURL, shipment.Status,
+		shipment.DeliveredAt, shipment.CheckedAt, shipment.UpdatedAt, shipment.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("shipment not found")
+	}
+	return nil
+}
+
+func (r *PostgresShipmentRepository) FindByTrackingNumber(ctx context.Context, userID, trackingNumber string) (*model.Shipment, error) {
+	query := `SELECT ` + shipmentColumns + ` FROM shipments WHERE user_id = $1 AND tracking_number = $2`
+	shipment, err := scanShipment(r.db.QueryRowContext(ctx, query, userID, trackingNumber))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("shipment not found")
+		}
+		return nil, err
+	}
+	return shipment, nil
+}
+
+func (r *PostgresShipmentRepository) FindByUserID(ctx context.Context, userID string) ([]*model.Shipment, error) {
+	query := `SELECT ` + shipmentColumns + ` FROM shipments WHERE user_id = $1 ORDER BY updated_at DESC, id`
+	return r.findMany(ctx, query, userID)
+}
+
+func (r *PostgresShipmentRepository) FindUndelivered(ctx context.Context) ([]*model.Shipment, error) {
+	query := `SELECT ` + shipmentColumns + ` FROM shipments WHERE status <> $1 ORDER BY id`
+	return r.findMany(ctx, query, model.ShipmentDelivered)
+}
+
+// Postgres SenderProfile repository implementation
+type PostgresSenderProfileRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSenderProfileRepository(db *sql.DB) *PostgresSenderProfileRepository {
+	return &PostgresSenderProfileRepository{db: db}
+}
+
+func (r *PostgresSenderProfileRepository) FindByAddress(ctx context.Context, userID, address string) (*model.SenderProfile, error) {
+	query := `SELECT user_id, address, description, email_count, generated_at FROM sender_profiles WHERE user_id = $1 AND address = $2`
+	profile := &model.SenderProfile{}
+	err := r.db.QueryRowContext(ctx, query, userID, address).Scan(
+		&profile.UserID, &profile.Address, &profile.Description, &profile.EmailCount, &profile.GeneratedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("sender profile not found")
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (r *PostgresSenderProfileRepository) Save(ctx context.Context, profile *model.SenderProfile) error {
+	query := `
+		INSERT INTO sender_profiles (user_id, address, description, email_count, generated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, address) DO UPDATE SET
+			description = EXCLUDED.description,
+			email_count = EXCLUDED.email_count,
+			generated_at = EXCLUDED.generated_at`
+	_, err := r.db.ExecContext(ctx, query, profile.UserID, profile.Address, profile.Description, profile.EmailCount, profile.GeneratedAt)
+	return err
+}
+
+// Postgres VIPSender repository implementation
+type PostgresVIPSenderRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresVIPSenderRepository(db *sql.DB) *PostgresVIPSenderRepository {
+	return &PostgresVIPSenderRepository{db: db}
+}
+
+func (r *PostgresVIPSenderRepository) Save(ctx context.Context, vip *model.VIPSender) error {
+	query := `
+		INSERT INTO vip_senders (user_id, address, name, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, address) DO UPDATE SET name = EXCLUDED.name
+		RETURNING created_at`
+	return r.db.QueryRowContext(ctx, query, vip.UserID, vip.Address, vip.Name, vip.CreatedAt).Scan(&vip.CreatedAt)
+}
+
+func (r *PostgresVIPSenderRepository) Delete(ctx context.Context, userID, address string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM vip_senders WHERE user_id = $1 AND address = $2`, userID, address)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("VIP sender not found")
+	}
+	return nil
+}
+
+func (r *PostgresVIPSenderRepository) FindByUserID(ctx context.Context, userID string) ([]*model.VIPSender, error) {
+	query := `SELECT user_id, address, name, created_at FROM vip_senders WHERE user_id = $1 ORDER BY address`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vips []*model.VIPSender
+	for rows.Next() {
+		vip := &model.VIPSender{}
+		if err := rows.Scan(&vip.UserID, &vip.Address, &vip.Name, &vip.CreatedAt); err != nil {
+			return nil, err
+		}
+		vips = append(vips, vip)
+	}
+	return vips, rows.Err()
+}
+
+// Postgres Report repository implementation
+type PostgresReportRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresReportRepository(db *sql.DB) *PostgresReportRepository {
+	return &PostgresReportRepository{db: db}
+}
+
+// reportColumns lists the reports table columns in the order scanReport expects them
+const reportColumns = `id, user_id, period_start, period_end, stats, created_at`
+
+// scanReport reads a report row; the stats are stored as a JSON document
+func scanReport(row rowScanner) (*model.InboxReport, error) {
+	report := &model.InboxReport{}
+	var stats []byte
+	if err := row.Scan(&report.ID, &report.UserID, &report.PeriodStart, &report.PeriodEnd, &stats, &report.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(stats, &report.Stats); err != nil {
+		return nil, fmt.Errorf("failed to decode report stats: %w", err)
+	}
+	return report, nil
+}
+
+func (r *PostgresReportRepository) Save(ctx context.Context, report *model.InboxReport) error {
+	stats, err := json.Marshal(report.Stats)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO reports (` + reportColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, period_start) DO UPDATE SET
+			period_end = EXCLUDED.period_end,
+			stats = EXCLUDED.stats,
+			created_at = EXCLUDED.created_at
+		RETURNING id`
+	return r.db.QueryRowContext(ctx, query,
+		report.ID, report.UserID, report.PeriodStart, report.PeriodEnd, stats, report.CreatedAt).Scan(&report.ID)
+}
+
+func (r *PostgresReportRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.InboxReport, error) {
+	report, err := scanReport(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("report not found")
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *PostgresReportRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.InboxReport, error) {
+	return r.findOne(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = $1 AND user_id = $2`, id, userID)
+}
+
+func (r *PostgresReportRepository) FindByPeriod(ctx context.Context, userID string, periodStart time.Time) (*model.InboxReport, error) {
+	return r.findOne(ctx, `SELECT `+reportColumns+` FROM reports WHERE user_id = $1 AND period_start = $2`, userID, periodStart)
+}
+
+func (r *PostgresReportRepository) FindByUserID(ctx context.Context, userID string, limit int) ([]*model.InboxReport, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE user_id = $1 ORDER BY period_start DESC` + limitClause(limit)
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*model.InboxReport
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// Postgres UnsubscribeAttempt repository implementation
+type PostgresUnsubscribeAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUnsubscribeAttemptRepository(db *sql.DB) *PostgresUnsubscribeAttemptRepository {
+	return &PostgresUnsubscribeAttemptRepository{db: db}
+}
+
+// unsubscribeAttemptColumns lists the unsubscribe_attempts table columns in the order
+// scanUnsubscribeAttempt expects them
+const unsubscribeAttemptColumns = `id, user_id, email_id, sender_address, url, succeeded, error, dark_patterns, selections, created_at,
+	ineffective_at, resurfaced_email_ids, escalation, manual_action_url`
+
+func scanUnsubscribeAttempt(row rowScanner) (*model.UnsubscribeAttempt, error) {
+	attempt := &model.UnsubscribeAttempt{}
+	err := row.Scan(&attempt.ID, &attempt.UserID, &attempt.EmailID, &attempt.SenderAddress, &attempt.URL,
+		&attempt.Succeeded, &attempt.Error, pq.Array(&attempt.DarkPatterns), pq.Array(&attempt.Selections), &attempt.CreatedAt,
+		&attempt.IneffectiveAt, pq.Array(&attempt.ResurfacedEmailIDs), &attempt.Escalation, &attempt.ManualActionURL)
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (r *PostgresUnsubscribeAttemptRepository) Create(ctx context.Context, attempt *model.UnsubscribeAttempt) error {
+	query := `
+		INSERT INTO unsubscribe_attempts (` + unsubscribeAttemptColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err := r.db.ExecContext(ctx, query,
+		attempt.ID, attempt.UserID, attempt.EmailID, attempt.SenderAddress, attempt.URL,
+		attempt.Succeeded, attempt.Error, pq.Array(attempt.DarkPatterns), pq.Array(attempt.Selections), attempt.CreatedAt,
+		attempt.IneffectiveAt, pq.Array(attempt.ResurfacedEmailIDs), attempt.Escalation, attempt.ManualActionURL)
+	return err
+}
+
+func (r *PostgresUnsubscribeAttemptRepository) Update(ctx context.Context, attempt *model.UnsubscribeAttempt) error {
+	query := `UPDATE unsubscribe_attempts SET succeeded = $1, error = $2, ineffective_at = $3, resurfaced_email_ids = $4, escalation = $5
+		WHERE id = $6`
+	result, err := r.db.ExecContext(ctx, query,
+		attempt.Succeeded, attempt.Error, attempt.IneffectiveAt, pq.Array(attempt.ResurfacedEmailIDs), attempt.Escalation, attempt.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NotFound("unsubscribe attempt not found")
+	}
+	return nil
+}
+
+func (r *PostgresUnsubscribeAttemptRepository) findOne(ctx context.Context, query string, args ...interface{}) (*model.UnsubscribeAttempt, error) {
+	attempt, err := scanUnsubscribeAttempt(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NotFound("unsubscribe attempt not found")
+		}
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (r *PostgresUnsubscribeAttemptRepository) FindByIDAndUser(ctx context.Context, id, userID string) (*model.UnsubscribeAttempt, error) {
+	query := `SELECT ` + unsubscribeAttemptColumns + ` FROM unsubscribe_attempts WHERE id = $1 AND user_id = $2`
+	return r.findOne(ctx, query, id, userID)
+}
+
+func (r *PostgresUnsubscribeAttemptRepository) FindLatestSucceeded(ctx context.Context, userID, senderAddress string) (*model.UnsubscribeAttempt, error) {
+	query := `SELECT ` + unsubscribeAttemptColumns + ` FROM unsubscribe_attempts
+		WHERE user_id = $1 AND sender_address = $2 AND succeeded ORDER BY created_at DESC, id LIMIT 1`
+	return r.findOne(ctx, query, userID, senderAddress)
+}
+
+func (r *PostgresUnsubscribeAttemptRepository) FindByUserID(ctx context.Context, userID string, limit int) ([]*model.UnsubscribeAttempt, error) {
+	query := `SELECT ` + unsubscribeAttemptColumns + ` FROM unsubscribe_attempts WHERE user_id = $1
+		ORDER BY created_at DESC, id` + limitClause(limit)
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*model.UnsubscribeAttempt
+	for rows.Next() {
+		attempt, err := scanUnsubscribeAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// duplicateCategories pairs every category sharing its owner and name with an older one with
+// the oldest of them, the one kept
+const duplicateCategories = `SELECT id, keep_id FROM (
+	SELECT id, FIRST_VALUE(id) OVER (PARTITION BY user_id, org_id, LOWER(name) ORDER BY created_at, id) AS keep_id
+	FROM categories) ranked
+	WHERE id <> keep_id`
+
+// InitializeDatabase creates the necessary tables
+func InitializeDatabase(db *sql.DB) error {
+	tables, migrations := schema()
+	for _, table := range tables {
+		_, err := db.Exec(table)
+		if err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+
+	// A server still running an older build mustn't record fewer migrations than were run
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (id INTEGER PRIMARY KEY, version INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	_, err := db.Exec(`INSERT INTO schema_version (id, version) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET version = GREATEST(schema_version.version, EXCLUDED.version)`, len(migrations))
+	if err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
+
+// ErrSchemaOutdated is returned by CheckSchema when the database misses migrations
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+// CheckSchema tells whether the database can be reached and InitializeDatabase has run every
+// migration of this build on it
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	_, migrations := schema()
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT version FROM schema_version WHERE id = 1`).Scan(&version)
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Code == "42P01") {
+		return fmt.Errorf("%w: no migration was recorded", ErrSchemaOutdated)
+	}
+	if err != nil {
+		return err
+	}
+	if version < len(migrations) {
+		return fmt.Errorf("%w: %d of %d migrations applied", ErrSchemaOutdated, version, len(migrations))
+	}
+	return nil
+}
+
+// schema returns the statements creating the tables, then those migrating them since; every
+// statement can run again
+func schema() (tables, migrations []string) {
+	tables = []string{
+		`CREATE TABLE IF NOT EXISTS users (
 			id VARCHAR(255) PRIMARY KEY,
 			google_id VARCHAR(255) UNIQUE NOT NULL,
 			email VARCHAR(255) NOT NULL,
@@ -362,7 +2552,7 @@ func InitializeDatabase(db *sql.DB) error {
 		`CREATE TABLE IF NOT EXISTS emails (
 			id VARCHAR(255) PRIMARY KEY,
 			user_id VARCHAR(255) NOT NULL,
-			gmail_id VARCHAR(255) UNIQUE NOT NULL,
+			gmail_id VARCHAR(255) NOT NULL,
 			from_email TEXT,
 			subject TEXT NOT NULL,
 			body TEXT,
@@ -375,12 +2565,322 @@ func InitializeDatabase(db *sql.DB) error {
 		)`,
 	}
 
-	for _, table := range tables {
-		_, err := db.Exec(table)
-		if err != nil {
-			return fmt.Errorf("failed to create table: %w", err)
-		}
+	// Columns added after the initial schema; ADD COLUMN IF NOT EXISTS keeps this idempotent
+	migrations = []string{
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS from_name TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS from_address TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS to_addresses TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS cc_addresses TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS reply_to TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS message_id TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS sent_at TIMESTAMP`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_message_id ON emails (user_id, message_id)`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS body_truncated BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_user_received ON emails (user_id, received_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_category ON emails (category_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_emails_user_gmail_unique ON emails (user_id, gmail_id)`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_deleted ON emails (deleted_at) WHERE deleted_at IS NOT NULL`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS body_pruned BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS locally_archived BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS unread BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_user_sender ON emails (user_id, from_address)`,
+		`CREATE TABLE IF NOT EXISTS retention_policies (
+			user_id VARCHAR(255) PRIMARY KEY,
+			body_retention_days INTEGER NOT NULL DEFAULT 0,
+			retention_days INTEGER NOT NULL DEFAULT 0,
+			max_emails_per_category INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS granted_scopes TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_categories_user ON categories (user_id)`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS starred BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS blocked_senders TEXT NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS automations (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			category_id VARCHAR(255) NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			label TEXT NOT NULL DEFAULT '',
+			delay_days INTEGER NOT NULL DEFAULT 0,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			last_run_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_automations_user ON automations (user_id)`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS important BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS push_subscriptions (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			endpoint TEXT UNIQUE NOT NULL,
+			p256dh TEXT NOT NULL,
+			auth TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions (user_id)`,
+		`CREATE TABLE IF NOT EXISTS pending_events (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			event_type VARCHAR(64) NOT NULL,
+			payload TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_pending_events_user ON pending_events (user_id, created_at)`,
+		`CREATE TABLE IF NOT EXISTS email_shares (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			email_id VARCHAR(255) NOT NULL,
+			include_body BOOLEAN NOT NULL DEFAULT FALSE,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_email_shares_email ON email_shares (email_id)`,
+		`CREATE TABLE IF NOT EXISTS telegram_links (
+			user_id VARCHAR(255) PRIMARY KEY,
+			chat_id BIGINT UNIQUE NOT NULL,
+			last_email_id VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS notification_preferences (
+			user_id VARCHAR(255) PRIMARY KEY,
+			quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '',
+			quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '',
+			time_zone VARCHAR(100) NOT NULL DEFAULT '',
+			event_types TEXT NOT NULL DEFAULT '',
+			min_priority VARCHAR(10) NOT NULL DEFAULT 'low',
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS organizations (
+			id VARCHAR(255) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS organization_members (
+			user_id VARCHAR(255) PRIMARY KEY,
+			org_id VARCHAR(255) NOT NULL,
+			role VARCHAR(20) NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_organization_members_org ON organization_members (org_id)`,
+		`CREATE TABLE IF NOT EXISTS organization_invitations (
+			id VARCHAR(255) PRIMARY KEY,
+			org_id VARCHAR(255) NOT NULL,
+			email VARCHAR(255) NOT NULL,
+			role VARCHAR(20) NOT NULL,
+			invited_by VARCHAR(255) NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			accepted_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_organization_invitations_email ON organization_invitations (email)`,
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS org_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE automations ADD COLUMN IF NOT EXISTS org_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS billing_accounts (
+			user_id VARCHAR(255) PRIMARY KEY,
+			plan VARCHAR(50) NOT NULL,
+			provider VARCHAR(50) NOT NULL DEFAULT '',
+			customer_id VARCHAR(255) NOT NULL DEFAULT '',
+			status VARCHAR(50) NOT NULL DEFAULT '',
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_billing_accounts_customer ON billing_accounts (provider, customer_id)`,
+		`CREATE TABLE IF NOT EXISTS usage_counters (
+			user_id VARCHAR(255) NOT NULL,
+			period VARCHAR(7) NOT NULL,
+			metric VARCHAR(50) NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (user_id, period, metric)
+		)`,
+		// Category names are unique per owner; duplicates created before are merged into the oldest
+		`UPDATE emails SET category_id = duplicates.keep_id FROM (` + duplicateCategories + `) duplicates
+			WHERE emails.category_id = duplicates.id`,
+		`UPDATE automations SET category_id = duplicates.keep_id FROM (` + duplicateCategories + `) duplicates
+			WHERE automations.category_id = duplicates.id`,
+		`DELETE FROM categories WHERE id IN (SELECT id FROM (` + duplicateCategories + `) duplicates)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_owner_name ON categories (user_id, org_id, LOWER(name))`,
+		`CREATE TABLE IF NOT EXISTS saved_views (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			category_id VARCHAR(255) NOT NULL DEFAULT '',
+			sender TEXT NOT NULL DEFAULT '',
+			received_after TIMESTAMP,
+			received_before TIMESTAMP,
+			unread BOOLEAN,
+			query TEXT NOT NULL DEFAULT '',
+			archive VARCHAR(10) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_saved_views_user ON saved_views (user_id)`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS triaged_at TIMESTAMP`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS triage_action VARCHAR(50) NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_user_untriaged ON emails (user_id, received_at) WHERE triaged_at IS NULL`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS unsubscribed_at TIMESTAMP`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS system_flag VARCHAR(20) NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS otp_code VARCHAR(20) NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS otp_expires_at TIMESTAMP`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS archive_system_emails BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS shipments (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			email_id VARCHAR(255) NOT NULL,
+			carrier VARCHAR(20) NOT NULL,
+			tracking_number VARCHAR(64) NOT NULL,
+			tracking_url TEXT NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL,
+			delivered_at TIMESTAMP,
+			checked_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			UNIQUE (user_id, tracking_number)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_shipments_undelivered ON shipments (status) WHERE status <> 'delivered'`,
+		`CREATE TABLE IF NOT EXISTS sender_profiles (
+			user_id VARCHAR(255) NOT NULL,
+			address VARCHAR(255) NOT NULL,
+			description TEXT NOT NULL,
+			email_count INTEGER NOT NULL,
+			generated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, address)
+		)`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS security_flag VARCHAR(30) NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_user_security ON emails (user_id, received_at DESC) WHERE security_flag <> ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS priority VARCHAR(20) NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS snippet TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_user_priority ON emails (user_id, priority, received_at DESC) WHERE priority <> ''`,
+		`CREATE TABLE IF NOT EXISTS vip_senders (
+			user_id VARCHAR(255) NOT NULL,
+			address VARCHAR(320) NOT NULL,
+			name VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (user_id, address)
+		)`,
+		`CREATE TABLE IF NOT EXISTS reports (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			period_start TIMESTAMP NOT NULL,
+			period_end TIMESTAMP NOT NULL,
+			stats JSONB NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			UNIQUE (user_id, period_start)
+		)`,
+		`CREATE TABLE IF NOT EXISTS unsubscribe_attempts (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			email_id VARCHAR(255) NOT NULL,
+			sender_address VARCHAR(320) NOT NULL DEFAULT '',
+			url TEXT NOT NULL DEFAULT '',
+			succeeded BOOLEAN NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			dark_patterns TEXT[] NOT NULL DEFAULT '{}',
+			selections TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_unsubscribe_attempts_user ON unsubscribe_attempts (user_id, created_at DESC)`,
+		`ALTER TABLE unsubscribe_attempts ADD COLUMN IF NOT EXISTS ineffective_at TIMESTAMP`,
+		`ALTER TABLE unsubscribe_attempts ADD COLUMN IF NOT EXISTS resurfaced_email_ids TEXT[] NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE unsubscribe_attempts ADD COLUMN IF NOT EXISTS escalation VARCHAR(16) NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_unsubscribe_attempts_sender ON unsubscribe_attempts (user_id, sender_address, created_at DESC)`,
+		`ALTER TABLE unsubscribe_attempts ADD COLUMN IF NOT EXISTS manual_action_url TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS needs_reprocessing BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_user_reprocessing ON emails (user_id, received_at DESC) WHERE needs_reprocessing`,
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS hints TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS summarize VARCHAR(16) NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS cleanup_policies (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			category_id VARCHAR(255) NOT NULL,
+			unread BOOLEAN,
+			older_than_days INTEGER NOT NULL DEFAULT 0,
+			action VARCHAR(50) NOT NULL,
+			schedule VARCHAR(16) NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			last_run_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cleanup_policies_user ON cleanup_policies (user_id)`,
+		`CREATE TABLE IF NOT EXISTS cleanup_runs (
+			id VARCHAR(255) PRIMARY KEY,
+			policy_id VARCHAR(255) NOT NULL,
+			user_id VARCHAR(255) NOT NULL,
+			policy_name VARCHAR(100) NOT NULL DEFAULT '',
+			action VARCHAR(50) NOT NULL,
+			matched INTEGER NOT NULL DEFAULT 0,
+			cleaned INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			ran_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cleanup_runs_policy ON cleanup_runs (policy_id, ran_at DESC)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone VARCHAR(100) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS privacy_mode BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE categories ADD COLUMN IF NOT EXISTS sensitive BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS ai_calls (
+			id VARCHAR(255) PRIMARY KEY,
+			provider VARCHAR(50) NOT NULL,
+			model VARCHAR(100) NOT NULL DEFAULT '',
+			operation VARCHAR(20) NOT NULL,
+			system TEXT NOT NULL DEFAULT '',
+			prompt TEXT NOT NULL,
+			max_tokens INTEGER NOT NULL DEFAULT 0,
+			response TEXT NOT NULL DEFAULT '',
+			error TEXT NOT NULL DEFAULT '',
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_calls_created ON ai_calls (created_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS experiment_results (
+			id VARCHAR(255) PRIMARY KEY,
+			experiment VARCHAR(100) NOT NULL,
+			arm VARCHAR(20) NOT NULL,
+			email_id VARCHAR(255) NOT NULL,
+			user_id VARCHAR(255) NOT NULL,
+			category_id VARCHAR(255) NOT NULL,
+			corrected_category_id VARCHAR(255) NOT NULL DEFAULT '',
+			classified_at TIMESTAMP NOT NULL,
+			corrected_at TIMESTAMP,
+			UNIQUE (experiment, email_id)
+		)`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS review_category_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS confidence DOUBLE PRECISION NOT NULL DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_emails_user_review ON emails (user_id, received_at DESC) WHERE review_category_id <> ''`,
+		// Gmail IDs used to be unique across users, so two users couldn't both store a list email;
+		// the rows already stored satisfy the per-user idx_emails_user_gmail_unique, which replaces
+		// the global constraint and the plain index it made redundant
+		`ALTER TABLE emails DROP CONSTRAINT IF EXISTS emails_gmail_id_key`,
+		`DROP INDEX IF EXISTS idx_emails_user_gmail`,
+		`ALTER TABLE emails ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS onboarding_step VARCHAR(20) NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS gmail_actions (
+			id VARCHAR(255) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			email_id VARCHAR(255) NOT NULL,
+			gmail_id VARCHAR(255) NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_gmail_actions_user ON gmail_actions (user_id)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS needs_reauth BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS workspace_mailbox BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS account_merge_codes (
+			code VARCHAR(64) PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			user_id VARCHAR(255) PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
 	}
-
-	return nil
+	return tables, migrations
 }
@@ -0,0 +1,18 @@
+package repository
+
+import "context"
+
+type replicaOKKey struct{}
+
+// ReplicaOK marks the reads made with the context as tolerating a replica that lags a moment
+// behind the writes, e.g. the listings and counts a user is shown. Reads whose results are
+// written back, or decide what is written, must not be made with it.
+func ReplicaOK(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaOKKey{}, true)
+}
+
+// AllowsReplica reports whether the context was marked with ReplicaOK
+func AllowsReplica(ctx context.Context) bool {
+	ok, _ := ctx.Value(replicaOKKey{}).(bool)
+	return ok
+}
@@ -2,26 +2,58 @@ package router
 
 import (
 	"fmt"
+	"io/fs"
 	"net/http"
-	"os"
-	"path/filepath"
 
 	"jump-challenge/internal/handler"
 	"jump-challenge/internal/middleware"
+	"jump-challenge/internal/openapi"
+	"jump-challenge/internal/static"
+	"jump-challenge/internal/validation"
 
 	"github.com/labstack/echo/v4"
 )
 
+// APIPrefix is the base path of the current API version
+const APIPrefix = "/api/v1"
+
 func SetupRoutes(
 	e *echo.Echo,
 	authHandler *handler.AuthHandler,
+	mergeHandler *handler.AccountMergeHandler,
 	categoryHandler *handler.CategoryHandler,
 	emailHandler *handler.EmailHandler,
 	unsubscribeHandler *handler.UnsubscribeHandler,
-	templatesPath string,
-) {
+	retentionHandler *handler.RetentionHandler,
+	automationHandler *handler.AutomationHandler,
+	notificationHandler *handler.NotificationHandler,
+	pushHandler *handler.PushHandler,
+	telegramHandler *handler.TelegramHandler,
+	webhookHandler *handler.WebhookHandler,
+	shareHandler *handler.ShareHandler,
+	orgHandler *handler.OrganizationHandler,
+	billingHandler *handler.BillingHandler,
+	viewHandler *handler.SavedViewHandler,
+	triageHandler *handler.TriageHandler,
+	shipmentHandler *handler.ShipmentHandler,
+	reportHandler *handler.ReportHandler,
+	imageProxyHandler *handler.ImageProxyHandler,
+	recommendationHandler *handler.RecommendationHandler,
+	onboardingHandler *handler.OnboardingHandler,
+	cleanupHandler *handler.CleanupHandler,
+	aiDebugHandler *handler.AIDebugHandler,
+	selfCheckHandler *handler.SelfCheckHandler,
+	workspaceHandler *handler.WorkspaceHandler,
+	pageHandler *handler.PageHandler,
+	templates fs.FS,
+	assets *static.Assets,
+) error {
 	// Apply session middleware globally
 	e.Use(middleware.SessionMiddleware())
+	e.Use(middleware.Compress())
+
+	// Static files, under fingerprinted names for the pages and their own for everyone else
+	e.GET(static.Prefix+"*", echo.WrapHandler(assets))
 
 	// Public routes
 	e.GET("/auth/:provider", authHandler.BeginAuthHandler)
@@ -29,61 +61,56 @@ func SetupRoutes(
 	e.GET("/auth/logout", authHandler.LogoutHandler)
 
 	// Serve the home page
-	e.GET("/", func(c echo.Context) error {
-		indexPath := filepath.Join(templatesPath, "index.html")
-		content, err := os.ReadFile(indexPath)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Template not found: %v", err))
-		}
-		return c.HTML(http.StatusOK, string(content))
-	})
+	e.GET("/", pageHandler.Home)
 
 	e.GET("/health", func(c echo.Context) error {
 		return c.String(http.StatusOK, "OK")
 	})
+	e.GET("/health/sse", emailHandler.SSEStats)
 
 	// Serve the main app page (public route)
-	e.GET("/app", func(c echo.Context) error {
-		appPath := filepath.Join(templatesPath, "app.html")
-		content, err := os.ReadFile(appPath)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Template not found: %v", err))
-		}
-		return c.HTML(http.StatusOK, string(content))
-	})
+	e.GET("/app", pageHandler.App)
+
+	// Public read-only pages behind email share links
+	e.GET("/share/:token", shareHandler.ViewShare)
+
+	// Payment provider webhooks, authenticated by their signatures
+	e.POST("/billing/webhooks/:provider", billingHandler.Webhook)
 
 	// Serve the categories management page (protected route)
 	categoriesGroup := e.Group("/categories")
 	categoriesGroup.Use(middleware.AuthMiddleware(authHandler))
 	categoriesGroup.GET("", func(c echo.Context) error {
-		categoriesPath := filepath.Join(templatesPath, "categories.html")
-		content, err := os.ReadFile(categoriesPath)
+		content, err := fs.ReadFile(templates, "categories.html")
 		if err != nil {
 			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Template not found: %v", err))
 		}
 		return c.HTML(http.StatusOK, string(content))
 	})
 
-	// Protected API routes
-	protected := e.Group("/api")
-	protected.Use(middleware.AuthMiddleware(authHandler))
-
-	// Category API routes
-	protected.POST("/categories", categoryHandler.CreateCategory)
-	protected.GET("/categories", categoryHandler.GetCategories)
-	protected.GET("/categories/:id", categoryHandler.GetCategory)
-	protected.PUT("/categories/:id", categoryHandler.UpdateCategory)
-	protected.DELETE("/categories/:id", categoryHandler.DeleteCategory)
-
-	// Email API routes
-	protected.GET("/emails", emailHandler.GetEmailsByUser)
-	protected.GET("/emails/category/:id", emailHandler.GetEmailsByCategory)
-	protected.POST("/emails/sync", emailHandler.SyncEmails)
-	protected.POST("/emails/bulk-action", emailHandler.PerformBulkAction)
-	protected.DELETE("/emails", emailHandler.DeleteEmails)
-	protected.POST("/emails/classify", emailHandler.ClassifyEmail)
-	protected.POST("/emails/unsubscribe", unsubscribeHandler.UnsubscribeEmails)
-	
-	// Real-time email updates via Server-Sent Events (SSE)
-	protected.GET("/sse", emailHandler.SSEEmailUpdates)
+	// Versioned JSON API, documented at /api/openapi.json
+	doc := openapi.NewDocument("Jump Challenge API", "1.0.0", APIPrefix, handler.ErrorResponse{})
+	v1 := e.Group(APIPrefix)
+	v1.Use(middleware.AuthMiddleware(authHandler))
+
+	// Unversioned paths stay as aliases of v1 for existing clients
+	legacy := e.Group("/api")
+	legacy.Use(middleware.AuthMiddleware(authHandler))
+
+	for _, route := range apiRoutes(authHandler, mergeHandler, categoryHandler, emailHandler, unsubscribeHandler, retentionHandler, automationHandler, notificationHandler, pushHandler, telegramHandler, webhookHandler, shareHandler, orgHandler, billingHandler, viewHandler, triageHandler, shipmentHandler, reportHandler, imageProxyHandler, recommendationHandler, onboardingHandler, cleanupHandler, aiDebugHandler, selfCheckHandler, workspaceHandler) {
+		// A mistyped validation rule fails startup rather than the requests it would validate
+		if err := validation.Check(route.Request, route.Query); err != nil {
+			return fmt.Errorf("%s %s: %w", route.Method, route.Path, err)
+		}
+		v1.Add(route.Method, route.Path, route.handler)
+		legacy.Add(route.Method, route.Path, route.handler)
+		doc.Add(route.Operation)
+	}
+
+	spec := doc.Spec()
+	e.GET("/api/openapi.json", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, spec)
+	})
+	e.GET("/api/docs", pageHandler.Docs)
+	return nil
 }
@@ -0,0 +1,337 @@
+package router
+
+import (
+	"net/http"
+
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/openapi"
+	"jump-challenge/internal/sse"
+
+	"github.com/labstack/echo/v4"
+)
+
+// apiRoute is an authenticated API endpoint together with its documentation
+type apiRoute struct {
+	openapi.Operation
+	handler echo.HandlerFunc
+}
+
+// apiRoutes lists every endpoint of the JSON API, relative to the version prefix
+func apiRoutes(
+	authHandler *handler.AuthHandler,
+	mergeHandler *handler.AccountMergeHandler,
+	categoryHandler *handler.CategoryHandler,
+	emailHandler *handler.EmailHandler,
+	unsubscribeHandler *handler.UnsubscribeHandler,
+	retentionHandler *handler.RetentionHandler,
+	automationHandler *handler.AutomationHandler,
+	notificationHandler *handler.NotificationHandler,
+	pushHandler *handler.PushHandler,
+	telegramHandler *handler.TelegramHandler,
+	webhookHandler *handler.WebhookHandler,
+	shareHandler *handler.ShareHandler,
+	orgHandler *handler.OrganizationHandler,
+	billingHandler *handler.BillingHandler,
+	viewHandler *handler.SavedViewHandler,
+	triageHandler *handler.TriageHandler,
+	shipmentHandler *handler.ShipmentHandler,
+	reportHandler *handler.ReportHandler,
+	imageProxyHandler *handler.ImageProxyHandler,
+	recommendationHandler *handler.RecommendationHandler,
+	onboardingHandler *handler.OnboardingHandler,
+	cleanupHandler *handler.CleanupHandler,
+	aiDebugHandler *handler.AIDebugHandler,
+	selfCheckHandler *handler.SelfCheckHandler,
+	workspaceHandler *handler.WorkspaceHandler,
+) []apiRoute {
+	return []apiRoute{
+		// Session state for the authenticated user
+		{openapi.Operation{Method: http.MethodGet, Path: "/me", Tag: "Session", Summary: "Get the authenticated user and session",
+			Response: handler.MeResponse{}}, authHandler.Me},
+		{openapi.Operation{Method: http.MethodPost, Path: "/me/merge-code", Tag: "Session", Summary: "Create a code to merge this duplicate account into another",
+			Response: model.AccountMergeCode{}, Status: http.StatusCreated}, mergeHandler.CreateMergeCode},
+		{openapi.Operation{Method: http.MethodPost, Path: "/me/merge", Tag: "Session", Summary: "Move the emails, categories and settings of the account a merge code was created in to this one, and delete it",
+			Request: handler.AccountMergeRequest{}, Response: model.AccountMerge{}}, mergeHandler.Merge},
+
+		// Categories
+		{openapi.Operation{Method: http.MethodPost, Path: "/categories", Tag: "Categories", Summary: "Create a category",
+			Request: handler.CategoryRequest{}, Response: model.Category{}, Status: http.StatusCreated}, categoryHandler.CreateCategory},
+		{openapi.Operation{Method: http.MethodGet, Path: "/categories", Tag: "Categories", Summary: "List categories with their email and unread counts",
+			Response: []*model.CategoryWithCounts{}, HTML: true}, categoryHandler.GetCategories},
+		{openapi.Operation{Method: http.MethodGet, Path: "/categories/:id", Tag: "Categories", Summary: "Get a category",
+			Response: model.Category{}}, categoryHandler.GetCategory},
+		{openapi.Operation{Method: http.MethodPut, Path: "/categories/:id", Tag: "Categories", Summary: "Update a category",
+			Request: handler.UpdateCategoryRequest{}, Response: model.Category{}}, categoryHandler.UpdateCategory},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/categories/:id", Tag: "Categories", Summary: "Delete a category",
+			Status: http.StatusNoContent}, categoryHandler.DeleteCategory},
+
+		// Emails
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails", Tag: "Emails", Summary: "List the user's emails, newest first",
+			Response: []*model.Email{}, Query: handler.ListEmailsQuery{}, HTML: true}, emailHandler.GetEmailsByUser},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/category/:id", Tag: "Emails", Summary: "List emails in a category",
+			Response: []*model.Email{}, Query: handler.ListEmailsQuery{}, HTML: true}, emailHandler.GetEmailsByCategory},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/:id", Tag: "Emails", Summary: "Get an email with its category, sender history and previous/next navigation",
+			Response: model.EmailDetail{}, Query: handler.EmailDetailQuery{}}, emailHandler.GetEmail},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/:id/body", Tag: "Emails", Summary: "Get the full body of an email",
+			Response: handler.EmailBodyResponse{}}, emailHandler.GetEmailBody},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/:id/otp", Tag: "Emails", Summary: "Get the verification code found in an email",
+			Response: model.OTP{}}, emailHandler.GetOTP},
+		{openapi.Operation{Method: http.MethodPost, Path: "/emails/:id/share", Tag: "Emails", Summary: "Create a public read-only link to an email's summary",
+			Request: handler.ShareEmailRequest{}, Response: model.EmailShare{}, Status: http.StatusCreated}, shareHandler.CreateShare},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/:id/shares", Tag: "Emails", Summary: "List the share links of an email",
+			Response: []*model.EmailShare{}}, shareHandler.GetShares},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/emails/:id/shares/:share_id", Tag: "Emails", Summary: "Revoke a share link",
+			Status: http.StatusNoContent}, shareHandler.RevokeShare},
+		{openapi.Operation{Method: http.MethodGet, Path: "/senders/:address/history", Tag: "Emails", Summary: "List every stored email from a sender with engagement stats",
+			Response: model.SenderHistory{}}, emailHandler.GetSenderHistory},
+		{openapi.Operation{Method: http.MethodPost, Path: "/senders/:address/profile", Tag: "Emails", Summary: "Have the AI characterize a sender from their recent emails",
+			Response: model.SenderProfile{}, Query: handler.SenderProfileQuery{}}, emailHandler.GenerateSenderProfile},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/vip", Tag: "Emails", Summary: "List the emails from VIP senders, newest first",
+			Response: []*model.Email{}, Query: handler.ListEmailsQuery{}}, emailHandler.GetVIPEmails},
+		{openapi.Operation{Method: http.MethodGet, Path: "/vip-senders", Tag: "Emails", Summary: "List the senders marked as VIP",
+			Response: []*model.VIPSender{}}, emailHandler.GetVIPSenders},
+		{openapi.Operation{Method: http.MethodPost, Path: "/vip-senders", Tag: "Emails", Summary: "Mark a sender as VIP",
+			Request: handler.VIPSenderRequest{}, Response: model.VIPSender{}, Status: http.StatusCreated}, emailHandler.AddVIPSender},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/vip-senders/:address", Tag: "Emails", Summary: "Remove a sender from the VIPs",
+			Status: http.StatusNoContent}, emailHandler.RemoveVIPSender},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/window", Tag: "Emails", Summary: "List a window of the user's emails as compact rows with the total, for virtualized lists",
+			Response: model.EmailWindow{}, Query: handler.EmailWindowQuery{}}, emailHandler.GetEmailWindow},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/delta", Tag: "Emails", Summary: "List the IDs of emails created, updated or trashed since a cursor",
+			Response: model.EmailDelta{}, Query: handler.EmailDeltaQuery{}}, emailHandler.GetEmailDelta},
+		{openapi.Operation{Method: http.MethodGet, Path: "/emails/trash", Tag: "Emails", Summary: "List trashed emails",
+			Response: []*model.Email{}, Query: handler.ListEmailsQuery{}}, emailHandler.GetTrash},
+		{openapi.Operation{Method: http.MethodPut, Path: "/emails/:id/category", Tag: "Emails", Summary: "File an email under another category, correcting its classification",
+			Request: handler.CategorizeEmailRequest{}, Response: model.Email{}}, emailHandler.CategorizeEmail},
+		{openapi.Operation{Method: http.MethodGet, Path: "/review", Tag: "Emails", Summary: "List the emails classified with too little confidence to be filed",
+			Response: []*model.Email{}, Query: handler.ListEmailsQuery{}}, emailHandler.GetReviewQueue},
+		{openapi.Operation{Method: http.MethodPost, Path: "/review/:id/resolve", Tag: "Emails", Summary: "File an email of the review queue under the suggested or another category",
+			Request: handler.ResolveReviewRequest{}, Response: model.Email{}}, emailHandler.ResolveReview},
+		{openapi.Operation{Method: http.MethodPost, Path: "/emails/:id/restore", Tag: "Emails", Summary: "Restore an email from the trash",
+			Response: model.Email{}}, emailHandler.RestoreEmail},
+		{openapi.Operation{Method: http.MethodPost, Path: "/emails/sync", Tag: "Emails", Summary: "Fetch and process new emails from Gmail",
+			Response: handler.SyncEmailsResponse{}, Query: handler.SyncEmailsQuery{}}, emailHandler.SyncEmails},
+		{openapi.Operation{Method: http.MethodPost, Path: "/emails/bulk-action", Tag: "Emails", Summary: "Apply an action to emails by ID, or by filter as a background job",
+			Request: handler.BulkActionRequest{}, Response: handler.MessageResponse{}}, emailHandler.PerformBulkAction},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/emails", Tag: "Emails", Summary: "Move emails to the trash by ID, or by filter as a background job",
+			Request: handler.EmailSelectionRequest{}, Response: handler.MessageResponse{}}, emailHandler.DeleteEmails},
+		{openapi.Operation{Method: http.MethodPost, Path: "/emails/classify", Tag: "Emails", Summary: "Classify an ad-hoc email into one of the user's categories",
+			Request: handler.ClassifyRequest{}, Response: handler.ClassifyResponse{}}, emailHandler.ClassifyEmail},
+		{openapi.Operation{Method: http.MethodPost, Path: "/emails/unsubscribe", Tag: "Emails", Summary: "Unsubscribe from the senders of emails by ID, or by filter as a background job",
+			Request: handler.EmailSelectionRequest{}, Response: handler.MessageResponse{}}, unsubscribeHandler.UnsubscribeEmails},
+		{openapi.Operation{Method: http.MethodGet, Path: "/unsubscribe-attempts", Tag: "Emails", Summary: "List unsubscribe attempts with the dark patterns found and what was selected, newest first",
+			Response: []*model.UnsubscribeAttempt{}, Query: handler.UnsubscribeAttemptsQuery{}}, unsubscribeHandler.GetAttempts},
+		{openapi.Operation{Method: http.MethodPost, Path: "/unsubscribe-attempts/:id/escalate", Tag: "Emails", Summary: "Block the sender of an ineffective unsubscribe, or report their emails as spam and block them",
+			Request: handler.EscalateUnsubscribeRequest{}, Response: model.UnsubscribeAttempt{}}, unsubscribeHandler.Escalate},
+		{openapi.Operation{Method: http.MethodPost, Path: "/unsubscribe-attempts/:id/complete", Tag: "Emails", Summary: "Mark an unsubscribe left to the user, e.g. behind a CAPTCHA, as finished by them",
+			Response: model.UnsubscribeAttempt{}}, unsubscribeHandler.CompleteManually},
+
+		// Saved filters, or smart views
+		{openapi.Operation{Method: http.MethodGet, Path: "/views", Tag: "Views", Summary: "List saved views",
+			Response: []*model.SavedView{}}, viewHandler.GetViews},
+		{openapi.Operation{Method: http.MethodPost, Path: "/views", Tag: "Views", Summary: "Save a named email filter as a view",
+			Request: handler.SavedViewRequest{}, Response: model.SavedView{}, Status: http.StatusCreated}, viewHandler.CreateView},
+		{openapi.Operation{Method: http.MethodGet, Path: "/views/:id", Tag: "Views", Summary: "Get a saved view",
+			Response: model.SavedView{}}, viewHandler.GetView},
+		{openapi.Operation{Method: http.MethodPut, Path: "/views/:id", Tag: "Views", Summary: "Replace a saved view",
+			Request: handler.SavedViewRequest{}, Response: model.SavedView{}}, viewHandler.UpdateView},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/views/:id", Tag: "Views", Summary: "Delete a saved view",
+			Status: http.StatusNoContent}, viewHandler.DeleteView},
+		{openapi.Operation{Method: http.MethodGet, Path: "/views/:id/emails", Tag: "Views", Summary: "List the emails matching a saved view, newest first",
+			Response: []*model.Email{}, Query: handler.ListEmailsQuery{}}, viewHandler.GetViewEmails},
+
+		// Rapid triage, one email at a time
+		{openapi.Operation{Method: http.MethodGet, Path: "/triage/next", Tag: "Triage", Summary: "Get the oldest email left to triage with a suggested action",
+			Response: model.TriageItem{}}, triageHandler.GetNext},
+		{openapi.Operation{Method: http.MethodPost, Path: "/triage/:id/decision", Tag: "Triage", Summary: "Apply a triage decision and get the next email",
+			Request: handler.TriageDecisionRequest{}, Response: model.TriageItem{}}, triageHandler.Decide},
+
+		// Account-security emails across providers
+		{openapi.Operation{Method: http.MethodGet, Path: "/security-events", Tag: "Security", Summary: "List password resets, new sign-ins and other account-security emails, newest first",
+			Response: []*model.SecurityEvent{}, Query: handler.SecurityEventsQuery{}}, emailHandler.GetSecurityEvents},
+
+		// Packages found in shipping notifications
+		{openapi.Operation{Method: http.MethodGet, Path: "/shipments", Tag: "Shipments", Summary: "List tracked packages, most recently updated first",
+			Response: []*model.Shipment{}}, shipmentHandler.GetShipments},
+
+		// Weekly inbox reports
+		{openapi.Operation{Method: http.MethodGet, Path: "/reports", Tag: "Reports", Summary: "List weekly inbox reports, latest week first",
+			Response: []*model.InboxReport{}, Query: handler.ReportsQuery{}}, reportHandler.GetReports},
+		{openapi.Operation{Method: http.MethodGet, Path: "/reports/:id", Tag: "Reports", Summary: "Get a weekly inbox report",
+			Response: model.InboxReport{}}, reportHandler.GetReport},
+
+		// Remote images of email bodies, loaded by the server to keep the user's IP from senders
+		{openapi.Operation{Method: http.MethodGet, Path: "/proxy/image", Tag: "Emails", Summary: "Load a remote image of an email body through the proxy",
+			Query: handler.ImageProxyQuery{}, ContentType: "image/*"}, imageProxyHandler.ProxyImage},
+
+		// Cleanups proposed from the user's emails, carried out as bulk jobs
+		{openapi.Operation{Method: http.MethodGet, Path: "/recommendations", Tag: "Recommendations", Summary: "List proposed cleanups, those concerning the most emails first",
+			Response: []*model.Recommendation{}}, recommendationHandler.GetRecommendations},
+		{openapi.Operation{Method: http.MethodPost, Path: "/recommendations/:id/apply", Tag: "Recommendations", Summary: "Queue the bulk job carrying out a recommendation",
+			Response: model.BulkJob{}, Status: http.StatusAccepted}, recommendationHandler.ApplyRecommendation},
+
+		// The onboarding wizard of new users
+		{openapi.Operation{Method: http.MethodGet, Path: "/onboarding", Tag: "Onboarding", Summary: "Get the user's onboarding step and what it offers",
+			Response: model.Onboarding{}}, onboardingHandler.GetOnboarding},
+		{openapi.Operation{Method: http.MethodPost, Path: "/onboarding", Tag: "Onboarding", Summary: "Complete or skip the current onboarding step; 409 when it isn't the one named",
+			Request: handler.OnboardingRequest{}, Response: handler.OnboardingResponse{}}, onboardingHandler.CompleteOnboardingStep},
+
+		// Filter-based bulk jobs
+		{openapi.Operation{Method: http.MethodGet, Path: "/jobs/:id", Tag: "Jobs", Summary: "Get the progress of a bulk job",
+			Response: model.BulkJob{}}, emailHandler.GetBulkJob},
+
+		// Retention policy
+		{openapi.Operation{Method: http.MethodGet, Path: "/retention", Tag: "Retention", Summary: "Get the retention policy and prune stats",
+			Response: handler.RetentionResponse{}}, retentionHandler.GetRetention},
+		{openapi.Operation{Method: http.MethodPut, Path: "/retention", Tag: "Retention", Summary: "Replace the retention policy",
+			Request: handler.RetentionRequest{}, Response: model.RetentionPolicy{}}, retentionHandler.UpdateRetention},
+
+		// Notification preferences for real-time events
+		{openapi.Operation{Method: http.MethodGet, Path: "/settings/notifications", Tag: "Settings", Summary: "Get notification preferences",
+			Response: model.NotificationPreferences{}}, notificationHandler.GetNotificationSettings},
+		{openapi.Operation{Method: http.MethodPut, Path: "/settings/notifications", Tag: "Settings", Summary: "Replace notification preferences",
+			Request: handler.NotificationSettingsRequest{}, Response: model.NotificationPreferences{}}, notificationHandler.UpdateNotificationSettings},
+		{openapi.Operation{Method: http.MethodGet, Path: "/settings/time-zone", Tag: "Settings", Summary: "Get the time zone schedules follow",
+			Response: handler.TimeZoneSettings{}}, authHandler.GetTimeZone},
+		{openapi.Operation{Method: http.MethodPut, Path: "/settings/time-zone", Tag: "Settings", Summary: "Set the time zone schedules follow",
+			Request: handler.TimeZoneSettings{}, Response: handler.TimeZoneSettings{}}, authHandler.UpdateTimeZone},
+		{openapi.Operation{Method: http.MethodGet, Path: "/settings/locale", Tag: "Settings", Summary: "Get the language of messages and summaries",
+			Response: handler.LocaleSettings{}}, authHandler.GetLocale},
+		{openapi.Operation{Method: http.MethodPut, Path: "/settings/locale", Tag: "Settings", Summary: "Set the language of messages and summaries",
+			Request: handler.LocaleSettings{}, Response: handler.LocaleSettings{}}, authHandler.UpdateLocale},
+		{openapi.Operation{Method: http.MethodGet, Path: "/settings/privacy", Tag: "Settings", Summary: "Get whether privacy mode is on",
+			Response: model.PrivacySettings{}}, emailHandler.GetPrivacySettings},
+		{openapi.Operation{Method: http.MethodPut, Path: "/settings/privacy", Tag: "Settings", Summary: "Turn privacy mode on or off",
+			Request: handler.PrivacySettingsRequest{}, Response: model.PrivacySettings{}}, emailHandler.UpdatePrivacySettings},
+		{openapi.Operation{Method: http.MethodGet, Path: "/settings/system-emails", Tag: "Settings", Summary: "Get how bounces and auto-replies are handled",
+			Response: model.SystemEmailSettings{}}, emailHandler.GetSystemEmailSettings},
+		{openapi.Operation{Method: http.MethodPut, Path: "/settings/system-emails", Tag: "Settings", Summary: "Replace how bounces and auto-replies are handled",
+			Request: handler.SystemEmailSettingsRequest{}, Response: model.SystemEmailSettings{}}, emailHandler.UpdateSystemEmailSettings},
+
+		// Web Push subscriptions for desktop notifications
+		{openapi.Operation{Method: http.MethodGet, Path: "/push/vapid-public-key", Tag: "Push", Summary: "Get the key browsers subscribe with",
+			Response: handler.VAPIDKeyResponse{}}, pushHandler.GetVAPIDPublicKey},
+		{openapi.Operation{Method: http.MethodPost, Path: "/push/subscriptions", Tag: "Push", Summary: "Register a browser push subscription",
+			Request: handler.PushSubscriptionRequest{}, Response: model.PushSubscription{}, Status: http.StatusCreated}, pushHandler.Subscribe},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/push/subscriptions", Tag: "Push", Summary: "Remove a browser push subscription",
+			Request: handler.PushUnsubscribeRequest{}, Status: http.StatusNoContent}, pushHandler.Unsubscribe},
+
+		// Telegram bot chat linking
+		{openapi.Operation{Method: http.MethodGet, Path: "/telegram", Tag: "Telegram", Summary: "Get the linked Telegram chat",
+			Response: model.TelegramLink{}}, telegramHandler.GetLink},
+		{openapi.Operation{Method: http.MethodPost, Path: "/telegram/link", Tag: "Telegram", Summary: "Create a code to link a Telegram chat",
+			Response: model.TelegramLinkCode{}, Status: http.StatusCreated}, telegramHandler.CreateLinkCode},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/telegram/link", Tag: "Telegram", Summary: "Unlink the Telegram chat",
+			Status: http.StatusNoContent}, telegramHandler.Unlink},
+
+		// Outgoing webhook
+		{openapi.Operation{Method: http.MethodGet, Path: "/webhook", Tag: "Webhook", Summary: "Get the URL VIP emails are posted to",
+			Response: model.Webhook{}}, webhookHandler.GetWebhook},
+		{openapi.Operation{Method: http.MethodPut, Path: "/webhook", Tag: "Webhook", Summary: "Post VIP emails to an https URL, signed with a new secret",
+			Request: handler.WebhookRequest{}, Response: model.Webhook{}}, webhookHandler.SetWebhook},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/webhook", Tag: "Webhook", Summary: "Stop posting VIP emails",
+			Status: http.StatusNoContent}, webhookHandler.DeleteWebhook},
+
+		// Plan quotas
+		{openapi.Operation{Method: http.MethodGet, Path: "/usage", Tag: "Billing", Summary: "Get the plan and this month's quota usage",
+			Response: model.UsageReport{}}, billingHandler.GetUsage},
+
+		// Organizations sharing team categories and automations
+		{openapi.Operation{Method: http.MethodPost, Path: "/organization", Tag: "Organizations", Summary: "Create an organization with the user as admin",
+			Request: handler.OrganizationRequest{}, Response: model.OrganizationDetail{}, Status: http.StatusCreated}, orgHandler.CreateOrganization},
+		{openapi.Operation{Method: http.MethodGet, Path: "/organization", Tag: "Organizations", Summary: "Get the user's organization and its members",
+			Response: model.OrganizationDetail{}}, orgHandler.GetOrganization},
+		{openapi.Operation{Method: http.MethodPut, Path: "/organization", Tag: "Organizations", Summary: "Rename the organization (admins)",
+			Request: handler.OrganizationRequest{}, Response: model.Organization{}}, orgHandler.RenameOrganization},
+		{openapi.Operation{Method: http.MethodPost, Path: "/organization/leave", Tag: "Organizations", Summary: "Leave the organization; the last member deletes it",
+			Status: http.StatusNoContent}, orgHandler.LeaveOrganization},
+		{openapi.Operation{Method: http.MethodGet, Path: "/organization/stats", Tag: "Organizations", Summary: "Get aggregate email stats across members (admins)",
+			Response: model.OrganizationStats{}}, orgHandler.GetStats},
+		{openapi.Operation{Method: http.MethodPut, Path: "/organization/members/:user_id", Tag: "Organizations", Summary: "Change a member's role (admins)",
+			Request: handler.MemberRoleRequest{}, Response: model.Membership{}}, orgHandler.UpdateMemberRole},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/organization/members/:user_id", Tag: "Organizations", Summary: "Remove a member (admins)",
+			Status: http.StatusNoContent}, orgHandler.RemoveMember},
+		{openapi.Operation{Method: http.MethodPost, Path: "/organization/invitations", Tag: "Organizations", Summary: "Invite an email address (admins)",
+			Request: handler.InvitationRequest{}, Response: model.Invitation{}, Status: http.StatusCreated}, orgHandler.Invite},
+		{openapi.Operation{Method: http.MethodGet, Path: "/organization/invitations", Tag: "Organizations", Summary: "List the organization's pending invitations (admins)",
+			Response: []*model.Invitation{}}, orgHandler.GetInvitations},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/organization/invitations/:id", Tag: "Organizations", Summary: "Revoke an invitation (admins)",
+			Status: http.StatusNoContent}, orgHandler.RevokeInvitation},
+		{openapi.Operation{Method: http.MethodGet, Path: "/organization/invitations/received", Tag: "Organizations", Summary: "List the invitations addressed to the user",
+			Response: []*model.Invitation{}}, orgHandler.GetReceivedInvitations},
+		{openapi.Operation{Method: http.MethodPost, Path: "/organization/invitations/:id/accept", Tag: "Organizations", Summary: "Accept an invitation and join its organization",
+			Response: model.OrganizationDetail{}}, orgHandler.AcceptInvitation},
+		{openapi.Operation{Method: http.MethodPost, Path: "/organization/invitations/:id/decline", Tag: "Organizations", Summary: "Decline an invitation",
+			Status: http.StatusNoContent}, orgHandler.DeclineInvitation},
+
+		// Category automations
+		{openapi.Operation{Method: http.MethodGet, Path: "/automations", Tag: "Automations", Summary: "List category automations",
+			Response: []*model.Automation{}}, automationHandler.GetAutomations},
+		{openapi.Operation{Method: http.MethodPost, Path: "/automations", Tag: "Automations", Summary: "Create a category automation",
+			Request: handler.AutomationRequest{}, Response: model.Automation{}, Status: http.StatusCreated}, automationHandler.CreateAutomation},
+		{openapi.Operation{Method: http.MethodPut, Path: "/automations/:id", Tag: "Automations", Summary: "Replace a category automation",
+			Request: handler.UpdateAutomationRequest{}, Response: model.Automation{}}, automationHandler.UpdateAutomation},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/automations/:id", Tag: "Automations", Summary: "Delete a category automation",
+			Status: http.StatusNoContent}, automationHandler.DeleteAutomation},
+
+		// Scheduled cleanup policies
+		{openapi.Operation{Method: http.MethodGet, Path: "/cleanup-policies", Tag: "Cleanup", Summary: "List cleanup policies",
+			Response: []*model.CleanupPolicy{}}, cleanupHandler.GetPolicies},
+		{openapi.Operation{Method: http.MethodPost, Path: "/cleanup-policies", Tag: "Cleanup", Summary: "Create a cleanup policy",
+			Request: handler.CleanupPolicyRequest{}, Response: model.CleanupPolicy{}, Status: http.StatusCreated}, cleanupHandler.CreatePolicy},
+		{openapi.Operation{Method: http.MethodPut, Path: "/cleanup-policies/:id", Tag: "Cleanup", Summary: "Replace a cleanup policy",
+			Request: handler.CleanupPolicyRequest{}, Response: model.CleanupPolicy{}}, cleanupHandler.UpdatePolicy},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/cleanup-policies/:id", Tag: "Cleanup", Summary: "Delete a cleanup policy and its history",
+			Status: http.StatusNoContent}, cleanupHandler.DeletePolicy},
+		{openapi.Operation{Method: http.MethodGet, Path: "/cleanup-policies/:id/preview", Tag: "Cleanup", Summary: "Preview what a cleanup policy would clean now, without running it",
+			Response: model.CleanupPreview{}}, cleanupHandler.PreviewPolicy},
+		{openapi.Operation{Method: http.MethodGet, Path: "/cleanup-policies/:id/runs", Tag: "Cleanup", Summary: "List the past runs of a cleanup policy, newest first",
+			Response: []*model.CleanupRun{}, Query: handler.CleanupRunsQuery{}}, cleanupHandler.GetRuns},
+
+		// Real-time email updates via Server-Sent Events (SSE)
+		{openapi.Operation{Method: http.MethodGet, Path: "/sse", Tag: "Events", Summary: "Stream real-time email updates",
+			ContentType: "text/event-stream", Events: sseEvents()}, emailHandler.SSEEmailUpdates},
+		{openapi.Operation{Method: http.MethodGet, Path: "/sse/status", Tag: "Events", Summary: "Tell whether the user receives live updates, over which connections",
+			Response: sse.Presence{}}, emailHandler.SSEPresence},
+		{openapi.Operation{Method: http.MethodGet, Path: "/admin/connections", Tag: "Events", Summary: "List every user's live update connections (operators)",
+			Response: handler.ConnectionsResponse{}}, emailHandler.SSEConnections},
+
+		// AI calls recorded in debug mode and classification experiments
+		{openapi.Operation{Method: http.MethodGet, Path: "/admin/ai-calls", Tag: "AI", Summary: "List the AI calls recorded in debug mode, newest first (operators)",
+			Response: []*model.AICall{}, Query: handler.AICallsQuery{}}, aiDebugHandler.GetCalls},
+		{openapi.Operation{Method: http.MethodGet, Path: "/admin/ai-calls/:id", Tag: "AI", Summary: "Get a recorded AI call (operators)",
+			Response: model.AICall{}}, aiDebugHandler.GetCall},
+		{openapi.Operation{Method: http.MethodPost, Path: "/admin/ai-calls/:id/replay", Tag: "AI", Summary: "Replay a recorded prompt against another provider or model (operators)",
+			Request: handler.ReplayAICallRequest{}, Response: model.AICallReplay{}}, aiDebugHandler.ReplayCall},
+		{openapi.Operation{Method: http.MethodGet, Path: "/admin/experiment", Tag: "AI", Summary: "Compare the accuracy of a classification experiment's arms (operators)",
+			Response: model.ExperimentReport{}, Query: handler.ExperimentReportQuery{}}, aiDebugHandler.GetExperimentReport},
+
+		// Operations
+		{openapi.Operation{Method: http.MethodGet, Path: "/admin/gmail-breakers", Tag: "Operations", Summary: "List each user's Gmail calls and circuit breaker state since the server started (operators)",
+			Response: []model.GmailBreaker{}}, emailHandler.GmailBreakers},
+		{openapi.Operation{Method: http.MethodGet, Path: "/admin/selfcheck", Tag: "Operations", Summary: "Check the configuration, Google OAuth client, AI provider and database; 503 when a check fails (operators)",
+			Response: model.SelfCheckReport{}}, selfCheckHandler.SelfCheck},
+
+		// Workspace domain-wide delegation
+		{openapi.Operation{Method: http.MethodGet, Path: "/admin/workspace", Tag: "Workspace", Summary: "Get the Workspace domain, what its admin authorizes for the service account and the designated mailboxes (operators)",
+			Response: model.WorkspaceConnection{}}, workspaceHandler.GetConnection},
+		{openapi.Operation{Method: http.MethodPost, Path: "/admin/workspace/mailboxes", Tag: "Workspace", Summary: "Designate a mailbox of the domain to be synced, once the service account read it (operators)",
+			Request: handler.WorkspaceMailboxRequest{}, Response: model.WorkspaceMailbox{}, Status: http.StatusCreated}, workspaceHandler.AddMailbox},
+		{openapi.Operation{Method: http.MethodDelete, Path: "/admin/workspace/mailboxes/:email", Tag: "Workspace", Summary: "Stop syncing a mailbox while nobody watches it; its emails are kept (operators)",
+			Status: http.StatusNoContent}, workspaceHandler.RemoveMailbox},
+	}
+}
+
+// sseEvents documents the events registered with the SSE manager
+func sseEvents() []openapi.Event {
+	var events []openapi.Event
+	for _, schema := range sse.Schemas() {
+		events = append(events, openapi.Event{
+			Name:        schema.Type,
+			Version:     schema.Version,
+			Description: schema.Description,
+			Data:        schema.Data,
+		})
+	}
+	return events
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// accountMergeCodeTTL is how long a merge code can be redeemed
+const accountMergeCodeTTL = 15 * time.Minute
+
+type accountMergeService struct {
+	codeRepo       repository.AccountMergeCodeRepository
+	userRepo       repository.UserRepository
+	categoryRepo   repository.CategoryRepository
+	emailRepo      repository.EmailRepository
+	orgRepo        repository.OrganizationRepository
+	automationRepo repository.AutomationRepository
+	cleanupRepo    repository.CleanupPolicyRepository
+	viewRepo       repository.SavedViewRepository
+	vipRepo        repository.VIPSenderRepository
+	retentionRepo  repository.RetentionPolicyRepository
+	preferenceRepo repository.NotificationPreferencesRepository
+	logger         *logger.Logger
+}
+
+func NewAccountMergeService(
+	codeRepo repository.AccountMergeCodeRepository,
+	userRepo repository.UserRepository,
+	categoryRepo repository.CategoryRepository,
+	emailRepo repository.EmailRepository,
+	orgRepo repository.OrganizationRepository,
+	automationRepo repository.AutomationRepository,
+	cleanupRepo repository.CleanupPolicyRepository,
+	viewRepo repository.SavedViewRepository,
+	vipRepo repository.VIPSenderRepository,
+	retentionRepo repository.RetentionPolicyRepository,
+	preferenceRepo repository.NotificationPreferencesRepository,
+	logger *logger.Logger,
+) AccountMergeService {
+	return &accountMergeService{
+		codeRepo:       codeRepo,
+		userRepo:       userRepo,
+		categoryRepo:   categoryRepo,
+		emailRepo:      emailRepo,
+		orgRepo:        orgRepo,
+		automationRepo: automationRepo,
+		cleanupRepo:    cleanupRepo,
+		viewRepo:       viewRepo,
+		vipRepo:        vipRepo,
+		retentionRepo:  retentionRepo,
+		preferenceRepo: preferenceRepo,
+		logger:         logger,
+	}
+}
+
+func (s *accountMergeService) CreateMergeCode(ctx context.Context, userID string) (*model.AccountMergeCode, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate merge code: %w", err)
+	}
+	now := time.Now()
+	code := &model.AccountMergeCode{
+		Code:      hex.EncodeToString(raw),
+		UserID:    userID,
+		ExpiresAt: now.Add(accountMergeCodeTTL),
+	}
+	if err := s.codeRepo.Create(ctx, code, now); err != nil {
+		return nil, fmt.Errorf("failed to save merge code: %w", err)
+	}
+	return code, nil
+}
+
+// Merge moves everything of the duplicate to the user. The stores don't share a transaction, so
+// each piece is created for the user before it is deleted from the duplicate: a merge failing
+// partway leaves the rest with the duplicate, and merging again with a new code finishes it.
+func (s *accountMergeService) Merge(ctx context.Context, userID, code string) (*model.AccountMerge, error) {
+	// Taking the code means it can't be used again
+	pending, err := s.codeRepo.Take(ctx, code, time.Now())
+	if errors.Is(err, apierror.ErrNotFound) {
+		return nil, apierror.Validation("The merge code is invalid or expired")
+	} else if err != nil {
+		return nil, err
+	}
+	duplicateID := pending.UserID
+	if duplicateID == userID {
+		return nil, apierror.Validation("Redeem the merge code signed in to the account to keep, not the one it was created in")
+	}
+	duplicate, err := s.userRepo.FindByID(ctx, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Team categories and automations stay with the organization
+	if _, err := s.orgRepo.FindMembership(ctx, duplicate.ID); err == nil {
+		return nil, apierror.Validation("The account to merge must leave its organization first")
+	} else if !errors.Is(err, apierror.ErrNotFound) {
+		return nil, err
+	}
+
+	merge := &model.AccountMerge{MergedUserID: duplicate.ID, MergedEmail: duplicate.Email}
+	categories, err := s.copyCategories(ctx, duplicate.ID, user.ID, merge)
+	if err != nil {
+		return nil, err
+	}
+	if merge.Emails, err = s.emailRepo.MoveToUser(ctx, duplicate.ID, user.ID, categories); err != nil {
+		return nil, fmt.Errorf("failed to move emails: %w", err)
+	}
+	if err := s.mergeRules(ctx, duplicate.ID, user.ID, categories, merge); err != nil {
+		return nil, err
+	}
+	if err := s.mergeSettings(ctx, duplicate, user, merge); err != nil {
+		return nil, err
+	}
+
+	// The duplicate's categories go last: until then, merging again maps them by name to the copies
+	for id := range categories {
+		if err := s.categoryRepo.Delete(ctx, id); err != nil && !errors.Is(err, apierror.ErrNotFound) {
+			return nil, fmt.Errorf("failed to delete merged category: %w", err)
+		}
+	}
+	if err := s.userRepo.Delete(ctx, duplicate.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete merged user: %w", err)
+	}
+	s.logger.Info("Merged user", duplicate.ID, "into user", user.ID, ":", merge.Emails, "emails,", merge.Categories, "categories")
+	return merge, nil
+}
+
+// copyCategories copies the duplicate's own categories to the user, except those named like one
+// the user sees, whose emails are filed under that one instead. It returns the user's category
+// for each of the duplicate's, by ID.
+func (s *accountMergeService) copyCategories(ctx context.Context, fromUserID, toUserID string, merge *model.AccountMerge) (map[string]string, error) {
+	existing, err := s.categoryRepo.FindByUserID(ctx, toUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	owned, err := s.categoryRepo.FindByUserID(ctx, fromUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	merged := make(map[string]string)
+	for _, category := range owned {
+		if category.UserID != fromUserID || category.IsTeam() {
+			continue
+		}
+		merge.Categories++
+		if same := findCategoryNamed(existing, category.Name); same != nil {
+			merged[category.ID] = same.ID
+			continue
+		}
+		copied := *category
+		copied.ID = uuid.New().String()
+		copied.UserID = toUserID
+		if err := s.categoryRepo.Create(ctx, &copied); err != nil {
+			return nil, fmt.Errorf("failed to copy category %s: %w", category.Name, err)
+		}
+		merged[category.ID] = copied.ID
+	}
+	return merged, nil
+}
+
+// mergeRules gives the duplicate's automations, cleanup policies and saved views to the user.
+// The stores don't change owners, so each is copied under a new ID before the original is deleted.
+func (s *accountMergeService) mergeRules(ctx context.Context, fromUserID, toUserID string, categories map[string]string, merge *model.AccountMerge) error {
+	category := func(id string) string {
+		if merged, ok := categories[id]; ok {
+			return merged
+		}
+		return id
+	}
+
+	automations, err := s.automationRepo.FindByUserID(ctx, fromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get automations: %w", err)
+	}
+	for _, automation := range automations {
+		if automation.OrgID != "" {
+			continue
+		}
+		copied := *automation
+		copied.ID = uuid.New().String()
+		copied.UserID = toUserID
+		copied.CategoryID = category(automation.CategoryID)
+		if err := s.automationRepo.Create(ctx, &copied); err != nil {
+			return fmt.Errorf("failed to move automation: %w", err)
+		}
+		if err := s.automationRepo.Delete(ctx, automation.ID); err != nil {
+			return fmt.Errorf("failed to move automation: %w", err)
+		}
+		merge.Automations++
+	}
+
+	policies, err := s.cleanupRepo.FindByUserID(ctx, fromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get cleanup policies: %w", err)
+	}
+	for _, policy := range policies {
+		copied := *policy
+		copied.ID = uuid.New().String()
+		copied.UserID = toUserID
+		copied.CategoryID = category(policy.CategoryID)
+		if err := s.cleanupRepo.Create(ctx, &copied); err != nil {
+			return fmt.Errorf("failed to move cleanup policy: %w", err)
+		}
+		if err := s.cleanupRepo.Delete(ctx, policy.ID); err != nil {
+			return fmt.Errorf("failed to move cleanup policy: %w", err)
+		}
+		merge.Cleanups++
+	}
+
+	existing, err := s.viewRepo.FindByUserID(ctx, toUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get views: %w", err)
+	}
+	views, err := s.viewRepo.FindByUserID(ctx, fromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get views: %w", err)
+	}
+	for _, view := range views {
+		if !hasViewNamed(existing, view.Name) {
+			copied := *view
+			copied.ID = uuid.New().String()
+			copied.UserID = toUserID
+			copied.Filter.CategoryID = category(view.Filter.CategoryID)
+			if err := s.viewRepo.Create(ctx, &copied); err != nil {
+				return fmt.Errorf("failed to move view: %w", err)
+			}
+			merge.SavedViews++
+		}
+		if err := s.viewRepo.Delete(ctx, view.ID); err != nil {
+			return fmt.Errorf("failed to move view: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeSettings adds the duplicate's settings to the user's; where both have one, the user's
+// is kept
+func (s *accountMergeService) mergeSettings(ctx context.Context, from, to *model.User, merge *model.AccountMerge) error {
+	for _, address := range strings.Fields(from.BlockedSenders) {
+		to.BlockSender(address)
+	}
+	if to.TimeZone == "" {
+		to.TimeZone = from.TimeZone
+	}
+	if to.Locale == "" {
+		to.Locale = from.Locale
+	}
+	to.PrivacyMode = to.PrivacyMode || from.PrivacyMode
+	to.ArchiveSystemEmails = to.ArchiveSystemEmails || from.ArchiveSystemEmails
+	if err := s.userRepo.Update(ctx, to); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	vips, err := s.vipRepo.FindByUserID(ctx, from.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get VIP senders: %w", err)
+	}
+	kept, err := s.vipRepo.FindByUserID(ctx, to.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get VIP senders: %w", err)
+	}
+	for _, vip := range vips {
+		if !hasVIP(kept, vip.Address) {
+			copied := *vip
+			copied.UserID = to.ID
+			if err := s.vipRepo.Save(ctx, &copied); err != nil {
+				return fmt.Errorf("failed to move VIP sender: %w", err)
+			}
+			merge.VIPSenders++
+		}
+		if err := s.vipRepo.Delete(ctx, from.ID, vip.Address); err != nil {
+			return fmt.Errorf("failed to move VIP sender: %w", err)
+		}
+	}
+
+	if policy, err := s.retentionRepo.FindByUserID(ctx, from.ID); err == nil {
+		if _, err := s.retentionRepo.FindByUserID(ctx, to.ID); errors.Is(err, apierror.ErrNotFound) {
+			policy.UserID = to.ID
+			if err := s.retentionRepo.Save(ctx, policy); err != nil {
+				return fmt.Errorf("failed to move retention policy: %w", err)
+			}
+		}
+	}
+	if preferences, err := s.preferenceRepo.FindByUserID(ctx, from.ID); err == nil {
+		if _, err := s.preferenceRepo.FindByUserID(ctx, to.ID); errors.Is(err, apierror.ErrNotFound) {
+			preferences.UserID = to.ID
+			if err := s.preferenceRepo.Save(ctx, preferences); err != nil {
+				return fmt.Errorf("failed to move notification preferences: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func findCategoryNamed(categories []*model.Category, name string) *model.Category {
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, name) {
+			return category
+		}
+	}
+	return nil
+}
+
+func hasViewNamed(views []*model.SavedView, name string) bool {
+	for _, view := range views {
+		if strings.EqualFold(view.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVIP(vips []*model.VIPSender, address string) bool {
+	for _, vip := range vips {
+		if strings.EqualFold(vip.Address, address) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/prompt"
+)
+
+// charsPerToken estimates tokens without the provider's tokenizer: about four characters
+// each holds well enough for English text to keep prompts inside context windows
+const charsPerToken = 4
+
+// combineSummariesTask is the reduce step of summarizing an email too long for one request
+const combineSummariesTask = `These are summaries of consecutive parts of one long email, in order. Combine them into a summary of the whole email in 2-3 sentences.
+
+Respond with only the summary.`
+
+// SetAIInputLimits bounds the text of an email sent to the AI: up to chunkTokens per request,
+// and up to maxChunks requests to summarize it; zero values keep the defaults
+func (s *emailService) SetAIInputLimits(chunkTokens, maxChunks int) {
+	if chunkTokens > 0 {
+		s.aiChunkTokens = chunkTokens
+	}
+	if maxChunks > 0 {
+		s.aiMaxChunks = maxChunks
+	}
+}
+
+// classify asks the AI for the category of the email from the start of its text
+func (s *emailService) classify(ctx context.Context, body string, categories []*model.Category) (string, error) {
+	name, _, err := s.classifyWith(ctx, s.aiClient, body, categories)
+	return name, err
+}
+
+// classifyWith also returns the client's confidence in the category when the review queue
+// needs it and the client can tell, else 1
+func (s *emailService) classifyWith(ctx context.Context, client AIClient, body string, categories []*model.Category) (string, float64, error) {
+	text := truncateTokens(plainText(body), s.aiChunkTokens)
+	if classifier, ok := client.(ConfidenceClassifier); ok && s.reviewThreshold > 0 {
+		return classifier.ClassifyEmailWithConfidence(ctx, text, categories)
+	}
+	name, err := client.ClassifyEmail(ctx, text, categories)
+	return name, 1, err
+}
+
+// classifyEmail classifies a stored email with the client of its experiment arm, and returns the
+// arm that answered; when the treatment fails, the control classifies the email instead
+func (s *emailService) classifyEmail(ctx context.Context, email *model.Email, categories []*model.Category) (name string, confidence float64, arm string, err error) {
+	if s.experiment != nil {
+		if arm, client := s.experiment.Arm(email.ID); client != nil {
+			name, confidence, err := s.classifyWith(ctx, client, email.Body, categories)
+			if err == nil {
+				return name, confidence, arm, nil
+			}
+			s.logger.Warn("Experiment arm", arm, "failed to classify email", email.ID, ", using the control:", err)
+		}
+	}
+
+	name, confidence, err = s.classifyWith(ctx, s.aiClient, email.Body, categories)
+	return name, confidence, model.ArmControl, err
+}
+
+// summarize asks the AI for a summary of the email's text. Text longer than one request is
+// split into chunks summarized on their own, then the chunk summaries are combined; chunks past
+// the limit are left out. A language, e.g. "Portuguese", asks for the summary in it.
+func (s *emailService) summarize(ctx context.Context, body, language string) (string, error) {
+	chunks := splitTokens(plainText(body), s.aiChunkTokens)
+	if len(chunks) > s.aiMaxChunks {
+		s.logger.Info("Summarizing the first", s.aiMaxChunks, "of", len(chunks), "chunks of a long email")
+		chunks = chunks[:s.aiMaxChunks]
+	}
+	if len(chunks) <= 1 {
+		return s.aiClient.SummarizeEmail(ctx, strings.Join(chunks, ""), language)
+	}
+
+	partials := make([]string, len(chunks))
+	labeled := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		partial, err := s.aiClient.SummarizeEmail(ctx, chunk, language)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize part %d of %d: %w", i+1, len(chunks), err)
+		}
+		partials[i] = strings.TrimSpace(partial)
+		labeled[i] = fmt.Sprintf("Part %d: %s", i+1, partials[i])
+	}
+	task := combineSummariesTask
+	if language != "" {
+		task += " " + prompt.Language(language)
+	}
+	summary, err := s.aiClient.Analyze(ctx, task, strings.Join(labeled, "\n\n"))
+	if errors.Is(err, ErrAnalysisUnsupported) {
+		// Without a model to combine them, the parts' summaries follow each other
+		return strings.Join(partials, " "), nil
+	}
+	return summary, err
+}
+
+// truncateTokens cuts the text to about maxTokens
+func truncateTokens(text string, maxTokens int) string {
+	return strings.TrimSpace(text[:cutPoint(text, maxTokens)])
+}
+
+// splitTokens splits the text into chunks of about maxTokens
+func splitTokens(text string, maxTokens int) []string {
+	var chunks []string
+	for text != "" {
+		cut := cutPoint(text, maxTokens)
+		if chunk := strings.TrimSpace(text[:cut]); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		text = strings.TrimSpace(text[cut:])
+	}
+	return chunks
+}
+
+// cutPoint is where to end text to keep it within about maxTokens: between paragraphs, else
+// lines, else words, as long as that keeps more than half of the allowance
+func cutPoint(text string, maxTokens int) int {
+	limit := maxTokens * charsPerToken
+	if maxTokens <= 0 || utf8.RuneCountInString(text) <= limit {
+		return len(text)
+	}
+	end := 0
+	for range limit {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	for _, separator := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(text[:end], separator); i > end/2 {
+			return i + len(separator)
+		}
+	}
+	return end
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/i18n"
+	"jump-challenge/internal/model"
+)
+
+// maxSummaryLength bounds a summary worth storing; the prompt asks for 2-3 sentences
+const maxSummaryLength = 1000
+
+// reprocessBatchSize bounds how many rejected summaries a sync asks for again per user
+const reprocessBatchSize = 5
+
+// ErrSummaryRejected is returned when the AI answered with something unfit to store as a summary
+var ErrSummaryRejected = errors.New("AI summary rejected")
+
+// ErrAnalysisUnsupported is returned by AI clients that only classify and summarize, such as the
+// local model; summaries then do without the steps that need Analyze
+var ErrAnalysisUnsupported = errors.New("AI client can't analyze content")
+
+// retrySummaryTask is asked when the plain summary prompt got an answer that was rejected; it
+// takes the language to write in
+const retrySummaryTask = `Summarize this email in 2-3 sentences, written in %s.
+
+Describe what the email says even when it is promotional, automated or unusual: never apologize, refuse, mention being an AI or comment on the request. Respond with only the summary.`
+
+// refusalPhrases mark answers where the model talked about itself instead of the email
+var refusalPhrases = []string{
+	"as an ai",
+	"as a language model",
+	"i'm sorry, but",
+	"i am sorry, but",
+	"i can't help with",
+	"i cannot help with",
+	"i can't assist",
+	"i cannot assist",
+	"i'm unable to",
+	"i am unable to",
+	"i'm not able to",
+	"i am not able to",
+	"i can't provide",
+	"i cannot provide",
+	"i won't be able to",
+	"against my guidelines",
+	"violates my",
+	"content policy",
+}
+
+// validateSummary rejects answers that aren't a summary of the email: empty or rambling ones,
+// refusals and policy text, and, unless a language was asked for, answers in another script
+// than the email's text
+func validateSummary(summary, text, language string) error {
+	if !strings.ContainsFunc(summary, unicode.IsLetter) {
+		return fmt.Errorf("%w: no text", ErrSummaryRejected)
+	}
+	if utf8.RuneCountInString(summary) > maxSummaryLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrSummaryRejected, maxSummaryLength)
+	}
+
+	lower := strings.ToLower(strings.ReplaceAll(summary, "’", "'"))
+	for _, phrase := range refusalPhrases {
+		if strings.Contains(lower, phrase) {
+			return fmt.Errorf("%w: refusal %q", ErrSummaryRejected, phrase)
+		}
+	}
+
+	if language != "" {
+		return nil
+	}
+	if want, got := dominantScript(text), dominantScript(summary); want != "" && got != "" && want != got {
+		return fmt.Errorf("%w: written in %s for an email in %s", ErrSummaryRejected, got, want)
+	}
+	return nil
+}
+
+// scripts are the writing systems told apart by dominantScript; Chinese and Japanese share
+// characters, so they count as one
+var scripts = []struct {
+	name   string
+	tables []*unicode.RangeTable
+}{
+	{"Latin", []*unicode.RangeTable{unicode.Latin}},
+	{"Cyrillic", []*unicode.RangeTable{unicode.Cyrillic}},
+	{"Greek", []*unicode.RangeTable{unicode.Greek}},
+	{"Arabic", []*unicode.RangeTable{unicode.Arabic}},
+	{"Hebrew", []*unicode.RangeTable{unicode.Hebrew}},
+	{"Devanagari", []*unicode.RangeTable{unicode.Devanagari}},
+	{"Thai", []*unicode.RangeTable{unicode.Thai}},
+	{"Hangul", []*unicode.RangeTable{unicode.Hangul}},
+	{"CJK", []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana}},
+}
+
+// dominantScript names the script most letters of the text are written in, empty for text
+// without letters
+func dominantScript(text string) string {
+	counts := make([]int, len(scripts))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for i, script := range scripts {
+			if unicode.In(r, script.tables...) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	best := -1
+	for i, count := range counts {
+		if count > 0 && (best < 0 || count > counts[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return scripts[best].name
+}
+
+// summarizeChecked summarizes the email in the user's language and validates the answer,
+// asking once more with a stricter prompt when it is rejected; it returns ErrSummaryRejected
+// when both are
+func (s *emailService) summarizeChecked(ctx context.Context, userID, body string) (string, error) {
+	language := s.summaryLanguage(ctx, userID)
+	summary, err := s.summarize(ctx, body, language)
+	if err != nil {
+		return "", err
+	}
+	text := truncateTokens(plainText(body), s.aiChunkTokens)
+	summary = strings.TrimSpace(summary)
+	rejection := validateSummary(summary, text, language)
+	if rejection == nil {
+		return summary, nil
+	}
+
+	s.logger.Warn("Asking again for a summary:", rejection)
+	written := "the same language as the email"
+	if language != "" {
+		written = language
+	}
+	summary, err = s.aiClient.Analyze(ctx, fmt.Sprintf(retrySummaryTask, written), text)
+	if errors.Is(err, ErrAnalysisUnsupported) {
+		return "", rejection
+	}
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if err := validateSummary(summary, text, language); err != nil {
+		return "", err
+	}
+	return summary, nil
+}
+
+// summaryLanguage is the language the user reads summaries in, empty to keep each email's own
+func (s *emailService) summaryLanguage(ctx context.Context, userID string) string {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	return i18n.LanguageName(user.Locale)
+}
+
+// ReprocessEmails asks again for the summaries the AI gave unusable answers for, a few of the
+// user's emails at a time, and returns how many were fixed
+func (s *emailService) ReprocessEmails(ctx context.Context, userID string) (int, error) {
+	emails, err := s.emailRepo.FindNeedingReprocessing(ctx, userID, reprocessBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get emails to reprocess: %w", err)
+	}
+
+	sensitive, err := s.sensitiveCategories(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	for _, email := range emails {
+		// Emails filed as sensitive since go without a summary
+		if sensitive[email.CategoryID] {
+			if err := s.updateEmail(ctx, email, func(email *model.Email) {
+				email.NeedsReprocessing = false
+			}); err != nil {
+				return fixed, fmt.Errorf("failed to save reprocessed email: %w", err)
+			}
+			continue
+		}
+
+		summary, err := s.summarizeChecked(ctx, userID, email.Body)
+		if errors.Is(err, ErrSummaryRejected) {
+			s.logger.Warn("Summary of email", email.ID, "rejected again:", err)
+			continue
+		}
+		if err != nil {
+			return fixed, apierror.Upstream("failed to summarize email", err)
+		}
+
+		if err := s.updateEmail(ctx, email, func(email *model.Email) {
+			email.Summary = summary
+			email.NeedsReprocessing = false
+		}); err != nil {
+			return fixed, fmt.Errorf("failed to save reprocessed email: %w", err)
+		}
+		fixed++
+	}
+	return fixed, nil
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/i18n"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
 	"jump-challenge/internal/repository"
@@ -12,6 +16,11 @@ import (
 type authService struct {
 	userRepo repository.UserRepository
 	logger   *logger.Logger
+
+	tokenHooks  []TokenRefreshHook
+	reauthHooks []NeedsReauthHook
+
+	workspaceDomain string // whose users' Gmail is called with the service account, empty without delegation
 }
 
 func NewAuthService(userRepo repository.UserRepository, logger *logger.Logger) AuthService {
@@ -24,6 +33,15 @@ func NewAuthService(userRepo repository.UserRepository, logger *logger.Logger) A
 func (s *authService) GetOrCreateUser(ctx context.Context, googleID, email, name, accessToken, refreshToken string, tokenExpiry interface{}) (*model.User, error) {
 	// Try to find existing user by Google ID
 	existingUser, err := s.userRepo.FindByGoogleID(ctx, googleID)
+	if err != nil {
+		// A mailbox an operator designated is taken over by its user's first sign-in
+		if mailbox, findErr := s.userRepo.FindByEmail(ctx, email); findErr == nil && !mailbox.SignedIn() {
+			s.logger.Info("User", mailbox.ID, "signed in to their Workspace mailbox")
+			mailbox.GoogleID = googleID
+			mailbox.Name = name
+			existingUser, err = mailbox, nil
+		}
+	}
 	if err != nil {
 		// User doesn't exist, create new one
 		var expiry time.Time
@@ -38,6 +56,7 @@ func (s *authService) GetOrCreateUser(ctx context.Context, googleID, email, name
 		}
 
 		newUser := model.NewUser(googleID, email, name, accessToken, refreshToken, expiry)
+		newUser.OnboardingStep = model.OnboardingCategories
 		if err := s.userRepo.Create(ctx, newUser); err != nil {
 			s.logger.Error("Failed to create user:", err)
 			return nil, err
@@ -50,6 +69,7 @@ func (s *authService) GetOrCreateUser(ctx context.Context, googleID, email, name
 	if accessToken != "" || refreshToken != "" {
 		existingUser.AccessToken = accessToken
 		existingUser.RefreshToken = refreshToken
+		existingUser.NeedsReauth = false
 		
 		// Update expiry if provided
 		if tokenExpiry != nil {
@@ -67,11 +87,128 @@ func (s *authService) GetOrCreateUser(ctx context.Context, googleID, email, name
 			return nil, err
 		}
 		s.logger.Info("Updated existing user:", existingUser.ID)
+		for _, hook := range s.tokenHooks {
+			hook(ctx, existingUser)
+		}
 	}
 
 	return existingUser, nil
 }
 
+func (s *authService) UseWorkspaceDomain(domain string) {
+	s.workspaceDomain = domain
+}
+
+func (s *authService) OnTokenRefresh(hook TokenRefreshHook) {
+	s.tokenHooks = append(s.tokenHooks, hook)
+}
+
+func (s *authService) OnNeedsReauth(hook NeedsReauthHook) {
+	s.reauthHooks = append(s.reauthHooks, hook)
+}
+
+func (s *authService) MarkNeedsReauth(ctx context.Context, userEmail string) {
+	user, err := s.userRepo.FindByEmail(ctx, userEmail)
+	if err != nil {
+		s.logger.Error("Failed to find user to mark as needing to sign in again:", err)
+		return
+	}
+	if user.NeedsReauth {
+		return
+	}
+
+	user.NeedsReauth = true
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to mark user", user.ID, "as needing to sign in again:", err)
+		return
+	}
+	s.logger.Warn("Google refused the grant of user", user.ID, ", they must sign in again")
+	for _, hook := range s.reauthHooks {
+		hook(ctx, user)
+	}
+}
+
 func (s *authService) GetUser(ctx context.Context, userID string) (*model.User, error) {
 	return s.userRepo.FindByID(ctx, userID)
-}
\ No newline at end of file
+}
+
+func (s *authService) SetGrantedScopes(ctx context.Context, userID string, scopes []string) (*model.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each consent issues a new token carrying exactly these scopes, so replace rather than merge
+	user.GrantedScopes = strings.Join(scopes, " ")
+	if model.InDomain(user.Email, s.workspaceDomain) {
+		user.GrantWorkspaceScopes()
+	}
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to update granted scopes:", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *authService) SetTimeZone(ctx context.Context, userID, timeZone string) (*model.User, error) {
+	if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "Local" {
+		return nil, apierror.InvalidFields([]apierror.FieldError{
+			{Field: "time_zone", Message: "must be an IANA time zone"},
+		})
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TimeZone = timeZone
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update time zone: %w", err)
+	}
+	s.logger.Info("Set time zone of user", userID, "to", timeZone)
+	return user, nil
+}
+
+func (s *authService) SetLocale(ctx context.Context, userID, locale string) (*model.User, error) {
+	if locale != "" && !i18n.Supported(locale) {
+		return nil, apierror.InvalidFields([]apierror.FieldError{
+			{Field: "locale", Message: "must be a supported locale"},
+		})
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Locale = locale
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update locale: %w", err)
+	}
+	s.logger.Info("Set locale of user", userID, "to", locale)
+	return user, nil
+}
+
+func (s *authService) DetectTimeZone(ctx context.Context, userID, timeZone string) error {
+	// time.LoadLocation takes "" and "Local" for UTC and the server's zone, neither detected
+	if timeZone == "" || timeZone == "Local" {
+		return nil
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		s.logger.Warn("Ignoring unknown time zone", timeZone, "reported for user", userID)
+		return nil
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TimeZone != "" {
+		return nil
+	}
+
+	user.TimeZone = timeZone
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update time zone: %w", err)
+	}
+	s.logger.Info("Detected time zone", timeZone, "for user", userID)
+	return nil
+}
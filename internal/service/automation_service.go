@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+type automationService struct {
+	automationRepo repository.AutomationRepository
+	categoryRepo   repository.CategoryRepository
+	emailRepo      repository.EmailRepository
+	orgRepo        repository.OrganizationRepository
+	emailService   EmailService
+	logger         *logger.Logger
+}
+
+func NewAutomationService(
+	automationRepo repository.AutomationRepository,
+	categoryRepo repository.CategoryRepository,
+	emailRepo repository.EmailRepository,
+	orgRepo repository.OrganizationRepository,
+	emailService EmailService,
+	logger *logger.Logger,
+) AutomationService {
+	return &automationService{
+		automationRepo: automationRepo,
+		categoryRepo:   categoryRepo,
+		emailRepo:      emailRepo,
+		orgRepo:        orgRepo,
+		emailService:   emailService,
+		logger:         logger,
+	}
+}
+
+func (s *automationService) CreateAutomation(ctx context.Context, userID, categoryID, action, label string, delayDays int) (*model.Automation, error) {
+	if err := s.validate(ctx, userID, categoryID, action, label, delayDays); err != nil {
+		return nil, err
+	}
+
+	automation := model.NewAutomation(userID, categoryID, action, label, delayDays)
+	if err := s.automationRepo.Create(ctx, automation); err != nil {
+		return nil, fmt.Errorf("failed to save automation: %w", err)
+	}
+
+	s.logger.Info("Created automation:", automation.ID, "for user:", userID)
+	return automation, nil
+}
+
+func (s *automationService) CreateTeamAutomation(ctx context.Context, userID, categoryID, action, label string, delayDays int) (*model.Automation, error) {
+	membership, err := findAdminMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateTeam(ctx, userID, categoryID, action, label, delayDays); err != nil {
+		return nil, err
+	}
+
+	automation := model.NewAutomation(userID, categoryID, action, label, delayDays)
+	automation.OrgID = membership.OrgID
+	if err := s.automationRepo.Create(ctx, automation); err != nil {
+		return nil, fmt.Errorf("failed to save automation: %w", err)
+	}
+
+	s.logger.Info("Created team automation:", automation.ID, "for organization:", membership.OrgID)
+	return automation, nil
+}
+
+func (s *automationService) GetAutomations(ctx context.Context, userID string) ([]*model.Automation, error) {
+	own, err := s.automationRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var automations []*model.Automation
+	for _, automation := range own {
+		if automation.OrgID == "" {
+			automations = append(automations, automation)
+		}
+	}
+
+	team, err := s.teamAutomations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return append(automations, team...), nil
+}
+
+// teamAutomations lists the automations of the user's organization, none when they are in no organization
+func (s *automationService) teamAutomations(ctx context.Context, userID string) ([]*model.Automation, error) {
+	membership, err := s.orgRepo.FindMembership(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apierror.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s.automationRepo.FindByOrgID(ctx, membership.OrgID)
+}
+
+// findManaged returns an automation the user may modify: their own, or their organization's
+// when they are one of its admins
+func (s *automationService) findManaged(ctx context.Context, userID, automationID string) (*model.Automation, error) {
+	automation, err := s.automationRepo.FindByID(ctx, automationID)
+	if err != nil {
+		return nil, err
+	}
+	if automation.OrgID == "" {
+		if automation.UserID != userID {
+			return nil, apierror.NotFound("automation not found")
+		}
+		return automation, nil
+	}
+
+	membership, err := s.orgRepo.FindMembership(ctx, userID)
+	if err != nil || membership.OrgID != automation.OrgID {
+		return nil, apierror.NotFound("automation not found")
+	}
+	if !membership.IsAdmin() {
+		return nil, apierror.Forbidden("only organization admins can modify team automations")
+	}
+	return automation, nil
+}
+
+func (s *automationService) UpdateAutomation(ctx context.Context, userID, automationID, categoryID, action, label string, delayDays int, enabled bool) (*model.Automation, error) {
+	automation, err := s.findManaged(ctx, userID, automationID)
+	if err != nil {
+		return nil, err
+	}
+	validate := s.validate
+	if automation.OrgID != "" {
+		validate = s.validateTeam
+	}
+	if err := validate(ctx, userID, categoryID, action, label, delayDays); err != nil {
+		return nil, err
+	}
+
+	// A changed rule starts over, so emails the old rule already handled get the new action
+	if automation.CategoryID != categoryID || automation.Action != action || automation.Label != label || automation.DelayDays != delayDays {
+		automation.LastRunAt = nil
+	}
+	automation.CategoryID = categoryID
+	automation.Action = action
+	automation.Label = label
+	automation.DelayDays = delayDays
+	automation.Enabled = enabled
+	automation.UpdatedAt = time.Now()
+
+	if err := s.automationRepo.Update(ctx, automation); err != nil {
+		return nil, fmt.Errorf("failed to update automation: %w", err)
+	}
+
+	s.logger.Info("Updated automation:", automation.ID)
+	return automation, nil
+}
+
+func (s *automationService) DeleteAutomation(ctx context.Context, userID, automationID string) error {
+	automation, err := s.findManaged(ctx, userID, automationID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.automationRepo.Delete(ctx, automation.ID); err != nil {
+		return fmt.Errorf("failed to delete automation: %w", err)
+	}
+
+	s.logger.Info("Deleted automation:", automation.ID)
+	return nil
+}
+
+// validate checks what request tags can't: the category must be visible to the user
+// and moving needs a label
+func (s *automationService) validate(ctx context.Context, userID, categoryID, action, label string, delayDays int) error {
+	var fields []apierror.FieldError
+	if _, err := s.categoryRepo.FindByIDAndUser(ctx, categoryID, userID); err != nil {
+		fields = append(fields, apierror.FieldError{Field: "category_id", Message: "does not exist"})
+	}
+	if !isAutomationAction(action) {
+		fields = append(fields, apierror.FieldError{Field: "action", Message: "is not supported by automations"})
+	}
+	if action == "move" && label == "" {
+		fields = append(fields, apierror.FieldError{Field: "label", Message: "is required for the move action"})
+	}
+	if delayDays < 0 {
+		fields = append(fields, apierror.FieldError{Field: "delay_days", Message: "must not be negative"})
+	}
+
+	if len(fields) > 0 {
+		return apierror.InvalidFields(fields)
+	}
+	return nil
+}
+
+// validateTeam additionally keeps team automations off the admin's personal categories,
+// which the other members can't see
+func (s *automationService) validateTeam(ctx context.Context, userID, categoryID, action, label string, delayDays int) error {
+	if err := s.validate(ctx, userID, categoryID, action, label, delayDays); err != nil {
+		return err
+	}
+	category, err := s.categoryRepo.FindByIDAndUser(ctx, categoryID, userID)
+	if err != nil {
+		return err
+	}
+	if !category.IsShared() && !category.IsTeam() {
+		return apierror.InvalidFields([]apierror.FieldError{
+			{Field: "category_id", Message: "must be a default or team category"},
+		})
+	}
+	return nil
+}
+
+func isAutomationAction(action string) bool {
+	for _, supported := range model.AutomationActions {
+		if action == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyToNewEmail runs the user's and their organization's immediate automations for the
+// category the email was classified into
+func (s *automationService) ApplyToNewEmail(ctx context.Context, email *model.Email) {
+	// Emails from VIP senders stay where they are
+	if email.IsUrgent() {
+		return
+	}
+	automations, err := s.GetAutomations(ctx, email.UserID)
+	if err != nil {
+		s.logger.Error("Failed to get automations for user", email.UserID, ":", err)
+		return
+	}
+
+	for _, automation := range automations {
+		if !automation.Enabled || automation.IsScheduled() || automation.CategoryID != email.CategoryID {
+			continue
+		}
+		if err := s.run(ctx, automation, email.UserID, []string{email.ID}); err != nil {
+			s.logger.Error("Failed to run automation", automation.ID, "on email", email.ID, ":", err)
+		}
+	}
+}
+
+// RunScheduled applies every enabled delayed automation to the emails that have become due
+// since its previous pass, and returns how many emails were acted on
+func (s *automationService) RunScheduled(ctx context.Context) (int, error) {
+	automations, err := s.automationRepo.FindAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get automations: %w", err)
+	}
+
+	now := time.Now()
+	applied := 0
+	for _, automation := range automations {
+		if !automation.Enabled || !automation.IsScheduled() {
+			continue
+		}
+
+		count, err := s.runScheduled(ctx, automation, now)
+		if err != nil {
+			// Keep going so one failing user doesn't block the others; the window is retried next pass
+			s.logger.Error("Failed to run scheduled automation", automation.ID, ":", err)
+			continue
+		}
+		applied += count
+	}
+
+	if applied > 0 {
+		s.logger.Info("Scheduled automations acted on", applied, "emails")
+	}
+	return applied, nil
+}
+
+func (s *automationService) runScheduled(ctx context.Context, automation *model.Automation, now time.Time) (int, error) {
+	// Team automations go through every member's mailbox
+	userIDs := []string{automation.UserID}
+	if automation.OrgID != "" {
+		members, err := s.orgRepo.FindMembers(ctx, automation.OrgID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get members: %w", err)
+		}
+		userIDs = userIDs[:0]
+		for _, member := range members {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+
+	cutoff := automation.Cutoff(now)
+	applied := 0
+	for _, userID := range userIDs {
+		count, err := s.applyDue(ctx, automation, userID, cutoff)
+		if err != nil {
+			return applied, err
+		}
+		applied += count
+	}
+
+	automation.LastRunAt = &now
+	if err := s.automationRepo.Update(ctx, automation); err != nil {
+		return applied, fmt.Errorf("failed to record automation progress: %w", err)
+	}
+	return applied, nil
+}
+
+// applyDue runs the automation on the user's emails received before the cutoff that its previous pass didn't cover
+func (s *automationService) applyDue(ctx context.Context, automation *model.Automation, userID string, cutoff time.Time) (int, error) {
+	filter := model.EmailFilter{CategoryID: automation.CategoryID, Before: &cutoff, Archive: model.ArchiveFilterAll}
+	emails, err := s.emailRepo.FindByFilter(ctx, userID, filter, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get emails: %w", err)
+	}
+
+	var emailIDs []string
+	for _, email := range emails {
+		if !automation.AlreadyApplied(email) && !email.IsUrgent() {
+			emailIDs = append(emailIDs, email.ID)
+		}
+	}
+
+	if len(emailIDs) > 0 {
+		if err := s.run(ctx, automation, userID, emailIDs); err != nil {
+			return 0, err
+		}
+	}
+	return len(emailIDs), nil
+}
+
+// run applies the automation's action to the user's emails through the same service methods as bulk actions
+func (s *automationService) run(ctx context.Context, automation *model.Automation, userID string, emailIDs []string) error {
+	switch automation.Action {
+	case "move":
+		return s.emailService.MoveEmails(ctx, emailIDs, automation.Label, userID)
+	case "delete":
+		return s.emailService.DeleteEmails(ctx, emailIDs, userID)
+	default:
+		return s.emailService.PerformBulkAction(ctx, emailIDs, automation.Action, userID)
+	}
+}
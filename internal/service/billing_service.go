@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// ErrQuotaExceeded is returned when the user's plan has no quota left for the month
+var ErrQuotaExceeded = &apierror.Error{
+	Status:  http.StatusTooManyRequests,
+	Code:    apierror.CodeQuotaExceeded,
+	Message: "Monthly quota exceeded, upgrade your plan to continue",
+}
+
+type billingService struct {
+	accountRepo repository.BillingAccountRepository
+	usageRepo   repository.UsageRepository
+	providers   map[string]BillingProvider
+	defaultPlan string // plan of users without a billing account
+	logger      *logger.Logger
+
+	// Serializes reservations so concurrent syncs can't both take the last units
+	mutex sync.Mutex
+}
+
+func NewBillingService(
+	accountRepo repository.BillingAccountRepository,
+	usageRepo repository.UsageRepository,
+	defaultPlan string,
+	logger *logger.Logger,
+	providers ...BillingProvider,
+) BillingService {
+	if !model.IsPlan(defaultPlan) {
+		logger.Warn("Unknown default plan", defaultPlan, ", quotas are disabled")
+		defaultPlan = model.PlanUnlimited
+	}
+
+	s := &billingService{
+		accountRepo: accountRepo,
+		usageRepo:   usageRepo,
+		providers:   make(map[string]BillingProvider),
+		defaultPlan: defaultPlan,
+		logger:      logger,
+	}
+	for _, provider := range providers {
+		s.providers[provider.Name()] = provider
+	}
+	return s
+}
+
+// plan returns the user's current plan, the default one when they have no account
+func (s *billingService) plan(ctx context.Context, userID string) (string, error) {
+	account, err := s.accountRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apierror.ErrNotFound) {
+			return s.defaultPlan, nil
+		}
+		return "", fmt.Errorf("failed to get billing account: %w", err)
+	}
+	if !model.IsPlan(account.Plan) {
+		return s.defaultPlan, nil
+	}
+	return account.Plan, nil
+}
+
+// Reserve records up to n units of the metric and returns how many the plan allows,
+// ErrQuotaExceeded when none are left
+func (s *billingService) Reserve(ctx context.Context, userID, metric string, n int) (int, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	plan, err := s.plan(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	period := model.UsagePeriod(time.Now())
+	granted := n
+	if limit := model.Plans[plan][metric]; limit > 0 {
+		counts, err := s.usageRepo.FindByPeriod(ctx, userID, period)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get usage: %w", err)
+		}
+		granted = min(n, limit-counts[metric])
+		if granted <= 0 {
+			return 0, ErrQuotaExceeded
+		}
+	}
+
+	if err := s.usageRepo.Add(ctx, userID, period, metric, granted); err != nil {
+		return 0, fmt.Errorf("failed to record usage: %w", err)
+	}
+	return granted, nil
+}
+
+func (s *billingService) Remaining(ctx context.Context, userID, metric string) (int, int, error) {
+	plan, err := s.plan(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	limit := model.Plans[plan][metric]
+	if limit <= 0 {
+		return 0, 0, nil
+	}
+
+	counts, err := s.usageRepo.FindByPeriod(ctx, userID, model.UsagePeriod(time.Now()))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return max(limit-counts[metric], 0), limit, nil
+}
+
+func (s *billingService) GetUsage(ctx context.Context, userID string) (*model.UsageReport, error) {
+	plan, err := s.plan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	period := model.UsagePeriod(now)
+	counts, err := s.usageRepo.FindByPeriod(ctx, userID, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	report := &model.UsageReport{
+		Plan:     plan,
+		Period:   period,
+		ResetsAt: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, metric := range model.QuotaMetrics {
+		usage := model.MetricUsage{Metric: metric, Used: counts[metric], Limit: model.Plans[plan][metric]}
+		if usage.Limit > 0 {
+			remaining := max(usage.Limit-usage.Used, 0)
+			usage.Remaining = &remaining
+		}
+		report.Metrics = append(report.Metrics, usage)
+	}
+	return report, nil
+}
+
+func (s *billingService) SetPlan(ctx context.Context, userID, plan string) (*model.BillingAccount, error) {
+	if !model.IsPlan(plan) {
+		return nil, apierror.InvalidFields([]apierror.FieldError{{Field: "plan", Message: "is not a known plan"}})
+	}
+
+	account, err := s.accountRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, apierror.ErrNotFound) {
+			return nil, fmt.Errorf("failed to get billing account: %w", err)
+		}
+		account = &model.BillingAccount{UserID: userID}
+	}
+
+	account.Plan = plan
+	account.UpdatedAt = time.Now()
+	if err := s.accountRepo.Save(ctx, account); err != nil {
+		return nil, fmt.Errorf("failed to save billing account: %w", err)
+	}
+	s.logger.Info("User", userID, "is now on plan:", plan)
+	return account, nil
+}
+
+// HandleWebhook verifies a provider's webhook and applies the plan change it carries
+func (s *billingService) HandleWebhook(ctx context.Context, providerName string, payload []byte, header http.Header) error {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return apierror.NotFound("billing provider not configured")
+	}
+
+	event, err := provider.ParseWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		return nil
+	}
+
+	account, err := s.findAccount(ctx, providerName, event)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		// Acknowledge anyway; the provider would keep retrying an event we can't place
+		s.logger.Warn("Ignoring", providerName, "event for unknown customer:", event.CustomerID)
+		return nil
+	}
+
+	plan := event.Plan
+	if plan == "" {
+		plan = s.defaultPlan
+	}
+	if !model.IsPlan(plan) {
+		return apierror.Validation("unknown plan " + plan)
+	}
+
+	account.Plan = plan
+	account.Provider = providerName
+	account.CustomerID = event.CustomerID
+	account.Status = event.Status
+	account.UpdatedAt = time.Now()
+	if err := s.accountRepo.Save(ctx, account); err != nil {
+		return fmt.Errorf("failed to save billing account: %w", err)
+	}
+
+	s.logger.Info("User", account.UserID, "is now on plan:", plan, "via", providerName)
+	return nil
+}
+
+// findAccount places a webhook event by the user ID it carries, or else by the provider's customer ID
+func (s *billingService) findAccount(ctx context.Context, providerName string, event *model.BillingEvent) (*model.BillingAccount, error) {
+	var account *model.BillingAccount
+	var err error
+	if event.UserID != "" {
+		account, err = s.accountRepo.FindByUserID(ctx, event.UserID)
+		if errors.Is(err, apierror.ErrNotFound) {
+			return &model.BillingAccount{UserID: event.UserID}, nil
+		}
+	} else {
+		account, err = s.accountRepo.FindByCustomerID(ctx, providerName, event.CustomerID)
+		if errors.Is(err, apierror.ErrNotFound) {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billing account: %w", err)
+	}
+	return account, nil
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
+	"jump-challenge/internal/apierror"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
 	"jump-challenge/internal/repository"
@@ -11,18 +14,27 @@ import (
 
 type categoryService struct {
 	categoryRepo repository.CategoryRepository
+	orgRepo      repository.OrganizationRepository
+	emailCounter EmailCounter
 	logger       *logger.Logger
 }
 
-func NewCategoryService(categoryRepo repository.CategoryRepository, logger *logger.Logger) CategoryService {
+func NewCategoryService(categoryRepo repository.CategoryRepository, orgRepo repository.OrganizationRepository, logger *logger.Logger) CategoryService {
 	return &categoryService{
 		categoryRepo: categoryRepo,
+		orgRepo:      orgRepo,
 		logger:       logger,
 	}
 }
 
 func (s *categoryService) CreateCategory(ctx context.Context, userID, name, description string) (*model.Category, error) {
+	name = strings.TrimSpace(name)
+	if err := s.checkNameAvailable(ctx, userID, name, ""); err != nil {
+		return nil, err
+	}
+
 	category := model.NewCategory(name, description)
+	category.UserID = userID
 	if err := s.categoryRepo.Create(ctx, category); err != nil {
 		s.logger.Error("Failed to create category:", err)
 		return nil, err
@@ -31,21 +43,106 @@ func (s *categoryService) CreateCategory(ctx context.Context, userID, name, desc
 	return category, nil
 }
 
-func (s *categoryService) GetCategory(ctx context.Context, categoryID string) (*model.Category, error) {
-	return s.categoryRepo.FindByID(ctx, categoryID)
+func (s *categoryService) CreateTeamCategory(ctx context.Context, userID, name, description string) (*model.Category, error) {
+	membership, err := findAdminMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if err := s.checkNameAvailable(ctx, userID, name, ""); err != nil {
+		return nil, err
+	}
+
+	category := model.NewCategory(name, description)
+	category.UserID = userID
+	category.OrgID = membership.OrgID
+	if err := s.categoryRepo.Create(ctx, category); err != nil {
+		s.logger.Error("Failed to create team category:", err)
+		return nil, err
+	}
+	s.logger.Info("Created team category:", category.ID, "for organization:", membership.OrgID)
+	return category, nil
+}
+
+func (s *categoryService) GetCategory(ctx context.Context, userID, categoryID string) (*model.Category, error) {
+	return s.categoryRepo.FindByIDAndUser(ctx, categoryID, userID)
+}
+
+func (s *categoryService) GetAllCategories(ctx context.Context, userID string) ([]*model.Category, error) {
+	return s.categoryRepo.FindByUserID(ctx, userID)
+}
+
+func (s *categoryService) UseEmailCounts(counter EmailCounter) {
+	s.emailCounter = counter
+}
+
+// GetCategoriesWithCounts lists the categories with counts from a single aggregate query
+// rather than one per category
+func (s *categoryService) GetCategoriesWithCounts(ctx context.Context, userID string) ([]*model.CategoryWithCounts, error) {
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts map[string]model.EmailCounts
+	if s.emailCounter != nil {
+		if counts, err = s.emailCounter.CountByCategory(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*model.CategoryWithCounts, len(categories))
+	for i, category := range categories {
+		result[i] = &model.CategoryWithCounts{Category: category, EmailCounts: counts[category.ID]}
+	}
+	return result, nil
 }
 
-func (s *categoryService) GetAllCategories(ctx context.Context) ([]*model.Category, error) {
-	return s.categoryRepo.FindAll(ctx)
+// checkNameAvailable rejects a name, compared case-insensitively, already used by another of
+// the categories the user sees; two categories with one name would make classification
+// pick between them at random
+func (s *categoryService) checkNameAvailable(ctx context.Context, userID, name, exceptID string) error {
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if category.ID != exceptID && strings.EqualFold(category.Name, name) {
+			return apierror.Conflict(fmt.Sprintf("A category named %q already exists", category.Name), category)
+		}
+	}
+	return nil
+}
+
+// findOwned returns a category the user may modify; shared categories are read-only and
+// team categories are managed by the organization's admins
+func (s *categoryService) findOwned(ctx context.Context, userID, categoryID string) (*model.Category, error) {
+	category, err := s.categoryRepo.FindByIDAndUser(ctx, categoryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if category.IsTeam() {
+		if _, err := findAdminMembership(ctx, s.orgRepo, userID); err != nil {
+			return nil, apierror.Forbidden("only organization admins can modify team categories")
+		}
+		return category, nil
+	}
+	if category.IsShared() {
+		return nil, apierror.Forbidden("default categories cannot be modified")
+	}
+	return category, nil
 }
 
-func (s *categoryService) UpdateCategory(ctx context.Context, categoryID, name, description string) (*model.Category, error) {
-	category, err := s.categoryRepo.FindByID(ctx, categoryID)
+func (s *categoryService) UpdateCategory(ctx context.Context, userID, categoryID, name, description string) (*model.Category, error) {
+	category, err := s.findOwned(ctx, userID, categoryID)
 	if err != nil {
 		return nil, err
 	}
 
-	if name != "" {
+	if name = strings.TrimSpace(name); name != "" {
+		if err := s.checkNameAvailable(ctx, userID, name, category.ID); err != nil {
+			return nil, err
+		}
 		category.Name = name
 	}
 	if description != "" {
@@ -61,8 +158,59 @@ func (s *categoryService) UpdateCategory(ctx context.Context, categoryID, name,
 	return category, nil
 }
 
-func (s *categoryService) DeleteCategory(ctx context.Context, categoryID string) error {
-	category, err := s.categoryRepo.FindByID(ctx, categoryID)
+func (s *categoryService) SetCategoryHints(ctx context.Context, userID, categoryID, hints string) (*model.Category, error) {
+	category, err := s.findOwned(ctx, userID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Hints = strings.TrimSpace(hints)
+	category.UpdatedAt = time.Now()
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		s.logger.Error("Failed to update category hints:", err)
+		return nil, err
+	}
+	s.logger.Info("Updated hints of category:", category.ID)
+	return category, nil
+}
+
+func (s *categoryService) SetCategorySummarize(ctx context.Context, userID, categoryID, mode string) (*model.Category, error) {
+	if !model.IsSummarizeMode(mode) {
+		return nil, apierror.Validation(fmt.Sprintf("unknown summarize mode %q", mode))
+	}
+	category, err := s.findOwned(ctx, userID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Summarize = mode
+	category.UpdatedAt = time.Now()
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		s.logger.Error("Failed to update category summarize mode:", err)
+		return nil, err
+	}
+	s.logger.Info("Set summarize mode of category:", category.ID, "to", mode)
+	return category, nil
+}
+
+func (s *categoryService) SetCategorySensitive(ctx context.Context, userID, categoryID string, sensitive bool) (*model.Category, error) {
+	category, err := s.findOwned(ctx, userID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Sensitive = sensitive
+	category.UpdatedAt = time.Now()
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		s.logger.Error("Failed to update category sensitivity:", err)
+		return nil, err
+	}
+	s.logger.Info("Set category:", category.ID, "sensitive:", sensitive)
+	return category, nil
+}
+
+func (s *categoryService) DeleteCategory(ctx context.Context, userID, categoryID string) error {
+	category, err := s.findOwned(ctx, userID, categoryID)
 	if err != nil {
 		return err
 	}
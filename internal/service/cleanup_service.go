@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+type cleanupService struct {
+	policyRepo   repository.CleanupPolicyRepository
+	runRepo      repository.CleanupRunRepository
+	categoryRepo repository.CategoryRepository
+	emailRepo    repository.EmailRepository
+	userRepo     repository.UserRepository
+	emailService EmailService
+	logger       *logger.Logger
+
+	cleanedHooks []CleanupHook
+}
+
+// NewCleanupService creates the service running the users' cleanup policies through the same
+// email service methods as bulk actions
+func NewCleanupService(policyRepo repository.CleanupPolicyRepository, runRepo repository.CleanupRunRepository, categoryRepo repository.CategoryRepository, emailRepo repository.EmailRepository, userRepo repository.UserRepository, emailService EmailService, logger *logger.Logger) CleanupService {
+	return &cleanupService{
+		policyRepo:   policyRepo,
+		runRepo:      runRepo,
+		categoryRepo: categoryRepo,
+		emailRepo:    emailRepo,
+		userRepo:     userRepo,
+		emailService: emailService,
+		logger:       logger,
+	}
+}
+
+func (s *cleanupService) OnCleaned(hook CleanupHook) {
+	s.cleanedHooks = append(s.cleanedHooks, hook)
+}
+
+func (s *cleanupService) CreatePolicy(ctx context.Context, userID, name, categoryID string, unread *bool, olderThanDays int, action, schedule string, enabled bool) (*model.CleanupPolicy, error) {
+	if err := s.validate(ctx, userID, categoryID, olderThanDays, action, schedule); err != nil {
+		return nil, err
+	}
+
+	policy := model.NewCleanupPolicy(userID, name, categoryID, unread, olderThanDays, action, schedule)
+	policy.Enabled = enabled
+	if err := s.policyRepo.Create(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to save cleanup policy: %w", err)
+	}
+
+	s.logger.Info("Created cleanup policy:", policy.ID, "for user:", userID)
+	return policy, nil
+}
+
+func (s *cleanupService) GetPolicies(ctx context.Context, userID string) ([]*model.CleanupPolicy, error) {
+	return s.policyRepo.FindByUserID(ctx, userID)
+}
+
+func (s *cleanupService) UpdatePolicy(ctx context.Context, userID, policyID, name, categoryID string, unread *bool, olderThanDays int, action, schedule string, enabled bool) (*model.CleanupPolicy, error) {
+	policy, err := s.policyRepo.FindByIDAndUser(ctx, policyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(ctx, userID, categoryID, olderThanDays, action, schedule); err != nil {
+		return nil, err
+	}
+
+	policy.Name = name
+	policy.CategoryID = categoryID
+	policy.Unread = unread
+	policy.OlderThanDays = olderThanDays
+	policy.Action = action
+	policy.Schedule = schedule
+	policy.Enabled = enabled
+	policy.UpdatedAt = time.Now()
+
+	if err := s.policyRepo.Update(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to update cleanup policy: %w", err)
+	}
+
+	s.logger.Info("Updated cleanup policy:", policy.ID)
+	return policy, nil
+}
+
+func (s *cleanupService) DeletePolicy(ctx context.Context, userID, policyID string) error {
+	policy, err := s.policyRepo.FindByIDAndUser(ctx, policyID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.policyRepo.Delete(ctx, policy.ID); err != nil {
+		return fmt.Errorf("failed to delete cleanup policy: %w", err)
+	}
+	if err := s.runRepo.DeleteByPolicyID(ctx, policy.ID); err != nil {
+		s.logger.Error("Failed to delete the runs of cleanup policy", policy.ID, ":", err)
+	}
+
+	s.logger.Info("Deleted cleanup policy:", policy.ID)
+	return nil
+}
+
+// validate checks what request tags can't: the category must be visible to the user
+func (s *cleanupService) validate(ctx context.Context, userID, categoryID string, olderThanDays int, action, schedule string) error {
+	var fields []apierror.FieldError
+	if _, err := s.categoryRepo.FindByIDAndUser(ctx, categoryID, userID); err != nil {
+		fields = append(fields, apierror.FieldError{Field: "category_id", Message: "does not exist"})
+	}
+	if !isCleanupAction(action) {
+		fields = append(fields, apierror.FieldError{Field: "action", Message: "is not supported by cleanup policies"})
+	}
+	if schedule != model.CleanupDaily && schedule != model.CleanupWeekly {
+		fields = append(fields, apierror.FieldError{Field: "schedule", Message: "must be daily or weekly"})
+	}
+	if olderThanDays < 0 {
+		fields = append(fields, apierror.FieldError{Field: "older_than_days", Message: "must not be negative"})
+	}
+
+	if len(fields) > 0 {
+		return apierror.InvalidFields(fields)
+	}
+	return nil
+}
+
+func isCleanupAction(action string) bool {
+	for _, supported := range model.CleanupActions {
+		if action == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// PreviewPolicy lists what the policy would clean if it ran now, without changing anything
+func (s *cleanupService) PreviewPolicy(ctx context.Context, userID, policyID string) (*model.CleanupPreview, error) {
+	policy, err := s.policyRepo.FindByIDAndUser(ctx, policyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	emails, err := s.matching(ctx, policy, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return model.NewCleanupPreview(emails), nil
+}
+
+func (s *cleanupService) GetRuns(ctx context.Context, userID, policyID string, limit int) ([]*model.CleanupRun, error) {
+	policy, err := s.policyRepo.FindByIDAndUser(ctx, policyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.runRepo.FindByPolicyID(ctx, policy.ID, limit)
+}
+
+// matching lists the emails the policy cleans as of now; emails from VIP senders stay where they are
+func (s *cleanupService) matching(ctx context.Context, policy *model.CleanupPolicy, now time.Time) ([]*model.Email, error) {
+	emails, err := s.emailRepo.FindByFilter(ctx, policy.UserID, policy.Filter(now), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emails: %w", err)
+	}
+
+	matched := emails[:0]
+	for _, email := range emails {
+		if !email.IsUrgent() {
+			matched = append(matched, email)
+		}
+	}
+	return matched, nil
+}
+
+// RunDue runs every enabled policy whose schedule has come round, records each run and returns
+// how many emails were cleaned
+func (s *cleanupService) RunDue(ctx context.Context) (int, error) {
+	policies, err := s.policyRepo.FindAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cleanup policies: %w", err)
+	}
+
+	now := time.Now()
+	locations := make(map[string]*time.Location)
+	cleaned := 0
+	for _, policy := range policies {
+		// Schedules follow the user's calendar, so daily policies run once per local day
+		location, known := locations[policy.UserID]
+		if !known {
+			location = time.UTC
+			if user, err := s.userRepo.FindByID(ctx, policy.UserID); err == nil {
+				location = user.Location()
+			}
+			locations[policy.UserID] = location
+		}
+		if !policy.IsDue(now.In(location)) {
+			continue
+		}
+
+		run := s.run(ctx, policy, now)
+		cleaned += run.Cleaned
+
+		// A failed run is retried on the next schedule, not the next pass, so a broken policy
+		// doesn't fail every pass
+		policy.LastRunAt = &now
+		if err := s.policyRepo.Update(ctx, policy); err != nil {
+			s.logger.Error("Failed to record the run of cleanup policy", policy.ID, ":", err)
+		}
+		if err := s.runRepo.Create(ctx, run); err != nil {
+			s.logger.Error("Failed to save the run of cleanup policy", policy.ID, ":", err)
+		}
+
+		if run.Cleaned > 0 || run.Error != "" {
+			for _, hook := range s.cleanedHooks {
+				hook(ctx, run)
+			}
+		}
+	}
+
+	if cleaned > 0 {
+		s.logger.Info("Cleanup policies cleaned", cleaned, "emails")
+	}
+	return cleaned, nil
+}
+
+// run applies the policy's action to the emails it matches now
+func (s *cleanupService) run(ctx context.Context, policy *model.CleanupPolicy, now time.Time) *model.CleanupRun {
+	run := model.NewCleanupRun(policy, now)
+
+	emails, err := s.matching(ctx, policy, now)
+	if err != nil {
+		run.Error = err.Error()
+		return run
+	}
+	run.Matched = len(emails)
+	if len(emails) == 0 {
+		return run
+	}
+
+	emailIDs := make([]string, len(emails))
+	for i, email := range emails {
+		emailIDs[i] = email.ID
+	}
+
+	if policy.Action == "delete" {
+		err = s.emailService.DeleteEmails(ctx, emailIDs, policy.UserID)
+	} else {
+		err = s.emailService.PerformBulkAction(ctx, emailIDs, policy.Action, policy.UserID)
+	}
+	if err != nil {
+		s.logger.Error("Failed to run cleanup policy", policy.ID, ":", err)
+		run.Error = err.Error()
+		return run
+	}
+
+	run.Cleaned = len(emailIDs)
+	return run
+}
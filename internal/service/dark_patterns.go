@@ -0,0 +1,204 @@
+package service
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"jump-challenge/internal/model"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Kinds of choices offered on unsubscribe pages
+const (
+	optionOther = iota
+	optionOptOut
+	optionPause
+	optionKeep
+)
+
+var (
+	// Checked in this order: "pause instead of unsubscribing" is a pause, "unsubscribe from
+	// marketing" an opt-out
+	pauseOptionText  = regexp.MustCompile(`(?i)\b(pause|snooze|take a break|fewer|less often|reduce|once a (week|month)|(weekly|monthly) (digest|summary))\b`)
+	optOutOptionText = regexp.MustCompile(`(?i)\b(unsubscribe|opt ?out|remove me|stop (all|sending|receiving)|no (more )?e-?mails?)\b`)
+	keepOptionText   = regexp.MustCompile(`(?i)\b(subscri\w*|newsletters?|marketing|offers?|promotions?|deals|news|updates|partners?|keep me|stay)\b`)
+	allOptionText    = regexp.MustCompile(`(?i)\ball\b`)
+)
+
+// unsubscribeOption is a choice on an unsubscribe form: a radio button, select option or
+// named submit button
+type unsubscribeOption struct {
+	name, value string
+	label       string // text shown to the user, for the attempt's selections
+	kind        int
+	all         bool // opts out of everything rather than one list
+	preselected bool
+}
+
+func newUnsubscribeOption(name, value, label string, preselected bool) unsubscribeOption {
+	text := strings.NewReplacer("_", " ", "-", " ").Replace(label + " " + name + " " + value)
+	option := unsubscribeOption{name: name, value: value, label: label, preselected: preselected}
+	switch {
+	case pauseOptionText.MatchString(text):
+		option.kind = optionPause
+	case optOutOptionText.MatchString(text):
+		option.kind = optionOptOut
+		option.all = allOptionText.MatchString(text)
+	case keepOptionText.MatchString(text):
+		option.kind = optionKeep
+	}
+	if option.label == "" {
+		option.label = name + "=" + value
+	}
+	return option
+}
+
+// inputOption describes a form input with its label, found by the for attribute or by wrapping
+func inputOption(input *goquery.Selection) unsubscribeOption {
+	name, _ := input.Attr("name")
+	value, hasValue := input.Attr("value")
+	if !hasValue {
+		value = "on"
+	}
+	_, checked := input.Attr("checked")
+
+	label := input.Closest("label").Text()
+	if id, ok := input.Attr("id"); ok && id != "" && label == "" {
+		input.Parents().Last().Find("label").Each(func(i int, candidate *goquery.Selection) {
+			if target, _ := candidate.Attr("for"); target == id {
+				label = candidate.Text()
+			}
+		})
+	}
+	return newUnsubscribeOption(name, value, strings.Join(strings.Fields(label), " "), checked)
+}
+
+// chooseCheckbox decides whether a checkbox is sent checked: opt-outs and confirmations are,
+// boxes keeping the user on a list never are, even when checked by default
+func chooseCheckbox(input *goquery.Selection, attempt *model.UnsubscribeAttempt) (string, string, bool) {
+	option := inputOption(input)
+	lowerName := strings.ToLower(option.name)
+	switch {
+	case option.kind == optionOptOut || strings.Contains(lowerName, "confirm") || strings.Contains(lowerName, "agree"):
+		attempt.Select("checked %q", option.label)
+		return option.name, option.value, true
+	case option.preselected && (option.kind == optionKeep || option.kind == optionPause):
+		attempt.AddDarkPattern(model.DarkPatternPrecheckedOptIn)
+		attempt.Select("unchecked %q", option.label)
+		return "", "", false
+	case option.preselected:
+		return option.name, option.value, true
+	default:
+		return "", "", false
+	}
+}
+
+// chooseOption picks among a radio group, select or submit buttons: the full opt-out when
+// offered, over pausing or receiving fewer emails, else what the page preselected
+func chooseOption(options []unsubscribeOption, attempt *model.UnsubscribeAttempt) (unsubscribeOption, bool) {
+	var optOut, preselected *unsubscribeOption
+	for i := range options {
+		option := &options[i]
+		if option.kind == optionPause {
+			attempt.AddDarkPattern(model.DarkPatternPauseInstead)
+		}
+		if option.kind == optionOptOut && (optOut == nil || (option.all && !optOut.all)) {
+			optOut = option
+		}
+		if option.preselected && preselected == nil {
+			preselected = option
+		}
+	}
+	switch {
+	case optOut != nil:
+		if preselected != nil && preselected != optOut && preselected.kind != optionOther {
+			attempt.AddDarkPattern(model.DarkPatternPrecheckedOptIn)
+		}
+		return *optOut, true
+	case preselected != nil:
+		return *preselected, true
+	default:
+		return unsubscribeOption{}, false
+	}
+}
+
+// chooseFormOptions fills the radio groups, selects and submit buttons of an unsubscribe form
+func chooseFormOptions(form *goquery.Selection, formData url.Values, attempt *model.UnsubscribeAttempt) {
+	var groupNames []string
+	groups := make(map[string][]unsubscribeOption)
+	form.Find("input[type='radio' i][name]").Each(func(i int, input *goquery.Selection) {
+		option := inputOption(input)
+		if _, seen := groups[option.name]; !seen {
+			groupNames = append(groupNames, option.name)
+		}
+		groups[option.name] = append(groups[option.name], option)
+	})
+	for _, name := range groupNames {
+		if option, ok := chooseOption(groups[name], attempt); ok {
+			formData[name] = append(formData[name], option.value)
+			attempt.Select("chose %q", option.label)
+		}
+	}
+
+	form.Find("select[name]").Each(func(i int, selectInput *goquery.Selection) {
+		name, _ := selectInput.Attr("name")
+		var options []unsubscribeOption
+		selectInput.Find("option").Each(func(i int, item *goquery.Selection) {
+			label := strings.Join(strings.Fields(item.Text()), " ")
+			value, hasValue := item.Attr("value")
+			if !hasValue {
+				value = label
+			}
+			_, selected := item.Attr("selected")
+			options = append(options, newUnsubscribeOption(name, value, label, selected))
+		})
+		option, ok := chooseOption(options, attempt)
+		if !ok && len(options) > 0 {
+			// Browsers submit the first option when none is selected
+			option, ok = options[0], true
+		}
+		if ok {
+			formData[name] = append(formData[name], option.value)
+			attempt.Select("chose %q", option.label)
+		}
+	})
+
+	// Named submit buttons tell the sender which one was pressed
+	var buttons []unsubscribeOption
+	form.Find("button[name], input[type='submit' i][name]").Each(func(i int, button *goquery.Selection) {
+		if buttonType, _ := button.Attr("type"); button.Is("button") && buttonType != "" && !strings.EqualFold(buttonType, "submit") {
+			return
+		}
+		name, _ := button.Attr("name")
+		value, _ := button.Attr("value")
+		label := strings.Join(strings.Fields(button.Text()), " ")
+		if label == "" {
+			label = value
+		}
+		buttons = append(buttons, newUnsubscribeOption(name, value, label, false))
+	})
+	if option, ok := chooseOption(buttons, attempt); ok {
+		formData[option.name] = append(formData[option.name], option.value)
+		attempt.Select("clicked %q", option.label)
+	}
+}
+
+// parseDarkPatterns records the DARK_PATTERN lines of the AI's answer and returns its action
+func parseDarkPatterns(answer string, attempt *model.UnsubscribeAttempt) string {
+	known := []string{model.DarkPatternPrecheckedOptIn, model.DarkPatternPauseInstead, model.DarkPatternConfirmshaming}
+	var action string
+	for _, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSpace(line)
+		if kind, found := strings.CutPrefix(line, "DARK_PATTERN:"); found {
+			if kind = strings.ToLower(strings.TrimSpace(kind)); slices.Contains(known, kind) {
+				attempt.AddDarkPattern(kind)
+			}
+		} else if action == "" && line != "" {
+			action = line
+		}
+	}
+	return action
+}
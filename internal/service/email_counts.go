@@ -0,0 +1,68 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"jump-challenge/internal/model"
+)
+
+// emailCountsTTL bounds how stale cached counts get after changes made outside the email
+// service, such as retention pruning; the service's own changes drop them right away
+const emailCountsTTL = time.Minute
+
+// emailCountsCache keeps each user's per-category email counts between list requests
+type emailCountsCache struct {
+	mutex   sync.Mutex
+	entries map[string]emailCountsEntry
+	// Bumped by every invalidation so counts computed before a change are never stored after it
+	generation uint64
+}
+
+type emailCountsEntry struct {
+	counts  map[string]model.EmailCounts
+	expires time.Time
+}
+
+func newEmailCountsCache() *emailCountsCache {
+	return &emailCountsCache{entries: make(map[string]emailCountsEntry)}
+}
+
+// get returns the user's cached counts, if fresh, and the generation to pass to set otherwise
+func (c *emailCountsCache) get(userID string) (map[string]model.EmailCounts, uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, c.generation, false
+	}
+	return entry.counts, c.generation, true
+}
+
+// set stores counts computed at generation, unless something was invalidated since
+func (c *emailCountsCache) set(userID string, counts map[string]model.EmailCounts, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.entries[userID] = emailCountsEntry{counts: counts, expires: time.Now().Add(emailCountsTTL)}
+}
+
+func (c *emailCountsCache) invalidate(userID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	delete(c.entries, userID)
+}
+
+func (c *emailCountsCache) invalidateAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	clear(c.entries)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+)
+
+// DefaultEmailDeltaLimit is how many changed emails one delta lists unless asked otherwise
+const DefaultEmailDeltaLimit = 500
+
+func (s *emailService) GetEmailDelta(ctx context.Context, userID, cursor string, limit int) (*model.EmailDelta, error) {
+	since, afterID, err := parseDeltaCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultEmailDeltaLimit
+	}
+
+	// One more than asked tells whether more changes follow
+	emails, err := s.emailRepo.FindChangedSince(ctx, userID, since, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	delta := &model.EmailDelta{Created: []string{}, Updated: []string{}, Deleted: []string{}, Cursor: cursor}
+	if len(emails) > limit {
+		emails = emails[:limit]
+		delta.HasMore = true
+	}
+
+	for _, email := range emails {
+		switch {
+		case email.DeletedAt != nil:
+			delta.Deleted = append(delta.Deleted, email.ID)
+		case email.CreatedAt.After(since):
+			delta.Created = append(delta.Created, email.ID)
+		default:
+			delta.Updated = append(delta.Updated, email.ID)
+		}
+	}
+	if len(emails) > 0 {
+		last := emails[len(emails)-1]
+		delta.Cursor = formatDeltaCursor(last.ChangedAt(), last.ID)
+	}
+	return delta, nil
+}
+
+// formatDeltaCursor encodes the position after an email in the order of changes; clients treat it as opaque
+func formatDeltaCursor(changedAt time.Time, emailID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(changedAt.UnixNano(), 10) + "." + emailID))
+}
+
+// parseDeltaCursor decodes a cursor, the empty one starting before every email
+func parseDeltaCursor(cursor string) (time.Time, string, error) {
+	if cursor == "" {
+		return time.Time{}, "", nil
+	}
+	invalid := apierror.Validation("invalid delta cursor")
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", invalid
+	}
+	nanos, emailID, found := strings.Cut(string(decoded), ".")
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if !found || err != nil {
+		return time.Time{}, "", invalid
+	}
+	return time.Unix(0, unixNano), emailID, nil
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/config"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
 	"jump-challenge/internal/repository"
@@ -16,16 +20,70 @@ type emailService struct {
 	emailRepo    repository.EmailRepository
 	categoryRepo repository.CategoryRepository
 	userRepo     repository.UserRepository
-	gmailClient  GmailClient
+	gmailClient  MailClient
 	aiClient     AIClient
 	logger       *logger.Logger
+	maxBodyBytes int
+
+	// Bounds of the email text sent to the AI, see SetAIInputLimits
+	aiChunkTokens int
+	aiMaxChunks   int
+
+	// Run after each newly synced email is saved; registered once at startup
+	classifiedHooks  []ClassifiedHook
+	categorizedHooks []CategorizedHook
+
+	// Meters synced emails and summaries; nil leaves them unlimited
+	quotas Quotas
+
+	// Percent of the monthly summaries kept from low priority categories
+	summaryBudgetReserve int
+
+	// Per-category counts for the category list, dropped by every change made here
+	counts *emailCountsCache
+
+	// Caches AI-generated sender profiles; nil disables them
+	profiles repository.SenderProfileRepository
+
+	// The senders whose emails are marked urgent; nil disables VIPs
+	vipSenders repository.VIPSenderRepository
+
+	// Routes part of the classifications to an alternative prompt or model; nil disables it
+	experiment ExperimentService
+
+	// Confidence, from 0 to 1, under which a classification waits in the review queue; 0
+	// disables the queue
+	reviewThreshold float64
+
+	// Records whether Gmail answers each user and queues the changes made while it doesn't;
+	// nil fails those changes instead
+	gmailMonitor *GmailMonitor
+	gmailQueue   repository.GmailActionRepository
+}
+
+// ErrGmailModifyScopeRequired is returned when an action changes Gmail state but the user
+// has only granted read access; the caller should send them through re-consent
+var ErrGmailModifyScopeRequired = &apierror.Error{
+	Status:  http.StatusForbidden,
+	Code:    apierror.CodeReauthRequired,
+	Message: "Gmail modify permission required",
+}
+
+// RequiresGmailModify reports whether a bulk action changes state in Gmail
+func RequiresGmailModify(action string) bool {
+	switch action {
+	case "archive", "read", "unread", "star", "unstar", "move", "spam", "delete":
+		return true
+	default:
+		return false
+	}
 }
 
 func NewEmailService(
 	emailRepo repository.EmailRepository,
 	categoryRepo repository.CategoryRepository,
 	userRepo repository.UserRepository,
-	gmailClient GmailClient,
+	gmailClient MailClient,
 	aiClient AIClient,
 	logger *logger.Logger,
 ) EmailService {
@@ -36,95 +94,142 @@ func NewEmailService(
 		gmailClient:  gmailClient,
 		aiClient:     aiClient,
 		logger:       logger,
+		maxBodyBytes: config.DefaultMaxEmailBodyBytes,
+		counts:       newEmailCountsCache(),
+
+		aiChunkTokens: config.DefaultAIChunkTokens,
+		aiMaxChunks:   config.DefaultAIMaxChunks,
 	}
 }
 
-func (s *emailService) SyncEmails(ctx context.Context, userID string, maxResults int64, afterEmailID string) error {
+// SyncEmails saves each fetched email at most once: new emails are prepared, classified and
+// archived in Gmail before being created in a single batch, stored ones only get Gmail's read
+// and starred state, and the rest is skipped
+func (s *emailService) SyncEmails(ctx context.Context, userID string, opts SyncOptions) (*model.SyncResult, error) {
+	defer s.counts.invalidate(userID)
+	result := &model.SyncResult{}
+
 	// Get user to access Gmail
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+		return result, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.NeedsReauth {
+		return result, gmailError("", ErrGmailReauthRequired)
 	}
 
-	// Get all categories to use for classification (shared across all users)
-	categories, err := s.categoryRepo.FindAll(ctx)
+	// Classify against the shared categories and the user's own
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get categories: %w", err)
+		return result, fmt.Errorf("failed to get categories: %w", err)
 	}
 
 	// Get emails from Gmail with the specified maxResults and afterEmailID
-	gmailEmails, err := s.gmailClient.SyncEmails(ctx, user.Email, maxResults, afterEmailID)
+	result.Fetched, err = s.fetchEmails(ctx, user.Email, opts.MaxResults, opts.AfterEmailID)
 	if err != nil {
-		return fmt.Errorf("failed to get emails from Gmail: %w", err)
+		return result, gmailError("failed to get emails from Gmail", err)
 	}
 
-	// Get the last 50 emails from the user's database to check for duplicates
-	userEmails, err := s.emailRepo.FindByUserID(ctx, userID)
+	// Look up the stored copies of the fetched emails, trashed ones included, which would
+	// otherwise be imported again
+	gmailIDs := make([]string, len(result.Fetched))
+	for i, email := range result.Fetched {
+		gmailIDs[i] = email.GmailID
+	}
+	stored, err := s.emailRepo.FindByGmailIDs(ctx, userID, gmailIDs)
 	if err != nil {
-		s.logger.Warn("Failed to get user's existing emails for comparison:", err)
-		// Continue anyway, just won't be able to check for duplicates properly
-		userEmails = []*model.Email{}
+		return result, fmt.Errorf("failed to look up stored emails: %w", err)
 	}
-
-	// Create a map for quick lookup of existing email IDs
-	existingEmailMap := make(map[string]*model.Email)
-	for _, email := range userEmails {
+	existingEmailMap := make(map[string]*model.Email, len(stored))
+	for _, email := range stored {
 		existingEmailMap[email.GmailID] = email
 	}
 
-	// Filter to only process emails that don't already exist
+	// Emails from VIP senders are marked urgent
+	vips := s.vipAddresses(ctx, userID)
+
+	// Gmail's read and starred state is behind the app's until the queued changes are replayed
+	queued := 0
+	if s.gmailQueue != nil {
+		if queued, err = s.gmailQueue.CountByUserID(ctx, userID); err != nil {
+			s.logger.Warn("Failed to count queued Gmail actions, keeping the stored state:", err)
+			queued = 1
+		}
+	}
+
+	// Split the fetched emails into the new ones, the stored ones Gmail changed, and the rest
 	var emailsToProcess []*model.Email
-	for _, gmailEmail := range gmailEmails {
-		if _, exists := existingEmailMap[gmailEmail.GmailID]; !exists {
-			gmailEmail.UserID = userID
-			emailsToProcess = append(emailsToProcess, gmailEmail)
-		} else {
-			s.logger.Info("Email already exists, skipping:", gmailEmail.GmailID)
+	for _, gmailEmail := range result.Fetched {
+		if user.IsSenderBlocked(gmailEmail.FromAddress) {
+			s.logger.Info("Sender is blocked, skipping:", gmailEmail.GmailID)
+			result.Skipped++
+			continue
+		}
+		if existing, exists := existingEmailMap[gmailEmail.GmailID]; exists {
+			if existing.DeletedAt == nil && queued == 0 && s.refreshGmailState(ctx, existing, gmailEmail) {
+				result.Updated = append(result.Updated, existing)
+			} else {
+				result.Skipped++
+			}
+			continue
 		}
+		s.prepareNewEmail(user, gmailEmail, vips)
+		emailsToProcess = append(emailsToProcess, gmailEmail)
 	}
 
-	s.logger.Info("Fetched", len(gmailEmails), "emails from Gmail, processing", len(emailsToProcess), "new emails")
+	granted, err := s.withinQuota(ctx, userID, emailsToProcess)
+	if err != nil {
+		result.Skipped += len(emailsToProcess)
+		return result, err
+	}
+	result.Skipped += len(emailsToProcess) - len(granted)
+	emailsToProcess = granted
+
+	s.logger.Info("Fetched", len(result.Fetched), "emails from Gmail, processing", len(emailsToProcess), "new emails")
 
-	// Process only the new emails
+	// Classify the new emails concurrently, then store them together
+	var mu sync.Mutex // protects classified
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(emailsToProcess))
+	var classified []*model.Email
 
 	for _, email := range emailsToProcess {
 		wg.Add(1)
 		go func(e *model.Email) {
 			defer wg.Done()
 
-			// Classify and summarize the email
-			if err := s.ClassifyAndSummarizeEmail(ctx, e, categories); err != nil {
-				s.logger.Error("Failed to classify and summarize email:", err)
-				errChan <- err
-				return
-			}
-
-			// Save the email to our database
-			if err := s.emailRepo.Create(ctx, e); err != nil {
-				s.logger.Error("Failed to save email:", err)
+			if err := s.classifyNewEmail(ctx, user, e, categories); err != nil {
 				errChan <- err
 				return
 			}
 
-			// Archive the email in Gmail
-			if err := s.gmailClient.ArchiveEmail(ctx, user.Email, e.GmailID); err != nil {
-				s.logger.Error("Failed to archive email in Gmail:", err)
-				// Don't return error here, we still want to save the email
-			} else {
-				e.Archived = true
-				// Update the email to mark as archived
-				if err := s.emailRepo.Update(ctx, e); err != nil {
-					s.logger.Error("Failed to update email archived status:", err)
-				}
-			}
+			mu.Lock()
+			classified = append(classified, e)
+			mu.Unlock()
 		}(email)
 	}
 
 	wg.Wait()
 	close(errChan)
 
+	// An email that fails to be saved is still fetched by the next sync, archived or not
+	if len(classified) > 0 {
+		if err := s.emailRepo.CreateBatch(ctx, classified); err != nil {
+			s.logger.Error("Failed to save emails:", err)
+			return result, fmt.Errorf("failed to save new emails: %w", err)
+		}
+	}
+	result.New = classified
+
+	for _, email := range result.New {
+		wg.Add(1)
+		go func(e *model.Email) {
+			defer wg.Done()
+			s.afterClassified(ctx, e)
+		}(email)
+	}
+	wg.Wait()
+
 	// Check for any errors during processing
 	var syncErr error
 	for err := range errChan {
@@ -135,131 +240,278 @@ func (s *emailService) SyncEmails(ctx context.Context, userID string, maxResults
 	}
 
 	if syncErr != nil {
-		return fmt.Errorf("failed to sync some emails: %w", syncErr)
+		return result, fmt.Errorf("failed to sync some emails: %w", syncErr)
 	}
 
-	return nil
+	return result, nil
 }
 
-// SyncEmailsWithNewEmails is similar to SyncEmails but returns the newly processed emails
-func (s *emailService) SyncEmailsWithNewEmails(ctx context.Context, userID string, maxResults int64, afterEmailID string) ([]*model.Email, []*model.Email, error) {
-	// Get user to access Gmail
-	user, err := s.userRepo.FindByID(ctx, userID)
+// fetchEmails gets the user's newest emails from Gmail, newest first. With afterEmailID, only
+// those newer than it, listed before it, are fetched. An email that fails to be fetched is
+// skipped, unless the failure says Gmail can't be called for the user at all.
+func (s *emailService) fetchEmails(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
+	messageIDs, err := s.gmailClient.List(ctx, userEmail, maxResults)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to list messages: %w", err)
 	}
 
-	// Get all categories to use for classification (shared across all users)
-	categories, err := s.categoryRepo.FindAll(ctx)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get categories: %w", err)
-	}
+	emails := []*model.Email{}
+	for _, messageID := range messageIDs {
+		if afterEmailID != "" && messageID == afterEmailID {
+			break
+		}
 
-	// Get emails from Gmail with the specified maxResults and afterEmailID
-	gmailEmails, err := s.gmailClient.SyncEmails(ctx, user.Email, maxResults, afterEmailID)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get emails from Gmail: %w", err)
+		email, err := s.gmailClient.Get(ctx, userEmail, messageID)
+		if err != nil {
+			if errors.Is(err, ErrGmailUnreachable) || errors.Is(err, ErrGmailUnauthorized) ||
+				errors.Is(err, ErrGmailReauthRequired) || errors.Is(err, ErrGmailCircuitOpen) || ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to get message %s: %w", messageID, err)
+			}
+			s.logger.Error("Failed to get message:", messageID, err)
+			continue
+		}
+		emails = append(emails, email)
 	}
 
-	// Get the last 50 emails from the user's database to check for duplicates
-	userEmails, err := s.emailRepo.FindByUserID(ctx, userID)
-	if err != nil {
-		s.logger.Warn("Failed to get user's existing emails for comparison:", err)
-		// Continue anyway, just won't be able to check for duplicates properly
-		userEmails = []*model.Email{}
+	s.logger.Info("Fetched", len(emails), "emails from Gmail")
+	return emails, nil
+}
+
+// prepareNewEmail readies an email fetched for the first time for storage: its links, snippet,
+// body size and the flags found in its content
+func (s *emailService) prepareNewEmail(user *model.User, email *model.Email, vips map[string]bool) {
+	email.UserID = user.ID
+	if unwrapTrackingLinks(email) {
+		s.logger.Info("Unwrapped tracking links in email:", email.GmailID)
+	}
+	if email.Snippet == "" {
+		email.SetSnippet(plainText(email.Body))
 	}
+	if email.TruncateBody(s.maxBodyBytes) {
+		s.logger.Info("Truncated oversized body for email:", email.GmailID)
+	}
+	if email.DetectSystemFlag() {
+		s.logger.Info("Detected", email.SystemFlag, "email:", email.GmailID)
+		email.LocallyArchived = user.ArchiveSystemEmails
+	}
+	if detectOTP(email) {
+		s.logger.Info("Found verification code in email:", email.GmailID)
+	}
+	if detectSecurityFlag(email) {
+		s.logger.Info("Detected", email.SecurityFlag, "security email:", email.GmailID)
+	}
+	if vips[email.FromAddress] {
+		email.Priority = model.EmailPriorityUrgent
+		email.LocallyArchived = false
+	}
+}
 
-	// Create a map for quick lookup of existing email IDs
-	existingEmailMap := make(map[string]*model.Email)
-	for _, email := range userEmails {
-		existingEmailMap[email.GmailID] = email
+// classifyNewEmail classifies and summarizes a new email and archives it in Gmail, readying it
+// to be stored
+func (s *emailService) classifyNewEmail(ctx context.Context, user *model.User, email *model.Email, categories []*model.Category) error {
+	if err := s.ClassifyAndSummarizeEmail(ctx, email, categories); err != nil {
+		s.logger.Error("Failed to classify and summarize email:", err)
+		return err
 	}
 
-	// Filter to only process emails that don't already exist
-	var emailsToProcess []*model.Email
-	for _, gmailEmail := range gmailEmails {
-		if _, exists := existingEmailMap[gmailEmail.GmailID]; !exists {
-			gmailEmail.UserID = userID
-			emailsToProcess = append(emailsToProcess, gmailEmail)
+	// Archive the email in Gmail; read-only users keep it in their inbox, as do VIP emails
+	if user.HasScope(model.ScopeGmailModify) && !email.IsUrgent() {
+		if err := s.gmailClient.Modify(ctx, user.Email, email.GmailID, nil, []string{model.LabelInbox, model.LabelUnread}); err != nil {
+			// The email is stored anyway, left in the inbox
+			s.logger.Error("Failed to archive email in Gmail:", err)
 		} else {
-			s.logger.Info("Email already exists, skipping:", gmailEmail.GmailID)
+			email.Archived = true
 		}
 	}
+	return nil
+}
+
+// refreshGmailState copies the read and starred state Gmail has for a stored email, and saves
+// it when that changed; the classification and everything else stored is kept. Trashed emails
+// are left as they are.
+func (s *emailService) refreshGmailState(ctx context.Context, stored, fetched *model.Email) bool {
+	if stored.DeletedAt != nil || (stored.Unread == fetched.Unread && stored.Starred == fetched.Starred) {
+		return false
+	}
 
-	s.logger.Info("Fetched", len(gmailEmails), "emails from Gmail, processing", len(emailsToProcess), "new emails")
+	if err := s.updateEmail(ctx, stored, func(email *model.Email) {
+		email.Unread = fetched.Unread
+		email.Starred = fetched.Starred
+	}); err != nil {
+		s.logger.Error("Failed to update email", stored.ID, "from Gmail:", err)
+		return false
+	}
+	return true
+}
 
-	// Process only the new emails
-	var processedEmails []*model.Email
-	var mu sync.Mutex // Mutex to protect access to processedEmails
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(emailsToProcess))
+// SetMaxBodyBytes limits how much of each synced body is stored, 0 disables the limit
+func (s *emailService) SetMaxBodyBytes(maxBytes int) {
+	s.maxBodyBytes = maxBytes
+}
 
-	for _, email := range emailsToProcess {
-		wg.Add(1)
-		go func(e *model.Email) {
-			defer wg.Done()
+func (s *emailService) UseQuotas(quotas Quotas) {
+	s.quotas = quotas
+}
 
-			// Classify and summarize the email
-			if err := s.ClassifyAndSummarizeEmail(ctx, e, categories); err != nil {
-				s.logger.Error("Failed to classify and summarize email:", err)
-				errChan <- err
-				return
-			}
+// withinQuota trims the new emails to as many as the user's plan still allows this month;
+// the rest stay in Gmail and are picked up by a sync once the quota resets
+func (s *emailService) withinQuota(ctx context.Context, userID string, emails []*model.Email) ([]*model.Email, error) {
+	if s.quotas == nil || len(emails) == 0 {
+		return emails, nil
+	}
 
-			// Save the email to our database
-			if err := s.emailRepo.Create(ctx, e); err != nil {
-				s.logger.Error("Failed to save email:", err)
-				errChan <- err
-				return
-			}
+	granted, err := s.quotas.Reserve(ctx, userID, model.MetricEmails, len(emails))
+	if err != nil {
+		return nil, err
+	}
+	if granted < len(emails) {
+		s.logger.Warn("Email quota reached for user", userID, ", skipping", len(emails)-granted, "new emails")
+	}
+	return emails[:granted], nil
+}
 
-			// Archive the email in Gmail
-			if err := s.gmailClient.ArchiveEmail(ctx, user.Email, e.GmailID); err != nil {
-				s.logger.Error("Failed to archive email in Gmail:", err)
-				// Don't return error here, we still want to save the email
-			} else {
-				e.Archived = true
-				// Update the email to mark as archived
-				if err := s.emailRepo.Update(ctx, e); err != nil {
-					s.logger.Error("Failed to update email archived status:", err)
-				}
-			}
+func (s *emailService) UseExperiment(experiment ExperimentService) {
+	s.experiment = experiment
+}
 
-			// Add to processed emails list in a thread-safe way
-			mu.Lock()
-			processedEmails = append(processedEmails, e)
-			mu.Unlock()
-		}(email)
+func (s *emailService) OnClassified(hook ClassifiedHook) {
+	s.classifiedHooks = append(s.classifiedHooks, hook)
+}
+
+func (s *emailService) OnCategorized(hook CategorizedHook) {
+	s.categorizedHooks = append(s.categorizedHooks, hook)
+}
+
+func (s *emailService) afterClassified(ctx context.Context, email *model.Email) {
+	for _, hook := range s.classifiedHooks {
+		hook(ctx, email)
 	}
+}
 
-	wg.Wait()
-	close(errChan)
+func (s *emailService) GetEmailsByUser(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	return s.emailRepo.FindByUserID(ctx, userID, limit)
+}
 
-	// Check for any errors during processing
-	var syncErr error
-	for err := range errChan {
+func (s *emailService) GetMostRecentEmail(ctx context.Context, userID string) (*model.Email, error) {
+	email, err := s.emailRepo.FindMostRecentByUserID(ctx, userID)
+	if errors.Is(err, apierror.ErrNotFound) {
+		return nil, nil
+	}
+	return email, err
+}
+
+// GetEmail returns a single email, making sure it belongs to the given user
+func (s *emailService) GetEmail(ctx context.Context, userID, emailID string) (*model.Email, error) {
+	return s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+}
+
+// GetEmailDetail returns one of the user's emails along with the context of the detail view
+func (s *emailService) GetEmailDetail(ctx context.Context, userID, emailID string, filter model.EmailFilter) (*model.EmailDetail, error) {
+	email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &model.EmailDetail{Email: email, CanUnsubscribe: HasUnsubscribeLink(email)}
+
+	if email.CategoryID != "" {
+		category, err := s.categoryRepo.FindByIDAndUser(ctx, email.CategoryID, userID)
+		if err != nil && !errors.Is(err, apierror.ErrNotFound) {
+			return nil, fmt.Errorf("failed to get category: %w", err)
+		}
+		detail.Category = category
+	}
+
+	if email.FromAddress != "" {
+		sender := model.EmailFilter{Sender: email.FromAddress, Archive: model.ArchiveFilterAll}
+		detail.SenderEmailCount, err = s.emailRepo.CountByFilter(ctx, userID, sender)
 		if err != nil {
-			syncErr = err // Just capture the first error for now
-			break
+			return nil, fmt.Errorf("failed to count sender emails: %w", err)
 		}
 	}
 
-	if syncErr != nil {
-		return gmailEmails, nil, fmt.Errorf("failed to sync some emails: %w", syncErr)
+	detail.PreviousID, detail.NextID, err = s.emailRepo.FindAdjacent(ctx, userID, filter, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find adjacent emails: %w", err)
+	}
+
+	return detail, nil
+}
+
+// GetSenderHistory returns every stored email from the address, trashed ones included, with
+// engagement stats to help decide on blocking or unsubscribing
+func (s *emailService) GetSenderHistory(ctx context.Context, userID, address string) (*model.SenderHistory, error) {
+	_, address = model.ParseFrom(address)
+	if !strings.Contains(address, "@") {
+		return nil, apierror.Validation("invalid sender address")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	emails, err := s.emailRepo.FindBySender(ctx, userID, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sender emails: %w", err)
+	}
+
+	history := model.NewSenderHistory(address, emails)
+	history.Blocked = user.IsSenderBlocked(address)
+	if history.Profile, err = s.findSenderProfile(ctx, userID, address); err != nil {
+		return nil, fmt.Errorf("failed to get sender profile: %w", err)
 	}
+	return history, nil
+}
+
+func (s *emailService) GetSystemEmailSettings(ctx context.Context, userID string) (*model.SystemEmailSettings, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &model.SystemEmailSettings{AutoArchive: user.ArchiveSystemEmails}, nil
+}
 
-	return gmailEmails, processedEmails, nil
+func (s *emailService) UpdateSystemEmailSettings(ctx context.Context, userID string, settings model.SystemEmailSettings) (*model.SystemEmailSettings, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	user.ArchiveSystemEmails = settings.AutoArchive
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save system email settings: %w", err)
+	}
+	return &settings, nil
 }
 
-func (s *emailService) GetEmailsByUser(ctx context.Context, userID string) ([]*model.Email, error) {
-	return s.emailRepo.FindByUserID(ctx, userID)
+func (s *emailService) GetEmailsByCategory(ctx context.Context, userID, categoryID string, limit int) ([]*model.Email, error) {
+	return s.emailRepo.FindByCategoryIDAndUser(ctx, categoryID, userID, limit)
 }
 
-func (s *emailService) GetEmailsByCategory(ctx context.Context, categoryID string) ([]*model.Email, error) {
-	return s.emailRepo.FindByCategoryID(ctx, categoryID)
+// ListEmails returns the user's emails matching the filter; locally archived emails are hidden unless requested
+func (s *emailService) ListEmails(ctx context.Context, userID string, filter model.EmailFilter, limit int) ([]*model.Email, error) {
+	return s.emailRepo.FindByFilter(ctx, userID, filter, limit)
 }
 
 func (s *emailService) ClassifyAndSummarizeEmail(ctx context.Context, email *model.Email, categories []*model.Category) error {
+	// Bounces and auto-replies would only pollute categories and spend AI quota
+	if email.SystemFlag != "" {
+		email.UpdatedAt = time.Now()
+		return nil
+	}
+
+	// In privacy mode the AI never reads emails of sensitive categories; they are recognized
+	// by the category they're in or their sender's
+	sensitive, err := s.sensitiveCategories(ctx, email.UserID)
+	if err != nil {
+		return err
+	}
+	if categoryID := s.sensitiveCategoryOf(ctx, email, sensitive); categoryID != "" {
+		email.CategoryID = categoryID
+		email.UpdatedAt = time.Now()
+		s.logger.Info("Filed email:", email.ID, "into sensitive category:", categoryID, "without the AI")
+		return nil
+	}
+
 	// Extract category names for classification
 	categoryInfo := make([]string, len(categories))
 	categoryMap := make(map[string]string) // name -> id
@@ -271,9 +523,9 @@ func (s *emailService) ClassifyAndSummarizeEmail(ctx context.Context, email *mod
 	}
 
 	// Classify the email
-	classifiedCategoryName, err := s.aiClient.ClassifyEmail(ctx, email.Body, categories)
+	classifiedCategoryName, confidence, arm, err := s.classifyEmail(ctx, email, categories)
 	if err != nil {
-		return fmt.Errorf("failed to classify email: %w", err)
+		return apierror.Upstream("failed to classify email", err)
 	}
 
 	// Find the category ID based on the name
@@ -288,71 +540,203 @@ func (s *emailService) ClassifyAndSummarizeEmail(ctx context.Context, email *mod
 	}
 
 	email.CategoryID = categoryID
+	email.Confidence = confidence
+	email.ReviewCategoryID = ""
+	email.UpdatedAt = time.Now()
+	if s.experiment != nil {
+		s.experiment.RecordClassification(ctx, email, arm)
+	}
+	s.holdForReview(email)
 
-	// Generate a summary for the email
-	summary, err := s.aiClient.SummarizeEmail(ctx, email.Body)
+	// Some categories are only classified, e.g. promotions not worth a summary, or sensitive
+	// ones in privacy mode
+	var category *model.Category
+	for _, candidate := range categories {
+		if candidate.ID == categoryID {
+			category = candidate
+		}
+	}
+	if sensitive[categoryID] || !s.shouldSummarize(ctx, email.UserID, category) {
+		s.logger.Info("Classified email:", email.ID, "into category:", categoryID, "without summarizing it")
+		return nil
+	}
+
+	// Summaries are metered on their own; without quota left the email is only classified
+	if s.quotas != nil {
+		if _, err := s.quotas.Reserve(ctx, email.UserID, model.MetricSummaries, 1); err != nil {
+			s.logger.Warn("Skipping summary of email", email.ID, ":", err)
+			return nil
+		}
+	}
+
+	// Generate a summary for the email; a rejected answer isn't stored, the email is summarized
+	// again on a later sync
+	summary, err := s.summarizeChecked(ctx, email.UserID, email.Body)
+	if errors.Is(err, ErrSummaryRejected) {
+		s.logger.Warn("Leaving email", email.ID, "without a summary for now:", err)
+		email.Summary = ""
+		email.NeedsReprocessing = true
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to summarize email: %w", err)
+		return apierror.Upstream("failed to summarize email", err)
 	}
 
 	email.Summary = summary
-	email.UpdatedAt = time.Now()
+	email.NeedsReprocessing = false
 
 	s.logger.Info("Classified and summarized email:", email.ID, "into category:", categoryID)
 	return nil
 }
 
+// ReclassifyCategory re-runs classification and summarization for every email in the category
+// and returns how many emails were updated. Each email is classified against the categories its owner can see.
+func (s *emailService) ReclassifyCategory(ctx context.Context, categoryID string) (int, error) {
+	// The category may be visible to many users
+	defer s.counts.invalidateAll()
+
+	categoriesByUser := make(map[string][]*model.Category)
+
+	emails, err := s.emailRepo.FindByCategoryID(ctx, categoryID, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get emails: %w", err)
+	}
+
+	updated := 0
+	for _, email := range emails {
+		// Nothing left to classify once retention dropped the body
+		if email.BodyPruned {
+			continue
+		}
+
+		categories, ok := categoriesByUser[email.UserID]
+		if !ok {
+			var err error
+			categories, err = s.categoryRepo.FindByUserID(ctx, email.UserID)
+			if err != nil {
+				return updated, fmt.Errorf("failed to get categories: %w", err)
+			}
+			categoriesByUser[email.UserID] = categories
+		}
+
+		if err := s.ClassifyAndSummarizeEmail(ctx, email, categories); err != nil {
+			s.logger.Error("Failed to reclassify email:", email.ID, err)
+			continue
+		}
+		classified := *email
+		err := s.updateEmail(ctx, email, func(email *model.Email) {
+			email.CategoryID = classified.CategoryID
+			email.ReviewCategoryID = classified.ReviewCategoryID
+			email.Confidence = classified.Confidence
+			email.Summary = classified.Summary
+			email.NeedsReprocessing = classified.NeedsReprocessing
+		})
+		if err != nil {
+			s.logger.Error("Failed to save reclassified email:", email.ID, err)
+			continue
+		}
+		updated++
+	}
+
+	s.logger.Info("Reclassified", updated, "of", len(emails), "emails in category:", categoryID)
+	return updated, nil
+}
+
 func (s *emailService) PerformBulkAction(ctx context.Context, emailIDs []string, action string, userID string) error {
+	defer s.counts.invalidate(userID)
+
 	// Get user to access Gmail
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
+	if RequiresGmailModify(action) && !user.HasScope(model.ScopeGmailModify) {
+		return ErrGmailModifyScopeRequired
+	}
+	if RequiresGmailModify(action) && user.NeedsReauth {
+		return gmailError("", ErrGmailReauthRequired)
+	}
+
 	// Process each email based on the action
 	for _, emailID := range emailIDs {
-		// Get email from database
-		email, err := s.emailRepo.FindByID(ctx, emailID)
+		// Get email from database, only if it belongs to the user
+		email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
 		if err != nil {
 			s.logger.Error("Failed to find email for bulk action:", err)
 			continue
 		}
 
-		// Verify that the email belongs to the user
-		if email.UserID != userID {
-			continue
-		}
-
 		switch action {
 		case "archive":
 			// Archive the email in Gmail
-			if err := s.gmailClient.ArchiveEmail(ctx, user.Email, email.GmailID); err != nil {
+			if err := s.modifyGmail(ctx, user, email, action); err != nil {
 				s.logger.Error("Failed to archive email in Gmail:", err)
 				continue
 			}
-			// Update the email to mark as archived in our DB
-			email.Archived = true
-			if err := s.emailRepo.Update(ctx, email); err != nil {
+			// Update the email to mark as archived in our DB; archiving also clears UNREAD in Gmail
+			if err := s.updateEmail(ctx, email, func(email *model.Email) {
+				email.Archived = true
+				email.Unread = false
+			}); err != nil {
 				s.logger.Error("Failed to update email archived status:", err)
 				continue
 			}
+		case "local_archive", "local_unarchive":
+			// Only hides or shows the email in the app; Gmail is left untouched
+			if err := s.updateEmail(ctx, email, func(email *model.Email) {
+				email.LocallyArchived = action == "local_archive"
+			}); err != nil {
+				s.logger.Error("Failed to update email local archive status:", err)
+				continue
+			}
 		case "read":
 			// Mark as read in Gmail
-			if err := s.gmailClient.MarkAsRead(ctx, user.Email, email.GmailID); err != nil {
+			if err := s.modifyGmail(ctx, user, email, action); err != nil {
 				s.logger.Error("Failed to mark email as read in Gmail:", err)
 				continue
 			}
+			if err := s.updateEmail(ctx, email, func(email *model.Email) {
+				email.Unread = false
+			}); err != nil {
+				s.logger.Error("Failed to update email read status:", err)
+				continue
+			}
+		case "unread":
+			// Mark as unread in Gmail
+			if err := s.modifyGmail(ctx, user, email, action); err != nil {
+				s.logger.Error("Failed to mark email as unread in Gmail:", err)
+				continue
+			}
+			if err := s.updateEmail(ctx, email, func(email *model.Email) {
+				email.Unread = true
+			}); err != nil {
+				s.logger.Error("Failed to update email read status:", err)
+				continue
+			}
+		case "star", "unstar":
+			// Add or remove the STARRED label in Gmail
+			if err := s.modifyGmail(ctx, user, email, action); err != nil {
+				s.logger.Error("Failed to", action, "email in Gmail:", err)
+				continue
+			}
+			if err := s.updateEmail(ctx, email, func(email *model.Email) {
+				email.Starred = action == "star"
+			}); err != nil {
+				s.logger.Error("Failed to update email starred status:", err)
+				continue
+			}
 		case "delete":
 			// Delete the email in Gmail (actually remove from Gmail)
-			// This would require implementing a DeleteEmail method in GmailClient
 			// For now, we'll implement archive functionality
-			if err := s.gmailClient.ArchiveEmail(ctx, user.Email, email.GmailID); err != nil {
+			if err := s.gmailClient.Modify(ctx, user.Email, email.GmailID, nil, []string{model.LabelInbox, model.LabelUnread}); err != nil {
 				s.logger.Error("Failed to archive email in Gmail (as delete action):", err)
 				continue
 			}
 			// Update the email to mark as archived in our DB
-			email.Archived = true
-			if err := s.emailRepo.Update(ctx, email); err != nil {
+			if err := s.updateEmail(ctx, email, func(email *model.Email) {
+				email.Archived = true
+			}); err != nil {
 				s.logger.Error("Failed to update email archived status:", err)
 				continue
 			}
@@ -366,29 +750,121 @@ func (s *emailService) PerformBulkAction(ctx context.Context, emailIDs []string,
 				continue
 			}
 		default:
-			return fmt.Errorf("unsupported bulk action: %s", action)
+			return apierror.Validation("unsupported bulk action: " + action)
+		}
+	}
+
+	return nil
+}
+
+func (s *emailService) MoveEmails(ctx context.Context, emailIDs []string, label string, userID string) error {
+	defer s.counts.invalidate(userID)
+
+	if label == "" {
+		return apierror.Validation("move requires a label")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.HasScope(model.ScopeGmailModify) {
+		return ErrGmailModifyScopeRequired
+	}
+
+	labelID, err := s.gmailClient.GetOrCreateLabel(ctx, user.Email, label)
+	if err != nil {
+		return gmailError("failed to resolve Gmail label", err)
+	}
+
+	for _, emailID := range emailIDs {
+		email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+		if err != nil {
+			s.logger.Error("Failed to find email for move:", err)
+			continue
+		}
+
+		if err := s.gmailClient.Modify(ctx, user.Email, email.GmailID, []string{labelID}, []string{model.LabelInbox}); err != nil {
+			s.logger.Error("Failed to move email in Gmail:", err)
+			continue
+		}
+		// Leaving the inbox is an archive as far as Gmail is concerned
+		if err := s.updateEmail(ctx, email, func(email *model.Email) {
+			email.Archived = true
+		}); err != nil {
+			s.logger.Error("Failed to update email archived status:", err)
+			continue
 		}
 	}
 
+	s.logger.Info("Moved", len(emailIDs), "emails to label:", label)
+	return nil
+}
+
+// ReportSpam moves the emails to Gmail's spam folder and, when asked, blocks their senders
+// so later syncs skip them
+func (s *emailService) ReportSpam(ctx context.Context, emailIDs []string, userID string, blockSender bool) error {
+	defer s.counts.invalidate(userID)
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.HasScope(model.ScopeGmailModify) {
+		return ErrGmailModifyScopeRequired
+	}
+
+	blocked := false
+	for _, emailID := range emailIDs {
+		email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+		if err != nil {
+			s.logger.Error("Failed to find email for spam report:", err)
+			continue
+		}
+
+		if err := s.gmailClient.Modify(ctx, user.Email, email.GmailID, []string{model.LabelSpam}, []string{model.LabelInbox}); err != nil {
+			s.logger.Error("Failed to report email as spam in Gmail:", err)
+			continue
+		}
+		// Spam leaves the inbox, and the app has no use for it either
+		if err := s.updateEmail(ctx, email, func(email *model.Email) {
+			email.Archived = true
+		}); err != nil {
+			s.logger.Error("Failed to update email archived status:", err)
+			continue
+		}
+		if err := s.emailRepo.Delete(ctx, email.ID); err != nil {
+			s.logger.Error("Failed to move spam email to trash:", err)
+			continue
+		}
+
+		if blockSender && user.BlockSender(email.FromAddress) {
+			blocked = true
+		}
+	}
+
+	if blocked {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to save blocked senders: %w", err)
+		}
+	}
+
+	s.logger.Info("Reported", len(emailIDs), "emails as spam for user:", userID)
 	return nil
 }
 
 func (s *emailService) DeleteEmails(ctx context.Context, emailIDs []string, userID string) error {
+	defer s.counts.invalidate(userID)
+
 	// Validate that all email IDs exist and belong to the user
 	var emailsToDelete []*model.Email
 	var gmailIDsToDelete []string
 
 	for _, emailID := range emailIDs {
-		// Get the email from database
-		email, err := s.emailRepo.FindByID(ctx, emailID)
+		// Get the email from database, only if it belongs to the user
+		email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
 		if err != nil {
-			s.logger.Error("Failed to find email for deletion:", emailID, err)
-			continue
-		}
-
-		// Verify that the email belongs to the user
-		if email.UserID != userID {
-			s.logger.Warn("User", userID, "attempted to delete email", emailID, "that doesn't belong to them")
+			s.logger.Warn("User", userID, "attempted to delete missing or foreign email", emailID, err)
 			continue
 		}
 
@@ -407,11 +883,15 @@ func (s *emailService) DeleteEmails(ctx context.Context, emailIDs []string, user
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
+	if !user.HasScope(model.ScopeGmailModify) {
+		return ErrGmailModifyScopeRequired
+	}
+
 	// Delete emails from Gmail first
-	if err := s.gmailClient.DeleteEmails(ctx, user.Email, gmailIDsToDelete); err != nil {
+	if err := s.gmailClient.Delete(ctx, user.Email, gmailIDsToDelete); err != nil {
 		s.logger.Error("Failed to delete emails from Gmail:", err)
 		// We should not continue with database deletion if Gmail deletion fails
-		return fmt.Errorf("failed to delete emails from Gmail: %w", err)
+		return gmailError("failed to delete emails from Gmail", err)
 	}
 
 	// Now delete from our database
@@ -421,7 +901,7 @@ func (s *emailService) DeleteEmails(ctx context.Context, emailIDs []string, user
 			s.logger.Error("Failed to delete email from database:", email.ID, err)
 			deletionErrors = append(deletionErrors, err)
 		} else {
-			s.logger.Info("Deleted email from database:", email.ID)
+			s.logger.Info("Moved email to trash:", email.ID)
 		}
 	}
 
@@ -436,17 +916,99 @@ func (s *emailService) DeleteEmails(ctx context.Context, emailIDs []string, user
 	return nil
 }
 
+// CountByCategory returns the user's email counts by category ID, cached until the next change
+func (s *emailService) CountByCategory(ctx context.Context, userID string) (map[string]model.EmailCounts, error) {
+	counts, generation, ok := s.counts.get(userID)
+	if ok {
+		return counts, nil
+	}
+
+	counts, err := s.emailRepo.CountByCategory(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count emails: %w", err)
+	}
+	s.counts.set(userID, counts, generation)
+	return counts, nil
+}
+
+// GetTrash returns the user's trashed emails, most recently deleted first
+func (s *emailService) GetTrash(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	return s.emailRepo.FindDeletedByUserID(ctx, userID, limit)
+}
+
+// RestoreEmail moves a trashed email back into the user's listings
+func (s *emailService) RestoreEmail(ctx context.Context, userID, emailID string) (*model.Email, error) {
+	defer s.counts.invalidate(userID)
+
+	email, err := s.GetEmail(ctx, userID, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	if email.DeletedAt == nil {
+		return nil, apierror.NotFound("email is not in the trash")
+	}
+
+	if err := s.emailRepo.Restore(ctx, email.ID); err != nil {
+		return nil, fmt.Errorf("failed to restore email: %w", err)
+	}
+
+	email.DeletedAt = nil
+	s.logger.Info("Restored email from trash:", email.ID)
+	return email, nil
+}
+
+func (s *emailService) CategorizeEmail(ctx context.Context, userID, emailID, categoryID string) (*model.Email, error) {
+	defer s.counts.invalidate(userID)
+
+	email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.categoryRepo.FindByIDAndUser(ctx, categoryID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.updateEmail(ctx, email, func(email *model.Email) {
+		email.CategoryID = category.ID
+		email.ReviewCategoryID = ""
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update email: %w", err)
+	}
+
+	s.logger.Info("Moved email", email.ID, "to category", category.Name)
+	for _, hook := range s.categorizedHooks {
+		hook(ctx, email)
+	}
+	return email, nil
+}
+
+// PurgeTrash permanently removes emails that have been in the trash for longer than retention
+func (s *emailService) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	purged, err := s.emailRepo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+
+	if purged > 0 {
+		s.logger.Info("Purged", purged, "emails from trash")
+	}
+	return purged, nil
+}
+
 func (s *emailService) ClassifyEmailByContent(ctx context.Context, userID string, emailBody string) (string, error) {
-	// Get all categories for classification (shared across all users)
-	categories, err := s.categoryRepo.FindAll(ctx)
+	// Classify against the shared categories and the user's own
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get categories: %w", err)
 	}
 
 	// Classify the email using AI with full category objects
-	classifiedCategory, err := s.aiClient.ClassifyEmail(ctx, emailBody, categories)
+	classifiedCategory, err := s.classify(ctx, emailBody, categories)
 	if err != nil {
-		return "", fmt.Errorf("failed to classify email: %w", err)
+		return "", apierror.Upstream("failed to classify email", err)
 	}
 
 	return classifiedCategory, nil
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// maxUpdateAttempts bounds how often an email saved concurrently is read again to retry an update
+const maxUpdateAttempts = 3
+
+// updateEmail applies change to the email and saves it. When sync, a bulk action or reprocessing
+// saved the email since it was read, the email is read again and the change applied to what they
+// saved, so change must only set the fields it is about.
+func (s *emailService) updateEmail(ctx context.Context, email *model.Email, change func(email *model.Email)) error {
+	for attempt := 1; ; attempt++ {
+		change(email)
+		email.UpdatedAt = time.Now()
+		err := s.emailRepo.Update(ctx, email)
+		if !errors.Is(err, repository.ErrEmailChanged) || attempt == maxUpdateAttempts {
+			return err
+		}
+
+		s.logger.Info("Email", email.ID, "was changed concurrently, retrying the update")
+		current, err := s.emailRepo.FindByID(ctx, email.ID)
+		if err != nil {
+			return err
+		}
+		*email = *current
+	}
+}
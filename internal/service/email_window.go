@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+
+	"jump-challenge/internal/model"
+)
+
+// DefaultEmailWindowLimit is how many emails one window lists unless asked otherwise
+const DefaultEmailWindowLimit = 50
+
+// ListEmailWindow lists a window of the emails ListEmails would, as rows
+func (s *emailService) ListEmailWindow(ctx context.Context, userID string, filter model.EmailFilter, offset, limit int) (*model.EmailWindow, error) {
+	if limit <= 0 {
+		limit = DefaultEmailWindowLimit
+	}
+	total, err := s.emailRepo.CountByFilter(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	emails, err := s.emailRepo.FindWindowByFilter(ctx, userID, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	window := &model.EmailWindow{Offset: offset, Total: total, Emails: make([]*model.EmailRow, len(emails))}
+	for i, email := range emails {
+		window.Emails[i] = model.NewEmailRow(email)
+	}
+	return window, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+type experimentService struct {
+	results repository.ExperimentResultRepository
+	logger  *logger.Logger
+
+	// The running experiment, set once at startup; no emails are routed while percent is 0
+	name      string
+	percent   int
+	control   model.ExperimentArm
+	treatment model.ExperimentArm
+	client    AIClient
+}
+
+// NewExperimentService creates the service without a running experiment; start one with StartExperiment
+func NewExperimentService(results repository.ExperimentResultRepository, logger *logger.Logger) ExperimentService {
+	return &experimentService{
+		results: results,
+		logger:  logger,
+	}
+}
+
+func (s *experimentService) StartExperiment(name string, percent int, control, treatment model.ExperimentArm, client AIClient) {
+	control.Name = model.ArmControl
+	treatment.Name = model.ArmTreatment
+	s.name = name
+	s.percent = percent
+	s.control = control
+	s.treatment = treatment
+	s.client = client
+	s.logger.Info("Started classification experiment", name, "on", percent, "percent of emails")
+}
+
+func (s *experimentService) running() bool {
+	return s.percent > 0 && s.client != nil
+}
+
+// Arm hashes the email ID with the experiment's name, so an email stays in its arm when it is
+// classified again, and each experiment draws its own sample
+func (s *experimentService) Arm(emailID string) (string, AIClient) {
+	if !s.running() {
+		return model.ArmControl, nil
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(s.name + "|" + emailID))
+	if int(hash.Sum32()%100) < s.percent {
+		return model.ArmTreatment, s.client
+	}
+	return model.ArmControl, nil
+}
+
+func (s *experimentService) RecordClassification(ctx context.Context, email *model.Email, arm string) {
+	if !s.running() {
+		return
+	}
+
+	// Classifying the email again, e.g. when its category is reclassified, replaces its result
+	if err := s.results.Save(ctx, model.NewExperimentResult(s.name, arm, email)); err != nil {
+		s.logger.Error("Failed to record experiment result of email", email.ID, ":", err)
+	}
+}
+
+func (s *experimentService) RecordCorrection(ctx context.Context, email *model.Email) {
+	if !s.running() {
+		return
+	}
+
+	result, err := s.results.FindByEmailID(ctx, s.name, email.ID)
+	if errors.Is(err, apierror.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get experiment result of email", email.ID, ":", err)
+		return
+	}
+
+	// Moving the email back where the arm put it takes the correction back
+	if email.CategoryID == result.CategoryID {
+		result.CorrectedCategoryID = ""
+		result.CorrectedAt = nil
+	} else {
+		now := time.Now()
+		result.CorrectedCategoryID = email.CategoryID
+		result.CorrectedAt = &now
+	}
+	if err := s.results.Save(ctx, result); err != nil {
+		s.logger.Error("Failed to record experiment correction of email", email.ID, ":", err)
+	}
+}
+
+func (s *experimentService) Report(ctx context.Context, name string) (*model.ExperimentReport, error) {
+	if name == "" {
+		name = s.name
+	}
+	if name == "" {
+		return nil, apierror.NotFound("no experiment is running")
+	}
+
+	counts, err := s.results.CountByArm(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ExperimentReport{Experiment: name}
+	byName := make(map[string]*model.ExperimentArmStats)
+	for _, arm := range counts {
+		byName[arm.Name] = arm
+	}
+	// The running experiment lists both arms with what they classify with, even before any
+	// email went to one; past experiments only have their counts
+	if name == s.name {
+		report.Running = s.running()
+		report.Percent = s.percent
+		for _, arm := range []model.ExperimentArm{s.control, s.treatment} {
+			if _, exists := byName[arm.Name]; !exists {
+				byName[arm.Name] = &model.ExperimentArmStats{}
+				counts = append(counts, byName[arm.Name])
+			}
+			byName[arm.Name].ExperimentArm = arm
+		}
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Name < counts[j].Name
+	})
+	for _, arm := range counts {
+		if arm.Classified > 0 {
+			arm.Accuracy = float64(arm.Classified-arm.Corrected) / float64(arm.Classified)
+		}
+	}
+	report.Arms = counts
+	return report, nil
+}
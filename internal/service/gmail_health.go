@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+)
+
+// ErrGmailUnreachable is wrapped by Gmail client errors that come from not reaching Gmail, such
+// as a network failure or a 5xx, rather than from Gmail refusing the request
+var ErrGmailUnreachable = errors.New("gmail is unreachable")
+
+// ErrGmailUnauthorized is wrapped by Gmail client errors that come from Gmail refusing the
+// user's credentials, such as an expired or revoked token
+var ErrGmailUnauthorized = errors.New("gmail refused the user's credentials")
+
+// ErrGmailReauthRequired is wrapped by Gmail client errors that come from Google refusing the
+// user's grant, such as answering invalid_grant to a token refresh once they revoked the app's
+// access; only signing in again gets the app new tokens
+var ErrGmailReauthRequired = errors.New("google refused the user's grant, they must sign in again")
+
+// ErrGmailCircuitOpen is wrapped, along with the error that opened the breaker, by the errors
+// of calls failed without reaching Gmail while the user's circuit breaker is open. They are
+// reported to API clients as unavailable.
+var ErrGmailCircuitOpen = errors.New("gmail calls are paused after repeated failures")
+
+// gmailError reports a failed Gmail call to API clients: as requiring the user to sign in again
+// when Google refused their grant, as unavailable while their breaker is open, and otherwise as
+// an upstream error with the message
+func gmailError(message string, err error) error {
+	switch {
+	case errors.Is(err, ErrGmailReauthRequired):
+		return &apierror.Error{
+			Status:  http.StatusForbidden,
+			Code:    apierror.CodeReauthRequired,
+			Message: "Google access was revoked, sign in again to reconnect Gmail",
+			Err:     err,
+		}
+	case errors.Is(err, ErrGmailCircuitOpen):
+		return err
+	default:
+		return apierror.Upstream(message, err)
+	}
+}
+
+// maxBreakerCooldown caps how long a breaker stays open as failed probes double its cooldown
+const maxBreakerCooldown = time.Hour
+
+// gmailCalls is what the monitor knows of one user's calls
+type gmailCalls struct {
+	since     time.Time // when the calls started failing to reach Gmail, zero while it answers
+	lastError string
+	requests  int64
+	failures  int64
+	rejected  int64
+
+	// Circuit breaker: open once consecutive reaches the monitor's threshold
+	consecutive int   // outages and refused credentials in a row
+	cause       error // the latest of them
+	openUntil   time.Time
+	cooldown    time.Duration // of the latest opening, doubled by each failed probe
+	probing     bool          // a half-open call is on its way
+}
+
+// reset ends the user's outage and closes their breaker, keeping the counts
+func (c *gmailCalls) reset() {
+	*c = gmailCalls{requests: c.requests, failures: c.failures, rejected: c.rejected}
+}
+
+// GmailMonitor is a MailClient recording, per user, whether their latest call reached Gmail.
+// A call Gmail answers, even with an error, ends an outage.
+//
+// It also guards each user's calls with a circuit breaker: after failures outages or refused
+// credentials in a row, their calls fail right away with ErrGmailCircuitOpen for the cooldown.
+// Then a single call probes Gmail; its success closes the breaker, its failure opens it again
+// for twice as long, up to an hour.
+type GmailMonitor struct {
+	client MailClient
+	logger *logger.Logger
+
+	mutex    sync.RWMutex
+	failures int
+	cooldown time.Duration
+	calls    map[string]*gmailCalls // by user address
+
+	reauthHooks []func(ctx context.Context, userEmail string)
+}
+
+var _ MailClient = (*GmailMonitor)(nil)
+
+// NewGmailMonitor wraps client; failures and cooldown configure the breakers, the defaults
+// when zero
+func NewGmailMonitor(client MailClient, failures int, cooldown time.Duration, logger *logger.Logger) *GmailMonitor {
+	m := &GmailMonitor{
+		client: client,
+		logger: logger,
+		calls:  make(map[string]*gmailCalls),
+	}
+	m.SetBreaker(failures, cooldown)
+	return m
+}
+
+// SetBreaker changes after how many failures in a row a user's breaker opens, and for how long
+// at first; the defaults when zero
+func (m *GmailMonitor) SetBreaker(failures int, cooldown time.Duration) {
+	if failures <= 0 {
+		failures = config.DefaultGmailBreakerFailures
+	}
+	if cooldown <= 0 {
+		cooldown = config.DefaultGmailBreakerCooldown
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.failures = failures
+	m.cooldown = cooldown
+}
+
+// OnReauthRequired adds a hook run with the address of a user whose call failed with
+// ErrGmailReauthRequired
+func (m *GmailMonitor) OnReauthRequired(hook func(ctx context.Context, userEmail string)) {
+	m.reauthHooks = append(m.reauthHooks, hook)
+}
+
+// Reset forgets the user's failures, closing their breaker, e.g. once they signed in again
+func (m *GmailMonitor) Reset(userEmail string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if calls, ok := m.calls[userEmail]; ok {
+		calls.reset()
+	}
+}
+
+// Health returns the user's Gmail status; QueuedActions is left for the caller to fill in
+func (m *GmailMonitor) Health(userEmail string) *model.GmailHealth {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	health := &model.GmailHealth{Status: model.GmailReachable, Breaker: model.BreakerClosed}
+	calls, ok := m.calls[userEmail]
+	if !ok {
+		return health
+	}
+	health.LastError = calls.lastError
+	if !calls.since.IsZero() {
+		since := calls.since
+		health.Status = model.GmailUnreachable
+		health.Since = &since
+	}
+	health.Breaker, health.RetryAt = m.state(calls, time.Now())
+	return health
+}
+
+// Breakers reports the calls of every user since the server started, by address
+func (m *GmailMonitor) Breakers() []model.GmailBreaker {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	now := time.Now()
+	breakers := make([]model.GmailBreaker, 0, len(m.calls))
+	for userEmail, calls := range m.calls {
+		breaker := model.GmailBreaker{
+			UserEmail:           userEmail,
+			ConsecutiveFailures: calls.consecutive,
+			LastError:           calls.lastError,
+			Requests:            calls.requests,
+			Failures:            calls.failures,
+			Rejected:            calls.rejected,
+		}
+		breaker.State, breaker.RetryAt = m.state(calls, now)
+		breakers = append(breakers, breaker)
+	}
+	sort.Slice(breakers, func(i, j int) bool {
+		return breakers[i].UserEmail < breakers[j].UserEmail
+	})
+	return breakers
+}
+
+// state returns the state of the user's breaker and, while open, when it lets a call through;
+// the caller holds the mutex
+func (m *GmailMonitor) state(calls *gmailCalls, now time.Time) (string, *time.Time) {
+	switch {
+	case calls.consecutive < m.failures:
+		return model.BreakerClosed, nil
+	case now.Before(calls.openUntil):
+		retryAt := calls.openUntil
+		return model.BreakerOpen, &retryAt
+	default:
+		return model.BreakerHalfOpen, nil
+	}
+}
+
+// callsOf returns the user's calls, adding them on their first one; the caller holds the mutex
+func (m *GmailMonitor) callsOf(userEmail string) *gmailCalls {
+	calls, ok := m.calls[userEmail]
+	if !ok {
+		calls = &gmailCalls{}
+		m.calls[userEmail] = calls
+	}
+	return calls
+}
+
+// call makes one of the user's calls unless their breaker is open, and records how it went
+func (m *GmailMonitor) call(ctx context.Context, userEmail string, request func() error) error {
+	if err := m.admit(userEmail); err != nil {
+		return err
+	}
+	err := request()
+	m.record(userEmail, err)
+	if errors.Is(err, ErrGmailReauthRequired) {
+		for _, hook := range m.reauthHooks {
+			hook(ctx, userEmail)
+		}
+	}
+	return err
+}
+
+// admit fails the call while the user's breaker is open. Once the cooldown is over, it lets a
+// single call at a time through to probe Gmail.
+func (m *GmailMonitor) admit(userEmail string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	calls := m.callsOf(userEmail)
+	if calls.consecutive >= m.failures {
+		if time.Now().Before(calls.openUntil) || calls.probing {
+			calls.rejected++
+			return &apierror.Error{
+				Status:  http.StatusServiceUnavailable,
+				Code:    apierror.CodeUnavailable,
+				Message: "Gmail calls are paused after repeated failures, try again later",
+				Err:     fmt.Errorf("%w until %s: %w", ErrGmailCircuitOpen, calls.openUntil.Format(time.RFC3339), calls.cause),
+			}
+		}
+		calls.probing = true
+	}
+	calls.requests++
+	return nil
+}
+
+// record notes the outcome of one of the user's calls
+func (m *GmailMonitor) record(userEmail string, err error) {
+	unreachable := errors.Is(err, ErrGmailUnreachable)
+	if !unreachable && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		// Says nothing of Gmail, but lets another call probe it
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		m.callsOf(userEmail).probing = false
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	calls := m.callsOf(userEmail)
+	calls.probing = false
+	if !unreachable && !errors.Is(err, ErrGmailUnauthorized) && !errors.Is(err, ErrGmailReauthRequired) {
+		if calls.consecutive >= m.failures {
+			m.logger.Info("Gmail answers", userEmail, "again, closing their circuit breaker")
+		}
+		calls.reset()
+		return
+	}
+
+	calls.failures++
+	calls.consecutive++
+	calls.cause = err
+	calls.lastError = err.Error()
+	if !unreachable {
+		calls.since = time.Time{}
+	} else if calls.since.IsZero() {
+		calls.since = time.Now()
+	}
+	if calls.consecutive < m.failures {
+		return
+	}
+	calls.cooldown = min(max(2*calls.cooldown, m.cooldown), maxBreakerCooldown)
+	calls.openUntil = time.Now().Add(calls.cooldown)
+	m.logger.Warn("Opened the Gmail circuit breaker of", userEmail, "for", calls.cooldown.String(), "after",
+		calls.consecutive, "failures in a row:", err)
+}
+
+func (m *GmailMonitor) List(ctx context.Context, userEmail string, maxResults int64) ([]string, error) {
+	var messageIDs []string
+	err := m.call(ctx, userEmail, func() (err error) {
+		messageIDs, err = m.client.List(ctx, userEmail, maxResults)
+		return err
+	})
+	return messageIDs, err
+}
+
+func (m *GmailMonitor) Get(ctx context.Context, userEmail, messageID string) (*model.Email, error) {
+	var email *model.Email
+	err := m.call(ctx, userEmail, func() (err error) {
+		email, err = m.client.Get(ctx, userEmail, messageID)
+		return err
+	})
+	return email, err
+}
+
+func (m *GmailMonitor) Modify(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+	return m.call(ctx, userEmail, func() error {
+		return m.client.Modify(ctx, userEmail, messageID, add, remove)
+	})
+}
+
+func (m *GmailMonitor) Trash(ctx context.Context, userEmail, messageID string) error {
+	return m.call(ctx, userEmail, func() error {
+		return m.client.Trash(ctx, userEmail, messageID)
+	})
+}
+
+func (m *GmailMonitor) Delete(ctx context.Context, userEmail string, messageIDs []string) error {
+	return m.call(ctx, userEmail, func() error {
+		return m.client.Delete(ctx, userEmail, messageIDs)
+	})
+}
+
+func (m *GmailMonitor) Send(ctx context.Context, userEmail string, raw []byte) error {
+	return m.call(ctx, userEmail, func() error {
+		return m.client.Send(ctx, userEmail, raw)
+	})
+}
+
+func (m *GmailMonitor) Watch(ctx context.Context, userEmail, topic string) (*model.MailWatch, error) {
+	var watch *model.MailWatch
+	err := m.call(ctx, userEmail, func() (err error) {
+		watch, err = m.client.Watch(ctx, userEmail, topic)
+		return err
+	})
+	return watch, err
+}
+
+func (m *GmailMonitor) GetOrCreateLabel(ctx context.Context, userEmail, name string) (string, error) {
+	var labelID string
+	err := m.call(ctx, userEmail, func() (err error) {
+		labelID, err = m.client.GetOrCreateLabel(ctx, userEmail, name)
+		return err
+	})
+	return labelID, err
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+func (s *emailService) UseGmailQueue(monitor *GmailMonitor, queue repository.GmailActionRepository) {
+	s.gmailMonitor = monitor
+	s.gmailQueue = queue
+}
+
+func (s *emailService) GetGmailHealth(ctx context.Context, userID string) (*model.GmailHealth, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &model.GmailHealth{Status: model.GmailReachable, Breaker: model.BreakerClosed}
+	if s.gmailMonitor != nil {
+		health = s.gmailMonitor.Health(user.Email)
+	}
+	if s.gmailQueue != nil {
+		if health.QueuedActions, err = s.gmailQueue.CountByUserID(ctx, userID); err != nil {
+			return nil, fmt.Errorf("failed to count queued Gmail actions: %w", err)
+		}
+	}
+	return health, nil
+}
+
+func (s *emailService) GetGmailBreakers() []model.GmailBreaker {
+	if s.gmailMonitor == nil {
+		return []model.GmailBreaker{}
+	}
+	return s.gmailMonitor.Breakers()
+}
+
+// gmailLabelChanges are the labels added and removed in Gmail to carry out the
+// QueueableGmailActions
+var gmailLabelChanges = map[string]struct{ add, remove []string }{
+	"archive": {remove: []string{model.LabelInbox, model.LabelUnread}},
+	"read":    {remove: []string{model.LabelUnread}},
+	"unread":  {add: []string{model.LabelUnread}},
+	"star":    {add: []string{model.LabelStarred}},
+	"unstar":  {remove: []string{model.LabelStarred}},
+}
+
+// gmailCall returns the Gmail call carrying out one of the QueueableGmailActions, nil for others
+func (s *emailService) gmailCall(action string) func(ctx context.Context, userEmail, messageID string) error {
+	labels, ok := gmailLabelChanges[action]
+	if !ok {
+		return nil
+	}
+	return func(ctx context.Context, userEmail, messageID string) error {
+		return s.gmailClient.Modify(ctx, userEmail, messageID, labels.add, labels.remove)
+	}
+}
+
+// modifyGmail carries one of the QueueableGmailActions out on the email in Gmail. While Gmail is
+// unreachable, or earlier actions of the user still wait to be replayed, the action is queued
+// behind them instead, and the caller applies it in the app meanwhile.
+func (s *emailService) modifyGmail(ctx context.Context, user *model.User, email *model.Email, action string) error {
+	queued := 0
+	if s.gmailQueue != nil {
+		var err error
+		if queued, err = s.gmailQueue.CountByUserID(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to count queued Gmail actions: %w", err)
+		}
+	}
+	if queued == 0 {
+		err := s.gmailCall(action)(ctx, user.Email, email.GmailID)
+		if err == nil || s.gmailQueue == nil || !errors.Is(err, ErrGmailUnreachable) {
+			return err
+		}
+	}
+
+	if err := s.gmailQueue.Create(ctx, model.NewGmailAction(user.ID, email.ID, email.GmailID, action)); err != nil {
+		return fmt.Errorf("failed to queue the %s action: %w", action, err)
+	}
+	s.logger.Warn("Queued", action, "of email", email.ID, "to replay in Gmail")
+	return nil
+}
+
+// ReplayGmailActions carries the queued actions out in Gmail, oldest first. The actions of a
+// user whose Gmail is still unreachable, whose calls are paused by their circuit breaker, or
+// who must sign in again are kept for the next pass; those Gmail refuses, e.g. because the email is gone there, are
+// dropped, and the next sync brings back Gmail's state.
+func (s *emailService) ReplayGmailActions(ctx context.Context) (int, error) {
+	if s.gmailQueue == nil {
+		return 0, nil
+	}
+	actions, err := s.gmailQueue.FindAll(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get queued Gmail actions: %w", err)
+	}
+
+	replayed := 0
+	users := make(map[string]*model.User)
+	unreachable := make(map[string]bool) // users to leave for the next pass
+	for _, action := range actions {
+		if unreachable[action.UserID] {
+			continue
+		}
+		user, ok := users[action.UserID]
+		if !ok {
+			user, err = s.userRepo.FindByID(ctx, action.UserID)
+			if err != nil && !errors.Is(err, apierror.ErrNotFound) {
+				return replayed, fmt.Errorf("failed to get user: %w", err)
+			}
+			users[action.UserID] = user
+		}
+
+		if user != nil && user.NeedsReauth {
+			unreachable[action.UserID] = true // replayed once they signed in again
+			continue
+		}
+		call := s.gmailCall(action.Action)
+		switch {
+		case user == nil:
+			s.logger.Warn("Dropping queued", action.Action, "of email", action.EmailID, "of a deleted user")
+		case call == nil:
+			s.logger.Warn("Dropping queued", action.Action, "of email", action.EmailID, ": not a queueable action")
+		default:
+			err := call(ctx, user.Email, action.GmailID)
+			if errors.Is(err, ErrGmailUnreachable) || errors.Is(err, ErrGmailCircuitOpen) {
+				unreachable[action.UserID] = true
+				continue
+			}
+			if err != nil {
+				s.logger.Warn("Dropping queued", action.Action, "of email", action.EmailID, "refused by Gmail:", err)
+			} else {
+				replayed++
+			}
+		}
+		if err := s.gmailQueue.Delete(ctx, action.ID); err != nil {
+			return replayed, fmt.Errorf("failed to dequeue Gmail action: %w", err)
+		}
+	}
+
+	if replayed > 0 {
+		s.logger.Info("Replayed", replayed, "queued actions in Gmail")
+	}
+	return replayed, nil
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ImageProxyPath is where rewritten images point, with the original address in the url parameter
+const ImageProxyPath = "/api/v1/proxy/image"
+
+const (
+	maxProxiedImageBytes = 5 << 20  // larger images are refused
+	imageCacheBytes      = 64 << 20 // the oldest images are evicted past this total
+	imageCacheTTL        = 24 * time.Hour
+	imageFetchTimeout    = 10 * time.Second
+)
+
+// trackingPixelDomains serve open-tracking pixels; their images are dropped from bodies, and
+// subdomains are matched too
+var trackingPixelDomains = []string{
+	"mailtrack.io",
+	"mandrillapp.com",
+	"list-manage.com",
+	"ct.sendgrid.net",
+	"sidekickopen01.com",
+	"sidekickopen02.com",
+	"sidekickopen03.com",
+	"hubspotlinks.com",
+	"track.hubspot.com",
+	"open.convertkit-mail.com",
+	"pixel.app.returnpath.net",
+	"google-analytics.com",
+	"bat.bing.com",
+	"mixpanel.com",
+	"track.customer.io",
+}
+
+// proxiedImageTypes are the image types served through the proxy; SVG is left out as it can
+// carry scripts
+var proxiedImageTypes = map[string]bool{
+	"image/png":    true,
+	"image/jpeg":   true,
+	"image/gif":    true,
+	"image/webp":   true,
+	"image/avif":   true,
+	"image/bmp":    true,
+	"image/x-icon": true,
+}
+
+type cachedImage struct {
+	image     *model.ProxiedImage
+	fetchedAt time.Time
+}
+
+type imageProxyService struct {
+	httpClient *http.Client
+	logger     *logger.Logger
+
+	cache      map[string]*cachedImage
+	cacheBytes int
+	mutex      sync.Mutex
+}
+
+// NewImageProxyService creates the proxy; a nil client defaults to one that refuses to connect
+// to loopback and private addresses, so the proxy can't reach internal services
+func NewImageProxyService(httpClient *http.Client, logger *logger.Logger) ImageProxyService {
+	if httpClient == nil {
+		httpClient = newPublicHTTPClient(imageFetchTimeout)
+	}
+	return &imageProxyService{
+		httpClient: httpClient,
+		logger:     logger,
+		cache:      make(map[string]*cachedImage),
+	}
+}
+
+func (s *imageProxyService) RewriteImages(body string) string {
+	if !strings.Contains(strings.ToLower(body), "<img") {
+		return body
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return body
+	}
+	doc.Find("img").Each(func(i int, img *goquery.Selection) {
+		// Responsive sources would be loaded directly
+		img.RemoveAttr("srcset")
+
+		src, _ := img.Attr("src")
+		imageURL, remote := remoteImageURL(src)
+		if !remote {
+			return
+		}
+		if isTrackingPixel(img, imageURL) {
+			img.Remove()
+			return
+		}
+		img.SetAttr("src", ImageProxyPath+"?url="+url.QueryEscape(imageURL.String()))
+	})
+
+	rewritten, err := doc.Html()
+	if err != nil {
+		return body
+	}
+	return rewritten
+}
+
+func (s *imageProxyService) FetchImage(ctx context.Context, rawURL string) (*model.ProxiedImage, error) {
+	imageURL, remote := remoteImageURL(rawURL)
+	if !remote {
+		return nil, apierror.Validation("url must be an http or https address")
+	}
+	if isTrackingDomain(imageURL.Hostname()) {
+		return nil, apierror.Forbidden("tracking images are blocked")
+	}
+	key := imageURL.String()
+
+	if image := s.cached(key); image != nil {
+		return image, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, apierror.Validation("invalid image url")
+	}
+	req.Header.Set("Accept", "image/*")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, errPrivateAddress) {
+			return nil, apierror.Forbidden("image address is not public")
+		}
+		return nil, apierror.Upstream("failed to fetch image", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, apierror.Upstream(fmt.Sprintf("image server returned %d", resp.StatusCode), nil)
+	}
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]))
+	if !proxiedImageTypes[contentType] {
+		return nil, apierror.Upstream("url is not a supported image", nil)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProxiedImageBytes+1))
+	if err != nil {
+		return nil, apierror.Upstream("failed to read image", err)
+	}
+	if len(data) > maxProxiedImageBytes {
+		return nil, apierror.Upstream("image is too large", nil)
+	}
+
+	image := &model.ProxiedImage{ContentType: contentType, Data: data}
+	s.store(key, image)
+	return image, nil
+}
+
+func (s *imageProxyService) cached(key string) *model.ProxiedImage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, exists := s.cache[key]
+	if !exists {
+		return nil
+	}
+	if time.Since(entry.fetchedAt) > imageCacheTTL {
+		s.evict(key)
+		return nil
+	}
+	return entry.image
+}
+
+// store caches the image, evicting the oldest ones once the cache is over its size budget
+func (s *imageProxyService) store(key string, image *model.ProxiedImage) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.cache[key]; exists {
+		s.evict(key)
+	}
+	s.cache[key] = &cachedImage{image: image, fetchedAt: time.Now()}
+	s.cacheBytes += len(image.Data)
+
+	for s.cacheBytes > imageCacheBytes {
+		oldest := ""
+		for cachedKey, entry := range s.cache {
+			if oldest == "" || entry.fetchedAt.Before(s.cache[oldest].fetchedAt) {
+				oldest = cachedKey
+			}
+		}
+		s.evict(oldest)
+	}
+}
+
+func (s *imageProxyService) evict(key string) {
+	s.cacheBytes -= len(s.cache[key].image.Data)
+	delete(s.cache, key)
+}
+
+// remoteImageURL parses an image source, reporting whether it is fetched over the network;
+// inline data: and cid: images are not. Protocol-relative sources are taken as https.
+func remoteImageURL(src string) (*url.URL, bool) {
+	src = strings.TrimSpace(src)
+	if strings.HasPrefix(src, "//") {
+		src = "https:" + src
+	}
+	parsed, err := url.Parse(src)
+	if err != nil || parsed.Host == "" {
+		return nil, false
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// isTrackingPixel reports whether an image only exists to confirm the email was opened: it is
+// served by a known tracking domain or sized 1x1 or smaller
+func isTrackingPixel(img *goquery.Selection, imageURL *url.URL) bool {
+	if isTrackingDomain(imageURL.Hostname()) {
+		return true
+	}
+	width, hasWidth := img.Attr("width")
+	height, hasHeight := img.Attr("height")
+	return hasWidth && hasHeight && isPixelSize(width) && isPixelSize(height)
+}
+
+func isPixelSize(value string) bool {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	return value == "0" || value == "1"
+}
+
+func isTrackingDomain(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range trackingPixelDomains {
+		if matchesDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// newPublicHTTPClient returns a client for addresses taken from email bodies, which only
+// connects to public hosts
+func newPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refusePrivateAddresses}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+}
+
+var errPrivateAddress = errors.New("connection to a non-public address refused")
+
+// refusePrivateAddresses is a dialer control rejecting loopback, private, link-local and other
+// non-public destinations, checked after DNS resolution and on every redirect
+func refusePrivateAddresses(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
@@ -2,44 +2,572 @@ package service
 
 import (
 	"context"
+	"net/http"
+	"time"
 
+	"jump-challenge/internal/emailtemplate"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
 )
 
 type AuthService interface {
 	GetOrCreateUser(ctx context.Context, googleID, email, name, accessToken, refreshToken string, tokenExpiry interface{}) (*model.User, error)
 	GetUser(ctx context.Context, userID string) (*model.User, error)
+	// SetGrantedScopes records the scopes granted by the user's latest consent
+	SetGrantedScopes(ctx context.Context, userID string, scopes []string) (*model.User, error)
+	// SetTimeZone sets the IANA time zone the user's schedules follow; empty means UTC
+	SetTimeZone(ctx context.Context, userID, timeZone string) (*model.User, error)
+	// DetectTimeZone sets the time zone the user's browser reported at login, unless the user
+	// already has one; unknown names are ignored
+	DetectTimeZone(ctx context.Context, userID, timeZone string) error
+	// SetLocale sets the language of the user's API messages and AI summaries; empty follows
+	// the browser's Accept-Language and leaves summaries in the email's language
+	SetLocale(ctx context.Context, userID, locale string) (*model.User, error)
+	// UseWorkspaceDomain grants the users of the Workspace domain the scopes delegated to the
+	// service account, which calls Gmail for them, whatever they consent to
+	UseWorkspaceDomain(domain string)
+	// OnTokenRefresh adds a hook run when a returning user signs in with new Google tokens
+	OnTokenRefresh(hook TokenRefreshHook)
+	// MarkNeedsReauth records that Google refused the grant of the user with the address, e.g.
+	// because they revoked the app's access, so they are asked to sign in again; signing in
+	// clears it. The hooks run when the user wasn't marked yet.
+	MarkNeedsReauth(ctx context.Context, userEmail string)
+	// OnNeedsReauth adds a hook run when a user is marked as needing to sign in again
+	OnNeedsReauth(hook NeedsReauthHook)
+}
+
+// TokenRefreshHook is called with a user whose new Google tokens were just saved
+type TokenRefreshHook func(ctx context.Context, user *model.User)
+
+// NeedsReauthHook is called with a user just marked as needing to sign in again
+type NeedsReauthHook func(ctx context.Context, user *model.User)
+
+// AccountMergeService merges a duplicate user, e.g. from signing in with a second Google
+// account, into the one the user keeps. Control of both is proven by a code created signed in
+// to the duplicate and redeemed signed in to the kept account.
+type AccountMergeService interface {
+	// CreateMergeCode returns a one-time code that lets another user take this one's data over
+	CreateMergeCode(ctx context.Context, userID string) (*model.AccountMergeCode, error)
+	// Merge moves the emails, categories and settings of the user who created the code to
+	// userID and deletes that user
+	Merge(ctx context.Context, userID, code string) (*model.AccountMerge, error)
+}
+
+// WorkspaceService manages the mailboxes of the Workspace domain synced with domain-wide
+// delegation, for operators. The service account calls Gmail for them, so they are synced
+// without their users signing in.
+type WorkspaceService interface {
+	// Connection returns the domain, what its admin authorizes for the service account and the
+	// designated mailboxes
+	Connection(ctx context.Context) (*model.WorkspaceConnection, error)
+	// AddMailbox designates a mailbox of the domain to be synced, once the service account read
+	// it; a user who already signed in to it keeps their account
+	AddMailbox(ctx context.Context, email, name string) (*model.WorkspaceMailbox, error)
+	// RemoveMailbox stops syncing the mailbox while nobody watches it; its emails are kept
+	RemoveMailbox(ctx context.Context, email string) error
 }
 
 type CategoryService interface {
 	CreateCategory(ctx context.Context, userID, name, description string) (*model.Category, error)
-	GetCategory(ctx context.Context, categoryID string) (*model.Category, error)
-	GetAllCategories(ctx context.Context) ([]*model.Category, error)
-	UpdateCategory(ctx context.Context, categoryID, name, description string) (*model.Category, error)
-	DeleteCategory(ctx context.Context, categoryID string) error
+	// CreateTeamCategory adds a category to the admin's organization taxonomy
+	CreateTeamCategory(ctx context.Context, userID, name, description string) (*model.Category, error)
+	// GetCategory and GetAllCategories only see shared categories, the user's own and their organization's
+	GetCategory(ctx context.Context, userID, categoryID string) (*model.Category, error)
+	GetAllCategories(ctx context.Context, userID string) ([]*model.Category, error)
+	// GetCategoriesWithCounts is GetAllCategories with each category's email counts for the user
+	GetCategoriesWithCounts(ctx context.Context, userID string) ([]*model.CategoryWithCounts, error)
+	// UseEmailCounts sets where GetCategoriesWithCounts gets counts from; without it they are zero
+	UseEmailCounts(counter EmailCounter)
+	// UpdateCategory and DeleteCategory only touch the user's own categories, or the
+	// organization's when the user is an admin
+	UpdateCategory(ctx context.Context, userID, categoryID, name, description string) (*model.Category, error)
+	DeleteCategory(ctx context.Context, userID, categoryID string) error
+	// SetCategoryHints replaces the examples given to the AI when classifying into one of the
+	// user's categories; empty hints remove them
+	SetCategoryHints(ctx context.Context, userID, categoryID, hints string) (*model.Category, error)
+	// SetCategorySummarize sets whether emails classified into one of the user's categories
+	// are summarized, to one of the model.Summarize modes
+	SetCategorySummarize(ctx context.Context, userID, categoryID, mode string) (*model.Category, error)
+	// SetCategorySensitive marks one of the user's categories as sensitive, keeping its emails
+	// away from the AI and out of exports in privacy mode
+	SetCategorySensitive(ctx context.Context, userID, categoryID string, sensitive bool) (*model.Category, error)
+}
+
+// SyncOptions selects the emails a sync fetches from Gmail
+type SyncOptions struct {
+	MaxResults   int64  // MAX_FETCH_EMAILS when 0
+	AfterEmailID string // only fetch emails newer than this Gmail ID when set
 }
 
 type EmailService interface {
-	SyncEmails(ctx context.Context, userID string, maxResults int64, afterEmailID string) error
-	SyncEmailsWithNewEmails(ctx context.Context, userID string, maxResults int64, afterEmailID string) ([]*model.Email, []*model.Email, error)
-	GetEmailsByUser(ctx context.Context, userID string) ([]*model.Email, error)
-	GetEmailsByCategory(ctx context.Context, categoryID string) ([]*model.Email, error)
+	// SyncEmails fetches the user's emails from Gmail, stores the new ones classified and
+	// summarized, and refreshes the stored ones Gmail changed. The result accounts for every
+	// fetched email, also when an error stopped the sync partway.
+	SyncEmails(ctx context.Context, userID string, opts SyncOptions) (*model.SyncResult, error)
+	GetEmailsByUser(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	// GetMostRecentEmail returns the user's newest email outside the trash, nil when they have none
+	GetMostRecentEmail(ctx context.Context, userID string) (*model.Email, error)
+	GetEmail(ctx context.Context, userID, emailID string) (*model.Email, error)
+	// GetEmailDetail returns an email with its category, sender history and neighbours within filter
+	GetEmailDetail(ctx context.Context, userID, emailID string, filter model.EmailFilter) (*model.EmailDetail, error)
+	GetEmailsByCategory(ctx context.Context, userID, categoryID string, limit int) ([]*model.Email, error)
+	ListEmails(ctx context.Context, userID string, filter model.EmailFilter, limit int) ([]*model.Email, error)
+	// ListEmailWindow lists limit emails matching the filter from offset on, as rows, with how
+	// many match in all; limit <= 0 means DefaultEmailWindowLimit
+	ListEmailWindow(ctx context.Context, userID string, filter model.EmailFilter, offset, limit int) (*model.EmailWindow, error)
+	// GetSenderHistory lists every stored email from the address, oldest first, with engagement stats
+	GetSenderHistory(ctx context.Context, userID, address string) (*model.SenderHistory, error)
+	GetSystemEmailSettings(ctx context.Context, userID string) (*model.SystemEmailSettings, error)
+	// UpdateSystemEmailSettings applies to bounces and auto-replies synced from now on
+	UpdateSystemEmailSettings(ctx context.Context, userID string, settings model.SystemEmailSettings) (*model.SystemEmailSettings, error)
+	GetPrivacySettings(ctx context.Context, userID string) (*model.PrivacySettings, error)
+	UpdatePrivacySettings(ctx context.Context, userID string, settings model.PrivacySettings) (*model.PrivacySettings, error)
+	// IsSensitive reports whether the email is in a sensitive category of a user in privacy
+	// mode, which keeps it away from the AI
+	IsSensitive(ctx context.Context, email *model.Email) bool
+	// ExportEmails lists every email of the user, without the content of sensitive ones in
+	// privacy mode
+	ExportEmails(ctx context.Context, userID string) ([]*model.Email, error)
+	// GenerateSenderProfile characterizes the sender with the AI, reusing the cached profile
+	// while the sender's emails are unchanged unless refresh is set
+	GenerateSenderProfile(ctx context.Context, userID, address string, refresh bool) (*model.SenderProfile, error)
 	ClassifyAndSummarizeEmail(ctx context.Context, email *model.Email, categories []*model.Category) error
+	ReclassifyCategory(ctx context.Context, categoryID string) (int, error)
+	// ReprocessEmails summarizes again a few of the user's emails whose summary the AI answered
+	// unusably, and returns how many now have one
+	ReprocessEmails(ctx context.Context, userID string) (int, error)
 	PerformBulkAction(ctx context.Context, emailIDs []string, action string, userID string) error
+	// MoveEmails files the emails under a Gmail label, created when missing, and removes them from the inbox
+	MoveEmails(ctx context.Context, emailIDs []string, label string, userID string) error
+	ReportSpam(ctx context.Context, emailIDs []string, userID string, blockSender bool) error
+	// CategorizeEmail files the email under one of the user's categories, overriding the AI classification
+	CategorizeEmail(ctx context.Context, userID, emailID, categoryID string) (*model.Email, error)
+	// GetReviewQueue lists the user's emails classified with too little confidence to be filed,
+	// newest first
+	GetReviewQueue(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	// ResolveReview files an email of the review queue under the category the user picked
+	ResolveReview(ctx context.Context, userID, emailID, categoryID string) (*model.Email, error)
 	DeleteEmails(ctx context.Context, emailIDs []string, userID string) error
+	GetTrash(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	// GetEmailDelta lists the IDs of the user's emails created, updated or trashed since the cursor,
+	// "" for all of them, at most limit of them (DefaultEmailDeltaLimit when <= 0)
+	GetEmailDelta(ctx context.Context, userID, cursor string, limit int) (*model.EmailDelta, error)
+	// GetGmailHealth reports whether Gmail answered the user's latest call and how many of their
+	// changes wait to be replayed there
+	GetGmailHealth(ctx context.Context, userID string) (*model.GmailHealth, error)
+	// GetGmailBreakers reports the Gmail calls and circuit breaker of every user who made one
+	// since the server started, for operators
+	GetGmailBreakers() []model.GmailBreaker
+	// ReplayGmailActions carries out in Gmail the changes queued while it was unreachable and
+	// returns how many went through
+	ReplayGmailActions(ctx context.Context) (int, error)
+	EmailCounter
+	RestoreEmail(ctx context.Context, userID, emailID string) (*model.Email, error)
+	PurgeTrash(ctx context.Context, retention time.Duration) (int, error)
+	// GetOTP returns the verification code found in the email at sync
+	GetOTP(ctx context.Context, userID, emailID string) (*model.OTP, error)
+	// GetSecurityEvents lists the user's password resets, new sign-ins and other account-security emails, newest first
+	GetSecurityEvents(ctx context.Context, userID string, limit int) ([]*model.SecurityEvent, error)
+	GetVIPSenders(ctx context.Context, userID string) ([]*model.VIPSender, error)
+	// AddVIPSender marks the sender as VIP; their emails, stored and to come, become urgent
+	AddVIPSender(ctx context.Context, userID, address, name string) (*model.VIPSender, error)
+	RemoveVIPSender(ctx context.Context, userID, address string) error
+	// GetVIPEmails lists the user's urgent emails, newest first
+	GetVIPEmails(ctx context.Context, userID string, limit int) ([]*model.Email, error)
+	// TrashOTPEmails moves emails holding a verification code to the trash once older than retention
+	TrashOTPEmails(ctx context.Context, retention time.Duration) (int, error)
 	ClassifyEmailByContent(ctx context.Context, userID string, emailBody string) (string, error)
+	// OnClassified adds a hook run for each new email once a sync has classified and saved it
+	OnClassified(hook ClassifiedHook)
+	// OnCategorized adds a hook run when the user files an email under another category
+	OnCategorized(hook CategorizedHook)
+	// SetMaxBodyBytes limits how much of each synced body is stored, 0 disables the limit
+	SetMaxBodyBytes(maxBytes int)
+	// SetAIInputLimits bounds the email text sent to the AI: chunkTokens per request, and
+	// maxChunks requests to summarize a long email
+	SetAIInputLimits(chunkTokens, maxChunks int)
+	// UseQuotas meters synced emails and AI summaries against the user's plan
+	UseQuotas(quotas Quotas)
+	// SetSummaryBudgetReserve stops summarizing low priority categories once less than percent
+	// of the user's monthly summaries are left; 0 summarizes them until the quota runs out
+	SetSummaryBudgetReserve(percent int)
+	// UseSenderProfiles caches generated sender profiles and adds them to sender histories
+	UseSenderProfiles(profiles repository.SenderProfileRepository)
+	// UseVIPSenders enables VIP senders, whose emails skip archiving and automations
+	UseVIPSenders(vipSenders repository.VIPSenderRepository)
+	// UseExperiment classifies part of the emails with the experiment's alternative AI client
+	UseExperiment(experiment ExperimentService)
+	// UseGmailQueue reports Gmail's health from the monitor wrapping the Gmail client, and queues
+	// archiving, reading and starring for replay while Gmail is unreachable instead of failing them
+	UseGmailQueue(monitor *GmailMonitor, queue repository.GmailActionRepository)
+	// SetReviewThreshold holds classifications made with less than percent confidence in the
+	// review queue instead of filing them; 0 files every email
+	SetReviewThreshold(percent int)
+}
+
+// ClassifiedHook is called with a newly synced email after it was classified and saved
+type ClassifiedHook func(ctx context.Context, email *model.Email)
+
+// CategorizedHook is called with an email the user filed by hand, already saved in its new category
+type CategorizedHook func(ctx context.Context, email *model.Email)
+
+// SensitiveCheck reports whether an email must be kept away from the AI
+type SensitiveCheck func(ctx context.Context, email *model.Email) bool
+
+type RetentionService interface {
+	// GetPolicy returns the user's policy, or a disabled policy if none was saved
+	GetPolicy(ctx context.Context, userID string) (*model.RetentionPolicy, error)
+	UpdatePolicy(ctx context.Context, userID string, bodyRetentionDays, retentionDays, maxEmailsPerCategory int) (*model.RetentionPolicy, error)
+	// GetStats returns what retention has pruned for the user since startup
+	GetStats(userID string) model.PruneStats
+	// EnforcePolicies applies every saved policy and returns the combined result
+	EnforcePolicies(ctx context.Context) (model.PruneStats, error)
+}
+
+type NotificationService interface {
+	// GetPreferences returns the user's preferences, or preferences that allow everything if none were saved
+	GetPreferences(ctx context.Context, userID string) (*model.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, userID, quietHoursStart, quietHoursEnd, timeZone string, eventTypes []string, minPriority string) (*model.NotificationPreferences, error)
+	// ShouldNotify reports whether an event of the type may be pushed to the user right now
+	ShouldNotify(ctx context.Context, userID, eventType string) bool
+	// UseUserTimeZones makes quiet hours without a time zone of their own follow the user's
+	UseUserTimeZones(userRepo repository.UserRepository)
+}
+
+type PushService interface {
+	// PublicKey returns the VAPID key browsers subscribe with
+	PublicKey() (string, error)
+	// Subscribe registers a browser's push subscription, replacing any previous one for the endpoint
+	Subscribe(ctx context.Context, userID, endpoint, p256dh, auth string) (*model.PushSubscription, error)
+	Unsubscribe(ctx context.Context, userID, endpoint string) error
+	HasSubscriptions(ctx context.Context, userID string) bool
+	// NotifyNewEmail pushes emails Gmail marked important; register it with OnClassified
+	NotifyNewEmail(ctx context.Context, email *model.Email)
+	// NotifyJobFinished pushes the outcome of a bulk job
+	NotifyJobFinished(ctx context.Context, job *model.BulkJob)
+	// NotifyReauthRequired asks the user to sign in again; register it with OnNeedsReauth
+	NotifyReauthRequired(ctx context.Context, user *model.User)
+}
+
+type TelegramService interface {
+	GetLink(ctx context.Context, userID string) (*model.TelegramLink, error)
+	// CreateLinkCode returns a one-time code the user sends to the bot to link their chat
+	CreateLinkCode(ctx context.Context, userID string) (*model.TelegramLinkCode, error)
+	Unlink(ctx context.Context, userID string) error
+	IsLinked(ctx context.Context, userID string) bool
+	// HandleMessage links chats and runs the commands users send to the bot
+	HandleMessage(ctx context.Context, message *model.TelegramMessage)
+	// NotifyNewEmail sends a summary of emails Gmail marked important; register it with OnClassified
+	NotifyNewEmail(ctx context.Context, email *model.Email)
+}
+
+type WebhookService interface {
+	GetWebhook(ctx context.Context, userID string) (*model.Webhook, error)
+	// SetWebhook posts the user's VIP emails to the https URL, signed with a new secret
+	SetWebhook(ctx context.Context, userID, url string) (*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID string) error
+	HasWebhook(ctx context.Context, userID string) bool
+	// NotifyNewEmail posts emails from VIP senders as vip_email events; register it with OnClassified
+	NotifyNewEmail(ctx context.Context, email *model.Email)
+}
+
+type ShareService interface {
+	// CreateShare creates a signed public link to the email's summary, valid for expiresIn
+	CreateShare(ctx context.Context, userID, emailID string, expiresIn time.Duration, includeBody bool) (*model.EmailShare, error)
+	GetShares(ctx context.Context, userID, emailID string) ([]*model.EmailShare, error)
+	RevokeShare(ctx context.Context, userID, emailID, shareID string) error
+	// OpenShare resolves the token of a share URL, failing alike for forged, expired and revoked links
+	OpenShare(ctx context.Context, token string) (*model.SharedEmail, error)
+}
+
+type AutomationService interface {
+	CreateAutomation(ctx context.Context, userID, categoryID, action, label string, delayDays int) (*model.Automation, error)
+	// CreateTeamAutomation adds an automation that runs on every member's emails of the admin's organization
+	CreateTeamAutomation(ctx context.Context, userID, categoryID, action, label string, delayDays int) (*model.Automation, error)
+	// GetAutomations lists the user's own automations followed by their organization's
+	GetAutomations(ctx context.Context, userID string) ([]*model.Automation, error)
+	// UpdateAutomation and DeleteAutomation only touch the user's own automations, or the
+	// organization's when the user is an admin
+	UpdateAutomation(ctx context.Context, userID, automationID, categoryID, action, label string, delayDays int, enabled bool) (*model.Automation, error)
+	DeleteAutomation(ctx context.Context, userID, automationID string) error
+	// ApplyToNewEmail runs the user's immediate automations for the email's category; register it with OnClassified
+	ApplyToNewEmail(ctx context.Context, email *model.Email)
+	// RunScheduled applies delayed automations to emails that have aged past them and returns how many were acted on
+	RunScheduled(ctx context.Context) (int, error)
+}
+
+// CleanupService manages the users' scheduled cleanup policies
+type CleanupService interface {
+	CreatePolicy(ctx context.Context, userID, name, categoryID string, unread *bool, olderThanDays int, action, schedule string, enabled bool) (*model.CleanupPolicy, error)
+	// GetPolicies lists the user's policies, oldest first
+	GetPolicies(ctx context.Context, userID string) ([]*model.CleanupPolicy, error)
+	UpdatePolicy(ctx context.Context, userID, policyID, name, categoryID string, unread *bool, olderThanDays int, action, schedule string, enabled bool) (*model.CleanupPolicy, error)
+	DeletePolicy(ctx context.Context, userID, policyID string) error
+	// PreviewPolicy is a dry run: what the policy would clean if it ran now, without changing anything
+	PreviewPolicy(ctx context.Context, userID, policyID string) (*model.CleanupPreview, error)
+	// GetRuns lists the policy's past runs, newest first; limit <= 0 means no limit
+	GetRuns(ctx context.Context, userID, policyID string, limit int) ([]*model.CleanupRun, error)
+	// RunDue runs the enabled policies whose schedule has come round and returns how many emails were cleaned
+	RunDue(ctx context.Context) (int, error)
+	// OnCleaned adds a hook run after each scheduled run that cleaned emails or failed
+	OnCleaned(hook CleanupHook)
+}
+
+// CleanupHook is called with a finished cleanup policy run
+type CleanupHook func(ctx context.Context, run *model.CleanupRun)
+
+// SavedViewService manages the users' saved email filters, or smart views
+type SavedViewService interface {
+	CreateView(ctx context.Context, userID, name string, filter model.EmailFilter) (*model.SavedView, error)
+	// GetViews lists the user's views, oldest first
+	GetViews(ctx context.Context, userID string) ([]*model.SavedView, error)
+	GetView(ctx context.Context, userID, viewID string) (*model.SavedView, error)
+	UpdateView(ctx context.Context, userID, viewID, name string, filter model.EmailFilter) (*model.SavedView, error)
+	DeleteView(ctx context.Context, userID, viewID string) error
+	// ListEmails lists the emails matching one of the user's views, newest first
+	ListEmails(ctx context.Context, userID, viewID string, limit int) ([]*model.Email, error)
+}
+
+// TriageService walks the user through their inbox one email at a time, oldest first
+type TriageService interface {
+	// Next returns the oldest email that needs triage with a suggested action; the item's email
+	// is nil once there is nothing left
+	Next(ctx context.Context, userID string) (*model.TriageItem, error)
+	// Decide runs the action on the email, records the decision and returns the next item
+	Decide(ctx context.Context, userID, emailID, action string) (*model.TriageItem, error)
+}
+
+// RecommendationService proposes cleanups from the user's stored emails
+type RecommendationService interface {
+	// GetRecommendations lists the cleanups worth doing now, those concerning the most emails first
+	GetRecommendations(ctx context.Context, userID string) ([]*model.Recommendation, error)
+	// GetRecommendation returns one of the current recommendations, not found once it no longer applies
+	GetRecommendation(ctx context.Context, userID, id string) (*model.Recommendation, error)
+}
+
+// OnboardingService walks a new user through the onboarding wizard, one step after the other
+// as listed in model.OnboardingSteps. Each step is completed by its own method, which fails
+// with a conflict unless it is the user's current step, so a repeated request can't skip one.
+type OnboardingService interface {
+	// GetOnboarding returns the user's current step and what it offers
+	GetOnboarding(ctx context.Context, userID string) (*model.Onboarding, error)
+	// ChooseCategories creates the named suggested categories for the user, none to skip them
+	ChooseCategories(ctx context.Context, userID string, names []string) (*model.Onboarding, error)
+	// Backfill syncs the user's latest emails, as many as the backfill step imports
+	Backfill(ctx context.Context, userID string) (*model.Onboarding, *model.SyncResult, error)
+	// CompleteStep moves on from the user's current step, which must be step, without doing
+	// anything; the unsubscribes of that step are queued by the caller
+	CompleteStep(ctx context.Context, userID, step string) (*model.Onboarding, error)
 }
 
-// GmailClient interface for interacting with Gmail API
-type GmailClient interface {
-	SyncEmails(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error)
-	ArchiveEmail(ctx context.Context, userEmail, messageID string) error
-	MarkAsRead(ctx context.Context, userEmail, messageID string) error
-	DeleteEmails(ctx context.Context, userEmail string, messageIDs []string) error
+// ShipmentService tracks the packages found in shipping notifications
+type ShipmentService interface {
+	// GetShipments lists the user's packages, most recently updated first
+	GetShipments(ctx context.Context, userID string) ([]*model.Shipment, error)
+	// DetectShipment records the tracking numbers of a shipping notification; register it with OnClassified
+	DetectShipment(ctx context.Context, email *model.Email)
+	// RefreshStatuses asks the carriers about packages still on their way and returns how many were delivered
+	RefreshStatuses(ctx context.Context) (int, error)
+	// OnDelivered adds a hook run for each package once it is known to be delivered
+	OnDelivered(hook ShipmentHook)
+	// UseSensitiveCheck stops asking the AI about notifications the check reports sensitive;
+	// their tracking numbers are still read when they match a known format
+	UseSensitiveCheck(check SensitiveCheck)
+}
+
+// ShipmentHook is called with a shipment whose status just changed
+type ShipmentHook func(ctx context.Context, shipment *model.Shipment)
+
+// ImageProxyService loads the remote images of email bodies on the user's behalf, so opening an
+// email neither leaks their IP address nor confirms it was read
+type ImageProxyService interface {
+	// RewriteImages points the body's remote images at the proxy and drops tracking pixels
+	RewriteImages(body string) string
+	// FetchImage downloads a remote image, or returns it from the cache
+	FetchImage(ctx context.Context, rawURL string) (*model.ProxiedImage, error)
+}
+
+// LinkService reveals where the click-tracking links of email bodies lead
+type LinkService interface {
+	// ResolveLinks follows the redirects of the body's click-tracking links and points them at
+	// their destination, keeping the original address in data-original-href
+	ResolveLinks(ctx context.Context, body string) string
+}
+
+// ReportService builds the weekly inbox reports
+type ReportService interface {
+	// GetReports lists the user's reports, latest week first; limit <= 0 means no limit
+	GetReports(ctx context.Context, userID string, limit int) ([]*model.InboxReport, error)
+	GetReport(ctx context.Context, userID, reportID string) (*model.InboxReport, error)
+	// GenerateWeeklyReports builds the report of the week before now's for every user missing
+	// it and returns how many were generated
+	GenerateWeeklyReports(ctx context.Context, now time.Time) (int, error)
+	// UseMailSender emails every generated report to its user, linking to the app at appURL
+	UseMailSender(sender MailSender, appURL string)
+}
+
+// OrganizationService manages teams; everything beyond reading one's own organization and
+// answering invitations is reserved to the organization's admins
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, userID, name string) (*model.OrganizationDetail, error)
+	GetOrganization(ctx context.Context, userID string) (*model.OrganizationDetail, error)
+	RenameOrganization(ctx context.Context, userID, name string) (*model.Organization, error)
+	LeaveOrganization(ctx context.Context, userID string) error
+	RemoveMember(ctx context.Context, userID, memberID string) error
+	UpdateMemberRole(ctx context.Context, userID, memberID, role string) (*model.Membership, error)
+	Invite(ctx context.Context, userID, email, role string) (*model.Invitation, error)
+	ListInvitations(ctx context.Context, userID string) ([]*model.Invitation, error)
+	RevokeInvitation(ctx context.Context, userID, invitationID string) error
+	// GetPendingInvitations lists the invitations addressed to the user's email
+	GetPendingInvitations(ctx context.Context, userID string) ([]*model.Invitation, error)
+	AcceptInvitation(ctx context.Context, userID, invitationID string) (*model.OrganizationDetail, error)
+	DeclineInvitation(ctx context.Context, userID, invitationID string) error
+	GetStats(ctx context.Context, userID string) (*model.OrganizationStats, error)
+}
+
+// MailClient is how the app calls a user's mailbox in Gmail, implemented by the gmail package's
+// client and mock, by the per-user and delegated clients the app wires in, and by the
+// GmailMonitor. Archiving, starring, reporting spam and moving emails are all label changes made
+// with Modify; syncing lists the newest messages and gets each of them.
+type MailClient interface {
+	// List returns the IDs of the user's newest messages, newest first; at most maxResults of
+	// them, or the client's default when it is zero
+	List(ctx context.Context, userEmail string, maxResults int64) ([]string, error)
+	// Get fetches a message in full
+	Get(ctx context.Context, userEmail, messageID string) (*model.Email, error)
+	// Modify adds and removes labels of a message
+	Modify(ctx context.Context, userEmail, messageID string, add, remove []string) error
+	// Trash moves a message to the trash, which Gmail empties after 30 days
+	Trash(ctx context.Context, userEmail, messageID string) error
+	// Delete deletes the messages for good, skipping those Gmail refuses to
+	Delete(ctx context.Context, userEmail string, messageIDs []string) error
+	// Send sends a raw RFC 5322 message from the user's account
+	Send(ctx context.Context, userEmail string, raw []byte) error
+	// Watch has Gmail publish the changes of the user's inbox to the Pub/Sub topic, until the
+	// watch expires
+	Watch(ctx context.Context, userEmail, topic string) (*model.MailWatch, error)
+	// GetOrCreateLabel returns the ID of the label with the given name, creating a user label when none exists
+	GetOrCreateLabel(ctx context.Context, userEmail, name string) (string, error)
+}
+
+// MailSender delivers the emails the app sends on its own, such as weekly reports, to one of its users
+type MailSender interface {
+	Send(ctx context.Context, to string, message *emailtemplate.Message) error
+}
+
+// PushClient delivers Web Push notifications
+type PushClient interface {
+	// PublicKey returns the base64url VAPID application server key
+	PublicKey() string
+	// Send encrypts and delivers the notification, returning ErrPushSubscriptionGone when the browser unsubscribed
+	Send(ctx context.Context, subscription *model.PushSubscription, notification *model.PushNotification) error
+}
+
+// TelegramClient talks to the Telegram Bot API
+type TelegramClient interface {
+	// GetUpdates long-polls for messages from offset on, waiting up to timeout for one to arrive
+	GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]*model.TelegramMessage, error)
+	SendMessage(ctx context.Context, chatID int64, text string) error
+}
+
+// TrackingClient looks packages up with the carriers' tracking APIs
+type TrackingClient interface {
+	// Track returns the package's current status, one of the model's Shipment statuses
+	Track(ctx context.Context, carrier, trackingNumber string) (string, error)
+}
+
+// EmailCounter tallies a user's emails by category
+type EmailCounter interface {
+	// CountByCategory returns counts by category ID, "" for unclassified emails; the map must not be modified
+	CountByCategory(ctx context.Context, userID string) (map[string]model.EmailCounts, error)
+}
+
+// Quotas meters work limited by the user's plan
+type Quotas interface {
+	// Reserve records up to n units of the metric and returns how many the plan allows,
+	// ErrQuotaExceeded when none are left this month
+	Reserve(ctx context.Context, userID, metric string, n int) (int, error)
+	// Remaining returns how much of the metric is left this month out of the plan's limit,
+	// a limit of 0 when unlimited
+	Remaining(ctx context.Context, userID, metric string) (remaining, limit int, err error)
+}
+
+// BillingService tracks plans and quota usage for hosted deployments
+type BillingService interface {
+	Quotas
+	// GetUsage returns the user's plan and this month's usage of each quota
+	GetUsage(ctx context.Context, userID string) (*model.UsageReport, error)
+	SetPlan(ctx context.Context, userID, plan string) (*model.BillingAccount, error)
+	// HandleWebhook verifies a webhook from the named provider and applies the plan change it carries
+	HandleWebhook(ctx context.Context, provider string, payload []byte, header http.Header) error
+}
+
+// BillingProvider adapts a payment provider's webhooks into plan changes
+type BillingProvider interface {
+	// Name identifies the provider in webhook URLs, e.g. stripe
+	Name() string
+	// ParseWebhook verifies the request's signature and returns the plan change it describes,
+	// nil for events that don't affect plans
+	ParseWebhook(payload []byte, header http.Header) (*model.BillingEvent, error)
 }
 
 // AIClient interface for interacting with AI services
 type AIClient interface {
 	ClassifyEmail(ctx context.Context, emailBody string, categories []*model.Category) (string, error)
-	SummarizeEmail(ctx context.Context, emailBody string) (string, error)
+	// SummarizeEmail summarizes the email in language, e.g. "Portuguese", or in the email's own
+	// language when it is empty
+	SummarizeEmail(ctx context.Context, emailBody, language string) (string, error)
+	// Analyze carries out task on content from a third party, e.g. a web page, which the model
+	// is told never to take instructions from
+	Analyze(ctx context.Context, task, content string) (string, error)
+}
+
+// ConfidenceClassifier is implemented by AI clients able to tell how sure they are of a
+// classification; the others' classifications are taken as certain
+type ConfidenceClassifier interface {
+	// ClassifyEmailWithConfidence is ClassifyEmail with the confidence in the category, from 0 to 1
+	ClassifyEmailWithConfidence(ctx context.Context, emailBody string, categories []*model.Category) (string, float64, error)
+}
+
+// ExperimentService runs a classification experiment: a share of the emails is classified by
+// an alternative prompt or model, the treatment, and the rest as usual, the control. Each arm is
+// scored by how often users move the emails it classified to another category.
+type ExperimentService interface {
+	// StartExperiment routes percent of the classifications to client; results are kept under
+	// name, so a new name starts counting from scratch
+	StartExperiment(name string, percent int, control, treatment model.ExperimentArm, client AIClient)
+	// Arm picks the arm the email is classified by, always the same one for an email, and the
+	// client to classify it with, nil for the control
+	Arm(emailID string) (string, AIClient)
+	// RecordClassification counts the email's category towards the arm that chose it
+	RecordClassification(ctx context.Context, email *model.Email, arm string)
+	// RecordCorrection notes that the user filed the email under another category; it is a
+	// CategorizedHook
+	RecordCorrection(ctx context.Context, email *model.Email)
+	// Report compares the arms of the named experiment, the running one when name is empty
+	Report(ctx context.Context, name string) (*model.ExperimentReport, error)
+}
+
+// AIDebugService exposes the AI calls recorded in debug mode to operators
+type AIDebugService interface {
+	// GetCalls lists the recorded calls, newest first, at most limit of them when limit is positive
+	GetCalls(ctx context.Context, limit int) ([]*model.AICall, error)
+	GetCall(ctx context.Context, id string) (*model.AICall, error)
+	// Replay sends a recorded prompt to another provider or model and returns both answers
+	Replay(ctx context.Context, id, provider, model string) (*model.AICallReplay, error)
+	// PurgeCalls deletes the calls recorded longer than retention ago and returns how many
+	PurgeCalls(ctx context.Context, retention time.Duration) (int, error)
+}
+
+// SelfCheck checks one of the services the app depends on. It returns what it found, or an
+// error saying what to fix.
+type SelfCheck func(ctx context.Context) (string, error)
+
+// SelfCheckService checks the credentials and services the app depends on, so that a
+// misconfiguration shows at startup rather than on a user's first action
+type SelfCheckService interface {
+	// AddCheck registers a check, reported under name
+	AddCheck(name string, check SelfCheck)
+	// Run runs every check at once, each within its own timeout
+	Run(ctx context.Context) *model.SelfCheckReport
 }
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	maxRedirectHops       = 5
+	maxFollowedLinks      = 20 // per body, the remaining tracked links are left as they are
+	linkFollowTimeout     = 5 * time.Second
+	maxCachedDestinations = 10000
+)
+
+// trackingRedirectors carry the link destination in a query parameter, so they are unwrapped
+// without any request; path is a prefix, empty for any
+var trackingRedirectors = []struct {
+	domain, path, param string
+}{
+	{"safelinks.protection.outlook.com", "", "url"},
+	{"google.com", "/url", "q"},
+	{"l.facebook.com", "/l.php", "u"},
+	{"l.messenger.com", "/l.php", "u"},
+	{"urldefense.proofpoint.com", "/v2/url", "u"},
+}
+
+// clickTrackingDomains hide the destination behind an ID; their redirects are followed to
+// find it, and subdomains are matched too
+var clickTrackingDomains = []string{
+	"ct.sendgrid.net",
+	"list-manage.com",
+	"mandrillapp.com",
+	"hubspotlinks.com",
+	"click.convertkit-mail.com",
+}
+
+type linkService struct {
+	httpClient *http.Client
+	logger     *logger.Logger
+
+	destinations map[string]string
+	mutex        sync.Mutex
+}
+
+// NewLinkService creates the link resolver; a nil client defaults to one that only connects to
+// public addresses
+func NewLinkService(httpClient *http.Client, logger *logger.Logger) LinkService {
+	if httpClient == nil {
+		httpClient = newPublicHTTPClient(linkFollowTimeout)
+	}
+	// Redirects are read one at a time, not followed to the destination page
+	noFollow := *httpClient
+	noFollow.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &linkService{
+		httpClient:   &noFollow,
+		logger:       logger,
+		destinations: make(map[string]string),
+	}
+}
+
+func (s *linkService) ResolveLinks(ctx context.Context, body string) string {
+	doc, ok := parseLinks(body)
+	if !ok {
+		return body
+	}
+
+	var tracked []string
+	seen := make(map[string]bool)
+	doc.Find("a[href]").Each(func(i int, link *goquery.Selection) {
+		href, _ := link.Attr("href")
+		if destination, unwrapped := unwrapStatic(href); unwrapped {
+			href = destination
+		}
+		if isClickTracked(href) && !seen[href] && len(tracked) < maxFollowedLinks {
+			seen[href] = true
+			tracked = append(tracked, href)
+		}
+	})
+	if len(tracked) == 0 {
+		return body
+	}
+
+	var wg sync.WaitGroup
+	for _, href := range tracked {
+		wg.Add(1)
+		go func(href string) {
+			defer wg.Done()
+			s.follow(ctx, href)
+		}(href)
+	}
+	wg.Wait()
+
+	resolved, changed := rewriteLinks(doc, func(href string) (string, bool) {
+		destination, unwrapped := unwrapStatic(href)
+		if cached, found := s.cachedDestination(destination); found {
+			return cached, true
+		}
+		return destination, unwrapped
+	})
+	if !changed {
+		return body
+	}
+	return resolved
+}
+
+// follow reads the redirects of a click-tracking link until they leave the trackers and caches
+// where they lead; failures leave the link as it is
+func (s *linkService) follow(ctx context.Context, href string) {
+	if _, found := s.cachedDestination(href); found {
+		return
+	}
+
+	current := href
+	for hop := 0; hop < maxRedirectHops; hop++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, current, nil)
+		if err != nil {
+			return
+		}
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			s.logger.Warn("Failed to follow tracked link:", err)
+			return
+		}
+		resp.Body.Close()
+		location, err := resp.Location()
+		if err != nil {
+			return
+		}
+
+		next := location.String()
+		if destination, unwrapped := unwrapStatic(next); unwrapped {
+			next = destination
+		}
+		if !isClickTracked(next) {
+			if parsed, err := url.Parse(next); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+				s.cacheDestination(href, next)
+			}
+			return
+		}
+		current = next
+	}
+}
+
+func (s *linkService) cachedDestination(href string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	destination, found := s.destinations[href]
+	return destination, found
+}
+
+func (s *linkService) cacheDestination(href, destination string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Destinations don't change, the cache is only bounded
+	if len(s.destinations) >= maxCachedDestinations {
+		s.destinations = make(map[string]string)
+	}
+	s.destinations[href] = destination
+}
+
+// unwrapTrackingLinks points the email's links wrapped by redirectors at their destination,
+// keeping the original address in data-original-href; reports whether a link was unwrapped
+func unwrapTrackingLinks(email *model.Email) bool {
+	doc, ok := parseLinks(email.Body)
+	if !ok {
+		return false
+	}
+	body, changed := rewriteLinks(doc, unwrapStatic)
+	if changed {
+		email.Body = body
+	}
+	return changed
+}
+
+func parseLinks(body string) (*goquery.Document, bool) {
+	if !strings.Contains(strings.ToLower(body), "<a") {
+		return nil, false
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// rewriteLinks replaces each link's href with what destination finds for it
+func rewriteLinks(doc *goquery.Document, destination func(href string) (string, bool)) (string, bool) {
+	changed := false
+	doc.Find("a[href]").Each(func(i int, link *goquery.Selection) {
+		href, _ := link.Attr("href")
+		target, found := destination(href)
+		if !found || target == href {
+			return
+		}
+		if _, kept := link.Attr("data-original-href"); !kept {
+			link.SetAttr("data-original-href", href)
+		}
+		link.SetAttr("href", target)
+		changed = true
+	})
+	if !changed {
+		return "", false
+	}
+
+	body, err := doc.Html()
+	if err != nil {
+		return "", false
+	}
+	return body, true
+}
+
+// unwrapStatic returns the destination of a link wrapped by redirectors that carry it in the
+// address, which may wrap each other
+func unwrapStatic(href string) (string, bool) {
+	unwrapped := false
+	for i := 0; i < maxRedirectHops; i++ {
+		destination, ok := redirectorDestination(href)
+		if !ok {
+			break
+		}
+		href, unwrapped = destination, true
+	}
+	return href, unwrapped
+}
+
+func redirectorDestination(href string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, redirector := range trackingRedirectors {
+		if !matchesDomain(host, redirector.domain) || !strings.HasPrefix(parsed.Path, redirector.path) {
+			continue
+		}
+		destination := parsed.Query().Get(redirector.param)
+		if redirector.domain == "urldefense.proofpoint.com" {
+			// Proofpoint encodes the address with - for % and _ for /
+			decoded, err := url.QueryUnescape(strings.NewReplacer("-", "%", "_", "/").Replace(destination))
+			if err != nil {
+				return "", false
+			}
+			destination = decoded
+		}
+		target, err := url.Parse(destination)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return "", false
+		}
+		return destination, true
+	}
+	return "", false
+}
+
+func isClickTracked(href string) bool {
+	parsed, err := url.Parse(href)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, domain := range clickTrackingDomains {
+		if matchesDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDomain reports whether host is the domain or one of its subdomains
+func matchesDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
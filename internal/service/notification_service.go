@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+type notificationService struct {
+	preferencesRepo repository.NotificationPreferencesRepository
+	logger          *logger.Logger
+
+	// Optional; when set, quiet hours without a time zone follow the user's
+	userRepo repository.UserRepository
+}
+
+func NewNotificationService(preferencesRepo repository.NotificationPreferencesRepository, logger *logger.Logger) NotificationService {
+	return &notificationService{
+		preferencesRepo: preferencesRepo,
+		logger:          logger,
+	}
+}
+
+func (s *notificationService) UseUserTimeZones(userRepo repository.UserRepository) {
+	s.userRepo = userRepo
+}
+
+func (s *notificationService) GetPreferences(ctx context.Context, userID string) (*model.NotificationPreferences, error) {
+	preferences, err := s.preferencesRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		// No saved preferences means every event is delivered
+		return model.NewNotificationPreferences(userID, "", "", "", nil, ""), nil
+	}
+	return preferences, nil
+}
+
+func (s *notificationService) UpdatePreferences(ctx context.Context, userID, quietHoursStart, quietHoursEnd, timeZone string, eventTypes []string, minPriority string) (*model.NotificationPreferences, error) {
+	var fields []apierror.FieldError
+	if (quietHoursStart == "") != (quietHoursEnd == "") {
+		fields = append(fields, apierror.FieldError{Field: "quiet_hours_end", Message: "must be set together with quiet_hours_start"})
+	}
+	if _, ok := model.ParseClock(quietHoursStart); quietHoursStart != "" && !ok {
+		fields = append(fields, apierror.FieldError{Field: "quiet_hours_start", Message: "must be a time as HH:MM"})
+	}
+	if _, ok := model.ParseClock(quietHoursEnd); quietHoursEnd != "" && !ok {
+		fields = append(fields, apierror.FieldError{Field: "quiet_hours_end", Message: "must be a time as HH:MM"})
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		fields = append(fields, apierror.FieldError{Field: "time_zone", Message: "must be an IANA time zone"})
+	}
+	if len(fields) > 0 {
+		return nil, apierror.InvalidFields(fields)
+	}
+
+	preferences := model.NewNotificationPreferences(userID, quietHoursStart, quietHoursEnd, timeZone, eventTypes, minPriority)
+	if err := s.preferencesRepo.Save(ctx, preferences); err != nil {
+		return nil, fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+
+	s.logger.Info("Updated notification preferences for user:", userID)
+	return preferences, nil
+}
+
+func (s *notificationService) ShouldNotify(ctx context.Context, userID, eventType string) bool {
+	preferences, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return true
+	}
+	if preferences.TimeZone == "" && preferences.QuietHoursStart != "" && s.userRepo != nil {
+		if user, err := s.userRepo.FindByID(ctx, userID); err == nil {
+			preferences.TimeZone = user.TimeZone
+		}
+	}
+	return preferences.Allows(eventType, time.Now())
+}
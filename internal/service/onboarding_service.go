@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// suggestedCategories are offered to new users on top of the shared defaults; their
+// descriptions are what the AI classifies by
+var suggestedCategories = []*model.CategorySuggestion{
+	{Name: "Work", Description: "Emails from colleagues, clients and work tools: meetings, projects, deadlines and anything the user has to act on for their job"},
+	{Name: "Travel", Description: "Flight, train and hotel bookings, boarding passes, itineraries, car rentals and other travel confirmations or changes"},
+	{Name: "Shopping", Description: "Order confirmations, receipts, shipping and delivery updates, returns and refunds from online stores"},
+	{Name: "Social", Description: "Notifications from social networks and community sites: mentions, messages, friend requests and activity digests"},
+	{Name: "Bills", Description: "Invoices, utility and phone bills, subscription renewals and payment reminders that have an amount due"},
+	{Name: "Events", Description: "Invitations, tickets, registrations and reminders for events, webinars, concerts and appointments"},
+}
+
+type onboardingService struct {
+	userRepo        repository.UserRepository
+	categoryService CategoryService
+	emailService    EmailService
+	recommendations RecommendationService
+	backfillEmails  int
+	logger          *logger.Logger
+}
+
+// NewOnboardingService takes how many emails the backfill step imports
+func NewOnboardingService(
+	userRepo repository.UserRepository,
+	categoryService CategoryService,
+	emailService EmailService,
+	recommendations RecommendationService,
+	backfillEmails int,
+	logger *logger.Logger,
+) OnboardingService {
+	return &onboardingService{
+		userRepo:        userRepo,
+		categoryService: categoryService,
+		emailService:    emailService,
+		recommendations: recommendations,
+		backfillEmails:  backfillEmails,
+		logger:          logger,
+	}
+}
+
+func (s *onboardingService) GetOnboarding(ctx context.Context, userID string) (*model.Onboarding, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.describe(ctx, user)
+}
+
+func (s *onboardingService) ChooseCategories(ctx context.Context, userID string, names []string) (*model.Onboarding, error) {
+	user, err := s.currentStep(ctx, userID, model.OnboardingCategories)
+	if err != nil {
+		return nil, err
+	}
+
+	var chosen []*model.CategorySuggestion
+	for _, name := range names {
+		suggestion := findSuggestion(name)
+		if suggestion == nil {
+			return nil, apierror.Validation(fmt.Sprintf("%q is not a suggested category", name))
+		}
+		chosen = append(chosen, suggestion)
+	}
+	for _, suggestion := range chosen {
+		if _, err := s.categoryService.CreateCategory(ctx, userID, suggestion.Name, suggestion.Description); err != nil {
+			// A category of that name is already there, the user's or a shared one
+			if errors.Is(err, apierror.ErrConflict) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to create category %s: %w", suggestion.Name, err)
+		}
+	}
+
+	return s.advance(ctx, user)
+}
+
+func (s *onboardingService) Backfill(ctx context.Context, userID string) (*model.Onboarding, *model.SyncResult, error) {
+	user, err := s.currentStep(ctx, userID, model.OnboardingBackfill)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A failed sync leaves the user at the step to try again
+	result, err := s.emailService.SyncEmails(ctx, userID, SyncOptions{MaxResults: int64(s.backfillEmails)})
+	if err != nil {
+		return nil, nil, err
+	}
+	s.logger.Info("Backfilled", len(result.New), "emails for user:", userID)
+
+	onboarding, err := s.advance(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return onboarding, result, nil
+}
+
+func (s *onboardingService) CompleteStep(ctx context.Context, userID, step string) (*model.Onboarding, error) {
+	user, err := s.currentStep(ctx, userID, step)
+	if err != nil {
+		return nil, err
+	}
+	return s.advance(ctx, user)
+}
+
+// currentStep returns the user, failing with a conflict carrying where they stand unless
+// they are at step
+func (s *onboardingService) currentStep(ctx context.Context, userID, step string) (*model.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Onboarding() != step {
+		onboarding, err := s.describe(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return nil, apierror.Conflict(fmt.Sprintf("The onboarding is at the %s step", onboarding.Step), onboarding)
+	}
+	return user, nil
+}
+
+func (s *onboardingService) advance(ctx context.Context, user *model.User) (*model.Onboarding, error) {
+	user.OnboardingStep = model.NextOnboardingStep(user.Onboarding())
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save onboarding step: %w", err)
+	}
+	s.logger.Info("User", user.ID, "moved on to onboarding step:", user.OnboardingStep)
+	return s.describe(ctx, user)
+}
+
+// describe lists what the user's current step offers
+func (s *onboardingService) describe(ctx context.Context, user *model.User) (*model.Onboarding, error) {
+	onboarding := &model.Onboarding{Step: user.Onboarding(), Steps: model.OnboardingSteps}
+	switch onboarding.Step {
+	case model.OnboardingCategories:
+		categories, err := s.categoryService.GetAllCategories(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get categories: %w", err)
+		}
+		for _, suggestion := range suggestedCategories {
+			if findCategoryNamed(categories, suggestion.Name) == nil {
+				onboarding.SuggestedCategories = append(onboarding.SuggestedCategories, suggestion)
+			}
+		}
+	case model.OnboardingBackfill:
+		onboarding.BackfillEmails = s.backfillEmails
+	case model.OnboardingUnsubscribe:
+		recommendations, err := s.recommendations.GetRecommendations(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, recommendation := range recommendations {
+			if recommendation.Kind == model.RecommendUnsubscribe {
+				onboarding.Recommendations = append(onboarding.Recommendations, recommendation)
+			}
+		}
+	}
+	return onboarding, nil
+}
+
+func findSuggestion(name string) *model.CategorySuggestion {
+	for _, suggestion := range suggestedCategories {
+		if strings.EqualFold(suggestion.Name, strings.TrimSpace(name)) {
+			return suggestion
+		}
+	}
+	return nil
+}
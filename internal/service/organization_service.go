@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+var errNotInOrganization = apierror.NotFound("you are not a member of an organization")
+
+type organizationService struct {
+	orgRepo        repository.OrganizationRepository
+	invitationRepo repository.InvitationRepository
+	userRepo       repository.UserRepository
+	categoryRepo   repository.CategoryRepository
+	automationRepo repository.AutomationRepository
+	emailRepo      repository.EmailRepository
+	logger         *logger.Logger
+}
+
+func NewOrganizationService(
+	orgRepo repository.OrganizationRepository,
+	invitationRepo repository.InvitationRepository,
+	userRepo repository.UserRepository,
+	categoryRepo repository.CategoryRepository,
+	automationRepo repository.AutomationRepository,
+	emailRepo repository.EmailRepository,
+	logger *logger.Logger,
+) OrganizationService {
+	return &organizationService{
+		orgRepo:        orgRepo,
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		categoryRepo:   categoryRepo,
+		automationRepo: automationRepo,
+		emailRepo:      emailRepo,
+		logger:         logger,
+	}
+}
+
+// findMembership returns the user's membership or errNotInOrganization
+func findMembership(ctx context.Context, orgRepo repository.OrganizationRepository, userID string) (*model.Membership, error) {
+	membership, err := orgRepo.FindMembership(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apierror.ErrNotFound) {
+			return nil, errNotInOrganization
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+// findAdminMembership returns the user's membership, forbidden unless they administer the organization
+func findAdminMembership(ctx context.Context, orgRepo repository.OrganizationRepository, userID string) (*model.Membership, error) {
+	membership, err := findMembership(ctx, orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !membership.IsAdmin() {
+		return nil, apierror.Forbidden("only organization admins can do this")
+	}
+	return membership, nil
+}
+
+func (s *organizationService) CreateOrganization(ctx context.Context, userID, name string) (*model.OrganizationDetail, error) {
+	if _, err := s.orgRepo.FindMembership(ctx, userID); err == nil {
+		return nil, apierror.Validation("leave your current organization before creating another")
+	} else if !errors.Is(err, apierror.ErrNotFound) {
+		return nil, err
+	}
+
+	org := model.NewOrganization(strings.TrimSpace(name))
+	if err := s.orgRepo.Create(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to save organization: %w", err)
+	}
+	if err := s.orgRepo.SaveMember(ctx, model.NewMembership(org.ID, userID, model.RoleAdmin)); err != nil {
+		return nil, fmt.Errorf("failed to save membership: %w", err)
+	}
+
+	s.logger.Info("Created organization:", org.ID, "for user:", userID)
+	return s.GetOrganization(ctx, userID)
+}
+
+func (s *organizationService) GetOrganization(ctx context.Context, userID string) (*model.OrganizationDetail, error) {
+	membership, err := findMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.FindByID(ctx, membership.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.members(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OrganizationDetail{Organization: org, Role: membership.Role, Members: members}, nil
+}
+
+// members lists the organization's members with their email and name filled in
+func (s *organizationService) members(ctx context.Context, orgID string) ([]*model.Membership, error) {
+	members, err := s.orgRepo.FindMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	for _, member := range members {
+		if user, err := s.userRepo.FindByID(ctx, member.UserID); err == nil {
+			member.Email = user.Email
+			member.Name = user.Name
+		}
+	}
+	return members, nil
+}
+
+func (s *organizationService) RenameOrganization(ctx context.Context, userID, name string) (*model.Organization, error) {
+	membership, err := findAdminMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.FindByID(ctx, membership.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	org.Name = strings.TrimSpace(name)
+	org.UpdatedAt = time.Now()
+	if err := s.orgRepo.Update(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+	return org, nil
+}
+
+// LeaveOrganization removes the user from their organization. The last admin must hand
+// over the role first, and the last member to leave deletes the organization.
+func (s *organizationService) LeaveOrganization(ctx context.Context, userID string) error {
+	membership, err := findMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return err
+	}
+	members, err := s.orgRepo.FindMembers(ctx, membership.OrgID)
+	if err != nil {
+		return fmt.Errorf("failed to get members: %w", err)
+	}
+
+	if len(members) == 1 {
+		return s.deleteOrganization(ctx, membership.OrgID)
+	}
+	if membership.IsAdmin() && countAdmins(members) == 1 {
+		return apierror.InvalidFields([]apierror.FieldError{
+			{Field: "role", Message: "make another member an admin before leaving"},
+		})
+	}
+
+	if err := s.orgRepo.RemoveMember(ctx, membership.OrgID, userID); err != nil {
+		return fmt.Errorf("failed to leave organization: %w", err)
+	}
+	s.logger.Info("User", userID, "left organization:", membership.OrgID)
+	return nil
+}
+
+func countAdmins(members []*model.Membership) int {
+	admins := 0
+	for _, member := range members {
+		if member.IsAdmin() {
+			admins++
+		}
+	}
+	return admins
+}
+
+// deleteOrganization removes the organization with its team categories, automations and invitations
+func (s *organizationService) deleteOrganization(ctx context.Context, orgID string) error {
+	automations, err := s.automationRepo.FindByOrgID(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to get team automations: %w", err)
+	}
+	for _, automation := range automations {
+		if err := s.automationRepo.Delete(ctx, automation.ID); err != nil {
+			return fmt.Errorf("failed to delete team automation: %w", err)
+		}
+	}
+
+	categories, err := s.categoryRepo.FindByOrgID(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to get team categories: %w", err)
+	}
+	for _, category := range categories {
+		if err := s.categoryRepo.Delete(ctx, category.ID); err != nil {
+			return fmt.Errorf("failed to delete team category: %w", err)
+		}
+	}
+
+	invitations, err := s.invitationRepo.FindByOrgID(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to get invitations: %w", err)
+	}
+	for _, invitation := range invitations {
+		if err := s.invitationRepo.Delete(ctx, invitation.ID); err != nil {
+			return fmt.Errorf("failed to delete invitation: %w", err)
+		}
+	}
+
+	if err := s.orgRepo.Delete(ctx, orgID); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	s.logger.Info("Deleted organization:", orgID)
+	return nil
+}
+
+// findMember returns another member of the admin's organization
+func (s *organizationService) findMember(ctx context.Context, adminID, memberID string) (*model.Membership, *model.Membership, error) {
+	admin, err := findAdminMembership(ctx, s.orgRepo, adminID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.orgRepo.FindMembership(ctx, memberID)
+	if err != nil || member.OrgID != admin.OrgID {
+		return nil, nil, apierror.NotFound("member not found")
+	}
+	return admin, member, nil
+}
+
+func (s *organizationService) RemoveMember(ctx context.Context, userID, memberID string) error {
+	if userID == memberID {
+		return apierror.InvalidFields([]apierror.FieldError{
+			{Field: "user_id", Message: "leave the organization instead of removing yourself"},
+		})
+	}
+	admin, member, err := s.findMember(ctx, userID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.RemoveMember(ctx, admin.OrgID, member.UserID); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	s.logger.Info("Removed user", member.UserID, "from organization:", admin.OrgID)
+	return nil
+}
+
+func (s *organizationService) UpdateMemberRole(ctx context.Context, userID, memberID, role string) (*model.Membership, error) {
+	admin, member, err := s.findMember(ctx, userID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member.IsAdmin() && role != model.RoleAdmin {
+		members, err := s.orgRepo.FindMembers(ctx, admin.OrgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get members: %w", err)
+		}
+		if countAdmins(members) == 1 {
+			return nil, apierror.InvalidFields([]apierror.FieldError{
+				{Field: "role", Message: "an organization needs at least one admin"},
+			})
+		}
+	}
+
+	member.Role = role
+	if err := s.orgRepo.SaveMember(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to update member: %w", err)
+	}
+	return member, nil
+}
+
+func (s *organizationService) Invite(ctx context.Context, userID, email, role string) (*model.Invitation, error) {
+	admin, err := findAdminMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != strings.TrimSpace(email) {
+		return nil, apierror.InvalidFields([]apierror.FieldError{{Field: "email", Message: "must be an email address"}})
+	}
+
+	if user, err := s.userRepo.FindByEmail(ctx, address.Address); err == nil {
+		if membership, err := s.orgRepo.FindMembership(ctx, user.ID); err == nil && membership.OrgID == admin.OrgID {
+			return nil, apierror.Validation("this user is already a member")
+		}
+	}
+
+	// Inviting the same address again replaces the earlier invitation
+	pending, err := s.invitationRepo.FindByOrgID(ctx, admin.OrgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+	for _, invitation := range pending {
+		if invitation.AcceptedAt == nil && strings.EqualFold(invitation.Email, address.Address) {
+			if err := s.invitationRepo.Delete(ctx, invitation.ID); err != nil {
+				return nil, fmt.Errorf("failed to replace invitation: %w", err)
+			}
+		}
+	}
+
+	invitation := model.NewInvitation(admin.OrgID, address.Address, role, userID)
+	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, fmt.Errorf("failed to save invitation: %w", err)
+	}
+	s.logger.Info("Invited", invitation.Email, "to organization:", admin.OrgID)
+	return invitation, nil
+}
+
+func (s *organizationService) ListInvitations(ctx context.Context, userID string) ([]*model.Invitation, error) {
+	admin, err := findAdminMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.invitationRepo.FindByOrgID(ctx, admin.OrgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+
+	now := time.Now()
+	var pending []*model.Invitation
+	for _, invitation := range invitations {
+		if invitation.IsPending(now) {
+			pending = append(pending, invitation)
+		}
+	}
+	return pending, nil
+}
+
+func (s *organizationService) RevokeInvitation(ctx context.Context, userID, invitationID string) error {
+	admin, err := findAdminMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return err
+	}
+	invitation, err := s.invitationRepo.FindByID(ctx, invitationID)
+	if err != nil || invitation.OrgID != admin.OrgID {
+		return apierror.NotFound("invitation not found")
+	}
+	if err := s.invitationRepo.Delete(ctx, invitation.ID); err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	return nil
+}
+
+func (s *organizationService) GetPendingInvitations(ctx context.Context, userID string) ([]*model.Invitation, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.invitationRepo.FindByEmail(ctx, user.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations: %w", err)
+	}
+
+	now := time.Now()
+	var pending []*model.Invitation
+	for _, invitation := range invitations {
+		if !invitation.IsPending(now) {
+			continue
+		}
+		if org, err := s.orgRepo.FindByID(ctx, invitation.OrgID); err == nil {
+			invitation.OrgName = org.Name
+			pending = append(pending, invitation)
+		}
+	}
+	return pending, nil
+}
+
+// findInvitation returns a pending invitation addressed to the user
+func (s *organizationService) findInvitation(ctx context.Context, userID, invitationID string) (*model.Invitation, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := s.invitationRepo.FindByID(ctx, invitationID)
+	if err != nil || !strings.EqualFold(invitation.Email, user.Email) || !invitation.IsPending(time.Now()) {
+		return nil, apierror.NotFound("invitation not found or expired")
+	}
+	return invitation, nil
+}
+
+func (s *organizationService) AcceptInvitation(ctx context.Context, userID, invitationID string) (*model.OrganizationDetail, error) {
+	invitation, err := s.findInvitation(ctx, userID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.orgRepo.FindMembership(ctx, userID); err == nil {
+		return nil, apierror.Validation("leave your current organization before joining another")
+	} else if !errors.Is(err, apierror.ErrNotFound) {
+		return nil, err
+	}
+	if _, err := s.orgRepo.FindByID(ctx, invitation.OrgID); err != nil {
+		return nil, apierror.NotFound("invitation not found or expired")
+	}
+
+	if err := s.orgRepo.SaveMember(ctx, model.NewMembership(invitation.OrgID, userID, invitation.Role)); err != nil {
+		return nil, fmt.Errorf("failed to save membership: %w", err)
+	}
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	if err := s.invitationRepo.Update(ctx, invitation); err != nil {
+		s.logger.Error("Failed to mark invitation", invitation.ID, "accepted:", err)
+	}
+
+	s.logger.Info("User", userID, "joined organization:", invitation.OrgID)
+	return s.GetOrganization(ctx, userID)
+}
+
+func (s *organizationService) DeclineInvitation(ctx context.Context, userID, invitationID string) error {
+	invitation, err := s.findInvitation(ctx, userID, invitationID)
+	if err != nil {
+		return err
+	}
+	if err := s.invitationRepo.Delete(ctx, invitation.ID); err != nil {
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+	return nil
+}
+
+// GetStats counts every member's emails, overall and per category, for the organization's admins
+func (s *organizationService) GetStats(ctx context.Context, userID string) (*model.OrganizationStats, error) {
+	admin, err := findAdminMembership(ctx, s.orgRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.members(ctx, admin.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &model.OrganizationStats{Members: len(members), EmailsByCategory: make(map[string]int)}
+	if categories, err := s.categoryRepo.FindByOrgID(ctx, admin.OrgID); err == nil {
+		stats.TeamCategories = len(categories)
+	}
+	if automations, err := s.automationRepo.FindByOrgID(ctx, admin.OrgID); err == nil {
+		stats.TeamAutomations = len(automations)
+	}
+
+	unread := true
+	for _, member := range members {
+		memberStats := model.MemberStats{UserID: member.UserID, Email: member.Email}
+		if memberStats.Emails, err = s.emailRepo.CountByFilter(ctx, member.UserID, model.EmailFilter{Archive: model.ArchiveFilterAll}); err != nil {
+			return nil, fmt.Errorf("failed to count emails: %w", err)
+		}
+		if memberStats.Unread, err = s.emailRepo.CountByFilter(ctx, member.UserID, model.EmailFilter{Unread: &unread, Archive: model.ArchiveFilterAll}); err != nil {
+			return nil, fmt.Errorf("failed to count emails: %w", err)
+		}
+		stats.Emails += memberStats.Emails
+		stats.Unread += memberStats.Unread
+		stats.EmailsByMember = append(stats.EmailsByMember, memberStats)
+
+		// Members see the shared and team categories plus their own, which are named per member
+		categories, err := s.categoryRepo.FindByUserID(ctx, member.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get categories: %w", err)
+		}
+		for _, category := range categories {
+			count, err := s.emailRepo.CountByFilter(ctx, member.UserID, model.EmailFilter{CategoryID: category.ID, Archive: model.ArchiveFilterAll})
+			if err != nil {
+				return nil, fmt.Errorf("failed to count emails: %w", err)
+			}
+			if count > 0 {
+				stats.EmailsByCategory[category.Name] += count
+			}
+		}
+	}
+	return stats, nil
+}
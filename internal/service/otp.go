@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+)
+
+// defaultOTPValidity is how long a code is assumed to work when the email doesn't say
+const defaultOTPValidity = 10 * time.Minute
+
+var (
+	// otpKeyword finds the phrases services introduce one-time codes with
+	otpKeyword = regexp.MustCompile(`(?i)(verification|security|confirmation|login|log-in|sign-in|signin|authentication|access|one-time|one time|2fa)\s+(code|passcode|password|pin)|\bpasscode\b|\botp\b|\byour code\b`)
+	// otpCode matches 4 to 8 digits, or two groups of 3 or 4 split by a space or hyphen
+	otpCode = regexp.MustCompile(`\b(\d{3,4}[ -]\d{3,4}|\d{4,8})\b`)
+	// otpValidity reads phrases like "expires in 10 minutes" or "valid for 1 hour"
+	otpValidity = regexp.MustCompile(`(?i)(?:expires?|valid|expire)\s+(?:in|for|after)\s+(\d{1,3})\s*(minutes?|mins?|hours?|hrs?)`)
+)
+
+// otpWindow bounds how far from its keyword a code is looked for, in bytes
+const (
+	otpWindowBefore = 60
+	otpWindowAfter  = 200
+)
+
+// detectOTP stores the verification code of the email, if it has one, with when it expires
+func detectOTP(email *model.Email) bool {
+	text := email.Subject + "\n" + plainText(email.Body)
+	code, ok := extractOTP(text)
+	if !ok {
+		return false
+	}
+
+	validity := defaultOTPValidity
+	if match := otpValidity.FindStringSubmatch(text); match != nil {
+		amount, _ := strconv.Atoi(match[1])
+		unit := time.Minute
+		if strings.HasPrefix(strings.ToLower(match[2]), "h") {
+			unit = time.Hour
+		}
+		if amount > 0 {
+			validity = time.Duration(amount) * unit
+		}
+	}
+
+	expiresAt := email.ReceivedAt.Add(validity)
+	email.OTPCode = code
+	email.OTPExpiresAt = &expiresAt
+	return true
+}
+
+// extractOTP returns the code closest after a keyword such as "verification code", else the
+// one right before it as in "123456 is your login code"
+func extractOTP(text string) (string, bool) {
+	for _, keyword := range otpKeyword.FindAllStringIndex(text, -1) {
+		after := text[keyword[1]:min(len(text), keyword[1]+otpWindowAfter)]
+		if code := otpCode.FindString(after); code != "" {
+			return normalizeOTP(code), true
+		}
+		before := text[max(0, keyword[0]-otpWindowBefore):keyword[0]]
+		if codes := otpCode.FindAllString(before, -1); len(codes) > 0 {
+			return normalizeOTP(codes[len(codes)-1]), true
+		}
+	}
+	return "", false
+}
+
+func normalizeOTP(code string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// GetOTP returns the verification code found in the email
+func (s *emailService) GetOTP(ctx context.Context, userID, emailID string) (*model.OTP, error) {
+	email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+	if err != nil {
+		return nil, err
+	}
+	otp := model.NewOTP(email, time.Now())
+	if otp == nil {
+		return nil, apierror.NotFound("no verification code found in the email")
+	}
+	return otp, nil
+}
+
+// TrashOTPEmails moves emails holding a verification code to the trash once they are older than
+// retention; they are purged with the rest of the trash
+func (s *emailService) TrashOTPEmails(ctx context.Context, retention time.Duration) (int, error) {
+	defer s.counts.invalidateAll()
+
+	trashed, err := s.emailRepo.TrashOTPReceivedBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if trashed > 0 {
+		s.logger.Info("Moved", trashed, "verification code emails to trash")
+	}
+	return trashed, nil
+}
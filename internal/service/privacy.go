@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+)
+
+func (s *emailService) GetPrivacySettings(ctx context.Context, userID string) (*model.PrivacySettings, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &model.PrivacySettings{Enabled: user.PrivacyMode}, nil
+}
+
+func (s *emailService) UpdatePrivacySettings(ctx context.Context, userID string, settings model.PrivacySettings) (*model.PrivacySettings, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	user.PrivacyMode = settings.Enabled
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save privacy settings: %w", err)
+	}
+	s.logger.Info("Set privacy mode of user", userID, "to", settings.Enabled)
+	return &settings, nil
+}
+
+// PlainText renders an HTML body as its text, which privacy mode shows instead of the HTML
+func PlainText(body string) string {
+	return plainText(body)
+}
+
+// sensitiveCategories returns the IDs of the categories the user marked sensitive, or nil when
+// the user isn't in privacy mode and every email may be processed. Only a failed lookup is an
+// error; a user that doesn't exist has no privacy mode to honor.
+func (s *emailService) sensitiveCategories(ctx context.Context, userID string) (map[string]bool, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if errors.Is(err, apierror.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.PrivacyMode {
+		return nil, nil
+	}
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	sensitive := make(map[string]bool)
+	for _, category := range categories {
+		if category.Sensitive {
+			sensitive[category.ID] = true
+		}
+	}
+	return sensitive, nil
+}
+
+// sensitiveCategoryOf returns the sensitive category the email belongs in without asking the
+// AI: the one it is already in, or else the one the sender's latest email was filed in. It is
+// empty when neither is sensitive.
+func (s *emailService) sensitiveCategoryOf(ctx context.Context, email *model.Email, sensitive map[string]bool) string {
+	if sensitive[email.CategoryID] {
+		return email.CategoryID
+	}
+	if email.FromAddress == "" {
+		return ""
+	}
+
+	previous, err := s.emailRepo.FindBySender(ctx, email.UserID, email.FromAddress)
+	if err != nil {
+		return ""
+	}
+	for i := len(previous) - 1; i >= 0; i-- {
+		if previous[i].ID != email.ID && previous[i].CategoryID != "" {
+			if sensitive[previous[i].CategoryID] {
+				return previous[i].CategoryID
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// IsSensitive reports whether the email must be kept away from the AI; when that can't be
+// told, it is
+func (s *emailService) IsSensitive(ctx context.Context, email *model.Email) bool {
+	sensitive, err := s.sensitiveCategories(ctx, email.UserID)
+	if err != nil {
+		s.logger.Error("Failed to get the sensitive categories of user", email.UserID, ":", err)
+		return true
+	}
+	return sensitive[email.CategoryID]
+}
+
+// ExportEmails lists every email of the user for an export, locally archived ones included;
+// in privacy mode, the emails of sensitive categories are exported without their content
+func (s *emailService) ExportEmails(ctx context.Context, userID string) ([]*model.Email, error) {
+	emails, err := s.emailRepo.FindByFilter(ctx, userID, model.EmailFilter{Archive: model.ArchiveFilterAll}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emails: %w", err)
+	}
+	sensitive, err := s.sensitiveCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, email := range emails {
+		if sensitive[email.CategoryID] {
+			emails[i] = email.Redacted()
+		}
+	}
+	return emails, nil
+}
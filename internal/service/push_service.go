@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// ErrPushSubscriptionGone is returned by a PushClient when the browser dropped the subscription
+var ErrPushSubscriptionGone = errors.New("push subscription is no longer valid")
+
+// errPushDisabled is returned when no VAPID keys are configured
+var errPushDisabled = apierror.Unavailable("push notifications are not configured")
+
+type pushService struct {
+	subscriptionRepo    repository.PushSubscriptionRepository
+	pushClient          PushClient // nil when push is disabled
+	notificationService NotificationService
+	logger              *logger.Logger
+}
+
+func NewPushService(subscriptionRepo repository.PushSubscriptionRepository, pushClient PushClient, notificationService NotificationService, logger *logger.Logger) PushService {
+	return &pushService{
+		subscriptionRepo:    subscriptionRepo,
+		pushClient:          pushClient,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+func (s *pushService) PublicKey() (string, error) {
+	if s.pushClient == nil {
+		return "", errPushDisabled
+	}
+	return s.pushClient.PublicKey(), nil
+}
+
+func (s *pushService) Subscribe(ctx context.Context, userID, endpoint, p256dh, auth string) (*model.PushSubscription, error) {
+	if s.pushClient == nil {
+		return nil, errPushDisabled
+	}
+	// Messages are posted to the endpoint, so only accept push services reachable over TLS
+	if !strings.HasPrefix(endpoint, "https://") {
+		return nil, apierror.InvalidFields([]apierror.FieldError{{Field: "endpoint", Message: "must be an https URL"}})
+	}
+
+	subscription := model.NewPushSubscription(userID, endpoint, p256dh, auth)
+	if err := s.subscriptionRepo.Save(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to save push subscription: %w", err)
+	}
+
+	s.logger.Info("Registered push subscription for user:", userID)
+	return subscription, nil
+}
+
+func (s *pushService) Unsubscribe(ctx context.Context, userID, endpoint string) error {
+	if err := s.subscriptionRepo.DeleteByEndpoint(ctx, userID, endpoint); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+
+	s.logger.Info("Removed push subscription for user:", userID)
+	return nil
+}
+
+func (s *pushService) HasSubscriptions(ctx context.Context, userID string) bool {
+	if s.pushClient == nil {
+		return false
+	}
+	subscriptions, err := s.subscriptionRepo.FindByUserID(ctx, userID)
+	return err == nil && len(subscriptions) > 0
+}
+
+func (s *pushService) NotifyNewEmail(ctx context.Context, email *model.Email) {
+	// Only emails Gmail considers important or from VIP senders are worth interrupting the user for
+	if !email.Important && !email.IsUrgent() {
+		return
+	}
+
+	sender := email.FromName
+	if sender == "" {
+		sender = email.FromAddress
+	}
+	s.notify(ctx, email.UserID, &model.PushNotification{
+		Type:    newEmailEvent(email),
+		Title:   sender,
+		Body:    email.Subject,
+		URL:     "/app",
+		Tag:     "email-" + email.ID,
+		Urgency: "high",
+	})
+}
+
+func (s *pushService) NotifyJobFinished(ctx context.Context, job *model.BulkJob) {
+	body := fmt.Sprintf("%s finished: %d of %d emails processed", job.Action, job.Processed, job.Total)
+	if job.Status == model.BulkJobFailed {
+		body = fmt.Sprintf("%s failed: %s", job.Action, job.Error)
+	}
+
+	s.notify(ctx, job.UserID, &model.PushNotification{
+		Type:    model.EventBulkJob,
+		Title:   "Bulk action " + job.Status,
+		Body:    body,
+		URL:     "/app",
+		Tag:     "job-" + job.ID,
+		Urgency: "normal",
+	})
+}
+
+func (s *pushService) NotifyReauthRequired(ctx context.Context, user *model.User) {
+	s.notify(ctx, user.ID, &model.PushNotification{
+		Type:    model.EventReauth,
+		Title:   "Sign in again to keep your inbox sorted",
+		Body:    "Google access was revoked, new emails aren't synced until you sign in again",
+		URL:     "/app",
+		Tag:     "reauth",
+		Urgency: "high",
+	})
+}
+
+// notify sends the notification to every browser the user subscribed, honoring their
+// notification preferences, and forgets subscriptions the push service reports as gone
+func (s *pushService) notify(ctx context.Context, userID string, notification *model.PushNotification) {
+	if s.pushClient == nil || !s.notificationService.ShouldNotify(ctx, userID, notification.Type) {
+		return
+	}
+
+	subscriptions, err := s.subscriptionRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get push subscriptions for user", userID, ":", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		err := s.pushClient.Send(ctx, subscription, notification)
+		if errors.Is(err, ErrPushSubscriptionGone) {
+			s.logger.Info("Dropping expired push subscription:", subscription.ID)
+			if err := s.subscriptionRepo.DeleteByEndpoint(ctx, userID, subscription.Endpoint); err != nil {
+				s.logger.Error("Failed to delete expired push subscription:", err)
+			}
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to send push notification:", err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// What makes a cleanup worth recommending
+const (
+	staleUnreadAge           = 30 * 24 * time.Hour // unread emails this old are unlikely to be read
+	minStaleUnread           = 10
+	ignoredSenderWindow      = 90 * 24 * time.Hour // senders are judged on their recent emails
+	minIgnoredSenderEmails   = 5
+	maxSenderRecommendations = 10
+)
+
+type recommendationService struct {
+	emailRepo    repository.EmailRepository
+	categoryRepo repository.CategoryRepository
+	logger       *logger.Logger
+}
+
+func NewRecommendationService(emailRepo repository.EmailRepository, categoryRepo repository.CategoryRepository, logger *logger.Logger) RecommendationService {
+	return &recommendationService{
+		emailRepo:    emailRepo,
+		categoryRepo: categoryRepo,
+		logger:       logger,
+	}
+}
+
+func (s *recommendationService) GetRecommendations(ctx context.Context, userID string) ([]*model.Recommendation, error) {
+	now := time.Now()
+
+	stale, err := s.staleUnread(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	ignored, err := s.ignoredSenders(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := append(stale, ignored...)
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Count > recommendations[j].Count
+	})
+	return recommendations, nil
+}
+
+func (s *recommendationService) GetRecommendation(ctx context.Context, userID, id string) (*model.Recommendation, error) {
+	recommendations, err := s.GetRecommendations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, recommendation := range recommendations {
+		if recommendation.ID == id {
+			return recommendation, nil
+		}
+	}
+	return nil, apierror.NotFound("recommendation not found")
+}
+
+// staleUnread proposes archiving the unread emails of each category left for weeks
+func (s *recommendationService) staleUnread(ctx context.Context, userID string, now time.Time) ([]*model.Recommendation, error) {
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	unread := true
+	before := now.Add(-staleUnreadAge)
+	var recommendations []*model.Recommendation
+	for _, category := range categories {
+		filter := model.EmailFilter{CategoryID: category.ID, Unread: &unread, Before: &before}
+		count, err := s.emailRepo.CountByFilter(ctx, userID, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count emails: %w", err)
+		}
+		if count < minStaleUnread {
+			continue
+		}
+		recommendations = append(recommendations, &model.Recommendation{
+			ID:   model.RecommendArchiveStale + ":" + category.ID,
+			Kind: model.RecommendArchiveStale,
+			Message: fmt.Sprintf("You have %d unread %s emails older than %d days. Archive them?",
+				count, category.Name, int(staleUnreadAge.Hours()/24)),
+			Count:  count,
+			Action: "archive",
+			Filter: filter,
+		})
+	}
+	return recommendations, nil
+}
+
+// ignoredSenders proposes unsubscribing from the senders whose recent emails all went unread.
+// Unsubscribing only takes the sender's latest email, since each email is one attempt.
+func (s *recommendationService) ignoredSenders(ctx context.Context, userID string, now time.Time) ([]*model.Recommendation, error) {
+	senders, err := s.emailRepo.CountBySender(ctx, userID, now.Add(-ignoredSenderWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count emails by sender: %w", err)
+	}
+
+	var recommendations []*model.Recommendation
+	for _, sender := range senders {
+		if len(recommendations) == maxSenderRecommendations {
+			break
+		}
+		if sender.Address == "" || sender.Total < minIgnoredSenderEmails || sender.Unread < sender.Total || sender.Unsubscribed > 0 {
+			continue
+		}
+		latest := sender.LastReceivedAt
+		recommendations = append(recommendations, &model.Recommendation{
+			ID:      model.RecommendUnsubscribe + ":" + strings.ToLower(sender.Address),
+			Kind:    model.RecommendUnsubscribe,
+			Message: fmt.Sprintf("You never opened the last %d emails from %s. Unsubscribe?", sender.Total, sender.Address),
+			Count:   sender.Total,
+			Action:  "unsubscribe",
+			Filter:  model.EmailFilter{Sender: sender.Address, After: &latest, Archive: model.ArchiveFilterAll},
+		})
+	}
+	return recommendations, nil
+}
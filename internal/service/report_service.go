@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/emailtemplate"
+	"jump-challenge/internal/i18n"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// topSenderCount is how many senders a report ranks
+const topSenderCount = 5
+
+// Rough reading time saved by the app, behind a report's time saved estimate
+const (
+	secondsSavedPerSummary     = 45     // reading the summary instead of the email
+	secondsSavedPerArchive     = 10     // not having to clear the email from the inbox
+	secondsSavedPerUnsubscribe = 5 * 60 // the sender's future emails, over the following weeks
+)
+
+type reportService struct {
+	reportRepo   repository.ReportRepository
+	emailRepo    repository.EmailRepository
+	categoryRepo repository.CategoryRepository
+	userRepo     repository.UserRepository
+	mailSender   MailSender // nil unless reports are emailed
+	appURL       string
+	logger       *logger.Logger
+}
+
+func NewReportService(reportRepo repository.ReportRepository, emailRepo repository.EmailRepository, categoryRepo repository.CategoryRepository, userRepo repository.UserRepository, logger *logger.Logger) ReportService {
+	return &reportService{
+		reportRepo:   reportRepo,
+		emailRepo:    emailRepo,
+		categoryRepo: categoryRepo,
+		userRepo:     userRepo,
+		logger:       logger,
+	}
+}
+
+func (s *reportService) UseMailSender(sender MailSender, appURL string) {
+	s.mailSender = sender
+	s.appURL = appURL
+}
+
+func (s *reportService) GetReports(ctx context.Context, userID string, limit int) ([]*model.InboxReport, error) {
+	reports, err := s.reportRepo.FindByUserID(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if reports == nil {
+		reports = []*model.InboxReport{}
+	}
+	return reports, nil
+}
+
+func (s *reportService) GetReport(ctx context.Context, userID, reportID string) (*model.InboxReport, error) {
+	return s.reportRepo.FindByIDAndUser(ctx, reportID, userID)
+}
+
+func (s *reportService) GenerateWeeklyReports(ctx context.Context, now time.Time) (int, error) {
+	users, err := s.userRepo.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for _, user := range users {
+		if ctx.Err() != nil {
+			return generated, ctx.Err()
+		}
+		// Weeks run from Monday midnight in the user's time zone
+		periodStart := model.StartOfWeek(now.In(user.Location())).AddDate(0, 0, -7)
+		_, err := s.reportRepo.FindByPeriod(ctx, user.ID, periodStart.UTC())
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, apierror.ErrNotFound) {
+			s.logger.Error("Failed to look up report for user", user.ID, ":", err)
+			continue
+		}
+
+		stats, err := s.computeStats(ctx, user.ID, user.Locale, periodStart, periodStart.AddDate(0, 0, 7))
+		if err != nil {
+			s.logger.Error("Failed to compute report for user", user.ID, ":", err)
+			continue
+		}
+		report := model.NewInboxReport(user.ID, periodStart, stats)
+		if err := s.reportRepo.Save(ctx, report); err != nil {
+			s.logger.Error("Failed to save report for user", user.ID, ":", err)
+			continue
+		}
+		generated++
+		s.sendReport(ctx, user, report)
+	}
+	return generated, nil
+}
+
+// computeStats tallies the user's emails received in [start, end), trashed ones included,
+// and the senders unsubscribed from in that time, labelling uncategorized emails in the locale
+func (s *reportService) computeStats(ctx context.Context, userID, locale string, start, end time.Time) (model.ReportStats, error) {
+	var stats model.ReportStats
+
+	emails, err := s.emailRepo.FindByUserID(ctx, userID, 0)
+	if err != nil {
+		return stats, err
+	}
+	trashed, err := s.emailRepo.FindDeletedByUserID(ctx, userID, 0)
+	if err != nil {
+		return stats, err
+	}
+	categories, err := s.categoryRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return stats, err
+	}
+	categoryNames := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	inPeriod := func(t time.Time) bool {
+		return !t.Before(start) && t.Before(end)
+	}
+	byCategory := make(map[string]int)
+	bySender := make(map[string]*model.SenderVolume)
+	unsubscribed := make(map[string]bool)
+	for _, email := range append(emails, trashed...) {
+		if email.UnsubscribedAt != nil && inPeriod(*email.UnsubscribedAt) {
+			unsubscribed[email.FromAddress] = true
+		}
+		if !inPeriod(email.ReceivedAt) {
+			continue
+		}
+
+		stats.TotalEmails++
+		byCategory[email.CategoryID]++
+		if email.Summary != "" {
+			stats.Summarized++
+		}
+		if email.Archived || email.LocallyArchived {
+			stats.Archived++
+		}
+		if email.FromAddress != "" {
+			sender, exists := bySender[email.FromAddress]
+			if !exists {
+				sender = &model.SenderVolume{Address: email.FromAddress}
+				bySender[email.FromAddress] = sender
+			}
+			sender.Count++
+			if sender.Name == "" {
+				sender.Name = email.FromName
+			}
+		}
+	}
+	stats.Unsubscribes = len(unsubscribed)
+
+	stats.Categories = make([]model.CategoryVolume, 0, len(byCategory))
+	for categoryID, count := range byCategory {
+		name, known := categoryNames[categoryID]
+		if !known {
+			name = i18n.Translate(locale, "Uncategorized")
+		}
+		stats.Categories = append(stats.Categories, model.CategoryVolume{CategoryID: categoryID, Name: name, Count: count})
+	}
+	sort.Slice(stats.Categories, func(i, j int) bool {
+		if stats.Categories[i].Count != stats.Categories[j].Count {
+			return stats.Categories[i].Count > stats.Categories[j].Count
+		}
+		return stats.Categories[i].Name < stats.Categories[j].Name
+	})
+
+	stats.TopSenders = make([]model.SenderVolume, 0, len(bySender))
+	for _, sender := range bySender {
+		stats.TopSenders = append(stats.TopSenders, *sender)
+	}
+	sort.Slice(stats.TopSenders, func(i, j int) bool {
+		if stats.TopSenders[i].Count != stats.TopSenders[j].Count {
+			return stats.TopSenders[i].Count > stats.TopSenders[j].Count
+		}
+		return stats.TopSenders[i].Address < stats.TopSenders[j].Address
+	})
+	if len(stats.TopSenders) > topSenderCount {
+		stats.TopSenders = stats.TopSenders[:topSenderCount]
+	}
+
+	saved := stats.Summarized*secondsSavedPerSummary + stats.Archived*secondsSavedPerArchive +
+		stats.Unsubscribes*secondsSavedPerUnsubscribe
+	stats.TimeSavedMinutes = saved / 60
+	return stats, nil
+}
+
+// sendReport emails the report to the user when reports are emailed; the report stays in the
+// app when that fails
+func (s *reportService) sendReport(ctx context.Context, user *model.User, report *model.InboxReport) {
+	if s.mailSender == nil {
+		return
+	}
+	message, err := emailtemplate.RenderReport(emailtemplate.Report{UserName: user.Name, Report: report, AppURL: s.appURL})
+	if err == nil {
+		err = s.mailSender.Send(ctx, user.Email, message)
+	}
+	if err != nil {
+		s.logger.Error("Failed to email report to user", user.ID, ":", err)
+	}
+}
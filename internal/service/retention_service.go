@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+type retentionService struct {
+	retentionRepo repository.RetentionPolicyRepository
+	emailRepo     repository.EmailRepository
+	logger        *logger.Logger
+
+	// Per-user totals of what has been pruned since startup
+	stats      map[string]model.PruneStats
+	statsMutex sync.RWMutex
+}
+
+func NewRetentionService(retentionRepo repository.RetentionPolicyRepository, emailRepo repository.EmailRepository, logger *logger.Logger) RetentionService {
+	return &retentionService{
+		retentionRepo: retentionRepo,
+		emailRepo:     emailRepo,
+		logger:        logger,
+		stats:         make(map[string]model.PruneStats),
+	}
+}
+
+func (s *retentionService) GetPolicy(ctx context.Context, userID string) (*model.RetentionPolicy, error) {
+	policy, err := s.retentionRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		// No saved policy means nothing is pruned
+		return model.NewRetentionPolicy(userID, 0, 0, 0), nil
+	}
+	return policy, nil
+}
+
+func (s *retentionService) UpdatePolicy(ctx context.Context, userID string, bodyRetentionDays, retentionDays, maxEmailsPerCategory int) (*model.RetentionPolicy, error) {
+	if bodyRetentionDays < 0 || retentionDays < 0 || maxEmailsPerCategory < 0 {
+		return nil, apierror.Validation("retention limits must not be negative")
+	}
+
+	policy := model.NewRetentionPolicy(userID, bodyRetentionDays, retentionDays, maxEmailsPerCategory)
+	if err := s.retentionRepo.Save(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to save retention policy: %w", err)
+	}
+
+	s.logger.Info("Updated retention policy for user:", userID)
+	return policy, nil
+}
+
+func (s *retentionService) GetStats(userID string) model.PruneStats {
+	s.statsMutex.RLock()
+	defer s.statsMutex.RUnlock()
+
+	return s.stats[userID]
+}
+
+func (s *retentionService) EnforcePolicies(ctx context.Context) (model.PruneStats, error) {
+	total := model.PruneStats{LastRunAt: time.Now()}
+
+	policies, err := s.retentionRepo.FindAll(ctx)
+	if err != nil {
+		return total, fmt.Errorf("failed to get retention policies: %w", err)
+	}
+
+	for _, policy := range policies {
+		if !policy.IsEnabled() {
+			continue
+		}
+
+		result, err := s.enforcePolicy(ctx, policy)
+		if err != nil {
+			// Keep going so one failing user doesn't block the others
+			s.logger.Error("Failed to enforce retention policy for user", policy.UserID, ":", err)
+		}
+
+		s.statsMutex.Lock()
+		userStats := s.stats[policy.UserID]
+		userStats.Add(result)
+		s.stats[policy.UserID] = userStats
+		s.statsMutex.Unlock()
+
+		total.Add(result)
+	}
+
+	if total.BodiesPruned > 0 || total.EmailsDeleted > 0 {
+		s.logger.Info("Retention pruned", total.BodiesPruned, "bodies and deleted", total.EmailsDeleted, "emails")
+	}
+	return total, nil
+}
+
+// enforcePolicy prunes bodies first, then whole records, so older mail degrades before it disappears
+func (s *retentionService) enforcePolicy(ctx context.Context, policy *model.RetentionPolicy) (model.PruneStats, error) {
+	now := time.Now()
+	result := model.PruneStats{LastRunAt: now}
+
+	if policy.BodyRetentionDays > 0 {
+		pruned, err := s.emailRepo.PruneBodiesBefore(ctx, policy.UserID, now.AddDate(0, 0, -policy.BodyRetentionDays))
+		if err != nil {
+			return result, fmt.Errorf("failed to prune bodies: %w", err)
+		}
+		result.BodiesPruned += pruned
+	}
+
+	if policy.RetentionDays > 0 {
+		deleted, err := s.emailRepo.DeleteReceivedBefore(ctx, policy.UserID, now.AddDate(0, 0, -policy.RetentionDays))
+		if err != nil {
+			return result, fmt.Errorf("failed to delete expired emails: %w", err)
+		}
+		result.EmailsDeleted += deleted
+	}
+
+	if policy.MaxEmailsPerCategory > 0 {
+		deleted, err := s.emailRepo.DeleteExcessPerCategory(ctx, policy.UserID, policy.MaxEmailsPerCategory)
+		if err != nil {
+			return result, fmt.Errorf("failed to delete excess emails: %w", err)
+		}
+		result.EmailsDeleted += deleted
+	}
+
+	return result, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+)
+
+func (s *emailService) SetReviewThreshold(percent int) {
+	s.reviewThreshold = float64(percent) / 100
+}
+
+// holdForReview moves a classification the AI wasn't confident enough of into the review
+// queue: the email keeps the category as a suggestion and is left uncategorized
+func (s *emailService) holdForReview(email *model.Email) {
+	if email.CategoryID == "" || email.Confidence >= s.reviewThreshold {
+		return
+	}
+	s.logger.Info("Holding email", email.ID, "for review, classified with confidence", email.Confidence)
+	email.ReviewCategoryID = email.CategoryID
+	email.CategoryID = ""
+}
+
+func (s *emailService) GetReviewQueue(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	return s.emailRepo.FindInReview(ctx, userID, limit)
+}
+
+// ResolveReview files an email of the review queue under the category the user picked, the
+// suggested one or another. Like any email the user files, the categorized hooks learn from it.
+func (s *emailService) ResolveReview(ctx context.Context, userID, emailID, categoryID string) (*model.Email, error) {
+	email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !email.InReview() {
+		return nil, apierror.Conflict("The email isn't waiting for review", email.WithoutBody())
+	}
+
+	return s.CategorizeEmail(ctx, userID, emailID, categoryID)
+}
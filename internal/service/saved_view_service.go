@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+type savedViewService struct {
+	viewRepo     repository.SavedViewRepository
+	categoryRepo repository.CategoryRepository
+	emailService EmailService
+	logger       *logger.Logger
+}
+
+func NewSavedViewService(
+	viewRepo repository.SavedViewRepository,
+	categoryRepo repository.CategoryRepository,
+	emailService EmailService,
+	logger *logger.Logger,
+) SavedViewService {
+	return &savedViewService{
+		viewRepo:     viewRepo,
+		categoryRepo: categoryRepo,
+		emailService: emailService,
+		logger:       logger,
+	}
+}
+
+func (s *savedViewService) CreateView(ctx context.Context, userID, name string, filter model.EmailFilter) (*model.SavedView, error) {
+	name = strings.TrimSpace(name)
+	if err := s.validate(ctx, userID, "", name, filter); err != nil {
+		return nil, err
+	}
+
+	view := model.NewSavedView(userID, name, filter)
+	if err := s.viewRepo.Create(ctx, view); err != nil {
+		return nil, fmt.Errorf("failed to save view: %w", err)
+	}
+
+	s.logger.Info("Created view:", view.ID, "for user:", userID)
+	return view, nil
+}
+
+func (s *savedViewService) GetViews(ctx context.Context, userID string) ([]*model.SavedView, error) {
+	return s.viewRepo.FindByUserID(ctx, userID)
+}
+
+func (s *savedViewService) GetView(ctx context.Context, userID, viewID string) (*model.SavedView, error) {
+	return s.viewRepo.FindByIDAndUser(ctx, viewID, userID)
+}
+
+func (s *savedViewService) UpdateView(ctx context.Context, userID, viewID, name string, filter model.EmailFilter) (*model.SavedView, error) {
+	view, err := s.viewRepo.FindByIDAndUser(ctx, viewID, userID)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if err := s.validate(ctx, userID, view.ID, name, filter); err != nil {
+		return nil, err
+	}
+
+	view.Name = name
+	view.Filter = filter
+	view.UpdatedAt = time.Now()
+	if err := s.viewRepo.Update(ctx, view); err != nil {
+		return nil, fmt.Errorf("failed to update view: %w", err)
+	}
+
+	s.logger.Info("Updated view:", view.ID)
+	return view, nil
+}
+
+func (s *savedViewService) DeleteView(ctx context.Context, userID, viewID string) error {
+	view, err := s.viewRepo.FindByIDAndUser(ctx, viewID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.viewRepo.Delete(ctx, view.ID); err != nil {
+		return fmt.Errorf("failed to delete view: %w", err)
+	}
+
+	s.logger.Info("Deleted view:", view.ID)
+	return nil
+}
+
+// ListEmails runs the view's filter through the same listing as the email endpoints
+func (s *savedViewService) ListEmails(ctx context.Context, userID, viewID string, limit int) ([]*model.Email, error) {
+	view, err := s.viewRepo.FindByIDAndUser(ctx, viewID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.emailService.ListEmails(ctx, userID, view.Filter, limit)
+}
+
+// validate checks what request tags can't: the name must be free among the user's other views,
+// the category visible to the user and the date range not empty
+func (s *savedViewService) validate(ctx context.Context, userID, viewID, name string, filter model.EmailFilter) error {
+	var fields []apierror.FieldError
+	if name == "" {
+		fields = append(fields, apierror.FieldError{Field: "name", Message: "is required"})
+	}
+	if filter.CategoryID != "" {
+		if _, err := s.categoryRepo.FindByIDAndUser(ctx, filter.CategoryID, userID); err != nil {
+			fields = append(fields, apierror.FieldError{Field: "filter.category_id", Message: "does not exist"})
+		}
+	}
+	if filter.After != nil && filter.Before != nil && !filter.After.Before(*filter.Before) {
+		fields = append(fields, apierror.FieldError{Field: "filter.before", Message: "must be later than after"})
+	}
+	if len(fields) > 0 {
+		return apierror.InvalidFields(fields)
+	}
+
+	views, err := s.viewRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, view := range views {
+		if view.ID != viewID && strings.EqualFold(view.Name, name) {
+			return apierror.Conflict(fmt.Sprintf("A view named %q already exists", view.Name), view)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"regexp"
+
+	"jump-challenge/internal/model"
+)
+
+// securityTextBytes bounds how much of the body is searched; the phrases open security emails,
+// while newsletters tend to mention "forgot your password?" in their footer
+const securityTextBytes = 500
+
+// securityPatterns map the wording providers use to a kind of security event, checked in order
+// since a suspicious sign-in or a password change notice usually also mentions signing in
+var securityPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{model.SecuritySuspicious, regexp.MustCompile(`(?i)\b(suspicious|unusual|unrecognized|unfamiliar) (activity|sign-in|sign in|login|log-in|attempt)|\bsomeone (tried|may have tried) to\b|\bblocked (a )?sign-in\b`)},
+	{model.SecurityPasswordReset, regexp.MustCompile(`(?i)\bpassword reset\b|\breset (your|the) password\b|\bforgot (your )?password\b|\bpassword recovery\b`)},
+	{model.SecurityAccountChange, regexp.MustCompile(`(?i)\b(password|email address|phone number|recovery (email|phone)) (was|has been) (changed|updated|added|removed)\b|\b(two-factor|2-step|two-step|2fa) (verification|authentication)? ?(was|has been|is now) (turned on|turned off|enabled|disabled)\b|\bpasskey (was|has been) (added|removed)\b`)},
+	{model.SecurityNewSignIn, regexp.MustCompile(`(?i)\bnew (sign-in|sign in|login|log-in)\b|\b(signed|logged) in (to|on|from) (a )?new\b|\bnew (device|browser) (signed|logged) in\b`)},
+	{model.SecuritySuspicious, regexp.MustCompile(`(?i)\bsecurity alert\b`)},
+}
+
+// detectSecurityFlag flags password resets, new sign-ins and other account-security emails,
+// independently of the user's categories
+func detectSecurityFlag(email *model.Email) bool {
+	if email.SystemFlag != "" {
+		return false
+	}
+	body := plainText(email.Body)
+	text := email.Subject + "\n" + body[:min(len(body), securityTextBytes)]
+	for _, candidate := range securityPatterns {
+		if candidate.pattern.MatchString(text) {
+			email.SecurityFlag = candidate.kind
+			return true
+		}
+	}
+	return false
+}
+
+// GetSecurityEvents lists the user's account-security emails, newest first
+func (s *emailService) GetSecurityEvents(ctx context.Context, userID string, limit int) ([]*model.SecurityEvent, error) {
+	emails, err := s.emailRepo.FindSecurityFlagged(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*model.SecurityEvent, 0, len(emails))
+	for _, email := range emails {
+		events = append(events, model.NewSecurityEvent(email))
+	}
+	return events, nil
+}
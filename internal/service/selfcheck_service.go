@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+)
+
+// selfCheckTimeout bounds each check, so one unreachable service doesn't hold up the report
+const selfCheckTimeout = 20 * time.Second
+
+type namedCheck struct {
+	name  string
+	check SelfCheck
+}
+
+type selfCheckService struct {
+	checks []namedCheck
+	logger *logger.Logger
+}
+
+func NewSelfCheckService(logger *logger.Logger) SelfCheckService {
+	return &selfCheckService{logger: logger}
+}
+
+func (s *selfCheckService) AddCheck(name string, check SelfCheck) {
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+func (s *selfCheckService) Run(ctx context.Context) *model.SelfCheckReport {
+	report := &model.SelfCheckReport{
+		OK:        true,
+		Checks:    make([]*model.SelfCheck, len(s.checks)),
+		CheckedAt: time.Now(),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, selfCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			message, err := check.check(checkCtx)
+			result := &model.SelfCheck{Name: check.name, OK: err == nil, Message: message, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Message = err.Error()
+				s.logger.Warn("Self check", check.name, "failed:", err)
+			}
+			report.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		report.OK = report.OK && check.OK
+	}
+	return report
+}
+
+// selfCheckCategories are what the AI check asks the provider to classify into
+var selfCheckCategories = []*model.Category{
+	model.NewCategory("Work", "Meetings, projects and messages from colleagues"),
+	model.NewCategory("Shopping", "Orders, receipts and deliveries from online stores"),
+}
+
+// AICheck has client classify a made-up email, which takes a completion from its provider
+func AICheck(client AIClient) SelfCheck {
+	return func(ctx context.Context) (string, error) {
+		category, err := client.ClassifyEmail(ctx, "Your order has shipped and will arrive on Monday.", selfCheckCategories)
+		if err != nil {
+			return "", fmt.Errorf("the AI provider failed a test classification, check AI_PROVIDER, AI_API_KEY and DEFAULT_MODEL: %w", err)
+		}
+		return fmt.Sprintf("the AI provider classified a test email as %q", category), nil
+	}
+}
+
+// GoogleOAuthCheck has Google's token endpoint at tokenURL redeem a made-up authorization code
+// with the OAuth client's credentials. Google rejects the code, but only after accepting the
+// client, so its answer tells whether the credentials and redirect URL are right.
+func GoogleOAuthCheck(clientID, clientSecret, redirectURL, tokenURL string, httpClient *http.Client) SelfCheck {
+	return func(ctx context.Context) (string, error) {
+		if clientID == "" || clientSecret == "" {
+			return "", errors.New("GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET must be set to the OAuth client's credentials from the Google Cloud console")
+		}
+		if !strings.HasSuffix(clientID, ".apps.googleusercontent.com") {
+			return "", errors.New("GOOGLE_CLIENT_ID should end in .apps.googleusercontent.com, copy the client ID from the Google Cloud console")
+		}
+
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {"self-check"},
+			"client_id":     {clientID},
+			"client_secret": {clientSecret},
+			"redirect_uri":  {redirectURL},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to reach Google: %w", err)
+		}
+		defer resp.Body.Close()
+
+		var answer struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+			return "", fmt.Errorf("unexpected answer from Google, status %d", resp.StatusCode)
+		}
+		switch answer.Error {
+		case "invalid_grant":
+			return "Google accepted the OAuth client", nil
+		case "invalid_client", "unauthorized_client":
+			return "", fmt.Errorf("Google rejected GOOGLE_CLIENT_ID or GOOGLE_CLIENT_SECRET (%s), copy them from the OAuth client in the Google Cloud console", answer.Description)
+		case "redirect_uri_mismatch":
+			return "", fmt.Errorf("add %s to the OAuth client's authorized redirect URIs, or fix BASE_URL", redirectURL)
+		}
+		return "", fmt.Errorf("unexpected answer from Google, status %d: %s %s", resp.StatusCode, answer.Error, answer.Description)
+	}
+}
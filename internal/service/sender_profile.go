@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// senderProfileEmails bounds how many of the sender's latest emails the AI reads
+const senderProfileEmails = 20
+
+// maxSenderProfileLength bounds the description accepted from the AI
+const maxSenderProfileLength = 200
+
+func (s *emailService) UseSenderProfiles(profiles repository.SenderProfileRepository) {
+	s.profiles = profiles
+}
+
+// GenerateSenderProfile asks the AI to characterize the sender from their recent emails. The
+// cached profile is returned while no emails were added or removed since, unless refresh is set.
+func (s *emailService) GenerateSenderProfile(ctx context.Context, userID, address string, refresh bool) (*model.SenderProfile, error) {
+	if s.profiles == nil {
+		return nil, errors.New("sender profiles are not configured")
+	}
+	history, err := s.GetSenderHistory(ctx, userID, address)
+	if err != nil {
+		return nil, err
+	}
+	if len(history.Emails) == 0 {
+		return nil, apierror.NotFound("no emails from this sender")
+	}
+	if history.Profile != nil && history.Profile.IsCurrent(history) && !refresh {
+		return history.Profile, nil
+	}
+	// The profile is drawn from the summaries and subjects of the sender's emails
+	sensitive, err := s.sensitiveCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, email := range history.Emails {
+		if sensitive[email.CategoryID] {
+			return nil, apierror.Forbidden("senders of emails in sensitive categories are not profiled in privacy mode")
+		}
+	}
+
+	// Profiles draw on the same AI budget as summaries
+	if s.quotas != nil {
+		if _, err := s.quotas.Reserve(ctx, userID, model.MetricSummaries, 1); err != nil {
+			return nil, err
+		}
+	}
+	task, content := senderProfilePrompt(history)
+	description, err := s.aiClient.Analyze(ctx, task, content)
+	if err != nil {
+		return nil, apierror.Upstream("failed to generate sender profile", err)
+	}
+	// One short line is asked for; a longer answer was steered by what the emails say
+	description = strings.TrimSpace(description)
+	if description == "" || strings.Contains(description, "\n") || len(description) > maxSenderProfileLength {
+		return nil, apierror.Upstream("failed to generate sender profile", errors.New("empty or malformed answer from AI"))
+	}
+
+	profile := &model.SenderProfile{
+		UserID:      userID,
+		Address:     history.Address,
+		Description: description,
+		EmailCount:  history.Stats.Total,
+		GeneratedAt: time.Now(),
+	}
+	if err := s.profiles.Save(ctx, profile); err != nil {
+		return nil, fmt.Errorf("failed to save sender profile: %w", err)
+	}
+
+	s.logger.Info("Generated profile of sender", history.Address, "for user", userID)
+	return profile, nil
+}
+
+// findSenderProfile returns the cached profile of the sender, nil when none was generated yet
+func (s *emailService) findSenderProfile(ctx context.Context, userID, address string) (*model.SenderProfile, error) {
+	if s.profiles == nil {
+		return nil, nil
+	}
+	profile, err := s.profiles.FindByAddress(ctx, userID, address)
+	if errors.Is(err, apierror.ErrNotFound) {
+		return nil, nil
+	}
+	return profile, err
+}
+
+// senderProfilePrompt describes the sender's volume and the user's engagement, which the AI
+// can't infer from the emails alone, as the task; the sender's name and the latest emails'
+// subjects and summaries, written by the sender, are the content
+func senderProfilePrompt(history *model.SenderHistory) (task, content string) {
+	stats := history.Stats
+	weeks := stats.LastReceivedAt.Sub(*stats.FirstReceivedAt).Hours() / (24 * 7)
+	if weeks < 1 {
+		weeks = 1
+	}
+	important := 0
+	for _, email := range history.Emails {
+		if email.Important {
+			important++
+		}
+	}
+
+	recent := history.Emails[max(0, len(history.Emails)-senderProfileEmails):]
+	lines := make([]string, 0, len(recent))
+	for i := len(recent) - 1; i >= 0; i-- {
+		email := recent[i]
+		lines = append(lines, fmt.Sprintf("- %s | %s | %s", email.ReceivedAt.Format("2006-01-02"), email.Subject, email.Summary))
+	}
+
+	task = fmt.Sprintf(`Characterize this email sender in one short line for the recipient's inbox.
+
+Emails received: %d between %s and %s (about %.1f per week)
+Marked important by Gmail: %d
+Left unread: %d, archived: %d, deleted: %d
+
+Respond with only the description, for example "weekly marketing newsletter, ~3 emails/week, rarely important".`,
+		stats.Total, stats.FirstReceivedAt.Format("2006-01-02"), stats.LastReceivedAt.Format("2006-01-02"),
+		float64(stats.Total)/weeks, important, stats.Unread, stats.Archived, stats.Deleted)
+
+	content = fmt.Sprintf("Sender: %s <%s>\n\nLatest emails (date | subject | summary):\n%s",
+		history.Name, history.Address, strings.Join(lines, "\n"))
+	return task, content
+}
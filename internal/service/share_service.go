@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultShareExpiry applies when a share is created without an expiry
+const DefaultShareExpiry = 72 * time.Hour
+
+// errShareUnavailable hides whether a link was forged, expired or revoked
+var errShareUnavailable = apierror.NotFound("this link is invalid, expired or revoked")
+
+// blankLines collapses the runs of empty lines left behind by HTML layout
+var blankLines = regexp.MustCompile(`\n\s*\n\s*\n+`)
+
+type shareService struct {
+	shareRepo    repository.EmailShareRepository
+	emailRepo    repository.EmailRepository
+	categoryRepo repository.CategoryRepository
+	secret       []byte // signs share tokens
+	baseURL      string
+	logger       *logger.Logger
+}
+
+func NewShareService(shareRepo repository.EmailShareRepository, emailRepo repository.EmailRepository, categoryRepo repository.CategoryRepository, secret, baseURL string, logger *logger.Logger) ShareService {
+	return &shareService{
+		shareRepo:    shareRepo,
+		emailRepo:    emailRepo,
+		categoryRepo: categoryRepo,
+		secret:       []byte(secret),
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		logger:       logger,
+	}
+}
+
+func (s *shareService) CreateShare(ctx context.Context, userID, emailID string, expiresIn time.Duration, includeBody bool) (*model.EmailShare, error) {
+	email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if expiresIn <= 0 {
+		expiresIn = DefaultShareExpiry
+	}
+
+	share := model.NewEmailShare(userID, email.ID, includeBody, time.Now().Add(expiresIn))
+	if err := s.shareRepo.Create(ctx, share); err != nil {
+		return nil, fmt.Errorf("failed to create share: %w", err)
+	}
+
+	s.logger.Info("Created share", share.ID, "for email", email.ID, "expiring at", share.ExpiresAt)
+	return s.withURL(share), nil
+}
+
+func (s *shareService) GetShares(ctx context.Context, userID, emailID string) ([]*model.EmailShare, error) {
+	if _, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID); err != nil {
+		return nil, err
+	}
+
+	shares, err := s.shareRepo.FindByEmail(ctx, userID, emailID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shares: %w", err)
+	}
+	for _, share := range shares {
+		s.withURL(share)
+	}
+	return shares, nil
+}
+
+func (s *shareService) RevokeShare(ctx context.Context, userID, emailID, shareID string) error {
+	share, err := s.shareRepo.FindByID(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if share.UserID != userID || share.EmailID != emailID {
+		return apierror.NotFound("share not found")
+	}
+	if share.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	share.RevokedAt = &now
+	if err := s.shareRepo.Update(ctx, share); err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+
+	s.logger.Info("Revoked share", share.ID, "for email", emailID)
+	return nil
+}
+
+func (s *shareService) OpenShare(ctx context.Context, token string) (*model.SharedEmail, error) {
+	shareID, _, _ := strings.Cut(token, ".")
+	share, err := s.shareRepo.FindByID(ctx, shareID)
+	if err != nil {
+		return nil, errShareUnavailable
+	}
+	if !hmac.Equal([]byte(token), []byte(s.token(share))) || !share.IsActive(time.Now()) {
+		return nil, errShareUnavailable
+	}
+
+	email, err := s.emailRepo.FindByIDAndUser(ctx, share.EmailID, share.UserID)
+	if err != nil || email.DeletedAt != nil {
+		return nil, errShareUnavailable
+	}
+
+	shared := &model.SharedEmail{
+		Subject:    email.Subject,
+		From:       email.From,
+		ReceivedAt: email.ReceivedAt,
+		Summary:    email.Summary,
+		ExpiresAt:  share.ExpiresAt,
+	}
+	if category, err := s.categoryRepo.FindByIDAndUser(ctx, email.CategoryID, share.UserID); err == nil {
+		shared.Category = category.Name
+	}
+	if share.IncludeBody {
+		shared.Body = plainText(email.Body)
+	}
+	return shared, nil
+}
+
+// token signs the share ID so links cannot be guessed; expiry and revocation are checked on the stored share
+func (s *shareService) token(share *model.EmailShare) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("email-share:" + share.ID))
+	return share.ID + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *shareService) withURL(share *model.EmailShare) *model.EmailShare {
+	share.URL = s.baseURL + "/share/" + s.token(share)
+	return share
+}
+
+// plainText strips markup from an email body, dropping scripts, styles and images
+func plainText(body string) string {
+	if !strings.Contains(body, "<") {
+		return strings.TrimSpace(body)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	doc.Find("script, style, head, img").Remove()
+	// Keep some of the layout that block elements give the text
+	doc.Find("br, p, div, tr, li, h1, h2, h3, h4").Each(func(i int, selection *goquery.Selection) {
+		selection.AppendHtml("\n")
+	})
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(doc.Text(), "\n\n"))
+}
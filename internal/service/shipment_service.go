@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+var (
+	// shippingKeyword tells shipping notifications apart from other emails quoting long numbers
+	shippingKeyword = regexp.MustCompile(`(?i)\b(shipped|shipping|shipment|tracking (number|#|no)|track your (package|order)|out for delivery|delivered|on (its|the) way)\b`)
+
+	// Tracking number formats; FedEx and DHL numbers are plain digits, so they are only taken
+	// from emails naming the carrier
+	upsTrackingNumber   = regexp.MustCompile(`\b1Z[0-9A-Z]{16}\b`)
+	uspsTrackingNumber  = regexp.MustCompile(`\b9[1-5]\d{20}\b`)
+	fedexTrackingNumber = regexp.MustCompile(`\b(\d{12}|\d{15})\b`)
+	dhlTrackingNumber   = regexp.MustCompile(`\b\d{10}\b`)
+	fedexMention        = regexp.MustCompile(`(?i)\bfedex\b`)
+	dhlMention          = regexp.MustCompile(`(?i)\bdhl\b`)
+
+	// aiTrackingNumber bounds what is accepted from the AI as a tracking number
+	aiTrackingNumber = regexp.MustCompile(`^[0-9A-Z]{8,34}$`)
+
+	// Status phrases, checked in this order since delivery notices often repeat earlier steps
+	shipmentExceptionText = regexp.MustCompile(`(?i)\b(delivery exception|delivery attempt(ed)?|unable to deliver|could not be delivered|returned to sender|delayed)\b`)
+	shipmentDeliveredText = regexp.MustCompile(`(?i)\b(has been|was|were|got|been) delivered\b|\bdelivered:|^delivered\b`)
+	shipmentOutText       = regexp.MustCompile(`(?i)\bout for delivery\b`)
+)
+
+type shipmentService struct {
+	shipmentRepo   repository.ShipmentRepository
+	aiClient       AIClient
+	trackingClient TrackingClient // nil when no carrier API is configured
+	logger         *logger.Logger
+
+	deliveredHooks []ShipmentHook
+	isSensitive    SensitiveCheck // nil lets the AI read every notification
+
+	// Hooks run concurrently during a sync and notices about a package often arrive together
+	mutex sync.Mutex
+}
+
+func NewShipmentService(shipmentRepo repository.ShipmentRepository, aiClient AIClient, trackingClient TrackingClient, logger *logger.Logger) ShipmentService {
+	return &shipmentService{
+		shipmentRepo:   shipmentRepo,
+		aiClient:       aiClient,
+		trackingClient: trackingClient,
+		logger:         logger,
+	}
+}
+
+func (s *shipmentService) OnDelivered(hook ShipmentHook) {
+	s.deliveredHooks = append(s.deliveredHooks, hook)
+}
+
+func (s *shipmentService) UseSensitiveCheck(check SensitiveCheck) {
+	s.isSensitive = check
+}
+
+func (s *shipmentService) GetShipments(ctx context.Context, userID string) ([]*model.Shipment, error) {
+	shipments, err := s.shipmentRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if shipments == nil {
+		shipments = []*model.Shipment{}
+	}
+	return shipments, nil
+}
+
+func (s *shipmentService) DetectShipment(ctx context.Context, email *model.Email) {
+	if email.SystemFlag != "" {
+		return
+	}
+	text := email.Subject + "\n" + plainText(email.Body)
+	if !shippingKeyword.MatchString(text) {
+		return
+	}
+
+	found := extractTrackingNumbers(text)
+	if len(found) == 0 {
+		if s.isSensitive != nil && s.isSensitive(ctx, email) {
+			return
+		}
+		carrier, number, err := s.askTrackingNumber(ctx, text)
+		if err != nil {
+			s.logger.Warn("Failed to extract tracking number with AI:", err)
+			return
+		}
+		if number == "" {
+			return
+		}
+		found = map[string]string{number: carrier}
+	}
+
+	status := shipmentStatusFromText(text)
+	for number, carrier := range found {
+		if err := s.saveShipment(ctx, email, carrier, number, status); err != nil {
+			s.logger.Error("Failed to save shipment", number, "for email", email.ID, ":", err)
+		}
+	}
+}
+
+// saveShipment records the package, or moves an existing one forward with the latest notice
+func (s *shipmentService) saveShipment(ctx context.Context, email *model.Email, carrier, number, status string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	shipment, err := s.shipmentRepo.FindByTrackingNumber(ctx, email.UserID, number)
+	if errors.Is(err, apierror.ErrNotFound) {
+		shipment = model.NewShipment(email.UserID, email.ID, carrier, number, status)
+		if err := s.shipmentRepo.Create(ctx, shipment); err != nil {
+			return err
+		}
+		s.logger.Info("Tracking", carrier, "shipment", number, "for user", email.UserID)
+		if shipment.IsDelivered() {
+			s.afterDelivered(ctx, shipment)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A delivered package stays delivered, whatever order its notices are synced in
+	if shipment.IsDelivered() {
+		return nil
+	}
+	shipment.EmailID = email.ID
+	changed := shipment.SetStatus(status, time.Now())
+	if err := s.shipmentRepo.Update(ctx, shipment); err != nil {
+		return err
+	}
+	if changed && shipment.IsDelivered() {
+		s.afterDelivered(ctx, shipment)
+	}
+	return nil
+}
+
+func (s *shipmentService) RefreshStatuses(ctx context.Context) (int, error) {
+	if s.trackingClient == nil {
+		return 0, nil
+	}
+	shipments, err := s.shipmentRepo.FindUndelivered(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, shipment := range shipments {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		status, err := s.trackingClient.Track(ctx, shipment.Carrier, shipment.TrackingNumber)
+		if err != nil {
+			s.logger.Warn("Failed to track", shipment.Carrier, "shipment", shipment.TrackingNumber, ":", err)
+			continue
+		}
+
+		s.mutex.Lock()
+		now := time.Now()
+		shipment.CheckedAt = &now
+		changed := shipment.SetStatus(status, now)
+		err = s.shipmentRepo.Update(ctx, shipment)
+		s.mutex.Unlock()
+		if err != nil {
+			s.logger.Error("Failed to update shipment", shipment.ID, ":", err)
+			continue
+		}
+		if changed && shipment.IsDelivered() {
+			delivered++
+			s.afterDelivered(ctx, shipment)
+		}
+	}
+	return delivered, nil
+}
+
+func (s *shipmentService) afterDelivered(ctx context.Context, shipment *model.Shipment) {
+	for _, hook := range s.deliveredHooks {
+		hook(ctx, shipment)
+	}
+}
+
+// askTrackingNumber has the AI read shipping notices none of the known formats matched, e.g.
+// numbers split by spaces; an empty number means the email has none
+func (s *shipmentService) askTrackingNumber(ctx context.Context, text string) (string, string, error) {
+	task := `Find the package tracking number in this shipping notification email.
+
+Respond with only two lines in the format "CARRIER: <ups, fedex, usps, dhl or other>" and "TRACKING: <number>". If the email has no tracking number, respond with "NONE".`
+
+	answer, err := s.aiClient.Analyze(ctx, task, text)
+	if err != nil {
+		return "", "", err
+	}
+
+	var carrier, number string
+	for _, line := range strings.Split(answer, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "CARRIER":
+			carrier = strings.ToLower(value)
+		case "TRACKING":
+			number = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(value))
+		}
+	}
+	if !aiTrackingNumber.MatchString(number) {
+		return "", "", nil
+	}
+	return carrier, number, nil
+}
+
+// extractTrackingNumbers returns the tracking numbers in the text with their carrier
+func extractTrackingNumbers(text string) map[string]string {
+	found := make(map[string]string)
+	for _, number := range upsTrackingNumber.FindAllString(text, -1) {
+		found[number] = model.CarrierUPS
+	}
+	for _, number := range uspsTrackingNumber.FindAllString(text, -1) {
+		found[number] = model.CarrierUSPS
+	}
+	if fedexMention.MatchString(text) {
+		for _, number := range fedexTrackingNumber.FindAllString(text, -1) {
+			found[number] = model.CarrierFedEx
+		}
+	}
+	if dhlMention.MatchString(text) {
+		for _, number := range dhlTrackingNumber.FindAllString(text, -1) {
+			found[number] = model.CarrierDHL
+		}
+	}
+	return found
+}
+
+func shipmentStatusFromText(text string) string {
+	switch {
+	case shipmentExceptionText.MatchString(text):
+		return model.ShipmentException
+	case shipmentDeliveredText.MatchString(text):
+		return model.ShipmentDelivered
+	case shipmentOutText.MatchString(text):
+		return model.ShipmentOutForDelivery
+	default:
+		return model.ShipmentInTransit
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+
+	"jump-challenge/internal/model"
+)
+
+func (s *emailService) SetSummaryBudgetReserve(percent int) {
+	s.summaryBudgetReserve = percent
+}
+
+// shouldSummarize tells whether an email classified into the category gets a summary: never
+// for classify-only categories, and for low priority ones only while the user has more than
+// the reserve of this month's summaries left
+func (s *emailService) shouldSummarize(ctx context.Context, userID string, category *model.Category) bool {
+	if category == nil {
+		return true
+	}
+	switch category.SummarizeMode() {
+	case model.SummarizeOff:
+		return false
+	case model.SummarizeLowPriority:
+		if s.quotas == nil || s.summaryBudgetReserve <= 0 {
+			return true
+		}
+		remaining, limit, err := s.quotas.Remaining(ctx, userID, model.MetricSummaries)
+		if err != nil {
+			// Reserve reports the failure if it persists
+			s.logger.Warn("Failed to check the summary budget of user", userID, ":", err)
+			return true
+		}
+		return limit == 0 || remaining*100 > limit*s.summaryBudgetReserve
+	default:
+		return true
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// telegramLinkCodeTTL is how long a link code can be sent to the bot
+const telegramLinkCodeTTL = 15 * time.Minute
+
+const telegramHelp = `Commands:
+/archive - archive the email in Gmail
+/unsubscribe - unsubscribe from its sender
+/categorize <category> - file it under a category
+/stop - stop sending emails to this chat
+
+Reply to a notification to act on that email; otherwise commands apply to the latest one.`
+
+// telegramEmailRef finds the email ID at the end of a notification being replied to
+var telegramEmailRef = regexp.MustCompile(`ref ([0-9a-f-]{36})`)
+
+// pendingTelegramLink is a link code waiting to be sent to the bot
+type pendingTelegramLink struct {
+	userID    string
+	expiresAt time.Time
+}
+
+type telegramService struct {
+	linkRepo            repository.TelegramLinkRepository
+	telegramClient      TelegramClient // nil when no bot token is configured
+	botUsername         string
+	emailService        EmailService
+	categoryService     CategoryService
+	unsubscribeService  UnsubscribeService
+	notificationService NotificationService
+	logger              *logger.Logger
+
+	// Link codes are short-lived, so they are only kept in memory
+	codes map[string]pendingTelegramLink
+	mutex sync.Mutex
+}
+
+func NewTelegramService(
+	linkRepo repository.TelegramLinkRepository,
+	telegramClient TelegramClient,
+	botUsername string,
+	emailService EmailService,
+	categoryService CategoryService,
+	unsubscribeService UnsubscribeService,
+	notificationService NotificationService,
+	logger *logger.Logger,
+) TelegramService {
+	return &telegramService{
+		linkRepo:            linkRepo,
+		telegramClient:      telegramClient,
+		botUsername:         botUsername,
+		emailService:        emailService,
+		categoryService:     categoryService,
+		unsubscribeService:  unsubscribeService,
+		notificationService: notificationService,
+		logger:              logger,
+		codes:               make(map[string]pendingTelegramLink),
+	}
+}
+
+func (s *telegramService) GetLink(ctx context.Context, userID string) (*model.TelegramLink, error) {
+	return s.linkRepo.FindByUserID(ctx, userID)
+}
+
+func (s *telegramService) CreateLinkCode(ctx context.Context, userID string) (*model.TelegramLinkCode, error) {
+	if s.telegramClient == nil {
+		return nil, apierror.Unavailable("telegram bot is not configured")
+	}
+
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate link code: %w", err)
+	}
+	code := &model.TelegramLinkCode{
+		Code:      hex.EncodeToString(raw),
+		ExpiresAt: time.Now().Add(telegramLinkCodeTTL),
+	}
+	if s.botUsername != "" {
+		code.URL = "https://t.me/" + s.botUsername + "?start=" + code.Code
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// Drop codes nobody used while we are here
+	for existing, pending := range s.codes {
+		if time.Now().After(pending.expiresAt) {
+			delete(s.codes, existing)
+		}
+	}
+	s.codes[code.Code] = pendingTelegramLink{userID: userID, expiresAt: code.ExpiresAt}
+
+	return code, nil
+}
+
+func (s *telegramService) Unlink(ctx context.Context, userID string) error {
+	if err := s.linkRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete telegram link: %w", err)
+	}
+
+	s.logger.Info("Unlinked telegram chat for user:", userID)
+	return nil
+}
+
+func (s *telegramService) IsLinked(ctx context.Context, userID string) bool {
+	if s.telegramClient == nil {
+		return false
+	}
+	_, err := s.linkRepo.FindByUserID(ctx, userID)
+	return err == nil
+}
+
+func (s *telegramService) HandleMessage(ctx context.Context, message *model.TelegramMessage) {
+	fields := strings.Fields(message.Text)
+	if len(fields) == 0 {
+		return
+	}
+	// Commands in group chats are addressed as /command@botname
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	args := fields[1:]
+
+	if command == "/start" && len(args) > 0 {
+		s.reply(ctx, message.ChatID, s.linkChat(ctx, message.ChatID, args[0]))
+		return
+	}
+
+	link, err := s.linkRepo.FindByChatID(ctx, message.ChatID)
+	if err != nil {
+		s.reply(ctx, message.ChatID, "This chat is not linked yet. Create a link code in the app and send /start <code>.")
+		return
+	}
+
+	switch command {
+	case "/archive":
+		s.reply(ctx, message.ChatID, s.runOnEmail(ctx, link, message, func(emailID string) (string, error) {
+			return "Archived.", s.emailService.PerformBulkAction(ctx, []string{emailID}, "archive", link.UserID)
+		}))
+	case "/unsubscribe":
+		s.reply(ctx, message.ChatID, s.runOnEmail(ctx, link, message, func(emailID string) (string, error) {
+			return "Unsubscribed.", s.unsubscribeService.UnsubscribeEmails(ctx, []string{emailID}, link.UserID)
+		}))
+	case "/categorize":
+		if len(args) == 0 {
+			s.reply(ctx, message.ChatID, "Usage: /categorize <category>")
+			return
+		}
+		s.reply(ctx, message.ChatID, s.runOnEmail(ctx, link, message, func(emailID string) (string, error) {
+			return s.categorize(ctx, link.UserID, emailID, strings.Join(args, " "))
+		}))
+	case "/stop":
+		if err := s.Unlink(ctx, link.UserID); err != nil {
+			s.logger.Error("Failed to unlink telegram chat:", err)
+			s.reply(ctx, message.ChatID, "Failed to unlink this chat.")
+			return
+		}
+		s.reply(ctx, message.ChatID, "This chat is unlinked. You will not get emails here anymore.")
+	default:
+		s.reply(ctx, message.ChatID, telegramHelp)
+	}
+}
+
+// linkChat consumes a link code and connects the chat to the user who created it
+func (s *telegramService) linkChat(ctx context.Context, chatID int64, code string) string {
+	s.mutex.Lock()
+	pending, exists := s.codes[code]
+	delete(s.codes, code)
+	s.mutex.Unlock()
+
+	if !exists || time.Now().After(pending.expiresAt) {
+		return "This link code is invalid or expired. Create a new one in the app."
+	}
+
+	if err := s.linkRepo.Save(ctx, model.NewTelegramLink(pending.userID, chatID)); err != nil {
+		s.logger.Error("Failed to save telegram link:", err)
+		return "Failed to link this chat, please try again."
+	}
+
+	s.logger.Info("Linked telegram chat for user:", pending.userID)
+	return "This chat is linked. Important emails will show up here.\n\n" + telegramHelp
+}
+
+// runOnEmail applies action to the email the message refers to and describes the outcome
+func (s *telegramService) runOnEmail(ctx context.Context, link *model.TelegramLink, message *model.TelegramMessage, action func(emailID string) (string, error)) string {
+	emailID := link.LastEmailID
+	if match := telegramEmailRef.FindStringSubmatch(message.ReplyToText); match != nil {
+		emailID = match[1]
+	}
+	if emailID == "" {
+		return "There is no email to act on yet. Reply to an email notification."
+	}
+
+	done, err := action(emailID)
+	if err != nil {
+		s.logger.Error("Telegram command failed for user", link.UserID, ":", err)
+		return "Failed: " + apierror.From(err, "something went wrong").Message
+	}
+	return done
+}
+
+// categorize files the email under the user's category with the given name, ignoring case
+func (s *telegramService) categorize(ctx context.Context, userID, emailID, name string) (string, error) {
+	categories, err := s.categoryService.GetAllCategories(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, name) {
+			if _, err := s.emailService.CategorizeEmail(ctx, userID, emailID, category.ID); err != nil {
+				return "", err
+			}
+			return "Moved to " + category.Name + ".", nil
+		}
+	}
+	return "", apierror.NotFound("no category named " + name)
+}
+
+func (s *telegramService) NotifyNewEmail(ctx context.Context, email *model.Email) {
+	// Like desktop notifications, only emails Gmail considers important or from VIP senders are sent
+	if s.telegramClient == nil || (!email.Important && !email.IsUrgent()) {
+		return
+	}
+
+	link, err := s.linkRepo.FindByUserID(ctx, email.UserID)
+	if err != nil {
+		if !errors.Is(err, apierror.ErrNotFound) {
+			s.logger.Error("Failed to get telegram link for user", email.UserID, ":", err)
+		}
+		return
+	}
+	if !s.notificationService.ShouldNotify(ctx, email.UserID, newEmailEvent(email)) {
+		return
+	}
+
+	sender := email.FromName
+	if sender == "" {
+		sender = email.FromAddress
+	}
+	label := "Important"
+	if email.IsUrgent() {
+		label = "VIP"
+	}
+	text := fmt.Sprintf("%s email from %s\n%s", label, sender, email.Subject)
+	if email.Summary != "" {
+		text += "\n\n" + email.Summary
+	}
+	text += "\n\nref " + email.ID
+
+	if err := s.telegramClient.SendMessage(ctx, link.ChatID, text); err != nil {
+		s.logger.Error("Failed to send telegram message:", err)
+		return
+	}
+
+	// Commands sent without replying act on the latest email
+	link.LastEmailID = email.ID
+	if err := s.linkRepo.Save(ctx, link); err != nil {
+		s.logger.Error("Failed to save telegram link:", err)
+	}
+}
+
+func (s *telegramService) reply(ctx context.Context, chatID int64, text string) {
+	if err := s.telegramClient.SendMessage(ctx, chatID, text); err != nil {
+		s.logger.Error("Failed to send telegram message:", err)
+	}
+}
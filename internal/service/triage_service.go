@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// senderHistoryLimit bounds how many of a sender's earlier emails inform a suggestion
+const senderHistoryLimit = 50
+
+type triageService struct {
+	emailRepo         repository.EmailRepository
+	emailService      EmailService
+	automationService AutomationService
+	logger            *logger.Logger
+}
+
+func NewTriageService(
+	emailRepo repository.EmailRepository,
+	emailService EmailService,
+	automationService AutomationService,
+	logger *logger.Logger,
+) TriageService {
+	return &triageService{
+		emailRepo:         emailRepo,
+		emailService:      emailService,
+		automationService: automationService,
+		logger:            logger,
+	}
+}
+
+func (s *triageService) Next(ctx context.Context, userID string) (*model.TriageItem, error) {
+	email, err := s.emailRepo.FindNextUntriaged(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apierror.ErrNotFound) {
+			return &model.TriageItem{}, nil
+		}
+		return nil, fmt.Errorf("failed to get next email to triage: %w", err)
+	}
+
+	remaining, err := s.emailRepo.CountUntriaged(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count emails to triage: %w", err)
+	}
+	suggestion, err := s.suggest(ctx, userID, email)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TriageItem{Email: email.WithoutBody(), Suggestion: suggestion, Remaining: remaining}, nil
+}
+
+func (s *triageService) Decide(ctx context.Context, userID, emailID, action string) (*model.TriageItem, error) {
+	if !slices.Contains(model.TriageActions, action) {
+		return nil, apierror.Validation("unsupported triage action: " + action)
+	}
+	email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if email.TriagedAt != nil {
+		return nil, apierror.Conflict("The email was already triaged", email.WithoutBody())
+	}
+
+	// The action runs first so a failure, such as a missing Gmail permission, leaves the email queued
+	if err := s.run(ctx, userID, email.ID, action); err != nil {
+		return nil, err
+	}
+	if err := s.emailRepo.MarkTriaged(ctx, email.ID, action, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to record triage decision: %w", err)
+	}
+
+	s.logger.Info("Triaged email:", email.ID, "with action:", action)
+	return s.Next(ctx, userID)
+}
+
+func (s *triageService) run(ctx context.Context, userID, emailID, action string) error {
+	emailIDs := []string{emailID}
+	switch action {
+	case "keep":
+		return nil
+	case "delete":
+		return s.emailService.DeleteEmails(ctx, emailIDs, userID)
+	case "spam":
+		return s.emailService.ReportSpam(ctx, emailIDs, userID, false)
+	default:
+		return s.emailService.PerformBulkAction(ctx, emailIDs, action, userID)
+	}
+}
+
+// suggest proposes an action from, in order: an immediate automation on the email's category,
+// the decisions the user made most on the sender's earlier emails, and Gmail's importance marker
+func (s *triageService) suggest(ctx context.Context, userID string, email *model.Email) (*model.TriageSuggestion, error) {
+	if email.CategoryID != "" {
+		automations, err := s.automationService.GetAutomations(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get automations: %w", err)
+		}
+		for _, automation := range automations {
+			if automation.Enabled && !automation.IsScheduled() && automation.CategoryID == email.CategoryID &&
+				slices.Contains(model.TriageActions, automation.Action) {
+				return &model.TriageSuggestion{Action: automation.Action, Reason: model.TriageReasonAutomation}, nil
+			}
+		}
+	}
+
+	if email.FromAddress != "" {
+		// Trashed emails are left out of listings, so deletions don't count towards the history
+		filter := model.EmailFilter{Sender: email.FromAddress, Archive: model.ArchiveFilterAll}
+		history, err := s.emailRepo.FindByFilter(ctx, userID, filter, senderHistoryLimit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sender history: %w", err)
+		}
+		if action := mostDecided(history); action != "" {
+			return &model.TriageSuggestion{Action: action, Reason: model.TriageReasonSender}, nil
+		}
+	}
+
+	if email.Important {
+		return &model.TriageSuggestion{Action: "star", Reason: model.TriageReasonImportant}, nil
+	}
+	return &model.TriageSuggestion{Action: "archive", Reason: model.TriageReasonDefault}, nil
+}
+
+// mostDecided returns the triage action taken most often on the emails, if at least two agree;
+// ties go to the most recently taken action
+func mostDecided(emails []*model.Email) string {
+	counts := make(map[string]int)
+	latest := make(map[string]time.Time)
+	for _, email := range emails {
+		if email.TriagedAt == nil {
+			continue
+		}
+		counts[email.TriageAction]++
+		if email.TriagedAt.After(latest[email.TriageAction]) {
+			latest[email.TriageAction] = *email.TriagedAt
+		}
+	}
+
+	var best string
+	for action, count := range counts {
+		if count > counts[best] || (count == counts[best] && latest[action].After(latest[best])) {
+			best = action
+		}
+	}
+	if counts[best] < 2 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ErrCaptchaRequired is returned for unsubscribe pages that put a CAPTCHA or bot challenge in the
+// way; the user is asked to finish those themselves
+var ErrCaptchaRequired = errors.New("unsubscribe page requires a CAPTCHA")
+
+// captchaScanBytes is how much of a form submission's response is looked through for a CAPTCHA
+const captchaScanBytes = 1 << 20
+
+// captchaSelectors match the widgets and scripts of the common CAPTCHA and bot challenge providers
+var captchaSelectors = []string{
+	// reCAPTCHA, hCaptcha and Cloudflare Turnstile widgets, which all carry a site key
+	".g-recaptcha", ".h-captcha", ".cf-turnstile", "[data-sitekey]",
+	"script[src*='recaptcha/api' i]", "script[src*='hcaptcha.com' i]", "script[src*='challenges.cloudflare.com' i]",
+	"iframe[src*='recaptcha' i]", "iframe[src*='hcaptcha' i]", "iframe[src*='challenges.cloudflare.com' i]",
+	// Arkose Labs (FunCaptcha)
+	"script[src*='arkoselabs' i]", "script[src*='funcaptcha' i]", "iframe[src*='arkoselabs' i]",
+	// Cloudflare's interstitial bot check
+	"#challenge-form", "#cf-challenge-running",
+	// Home-grown image CAPTCHAs
+	"input[name*='captcha' i]", "img[src*='captcha' i]",
+}
+
+// hasCaptcha reports whether the page asks to prove a human is there
+func hasCaptcha(doc *goquery.Document) bool {
+	for _, selector := range captchaSelectors {
+		if doc.Find(selector).Length() > 0 {
+			return true
+		}
+	}
+	title := strings.ToLower(strings.TrimSpace(doc.Find("title").First().Text()))
+	return title == "just a moment..." || strings.Contains(title, "captcha")
+}
+
+func (s *unsubscribeService) OnManualAction(hook UnsubscribeAttemptHook) {
+	s.manualActionHooks = append(s.manualActionHooks, hook)
+}
+
+func (s *unsubscribeService) CompleteManually(ctx context.Context, userID, attemptID string) (*model.UnsubscribeAttempt, error) {
+	if s.attempts == nil {
+		return nil, apierror.Unavailable("unsubscribe attempts are not recorded")
+	}
+	attempt, err := s.attempts.FindByIDAndUser(ctx, attemptID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !attempt.RequiresManualAction() {
+		return nil, apierror.Validation("the unsubscribe does not need to be finished manually")
+	}
+
+	// From here on the unsubscribe is watched like any other that succeeded
+	attempt.Succeeded = true
+	attempt.Error = ""
+	if err := s.attempts.Update(ctx, attempt); err != nil {
+		return nil, err
+	}
+	s.logger.Info("User", userID, "finished unsubscribing from", attempt.SenderAddress, "manually")
+	return attempt, nil
+}
@@ -2,9 +2,46 @@ package service
 
 import (
 	"context"
+	"time"
+
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
 )
 
 // UnsubscribeService interface for handling email unsubscriptions
 type UnsubscribeService interface {
 	UnsubscribeEmails(ctx context.Context, emailIDs []string, userID string) error
-}
\ No newline at end of file
+	// GetAttempts lists the user's unsubscribe attempts, newest first, with the dark patterns
+	// found and what was selected on each page
+	GetAttempts(ctx context.Context, userID string, limit int) ([]*model.UnsubscribeAttempt, error)
+	// CheckEffectiveness flags the latest unsubscribe from the new email's sender as ineffective
+	// when the email arrived after the grace period; registered as a ClassifiedHook
+	CheckEffectiveness(ctx context.Context, email *model.Email)
+	// Escalate blocks the sender of an ineffective unsubscribe, or reports their emails as spam too
+	Escalate(ctx context.Context, userID, attemptID, action string) (*model.UnsubscribeAttempt, error)
+	// OnIneffective adds a hook run when a sender first emails again after the grace period
+	OnIneffective(hook UnsubscribeAttemptHook)
+	// CompleteManually marks an unsubscribe left to the user, e.g. behind a CAPTCHA, as done by them
+	CompleteManually(ctx context.Context, userID, attemptID string) (*model.UnsubscribeAttempt, error)
+	// OnManualAction adds a hook run when an unsubscribe is left for the user to finish on the sender's page
+	OnManualAction(hook UnsubscribeAttemptHook)
+	// SetCrawlLimits sets the per-host politeness limits and the time budget of each batch
+	SetCrawlLimits(limits CrawlLimits)
+	// SetGracePeriod sets how long senders have to honor an unsubscribe
+	SetGracePeriod(grace time.Duration)
+	// UseQuotas meters unsubscribe attempts against the user's plan
+	UseQuotas(quotas Quotas)
+	// UseAttempts records each attempt for the user to review
+	UseAttempts(attempts repository.UnsubscribeAttemptRepository)
+	// UseSpamReports enables the spam escalation of ineffective unsubscribes
+	UseSpamReports(reporter SpamReporter)
+}
+
+// UnsubscribeAttemptHook is called with an unsubscribe that needs the user's attention: one the
+// sender just ignored, or one left for the user to finish
+type UnsubscribeAttemptHook func(ctx context.Context, attempt *model.UnsubscribeAttempt)
+
+// SpamReporter reports emails as spam, optionally blocking their senders; EmailService is one
+type SpamReporter interface {
+	ReportSpam(ctx context.Context, emailIDs []string, userID string, blockSender bool) error
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/model"
+)
+
+func (s *unsubscribeService) SetGracePeriod(grace time.Duration) {
+	if grace <= 0 {
+		grace = config.DefaultUnsubscribeGrace
+	}
+	s.grace = grace
+}
+
+func (s *unsubscribeService) UseSpamReports(reporter SpamReporter) {
+	s.spamReporter = reporter
+}
+
+func (s *unsubscribeService) OnIneffective(hook UnsubscribeAttemptHook) {
+	s.ineffectiveHooks = append(s.ineffectiveHooks, hook)
+}
+
+func (s *unsubscribeService) CheckEffectiveness(ctx context.Context, email *model.Email) {
+	if s.attempts == nil || email.FromAddress == "" {
+		return
+	}
+
+	s.monitorMutex.Lock()
+	defer s.monitorMutex.Unlock()
+
+	attempt, err := s.attempts.FindLatestSucceeded(ctx, email.UserID, email.FromAddress)
+	if errors.Is(err, apierror.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		s.logger.Warn("Failed to get unsubscribe attempts for sender", email.FromAddress, ":", err)
+		return
+	}
+
+	first := !attempt.IsIneffective()
+	if !attempt.Resurfaced(email, s.grace, time.Now()) {
+		return
+	}
+	if err := s.attempts.Update(ctx, attempt); err != nil {
+		s.logger.Error("Failed to flag unsubscribe from", email.FromAddress, "as ineffective:", err)
+		return
+	}
+	if first {
+		s.logger.Info("Unsubscribe from", email.FromAddress, "for user", email.UserID, "was ignored, email", email.ID)
+		for _, hook := range s.ineffectiveHooks {
+			hook(ctx, attempt)
+		}
+	}
+}
+
+func (s *unsubscribeService) Escalate(ctx context.Context, userID, attemptID, action string) (*model.UnsubscribeAttempt, error) {
+	if s.attempts == nil {
+		return nil, apierror.Unavailable("unsubscribe attempts are not recorded")
+	}
+	attempt, err := s.attempts.FindByIDAndUser(ctx, attemptID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !attempt.IsIneffective() {
+		return nil, apierror.Validation("the sender has not emailed since the unsubscribe")
+	}
+
+	switch action {
+	case model.EscalationBlock:
+	case model.EscalationSpam:
+		if s.spamReporter == nil {
+			return nil, apierror.Unavailable("spam reports are not available")
+		}
+		if err := s.spamReporter.ReportSpam(ctx, attempt.ResurfacedEmailIDs, userID, false); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, apierror.Validation("unknown escalation: " + action)
+	}
+
+	// Both escalations keep the sender's later emails out, even once theirs are all trashed
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.BlockSender(attempt.SenderAddress) {
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	attempt.Escalation = action
+	if err := s.attempts.Update(ctx, attempt); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Escalated ineffective unsubscribe from", attempt.SenderAddress, "for user", userID, "to", action)
+	return attempt, nil
+}
@@ -3,47 +3,93 @@ package service
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"jump-challenge/internal/config"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/prompt"
 	"jump-challenge/internal/repository"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
 type unsubscribeService struct {
-	emailRepo    repository.EmailRepository
-	userRepo     repository.UserRepository
-	gmailClient  GmailClient
-	aiClient     AIClient
-	logger       *logger.Logger
-	httpClient   *http.Client
+	emailRepo   repository.EmailRepository
+	userRepo    repository.UserRepository
+	gmailClient MailClient
+	aiClient    AIClient
+	logger      *logger.Logger
+	transport   *politeTransport // shared by the session clients of every unsubscribe
+	budget      time.Duration    // for one UnsubscribeEmails call, 0 for none
+	quotas      Quotas                                  // optional
+	attempts    repository.UnsubscribeAttemptRepository // optional
+
+	manualActionHooks []UnsubscribeAttemptHook
+
+	// Effectiveness monitoring of successful unsubscribes
+	grace            time.Duration
+	spamReporter     SpamReporter // optional, enables the spam escalation
+	ineffectiveHooks []UnsubscribeAttemptHook
+	monitorMutex     sync.Mutex // serializes updates to an attempt from concurrently synced emails
 }
 
 func NewUnsubscribeService(
 	emailRepo repository.EmailRepository,
 	userRepo repository.UserRepository,
-	gmailClient GmailClient,
+	gmailClient MailClient,
 	aiClient AIClient,
 	logger *logger.Logger,
 ) UnsubscribeService {
-	return &unsubscribeService{
+	service := &unsubscribeService{
 		emailRepo:   emailRepo,
 		userRepo:    userRepo,
 		gmailClient: gmailClient,
 		aiClient:    aiClient,
 		logger:      logger,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		grace: config.DefaultUnsubscribeGrace,
 	}
+	service.SetCrawlLimits(DefaultCrawlLimits())
+	return service
+}
+
+// SetCrawlLimits replaces the politeness limits, starting afresh with every host
+func (s *unsubscribeService) SetCrawlLimits(limits CrawlLimits) {
+	s.transport = newPoliteTransport(http.DefaultTransport, limits)
+	s.budget = limits.Budget
+}
+
+// errUnsubscribeBudgetExhausted is recorded for the emails left once a batch ran out of time
+var errUnsubscribeBudgetExhausted = errors.New("unsubscribe time budget exhausted")
+
+func (s *unsubscribeService) UseQuotas(quotas Quotas) {
+	s.quotas = quotas
+}
+
+func (s *unsubscribeService) UseAttempts(attempts repository.UnsubscribeAttemptRepository) {
+	s.attempts = attempts
+}
+
+func (s *unsubscribeService) GetAttempts(ctx context.Context, userID string, limit int) ([]*model.UnsubscribeAttempt, error) {
+	if s.attempts == nil {
+		return []*model.UnsubscribeAttempt{}, nil
+	}
+	attempts, err := s.attempts.FindByUserID(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if attempts == nil {
+		attempts = []*model.UnsubscribeAttempt{}
+	}
+	return attempts, nil
 }
 
 func (s *unsubscribeService) UnsubscribeEmails(ctx context.Context, emailIDs []string, userID string) error {
@@ -51,16 +97,10 @@ func (s *unsubscribeService) UnsubscribeEmails(ctx context.Context, emailIDs []s
 	var emailsToUnsubscribe []*model.Email
 
 	for _, emailID := range emailIDs {
-		// Get the email from database
-		email, err := s.emailRepo.FindByID(ctx, emailID)
+		// Get the email from database, only if it belongs to the user
+		email, err := s.emailRepo.FindByIDAndUser(ctx, emailID, userID)
 		if err != nil {
-			s.logger.Error("Failed to find email for unsubscribe:", emailID, err)
-			continue
-		}
-
-		// Verify that the email belongs to the user
-		if email.UserID != userID {
-			s.logger.Warn("User", userID, "attempted to unsubscribe from email", emailID, "that doesn't belong to them")
+			s.logger.Warn("User", userID, "attempted to unsubscribe from missing or foreign email", emailID, err)
 			continue
 		}
 
@@ -72,22 +112,77 @@ func (s *unsubscribeService) UnsubscribeEmails(ctx context.Context, emailIDs []s
 		return nil
 	}
 
+	// Each email is one unsubscribe attempt; the plan may only allow some of them
+	if s.quotas != nil {
+		granted, err := s.quotas.Reserve(ctx, userID, model.MetricUnsubscribes, len(emailsToUnsubscribe))
+		if err != nil {
+			return err
+		}
+		if granted < len(emailsToUnsubscribe) {
+			s.logger.Warn("Unsubscribe quota reached for user", userID, ", skipping", len(emailsToUnsubscribe)-granted, "emails")
+		}
+		emailsToUnsubscribe = emailsToUnsubscribe[:granted]
+	}
+
+	// The whole batch shares one time budget, so a slow host can't hold up a bulk job for long,
+	// while the outcomes are still recorded once it runs out
+	crawlCtx := ctx
+	if s.budget > 0 {
+		var cancel context.CancelFunc
+		crawlCtx, cancel = context.WithTimeout(ctx, s.budget)
+		defer cancel()
+	}
+
 	// Process each email for unsubscribe
 	for _, email := range emailsToUnsubscribe {
-		if err := s.processEmailUnsubscribe(ctx, email); err != nil {
+		attempt := model.NewUnsubscribeAttempt(email)
+		err := errUnsubscribeBudgetExhausted
+		if crawlCtx.Err() == nil {
+			err = s.processEmailUnsubscribe(crawlCtx, s.newSessionClient(), email, attempt)
+		}
+		s.recordAttempt(ctx, attempt, err)
+		if err != nil {
 			s.logger.Error("Failed to unsubscribe from email:", email.ID, err)
 			// Continue with other emails even if one fails
+			continue
+		}
+		// Recorded for the sender's engagement stats
+		if err := s.emailRepo.MarkUnsubscribed(ctx, email.ID, time.Now()); err != nil {
+			s.logger.Error("Failed to record unsubscribe from email:", email.ID, err)
 		}
 	}
 
 	return nil
 }
 
-func (s *unsubscribeService) processEmailUnsubscribe(ctx context.Context, email *model.Email) error {
+// recordAttempt saves what was done on the sender's page, with any dark patterns found there
+func (s *unsubscribeService) recordAttempt(ctx context.Context, attempt *model.UnsubscribeAttempt, err error) {
+	if len(attempt.DarkPatterns) > 0 {
+		s.logger.Info("Dark patterns on unsubscribe page", attempt.URL, ":", strings.Join(attempt.DarkPatterns, ", "))
+	}
+	if s.attempts == nil {
+		return
+	}
+	attempt.Succeeded = err == nil
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	if err := s.attempts.Create(ctx, attempt); err != nil {
+		s.logger.Error("Failed to record unsubscribe attempt for email:", attempt.EmailID, err)
+		return
+	}
+	if attempt.RequiresManualAction() {
+		for _, hook := range s.manualActionHooks {
+			hook(ctx, attempt)
+		}
+	}
+}
+
+func (s *unsubscribeService) processEmailUnsubscribe(ctx context.Context, client *http.Client, email *model.Email, attempt *model.UnsubscribeAttempt) error {
 	s.logger.Info("Processing unsubscribe for email:", email.ID)
 
 	// Look for unsubscribe links in the email body
-	unsubscribeURLs, err := s.findUnsubscribeLinks(email)
+	unsubscribeURLs, err := findUnsubscribeLinks(email)
 	if err != nil {
 		return fmt.Errorf("failed to find unsubscribe links: %w", err)
 	}
@@ -100,20 +195,36 @@ func (s *unsubscribeService) processEmailUnsubscribe(ctx context.Context, email
 	// Try each unsubscribe URL until one succeeds
 	for _, unsubscribeURL := range unsubscribeURLs {
 		s.logger.Info("Attempting to unsubscribe using URL:", unsubscribeURL)
+		attempt.StartPage(unsubscribeURL)
 		
-		if err := s.handleUnsubscribeURL(ctx, unsubscribeURL); err != nil {
+		if err := s.handleUnsubscribeURL(ctx, client, unsubscribeURL, attempt); err != nil {
 			s.logger.Error("Failed to unsubscribe using URL:", unsubscribeURL, err)
+			// The first page behind a CAPTCHA is left to the user, unless another one works
+			if errors.Is(err, ErrCaptchaRequired) && attempt.ManualActionURL == "" {
+				attempt.ManualActionURL = unsubscribeURL
+			}
 			continue // Try the next URL
 		}
 
 		s.logger.Info("Successfully unsubscribed using URL:", unsubscribeURL)
+		attempt.ManualActionURL = ""
 		return nil
 	}
 
+	if attempt.ManualActionURL != "" {
+		attempt.URL = attempt.ManualActionURL
+		return ErrCaptchaRequired
+	}
 	return fmt.Errorf("failed to unsubscribe using any of the found URLs")
 }
 
-func (s *unsubscribeService) findUnsubscribeLinks(email *model.Email) ([]string, error) {
+// HasUnsubscribeLink reports whether the email body offers a way to unsubscribe
+func HasUnsubscribeLink(email *model.Email) bool {
+	links, err := findUnsubscribeLinks(email)
+	return err == nil && len(links) > 0
+}
+
+func findUnsubscribeLinks(email *model.Email) ([]string, error) {
 	var urls []string
 
 	// Look for common unsubscribe patterns in the email body
@@ -201,22 +312,29 @@ func isValidURL(input string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
-func (s *unsubscribeService) handleUnsubscribeURL(ctx context.Context, unsubURL string) error {
+// getPage loads a page within the context, so the batch's time budget applies
+func (s *unsubscribeService) getPage(ctx context.Context, client *http.Client, pageURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+func (s *unsubscribeService) handleUnsubscribeURL(ctx context.Context, client *http.Client, unsubURL string, attempt *model.UnsubscribeAttempt) error {
 	// First, get the page content
-	resp, err := s.httpClient.Get(unsubURL)
+	resp, err := s.getPage(ctx, client, unsubURL)
 	if err != nil {
 		return fmt.Errorf("failed to get unsubscribe page: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("unsubscribe page returned status code: %d", resp.StatusCode)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("failed to read unsubscribe page: %w", err)
 	}
+	// The host's request slot is freed before the page's next step is requested
+	resp.Body.Close()
 
 	// Parse the HTML
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
@@ -224,10 +342,18 @@ func (s *unsubscribeService) handleUnsubscribeURL(ctx context.Context, unsubURL
 		return fmt.Errorf("failed to parse unsubscribe page: %w", err)
 	}
 
+	// Bot challenges often come with an error status, so they are looked for first
+	if hasCaptcha(doc) {
+		return ErrCaptchaRequired
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unsubscribe page returned status code: %d", resp.StatusCode)
+	}
+
 	// Check if there's a form on the page that needs to be filled
 	form := doc.Find("form").First()
 	if form.Length() > 0 {
-		return s.handleUnsubscribeForm(ctx, form, resp.Request.URL, string(body))
+		return s.handleUnsubscribeForm(ctx, client, form, resp.Request.URL, string(body), attempt)
 	}
 
 	// Check if there's an unsubscribe button or link
@@ -251,14 +377,14 @@ func (s *unsubscribeService) handleUnsubscribeURL(ctx context.Context, unsubURL
 				href, exists := element.Attr("href")
 				if exists {
 					absoluteURL := resolveURL(resp.Request.URL, href)
-					return s.handleUnsubscribeLink(ctx, absoluteURL.String())
+					return s.handleUnsubscribeLink(ctx, client, absoluteURL.String(), attempt)
 				}
 			} else if element.Is("input") || element.Is("button") {
 				// If it's a button, try to click it by simulating form submission
 				// Find the closest form and submit it
 				form = element.Closest("form")
 				if form.Length() > 0 {
-					return s.handleUnsubscribeForm(ctx, form, resp.Request.URL, string(body))
+					return s.handleUnsubscribeForm(ctx, client, form, resp.Request.URL, string(body), attempt)
 				}
 			}
 		}
@@ -266,10 +392,10 @@ func (s *unsubscribeService) handleUnsubscribeURL(ctx context.Context, unsubURL
 
 	// If no specific action found but it's a simple unsubscribe page,
 	// we might need AI to analyze the page for the best action
-	return s.handleUnsubscribeWithAI(ctx, string(body), resp.Request.URL.String())
+	return s.handleUnsubscribeWithAI(ctx, client, string(body), resp.Request.URL.String(), attempt)
 }
 
-func (s *unsubscribeService) handleUnsubscribeForm(ctx context.Context, form *goquery.Selection, baseURL *url.URL, pageContent string) error {
+func (s *unsubscribeService) handleUnsubscribeForm(ctx context.Context, client *http.Client, form *goquery.Selection, baseURL *url.URL, pageContent string, attempt *model.UnsubscribeAttempt) error {
 	// Extract form attributes
 	action, _ := form.Attr("action")
 	method, exists := form.Attr("method")
@@ -294,18 +420,12 @@ func (s *unsubscribeService) handleUnsubscribeForm(ctx context.Context, form *go
 		}
 
 		switch strings.ToLower(inputType) {
-		case "submit", "button":
-			// Skip submit buttons, we'll handle submission separately
+		case "submit", "button", "radio":
+			// Buttons and radio groups are chosen below, once all their options are known
 			return
-		case "checkbox", "radio":
-			// Check if it's checked or if we should check it
-			_, isChecked := input.Attr("checked")
-			if isChecked || strings.Contains(strings.ToLower(name), "confirm") || 
-				strings.Contains(strings.ToLower(name), "agree") {
-				value, valueExists := input.Attr("value")
-				if !valueExists {
-					value = "on"
-				}
+		case "checkbox":
+			// Opt-outs and confirmations are checked, boxes keeping the user subscribed are not
+			if name, value, checked := chooseCheckbox(input, attempt); checked {
 				formData.Add(name, value)
 			}
 		default:
@@ -320,6 +440,7 @@ func (s *unsubscribeService) handleUnsubscribeForm(ctx context.Context, form *go
 			}
 		}
 	})
+	chooseFormOptions(form, formData, attempt)
 
 	// Submit the form
 	var req *http.Request
@@ -353,14 +474,21 @@ func (s *unsubscribeService) handleUnsubscribeForm(ctx context.Context, form *go
 	req.Header.Set("Accept-Encoding", "gzip, deflate")
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
+	// Some flows only accept the confirmation coming from their own page
+	req.Header.Set("Referer", baseURL.String())
 
 	// Execute the request
-	resp, err := s.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to submit form: %w", err)
 	}
 	defer resp.Body.Close()
 
+	// Some senders only ask for a CAPTCHA once the form is submitted
+	if doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, captchaScanBytes)); err == nil && hasCaptcha(doc) {
+		return ErrCaptchaRequired
+	}
+
 	// Check if the request was successful
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		return nil
@@ -369,7 +497,8 @@ func (s *unsubscribeService) handleUnsubscribeForm(ctx context.Context, form *go
 	return fmt.Errorf("form submission returned status code: %d", resp.StatusCode)
 }
 
-func (s *unsubscribeService) handleUnsubscribeLink(ctx context.Context, linkURL string) error {
+func (s *unsubscribeService) handleUnsubscribeLink(ctx context.Context, client *http.Client, linkURL string, attempt *model.UnsubscribeAttempt) error {
+	attempt.Select("followed %s", linkURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", linkURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -380,7 +509,7 @@ func (s *unsubscribeService) handleUnsubscribeLink(ctx context.Context, linkURL
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to follow unsubscribe link: %w", err)
 	}
@@ -394,43 +523,66 @@ func (s *unsubscribeService) handleUnsubscribeLink(ctx context.Context, linkURL
 	return fmt.Errorf("unsubscribe link returned status code: %d", resp.StatusCode)
 }
 
-func (s *unsubscribeService) handleUnsubscribeWithAI(ctx context.Context, pageContent, pageURL string) error {
-	// Use AI to analyze the page and determine the best action to unsubscribe
-	prompt := fmt.Sprintf(`Analyze this unsubscribe page and provide the most likely way to unsubscribe.
+// aiSelector is the strict format of the CSS selectors the AI may answer with: tags, ids,
+// classes, attribute tests and child or descendant combinators, so an answer steered by the
+// page can't smuggle anything else into the action
+var aiSelector = regexp.MustCompile(`^(?:[A-Za-z][\w-]*|[#.][\w-]+|\[[\w-]+(?:[~|^$*]?=(?:"[^"\\\]\[]*"|'[^'\\\]\[]*'|[\w-]+))?\]|\s*>\s*|\s+)`)
+
+const maxAISelectorLength = 200
+
+// validAISelector reports whether the selector is made only of the parts aiSelector allows
+func validAISelector(selector string) bool {
+	if selector == "" || len(selector) > maxAISelectorLength {
+		return false
+	}
+	rest := selector
+	for rest != "" {
+		loc := aiSelector.FindStringIndex(rest)
+		if loc == nil || loc[1] == 0 {
+			return false
+		}
+		rest = rest[loc[1]:]
+	}
+	return true
+}
+
+func (s *unsubscribeService) handleUnsubscribeWithAI(ctx context.Context, client *http.Client, pageContent, pageURL string, attempt *model.UnsubscribeAttempt) error {
+	// Use AI to analyze the page and determine the best action to unsubscribe; the page is
+	// handed over as untrusted content, never as part of the instructions
+	task := fmt.Sprintf(`Analyze this unsubscribe page and provide the most likely way to unsubscribe.
 
 Page URL: %s
 
-Page Content:
-%s
+Please respond with only the action to take in the format "CLICK:selector" or "FORM:submit_button_selector" where selector is a CSS selector that would identify the unsubscribe element, made only of tag names, #ids, .classes, [attribute="value"] tests and > or space combinators. If the page already confirms unsubscription, respond with "CONFIRMED".
 
-Please respond with only the action to take in the format "CLICK:selector" or "FORM:submit_button_selector" where selector is a CSS selector that would identify the unsubscribe element. If the page already confirms unsubscription, respond with "CONFIRMED".`, pageURL, pageContent)
+The action must opt out of all emails: never choose to pause emails, receive fewer emails or stay subscribed to some lists. If the page uses dark patterns, add one line per pattern after the action in the format "DARK_PATTERN:name", where name is "prechecked_opt_in" for boxes checked by default that keep the user subscribed, "pause_instead" for offering to pause or send fewer emails in place of unsubscribing, or "confirmshaming" for wording that guilts the user into staying.`, prompt.Sanitize(pageURL))
 
-	// We'll use the AI client to analyze the page - using SummarizeEmail for general text processing
-	// since we don't need category-based classification here
-	action, err := s.aiClient.SummarizeEmail(ctx, prompt)
+	action, err := s.aiClient.Analyze(ctx, task, pageContent)
 	if err != nil {
 		return fmt.Errorf("failed to analyze page with AI: %w", err)
 	}
 
-	// Process the AI's action recommendation
-	action = strings.TrimSpace(action)
-	if strings.HasPrefix(action, "CLICK:") {
-		selector := strings.TrimPrefix(action, "CLICK:")
-		selector = strings.TrimSpace(selector)
-		return s.performClickAction(ctx, pageURL, selector)
-	} else if strings.HasPrefix(action, "FORM:") {
-		selector := strings.TrimPrefix(action, "FORM:")
+	// Process the AI's action recommendation, reported dark patterns follow it
+	action = parseDarkPatterns(action, attempt)
+	if strings.HasPrefix(action, "CLICK:") || strings.HasPrefix(action, "FORM:") {
+		kind, selector, _ := strings.Cut(action, ":")
 		selector = strings.TrimSpace(selector)
-		return s.performFormAction(ctx, pageURL, selector)
+		if !validAISelector(selector) {
+			return fmt.Errorf("AI returned an invalid selector: %q", selector)
+		}
+		if kind == "CLICK" {
+			return s.performClickAction(ctx, client, pageURL, selector, attempt)
+		}
+		return s.performFormAction(ctx, client, pageURL, selector, attempt)
 	} else if action == "CONFIRMED" {
 		// Already unsubscribed
 		return nil
 	}
 
-	return fmt.Errorf("AI returned unrecognized action: %s", action)
+	return fmt.Errorf("AI returned unrecognized action: %q", action)
 }
 
-func (s *unsubscribeService) performClickAction(ctx context.Context, pageURL, selector string) error {
+func (s *unsubscribeService) performClickAction(ctx context.Context, client *http.Client, pageURL, selector string, attempt *model.UnsubscribeAttempt) error {
 	// For now, this is a simplified implementation
 	// In a real-world scenario, we'd need a more sophisticated approach
 	// such as using a headless browser (e.g., Chrome DevTools Protocol)
@@ -439,7 +591,7 @@ func (s *unsubscribeService) performClickAction(ctx context.Context, pageURL, se
 	// But for a complete solution, we'd need to implement browser automation
 	
 	// For now, let's try to get the page again and look for specific elements
-	resp, err := s.httpClient.Get(pageURL)
+	resp, err := s.getPage(ctx, client, pageURL)
 	if err != nil {
 		return fmt.Errorf("failed to get page for click action: %w", err)
 	}
@@ -453,6 +605,7 @@ func (s *unsubscribeService) performClickAction(ctx context.Context, pageURL, se
 	if err != nil {
 		return fmt.Errorf("failed to read page for click action: %w", err)
 	}
+	resp.Body.Close()
 
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
@@ -470,23 +623,23 @@ func (s *unsubscribeService) performClickAction(ctx context.Context, pageURL, se
 		href, exists := element.Attr("href")
 		if exists {
 			absoluteURL := resolveURL(resp.Request.URL, href)
-			return s.handleUnsubscribeLink(ctx, absoluteURL.String())
+			return s.handleUnsubscribeLink(ctx, client, absoluteURL.String(), attempt)
 		}
 	}
 
 	// If it's a button, find its form and submit it
 	form := element.Closest("form")
 	if form.Length() > 0 {
-		return s.handleUnsubscribeForm(ctx, form, resp.Request.URL, string(body))
+		return s.handleUnsubscribeForm(ctx, client, form, resp.Request.URL, string(body), attempt)
 	}
 
 	// If no specific action found, return error
 	return fmt.Errorf("unable to determine action for element: %s", selector)
 }
 
-func (s *unsubscribeService) performFormAction(ctx context.Context, pageURL, selector string) error {
+func (s *unsubscribeService) performFormAction(ctx context.Context, client *http.Client, pageURL, selector string, attempt *model.UnsubscribeAttempt) error {
 	// Get the page
-	resp, err := s.httpClient.Get(pageURL)
+	resp, err := s.getPage(ctx, client, pageURL)
 	if err != nil {
 		return fmt.Errorf("failed to get page for form action: %w", err)
 	}
@@ -500,6 +653,7 @@ func (s *unsubscribeService) performFormAction(ctx context.Context, pageURL, sel
 	if err != nil {
 		return fmt.Errorf("failed to read page for form action: %w", err)
 	}
+	resp.Body.Close()
 
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
@@ -521,7 +675,7 @@ func (s *unsubscribeService) performFormAction(ctx context.Context, pageURL, sel
 		return fmt.Errorf("form not found with selector: %s", selector)
 	}
 
-	return s.handleUnsubscribeForm(ctx, form, resp.Request.URL, string(body))
+	return s.handleUnsubscribeForm(ctx, client, form, resp.Request.URL, string(body), attempt)
 }
 
 func (s *unsubscribeService) inferFieldValue(fieldName string) string {
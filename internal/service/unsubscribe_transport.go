@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/config"
+
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/time/rate"
+)
+
+// unsubscribeUserAgent identifies unsubscribe requests, and is the name robots.txt rules are matched against
+const unsubscribeUserAgent = "jump-challenge-unsubscribe"
+
+// robotsTTL is how long a host's robots.txt is trusted before being fetched again
+const robotsTTL = time.Hour
+
+// maxUnsubscribeRedirects bounds the redirects followed for one request, cutting off redirect loops
+const maxUnsubscribeRedirects = 10
+
+// ErrDisallowedByRobots is returned for unsubscribe pages the host's robots.txt excludes
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// CrawlLimits keeps unsubscribing polite to senders' servers, as bulk unsubscribes often
+// land on the few hosts of the big email service providers
+type CrawlLimits struct {
+	HostConcurrency int           // requests in flight per host
+	HostInterval    time.Duration // minimum time between requests to a host
+	Budget          time.Duration // time one UnsubscribeEmails call may take, across its emails
+}
+
+// DefaultCrawlLimits returns the limits used unless SetCrawlLimits is called
+func DefaultCrawlLimits() CrawlLimits {
+	return CrawlLimits{
+		HostConcurrency: config.DefaultUnsubscribeHostConcurrency,
+		HostInterval:    config.DefaultUnsubscribeHostInterval,
+		Budget:          config.DefaultUnsubscribeBudget,
+	}
+}
+
+// newSessionClient returns a client for one email's unsubscribe flow. Its cookie jar keeps the
+// session a landing page sets for the confirmation that follows, and shares cookies across the
+// sender's subdomains, but not with other senders' flows.
+func (s *unsubscribeService) newSessionClient() *http.Client {
+	// The jar only fails on invalid options
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &http.Client{
+		Timeout:       30 * time.Second,
+		Transport:     s.transport,
+		Jar:           jar,
+		CheckRedirect: checkUnsubscribeRedirect,
+	}
+}
+
+// checkUnsubscribeRedirect follows redirects to web pages only, up to maxUnsubscribeRedirects
+func checkUnsubscribeRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxUnsubscribeRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxUnsubscribeRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	return nil
+}
+
+// hostLimit throttles the requests to one host
+type hostLimit struct {
+	slots   chan struct{}
+	limiter *rate.Limiter
+}
+
+// politeTransport rate limits and caps concurrent requests per host, and skips the paths the
+// host's robots.txt disallows. The limits are shared by every unsubscribe, whichever user or
+// job it runs for.
+type politeTransport struct {
+	base   http.RoundTripper
+	limits CrawlLimits
+
+	mutex  sync.Mutex
+	hosts  map[string]*hostLimit
+	robots map[string]*robotsRules // by scheme and host
+}
+
+func newPoliteTransport(base http.RoundTripper, limits CrawlLimits) *politeTransport {
+	if limits.HostConcurrency <= 0 {
+		limits.HostConcurrency = config.DefaultUnsubscribeHostConcurrency
+	}
+	return &politeTransport{
+		base:   base,
+		limits: limits,
+		hosts:  make(map[string]*hostLimit),
+		robots: make(map[string]*robotsRules),
+	}
+}
+
+func (t *politeTransport) host(host string) *hostLimit {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	limit, exists := t.hosts[host]
+	if !exists {
+		every := rate.Inf
+		if t.limits.HostInterval > 0 {
+			every = rate.Every(t.limits.HostInterval)
+		}
+		limit = &hostLimit{
+			slots:   make(chan struct{}, t.limits.HostConcurrency),
+			limiter: rate.NewLimiter(every, 1),
+		}
+		t.hosts[host] = limit
+	}
+	return limit
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := strings.ToLower(req.URL.Host)
+	limit := t.host(host)
+
+	rules, err := t.robotsFor(ctx, req.URL.Scheme, host, limit)
+	if err != nil {
+		return nil, err
+	}
+	if !rules.allows(req.URL.RequestURI()) {
+		return nil, fmt.Errorf("%s: %w", req.URL.Redacted(), ErrDisallowedByRobots)
+	}
+
+	// The slot is held until the caller is done reading the response
+	select {
+	case limit.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := limit.limiter.Wait(ctx); err != nil {
+		<-limit.slots
+		return nil, err
+	}
+
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(ctx)
+		req.Header.Set("User-Agent", unsubscribeUserAgent)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-limit.slots
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { <-limit.slots }}
+	return resp, nil
+}
+
+// robotsFor returns the host's robots.txt rules, fetching them when missing or stale. A
+// robots.txt that can't be fetched or read allows everything.
+func (t *politeTransport) robotsFor(ctx context.Context, scheme, host string, limit *hostLimit) (*robotsRules, error) {
+	key := scheme + "://" + host
+	t.mutex.Lock()
+	rules, cached := t.robots[key]
+	t.mutex.Unlock()
+	if cached && time.Since(rules.fetchedAt) < robotsTTL {
+		return rules, nil
+	}
+
+	if err := limit.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	rules = &robotsRules{fetchedAt: time.Now()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, key+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", unsubscribeUserAgent)
+	if resp, err := t.base.RoundTrip(req); err == nil {
+		if resp.StatusCode == http.StatusOK {
+			rules = parseRobots(io.LimitReader(resp.Body, 512*1024), unsubscribeUserAgent)
+		}
+		resp.Body.Close()
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	t.mutex.Lock()
+	t.robots[key] = rules
+	t.mutex.Unlock()
+	return rules, nil
+}
+
+// releasingBody frees the host's request slot once the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// robotsRule is an Allow or Disallow line of robots.txt
+type robotsRule struct {
+	pattern *regexp.Regexp
+	length  int // of the path pattern; the longest matching rule wins
+	allow   bool
+}
+
+type robotsRules struct {
+	rules     []robotsRule
+	fetchedAt time.Time
+}
+
+// allows reports whether the path, with its query, may be requested
+func (r *robotsRules) allows(path string) bool {
+	var best *robotsRule
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		// On a tie, Allow wins
+		if best == nil || rule.length > best.length || (rule.length == best.length && rule.allow) {
+			best = rule
+		}
+	}
+	return best == nil || best.allow
+}
+
+// parseRobots reads the rules of the groups naming the lower-case agent, or of the * group when none does
+func parseRobots(reader io.Reader, agent string) *robotsRules {
+	var named, wildcard []robotsRule
+	var groupAgents []string
+	inAgents, namedFound := false, false
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		if field == "user-agent" {
+			// Consecutive user-agent lines share the rules that follow
+			if !inAgents {
+				groupAgents = nil
+			}
+			value = strings.ToLower(value)
+			groupAgents = append(groupAgents, value)
+			namedFound = namedFound || (value != "*" && value != "" && strings.Contains(agent, value))
+			inAgents = true
+			continue
+		}
+		inAgents = false
+		if (field != "allow" && field != "disallow") || len(groupAgents) == 0 {
+			continue
+		}
+		if value == "" {
+			// An empty Disallow allows everything, which is also what no rule means
+			continue
+		}
+		rule := robotsRule{pattern: robotsPattern(value), length: len(value), allow: field == "allow"}
+		for _, groupAgent := range groupAgents {
+			if groupAgent == "*" {
+				wildcard = append(wildcard, rule)
+			} else if groupAgent != "" && strings.Contains(agent, groupAgent) {
+				named = append(named, rule)
+			}
+		}
+	}
+
+	rules := &robotsRules{rules: wildcard, fetchedAt: time.Now()}
+	if namedFound {
+		rules.rules = named
+	}
+	return rules
+}
+
+// robotsPattern turns a robots.txt path, where * matches anything and a trailing $ anchors
+// the end, into a regexp
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(path), `\*`, ".*")
+	if anchored {
+		expression += "$"
+	}
+	return regexp.MustCompile(expression)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+// errVIPSendersDisabled is returned by the VIP methods when no repository was set
+var errVIPSendersDisabled = errors.New("VIP senders are not configured")
+
+func (s *emailService) UseVIPSenders(vipSenders repository.VIPSenderRepository) {
+	s.vipSenders = vipSenders
+}
+
+func (s *emailService) GetVIPSenders(ctx context.Context, userID string) ([]*model.VIPSender, error) {
+	if s.vipSenders == nil {
+		return nil, errVIPSendersDisabled
+	}
+	vips, err := s.vipSenders.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if vips == nil {
+		vips = []*model.VIPSender{}
+	}
+	return vips, nil
+}
+
+// AddVIPSender marks the sender as VIP, making the emails already stored from them urgent too
+func (s *emailService) AddVIPSender(ctx context.Context, userID, address, name string) (*model.VIPSender, error) {
+	if s.vipSenders == nil {
+		return nil, errVIPSendersDisabled
+	}
+	address = strings.ToLower(strings.TrimSpace(address))
+	if !strings.Contains(address, "@") {
+		return nil, apierror.Validation("invalid sender address")
+	}
+	vip := &model.VIPSender{
+		UserID:    userID,
+		Address:   address,
+		Name:      strings.TrimSpace(name),
+		CreatedAt: time.Now(),
+	}
+	if err := s.vipSenders.Save(ctx, vip); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.emailRepo.SetPriorityBySender(ctx, userID, vip.Address, model.EmailPriorityUrgent)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Marked", vip.Address, "as VIP for user", userID, "-", changed, "emails now urgent")
+	return vip, nil
+}
+
+// RemoveVIPSender drops the sender from the VIPs and their emails back to normal priority
+func (s *emailService) RemoveVIPSender(ctx context.Context, userID, address string) error {
+	if s.vipSenders == nil {
+		return errVIPSendersDisabled
+	}
+	address = strings.ToLower(strings.TrimSpace(address))
+	if err := s.vipSenders.Delete(ctx, userID, address); err != nil {
+		return err
+	}
+	_, err := s.emailRepo.SetPriorityBySender(ctx, userID, address, "")
+	return err
+}
+
+func (s *emailService) GetVIPEmails(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	return s.emailRepo.FindByPriority(ctx, userID, model.EmailPriorityUrgent, limit)
+}
+
+// vipAddresses returns the user's VIP sender addresses; a failure only costs the urgent marking
+func (s *emailService) vipAddresses(ctx context.Context, userID string) map[string]bool {
+	addresses := make(map[string]bool)
+	if s.vipSenders == nil {
+		return addresses
+	}
+	vips, err := s.vipSenders.FindByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to get VIP senders for user", userID, ":", err)
+	}
+	for _, vip := range vips {
+		addresses[vip.Address] = true
+	}
+	return addresses
+}
+
+// newEmailEvent is the event a new email is notified as; VIP emails are high priority, so they
+// get through quiet hours
+func newEmailEvent(email *model.Email) string {
+	if email.IsUrgent() {
+		return model.EventVIPEmail
+	}
+	return model.EventNewEmail
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	// vipEmailVersion is the version of the vip_email event's data, as the SSE stream sends it
+	vipEmailVersion = 1
+)
+
+// WebhookSignatureHeader signs every webhook request as t=<unix>,v1=<hex hmac>, the HMAC-SHA256
+// of "<unix>.<body>" keyed with the webhook's secret
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// webhookEvent is the body of a webhook request, shaped like the SSE envelope of the same event
+type webhookEvent struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Data    any    `json:"data"`
+	Time    int64  `json:"time"`
+}
+
+type webhookService struct {
+	webhookRepo         repository.WebhookRepository
+	httpClient          *http.Client
+	notificationService NotificationService
+	logger              *logger.Logger
+}
+
+// NewWebhookService creates the service; a nil client defaults to one that refuses to connect
+// to loopback and private addresses, so a webhook can't reach internal services
+func NewWebhookService(webhookRepo repository.WebhookRepository, httpClient *http.Client, notificationService NotificationService, logger *logger.Logger) WebhookService {
+	if httpClient == nil {
+		httpClient = newPublicHTTPClient(webhookTimeout)
+	}
+	return &webhookService{
+		webhookRepo:         webhookRepo,
+		httpClient:          httpClient,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, userID string) (*model.Webhook, error) {
+	return s.webhookRepo.FindByUserID(ctx, userID)
+}
+
+func (s *webhookService) SetWebhook(ctx context.Context, userID, rawURL string) (*model.Webhook, error) {
+	// Emails are posted to it, so only accept endpoints reachable over TLS
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Scheme != "https" || target.Host == "" {
+		return nil, apierror.InvalidFields([]apierror.FieldError{{Field: "url", Message: "must be an https URL"}})
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	webhook := model.NewWebhook(userID, target.String(), hex.EncodeToString(secret))
+	if err := s.webhookRepo.Save(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to save webhook: %w", err)
+	}
+
+	s.logger.Info("Set webhook for user:", userID)
+	return webhook, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, userID string) error {
+	if err := s.webhookRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	s.logger.Info("Removed webhook for user:", userID)
+	return nil
+}
+
+func (s *webhookService) HasWebhook(ctx context.Context, userID string) bool {
+	_, err := s.webhookRepo.FindByUserID(ctx, userID)
+	return err == nil
+}
+
+func (s *webhookService) NotifyNewEmail(ctx context.Context, email *model.Email) {
+	// Only emails from VIP senders are posted, as the vip_email event
+	if !email.IsUrgent() {
+		return
+	}
+
+	webhook, err := s.webhookRepo.FindByUserID(ctx, email.UserID)
+	if err != nil {
+		if !errors.Is(err, apierror.ErrNotFound) {
+			s.logger.Error("Failed to get webhook for user", email.UserID, ":", err)
+		}
+		return
+	}
+	if !s.notificationService.ShouldNotify(ctx, email.UserID, model.EventVIPEmail) {
+		return
+	}
+
+	payload, err := json.Marshal(webhookEvent{
+		Type:    model.EventVIPEmail,
+		Version: vipEmailVersion,
+		Data:    email.WithoutBody(),
+		Time:    time.Now().Unix(),
+	})
+	if err != nil {
+		s.logger.Error("Failed to encode webhook event:", err)
+		return
+	}
+	if err := s.post(ctx, webhook, payload); err != nil {
+		s.logger.Error("Failed to post webhook for user", email.UserID, ":", err)
+	}
+}
+
+// post sends the payload to the webhook, signed with its secret
+func (s *webhookService) post(ctx context.Context, webhook *model.Webhook, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, "t="+timestamp+",v1="+webhookSignature(webhook.Secret, timestamp, payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookSignature is the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed with secret
+func webhookSignature(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+)
+
+var errWorkspaceDisabled = apierror.Unavailable("Workspace delegation is not configured")
+
+type workspaceService struct {
+	userRepo    repository.UserRepository
+	gmailClient MailClient
+	domain      string // empty without delegation
+	clientID    string
+	logger      *logger.Logger
+}
+
+// NewWorkspaceService manages the mailboxes of domain, which the service account with clientID
+// calls Gmail for; an empty domain disables it
+func NewWorkspaceService(userRepo repository.UserRepository, gmailClient MailClient, domain, clientID string, logger *logger.Logger) WorkspaceService {
+	return &workspaceService{
+		userRepo:    userRepo,
+		gmailClient: gmailClient,
+		domain:      strings.ToLower(domain),
+		clientID:    clientID,
+		logger:      logger,
+	}
+}
+
+func (s *workspaceService) Connection(ctx context.Context) (*model.WorkspaceConnection, error) {
+	if s.domain == "" {
+		return nil, errWorkspaceDisabled
+	}
+
+	users, err := s.userRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mailboxes := []model.WorkspaceMailbox{}
+	for _, user := range users {
+		if user.WorkspaceMailbox {
+			mailboxes = append(mailboxes, workspaceMailbox(user))
+		}
+	}
+	sort.Slice(mailboxes, func(i, j int) bool {
+		return mailboxes[i].Email < mailboxes[j].Email
+	})
+
+	return &model.WorkspaceConnection{
+		Domain:    s.domain,
+		ClientID:  s.clientID,
+		Scopes:    model.WorkspaceScopes,
+		Mailboxes: mailboxes,
+	}, nil
+}
+
+func (s *workspaceService) AddMailbox(ctx context.Context, email, name string) (*model.WorkspaceMailbox, error) {
+	if s.domain == "" {
+		return nil, errWorkspaceDisabled
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || !model.InDomain(address.Address, s.domain) {
+		return nil, apierror.InvalidFields([]apierror.FieldError{
+			{Field: "email", Message: "must be an address of " + s.domain},
+		})
+	}
+	email = strings.ToLower(address.Address)
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err == nil && user.WorkspaceMailbox {
+		mailbox := workspaceMailbox(user)
+		return &mailbox, nil
+	}
+
+	// Listing the newest email tells whether the domain's admin authorized the service account
+	if _, err := s.gmailClient.List(ctx, email, 1); err != nil {
+		s.logger.Error("Failed to read Workspace mailbox", email, "with the service account:", err)
+		return nil, gmailError("Failed to read the mailbox with the service account, check its domain-wide delegation in the Admin console", err)
+	}
+
+	if user != nil {
+		user.WorkspaceMailbox = true
+		user.GrantWorkspaceScopes()
+		err = s.userRepo.Update(ctx, user)
+	} else {
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
+		user = model.NewWorkspaceMailbox(email, name)
+		err = s.userRepo.Create(ctx, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Designated Workspace mailbox", email, "of user", user.ID)
+
+	mailbox := workspaceMailbox(user)
+	return &mailbox, nil
+}
+
+func (s *workspaceService) RemoveMailbox(ctx context.Context, email string) error {
+	if s.domain == "" {
+		return errWorkspaceDisabled
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, strings.ToLower(email))
+	if err != nil || !user.WorkspaceMailbox {
+		return apierror.NotFound("Workspace mailbox not found")
+	}
+	user.WorkspaceMailbox = false
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.logger.Info("Stopped syncing Workspace mailbox", email, "of user", user.ID)
+	return nil
+}
+
+func workspaceMailbox(user *model.User) model.WorkspaceMailbox {
+	return model.WorkspaceMailbox{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		SignedIn:  user.SignedIn(),
+		CreatedAt: user.CreatedAt,
+	}
+}
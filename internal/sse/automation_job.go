@@ -0,0 +1,92 @@
+package sse
+
+import (
+	"context"
+	"time"
+
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/service"
+)
+
+// AutomationJob periodically runs the delayed automations, e.g. archiving promotions after a week,
+// and the cleanup policies whose schedule has come round
+type AutomationJob struct {
+	automationService service.AutomationService
+	logger            *logger.Logger
+	interval          time.Duration
+
+	// Optional; when set, cleanup policies run along with the automations
+	cleanupService service.CleanupService
+
+	// Context for managing the job lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewAutomationJob creates a new automation job that runs every interval, hourly when zero
+func NewAutomationJob(automationService service.AutomationService, interval time.Duration, logger *logger.Logger) *AutomationJob {
+	if interval <= 0 {
+		interval = config.DefaultAutomationInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AutomationJob{
+		automationService: automationService,
+		logger:            logger,
+		interval:          interval,
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+// UseCleanupPolicies makes each pass also run the users' due cleanup policies
+func (j *AutomationJob) UseCleanupPolicies(cleanupService service.CleanupService) {
+	j.cleanupService = cleanupService
+}
+
+// Start begins the periodic automation job
+func (j *AutomationJob) Start() {
+	j.logger.Info("Starting automation job with interval:", j.interval.String())
+
+	j.RunAutomations()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.RunAutomations()
+		case <-j.ctx.Done():
+			j.logger.Info("Automation job stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the periodic automation job
+func (j *AutomationJob) Stop() {
+	j.cancel()
+}
+
+// RunAutomations executes a single pass of the delayed automations - exported for testing
+func (j *AutomationJob) RunAutomations() {
+	applied, err := j.automationService.RunScheduled(j.ctx)
+	if err != nil {
+		j.logger.Error("Failed to run scheduled automations:", err)
+	} else {
+		j.logger.Info("Automation pass complete - emails acted on:", applied)
+	}
+
+	if j.cleanupService == nil {
+		return
+	}
+	cleaned, err := j.cleanupService.RunDue(j.ctx)
+	if err != nil {
+		j.logger.Error("Failed to run cleanup policies:", err)
+		return
+	}
+	j.logger.Info("Cleanup policy pass complete - emails cleaned:", cleaned)
+}
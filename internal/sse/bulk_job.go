@@ -0,0 +1,250 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+)
+
+// bulkJobActions lists the actions a filter-based bulk job can run
+var bulkJobActions = map[string]bool{
+	"archive":         true,
+	"read":            true,
+	"unread":          true,
+	"star":            true,
+	"move":            true,
+	"spam":            true,
+	"unstar":          true,
+	"delete":          true,
+	"local_archive":   true,
+	"local_unarchive": true,
+	"unsubscribe":     true,
+}
+
+// BulkJobQueue runs filter-based bulk actions in the background, in batches
+type BulkJobQueue struct {
+	emailService       service.EmailService
+	unsubscribeService service.UnsubscribeService
+	sseManager         *SSEManager
+	push               service.PushService // optional; notifies offline users when a job finishes
+	logger             *logger.Logger
+	batchSize          int
+
+	jobs  map[string]*model.BulkJob
+	mutex sync.RWMutex
+	queue chan *model.BulkJob
+
+	// Context for managing the job lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBulkJobQueue creates a new bulk job queue that acts on batchSize emails at a time
+func NewBulkJobQueue(
+	emailService service.EmailService,
+	unsubscribeService service.UnsubscribeService,
+	sseManager *SSEManager,
+	batchSize int,
+	logger *logger.Logger,
+) *BulkJobQueue {
+	if batchSize <= 0 {
+		batchSize = config.DefaultBulkBatchSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &BulkJobQueue{
+		emailService:       emailService,
+		unsubscribeService: unsubscribeService,
+		sseManager:         sseManager,
+		logger:             logger,
+		batchSize:          batchSize,
+		jobs:               make(map[string]*model.BulkJob),
+		queue:              make(chan *model.BulkJob, 100),
+		ctx:                ctx,
+		cancel:             cancel,
+	}
+}
+
+// Enqueue validates and schedules a bulk action over every email matching the filter
+func (q *BulkJobQueue) Enqueue(userID, action string, filter model.EmailFilter) (*model.BulkJob, error) {
+	return q.Submit(model.NewBulkJob(userID, action, filter))
+}
+
+// Submit validates and schedules a job built by the caller, for actions that take parameters
+func (q *BulkJobQueue) Submit(job *model.BulkJob) (*model.BulkJob, error) {
+	if !bulkJobActions[job.Action] {
+		return nil, apierror.Validation("unsupported bulk action: " + job.Action)
+	}
+	if job.Action == "move" && job.Label == "" {
+		return nil, apierror.Validation("move requires a label")
+	}
+	if !job.Filter.HasCriteria() {
+		return nil, apierror.Validation("filter must include at least one criterion")
+	}
+
+	q.mutex.Lock()
+	q.jobs[job.ID] = job
+	q.mutex.Unlock()
+
+	select {
+	case q.queue <- job:
+	default:
+		q.mutex.Lock()
+		delete(q.jobs, job.ID)
+		q.mutex.Unlock()
+		return nil, apierror.Unavailable("bulk job queue is full")
+	}
+
+	q.logger.Info("Queued bulk job", job.ID, "action:", job.Action, "for user:", job.UserID)
+	return q.snapshot(job), nil
+}
+
+// GetJob returns a copy of the user's job
+func (q *BulkJobQueue) GetJob(userID, jobID string) (*model.BulkJob, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	job, exists := q.jobs[jobID]
+	if !exists || job.UserID != userID {
+		return nil, apierror.NotFound("bulk job not found")
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// Start processes queued jobs one at a time until stopped
+func (q *BulkJobQueue) Start() {
+	q.logger.Info("Starting bulk job queue with batch size:", q.batchSize)
+
+	for {
+		select {
+		case job := <-q.queue:
+			q.runJob(job)
+		case <-q.ctx.Done():
+			q.logger.Info("Bulk job queue stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the bulk job queue
+func (q *BulkJobQueue) Stop() {
+	q.cancel()
+}
+
+// RunPending processes every queued job synchronously - exported for testing
+func (q *BulkJobQueue) RunPending() {
+	for {
+		select {
+		case job := <-q.queue:
+			q.runJob(job)
+		default:
+			return
+		}
+	}
+}
+
+// runJob resolves the job's filter and applies the action batch by batch
+func (q *BulkJobQueue) runJob(job *model.BulkJob) {
+	q.update(job, func(j *model.BulkJob) { j.Status = model.BulkJobRunning })
+
+	emails, err := q.emailService.ListEmails(q.ctx, job.UserID, job.Filter, 0)
+	if err != nil {
+		q.logger.Error("Failed to resolve bulk job filter:", err)
+		q.finish(job, fmt.Errorf("failed to resolve filter: %w", err))
+		return
+	}
+
+	emailIDs := make([]string, len(emails))
+	for i, email := range emails {
+		emailIDs[i] = email.ID
+	}
+	q.update(job, func(j *model.BulkJob) { j.Total = len(emailIDs) })
+
+	for start := 0; start < len(emailIDs); start += q.batchSize {
+		if q.ctx.Err() != nil {
+			q.finish(job, q.ctx.Err())
+			return
+		}
+
+		end := start + q.batchSize
+		if end > len(emailIDs) {
+			end = len(emailIDs)
+		}
+		batch := emailIDs[start:end]
+
+		if err := q.runBatch(job, batch); err != nil {
+			q.logger.Error("Bulk job", job.ID, "batch failed:", err)
+			q.update(job, func(j *model.BulkJob) { j.Failed += len(batch) })
+			continue
+		}
+		q.update(job, func(j *model.BulkJob) { j.Processed += len(batch) })
+	}
+
+	q.finish(job, nil)
+}
+
+// runBatch dispatches a batch to the service that owns the action
+func (q *BulkJobQueue) runBatch(job *model.BulkJob, emailIDs []string) error {
+	switch job.Action {
+	case "delete":
+		return q.emailService.DeleteEmails(q.ctx, emailIDs, job.UserID)
+	case "unsubscribe":
+		return q.unsubscribeService.UnsubscribeEmails(q.ctx, emailIDs, job.UserID)
+	case "move":
+		return q.emailService.MoveEmails(q.ctx, emailIDs, job.Label, job.UserID)
+	case "spam":
+		return q.emailService.ReportSpam(q.ctx, emailIDs, job.UserID, job.BlockSender)
+	default:
+		return q.emailService.PerformBulkAction(q.ctx, emailIDs, job.Action, job.UserID)
+	}
+}
+
+// finish marks the job done and notifies the user's connected clients
+func (q *BulkJobQueue) finish(job *model.BulkJob, err error) {
+	q.update(job, func(j *model.BulkJob) {
+		j.Status = model.BulkJobCompleted
+		if err != nil {
+			j.Status = model.BulkJobFailed
+			j.Error = err.Error()
+		}
+	})
+
+	snapshot := q.snapshot(job)
+	q.logger.Info("Bulk job", job.ID, snapshot.Status, "- processed:", snapshot.Processed, "failed:", snapshot.Failed)
+	if q.sseManager != nil {
+		q.sseManager.Publish(job.UserID, JobCompleted{snapshot})
+	}
+	if q.push != nil {
+		q.push.NotifyJobFinished(q.ctx, snapshot)
+	}
+}
+
+// UsePush sends a push notification when a job finishes
+func (q *BulkJobQueue) UsePush(push service.PushService) {
+	q.push = push
+}
+
+func (q *BulkJobQueue) update(job *model.BulkJob, apply func(*model.BulkJob)) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	apply(job)
+	job.UpdatedAt = time.Now()
+}
+
+func (q *BulkJobQueue) snapshot(job *model.BulkJob) *model.BulkJob {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	copied := *job
+	return &copied
+}
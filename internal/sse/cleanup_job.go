@@ -0,0 +1,116 @@
+package sse
+
+import (
+	"context"
+	"time"
+
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/service"
+)
+
+// CleanupJob periodically purges expired trash, trashes old verification code emails and enforces
+// user retention policies
+type CleanupJob struct {
+	emailService     service.EmailService
+	retentionService service.RetentionService
+	logger           *logger.Logger
+	interval         time.Duration
+	trashRetention   time.Duration
+	otpRetention     time.Duration
+
+	aiDebug          service.AIDebugService // purges the AI calls recorded in debug mode; optional
+	aiDebugRetention time.Duration
+
+	// Context for managing the job lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewCleanupJob creates a new cleanup job that purges emails trashed longer than trashRetention ago
+// and trashes verification code emails received longer than otpRetention ago
+func NewCleanupJob(emailService service.EmailService, retentionService service.RetentionService, trashRetention, otpRetention time.Duration, logger *logger.Logger) *CleanupJob {
+	// Trashed emails are kept for 30 days by default before being purged
+	if trashRetention <= 0 {
+		trashRetention = config.DefaultTrashRetention
+	}
+	if otpRetention <= 0 {
+		otpRetention = config.DefaultOTPRetention
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CleanupJob{
+		emailService:     emailService,
+		retentionService: retentionService,
+		logger:           logger,
+		interval:         time.Hour,
+		trashRetention:   trashRetention,
+		otpRetention:     otpRetention,
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+}
+
+// UseAIDebug also purges the AI calls recorded longer than retention ago
+func (j *CleanupJob) UseAIDebug(aiDebug service.AIDebugService, retention time.Duration) {
+	if retention <= 0 {
+		retention = config.DefaultAIDebugRetention
+	}
+	j.aiDebug = aiDebug
+	j.aiDebugRetention = retention
+}
+
+// Start begins the periodic cleanup job
+func (j *CleanupJob) Start() {
+	j.logger.Info("Starting cleanup job with interval:", j.interval.String(), "trash retention:", j.trashRetention.String(),
+		"verification code retention:", j.otpRetention.String())
+
+	j.RunCleanup()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.RunCleanup()
+		case <-j.ctx.Done():
+			j.logger.Info("Cleanup job stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the periodic cleanup job
+func (j *CleanupJob) Stop() {
+	j.cancel()
+}
+
+// RunCleanup executes a single cleanup pass - exported for testing
+func (j *CleanupJob) RunCleanup() {
+	// Trashed before the purge, so they stay recoverable for the usual trash retention
+	if _, err := j.emailService.TrashOTPEmails(j.ctx, j.otpRetention); err != nil {
+		j.logger.Error("Failed to trash verification code emails:", err)
+	}
+	if _, err := j.emailService.PurgeTrash(j.ctx, j.trashRetention); err != nil {
+		j.logger.Error("Failed to purge trash:", err)
+	}
+	if j.aiDebug != nil {
+		if _, err := j.aiDebug.PurgeCalls(j.ctx, j.aiDebugRetention); err != nil {
+			j.logger.Error("Failed to purge AI debug calls:", err)
+		}
+	}
+
+	stats, err := j.retentionService.EnforcePolicies(j.ctx)
+	if err != nil {
+		j.logger.Error("Failed to enforce retention policies:", err)
+		return
+	}
+	j.logger.Info("Retention pass complete - bodies pruned:", stats.BodiesPruned, "emails deleted:", stats.EmailsDeleted)
+}
+
+// TrashRetention returns how long trashed emails are kept before being purged
+func (j *CleanupJob) TrashRetention() time.Duration {
+	return j.trashRetention
+}
@@ -0,0 +1,172 @@
+package sse
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Buffering of each connection. A broadcast never waits on a connection: events wait in its
+// channel, then in its overflow, and once both are full its oldest waiting events are dropped.
+const (
+	clientBufferSize   = 10
+	clientOverflowSize = 100
+)
+
+// DeliveryStats counts what became of the events broadcast since the server started
+type DeliveryStats struct {
+	Connections int   `json:"connections"`
+	Sent        int64 `json:"sent"`         // events handed to a connection
+	Dropped     int64 `json:"dropped"`      // events a connection fell too far behind to receive
+	SlowClients int64 `json:"slow_clients"` // connections that dropped events
+}
+
+type deliveryCounters struct {
+	sent        atomic.Int64
+	dropped     atomic.Int64
+	slowClients atomic.Int64
+}
+
+// client is one SSE connection. Events behind a full channel are moved to it by its own
+// goroutine, so a reader that stalls only holds up itself.
+type client struct {
+	channel     chan []byte
+	counters    *deliveryCounters
+	connectedAt time.Time
+
+	mutex    sync.Mutex
+	overflow [][]byte
+	sending  bool // the pump holds an event taken from the overflow
+	dropped  int  // events dropped since the reader was last told
+	slow     bool
+	closed   bool
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newClient(counters *deliveryCounters) *client {
+	c := &client{
+		channel:     make(chan []byte, clientBufferSize),
+		counters:    counters,
+		connectedAt: time.Now(),
+		wake:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go c.pump()
+	return c
+}
+
+// send queues the event without blocking; it reports whether this made the client a slow consumer
+func (c *client) send(data []byte) (becameSlow bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return false
+	}
+	// Straight to the channel, unless older events are still waiting
+	if len(c.overflow) == 0 && !c.sending {
+		select {
+		case c.channel <- data:
+			c.counters.sent.Add(1)
+			return false
+		default:
+		}
+	}
+
+	if len(c.overflow) >= clientOverflowSize {
+		c.overflow[0] = nil
+		c.overflow = c.overflow[1:]
+		c.dropped++
+		c.counters.dropped.Add(1)
+		if !c.slow {
+			c.slow = true
+			c.counters.slowClients.Add(1)
+			becameSlow = true
+		}
+	}
+	c.overflow = append(c.overflow, data)
+
+	select {
+	case c.wake <- struct{}{}:
+	default: // the pump is already awake
+	}
+	return becameSlow
+}
+
+// pump moves the overflow to the channel as the reader catches up, telling it first how many
+// events it missed
+func (c *client) pump() {
+	defer close(c.stopped)
+
+	for {
+		select {
+		case <-c.wake:
+		case <-c.done:
+			return
+		}
+
+		for {
+			c.mutex.Lock()
+			var next []byte
+			if c.dropped > 0 {
+				next = droppedEvent(c.dropped)
+				c.dropped = 0
+			} else if len(c.overflow) > 0 {
+				next = c.overflow[0]
+				c.overflow[0] = nil
+				c.overflow = c.overflow[1:]
+				c.counters.sent.Add(1)
+			}
+			c.sending = next != nil
+			c.mutex.Unlock()
+
+			if next == nil {
+				break
+			}
+			select {
+			case c.channel <- next:
+			case <-c.done:
+				return
+			}
+		}
+	}
+}
+
+// info describes the connection as of now
+func (c *client) info(now time.Time) ConnectionInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return ConnectionInfo{
+		ConnectedAt:     c.connectedAt,
+		DurationSeconds: int64(now.Sub(c.connectedAt).Seconds()),
+		Waiting:         len(c.overflow),
+		Slow:            c.slow,
+	}
+}
+
+// close stops the pump, then closes the channel so the reader knows the connection is over
+func (c *client) close() {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return
+	}
+	c.closed = true
+	c.mutex.Unlock()
+
+	close(c.done)
+	<-c.stopped
+	close(c.channel)
+}
+
+// droppedEvent tells a reader how many events it missed, for it to reconcile, e.g. through the
+// email delta endpoint
+func droppedEvent(count int) []byte {
+	event, _ := Encode(EventsDropped{Count: count})
+	return event
+}
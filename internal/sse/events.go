@@ -0,0 +1,194 @@
+package sse
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"jump-challenge/internal/model"
+)
+
+// Envelope is the JSON of every SSE message. Version is that of the data's schema for the
+// event type: fields may be added to a version, and removing or changing the meaning of one
+// bumps it, so clients can skip versions they don't understand.
+type Envelope struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Data    any    `json:"data"`
+	Time    int64  `json:"time"` // Unix seconds when the event was broadcast
+}
+
+// Event is a typed SSE payload, broadcast with Publish
+type Event interface {
+	EventType() string
+}
+
+// EventSchema documents one event type: the current version of its data and the Go type it is
+// encoded from
+type EventSchema struct {
+	Type        string
+	Version     int
+	Description string
+	Data        any // zero value of the payload
+}
+
+// Connected is sent first on every connection
+type Connected struct {
+	Message string `json:"message"`
+	UserID  string `json:"userId"`
+}
+
+func (Connected) EventType() string { return model.EventConnection }
+
+// NewEmail is a newly synced email, without its body
+type NewEmail struct {
+	*model.Email
+}
+
+func (NewEmail) EventType() string { return model.EventNewEmail }
+
+// VIPEmail is a newly synced email from a VIP sender, without its body
+type VIPEmail struct {
+	*model.Email
+}
+
+func (VIPEmail) EventType() string { return model.EventVIPEmail }
+
+// SyncProgress tells how many new emails a sync processed for the user
+type SyncProgress struct {
+	Count   int    `json:"count"`
+	Message string `json:"message"`
+}
+
+func (SyncProgress) EventType() string { return model.EventEmailSummary }
+
+// JobCompleted is a background bulk action that finished or failed
+type JobCompleted struct {
+	*model.BulkJob
+}
+
+func (JobCompleted) EventType() string { return model.EventBulkJob }
+
+// OTPReceived is a verification code that arrived
+type OTPReceived struct {
+	*model.OTP
+}
+
+func (OTPReceived) EventType() string { return model.EventOTP }
+
+// SecurityAlert is an account-security email that arrived
+type SecurityAlert struct {
+	*model.SecurityEvent
+}
+
+func (SecurityAlert) EventType() string { return model.EventSecurity }
+
+// ShipmentDelivered is a tracked package that arrived
+type ShipmentDelivered struct {
+	*model.Shipment
+}
+
+func (ShipmentDelivered) EventType() string { return model.EventShipment }
+
+// UnsubscribeResult is an unsubscribe that needs the user's attention: one the sender ignored,
+// or one left for the user to finish
+type UnsubscribeResult struct {
+	*model.UnsubscribeAttempt
+	eventType string
+}
+
+func (r UnsubscribeResult) EventType() string { return r.eventType }
+
+// UnsubscribeIneffective reports a sender that kept emailing after the unsubscribe
+func UnsubscribeIneffective(attempt *model.UnsubscribeAttempt) UnsubscribeResult {
+	return UnsubscribeResult{UnsubscribeAttempt: attempt, eventType: model.EventUnsubscribeIneffective}
+}
+
+// UnsubscribeManualAction reports an unsubscribe page the user has to finish, e.g. for a CAPTCHA
+func UnsubscribeManualAction(attempt *model.UnsubscribeAttempt) UnsubscribeResult {
+	return UnsubscribeResult{UnsubscribeAttempt: attempt, eventType: model.EventUnsubscribeManualAction}
+}
+
+// CleanupCompleted is a scheduled cleanup policy run that cleaned emails or failed
+type CleanupCompleted struct {
+	*model.CleanupRun
+}
+
+func (CleanupCompleted) EventType() string { return model.EventCleanup }
+
+// ReauthRequired asks the user to sign in again because Google refused their grant; new emails
+// aren't synced until they do
+type ReauthRequired struct {
+	Message string `json:"message"`
+	URL     string `json:"url"` // starts the sign-in
+}
+
+func (ReauthRequired) EventType() string { return model.EventReauth }
+
+// EventsDropped tells a connection that fell behind how many events it missed
+type EventsDropped struct {
+	Count int `json:"count"`
+}
+
+func (EventsDropped) EventType() string { return model.EventDropped }
+
+// eventSchemas is the registry of the event types sent over SSE
+var eventSchemas = map[string]EventSchema{}
+
+func registerEvent(version int, description string, data Event) {
+	eventSchemas[data.EventType()] = EventSchema{
+		Type:        data.EventType(),
+		Version:     version,
+		Description: description,
+		Data:        data,
+	}
+}
+
+func init() {
+	registerEvent(1, "Sent first on every connection", Connected{})
+	registerEvent(1, "A newly synced email, without its body", NewEmail{})
+	registerEvent(1, "A newly synced email from a VIP sender, without its body", VIPEmail{})
+	registerEvent(1, "How many new emails a sync processed", SyncProgress{})
+	registerEvent(1, "A background bulk action finished or failed", JobCompleted{})
+	registerEvent(1, "A verification code arrived", OTPReceived{})
+	registerEvent(1, "An account-security email arrived", SecurityAlert{})
+	registerEvent(1, "A tracked package was delivered", ShipmentDelivered{})
+	registerEvent(1, "A sender kept emailing after an unsubscribe", UnsubscribeIneffective(nil))
+	registerEvent(1, "An unsubscribe page needs the user, e.g. for a CAPTCHA", UnsubscribeManualAction(nil))
+	registerEvent(1, "A scheduled cleanup policy cleaned emails or failed", CleanupCompleted{})
+	registerEvent(1, "Google refused the user's grant; sync is paused until they sign in again", ReauthRequired{})
+	registerEvent(1, "The connection fell behind and missed events; reconcile through the email delta", EventsDropped{})
+}
+
+// Schemas lists the registered event types by name
+func Schemas() []EventSchema {
+	schemas := make([]EventSchema, 0, len(eventSchemas))
+	for _, schema := range eventSchemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Type < schemas[j].Type
+	})
+	return schemas
+}
+
+// LookupSchema returns the registered schema of an event type
+func LookupSchema(eventType string) (EventSchema, bool) {
+	schema, ok := eventSchemas[eventType]
+	return schema, ok
+}
+
+// Encode wraps the event in its envelope, stamped with its schema version and the current time
+func Encode(event Event) ([]byte, error) {
+	return encode(event.EventType(), event)
+}
+
+func encode(eventType string, data any) ([]byte, error) {
+	schema := eventSchemas[eventType]
+	return json.Marshal(Envelope{
+		Type:    eventType,
+		Version: schema.Version,
+		Data:    data,
+		Time:    time.Now().Unix(),
+	})
+}
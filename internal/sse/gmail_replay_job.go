@@ -0,0 +1,68 @@
+package sse
+
+import (
+	"context"
+	"time"
+
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/service"
+)
+
+// GmailReplayJob periodically retries in Gmail the changes queued while it was unreachable
+type GmailReplayJob struct {
+	emailService service.EmailService
+	logger       *logger.Logger
+	interval     time.Duration
+
+	// Context for managing the job lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewGmailReplayJob creates a new replay job that runs every interval, the default when zero
+func NewGmailReplayJob(emailService service.EmailService, interval time.Duration, logger *logger.Logger) *GmailReplayJob {
+	if interval <= 0 {
+		interval = config.DefaultGmailReplay
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &GmailReplayJob{
+		emailService: emailService,
+		logger:       logger,
+		interval:     interval,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start begins the periodic replay job
+func (j *GmailReplayJob) Start() {
+	j.logger.Info("Starting Gmail replay job with interval:", j.interval.String())
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.RunReplay()
+		case <-j.ctx.Done():
+			j.logger.Info("Gmail replay job stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the periodic replay job
+func (j *GmailReplayJob) Stop() {
+	j.cancel()
+}
+
+// RunReplay executes a single pass over the queued changes - exported for testing
+func (j *GmailReplayJob) RunReplay() {
+	if _, err := j.emailService.ReplayGmailActions(j.ctx); err != nil {
+		j.logger.Error("Failed to replay queued Gmail actions:", err)
+	}
+}
@@ -2,8 +2,9 @@ package sse
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"sync"
 	"time"
 
 	"jump-challenge/internal/config"
@@ -19,7 +20,17 @@ type EmailSyncJob struct {
 	userRepo     repository.UserRepository
 	sseManager   *SSEManager
 	logger       *logger.Logger
-	interval     time.Duration
+
+	// Settings a config reload can change while the job runs
+	interval        time.Duration
+	maxFetchEmails  int64
+	settingsMutex   sync.Mutex
+	intervalChanged chan struct{}
+
+	// Channels that reach users without an SSE connection, e.g. Web Push or Telegram
+	offlineChannels []func(ctx context.Context, userID string) bool
+	// Users synced whether or not they can be notified, e.g. designated Workspace mailboxes
+	alwaysSynced func(user *model.User) bool
 
 	// Context for managing the job lifecycle
 	ctx    context.Context
@@ -31,15 +42,10 @@ func NewEmailSyncJob(
 	emailService service.EmailService,
 	userRepo repository.UserRepository,
 	sseManager *SSEManager,
+	interval time.Duration,
+	maxFetchEmails int64,
 	logger *logger.Logger,
 ) *EmailSyncJob {
-	// Get sync interval from environment variable, default to 1 minute
-	intervalStr := config.GetEnv("EMAIL_SYNC_INTERVAL_SECONDS", "30")
-	intervalSeconds, err := strconv.Atoi(intervalStr)
-	if err != nil || intervalSeconds <= 0 {
-		intervalSeconds = 30 // Default to 1 minute
-	}
-
 	ctx, cancel := context.WithCancel(context.Background())
 
 	job := &EmailSyncJob{
@@ -47,14 +53,79 @@ func NewEmailSyncJob(
 		userRepo:     userRepo,
 		sseManager:   sseManager,
 		logger:       logger,
-		interval:     time.Duration(intervalSeconds) * time.Second,
 		ctx:          ctx,
 		cancel:       cancel,
+
+		interval:        config.DefaultSyncInterval,
+		maxFetchEmails:  config.DefaultMaxFetchEmails,
+		intervalChanged: make(chan struct{}, 1),
 	}
+	job.Reconfigure(interval, maxFetchEmails)
 
 	return job
 }
 
+// Reconfigure changes how often the job syncs and how many emails it fetches per user; a
+// running job waits the new interval from now on. Zero values keep the current setting.
+func (j *EmailSyncJob) Reconfigure(interval time.Duration, maxFetchEmails int64) {
+	j.settingsMutex.Lock()
+	changed := interval > 0 && interval != j.interval
+	if interval > 0 {
+		j.interval = interval
+	}
+	if maxFetchEmails > 0 {
+		j.maxFetchEmails = maxFetchEmails
+	}
+	j.settingsMutex.Unlock()
+
+	if changed {
+		select {
+		case j.intervalChanged <- struct{}{}:
+		default: // a change is already pending and will read the latest interval
+		}
+	}
+}
+
+// Interval returns how often the job syncs
+func (j *EmailSyncJob) Interval() time.Duration {
+	j.settingsMutex.Lock()
+	defer j.settingsMutex.Unlock()
+	return j.interval
+}
+
+// MaxFetchEmails returns how many emails each sync fetches per user
+func (j *EmailSyncJob) MaxFetchEmails() int64 {
+	j.settingsMutex.Lock()
+	defer j.settingsMutex.Unlock()
+	return j.maxFetchEmails
+}
+
+// AddOfflineChannel keeps syncing users that reachable reports can be notified while they are offline
+func (j *EmailSyncJob) AddOfflineChannel(reachable func(ctx context.Context, userID string) bool) {
+	j.offlineChannels = append(j.offlineChannels, reachable)
+}
+
+// SyncAlways keeps syncing the users designated reports, even when nobody can be told about
+// their new emails
+func (j *EmailSyncJob) SyncAlways(designated func(user *model.User) bool) {
+	j.alwaysSynced = designated
+}
+
+func (j *EmailSyncJob) shouldSync(user *model.User) bool {
+	if j.alwaysSynced != nil && j.alwaysSynced(user) {
+		return true
+	}
+	if j.sseManager.HasUserConnection(user.ID) {
+		return true
+	}
+	for _, reachable := range j.offlineChannels {
+		if reachable(j.ctx, user.ID) {
+			return true
+		}
+	}
+	return false
+}
+
 // RunSync executes the email sync for all users - exported for testing
 func (j *EmailSyncJob) RunSync() {
 	j.logger.Info("Running periodic email sync...")
@@ -68,71 +139,93 @@ func (j *EmailSyncJob) RunSync() {
 
 	j.logger.Info("Syncing emails for", len(users), "users")
 
-	maxFetchEmails := config.GetEnv("MAX_FETCH_EMAILS", "3")
-	maxFetch, _ := strconv.Atoi(maxFetchEmails)
-	maxResults := int64(maxFetch)
-
 	for _, user := range users {
-		// Check if this user has active SSE connections
-		hasConnection := j.sseManager.HasUserConnection(user.ID)
-		if !hasConnection {
-			j.logger.Info("Skipping email sync for user", user.ID, "no active SSE connections")
+		// Gmail refuses the tokens of users who must sign in again
+		if user.NeedsReauth {
+			j.logger.Info("Skipping email sync for user", user.ID, "until they sign in again")
 			continue
 		}
 
-		// Get the most recent email for this user as a reference point
-		lastEmail, err := j.getMostRecentEmailForUser(user.ID)
-		var afterEmailID string
-		if err == nil && lastEmail != nil {
-			afterEmailID = lastEmail.GmailID
-		}
-
-		// Sync emails for this user - get both fetched emails and newly processed emails
-		fetchedEmails, newProcessedEmails, err := j.emailService.SyncEmailsWithNewEmails(j.ctx, user.ID, maxResults, afterEmailID)
-		if err != nil {
-			j.logger.Error("Failed to sync emails for user", user.ID, ":", err)
+		// Only sync users who can be told about new emails
+		if !j.shouldSync(user) {
+			j.logger.Info("Skipping email sync for user", user.ID, "no active SSE connections or offline notifications")
 			continue
 		}
 
-		j.logger.Info("Fetched", len(fetchedEmails), "emails from Gmail for user", user.ID, ", processed", len(newProcessedEmails), "new emails")
+		j.syncUser(user.ID)
+		j.reprocess(user.ID)
+	}
+
+	j.logger.Info("Completed periodic email sync")
+}
+
+// syncUser fetches the emails newer than the user's most recent one and sends the new ones
+// over SSE
+func (j *EmailSyncJob) syncUser(userID string) {
+	// Get the most recent email for this user as a reference point
+	opts := service.SyncOptions{MaxResults: j.MaxFetchEmails()}
+	if lastEmail, err := j.emailService.GetMostRecentEmail(j.ctx, userID); err == nil && lastEmail != nil {
+		opts.AfterEmailID = lastEmail.GmailID
+	}
 
-		// Send only the newly processed emails via SSE to the user
-		if len(newProcessedEmails) > 0 {
-			j.logger.Info("Sending", len(newProcessedEmails), "new emails via SSE to user", user.ID)
+	result, err := j.emailService.SyncEmails(j.ctx, userID, opts)
+	if errors.Is(err, service.ErrGmailCircuitOpen) {
+		j.logger.Info("Skipping email sync for user", userID, ": their Gmail calls are paused")
+		return
+	}
+	if err != nil {
+		j.logger.Error("Failed to sync emails for user", userID, ":", err)
+		return
+	}
 
-			// Send the new emails via SSE to the user - these are already processed (have summaries)
-			for _, email := range newProcessedEmails {
-				// Send emails that have been processed (have summaries)
-				j.sseManager.BroadcastEmailToUser(user.ID, email)
-			}
+	j.logger.Info("Fetched", len(result.Fetched), "emails from Gmail for user", userID, ", processed", len(result.New),
+		"new emails, updated", len(result.Updated), "and skipped", result.Skipped)
+	if len(result.New) == 0 {
+		return
+	}
 
-			// Send a summary notification
-			summary := map[string]interface{}{
-				"count":   len(newProcessedEmails),
-				"message": fmt.Sprintf("%d new emails received and processed", len(newProcessedEmails)),
-			}
-			j.sseManager.BroadcastToUser(user.ID, "email_summary", summary)
+	// Send the new emails via SSE to the user - these are already processed (have summaries)
+	j.logger.Info("Sending", len(result.New), "new emails via SSE to user", userID)
+	for _, email := range result.New {
+		j.sseManager.BroadcastEmailToUser(userID, email.WithoutBody())
+		if email.IsUrgent() {
+			j.sseManager.Publish(userID, VIPEmail{email.WithoutBody()})
+		}
+		if otp := model.NewOTP(email, time.Now()); otp != nil {
+			j.sseManager.Publish(userID, OTPReceived{otp})
+		}
+		if event := model.NewSecurityEvent(email); event != nil {
+			j.sseManager.Publish(userID, SecurityAlert{event})
 		}
 	}
 
-	j.logger.Info("Completed periodic email sync")
+	// Send a summary notification
+	j.sseManager.Publish(userID, SyncProgress{
+		Count:   len(result.New),
+		Message: fmt.Sprintf("%d new emails received and processed", len(result.New)),
+	})
 }
 
 // Start begins the periodic email sync job
 func (j *EmailSyncJob) Start() {
-	j.logger.Info("Starting email sync job with interval:", j.interval.String())
+	interval := j.Interval()
+	j.logger.Info("Starting email sync job with interval:", interval.String())
 
 	// Run the initial sync
-	go j.runSync()
+	go j.RunSync()
 
 	// Start the ticker for periodic syncs
-	ticker := time.NewTicker(j.interval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			go j.runSync()
+			go j.RunSync()
+		case <-j.intervalChanged:
+			interval := j.Interval()
+			ticker.Reset(interval)
+			j.logger.Info("Email sync interval changed to:", interval.String())
 		case <-j.ctx.Done():
 			j.logger.Info("Email sync job stopped")
 			return
@@ -145,113 +238,16 @@ func (j *EmailSyncJob) Stop() {
 	j.cancel()
 }
 
-// runSync executes the email sync for all users
-func (j *EmailSyncJob) runSync() {
-	j.logger.Info("Running periodic email sync...")
-
-	// Get all users to sync emails for
-	users, err := j.userRepo.FindAll(j.ctx)
+// reprocess asks again for the summaries the AI answered unusably on earlier syncs
+func (j *EmailSyncJob) reprocess(userID string) {
+	fixed, err := j.emailService.ReprocessEmails(j.ctx, userID)
 	if err != nil {
-		j.logger.Error("Failed to get users for email sync:", err)
+		j.logger.Error("Failed to reprocess emails for user", userID, ":", err)
 		return
 	}
-
-	j.logger.Info("Syncing emails for", len(users), "users")
-
-	for _, user := range users {
-		// Check if this user has active SSE connections
-		hasConnection := j.sseManager.HasUserConnection(user.ID)
-		if !hasConnection {
-			j.logger.Info("Skipping email sync for user", user.ID, "no active SSE connections")
-			continue
-		}
-
-		// Get the most recent email for this user as a reference point
-		lastEmail, err := j.getMostRecentEmailForUser(user.ID)
-		var afterEmailID string
-		if err == nil && lastEmail != nil {
-			afterEmailID = lastEmail.GmailID
-		}
-
-		maxFetchEmails := config.GetEnv("MAX_FETCH_EMAILS", "3")
-		maxFetch, _ := strconv.Atoi(maxFetchEmails)
-		maxResults := int64(maxFetch)
-
-		// Sync emails for this user - get both fetched emails and newly processed emails
-		fetchedEmails, newProcessedEmails, err := j.emailService.SyncEmailsWithNewEmails(j.ctx, user.ID, maxResults, afterEmailID)
-		if err != nil {
-			j.logger.Error("Failed to sync emails for user", user.ID, ":", err)
-			continue
-		}
-
-		j.logger.Info("Fetched", len(fetchedEmails), "emails from Gmail for user", user.ID, ", processed", len(newProcessedEmails), "new emails")
-
-		// Send only the newly processed emails via SSE to the user
-		if len(newProcessedEmails) > 0 {
-			j.logger.Info("Sending", len(newProcessedEmails), "new emails via SSE to user", user.ID)
-
-			// Send the new emails via SSE to the user - these are already processed (have summaries)
-			for _, email := range newProcessedEmails {
-				// Send emails that have been processed (have summaries)
-				j.sseManager.BroadcastEmailToUser(user.ID, email)
-			}
-
-			// Send a summary notification
-			summary := map[string]interface{}{
-				"count":   len(newProcessedEmails),
-				"message": fmt.Sprintf("%d new emails received and processed", len(newProcessedEmails)),
-			}
-			j.sseManager.BroadcastToUser(user.ID, "email_summary", summary)
-		}
-	}
-
-	j.logger.Info("Completed periodic email sync")
-}
-
-// getMostRecentEmailForUser gets the most recent email for a specific user
-func (j *EmailSyncJob) getMostRecentEmailForUser(userID string) (*model.Email, error) {
-	emails, err := j.emailService.GetEmailsByUser(j.ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(emails) == 0 {
-		return nil, nil
-	}
-
-	// Find the most recent email based on receivedAt timestamp
-	mostRecent := emails[0]
-	for _, email := range emails {
-		if email.ReceivedAt.After(mostRecent.ReceivedAt) {
-			mostRecent = email
-		}
+	if fixed > 0 {
+		j.logger.Info("Reprocessed", fixed, "emails for user", userID)
 	}
-
-	return mostRecent, nil
-}
-
-// getEmailsAfter gets emails that were received after the specified email
-func (j *EmailSyncJob) getEmailsAfter(userID, afterEmailID string) ([]*model.Email, error) {
-	allEmails, err := j.emailService.GetEmailsByUser(j.ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	var newEmails []*model.Email
-
-	// Find the reference email and collect emails that came after it
-	afterFound := false
-	for _, email := range allEmails {
-		if !afterFound {
-			if email.GmailID == afterEmailID {
-				afterFound = true
-			}
-		} else {
-			newEmails = append(newEmails, email)
-		}
-	}
-
-	return newEmails, nil
 }
 
 // GetInterval returns the sync interval
@@ -2,21 +2,37 @@ package sse
 
 import (
 	"context"
-	"encoding/json"
 	"sync"
 	"time"
 
+	"jump-challenge/internal/config"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/service"
 )
 
 // SSEManager manages Server-Sent Event connections
 type SSEManager struct {
-	clients    map[string]map[chan []byte]bool // userID -> connection channels
+	clients    map[string]map[chan []byte]*client // userID -> connection channels
 	clientsMux sync.RWMutex
+	counters   deliveryCounters
 	
 	broadcast chan []byte
 	logger    *logger.Logger
+
+	// Optional; when set, events are filtered by each user's notification preferences
+	notifications service.NotificationService
+
+	// Optional; when set, events for users without a connection are kept until they reconnect
+	queue      repository.PendingEventRepository
+	queueLimit int
+	queueTTL   time.Duration
+	lastSeen   map[string]time.Time // userID -> when their last connection closed, guarded by clientsMux
+
+	// Serializes the deliveries to each user, so the events kept for them are stored in the
+	// order they were broadcast without holding clientsMux while the queue is written
+	deliveries deliveryLocks
 	
 	// Context for managing the SSE service lifecycle
 	ctx    context.Context
@@ -28,7 +44,8 @@ func NewSSEManager(logger *logger.Logger) *SSEManager {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	manager := &SSEManager{
-		clients:   make(map[string]map[chan []byte]bool),
+		clients:   make(map[string]map[chan []byte]*client),
+		lastSeen:  make(map[string]time.Time),
 		broadcast: make(chan []byte, 100), // Buffered channel for broadcasting
 		logger:    logger,
 		ctx:       ctx,
@@ -48,16 +65,16 @@ func (s *SSEManager) AddClient(userID string) chan []byte {
 	
 	// Create user-specific clients map if it doesn't exist
 	if s.clients[userID] == nil {
-		s.clients[userID] = make(map[chan []byte]bool)
+		s.clients[userID] = make(map[chan []byte]*client)
 	}
 	
 	// Create a new channel for this client
-	channel := make(chan []byte, 10) // Buffered channel for this specific client
-	s.clients[userID][channel] = true
+	client := newClient(&s.counters)
+	s.clients[userID][client.channel] = client
 	
 	s.logger.Info("Added SSE client for user:", userID, "total clients:", len(s.clients[userID]))
 	
-	return channel
+	return client.channel
 }
 
 // RemoveClient removes a client connection
@@ -66,16 +83,21 @@ func (s *SSEManager) RemoveClient(userID string, channel chan []byte) {
 	defer s.clientsMux.Unlock()
 	
 	if userClients, exists := s.clients[userID]; exists {
+		client, found := userClients[channel]
+		if !found {
+			return
+		}
 		delete(userClients, channel)
 		
 		// Close the channel to free resources
-		close(channel)
+		client.close()
 		
 		s.logger.Info("Removed SSE client for user:", userID, "remaining clients:", len(userClients))
 		
 		// If this was the last client for the user, remove the user's map
 		if len(userClients) == 0 {
 			delete(s.clients, userID)
+			s.lastSeen[userID] = time.Now()
 			s.logger.Info("Removed empty user SSE map for user:", userID)
 		}
 	}
@@ -83,72 +105,131 @@ func (s *SSEManager) RemoveClient(userID string, channel chan []byte) {
 
 // BroadcastEmailToUser broadcasts an email to a specific user
 func (s *SSEManager) BroadcastEmailToUser(userID string, email *model.Email) {
-	s.clientsMux.RLock()
-	defer s.clientsMux.RUnlock()
-	
-	userClients, exists := s.clients[userID]
-	if !exists {
-		return // No active connections for this user
+	s.Publish(userID, NewEmail{email})
+}
+
+// Publish broadcasts a typed event to a specific user
+func (s *SSEManager) Publish(userID string, event Event) {
+	s.BroadcastToUser(userID, event.EventType(), event)
+}
+
+// UseNotificationPreferences makes broadcasts honor each user's quiet hours and event preferences
+func (s *SSEManager) UseNotificationPreferences(notifications service.NotificationService) {
+	s.notifications = notifications
+}
+
+// UseOfflineQueue keeps up to limit events, for ttl, for each user without a connection, and
+// sends them when the user reconnects
+func (s *SSEManager) UseOfflineQueue(queue repository.PendingEventRepository, limit int, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = config.DefaultSSEQueueTTL
 	}
-	
+	s.queue = queue
+	s.queueLimit = limit
+	s.queueTTL = ttl
+}
+
+// BroadcastToUser broadcasts a generic message to a specific user; prefer Publish, whose
+// payloads are documented
+func (s *SSEManager) BroadcastToUser(userID string, eventType string, data interface{}) {
+	// Only look up preferences for users that are connected or have events kept for them
+	if s.queue == nil && !s.HasUserConnection(userID) {
+		return
+	}
+	if s.notifications != nil && !s.notifications.ShouldNotify(s.ctx, userID, eventType) {
+		s.logger.Info("Suppressed", eventType, "event for user", userID, "by notification preferences")
+		return
+	}
+
 	// Prepare the event data
-	event := map[string]interface{}{
-		"type":  "new_email",
-		"data":  email,
-		"time":  time.Now().Unix(),
+	if _, registered := LookupSchema(eventType); !registered {
+		s.logger.Warn("Broadcasting unregistered SSE event type", eventType)
 	}
-	
-	jsonData, err := json.Marshal(event)
+	jsonData, err := encode(eventType, data)
 	if err != nil {
-		s.logger.Error("Failed to marshal email event:", err)
+		s.logger.Error("Failed to marshal broadcast event:", err)
 		return
 	}
-	
-	// Send to all active connections for this user
-	for channel := range userClients {
-		select {
-		case channel <- jsonData:
-			// Message sent successfully
-		case <-time.After(5 * time.Second):
-			// Timeout - client might be disconnected
-			s.logger.Warn("Timeout sending message to user:", userID)
-		}
+
+	// Holding the user's delivery lock, a connection opening now takes the queue only once
+	// the event is in it, and the user's next event waits for this one
+	unlock := s.deliveries.lock(userID)
+	defer unlock()
+
+	if s.sendToClients(userID, jsonData) {
+		return
 	}
+	// No active connections for this user; the queue is written outside clientsMux, so a slow
+	// store holds up this user's events alone
+	s.enqueue(userID, eventType, jsonData)
 }
 
-// BroadcastToUser broadcasts a generic message to a specific user
-func (s *SSEManager) BroadcastToUser(userID string, eventType string, data interface{}) {
+// sendToClients hands the event to every connection of the user, none of which is waited on,
+// and reports whether there was any
+func (s *SSEManager) sendToClients(userID string, jsonData []byte) bool {
 	s.clientsMux.RLock()
 	defer s.clientsMux.RUnlock()
-	
+
 	userClients, exists := s.clients[userID]
-	if !exists {
-		return // No active connections for this user
+	for _, client := range userClients {
+		if client.send(jsonData) {
+			s.logger.Warn("SSE client of user", userID, "fell behind, dropping its oldest events")
+		}
 	}
-	
-	// Prepare the event data
-	event := map[string]interface{}{
-		"type": eventType,
-		"data": data,
-		"time": time.Now().Unix(),
+	return exists
+}
+
+func (s *SSEManager) enqueue(userID, eventType string, jsonData []byte) {
+	if s.queue == nil {
+		return
 	}
-	
-	jsonData, err := json.Marshal(event)
+	event := model.NewPendingEvent(userID, eventType, jsonData, s.queueTTL)
+	if err := s.queue.Enqueue(s.ctx, event, s.queueLimit); err != nil {
+		s.logger.Error("Failed to keep", eventType, "event for user", userID, ":", err)
+	}
+}
+
+// TakePending returns the events kept for the user while they had no connection, oldest first,
+// for a new connection to send before any other; every event is returned only once
+func (s *SSEManager) TakePending(userID string) [][]byte {
+	if s.queue == nil {
+		return nil
+	}
+	// Waits for an event being kept to be stored
+	unlock := s.deliveries.lock(userID)
+	defer unlock()
+
+	events, err := s.queue.TakeByUserID(s.ctx, userID, time.Now())
 	if err != nil {
-		s.logger.Error("Failed to marshal broadcast event:", err)
-		return
+		s.logger.Error("Failed to load kept events for user", userID, ":", err)
+		return nil
 	}
-	
-	// Send to all active connections for this user
-	for channel := range userClients {
-		select {
-		case channel <- jsonData:
-			// Message sent successfully
-		case <-time.After(5 * time.Second):
-			// Timeout - client might be disconnected
-			s.logger.Warn("Timeout sending broadcast to user:", userID)
-		}
+
+	payloads := make([][]byte, len(events))
+	for i, event := range events {
+		payloads[i] = event.Payload
+	}
+	return payloads
+}
+
+// AwaitsReplay reports whether the user is connected, or disconnected recently enough that
+// events kept for them will still be sent when they reconnect
+func (s *SSEManager) AwaitsReplay(ctx context.Context, userID string) bool {
+	if s.queue == nil {
+		return false
+	}
+	s.clientsMux.Lock()
+	defer s.clientsMux.Unlock()
+
+	if _, connected := s.clients[userID]; connected {
+		return true
+	}
+	lastSeen, seen := s.lastSeen[userID]
+	if seen && time.Since(lastSeen) >= s.queueTTL {
+		delete(s.lastSeen, userID)
+		return false
 	}
+	return seen
 }
 
 // broadcastEvents handles the global broadcast channel
@@ -175,8 +256,8 @@ func (s *SSEManager) Close() {
 	defer s.clientsMux.Unlock()
 	
 	for userID, userClients := range s.clients {
-		for channel := range userClients {
-			close(channel)
+		for _, client := range userClients {
+			client.close()
 		}
 		delete(s.clients, userID)
 	}
@@ -198,4 +279,58 @@ func (s *SSEManager) GetUserConnectionCount(userID string) int {
 // HasUserConnection checks if a user has active SSE connections
 func (s *SSEManager) HasUserConnection(userID string) bool {
 	return s.GetUserConnectionCount(userID) > 0
-}
\ No newline at end of file
+}
+
+// Stats returns the delivery counters along with the number of open connections
+func (s *SSEManager) Stats() DeliveryStats {
+	s.clientsMux.RLock()
+	connections := 0
+	for _, userClients := range s.clients {
+		connections += len(userClients)
+	}
+	s.clientsMux.RUnlock()
+
+	return DeliveryStats{
+		Connections: connections,
+		Sent:        s.counters.sent.Load(),
+		Dropped:     s.counters.dropped.Load(),
+		SlowClients: s.counters.slowClients.Load(),
+	}
+}
+
+// deliveryLocks hold a lock per user with events being delivered, dropped once unused
+type deliveryLocks struct {
+	mutex sync.Mutex
+	locks map[string]*deliveryLock
+}
+
+type deliveryLock struct {
+	sync.Mutex
+	holders int // waiting for or holding the lock
+}
+
+// lock locks the user's deliveries and returns the function unlocking them
+func (l *deliveryLocks) lock(userID string) func() {
+	l.mutex.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*deliveryLock)
+	}
+	userLock, ok := l.locks[userID]
+	if !ok {
+		userLock = &deliveryLock{}
+		l.locks[userID] = userLock
+	}
+	userLock.holders++
+	l.mutex.Unlock()
+
+	userLock.Lock()
+	return func() {
+		userLock.Unlock()
+		l.mutex.Lock()
+		userLock.holders--
+		if userLock.holders == 0 {
+			delete(l.locks, userID)
+		}
+		l.mutex.Unlock()
+	}
+}
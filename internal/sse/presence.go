@@ -0,0 +1,58 @@
+package sse
+
+import (
+	"sort"
+	"time"
+)
+
+// ConnectionInfo describes one open SSE connection
+type ConnectionInfo struct {
+	ConnectedAt     time.Time `json:"connected_at"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	Waiting         int       `json:"waiting"` // events behind a full channel, for a reader catching up
+	Slow            bool      `json:"slow"`    // it fell behind and dropped events
+}
+
+// Presence tells whether a user receives live updates, and over which connections
+type Presence struct {
+	UserID      string           `json:"user_id"`
+	Connected   bool             `json:"connected"`
+	Connections []ConnectionInfo `json:"connections"`
+}
+
+// Presence returns the user's open connections, oldest first
+func (s *SSEManager) Presence(userID string) Presence {
+	s.clientsMux.RLock()
+	defer s.clientsMux.RUnlock()
+
+	return s.presence(userID, time.Now())
+}
+
+// Presences returns the presence of every connected user, by user ID
+func (s *SSEManager) Presences() []Presence {
+	s.clientsMux.RLock()
+	defer s.clientsMux.RUnlock()
+
+	now := time.Now()
+	presences := make([]Presence, 0, len(s.clients))
+	for userID := range s.clients {
+		presences = append(presences, s.presence(userID, now))
+	}
+	sort.Slice(presences, func(i, j int) bool {
+		return presences[i].UserID < presences[j].UserID
+	})
+	return presences
+}
+
+// presence must be called with clientsMux held
+func (s *SSEManager) presence(userID string, now time.Time) Presence {
+	presence := Presence{UserID: userID, Connections: []ConnectionInfo{}}
+	for _, client := range s.clients[userID] {
+		presence.Connections = append(presence.Connections, client.info(now))
+	}
+	sort.Slice(presence.Connections, func(i, j int) bool {
+		return presence.Connections[i].ConnectedAt.Before(presence.Connections[j].ConnectedAt)
+	})
+	presence.Connected = len(presence.Connections) > 0
+	return presence
+}
@@ -0,0 +1,71 @@
+package sse
+
+import (
+	"context"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/service"
+)
+
+// reportCheckInterval is how often the job looks for users missing last week's report; reports
+// are weekly, checking hourly only makes sure a restart doesn't skip one
+const reportCheckInterval = time.Hour
+
+// ReportJob generates each user's inbox report once a week is over
+type ReportJob struct {
+	reportService service.ReportService
+	logger        *logger.Logger
+
+	// Context for managing the job lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewReportJob(reportService service.ReportService, logger *logger.Logger) *ReportJob {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ReportJob{
+		reportService: reportService,
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Start begins the periodic report job
+func (j *ReportJob) Start() {
+	j.logger.Info("Starting weekly report job")
+
+	j.RunReports(time.Now())
+
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			j.RunReports(now)
+		case <-j.ctx.Done():
+			j.logger.Info("Weekly report job stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the periodic report job
+func (j *ReportJob) Stop() {
+	j.cancel()
+}
+
+// RunReports generates the reports of the week before now's that are still missing - exported for testing
+func (j *ReportJob) RunReports(now time.Time) {
+	generated, err := j.reportService.GenerateWeeklyReports(j.ctx, now)
+	if err != nil {
+		j.logger.Error("Failed to generate weekly reports:", err)
+		return
+	}
+	if generated > 0 {
+		j.logger.Info("Weekly reports generated:", generated)
+	}
+}
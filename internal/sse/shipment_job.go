@@ -0,0 +1,79 @@
+package sse
+
+import (
+	"context"
+	"time"
+
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/service"
+)
+
+// ShipmentTrackingJob periodically asks the carriers about packages still on their way
+type ShipmentTrackingJob struct {
+	trackingClient  service.TrackingClient // nil when no carrier API is configured
+	shipmentService service.ShipmentService
+	logger          *logger.Logger
+	interval        time.Duration
+
+	// Context for managing the job lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewShipmentTrackingJob creates a new tracking job that runs every interval, the default when zero
+func NewShipmentTrackingJob(trackingClient service.TrackingClient, shipmentService service.ShipmentService, interval time.Duration, logger *logger.Logger) *ShipmentTrackingJob {
+	if interval <= 0 {
+		interval = config.DefaultTrackingInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ShipmentTrackingJob{
+		trackingClient:  trackingClient,
+		shipmentService: shipmentService,
+		logger:          logger,
+		interval:        interval,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Start begins the periodic tracking job
+func (j *ShipmentTrackingJob) Start() {
+	if j.trackingClient == nil {
+		j.logger.Info("Shipment tracking disabled: no tracking client is configured")
+		return
+	}
+	j.logger.Info("Starting shipment tracking job with interval:", j.interval.String())
+
+	j.RunTracking()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.RunTracking()
+		case <-j.ctx.Done():
+			j.logger.Info("Shipment tracking job stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the periodic tracking job
+func (j *ShipmentTrackingJob) Stop() {
+	j.cancel()
+}
+
+// RunTracking executes a single pass over the undelivered shipments - exported for testing
+func (j *ShipmentTrackingJob) RunTracking() {
+	delivered, err := j.shipmentService.RefreshStatuses(j.ctx)
+	if err != nil {
+		j.logger.Error("Failed to refresh shipment statuses:", err)
+		return
+	}
+	j.logger.Info("Shipment tracking pass complete - delivered:", delivered)
+}
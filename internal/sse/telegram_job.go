@@ -0,0 +1,83 @@
+package sse
+
+import (
+	"context"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/service"
+)
+
+// telegramPollTimeout is how long a single getUpdates call waits for new messages
+const telegramPollTimeout = 30 * time.Second
+
+// TelegramBotJob long-polls the Telegram Bot API and hands incoming messages to the bot
+type TelegramBotJob struct {
+	telegramClient  service.TelegramClient // nil when no bot token is configured
+	telegramService service.TelegramService
+	logger          *logger.Logger
+	offset          int64 // next update to ask for
+
+	// Context for managing the job lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewTelegramBotJob creates a new telegram bot job
+func NewTelegramBotJob(telegramClient service.TelegramClient, telegramService service.TelegramService, logger *logger.Logger) *TelegramBotJob {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &TelegramBotJob{
+		telegramClient:  telegramClient,
+		telegramService: telegramService,
+		logger:          logger,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Start polls for messages until the job is stopped
+func (j *TelegramBotJob) Start() {
+	if j.telegramClient == nil {
+		j.logger.Info("Telegram bot disabled: TELEGRAM_BOT_TOKEN is not set")
+		return
+	}
+	j.logger.Info("Starting telegram bot job")
+
+	for {
+		if err := j.Poll(); err != nil && j.ctx.Err() == nil {
+			j.logger.Error("Failed to get telegram updates:", err)
+			// Back off so an outage or a bad token does not spin
+			select {
+			case <-time.After(5 * time.Second):
+			case <-j.ctx.Done():
+			}
+		}
+
+		if j.ctx.Err() != nil {
+			j.logger.Info("Telegram bot job stopped")
+			return
+		}
+	}
+}
+
+// Stop stops the telegram bot job
+func (j *TelegramBotJob) Stop() {
+	j.cancel()
+}
+
+// Poll fetches one batch of messages and handles them in order - exported for testing
+func (j *TelegramBotJob) Poll() error {
+	messages, err := j.telegramClient.GetUpdates(j.ctx, j.offset, telegramPollTimeout)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		j.offset = message.UpdateID + 1
+		if message.ChatID != 0 {
+			j.telegramService.HandleMessage(j.ctx, message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Prefix is the URL path the assets are served under
+const Prefix = "/static/"
+
+// Cache-Control of the assets: a fingerprinted name always serves the same content, so caches
+// and CDNs keep it for good, while the plain name is checked again on every use
+const (
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "public, no-cache"
+)
+
+// Assets serves the static files both under their own names and under fingerprinted ones,
+// e.g. favicon.3f2a9c1b07.svg, which the pages link through Path. The files are
+// read and hashed once, when the Assets are built, so a changed file takes a restart.
+type Assets struct {
+	byName   map[string]*asset // by the file's own name
+	byHashed map[string]*asset // by its fingerprinted name
+}
+
+type asset struct {
+	name    string
+	hashed  string
+	etag    string
+	content []byte
+}
+
+// NewAssets reads and hashes every file of fsys
+func NewAssets(fsys fs.FS) (*Assets, error) {
+	assets := &Assets{byName: make(map[string]*asset), byHashed: make(map[string]*asset)}
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])[:10]
+		ext := path.Ext(name)
+		file := &asset{
+			name:    name,
+			hashed:  strings.TrimSuffix(name, ext) + "." + hash + ext,
+			etag:    `"` + hash + `"`,
+			content: content,
+		}
+		assets.byName[file.name] = file
+		assets.byHashed[file.hashed] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// Path returns the URL of the named asset under its fingerprinted name, or under its own name
+// when there is no such asset
+func (a *Assets) Path(name string) string {
+	if file, ok := a.byName[name]; ok {
+		return Prefix + file.hashed
+	}
+	return Prefix + name
+}
+
+// ServeHTTP serves the asset the request path names under Prefix
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, Prefix)
+	cacheControl := immutableCacheControl
+	file, ok := a.byHashed[name]
+	if !ok {
+		cacheControl = revalidateCacheControl
+		if file, ok = a.byName[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", file.etag)
+	http.ServeContent(w, r, file.name, time.Time{}, bytes.NewReader(file.content))
+}
@@ -0,0 +1,8 @@
+package static
+
+import "embed"
+
+// FS holds the static assets served under /static
+//
+//go:embed *.svg *.js
+var FS embed.FS
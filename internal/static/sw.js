@@ -0,0 +1,37 @@
+// Service worker that turns Web Push messages into desktop notifications
+self.addEventListener('push', event => {
+    if (!event.data) {
+        return;
+    }
+    const message = event.data.json();
+
+    event.waitUntil(
+        self.clients.matchAll({ type: 'window', includeUncontrolled: true }).then(windows => {
+            // The open app already shows the event over SSE
+            if (windows.some(client => client.focused)) {
+                return;
+            }
+            return self.registration.showNotification(message.title, {
+                body: message.body,
+                tag: message.tag,
+                icon: '/static/favicon.svg',
+                data: { url: message.url || '/app' }
+            });
+        })
+    );
+});
+
+self.addEventListener('notificationclick', event => {
+    event.notification.close();
+    const url = event.notification.data.url;
+
+    event.waitUntil(
+        self.clients.matchAll({ type: 'window', includeUncontrolled: true }).then(windows => {
+            const existing = windows.find(client => new URL(client.url).pathname === url);
+            if (existing) {
+                return existing.focus();
+            }
+            return self.clients.openWindow(url);
+        })
+    );
+});
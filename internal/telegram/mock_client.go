@@ -0,0 +1,56 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"jump-challenge/internal/model"
+)
+
+// MockTelegramClient is a mock implementation of TelegramClient for testing
+type MockTelegramClient struct {
+	GetUpdatesFunc func(ctx context.Context, offset int64, timeout time.Duration) ([]*model.TelegramMessage, error)
+
+	// Sent records every message by chat ID
+	Sent  map[int64][]string
+	mutex sync.Mutex
+}
+
+func NewMockTelegramClient() *MockTelegramClient {
+	return &MockTelegramClient{Sent: make(map[int64][]string)}
+}
+
+func (m *MockTelegramClient) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]*model.TelegramMessage, error) {
+	if m.GetUpdatesFunc != nil {
+		return m.GetUpdatesFunc(ctx, offset, timeout)
+	}
+
+	// Default mock behavior: wait out the poll without updates
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(timeout):
+		return nil, nil
+	}
+}
+
+func (m *MockTelegramClient) SendMessage(ctx context.Context, chatID int64, text string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.Sent[chatID] = append(m.Sent[chatID], text)
+	return nil
+}
+
+// LastMessage returns the most recent message sent to the chat
+func (m *MockTelegramClient) LastMessage(chatID int64) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	messages := m.Sent[chatID]
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1]
+}
@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+)
+
+// telegramClient calls the Telegram Bot API
+type telegramClient struct {
+	baseURL    string // API URL including the bot token
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+func NewTelegramClient(token string, logger *logger.Logger) service.TelegramClient {
+	return &telegramClient{
+		baseURL:    "https://api.telegram.org/bot" + token,
+		httpClient: &http.Client{},
+		logger:     logger,
+	}
+}
+
+// Bot API request/response structures
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+type message struct {
+	Chat           chat     `json:"chat"`
+	Text           string   `json:"text"`
+	ReplyToMessage *message `json:"reply_to_message"`
+}
+
+type chat struct {
+	ID int64 `json:"id"`
+}
+
+func (t *telegramClient) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]*model.TelegramMessage, error) {
+	var updates []update
+	err := t.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*model.TelegramMessage, 0, len(updates))
+	for _, u := range updates {
+		msg := &model.TelegramMessage{UpdateID: u.UpdateID}
+		// Other update kinds still advance the offset but carry no command
+		if u.Message != nil {
+			msg.ChatID = u.Message.Chat.ID
+			msg.Text = u.Message.Text
+			if u.Message.ReplyToMessage != nil {
+				msg.ReplyToText = u.Message.ReplyToMessage.Text
+			}
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+func (t *telegramClient) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return t.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}, nil)
+}
+
+// call posts params to a Bot API method and decodes its result into out, when given
+func (t *telegramClient) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		// The URL embeds the bot token, so leave it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to call telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode telegram %s response: %w", method, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, result.Description)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(result.Result, out); err != nil {
+			return fmt.Errorf("failed to decode telegram %s result: %w", method, err)
+		}
+	}
+	return nil
+}
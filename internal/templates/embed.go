@@ -0,0 +1,8 @@
+package templates
+
+import "embed"
+
+// FS holds the HTML pages, their layouts and the partials rendered for HTMX, compiled into the binary
+//
+//go:embed *.html layouts/*.html partials/*.html
+var FS embed.FS
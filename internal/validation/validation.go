@@ -0,0 +1,334 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"jump-challenge/internal/apierror"
+)
+
+// Validator checks `validate` struct tags and plugs into echo as e.Validator.
+//
+// Supported rules, comma separated and applied in order:
+//
+//	required      value must not be the zero value
+//	omitempty     skip the remaining rules when the value is the zero value
+//	min=N, max=N  bounds on numbers, or on the length of strings, slices and maps
+//	oneof=a b c   value must be one of the space separated options
+//	dive          apply the remaining rules to every element of a slice
+//
+// Nested structs and non-nil struct pointers are validated recursively. The tags of each type
+// are checked once, by Check or on the type's first validation; a type with a rule the
+// validator doesn't know fails every validation with the error Check returns.
+type Validator struct{}
+
+// New creates a validator
+func New() *Validator {
+	return &Validator{}
+}
+
+// Validate returns an apierror listing every invalid field, or nil when i is valid
+func (v *Validator) Validate(i any) error {
+	fields, err := Struct(i)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return apierror.InvalidFields(fields)
+	}
+	return nil
+}
+
+// Check checks the tags of the types of the values, e.g. every request type at startup, so
+// a mistyped rule is found before a request needs it. Values that aren't structs or struct
+// pointers are ignored.
+func Check(values ...any) error {
+	var errs []error
+	for _, value := range values {
+		if t := structType(reflect.TypeOf(value)); t != nil {
+			if _, err := compile(t); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Struct validates a struct or struct pointer and returns its field errors, or an error when
+// its tags are invalid
+func Struct(i any) ([]apierror.FieldError, error) {
+	value := reflect.ValueOf(i)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	plan, err := compile(value.Type())
+	if err != nil {
+		return nil, err
+	}
+	var errs []apierror.FieldError
+	plan.validate(value, "", &errs)
+	return errs, nil
+}
+
+// rule is one parsed rule of a tag
+type rule struct {
+	key     string
+	param   string
+	limit   float64  // of min and max
+	options []string // of oneof
+}
+
+// fieldRules are the rules of one field, and the rules of its type when it is a struct
+type fieldRules struct {
+	index  int
+	name   string
+	rules  []rule
+	nested *structRules // of the field, or of its elements after dive; nil when not a struct
+}
+
+// structRules are the parsed tags of a struct type
+type structRules struct {
+	fields []fieldRules
+	err    error
+}
+
+// compiled caches the structRules of every type seen
+var (
+	compiled      = make(map[reflect.Type]*structRules)
+	compiledMutex sync.Mutex
+)
+
+// compile parses the tags of the struct type and of the structs it nests, once
+func compile(t reflect.Type) (*structRules, error) {
+	compiledMutex.Lock()
+	defer compiledMutex.Unlock()
+	return compileLocked(t)
+}
+
+func compileLocked(t reflect.Type) (*structRules, error) {
+	if plan, ok := compiled[t]; ok {
+		return plan, plan.err
+	}
+
+	plan := &structRules{}
+	// Stored before its fields are parsed, so a type nesting itself ends the recursion
+	compiled[t] = plan
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("validate")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		rules, elem, err := parseRules(field.Type, splitRules(tag))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("validation: field %s.%s: %w", t.Name(), field.Name, err))
+		}
+		compiledField := fieldRules{index: i, name: fieldName(field), rules: rules}
+		if nested := structType(elem); nested != nil {
+			if compiledField.nested, err = compileLocked(nested); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(compiledField.rules) > 0 || compiledField.nested != nil {
+			plan.fields = append(plan.fields, compiledField)
+		}
+	}
+	plan.err = errors.Join(errs...)
+	return plan, plan.err
+}
+
+// parseRules parses the rules for a value of type t, returning the type the last ones apply
+// to, which is the element type after dive
+func parseRules(t reflect.Type, tags []string) ([]rule, reflect.Type, error) {
+	var rules []rule
+	for _, tag := range tags {
+		key, param, _ := strings.Cut(tag, "=")
+		parsed := rule{key: key, param: param}
+
+		switch key {
+		case "", "omitempty", "required":
+		case "min", "max":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, t, fmt.Errorf("invalid %s parameter %q", key, param)
+			}
+			if !bounded(indirect(t).Kind()) {
+				return nil, t, fmt.Errorf("%s on a %s", key, t)
+			}
+			parsed.limit = limit
+		case "oneof":
+			parsed.options = strings.Fields(param)
+			if len(parsed.options) == 0 {
+				return nil, t, errors.New("oneof without options")
+			}
+		case "dive":
+			if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+				return nil, t, fmt.Errorf("dive on a %s", t)
+			}
+			t = t.Elem()
+		default:
+			return nil, t, fmt.Errorf("unknown rule %q", key)
+		}
+		rules = append(rules, parsed)
+	}
+	return rules, t, nil
+}
+
+func (p *structRules) validate(value reflect.Value, prefix string, errs *[]apierror.FieldError) {
+	for _, field := range p.fields {
+		validateValue(value.Field(field.index), prefix+field.name, field.rules, field.nested, errs)
+	}
+}
+
+func validateValue(value reflect.Value, name string, rules []rule, nested *structRules, errs *[]apierror.FieldError) {
+	for i, rule := range rules {
+		switch rule.key {
+		case "omitempty":
+			if value.IsZero() {
+				return
+			}
+		case "required":
+			if value.IsZero() {
+				addError(errs, name, "is required")
+				return
+			}
+		case "min", "max":
+			if msg, ok := checkBound(reflect.Indirect(value), rule); !ok {
+				addError(errs, name, msg)
+				return
+			}
+		case "oneof":
+			if !contains(rule.options, scalarString(reflect.Indirect(value))) {
+				addError(errs, name, "must be one of: "+strings.Join(rule.options, ", "))
+				return
+			}
+		case "dive":
+			for j := 0; j < value.Len(); j++ {
+				validateValue(value.Index(j), fmt.Sprintf("%s[%d]", name, j), rules[i+1:], nested, errs)
+			}
+			return
+		}
+	}
+
+	// Descend into nested structs
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if nested != nil && value.Kind() == reflect.Struct {
+		nested.validate(value, name+".", errs)
+	}
+}
+
+// checkBound applies a min or max rule, returning the failure message when it does not hold
+func checkBound(value reflect.Value, rule rule) (string, bool) {
+	var actual float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		actual, unit = float64(len([]rune(value.String()))), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual, unit = float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	default:
+		return "", true // a nil pointer
+	}
+
+	if rule.key == "min" && actual < rule.limit {
+		return "must be at least " + rule.param + unit, false
+	}
+	if rule.key == "max" && actual > rule.limit {
+		return "must be at most " + rule.param + unit, false
+	}
+	return "", true
+}
+
+// bounded reports whether min and max apply to values of the kind
+func bounded(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// indirect returns the type pointers of t point to
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// structType returns the struct type t is or points to, nil for other types and time.Time
+func structType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	t = indirect(t)
+	if t.Kind() != reflect.Struct || t.PkgPath() == "time" {
+		return nil
+	}
+	return t
+}
+
+// splitRules splits a tag into its comma separated rules
+func splitRules(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+// fieldName reports the field the way clients send it: json name, then query name, then Go name
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func scalarString(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func addError(errs *[]apierror.FieldError, field, message string) {
+	*errs = append(*errs, apierror.FieldError{Field: field, Message: message})
+}
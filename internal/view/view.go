@@ -0,0 +1,152 @@
+package view
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/static"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Partial names, as declared with {{define}} in templates/partials
+const (
+	CategoryList = "category_list"
+	EmailList    = "email_list"
+	SharedEmail  = "shared_email" // full page behind a public share link
+)
+
+// Page names, the files at the root of templates rendered with a Page
+const (
+	HomePage = "index.html"
+	AppPage  = "app.html"
+	DocsPage = "docs.html"
+)
+
+// Renderer renders the HTML pages and the partials returned to HTMX clients, and plugs into
+// echo as e.Renderer. Templates are parsed once, when the Renderer is built.
+type Renderer struct {
+	templates *template.Template            // the layouts and partials
+	pages     map[string]*template.Template // each page with its own copy of them
+}
+
+// New parses the layouts under layouts/ and the partials under partials/ in fsys, then every
+// page at its root. A page fills in the blocks of a layout by calling it, e.g.
+// {{template "layout" .}}; a page that doesn't is rendered as it is.
+func New(fsys fs.FS, assets *static.Assets) (*Renderer, error) {
+	templates, err := template.New("").Funcs(template.FuncMap{
+		"formatDate":    formatDate,
+		"categoryClass": categoryClass,
+		"asset":         assets.Path,
+	}).ParseFS(fsys, "layouts/*.html", "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		page, err := template.Must(templates.Clone()).ParseFS(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		pages[name] = page
+	}
+	return &Renderer{templates: templates, pages: pages}, nil
+}
+
+// Render executes the named page or partial
+func (r *Renderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	if page, ok := r.pages[name]; ok {
+		return page.ExecuteTemplate(w, name, data)
+	}
+	return r.templates.ExecuteTemplate(w, name, data)
+}
+
+// Page is the data pages are rendered with
+type Page struct {
+	User       *model.User       // nil when signed out
+	Categories []*model.Category // the user's
+	Features   map[string]bool   // the optional features the deployment has set up, e.g. "push"
+}
+
+// PageUser is the part of the user a page's scripts may see, without their tokens
+type PageUser struct {
+	ID             string `json:"id"`
+	Email          string `json:"email"`
+	Name           string `json:"name"`
+	OnboardingStep string `json:"onboarding_step"` // "done" once through the onboarding wizard
+}
+
+// PageData is what a page's scripts get as window.serverData
+type PageData struct {
+	User       *PageUser         `json:"user"`
+	Categories []*model.Category `json:"categories"`
+	Features   map[string]bool   `json:"features"`
+}
+
+// ServerData is the data handed to the page's scripts, so they don't have to ask for it
+func (p Page) ServerData() PageData {
+	data := PageData{Categories: p.Categories, Features: p.Features}
+	if data.Categories == nil {
+		data.Categories = []*model.Category{}
+	}
+	if p.User != nil {
+		data.User = &PageUser{ID: p.User.ID, Email: p.User.Email, Name: p.User.Name, OnboardingStep: p.User.Onboarding()}
+	}
+	return data
+}
+
+// Emails is the data of the email list partial
+type Emails struct {
+	Emails     []*model.Email
+	categories map[string]*model.Category
+}
+
+// NewEmails pairs emails with the categories used to label them
+func NewEmails(emails []*model.Email, categories []*model.Category) Emails {
+	byID := make(map[string]*model.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	return Emails{Emails: emails, categories: byID}
+}
+
+// CategoryName labels an email's category, falling back to Uncategorized
+func (e Emails) CategoryName(categoryID string) string {
+	if category, ok := e.categories[categoryID]; ok {
+		return category.Name
+	}
+	return "Uncategorized"
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("Jan 2, 2006")
+}
+
+// categoryClass picks the CSS class that colors a category badge, mirroring app.html
+func categoryClass(name string) string {
+	normalized := strings.ToLower(name)
+	switch {
+	case strings.Contains(normalized, "work"):
+		return "category-work"
+	case strings.Contains(normalized, "personal"):
+		return "category-personal"
+	case strings.Contains(normalized, "financ"):
+		return "category-financial"
+	case strings.Contains(normalized, "sales"):
+		return "category-sales"
+	case strings.Contains(normalized, "newsletter"):
+		return "category-newsletters"
+	default:
+		return "category-all"
+	}
+}
@@ -0,0 +1,245 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountMerge(t *testing.T) {
+	ctx := context.Background()
+	container, _, _ := newTestApp(t)
+
+	user := model.NewUser("google_1", "ada@example.com", "Ada", "access_token", "refresh_token", time.Time{})
+	assert.NoError(t, container.UserRepo.Create(ctx, user))
+	duplicate := model.NewUser("google_2", "ada@work.example", "Ada", "access_token", "refresh_token", time.Time{})
+	duplicate.TimeZone = "Europe/Lisbon"
+	duplicate.BlockSender("spam@example.com")
+	assert.NoError(t, container.UserRepo.Create(ctx, duplicate))
+
+	work, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Work emails")
+	assert.NoError(t, err)
+	duplicateWork, err := container.CategoryService.CreateCategory(ctx, duplicate.ID, "work", "Work emails")
+	assert.NoError(t, err)
+	travel, err := container.CategoryService.CreateCategory(ctx, duplicate.ID, "Travel", "Trips")
+	assert.NoError(t, err)
+
+	create := func(userID, gmailID, categoryID string) *model.Email {
+		email := model.NewEmail(userID, gmailID, "a@example.com", gmailID, "body", time.Now().Add(-time.Hour))
+		email.CategoryID = categoryID
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		return email
+	}
+	kept := create(user.ID, "msg_both", work.ID)
+	create(duplicate.ID, "msg_both", duplicateWork.ID)
+	flight := create(duplicate.ID, "msg_flight", travel.ID)
+	meeting := create(duplicate.ID, "msg_meeting", duplicateWork.ID)
+
+	automation := model.NewAutomation(duplicate.ID, duplicateWork.ID, "archive", "", 7)
+	assert.NoError(t, container.AutomationRepo.Create(ctx, automation))
+	assert.NoError(t, container.ViewRepo.Create(ctx, model.NewSavedView(user.ID, "Unread", model.EmailFilter{})))
+	assert.NoError(t, container.ViewRepo.Create(ctx, model.NewSavedView(duplicate.ID, "Unread", model.EmailFilter{})))
+	trips := model.NewSavedView(duplicate.ID, "Trips", model.EmailFilter{CategoryID: travel.ID})
+	assert.NoError(t, container.ViewRepo.Create(ctx, trips))
+	assert.NoError(t, container.VIPRepo.Save(ctx, &model.VIPSender{UserID: duplicate.ID, Address: "boss@example.com", CreatedAt: time.Now()}))
+
+	call := func(userID, method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		rec := serve(container, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+	mergeCode := func(userID string) string {
+		status, body := call(userID, http.MethodPost, "/me/merge-code", "")
+		assert.Equal(t, http.StatusCreated, status, string(body))
+		var code model.AccountMergeCode
+		assert.NoError(t, json.Unmarshal(body, &code))
+		assert.True(t, code.ExpiresAt.After(time.Now()))
+		return code.Code
+	}
+
+	// The code is redeemed from the account to keep, never the one it was created in
+	code := mergeCode(duplicate.ID)
+	status, _ := call(duplicate.ID, http.MethodPost, "/me/merge", `{"code":"`+code+`"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(user.ID, http.MethodPost, "/me/merge", `{"code":"`+code+`"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(user.ID, http.MethodPost, "/me/merge", `{"code":"unknown"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	code = mergeCode(duplicate.ID)
+	status, body := call(user.ID, http.MethodPost, "/me/merge", `{"code":"`+code+`"}`)
+	assert.Equal(t, http.StatusOK, status, string(body))
+	var merge model.AccountMerge
+	assert.NoError(t, json.Unmarshal(body, &merge))
+	assert.Equal(t, model.AccountMerge{
+		MergedUserID: duplicate.ID,
+		MergedEmail:  "ada@work.example",
+		Emails:       2,
+		Categories:   2,
+		Automations:  1,
+		SavedViews:   1,
+		VIPSenders:   1,
+	}, merge)
+
+	// The duplicate is gone and its code can't be used again
+	_, err = container.UserRepo.FindByID(ctx, duplicate.ID)
+	assertNotFound(t, err)
+	status, _ = call(user.ID, http.MethodPost, "/me/merge", `{"code":"`+code+`"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Emails keep the categories they were filed under, copied or merged with same-named ones
+	categories, err := container.CategoryService.GetAllCategories(ctx, user.ID)
+	assert.NoError(t, err)
+	owned := map[string]string{}
+	for _, category := range categories {
+		if category.UserID == user.ID {
+			owned[category.Name] = category.ID
+		}
+	}
+	assert.Len(t, owned, 2)
+	assert.Equal(t, work.ID, owned["Work"])
+	assert.NotEqual(t, travel.ID, owned["Travel"])
+	_, err = container.CategoryRepo.FindByID(ctx, travel.ID)
+	assertNotFound(t, err)
+
+	emails, err := container.EmailRepo.FindByUserID(ctx, user.ID, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{kept.ID, flight.ID, meeting.ID}, emailIDs(emails))
+	for _, email := range emails {
+		switch email.ID {
+		case flight.ID:
+			assert.Equal(t, owned["Travel"], email.CategoryID)
+		default:
+			assert.Equal(t, work.ID, email.CategoryID)
+		}
+	}
+
+	automations, err := container.AutomationRepo.FindByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, automations, 1) {
+		assert.Equal(t, automation.Action, automations[0].Action)
+		assert.Equal(t, work.ID, automations[0].CategoryID)
+	}
+	_, err = container.AutomationRepo.FindByID(ctx, automation.ID)
+	assertNotFound(t, err)
+	views, err := container.ViewRepo.FindByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, views, 2)
+	vips, err := container.VIPRepo.FindByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, vips, 1)
+
+	merged, err := container.UserRepo.FindByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Lisbon", merged.TimeZone)
+	assert.Equal(t, "spam@example.com", merged.BlockedSenders)
+}
+
+func TestAccountMergeRejectsOrganizationMembers(t *testing.T) {
+	ctx := context.Background()
+	container, _, _ := newTestApp(t)
+
+	user := model.NewUser("google_1", "ada@example.com", "Ada", "access_token", "refresh_token", time.Time{})
+	assert.NoError(t, container.UserRepo.Create(ctx, user))
+	duplicate := model.NewUser("google_2", "ada@work.example", "Ada", "access_token", "refresh_token", time.Time{})
+	assert.NoError(t, container.UserRepo.Create(ctx, duplicate))
+	_, err := container.OrgService.CreateOrganization(ctx, duplicate.ID, "Acme")
+	assert.NoError(t, err)
+
+	code, err := container.AccountMerge.CreateMergeCode(ctx, duplicate.ID)
+	assert.NoError(t, err)
+	_, err = container.AccountMerge.Merge(ctx, user.ID, code.Code)
+	assert.Error(t, err)
+	_, err = container.UserRepo.FindByID(ctx, duplicate.ID)
+	assert.NoError(t, err)
+}
+
+// failingAutomationRepository fails to create automations while fail is set
+type failingAutomationRepository struct {
+	repository.AutomationRepository
+	fail bool
+}
+
+func (r *failingAutomationRepository) Create(ctx context.Context, automation *model.Automation) error {
+	if r.fail {
+		return errors.New("connection reset")
+	}
+	return r.AutomationRepository.Create(ctx, automation)
+}
+
+func TestAccountMergeFailingPartwayKeepsData(t *testing.T) {
+	ctx := context.Background()
+	container, _, _ := newTestApp(t)
+
+	automationRepo := &failingAutomationRepository{AutomationRepository: container.AutomationRepo, fail: true}
+	merger := service.NewAccountMergeService(container.MergeCodeRepo, container.UserRepo, container.CategoryRepo, container.EmailRepo,
+		container.OrgRepo, automationRepo, container.CleanupPolicyRepo, container.ViewRepo, container.VIPRepo, container.RetentionRepo,
+		container.PreferenceRepo, container.Logger)
+
+	user := model.NewUser("google_1", "ada@example.com", "Ada", "access_token", "refresh_token", time.Time{})
+	assert.NoError(t, container.UserRepo.Create(ctx, user))
+	duplicate := model.NewUser("google_2", "ada@work.example", "Ada", "access_token", "refresh_token", time.Time{})
+	assert.NoError(t, container.UserRepo.Create(ctx, duplicate))
+	travel, err := container.CategoryService.CreateCategory(ctx, duplicate.ID, "Travel", "Trips")
+	assert.NoError(t, err)
+	flight := model.NewEmail(duplicate.ID, "msg_flight", "a@example.com", "Flight", "body", time.Now().Add(-time.Hour))
+	flight.CategoryID = travel.ID
+	assert.NoError(t, container.EmailRepo.Create(ctx, flight))
+	automation := model.NewAutomation(duplicate.ID, travel.ID, "archive", "", 7)
+	assert.NoError(t, container.AutomationRepo.Create(ctx, automation))
+
+	// Nothing is deleted from the duplicate before it exists for the user
+	code, err := merger.CreateMergeCode(ctx, duplicate.ID)
+	assert.NoError(t, err)
+	_, err = merger.Merge(ctx, user.ID, code.Code)
+	assert.Error(t, err)
+	_, err = container.UserRepo.FindByID(ctx, duplicate.ID)
+	assert.NoError(t, err)
+	_, err = container.AutomationRepo.FindByID(ctx, automation.ID)
+	assert.NoError(t, err)
+	_, err = container.CategoryRepo.FindByID(ctx, travel.ID)
+	assert.NoError(t, err)
+
+	// Merging again finishes it, without copying the categories twice
+	automationRepo.fail = false
+	code, err = merger.CreateMergeCode(ctx, duplicate.ID)
+	assert.NoError(t, err)
+	merge, err := merger.Merge(ctx, user.ID, code.Code)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, merge.Automations)
+
+	categories, err := container.CategoryRepo.FindByUserID(ctx, user.ID)
+	assert.NoError(t, err)
+	var copied []*model.Category
+	for _, category := range categories {
+		if category.UserID == user.ID {
+			copied = append(copied, category)
+		}
+	}
+	if assert.Len(t, copied, 1) {
+		email, err := container.EmailRepo.FindByID(ctx, flight.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, email.UserID)
+		assert.Equal(t, copied[0].ID, email.CategoryID)
+		automations, err := container.AutomationRepo.FindByUserID(ctx, user.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, automations, 1) {
+			assert.Equal(t, copied[0].ID, automations[0].CategoryID)
+		}
+	}
+	_, err = container.UserRepo.FindByID(ctx, duplicate.ID)
+	assertNotFound(t, err)
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIDebugRecordsAndReplaysCalls(t *testing.T) {
+	ctx := context.Background()
+	calls := memory.NewInMemoryAICallRepository()
+	fake := newFakeAIServer(t, openAIAnswer("Work"), geminiAnswer("Shopping"), cannedResponse{http.StatusTooManyRequests, `{"error":"slow down"}`})
+	client := ai.NewAIClientWithBaseURL(ai.ProviderOpenAI, "sk-test", "", fake.URL, logger.New())
+	client.UseDebugStore(calls)
+
+	// Prompts are recorded without addresses, link tokens or long numbers
+	body := "Hi ana@example.com, reset at https://shop.example/reset?token=abc123 with code 4821 9930, account 1234-5678-9012"
+	_, err := client.ClassifyEmail(ctx, body, aiTestCategories)
+	assert.NoError(t, err)
+
+	recorded, err := calls.FindRecent(ctx, 0)
+	assert.NoError(t, err)
+	if !assert.Len(t, recorded, 1) {
+		return
+	}
+	call := recorded[0]
+	assert.Equal(t, ai.ProviderOpenAI, call.Provider)
+	assert.Equal(t, model.AIOperationClassify, call.Operation)
+	assert.Equal(t, 20, call.MaxTokens)
+	assert.Equal(t, "Work", call.Response)
+	assert.NotEmpty(t, call.System)
+	assert.Contains(t, call.Prompt, "Hi [email], reset at https://shop.example/reset?[redacted] with code [number], account [number]")
+	assert.NotContains(t, call.Prompt, "abc123")
+
+	// The recorded prompt is replayed against another provider and model
+	keys := map[string]string{ai.ProviderGemini: "gemini-key"}
+	debug := ai.NewDebugServiceWithBaseURL(calls, func(provider string) string { return keys[provider] }, fake.URL, logger.New())
+	replay, err := debug.Replay(ctx, call.ID, ai.ProviderGemini, "gemini-test")
+	assert.NoError(t, err)
+	assert.Equal(t, "Shopping", replay.Response)
+	assert.Equal(t, "Work", replay.Call.Response)
+	request := fake.lastRequest(t)
+	assert.Equal(t, "/models/gemini-test:generateContent", request.path)
+	sent, _ := json.Marshal(request.body)
+	assert.Contains(t, string(sent), "Hi [email], reset at")
+
+	// A failing provider is part of the comparison
+	replay, err = debug.Replay(ctx, call.ID, ai.ProviderGemini, "")
+	assert.NoError(t, err)
+	assert.Contains(t, replay.Error, "rate limit")
+
+	_, err = debug.Replay(ctx, call.ID, "no-such-provider", "")
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+	_, err = debug.Replay(ctx, call.ID, ai.ProviderOpenAI, "")
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+	_, err = debug.Replay(ctx, "missing", "", "")
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	// Old calls are purged
+	purged, err := debug.PurgeCalls(ctx, time.Hour)
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+	old := model.NewAICall(ai.ProviderOpenAI, "", model.AIOperationSummarize, "system", "prompt", 150)
+	old.CreatedAt = time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, calls.Create(ctx, old))
+	purged, err = debug.PurgeCalls(ctx, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+}
+
+func TestAIDebugEndpointsAreForOperators(t *testing.T) {
+	ctx := context.Background()
+	container, _, _ := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AdminEmails = []string{"ops@example.com"}
+	}))
+
+	operator := model.NewUser("google_1", "ops@example.com", "Ops", "access_token", "refresh_token", time.Time{})
+	member := model.NewUser("google_2", "member@example.com", "Member", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, operator)
+	container.UserRepo.Create(ctx, member)
+	call := model.NewAICall(ai.ProviderOpenAI, "", model.AIOperationClassify, "system", "prompt", 20)
+	assert.NoError(t, container.AICallRepo.Create(ctx, call))
+
+	request := func(user *model.User, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+		return serve(container, req)
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(member, http.MethodGet, "/admin/ai-calls", "").Code)
+	assert.Equal(t, http.StatusForbidden, request(member, http.MethodGet, "/admin/ai-calls/"+call.ID, "").Code)
+	assert.Equal(t, http.StatusForbidden, request(member, http.MethodPost, "/admin/ai-calls/"+call.ID+"/replay", `{}`).Code)
+
+	rec := request(operator, http.MethodGet, "/admin/ai-calls?limit=10", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var listed []*model.AICall
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, "prompt", listed[0].Prompt)
+	}
+
+	assert.Equal(t, http.StatusNotFound, request(operator, http.MethodGet, "/admin/ai-calls/missing", "").Code)
+
+	// Without a configured key nothing is sent
+	rec = request(operator, http.MethodPost, "/admin/ai-calls/"+call.ID+"/replay", `{"provider":"openai"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "no API key configured")
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIErrorMatchesByCode(t *testing.T) {
+	err := fmt.Errorf("failed to get user: %w", apierror.NotFound("user not found"))
+
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+	assert.False(t, errors.Is(err, apierror.ErrForbidden))
+
+	// Typed errors survive wrapping; anything else becomes an internal error
+	assert.Equal(t, http.StatusNotFound, apierror.From(err, "fallback").Status)
+	internal := apierror.From(errors.New("boom"), "Failed to do it")
+	assert.Equal(t, http.StatusInternalServerError, internal.Status)
+	assert.Equal(t, "Failed to do it", internal.Message)
+}
+
+func TestHTTPErrorHandlerRendersUniformErrors(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	e.GET("/missing", func(c echo.Context) error { return apierror.NotFound("email not found") })
+	e.GET("/gmail", func(c echo.Context) error { return apierror.Upstream("failed to get emails from Gmail", errors.New("timeout")) })
+	e.GET("/reauth", func(c echo.Context) error { return service.ErrGmailModifyScopeRequired })
+	e.GET("/untyped", func(c echo.Context) error { return errors.New("secret detail") })
+
+	tests := []struct {
+		path    string
+		status  int
+		code    string
+		message string
+	}{
+		{"/missing", http.StatusNotFound, apierror.CodeNotFound, "email not found"},
+		{"/gmail", http.StatusBadGateway, apierror.CodeUpstream, "failed to get emails from Gmail"},
+		{"/reauth", http.StatusForbidden, apierror.CodeReauthRequired, "Gmail modify permission required"},
+		{"/untyped", http.StatusInternalServerError, apierror.CodeInternal, "Internal server error"},
+		{"/no-such-route", http.StatusNotFound, apierror.CodeNotFound, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		var body handler.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), tt.path)
+		assert.Equal(t, tt.status, rec.Code, tt.path)
+		assert.Equal(t, tt.code, body.Code, tt.path)
+		assert.Equal(t, tt.message, body.Error, tt.path)
+		if tt.code == apierror.CodeReauthRequired {
+			assert.Equal(t, handler.ReauthURL, body.ReauthURL)
+		}
+	}
+}
+
+func TestEmailServiceReturnsTypedErrors(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, mockGmailClient, ai.NewMockAIClient(), logger.New())
+
+	owner := model.NewUser("google_1", "owner@example.com", "Owner", "token", "refresh", time.Time{})
+	userRepo.Create(context.Background(), owner)
+	email := model.NewEmail(owner.ID, "msg_1", "sender@example.com", "Subject", "Body", time.Now())
+	emailRepo.Create(context.Background(), email)
+
+	// Another user's email looks exactly like a missing one
+	_, err := emailService.GetEmail(context.Background(), "someone-else", email.ID)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	_, err = emailService.GetEmail(context.Background(), owner.ID, "missing")
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return nil, errors.New("gmail unavailable")
+	}
+	_, err = emailService.SyncEmails(context.Background(), owner.ID, service.SyncOptions{MaxResults: 10})
+	assert.True(t, errors.Is(err, apierror.ErrUpstream))
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerBootsWholeApp(t *testing.T) {
+	cfg := &config.Config{
+		Port:          "0",
+		BaseURL:       "http://localhost:8080",
+		SessionSecret: "test-secret",
+		SessionTTL:    time.Hour,
+	}
+
+	container, err := app.New(cfg, app.WithGmailClient(gmail.NewMockGmailClient()), app.WithAIClient(ai.NewMockAIClient()))
+	assert.NoError(t, err)
+
+	// Default categories are loaded from categories.json
+	categories, err := container.CategoryService.GetAllCategories(context.Background(), "user_1")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, categories)
+
+	container.Start()
+
+	rec := httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Protected routes are wired behind the auth middleware
+	rec = httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/emails", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, container.Stop(ctx))
+}
+
+func TestContainerServesEmbeddedAssetsWithOverride(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "templates"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "templates", "index.html"), []byte("custom home"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "categories.json"), []byte(`[{"name":"Custom","description":"Custom category"}]`), 0o644))
+
+	cfg := &config.Config{
+		Port:          "0",
+		BaseURL:       "http://localhost:8080",
+		SessionSecret: "test-secret",
+		SessionTTL:    time.Hour,
+		AssetsDir:     dir,
+	}
+
+	container, err := app.New(cfg, app.WithGmailClient(gmail.NewMockGmailClient()), app.WithAIClient(ai.NewMockAIClient()))
+	assert.NoError(t, err)
+	defer container.Stop(context.Background())
+
+	categories, err := container.CategoryService.GetAllCategories(context.Background(), "user_1")
+	assert.NoError(t, err)
+	assert.Len(t, categories, 1)
+	assert.Equal(t, "Custom", categories[0].Name)
+
+	// Overridden page
+	rec := httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "custom home", rec.Body.String())
+
+	// Embedded fallbacks
+	rec = httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Email Organization App")
+
+	rec = httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/favicon.svg", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/middleware"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"github.com/markbates/goth/gothic"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSessionTestServer wires /api/me behind the auth middleware the way the router does
+func newSessionTestServer() (*echo.Echo, *memory.InMemoryUserRepository) {
+	userRepo := memory.NewInMemoryUserRepository()
+	return newSessionTestServerWith(userRepo), userRepo
+}
+
+// newSessionTestServerWith is newSessionTestServer reading users from userRepo
+func newSessionTestServerWith(userRepo repository.UserRepository) *echo.Echo {
+	authService := service.NewAuthService(userRepo, logger.New())
+
+	e := echo.New()
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
+	cfg := &config.Config{SessionSecret: "test-secret", SessionTTL: time.Hour, BaseURL: "http://localhost:8080"}
+	authHandler := handler.NewAuthHandler(authService, cfg, e.Logger)
+
+	protected := e.Group("/api")
+	protected.Use(middleware.AuthMiddleware(authHandler))
+	protected.GET("/me", authHandler.Me)
+
+	return e
+}
+
+// sessionCookie builds a session cookie for the user with the given expiry
+func sessionCookie(t *testing.T, userID string, expiresAt time.Time) *http.Cookie {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	session, err := gothic.Store.Get(req, "gothic_session")
+	assert.NoError(t, err)
+	session.Values["user_id"] = userID
+	session.Values["issued_at"] = time.Now().Unix()
+	session.Values["expires_at"] = expiresAt.Unix()
+	assert.NoError(t, session.Save(req, rec))
+
+	cookies := rec.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	return cookies[0]
+}
+
+func getMe(e *echo.Echo, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSessionMeReturnsSessionState(t *testing.T) {
+	e, userRepo := newSessionTestServer()
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(context.Background(), user)
+
+	rec := getMe(e, sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "test@example.com", body["user"]["email"])
+	assert.NotContains(t, body["user"], "access_token")
+	assert.Equal(t, float64(3600), body["session"]["ttl_seconds"])
+}
+
+func TestSessionInvalidatedWhenExpiredOrUserGone(t *testing.T) {
+	e, userRepo := newSessionTestServer()
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(context.Background(), user)
+
+	// Expired session
+	rec := getMe(e, sessionCookie(t, user.ID, time.Now().Add(-time.Minute)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	cookies := rec.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.True(t, cookies[0].MaxAge < 0)
+	}
+
+	// Deleted user
+	cookie := sessionCookie(t, user.ID, time.Now().Add(time.Hour))
+	userRepo.Delete(context.Background(), user.ID)
+	rec = getMe(e, cookie)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestSessionInvalidatedWhenGrantRevoked(t *testing.T) {
+	e, userRepo := newSessionTestServer()
+	user := model.NewUser("google_123", "test@example.com", "Test User", "", "", time.Time{})
+	userRepo.Create(context.Background(), user)
+
+	rec := getMe(e, sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// unreachableUsers fails to read users while down is set, as a database that timed out
+type unreachableUsers struct {
+	repository.UserRepository
+	down bool
+}
+
+func (r *unreachableUsers) FindByID(ctx context.Context, id string) (*model.User, error) {
+	if r.down {
+		return nil, errors.New("dial tcp 10.0.0.5:5432: i/o timeout")
+	}
+	return r.UserRepository.FindByID(ctx, id)
+}
+
+func TestSessionKeptWhenUserLookupFails(t *testing.T) {
+	userRepo := &unreachableUsers{UserRepository: memory.NewInMemoryUserRepository(), down: true}
+	e := newSessionTestServerWith(userRepo)
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(context.Background(), user)
+	cookie := sessionCookie(t, user.ID, time.Now().Add(time.Hour))
+
+	// The request fails without signing the user out
+	rec := getMe(e, cookie)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Result().Cookies())
+
+	userRepo.down = false
+	rec = getMe(e, cookie)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutomationServiceManagesUserAutomations(t *testing.T) {
+	ctx := context.Background()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, gmail.NewMockGmailClient(), ai.NewMockAIClient(), logger.New())
+	automationService := service.NewAutomationService(memory.NewInMemoryAutomationRepository(), categoryRepo, emailRepo, memory.NewInMemoryOrganizationRepository(), emailService, logger.New())
+
+	promotions := model.NewCategory("Promotions", "Deals")
+	categoryRepo.Create(ctx, promotions)
+	private := model.NewCategory("Bob's", "Owned by someone else")
+	private.UserID = "bob"
+	categoryRepo.Create(ctx, private)
+
+	// Categories must be visible to the user and moving needs a label
+	_, err := automationService.CreateAutomation(ctx, "alice", private.ID, "archive", "", 0)
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+	_, err = automationService.CreateAutomation(ctx, "alice", promotions.ID, "move", "", 0)
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+
+	automation, err := automationService.CreateAutomation(ctx, "alice", promotions.ID, "local_archive", "", 7)
+	assert.NoError(t, err)
+	assert.True(t, automation.Enabled)
+
+	// Other users can neither see nor change it
+	automations, err := automationService.GetAutomations(ctx, "bob")
+	assert.NoError(t, err)
+	assert.Empty(t, automations)
+	_, err = automationService.UpdateAutomation(ctx, "bob", automation.ID, promotions.ID, "read", "", 0, true)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+	assert.True(t, errors.Is(automationService.DeleteAutomation(ctx, "bob", automation.ID), apierror.ErrNotFound))
+
+	updated, err := automationService.UpdateAutomation(ctx, "alice", automation.ID, promotions.ID, "read", "", 3, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "read", updated.Action)
+	assert.False(t, updated.Enabled)
+
+	assert.NoError(t, automationService.DeleteAutomation(ctx, "alice", automation.ID))
+	automations, err = automationService.GetAutomations(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, automations)
+}
+
+func TestAutomationsRunOnSyncAndOnSchedule(t *testing.T) {
+	ctx := context.Background()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+	mockAIClient := ai.NewMockAIClient()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	userRepo.Create(ctx, user)
+
+	receipts := model.NewCategory("Receipts", "Purchase receipts")
+	promotions := model.NewCategory("Promotions", "Deals")
+	categoryRepo.Create(ctx, receipts)
+	categoryRepo.Create(ctx, promotions)
+
+	var starred []string
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		if slices.Contains(add, model.LabelStarred) {
+			starred = append(starred, messageID)
+		}
+		return nil
+	}
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return []*model.Email{
+			model.NewEmail(user.ID, "msg_receipt", "shop@example.com", "Your receipt", "receipt", time.Now()),
+			model.NewEmail(user.ID, "msg_deal", "deals@example.com", "50% off", "deal", time.Now()),
+		}, nil
+	}
+	mockAIClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		if emailBody == "receipt" {
+			return "Receipts", nil
+		}
+		return "Promotions", nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, mockAIClient, logger.New())
+	automationService := service.NewAutomationService(memory.NewInMemoryAutomationRepository(), categoryRepo, emailRepo, memory.NewInMemoryOrganizationRepository(), emailService, logger.New())
+	emailService.OnClassified(automationService.ApplyToNewEmail)
+
+	_, err := automationService.CreateAutomation(ctx, user.ID, receipts.ID, "star", "", 0)
+	assert.NoError(t, err)
+	_, err = automationService.CreateAutomation(ctx, user.ID, promotions.ID, "local_archive", "", 7)
+	assert.NoError(t, err)
+
+	// Immediate automations run as soon as sync classifies an email
+	_, err = emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"msg_receipt"}, starred)
+	receipt, err := emailRepo.FindByGmailID(ctx, user.ID, "msg_receipt")
+	assert.NoError(t, err)
+	assert.True(t, receipt.Starred)
+
+	// Delayed automations wait until emails are old enough
+	deal, err := emailRepo.FindByGmailID(ctx, user.ID, "msg_deal")
+	assert.NoError(t, err)
+	applied, err := automationService.RunScheduled(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+	assert.False(t, deal.LocallyArchived)
+
+	oldDeal := model.NewEmail(user.ID, "msg_old_deal", "deals@example.com", "Last week's deal", "deal", time.Now().AddDate(0, 0, -8))
+	oldDeal.CategoryID = promotions.ID
+	emailRepo.Create(ctx, oldDeal)
+
+	applied, err = automationService.RunScheduled(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	stored, _ := emailRepo.FindByID(ctx, oldDeal.ID)
+	assert.True(t, stored.LocallyArchived)
+
+	// Emails handled by a previous pass are not acted on again
+	applied, err = automationService.RunScheduled(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+}
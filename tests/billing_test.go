@@ -0,0 +1,217 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBillingServiceEnforcesPlanQuotas(t *testing.T) {
+	ctx := context.Background()
+	usageRepo := memory.NewInMemoryUsageRepository()
+	billingService := service.NewBillingService(memory.NewInMemoryBillingAccountRepository(), usageRepo, model.PlanFree, logger.New())
+
+	limit := model.Plans[model.PlanFree][model.MetricUnsubscribes]
+	period := model.UsagePeriod(time.Now())
+	assert.NoError(t, usageRepo.Add(ctx, "alice", period, model.MetricUnsubscribes, limit-2))
+
+	// Only what is left of the quota is granted, then nothing
+	granted, err := billingService.Reserve(ctx, "alice", model.MetricUnsubscribes, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, granted)
+	_, err = billingService.Reserve(ctx, "alice", model.MetricUnsubscribes, 1)
+	assert.True(t, errors.Is(err, service.ErrQuotaExceeded))
+
+	report, err := billingService.GetUsage(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, model.PlanFree, report.Plan)
+	assert.Equal(t, period, report.Period)
+	assert.Equal(t, 1, report.ResetsAt.Day())
+	if assert.Len(t, report.Metrics, 3) {
+		unsubscribes := report.Metrics[2]
+		assert.Equal(t, model.MetricUnsubscribes, unsubscribes.Metric)
+		assert.Equal(t, limit, unsubscribes.Used)
+		assert.Equal(t, 0, *unsubscribes.Remaining)
+	}
+
+	// Unlimited plans still record usage
+	_, err = billingService.SetPlan(ctx, "alice", model.PlanUnlimited)
+	assert.NoError(t, err)
+	granted, err = billingService.Reserve(ctx, "alice", model.MetricUnsubscribes, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, granted)
+	report, err = billingService.GetUsage(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, limit+5, report.Metrics[2].Used)
+	assert.Nil(t, report.Metrics[2].Remaining)
+
+	_, err = billingService.SetPlan(ctx, "alice", "platinum")
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+}
+
+func TestSyncStopsAtEmailAndSummaryQuotas(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	usageRepo := memory.NewInMemoryUsageRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(ctx, user)
+	categoryRepo.Create(ctx, model.NewCategory("Work", "Work emails"))
+
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return []*model.Email{
+			model.NewEmail(user.ID, "msg_1", "a@example.com", "One", "body", time.Now()),
+			model.NewEmail(user.ID, "msg_2", "b@example.com", "Two", "body", time.Now()),
+			model.NewEmail(user.ID, "msg_3", "c@example.com", "Three", "body", time.Now()),
+		}, nil
+	}
+
+	billingService := service.NewBillingService(memory.NewInMemoryBillingAccountRepository(), usageRepo, model.PlanFree, logger.New())
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, ai.NewMockAIClient(), logger.New())
+	emailService.UseQuotas(billingService)
+
+	// One email and no summaries left this month
+	period := model.UsagePeriod(time.Now())
+	usageRepo.Add(ctx, user.ID, period, model.MetricEmails, model.Plans[model.PlanFree][model.MetricEmails]-1)
+	usageRepo.Add(ctx, user.ID, period, model.MetricSummaries, model.Plans[model.PlanFree][model.MetricSummaries])
+
+	_, err := emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+	emails, err := emailRepo.FindByUserID(ctx, user.ID, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, emails, 1) {
+		assert.NotEmpty(t, emails[0].CategoryID)
+		assert.Empty(t, emails[0].Summary)
+	}
+
+	// With the quota used up the sync reports it
+	_, err = emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.True(t, errors.Is(err, service.ErrQuotaExceeded))
+}
+
+func TestCategoriesOptOutOfSummaries(t *testing.T) {
+	ctx := context.Background()
+	usageRepo := memory.NewInMemoryUsageRepository()
+	summaries := 0
+	aiClient := ai.NewMockAIClient()
+	aiClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		return emailBody, nil // each email names its category
+	}
+	aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+		summaries++
+		return "A summary.", nil
+	}
+	billingService := service.NewBillingService(memory.NewInMemoryBillingAccountRepository(), usageRepo, model.PlanFree, logger.New())
+	emailService := service.NewEmailService(memory.NewInMemoryEmailRepository(), memory.NewInMemoryCategoryRepository(),
+		memory.NewInMemoryUserRepository(), nil, aiClient, logger.New())
+	emailService.UseQuotas(billingService)
+	emailService.SetSummaryBudgetReserve(20)
+
+	work := model.NewCategory("Work", "Work emails")
+	newsletters := model.NewCategory("Newsletters", "Bulk mail")
+	newsletters.Summarize = model.SummarizeLowPriority
+	promotions := model.NewCategory("Promotions", "Deals")
+	promotions.Summarize = model.SummarizeOff
+	categories := []*model.Category{work, newsletters, promotions}
+
+	summarize := func(category *model.Category) *model.Email {
+		email := model.NewEmail("alice", "msg_"+category.Name, "a@example.com", category.Name, category.Name, time.Now())
+		assert.NoError(t, emailService.ClassifyAndSummarizeEmail(ctx, email, categories))
+		assert.Equal(t, category.ID, email.CategoryID)
+		return email
+	}
+
+	// Classify-only categories never spend a summary
+	assert.Empty(t, summarize(promotions).Summary)
+	assert.Equal(t, 0, summaries)
+	assert.Equal(t, "A summary.", summarize(newsletters).Summary)
+	assert.Equal(t, 1, summaries)
+
+	// Near the monthly cap only low priority categories stop being summarized
+	limit := model.Plans[model.PlanFree][model.MetricSummaries]
+	assert.NoError(t, usageRepo.Add(ctx, "alice", model.UsagePeriod(time.Now()), model.MetricSummaries, limit*80/100-1))
+	remaining, _, err := billingService.Remaining(ctx, "alice", model.MetricSummaries)
+	assert.NoError(t, err)
+	assert.Equal(t, limit*20/100, remaining)
+	assert.Empty(t, summarize(newsletters).Summary)
+	assert.Equal(t, "A summary.", summarize(work).Summary)
+	assert.Equal(t, 2, summaries)
+
+	// Unlimited plans keep summarizing them
+	_, err = billingService.SetPlan(ctx, "alice", model.PlanUnlimited)
+	assert.NoError(t, err)
+	assert.Equal(t, "A summary.", summarize(newsletters).Summary)
+}
+
+// signStripe builds a Stripe-Signature header for the payload
+func signStripe(secret, payload string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestStripeWebhookChangesPlan(t *testing.T) {
+	container, user, request := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.DefaultPlan = model.PlanFree
+		cfg.StripeWebhookKey = "whsec_test"
+	}))
+
+	send := func(payload, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/billing/webhooks/stripe", strings.NewReader(payload))
+		req.Header.Set("Stripe-Signature", signature)
+		rec := serve(container, req)
+		return rec.Code
+	}
+	usage := func() *model.UsageReport {
+		rec := serve(container, request(http.MethodGet, "/api/v1/usage", ""))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var report model.UsageReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return &report
+	}
+
+	assert.Equal(t, model.PlanFree, usage().Plan)
+
+	created := `{"type":"customer.subscription.created","data":{"object":{"customer":"cus_1","status":"active","metadata":{"user_id":"` + user.ID + `","plan":"pro"}}}}`
+	assert.Equal(t, http.StatusBadRequest, send(created, signStripe("wrong", created, time.Now())))
+	assert.Equal(t, http.StatusBadRequest, send(created, signStripe("whsec_test", created, time.Now().Add(-time.Hour))))
+	assert.Equal(t, model.PlanFree, usage().Plan)
+
+	assert.Equal(t, http.StatusOK, send(created, signStripe("whsec_test", created, time.Now())))
+	assert.Equal(t, model.PlanPro, usage().Plan)
+
+	// Later events only carry the customer, which now maps to the user
+	deleted := `{"type":"customer.subscription.deleted","data":{"object":{"customer":"cus_1","status":"canceled"}}}`
+	assert.Equal(t, http.StatusOK, send(deleted, signStripe("whsec_test", deleted, time.Now())))
+	assert.Equal(t, model.PlanFree, usage().Plan)
+
+	// Unknown providers are not routed anywhere
+	req := httptest.NewRequest(http.MethodPost, "/billing/webhooks/paypal", strings.NewReader("{}"))
+	rec := serve(container, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
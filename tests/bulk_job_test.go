@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkJobQueueAppliesActionToFilterMatches(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	appLogger := logger.New()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(context.Background(), user)
+
+	// Five promotions from one sender and one email from someone else
+	for i := 0; i < 5; i++ {
+		email := model.NewEmail(user.ID, "promo_"+string(rune('a'+i)), "Deals <deals@shop.example>", "Sale", "Body", time.Now())
+		emailRepo.Create(context.Background(), email)
+	}
+	keep := model.NewEmail(user.ID, "keep", "friend@example.com", "Hi", "Body", time.Now())
+	emailRepo.Create(context.Background(), keep)
+
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, gmail.NewMockGmailClient(), ai.NewMockAIClient(), appLogger)
+	unsubscribeService := service.NewUnsubscribeService(emailRepo, userRepo, gmail.NewMockGmailClient(), ai.NewMockAIClient(), appLogger)
+	queue := sse.NewBulkJobQueue(emailService, unsubscribeService, nil, 2, appLogger)
+
+	job, err := queue.Enqueue(user.ID, "local_archive", model.EmailFilter{Sender: "DEALS@shop.example"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.BulkJobPending, job.Status)
+
+	queue.RunPending()
+
+	finished, err := queue.GetJob(user.ID, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.BulkJobCompleted, finished.Status)
+	assert.Equal(t, 5, finished.Total)
+	assert.Equal(t, 5, finished.Processed)
+	assert.Equal(t, 0, finished.Failed)
+
+	emails, err := emailService.ListEmails(context.Background(), user.ID, model.EmailFilter{}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 1)
+	assert.Equal(t, keep.ID, emails[0].ID)
+
+	// Jobs are private to their owner
+	_, err = queue.GetJob("someone_else", job.ID)
+	assert.Error(t, err)
+}
+
+func TestBulkJobQueueRejectsInvalidRequests(t *testing.T) {
+	appLogger := logger.New()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, gmail.NewMockGmailClient(), ai.NewMockAIClient(), appLogger)
+	queue := sse.NewBulkJobQueue(emailService, nil, nil, 0, appLogger)
+
+	// An empty filter would match the whole mailbox
+	_, err := queue.Enqueue("user_1", "delete", model.EmailFilter{})
+	assert.Error(t, err)
+
+	_, err = queue.Enqueue("user_1", "explode", model.EmailFilter{CategoryID: "cat_1"})
+	assert.Error(t, err)
+
+	// Moving needs somewhere to move to
+	_, err = queue.Enqueue("user_1", "move", model.EmailFilter{CategoryID: "cat_1"})
+	assert.Error(t, err)
+}
+
+func TestBulkJobQueueMovesFilterMatchesToLabel(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+	appLogger := logger.New()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailModify
+	userRepo.Create(context.Background(), user)
+
+	receipt := model.NewEmail(user.ID, "msg_1", "shop@example.com", "Receipt", "Body", time.Now())
+	emailRepo.Create(context.Background(), receipt)
+
+	var moved []string
+	mockGmailClient.GetOrCreateLabelFunc = func(ctx context.Context, userEmail, name string) (string, error) {
+		return "Label_" + name, nil
+	}
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		assert.Equal(t, []string{model.LabelInbox}, remove)
+		moved = append(moved, messageID+"->"+strings.Join(add, ","))
+		return nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, mockGmailClient, ai.NewMockAIClient(), appLogger)
+	queue := sse.NewBulkJobQueue(emailService, nil, nil, 0, appLogger)
+
+	job := model.NewBulkJob(user.ID, "move", model.EmailFilter{Sender: "shop@example.com"})
+	job.Label = "Receipts"
+	job, err := queue.Submit(job)
+	assert.NoError(t, err)
+	assert.Equal(t, "Receipts", job.Label)
+
+	queue.RunPending()
+
+	finished, err := queue.GetJob(user.ID, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.BulkJobCompleted, finished.Status)
+	assert.Equal(t, []string{"msg_1->Label_Receipts"}, moved)
+
+	stored, err := emailRepo.FindByID(context.Background(), receipt.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.Archived)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryListIncludesEmailCounts(t *testing.T) {
+	ctx := context.Background()
+	gmailClient := gmail.NewMockGmailClient()
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient)), withGmailModify())
+
+	receipts, err := container.CategoryService.CreateCategory(ctx, user.ID, "Receipts", "Orders and invoices")
+	assert.NoError(t, err)
+
+	addEmail := func(gmailID, categoryID string, unread bool) *model.Email {
+		email := model.NewEmail(user.ID, gmailID, "shop@example.com", "Your order", "<p>Thanks</p>", time.Now())
+		email.CategoryID = categoryID
+		email.Unread = unread
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		return email
+	}
+	unread := addEmail("msg_1", receipts.ID, true)
+	addEmail("msg_2", receipts.ID, false)
+	addEmail("msg_3", "", true)
+	trashed := addEmail("msg_4", receipts.ID, true)
+	now := time.Now()
+	trashed.DeletedAt = &now
+	assert.NoError(t, container.EmailRepo.Update(ctx, trashed))
+
+	listCounts := func() model.EmailCounts {
+		rec := serve(container, request(http.MethodGet, "/api/v1/categories", ""))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var categories []struct {
+			ID          string `json:"id"`
+			EmailCount  *int   `json:"email_count"`
+			UnreadCount *int   `json:"unread_count"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &categories))
+		for _, category := range categories {
+			// Every category carries both counts, zero included
+			if assert.NotNil(t, category.EmailCount) && assert.NotNil(t, category.UnreadCount) && category.ID == receipts.ID {
+				return model.EmailCounts{Total: *category.EmailCount, Unread: *category.UnreadCount}
+			}
+		}
+		t.Fatal("the category list is missing the created category")
+		return model.EmailCounts{}
+	}
+
+	// Trashed emails are not counted
+	assert.Equal(t, model.EmailCounts{Total: 2, Unread: 1}, listCounts())
+	counts, err := container.EmailService.CountByCategory(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.EmailCounts{Total: 1, Unread: 1}, counts[""])
+
+	// Counts are cached, so a change made behind the service's back is not seen yet
+	addEmail("msg_5", receipts.ID, true)
+	assert.Equal(t, model.EmailCounts{Total: 2, Unread: 1}, listCounts())
+
+	// Bulk actions drop the cached counts
+	assert.NoError(t, container.EmailService.PerformBulkAction(ctx, []string{unread.ID}, "read", user.ID))
+	assert.Equal(t, model.EmailCounts{Total: 3, Unread: 1}, listCounts())
+
+	// So do syncs
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return []*model.Email{model.NewEmail("", "msg_6", "news@example.com", "Weekly news", "<p>News</p>", time.Now())}, nil
+	}
+	_, err = container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+	counts, err = container.EmailService.CountByCategory(ctx, user.ID)
+	assert.NoError(t, err)
+	total := 0
+	for _, count := range counts {
+		total += count.Total
+	}
+	assert.Equal(t, 5, total)
+}
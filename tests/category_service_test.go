@@ -2,9 +2,15 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"jump-challenge/internal/apierror"
 	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
 	"jump-challenge/internal/repository/memory"
 	"jump-challenge/internal/service"
 
@@ -17,37 +23,150 @@ func TestCategoryServiceCRUD(t *testing.T) {
 	appLogger := logger.New()
 
 	// Create service
-	categoryService := service.NewCategoryService(categoryRepo, appLogger)
+	categoryService := service.NewCategoryService(categoryRepo, memory.NewInMemoryOrganizationRepository(), appLogger)
 
 	// Test Create
-	category, err := categoryService.CreateCategory(context.Background(), "", "Work", "Work related emails")
+	category, err := categoryService.CreateCategory(context.Background(), "user_1", "Work", "Work related emails")
 	assert.NoError(t, err)
 	assert.Equal(t, "Work", category.Name)
 	assert.Equal(t, "Work related emails", category.Description)
+	assert.Equal(t, "user_1", category.UserID)
 
 	// Test Get by ID
-	retrievedCategory, err := categoryService.GetCategory(context.Background(), category.ID)
+	retrievedCategory, err := categoryService.GetCategory(context.Background(), "user_1", category.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, category.ID, retrievedCategory.ID)
 	assert.Equal(t, "Work", retrievedCategory.Name)
 
 	// Test Get all categories
-	categories, err := categoryService.GetAllCategories(context.Background())
+	categories, err := categoryService.GetAllCategories(context.Background(), "user_1")
 	assert.NoError(t, err)
 	assert.Len(t, categories, 1)
 	assert.Equal(t, "Work", categories[0].Name)
 
 	// Test Update
-	updatedCategory, err := categoryService.UpdateCategory(context.Background(), category.ID, "Updated Work", "Updated description")
+	updatedCategory, err := categoryService.UpdateCategory(context.Background(), "user_1", category.ID, "Updated Work", "Updated description")
 	assert.NoError(t, err)
 	assert.Equal(t, "Updated Work", updatedCategory.Name)
 	assert.Equal(t, "Updated description", updatedCategory.Description)
 
 	// Test Delete
-	err = categoryService.DeleteCategory(context.Background(), category.ID)
+	err = categoryService.DeleteCategory(context.Background(), "user_1", category.ID)
 	assert.NoError(t, err)
 
 	// Verify deletion
-	_, err = categoryService.GetCategory(context.Background(), category.ID)
+	_, err = categoryService.GetCategory(context.Background(), "user_1", category.ID)
+	assert.Error(t, err)
+}
+
+func TestCategoryNamesAreUniquePerUser(t *testing.T) {
+	ctx := context.Background()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	categoryService := service.NewCategoryService(categoryRepo, memory.NewInMemoryOrganizationRepository(), logger.New())
+
+	work, err := categoryService.CreateCategory(ctx, "user_1", "Work", "Work related emails")
+	assert.NoError(t, err)
+
+	// The same name in another case or with spaces conflicts and carries the existing category
+	_, err = categoryService.CreateCategory(ctx, "user_1", " work ", "Again")
+	assert.True(t, errors.Is(err, apierror.ErrConflict))
+	var apiErr *apierror.Error
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusConflict, apiErr.Status)
+		assert.Equal(t, work.ID, apiErr.Current.(*model.Category).ID)
+	}
+
+	// Other users have their own names
+	_, err = categoryService.CreateCategory(ctx, "user_2", "Work", "Someone else's")
+	assert.NoError(t, err)
+
+	// Renaming onto a taken name conflicts too, keeping the name doesn't
+	personal, err := categoryService.CreateCategory(ctx, "user_1", "Personal", "")
+	assert.NoError(t, err)
+	_, err = categoryService.UpdateCategory(ctx, "user_1", personal.ID, "WORK", "")
+	assert.True(t, errors.Is(err, apierror.ErrConflict))
+	_, err = categoryService.UpdateCategory(ctx, "user_1", personal.ID, "Personal", "Family and friends")
+	assert.NoError(t, err)
+
+	// The repository enforces it even when the service is bypassed
+	duplicate := model.NewCategory("WORK", "")
+	duplicate.UserID = "user_1"
+	assert.True(t, errors.Is(categoryRepo.Create(ctx, duplicate), apierror.ErrConflict))
+}
+
+func TestCreateDuplicateCategoryReturnsConflict(t *testing.T) {
+	container, _, request := newTestApp(t)
+
+	create := func() *httptest.ResponseRecorder {
+		return serve(container, request(http.MethodPost, "/api/v1/categories", `{"name":"Receipts"}`))
+	}
+
+	first := create()
+	assert.Equal(t, http.StatusCreated, first.Code)
+	var created model.Category
+	assert.NoError(t, json.Unmarshal(first.Body.Bytes(), &created))
+
+	second := create()
+	assert.Equal(t, http.StatusConflict, second.Code)
+	var body struct {
+		Code    string         `json:"code"`
+		Current model.Category `json:"current"`
+	}
+	assert.NoError(t, json.Unmarshal(second.Body.Bytes(), &body))
+	assert.Equal(t, apierror.CodeConflict, body.Code)
+	assert.Equal(t, created.ID, body.Current.ID)
+}
+
+func TestCategoryHints(t *testing.T) {
+	ctx := context.Background()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	categoryService := service.NewCategoryService(categoryRepo, memory.NewInMemoryOrganizationRepository(), logger.New())
+
+	receipts, err := categoryService.CreateCategory(ctx, "user_1", "Receipts", "Orders and invoices")
+	assert.NoError(t, err)
+
+	updated, err := categoryService.SetCategoryHints(ctx, "user_1", receipts.ID, "  invoices, order confirmations; not bank statements\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "invoices, order confirmations; not bank statements", updated.Hints)
+	stored, err := categoryService.GetCategory(ctx, "user_1", receipts.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, updated.Hints, stored.Hints)
+
+	// Renaming keeps the hints, and empty hints remove them
+	_, err = categoryService.UpdateCategory(ctx, "user_1", receipts.ID, "Purchases", "")
+	assert.NoError(t, err)
+	stored, _ = categoryService.GetCategory(ctx, "user_1", receipts.ID)
+	assert.Equal(t, updated.Hints, stored.Hints)
+	updated, err = categoryService.SetCategoryHints(ctx, "user_1", receipts.ID, "")
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Hints)
+
+	// Only the owner may give hints, and shared categories are read-only
+	_, err = categoryService.SetCategoryHints(ctx, "user_2", receipts.ID, "anything")
+	assert.Error(t, err)
+	shared := model.NewCategory("Newsletters", "Bulk mail")
+	assert.NoError(t, categoryRepo.Create(ctx, shared))
+	_, err = categoryService.SetCategoryHints(ctx, "user_1", shared.ID, "anything")
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+}
+
+func TestCategorySummarizeMode(t *testing.T) {
+	ctx := context.Background()
+	categoryService := service.NewCategoryService(memory.NewInMemoryCategoryRepository(), memory.NewInMemoryOrganizationRepository(), logger.New())
+
+	promotions, err := categoryService.CreateCategory(ctx, "user_1", "Promotions", "Deals")
+	assert.NoError(t, err)
+	assert.Equal(t, model.SummarizeOn, promotions.SummarizeMode())
+
+	updated, err := categoryService.SetCategorySummarize(ctx, "user_1", promotions.ID, model.SummarizeOff)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SummarizeOff, updated.SummarizeMode())
+	stored, err := categoryService.GetCategory(ctx, "user_1", promotions.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SummarizeOff, stored.Summarize)
+
+	_, err = categoryService.SetCategorySummarize(ctx, "user_1", promotions.ID, "sometimes")
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+	_, err = categoryService.SetCategorySummarize(ctx, "user_2", promotions.ID, model.SummarizeOn)
 	assert.Error(t, err)
 }
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanupPoliciesAPI(t *testing.T) {
+	ctx := context.Background()
+	container, user, request := newTestApp(t, withGmailModify())
+
+	social, err := container.CategoryService.CreateCategory(ctx, user.ID, "Social", "Social networks")
+	assert.NoError(t, err)
+
+	addEmail := func(gmailID string, age time.Duration, priority string) *model.Email {
+		email := model.NewEmail(user.ID, gmailID, "friends@social.example", "Subject", "body", time.Now().Add(-age))
+		email.CategoryID = social.ID
+		email.Priority = priority
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		return email
+	}
+	var old []*model.Email
+	for i := range 3 {
+		old = append(old, addEmail(fmt.Sprintf("old_%d", i), time.Duration(20+i)*24*time.Hour, ""))
+	}
+	recent := addEmail("recent", 2*24*time.Hour, "")
+	vip := addEmail("vip", 30*24*time.Hour, model.EmailPriorityUrgent)
+
+	call := func(method, path string, body any) (int, []byte) {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		rec := serve(container, request(method, "/api/v1"+path, string(payload)))
+		return rec.Code, rec.Body.Bytes()
+	}
+
+	// Schedules are daily or weekly, on a category the user can see
+	status, _ := call(http.MethodPost, "/cleanup-policies", map[string]any{
+		"name": "Social", "category_id": social.ID, "older_than_days": 14, "action": "delete", "schedule": "hourly",
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(http.MethodPost, "/cleanup-policies", map[string]any{
+		"name": "Social", "category_id": "missing", "older_than_days": 14, "action": "delete", "schedule": "daily",
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Created disabled, a policy can be previewed before it ever runs
+	status, body := call(http.MethodPost, "/cleanup-policies", map[string]any{
+		"name": "Social", "category_id": social.ID, "older_than_days": 14, "action": "delete", "schedule": "daily", "enabled": false,
+	})
+	assert.Equal(t, http.StatusCreated, status)
+	var policy model.CleanupPolicy
+	assert.NoError(t, json.Unmarshal(body, &policy))
+	assert.False(t, policy.Enabled)
+
+	status, body = call(http.MethodGet, "/cleanup-policies/"+policy.ID+"/preview", nil)
+	assert.Equal(t, http.StatusOK, status)
+	var preview model.CleanupPreview
+	assert.NoError(t, json.Unmarshal(body, &preview))
+	assert.Equal(t, 3, preview.Count)
+	assert.Equal(t, []string{old[0].ID, old[1].ID, old[2].ID}, emailIDs(preview.Emails))
+	assert.Empty(t, preview.Emails[0].Body)
+
+	trashed := func(email *model.Email) bool {
+		stored, err := container.EmailRepo.FindByIDAndUser(ctx, email.ID, user.ID)
+		assert.NoError(t, err)
+		return stored.DeletedAt != nil
+	}
+	container.AutomationJob.RunAutomations()
+	assert.False(t, trashed(old[0]))
+
+	// Once enabled, the scheduler cleans the matched emails, leaving VIP and recent ones alone,
+	// and tells the user what was cleaned
+	policy.Enabled = true
+	status, _ = call(http.MethodPut, "/cleanup-policies/"+policy.ID, policy)
+	assert.Equal(t, http.StatusOK, status)
+
+	events := container.SSEManager.AddClient(user.ID)
+	container.AutomationJob.RunAutomations()
+	for _, email := range old {
+		assert.True(t, trashed(email))
+	}
+	assert.False(t, trashed(recent))
+	assert.False(t, trashed(vip))
+
+	var event struct {
+		Type string           `json:"type"`
+		Data model.CleanupRun `json:"data"`
+	}
+	if assert.Len(t, events, 1) {
+		assert.NoError(t, json.Unmarshal(<-events, &event))
+		assert.Equal(t, model.EventCleanup, event.Type)
+		assert.Equal(t, "Social", event.Data.PolicyName)
+		assert.Equal(t, 3, event.Data.Cleaned)
+	}
+
+	// The next pass waits for the schedule to come round
+	container.AutomationJob.RunAutomations()
+	assert.Empty(t, events)
+
+	status, body = call(http.MethodGet, "/cleanup-policies/"+policy.ID+"/runs", nil)
+	assert.Equal(t, http.StatusOK, status)
+	var runs []model.CleanupRun
+	assert.NoError(t, json.Unmarshal(body, &runs))
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, 3, runs[0].Matched)
+		assert.Equal(t, 3, runs[0].Cleaned)
+		assert.Empty(t, runs[0].Error)
+	}
+
+	status, body = call(http.MethodGet, "/cleanup-policies", nil)
+	assert.Equal(t, http.StatusOK, status)
+	var policies []model.CleanupPolicy
+	assert.NoError(t, json.Unmarshal(body, &policies))
+	if assert.Len(t, policies, 1) {
+		assert.NotNil(t, policies[0].LastRunAt)
+	}
+
+	// Other users see none of it
+	other := model.NewUser("google_456", "other@example.com", "Other User", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, other)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cleanup-policies/"+policy.ID+"/runs", nil)
+	req.AddCookie(sessionCookie(t, other.ID, time.Now().Add(time.Hour)))
+	rec := serve(container, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	status, _ = call(http.MethodDelete, "/cleanup-policies/"+policy.ID, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = call(http.MethodGet, "/cleanup-policies/"+policy.ID+"/runs", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"jump-challenge/internal/cli"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCLIUnknownCommand(t *testing.T) {
+	var out, errOut bytes.Buffer
+
+	code := cli.Run([]string{"bogus"}, &out, &errOut)
+
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut.String(), "unknown command: bogus")
+	assert.Contains(t, errOut.String(), "reclassify")
+}
+
+func TestCLIRequiresFlags(t *testing.T) {
+	var out, errOut bytes.Buffer
+
+	assert.Equal(t, 1, cli.Run([]string{"sync"}, &out, &errOut))
+	assert.Contains(t, errOut.String(), "--user is required")
+
+	errOut.Reset()
+	assert.Equal(t, 1, cli.Run([]string{"reclassify"}, &out, &errOut))
+	assert.Contains(t, errOut.String(), "--category is required")
+}
+
+func TestCLIHelp(t *testing.T) {
+	var out, errOut bytes.Buffer
+
+	assert.Equal(t, 0, cli.Run([]string{"help"}, &out, &errOut))
+	assert.Contains(t, out.String(), "Usage:")
+}
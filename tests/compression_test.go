@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/jsonstream"
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteArrayMatchesEncodingTheWholeArray(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+		Tags []int  `json:"tags"`
+	}
+	items := []item{{Name: "<a>", Tags: []int{1, 2}}, {Name: "b"}}
+
+	for _, list := range [][]item{items, {}, nil} {
+		var compact bytes.Buffer
+		assert.NoError(t, jsonstream.WriteArray(&compact, list, ""))
+		expected, _ := json.Marshal(list)
+		if list == nil {
+			// A missing list is still written as an empty array
+			expected = []byte("[]")
+		}
+		assert.JSONEq(t, string(expected), compact.String())
+		assert.True(t, strings.HasSuffix(compact.String(), "]\n"))
+	}
+
+	var indented bytes.Buffer
+	assert.NoError(t, jsonstream.WriteArray(&indented, items, "  "))
+	expected, _ := json.MarshalIndent(items, "", "  ")
+	assert.Equal(t, string(expected)+"\n", indented.String())
+}
+
+func TestEmailListsAreCompressedAndStreamed(t *testing.T) {
+	container, user, request := newTestApp(t)
+	ctx := context.Background()
+
+	for i := 0; i < 50; i++ {
+		email := model.NewEmail(user.ID, fmt.Sprint("msg_", i), "news@shop.example", fmt.Sprint("Weekly deals #", i), "Body", time.Now())
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+	}
+
+	get := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := request(http.MethodGet, "/api/v1/emails", "")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		return serve(container, req)
+	}
+
+	plain := get("")
+	assert.Equal(t, http.StatusOK, plain.Code)
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+	assert.Contains(t, plain.Header().Get("Content-Type"), "application/json")
+
+	compressed := get("gzip, deflate, br")
+	assert.Equal(t, http.StatusOK, compressed.Code)
+	assert.Equal(t, "gzip", compressed.Header().Get("Content-Encoding"))
+	assert.Less(t, compressed.Body.Len(), plain.Body.Len())
+
+	reader, err := gzip.NewReader(compressed.Body)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, plain.Body.String(), string(body))
+	var emails []*model.Email
+	assert.NoError(t, json.Unmarshal(body, &emails))
+	assert.Len(t, emails, 50)
+	assert.Empty(t, emails[0].Body)
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListEndpointsAnswerConditionalRequests(t *testing.T) {
+	container, user, request := newTestApp(t, withGmailModify())
+	ctx := context.Background()
+
+	category, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Work related emails")
+	assert.NoError(t, err)
+	first := model.NewEmail(user.ID, "msg_1", "boss@example.com", "Q3 plan", "Body", time.Now())
+	first.CategoryID = category.ID
+	assert.NoError(t, container.EmailRepo.Create(ctx, first))
+	second := model.NewEmail(user.ID, "msg_2", "boss@example.com", "Q4 plan", "Body", time.Now())
+	assert.NoError(t, container.EmailRepo.Create(ctx, second))
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := request(http.MethodGet, path, "")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return serve(container, req)
+	}
+
+	for _, path := range []string{"/api/v1/emails", "/api/v1/categories"} {
+		rec := get(path, nil)
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		etag := rec.Header().Get("ETag")
+		assert.NotEmpty(t, etag, path)
+		assert.Empty(t, rec.Header().Get("Last-Modified"), path)
+
+		rec = get(path, map[string]string{"If-None-Match": `"other", ` + etag})
+		assert.Equal(t, http.StatusNotModified, rec.Code, path)
+		assert.Empty(t, rec.Body.String(), path)
+		assert.Equal(t, etag, rec.Header().Get("ETag"), path)
+
+	}
+
+	// Other representations of the same URL have their own ETag
+	listETag := get("/api/v1/emails", nil).Header().Get("ETag")
+	withBodies := get("/api/v1/emails?include_body=true", nil).Header().Get("ETag")
+	assert.NotEqual(t, listETag, withBodies)
+
+	// Changing an email, or removing one, changes both lists: the emails and the category counts
+	categories := get("/api/v1/categories", nil).Header().Get("ETag")
+	assert.NoError(t, container.EmailRepo.MarkTriaged(ctx, second.ID, "keep", time.Now()))
+	rec := get("/api/v1/emails", map[string]string{"If-None-Match": listETag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	listETag = rec.Header().Get("ETag")
+
+	assert.NoError(t, container.EmailService.DeleteEmails(ctx, []string{first.ID}, user.ID))
+	rec = get("/api/v1/emails", map[string]string{"If-None-Match": listETag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = get("/api/v1/categories", map[string]string{"If-None-Match": categories})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// A date can't tell the removal apart, so If-Modified-Since never gets a 304
+	since := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	for _, path := range []string{"/api/v1/emails", "/api/v1/categories"} {
+		rec = get(path, map[string]string{"If-Modified-Since": since})
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.NotContains(t, rec.Body.String(), "Q3 plan", path)
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigPicksUpEnvFileEdits(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeEnv := func(content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte(content), 0o600))
+	}
+	// Unset what the file set so later tests see the usual defaults
+	t.Cleanup(func() {
+		writeEnv("")
+		config.LoadConfig()
+	})
+
+	writeEnv("EMAIL_SYNC_INTERVAL_SECONDS=45\nDEFAULT_MODEL=gemini-a\n")
+	cfg, err := config.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 45*time.Second, cfg.SyncInterval)
+	assert.Equal(t, "gemini-a", cfg.AIModel)
+
+	// Edited and removed variables are both picked up
+	writeEnv("EMAIL_SYNC_INTERVAL_SECONDS=90\n")
+	cfg, err = config.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, cfg.SyncInterval)
+	assert.Empty(t, cfg.AIModel)
+
+	writeEnv("EMAIL_SYNC_INTERVAL_SECONDS=soon\n")
+	_, err = config.LoadConfig()
+	assert.Error(t, err)
+}
+
+func TestConfigStoreReload(t *testing.T) {
+	next := &config.Config{SyncInterval: time.Minute}
+	var loadErr error
+	store := config.NewStore(&config.Config{SyncInterval: time.Hour}, func() (*config.Config, error) {
+		return next, loadErr
+	})
+
+	var seen []time.Duration
+	store.Subscribe(func(cfg *config.Config) {
+		seen = append(seen, cfg.SyncInterval)
+	})
+
+	cfg, err := store.Reload()
+	assert.NoError(t, err)
+	assert.Same(t, next, cfg)
+	assert.Same(t, next, store.Get())
+
+	// A broken reload keeps the current snapshot and tells no one
+	loadErr = errors.New("bad value")
+	_, err = store.Reload()
+	assert.Error(t, err)
+	assert.Same(t, next, store.Get())
+	assert.Equal(t, []time.Duration{time.Minute}, seen)
+}
+
+func TestContainerFollowsConfigReload(t *testing.T) {
+	cfg := &config.Config{
+		Port:          "0",
+		BaseURL:       "http://localhost:8080",
+		SessionSecret: "test-secret",
+		SessionTTL:    time.Hour,
+		SyncInterval:  30 * time.Second,
+	}
+	container, err := app.New(cfg, app.WithGmailClient(gmail.NewMockGmailClient()), app.WithAIClient(ai.NewMockAIClient()))
+	assert.NoError(t, err)
+	defer container.Stop(context.Background())
+	assert.Equal(t, 30*time.Second, container.EmailSyncJob.Interval())
+
+	reloaded := *cfg
+	reloaded.SyncInterval = 5 * time.Second
+	container.ConfigStore.Set(&reloaded)
+
+	assert.Equal(t, 5*time.Second, container.EmailSyncJob.Interval())
+	assert.Same(t, &reloaded, container.ConfigStore.Get())
+}
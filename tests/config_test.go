@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"jump-challenge/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigParsesTypedSettings(t *testing.T) {
+	t.Setenv("SESSION_TTL_HOURS", "24")
+	t.Setenv("EMAIL_SYNC_INTERVAL_SECONDS", "")
+	t.Setenv("MAX_FETCH_EMAILS", "25")
+	t.Setenv("MAX_EMAIL_BODY_BYTES", "0")
+	t.Setenv("TRASH_RETENTION_DAYS", "7")
+	t.Setenv("AUTOMATION_INTERVAL_MINUTES", "15")
+
+	cfg, err := config.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, cfg.SessionTTL)
+	assert.Equal(t, config.DefaultSyncInterval, cfg.SyncInterval)
+	assert.Equal(t, int64(25), cfg.MaxFetchEmails)
+	assert.Equal(t, 0, cfg.MaxEmailBodyBytes)
+	assert.Equal(t, 7*24*time.Hour, cfg.TrashRetention)
+	assert.Equal(t, config.DefaultBulkBatchSize, cfg.BulkBatchSize)
+	assert.Equal(t, 15*time.Minute, cfg.AutomationInterval)
+}
+
+func TestLoadConfigReportsEveryInvalidSetting(t *testing.T) {
+	t.Setenv("SESSION_TTL_HOURS", "0")
+	t.Setenv("MAX_FETCH_EMAILS", "many")
+	t.Setenv("MAX_EMAIL_BODY_BYTES", "-1")
+	t.Setenv("BULK_ACTION_BATCH_SIZE", "50")
+
+	_, err := config.LoadConfig()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `SESSION_TTL_HOURS must be an integer of at least 1, got "0"`)
+		assert.Contains(t, err.Error(), `MAX_FETCH_EMAILS must be an integer of at least 1, got "many"`)
+		assert.Contains(t, err.Error(), `MAX_EMAIL_BODY_BYTES must be an integer of at least 0, got "-1"`)
+		assert.NotContains(t, err.Error(), "BULK_ACTION_BATCH_SIZE")
+	}
+}
+
+func TestValidateReportsEveryMissingSetting(t *testing.T) {
+	cfg := &config.Config{SessionSecret: "secret", AIKey: "key", VAPIDPrivateKey: "private"}
+
+	err := cfg.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "GOOGLE_CLIENT_ID is required")
+		assert.Contains(t, err.Error(), "GOOGLE_CLIENT_SECRET is required")
+		assert.Contains(t, err.Error(), "VAPID_PUBLIC_KEY is required with VAPID_PRIVATE_KEY")
+		assert.NotContains(t, err.Error(), "AI_API_KEY")
+	}
+
+	cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.VAPIDPublicKey = "id", "secret", "public"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.GmailAuth = config.GmailAuthDelegation
+	err = cfg.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "GOOGLE_SERVICE_ACCOUNT_FILE is required with GMAIL_AUTH=delegation")
+		assert.Contains(t, err.Error(), "WORKSPACE_DOMAIN is required with GMAIL_AUTH=delegation")
+	}
+	cfg.GmailAuth = "service_account"
+	assert.ErrorContains(t, cfg.Validate(), `GMAIL_AUTH must be oauth or delegation, got "service_account"`)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailDeltaReconcilesALocalCopy(t *testing.T) {
+	container, user, request := newTestApp(t, withGmailModify())
+	ctx := context.Background()
+
+	create := func(gmailID string) *model.Email {
+		email := model.NewEmail(user.ID, gmailID, "news@shop.example", gmailID, "Body", time.Now())
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		return email
+	}
+	delta := func(query url.Values) (int, *model.EmailDelta) {
+		rec := serve(container, request(http.MethodGet, "/api/v1/emails/delta?"+query.Encode(), ""))
+		var result model.EmailDelta
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		}
+		return rec.Code, &result
+	}
+
+	first, second, third := create("msg_1"), create("msg_2"), create("msg_3")
+
+	// Without a cursor every email is new, in pages when asked
+	code, page := delta(url.Values{"limit": {"2"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{first.ID, second.ID}, page.Created)
+	assert.True(t, page.HasMore)
+	code, page = delta(url.Values{"since": {page.Cursor}, "limit": {"2"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{third.ID}, page.Created)
+	assert.False(t, page.HasMore)
+	cursor := page.Cursor
+
+	// Nothing changed, the cursor stays
+	_, page = delta(url.Values{"since": {cursor}})
+	assert.Empty(t, page.Created)
+	assert.Empty(t, page.Updated)
+	assert.Empty(t, page.Deleted)
+	assert.Equal(t, cursor, page.Cursor)
+
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, container.EmailRepo.MarkTriaged(ctx, first.ID, "keep", time.Now()))
+	assert.NoError(t, container.EmailService.DeleteEmails(ctx, []string{second.ID}, user.ID))
+	fourth := create("msg_4")
+
+	_, page = delta(url.Values{"since": {cursor}})
+	assert.Equal(t, []string{first.ID}, page.Updated)
+	assert.Equal(t, []string{second.ID}, page.Deleted)
+	assert.Equal(t, []string{fourth.ID}, page.Created)
+
+	code, _ = delta(url.Values{"since": {"not a cursor"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFrom(t *testing.T) {
+	cases := []struct {
+		raw     string
+		name    string
+		address string
+	}{
+		{"Jane Doe <jane@x.com>", "Jane Doe", "jane@x.com"},
+		{`"Doe, Jane" <Jane@X.com>`, "Doe, Jane", "jane@x.com"},
+		{"jane@x.com", "", "jane@x.com"},
+		{"<jane@x.com>", "", "jane@x.com"},
+		{"=?UTF-8?B?SsOjbmU=?= <jane@x.com>", "Jãne", "jane@x.com"},
+		{"Broken <name <jane@x.com>", "Broken <name", "jane@x.com"},
+		{"Mailer Daemon", "Mailer Daemon", ""},
+		{"", "", ""},
+	}
+
+	for _, tc := range cases {
+		name, address := model.ParseFrom(tc.raw)
+		assert.Equal(t, tc.name, name, "name for %q", tc.raw)
+		assert.Equal(t, tc.address, address, "address for %q", tc.raw)
+	}
+}
+
+func TestNewEmailParsesFrom(t *testing.T) {
+	email := model.NewEmail("user_id", "msg_123", "Jane Doe <jane@x.com>", "Subject", "Body", time.Now())
+
+	assert.Equal(t, "Jane Doe <jane@x.com>", email.From)
+	assert.Equal(t, "Jane Doe", email.FromName)
+	assert.Equal(t, "jane@x.com", email.FromAddress)
+}
+
+func TestTruncateBody(t *testing.T) {
+	email := model.NewEmail("user_id", "msg_123", "sender@example.com", "Subject", "héllo world", time.Now())
+
+	// Limit lands in the middle of the two-byte "é", which must not be split
+	assert.True(t, email.TruncateBody(2))
+	assert.Equal(t, "h", email.Body)
+	assert.True(t, email.BodyTruncated)
+
+	small := model.NewEmail("user_id", "msg_456", "sender@example.com", "Subject", "short", time.Now())
+	assert.False(t, small.TruncateBody(100))
+	assert.False(t, small.TruncateBody(0))
+	assert.Equal(t, "short", small.Body)
+	assert.False(t, small.BodyTruncated)
+}
+
+func TestWithoutBodyLeavesOriginalIntact(t *testing.T) {
+	email := model.NewEmail("user_id", "msg_123", "sender@example.com", "Subject", "Body", time.Now())
+
+	stripped := email.WithoutBody()
+
+	assert.Empty(t, stripped.Body)
+	assert.Equal(t, email.ID, stripped.ID)
+	assert.Equal(t, "Body", email.Body)
+}
+
+func TestSetSnippet(t *testing.T) {
+	email := model.NewEmail("user_id", "msg_123", "sender@example.com", "Subject", "", time.Now())
+
+	email.SetSnippet("  Hello\n\n  there,\tfriend  ")
+	assert.Equal(t, "Hello there, friend", email.Snippet)
+
+	// Long text is cut on a word boundary, counting characters rather than bytes
+	email.SetSnippet(strings.Repeat("héllo ", 40))
+	assert.LessOrEqual(t, len([]rune(email.Snippet)), model.SnippetLength)
+	assert.True(t, strings.HasSuffix(email.Snippet, "héllo…"), email.Snippet)
+}
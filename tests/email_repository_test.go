@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailRepositoryFindByUserIDOrderAndLimit(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	oldest := model.NewEmail("user_1", "msg_1", "a@example.com", "Oldest", "Body", now.Add(-2*time.Hour))
+	newest := model.NewEmail("user_1", "msg_2", "a@example.com", "Newest", "Body", now)
+	middle := model.NewEmail("user_1", "msg_3", "a@example.com", "Middle", "Body", now.Add(-1*time.Hour))
+	other := model.NewEmail("user_2", "msg_4", "a@example.com", "Other user", "Body", now)
+	for _, email := range []*model.Email{oldest, newest, middle, other} {
+		assert.NoError(t, emailRepo.Create(ctx, email))
+	}
+
+	emails, err := emailRepo.FindByUserID(ctx, "user_1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 3)
+	assert.Equal(t, []string{newest.ID, middle.ID, oldest.ID}, []string{emails[0].ID, emails[1].ID, emails[2].ID})
+
+	limited, err := emailRepo.FindByUserID(ctx, "user_1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, limited, 2)
+	assert.Equal(t, newest.ID, limited[0].ID)
+	assert.Equal(t, middle.ID, limited[1].ID)
+}
+
+func TestEmailRepositorySoftDeleteAndRestore(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	ctx := context.Background()
+
+	email := model.NewEmail("user_1", "msg_1", "a@example.com", "Subject", "Body", time.Now())
+	assert.NoError(t, emailRepo.Create(ctx, email))
+
+	// Deleting hides the email from listings but keeps it in the trash
+	assert.NoError(t, emailRepo.Delete(ctx, email.ID))
+	emails, err := emailRepo.FindByUserID(ctx, "user_1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 0)
+
+	trash, err := emailRepo.FindDeletedByUserID(ctx, "user_1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+
+	// Restoring brings it back
+	assert.NoError(t, emailRepo.Restore(ctx, email.ID))
+	emails, err = emailRepo.FindByUserID(ctx, "user_1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 1)
+
+	// Purge only removes emails trashed before the cutoff
+	assert.NoError(t, emailRepo.Delete(ctx, email.ID))
+	purged, err := emailRepo.PurgeDeletedBefore(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+
+	purged, err = emailRepo.PurgeDeletedBefore(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	_, err = emailRepo.FindByID(ctx, email.ID)
+	assert.Error(t, err)
+}
+
+func TestEmailRepositoryFindByFilterLocalArchive(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	ctx := context.Background()
+
+	visible := model.NewEmail("user_1", "msg_1", "a@example.com", "Visible", "Body", time.Now())
+	visible.CategoryID = "cat_1"
+	hidden := model.NewEmail("user_1", "msg_2", "a@example.com", "Hidden", "Body", time.Now())
+	hidden.CategoryID = "cat_1"
+	hidden.LocallyArchived = true
+	other := model.NewEmail("user_2", "msg_3", "b@example.com", "Other", "Body", time.Now())
+	other.CategoryID = "cat_1"
+	for _, email := range []*model.Email{visible, hidden, other} {
+		assert.NoError(t, emailRepo.Create(ctx, email))
+	}
+
+	// Locally archived emails are hidden by default
+	emails, err := emailRepo.FindByFilter(ctx, "user_1", model.EmailFilter{CategoryID: "cat_1"}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 1)
+	assert.Equal(t, visible.ID, emails[0].ID)
+
+	emails, err = emailRepo.FindByFilter(ctx, "user_1", model.EmailFilter{Archive: model.ArchiveFilterOnly}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 1)
+	assert.Equal(t, hidden.ID, emails[0].ID)
+
+	emails, err = emailRepo.FindByFilter(ctx, "user_1", model.EmailFilter{Archive: model.ArchiveFilterAll}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 2)
+}
+
+func TestEmailRepositoryFindAdjacentWithinFilter(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	newest := model.NewEmail("user_1", "msg_1", "a@example.com", "Newest", "Body", now)
+	middle := model.NewEmail("user_1", "msg_2", "a@example.com", "Middle", "Body", now.Add(-time.Hour))
+	oldest := model.NewEmail("user_1", "msg_3", "a@example.com", "Oldest", "Body", now.Add(-2*time.Hour))
+	elsewhere := model.NewEmail("user_1", "msg_4", "b@example.com", "Other category", "Body", now.Add(-30*time.Minute))
+	elsewhere.CategoryID = "cat_2"
+	for _, email := range []*model.Email{newest, middle, oldest} {
+		email.CategoryID = "cat_1"
+	}
+	for _, email := range []*model.Email{newest, middle, oldest, elsewhere} {
+		assert.NoError(t, emailRepo.Create(ctx, email))
+	}
+
+	filter := model.EmailFilter{CategoryID: "cat_1"}
+	previousID, nextID, err := emailRepo.FindAdjacent(ctx, "user_1", filter, middle)
+	assert.NoError(t, err)
+	assert.Equal(t, newest.ID, previousID)
+	assert.Equal(t, oldest.ID, nextID)
+
+	previousID, nextID, err = emailRepo.FindAdjacent(ctx, "user_1", filter, newest)
+	assert.NoError(t, err)
+	assert.Empty(t, previousID)
+	assert.Equal(t, middle.ID, nextID)
+
+	// Without a category the other email sits between newest and middle
+	previousID, nextID, err = emailRepo.FindAdjacent(ctx, "user_1", model.EmailFilter{}, newest)
+	assert.NoError(t, err)
+	assert.Empty(t, previousID)
+	assert.Equal(t, elsewhere.ID, nextID)
+
+	count, err := emailRepo.CountByFilter(ctx, "user_1", model.EmailFilter{Sender: "A@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
@@ -22,16 +22,16 @@ import (
 // MockAIClientWithSummary simulates the AI client for testing
 type MockAIClientWithSummary struct {
 	ClassifyEmailFunc  func(ctx context.Context, emailBody string, categories []*model.Category) (string, error)
-	SummarizeEmailFunc func(ctx context.Context, emailBody string) (string, error)
+	SummarizeEmailFunc func(ctx context.Context, emailBody, language string) (string, error)
 	SummarizeResponse string
 	ClassifyResponse  string
 	ExpectedBody      string
 	ExpectedCategories []string
 }
 
-func (m *MockAIClientWithSummary) SummarizeEmail(ctx context.Context, emailBody string) (string, error) {
+func (m *MockAIClientWithSummary) SummarizeEmail(ctx context.Context, emailBody, language string) (string, error) {
 	if m.SummarizeEmailFunc != nil {
-		return m.SummarizeEmailFunc(ctx, emailBody)
+		return m.SummarizeEmailFunc(ctx, emailBody, language)
 	}
 	
 	// Verify the email body contains multiple paragraphs as expected
@@ -42,6 +42,10 @@ func (m *MockAIClientWithSummary) SummarizeEmail(ctx context.Context, emailBody
 	return m.SummarizeResponse, nil
 }
 
+func (m *MockAIClientWithSummary) Analyze(ctx context.Context, task, content string) (string, error) {
+	return "", nil
+}
+
 func (m *MockAIClientWithSummary) ClassifyEmail(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
 	if m.ClassifyEmailFunc != nil {
 		return m.ClassifyEmailFunc(ctx, emailBody, categories)
@@ -176,7 +180,7 @@ The Team`
 		ctx2 := context.Background()
 		
 		// For this test, we'll just directly test the AI summarization
-		summary2, err := mockAIClient2.SummarizeEmail(ctx2, email2.Body)
+		summary2, err := mockAIClient2.SummarizeEmail(ctx2, email2.Body, "")
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -348,7 +352,7 @@ func TestRealAICall(t *testing.T) {
 	mockAIClient := ai.NewMockAIClient()
 	
 	// Override the default behavior to return a specific summary
-	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody string) (string, error) {
+	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
 		// Verify the body has 3 paragraphs
 		paragraphCount := strings.Count(emailBody, "\n\n") + 1
 		
@@ -379,11 +383,123 @@ Project Team`
 
 	// Call the AI client to summarize
 	ctx := context.Background()
-	summary, err := mockAIClient.SummarizeEmail(ctx, sampleEmail)
+	summary, err := mockAIClient.SummarizeEmail(ctx, sampleEmail, "")
 	
 	assert.NoError(t, err, "SummarizeEmail should not return an error")
 	assert.NotEmpty(t, summary, "Summary should not be empty")
 	assert.True(t, len(summary) < len(sampleEmail), 
 		"Summary (%d chars) should be shorter than original (%d chars)", 
 		len(summary), len(sampleEmail))
-}
\ No newline at end of file
+}
+func TestLongEmailsAreSummarizedInChunks(t *testing.T) {
+	ctx := context.Background()
+	var classified string
+	var summarized []string
+	var combined string
+	aiClient := ai.NewMockAIClient()
+	aiClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		classified = emailBody
+		return categories[0].Name, nil
+	}
+	aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+		summarized = append(summarized, emailBody)
+		return fmt.Sprintf("summary %d", len(summarized)), nil
+	}
+	aiClient.AnalyzeFunc = func(ctx context.Context, task, content string) (string, error) {
+		combined = content
+		return "The whole email in short.", nil
+	}
+	emailService := service.NewEmailService(memory.NewInMemoryEmailRepository(), memory.NewInMemoryCategoryRepository(),
+		memory.NewInMemoryUserRepository(), nil, aiClient, logger.New())
+	emailService.SetAIInputLimits(100, 3) // about 400 characters per request
+	category := model.NewCategory("Work", "Work related emails")
+
+	// Five paragraphs of about 300 characters, in HTML
+	var body strings.Builder
+	body.WriteString("<html><head><style>p { color: red }</style></head><body>")
+	for i := range 5 {
+		fmt.Fprintf(&body, "<p>Paragraph %d %s</p>", i+1, strings.Repeat("lorem ipsum ", 24))
+	}
+	body.WriteString("</body></html>")
+	email := model.NewEmail("user_1", "gmail_1", "boss@example.com", "Report", body.String(), time.Now())
+
+	assert.NoError(t, emailService.ClassifyAndSummarizeEmail(ctx, email, []*model.Category{category}))
+
+	// Markup is stripped and the classification reads the start of the text
+	assert.True(t, strings.HasPrefix(classified, "Paragraph 1 lorem ipsum"), classified)
+	assert.NotContains(t, classified, "<p>")
+	assert.NotContains(t, classified, "color: red")
+	assert.LessOrEqual(t, len(classified), 400)
+
+	// Each chunk ends between paragraphs, the last two are left out, and the chunk summaries are combined
+	if assert.Len(t, summarized, 3) {
+		for i, chunk := range summarized {
+			assert.True(t, strings.HasPrefix(chunk, fmt.Sprintf("Paragraph %d ", i+1)), chunk)
+			assert.LessOrEqual(t, len(chunk), 400)
+		}
+	}
+	assert.Equal(t, "Part 1: summary 1\n\nPart 2: summary 2\n\nPart 3: summary 3", combined)
+	assert.Equal(t, "The whole email in short.", email.Summary)
+
+	// Short emails take a single request
+	summarized = nil
+	email = model.NewEmail("user_1", "gmail_2", "boss@example.com", "Hi", "<p>See you at 3pm</p>", time.Now())
+	assert.NoError(t, emailService.ClassifyAndSummarizeEmail(ctx, email, []*model.Category{category}))
+	assert.Equal(t, []string{"See you at 3pm"}, summarized)
+	assert.Equal(t, "summary 1", email.Summary)
+}
+
+func TestRejectedSummariesAreAskedAgain(t *testing.T) {
+	ctx := context.Background()
+	summaries := []string{"I'm sorry, but I can't help with that request."}
+	var tasks []string
+	aiClient := ai.NewMockAIClient()
+	aiClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		return categories[0].Name, nil
+	}
+	aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+		return summaries[0], nil
+	}
+	aiClient.AnalyzeFunc = func(ctx context.Context, task, content string) (string, error) {
+		tasks = append(tasks, task)
+		return summaries[len(summaries)-1], nil
+	}
+	emailRepo := memory.NewInMemoryEmailRepository()
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(),
+		memory.NewInMemoryUserRepository(), nil, aiClient, logger.New())
+	category := model.NewCategory("Promotions", "Deals and offers")
+
+	// A refusal is followed by a stricter request, whose answer is kept
+	summaries = append(summaries, "A store announces a weekend sale on shoes.")
+	email := model.NewEmail("user_1", "gmail_1", "shop@example.com", "Sale", "Shoes are 50% off this weekend only.", time.Now())
+	assert.NoError(t, emailService.ClassifyAndSummarizeEmail(ctx, email, []*model.Category{category}))
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "A store announces a weekend sale on shoes.", email.Summary)
+	assert.False(t, email.NeedsReprocessing)
+
+	// Summaries in another script than the email's are rejected too; when the retry is no
+	// better, the email is kept without a summary and marked for reprocessing
+	summaries = []string{"Магазин объявляет распродажу."}
+	tasks = nil
+	email = model.NewEmail("user_1", "gmail_2", "shop@example.com", "Sale", "Boots are 30% off until Sunday.", time.Now())
+	assert.NoError(t, emailService.ClassifyAndSummarizeEmail(ctx, email, []*model.Category{category}))
+	assert.Len(t, tasks, 1)
+	assert.Empty(t, email.Summary)
+	assert.True(t, email.NeedsReprocessing)
+	assert.NoError(t, emailRepo.Create(ctx, email))
+
+	// Reprocessing stores the summary once the AI gives a usable one
+	fixed, err := emailService.ReprocessEmails(ctx, "user_1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fixed)
+
+	summaries = []string{"A store has boots on sale until Sunday."}
+	fixed, err = emailService.ReprocessEmails(ctx, "user_1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fixed)
+	stored, err := emailRepo.FindByID(ctx, email.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "A store has boots on sale until Sunday.", stored.Summary)
+		assert.False(t, stored.NeedsReprocessing)
+	}
+}
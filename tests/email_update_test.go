@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// racingEmailUpdates saves a summary over the email before the first updates, as if another
+// request did it between the service reading and saving the email
+type racingEmailUpdates struct {
+	repository.EmailRepository
+	races int
+}
+
+func (r *racingEmailUpdates) Update(ctx context.Context, email *model.Email) error {
+	if r.races > 0 {
+		r.races--
+		current, err := r.EmailRepository.FindByID(ctx, email.ID)
+		if err != nil {
+			return err
+		}
+		current.Summary = "Summarized meanwhile"
+		if err := r.EmailRepository.Update(ctx, current); err != nil {
+			return err
+		}
+	}
+	return r.EmailRepository.Update(ctx, email)
+}
+
+func TestEmailUpdatesRetryConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := &racingEmailUpdates{EmailRepository: memory.NewInMemoryEmailRepository()}
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	emailService := service.NewEmailService(emailRepo, categoryRepo, memory.NewInMemoryUserRepository(), gmail.NewMockGmailClient(), ai.NewMockAIClient(), logger.New())
+
+	work := model.NewCategory("Work", "Colleagues")
+	work.UserID = "user_1"
+	categoryRepo.Create(ctx, work)
+	email := model.NewEmail("user_1", "msg_1", "a@example.com", "Hi", "body", time.Now())
+	emailRepo.Create(ctx, email)
+
+	// The category is applied to the email as the other request saved it
+	emailRepo.races = 1
+	_, err := emailService.CategorizeEmail(ctx, "user_1", email.ID, work.ID)
+	assert.NoError(t, err)
+	stored, err := emailRepo.FindByID(ctx, email.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, work.ID, stored.CategoryID)
+	assert.Equal(t, "Summarized meanwhile", stored.Summary)
+
+	// An email that keeps changing gives up with a conflict
+	emailRepo.races = 5
+	_, err = emailService.CategorizeEmail(ctx, "user_1", email.ID, work.ID)
+	assert.True(t, errors.Is(err, repository.ErrEmailChanged), "%v", err)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailWindowListsCompactRows(t *testing.T) {
+	container, user, request := newTestApp(t)
+	ctx := context.Background()
+
+	// Five emails received at the same time, so only the IDs keep the order stable
+	received := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		email := model.NewEmail(user.ID, fmt.Sprintf("msg_%d", i), "Shop News <news@shop.example>", fmt.Sprintf("Sale %d", i), "A long body", received)
+		email.ID = fmt.Sprintf("email_%d", i)
+		email.Snippet = "Everything must go"
+		email.CategoryID = "cat_deals"
+		email.Unread = i == 0
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		ids = append(ids, email.ID)
+	}
+	other := model.NewEmail(user.ID, "msg_other", "boss@work.example", "Report", "Body", received.Add(-time.Minute))
+	assert.NoError(t, container.EmailRepo.Create(ctx, other))
+
+	window := func(query url.Values) (int, *model.EmailWindow, map[string]interface{}) {
+		rec := serve(container, request(http.MethodGet, "/api/v1/emails/window?"+query.Encode(), ""))
+		var result model.EmailWindow
+		var raw map[string]interface{}
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &raw))
+		}
+		return rec.Code, &result, raw
+	}
+	rowIDs := func(window *model.EmailWindow) []string {
+		var ids []string
+		for _, row := range window.Emails {
+			ids = append(ids, row.ID)
+		}
+		return ids
+	}
+
+	code, page, raw := window(url.Values{"offset": {"1"}, "limit": {"2"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 6, page.Total)
+	assert.Equal(t, 1, page.Offset)
+	assert.Equal(t, []string{ids[1], ids[2]}, rowIDs(page))
+
+	// Rows carry what a list shows and nothing more
+	row := page.Emails[0]
+	assert.Equal(t, "Shop News", row.From)
+	assert.Equal(t, "Sale 1", row.Subject)
+	assert.Equal(t, "Everything must go", row.Snippet)
+	assert.Equal(t, "cat_deals", row.CategoryID)
+	fields := raw["emails"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, fields, "body")
+	assert.NotContains(t, fields, "summary")
+
+	// Windows pick up where the previous one stopped, within a category too
+	_, page, _ = window(url.Values{"offset": {"3"}, "limit": {"10"}, "category_id": {"cat_deals"}})
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, []string{ids[3], ids[4]}, rowIDs(page))
+	_, page, _ = window(url.Values{"offset": {"0"}, "limit": {"1"}})
+	assert.True(t, page.Emails[0].Unread)
+
+	// Past the end the window is empty, but the total still sizes the list
+	_, page, raw = window(url.Values{"offset": {"50"}})
+	assert.Equal(t, 6, page.Total)
+	assert.Equal(t, []interface{}{}, raw["emails"])
+
+	code, _, _ = window(url.Values{"limit": {"500"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = window(url.Values{"offset": {"-1"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
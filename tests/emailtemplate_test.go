@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/emailtemplate"
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const emailTemplateFixtures = "testdata/emailtemplate"
+
+// assertGolden compares a rendered message with its golden files, name.golden.html and
+// name.golden.txt
+func assertGolden(t *testing.T, name string, message *emailtemplate.Message) {
+	for ext, content := range map[string]string{".html": message.HTML, ".txt": message.Text} {
+		golden := filepath.Join(emailTemplateFixtures, name+".golden"+ext)
+		if *updateGolden {
+			assert.NoError(t, os.WriteFile(golden, []byte(content), 0o644))
+		}
+		expected, err := os.ReadFile(golden)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), content, golden)
+	}
+}
+
+func TestEmailTemplateDigestGolden(t *testing.T) {
+	received := time.Date(2026, 3, 9, 8, 30, 0, 0, time.UTC)
+	digest := emailtemplate.Digest{
+		UserName: "Ada",
+		Since:    received.Add(-24 * time.Hour),
+		Sections: []emailtemplate.DigestSection{
+			{Category: "Work", Emails: []emailtemplate.DigestEmail{
+				{From: "Grace Hopper", Subject: "Compiler review <today>", Summary: "Grace wants the review done before the 3pm meeting.", ReceivedAt: received},
+				{From: "build@ci.example", Subject: "Build #42 passed", ReceivedAt: received.Add(time.Hour)},
+			}},
+			{Category: "Newsletters", Emails: []emailtemplate.DigestEmail{
+				{From: "Weekly Go", Subject: "Issue 500", Summary: "Generics tips & tricks.", ReceivedAt: received.Add(2 * time.Hour)},
+			}},
+			{Category: "Sales"},
+		},
+		AppURL: "https://mail.example.com/app",
+	}
+
+	message, err := emailtemplate.RenderDigest(digest)
+	assert.NoError(t, err)
+	assert.Equal(t, "Your digest: 3 new emails", message.Subject)
+	// Content is escaped in the HTML and kept as written in the text
+	assert.Contains(t, message.HTML, "Compiler review &lt;today&gt;")
+	assert.Contains(t, message.Text, "Compiler review <today>")
+	// Categories are colored as in the app, and empty ones are left out
+	assert.Contains(t, message.HTML, "border-left:4px solid #1976d2")
+	assert.NotContains(t, message.HTML, "Sales")
+	assertGolden(t, "digest", message)
+
+	single, err := emailtemplate.RenderDigest(emailtemplate.Digest{Since: received, Sections: digest.Sections[1:2]})
+	assert.NoError(t, err)
+	assert.Equal(t, "Your digest: 1 new email", single.Subject)
+	assert.Contains(t, single.Text, "Here is the email you got")
+	assert.NotContains(t, single.HTML, "Read them in the app")
+}
+
+func TestEmailTemplateReportGolden(t *testing.T) {
+	report := model.NewInboxReport("user_1", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), model.ReportStats{
+		TotalEmails: 120,
+		Categories: []model.CategoryVolume{
+			{CategoryID: "cat_1", Name: "Newsletters", Count: 60},
+			{CategoryID: "cat_2", Name: "Financial", Count: 45},
+			{CategoryID: "", Name: "Uncategorized", Count: 0},
+		},
+		TopSenders: []model.SenderVolume{
+			{Address: "news@shop.example", Name: "Shop", Count: 30},
+			{Address: "alerts@bank.example", Count: 12},
+		},
+		Summarized:       100,
+		Archived:         80,
+		Unsubscribes:     1,
+		TimeSavedMinutes: 93,
+	})
+
+	message, err := emailtemplate.RenderReport(emailtemplate.Report{UserName: "Ada", Report: report, AppURL: "https://mail.example.com/app"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Your week in email: Mar 2, 2026 - Mar 8, 2026", message.Subject)
+	assert.Contains(t, message.Text, "Time saved: 1 h 33 min")
+	assert.Contains(t, message.Text, "Unsubscribed from: 1 sender")
+	// Bars are sized against the busiest category
+	assert.Contains(t, message.HTML, "width:100%;height:10px")
+	assert.Contains(t, message.HTML, "width:75%;height:10px")
+	assertGolden(t, "report", message)
+}
+
+func TestEmailTemplateColors(t *testing.T) {
+	assert.Equal(t, emailtemplate.CategoryColor{Background: "#e8f5e9", Text: "#388e3c"}, emailtemplate.ColorOf("Finances"))
+	assert.Equal(t, emailtemplate.ColorOf("Anything"), emailtemplate.ColorOf("Uncategorized"))
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyPromptVariants(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeAIServer(t, openAIAnswer("Work"))
+	client := ai.NewAIClientWithBaseURL(ai.ProviderOpenAI, "sk-test", "", fake.URL, logger.New())
+
+	assert.Error(t, client.UseClassifyPrompt("no-such-prompt"))
+	assert.Contains(t, ai.ClassifyPrompts(), ai.DefaultClassifyPrompt)
+	assert.NoError(t, client.UseClassifyPrompt("compact"))
+
+	category, err := client.ClassifyEmail(ctx, "Can you send the report?", aiTestCategories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Work", category)
+	sent, _ := json.Marshal(fake.lastRequest(t).body)
+	assert.Contains(t, string(sent), `- Work: Emails from colleagues\n- Shopping: Orders and receipts`)
+}
+
+func TestClassificationExperiment(t *testing.T) {
+	ctx := context.Background()
+	control := ai.NewMockAIClient()
+	control.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		return "Work", nil
+	}
+	treatment := ai.NewMockAIClient()
+	treatment.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		return "Shopping", nil
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	var synced []*model.Email
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	container, _, _ := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AdminEmails = []string{"ops@example.com"}
+	}), withAppOptions(app.WithGmailClient(gmailClient), app.WithAIClient(control)))
+
+	user := model.NewUser("google_1", "ops@example.com", "Ops", "access_token", "refresh_token", time.Time{})
+	member := model.NewUser("google_2", "member@example.com", "Member", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, user)
+	container.UserRepo.Create(ctx, member)
+	work, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Colleagues")
+	assert.NoError(t, err)
+	shopping, err := container.CategoryService.CreateCategory(ctx, user.ID, "Shopping", "Orders")
+	assert.NoError(t, err)
+
+	request := func(user *model.User, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+		return serve(container, req)
+	}
+	report := func(query string) *model.ExperimentReport {
+		rec := request(user, http.MethodGet, "/admin/experiment"+query, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var report model.ExperimentReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return &report
+	}
+	syncEmails := func(prefix string, count int) []*model.Email {
+		synced = nil
+		for i := 0; i < count; i++ {
+			synced = append(synced, model.NewEmail("", fmt.Sprintf("%s_%d", prefix, i), "someone@example.com", "Hello", "<p>hello</p>", time.Now()))
+		}
+		_, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: int64(count)})
+		assert.NoError(t, err)
+
+		emails := make([]*model.Email, count)
+		for i := range emails {
+			emails[i], err = container.EmailRepo.FindByGmailID(ctx, user.ID, fmt.Sprintf("%s_%d", prefix, i))
+			assert.NoError(t, err)
+		}
+		return emails
+	}
+
+	// Without an experiment nothing is routed or reported
+	assert.Equal(t, http.StatusNotFound, request(user, http.MethodGet, "/admin/experiment", "").Code)
+
+	container.Experiments.StartExperiment("compact-prompt", 50,
+		model.ExperimentArm{Provider: "openai", Prompt: "default"},
+		model.ExperimentArm{Provider: "openai", Prompt: "compact"}, treatment)
+
+	// Each email is classified by its arm's client, the same arm every time
+	var treated, controlled []*model.Email
+	for _, email := range syncEmails("email", 20) {
+		arm, _ := container.Experiments.Arm(email.ID)
+		again, _ := container.Experiments.Arm(email.ID)
+		assert.Equal(t, arm, again)
+		if arm == model.ArmTreatment {
+			assert.Equal(t, shopping.ID, email.CategoryID)
+			treated = append(treated, email)
+		} else {
+			assert.Equal(t, work.ID, email.CategoryID)
+			controlled = append(controlled, email)
+		}
+	}
+	if !assert.NotEmpty(t, treated) || !assert.NotEmpty(t, controlled) {
+		return
+	}
+
+	// Users moving an email count against the arm that classified it
+	rec := request(user, http.MethodPut, "/emails/"+treated[0].ID+"/category", `{"category_id":"`+work.ID+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	results := report("")
+	assert.Equal(t, "compact-prompt", results.Experiment)
+	assert.True(t, results.Running)
+	assert.Equal(t, 50, results.Percent)
+	if assert.Len(t, results.Arms, 2) {
+		assert.Equal(t, model.ArmControl, results.Arms[0].Name)
+		assert.Equal(t, len(controlled), results.Arms[0].Classified)
+		assert.Zero(t, results.Arms[0].Corrected)
+		assert.Equal(t, 1.0, results.Arms[0].Accuracy)
+
+		assert.Equal(t, model.ArmTreatment, results.Arms[1].Name)
+		assert.Equal(t, "compact", results.Arms[1].Prompt)
+		assert.Equal(t, len(treated), results.Arms[1].Classified)
+		assert.Equal(t, 1, results.Arms[1].Corrected)
+		assert.InDelta(t, float64(len(treated)-1)/float64(len(treated)), results.Arms[1].Accuracy, 0.001)
+	}
+
+	// Moving it back takes the correction back
+	_, err = container.EmailService.CategorizeEmail(ctx, user.ID, treated[0].ID, shopping.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, report("").Arms[1].Corrected)
+
+	// When the treatment fails, the control classifies the email
+	failing := ai.NewMockAIClient()
+	failing.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		return "", errors.New("model not found")
+	}
+	container.Experiments.StartExperiment("failing-model", 100,
+		model.ExperimentArm{Provider: "openai", Prompt: "default"},
+		model.ExperimentArm{Provider: "openai", Model: "missing", Prompt: "default"}, failing)
+	for _, email := range syncEmails("failing", 2) {
+		assert.Equal(t, work.ID, email.CategoryID)
+	}
+	results = report("")
+	if assert.Len(t, results.Arms, 2) {
+		assert.Equal(t, 2, results.Arms[0].Classified)
+		assert.Zero(t, results.Arms[1].Classified)
+	}
+
+	// Past experiments are still reported, with their counts only
+	results = report("?name=compact-prompt")
+	assert.False(t, results.Running)
+	if assert.Len(t, results.Arms, 2) {
+		assert.Equal(t, len(treated), results.Arms[1].Classified)
+		assert.Empty(t, results.Arms[1].Prompt)
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(member, http.MethodGet, "/admin/experiment", "").Code)
+}
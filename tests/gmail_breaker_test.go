@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGmailCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	// Gmail answers Ada with the error set for it, counting the calls that reached it
+	var mutex sync.Mutex
+	var answer error
+	calls := 0
+	setAnswer := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		answer = err
+	}
+	callCount := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return calls
+	}
+	client := gmail.NewMockGmailClient()
+	client.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls++
+		if userEmail != "ada@example.com" {
+			return nil
+		}
+		return answer
+	}
+	monitor := service.NewGmailMonitor(client, 2, 200*time.Millisecond, logger.New())
+	markAsRead := func() error {
+		return monitor.Modify(ctx, "ada@example.com", "msg_1", nil, []string{model.LabelUnread})
+	}
+
+	// Refusals of the request itself don't count
+	setAnswer(errors.New("failed to modify email: 404 Not Found"))
+	for range 3 {
+		assert.Error(t, markAsRead())
+	}
+	assert.Equal(t, model.BreakerClosed, monitor.Health("ada@example.com").Breaker)
+
+	// Refused credentials in a row open the breaker
+	revoked := fmt.Errorf("failed to modify email: %w: 401 Unauthorized", service.ErrGmailUnauthorized)
+	setAnswer(revoked)
+	assert.ErrorIs(t, markAsRead(), service.ErrGmailUnauthorized)
+	assert.Equal(t, model.BreakerClosed, monitor.Health("ada@example.com").Breaker)
+	assert.ErrorIs(t, markAsRead(), service.ErrGmailUnauthorized)
+	health := monitor.Health("ada@example.com")
+	assert.Equal(t, model.BreakerOpen, health.Breaker)
+	assert.Equal(t, model.GmailReachable, health.Status) // Gmail answered, refusing the token
+	assert.NotNil(t, health.RetryAt)
+	assert.Contains(t, health.LastError, "401 Unauthorized")
+
+	// While open, calls fail without reaching Gmail, as unavailable
+	reached := callCount()
+	err := markAsRead()
+	assert.ErrorIs(t, err, service.ErrGmailCircuitOpen)
+	assert.ErrorIs(t, err, service.ErrGmailUnauthorized)
+	assert.ErrorIs(t, err, apierror.ErrUnavailable)
+	assert.Equal(t, reached, callCount())
+
+	// Other users aren't affected
+	assert.NoError(t, monitor.Modify(ctx, "bob@example.com", "msg_2", nil, []string{model.LabelUnread}))
+	assert.Equal(t, reached+1, callCount())
+
+	// After the cooldown, a failed probe opens it again for twice as long
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, model.BreakerHalfOpen, monitor.Health("ada@example.com").Breaker)
+	probed := time.Now()
+	assert.ErrorIs(t, markAsRead(), service.ErrGmailUnauthorized)
+	assert.Equal(t, reached+2, callCount())
+	health = monitor.Health("ada@example.com")
+	assert.Equal(t, model.BreakerOpen, health.Breaker)
+	assert.True(t, health.RetryAt.Sub(probed) > 300*time.Millisecond, "retry at %v", health.RetryAt)
+	assert.ErrorIs(t, markAsRead(), service.ErrGmailCircuitOpen)
+
+	// A successful probe closes it
+	time.Sleep(450 * time.Millisecond)
+	setAnswer(nil)
+	assert.NoError(t, markAsRead())
+	assert.Equal(t, &model.GmailHealth{Status: model.GmailReachable, Breaker: model.BreakerClosed}, monitor.Health("ada@example.com"))
+
+	breakers := monitor.Breakers()
+	assert.Len(t, breakers, 2)
+	assert.Equal(t, model.GmailBreaker{
+		UserEmail: "ada@example.com",
+		State:     model.BreakerClosed,
+		Requests:  7,
+		Failures:  3, // the 404s were answers
+		Rejected:  2,
+	}, breakers[0])
+	assert.Equal(t, "bob@example.com", breakers[1].UserEmail)
+
+	// Outages open it too, and calls failed meanwhile still count as Gmail being unreachable
+	setAnswer(fmt.Errorf("failed to modify email: %w: 503 Service Unavailable", service.ErrGmailUnreachable))
+	assert.Error(t, markAsRead())
+	assert.Error(t, markAsRead())
+	err = markAsRead()
+	assert.ErrorIs(t, err, service.ErrGmailCircuitOpen)
+	assert.ErrorIs(t, err, service.ErrGmailUnreachable)
+	health = monitor.Health("ada@example.com")
+	assert.Equal(t, model.GmailUnreachable, health.Status)
+	assert.Equal(t, model.BreakerOpen, health.Breaker)
+}
+
+func TestGmailCircuitBreakerPausesSync(t *testing.T) {
+	ctx := context.Background()
+	// The user's token was revoked
+	var mutex sync.Mutex
+	syncs := 0
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		syncs++
+		return nil, fmt.Errorf("failed to list messages: %w: 401 Unauthorized", service.ErrGmailUnauthorized)
+	}
+	container, user, request := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AdminEmails = []string{"ops@example.com"}
+		cfg.GmailBreakerFailures = 2
+		cfg.GmailBreakerCooldown = time.Hour
+	}), withAppOptions(app.WithGmailClient(gmailClient)), withUser(func(user *model.User) {
+		user.Email = "ada@example.com"
+	}))
+	operator := model.NewUser("google_2", "ops@example.com", "Ops", "access_token", "refresh_token", time.Time{})
+	assert.NoError(t, container.UserRepo.Create(ctx, operator))
+
+	// Once the breaker opens, sync cycles stop calling Gmail for the user
+	events := container.SSEManager.AddClient(user.ID)
+	defer container.SSEManager.RemoveClient(user.ID, events)
+	for range 5 {
+		container.EmailSyncJob.RunSync()
+	}
+	mutex.Lock()
+	assert.Equal(t, 2, syncs)
+	mutex.Unlock()
+
+	call := func(userID, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1"+path, nil)
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		return serve(container, req)
+	}
+
+	// The user sees the breaker state, and syncing by hand is unavailable
+	rec := call(user.ID, "/me")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var me handler.MeResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &me))
+	assert.Equal(t, model.BreakerOpen, me.Gmail.Breaker)
+	assert.NotNil(t, me.Gmail.RetryAt)
+
+	rec = serve(container, request(http.MethodPost, "/api/v1/emails/sync", ""))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, rec.Body.String())
+
+	// Operators see every user's breaker
+	assert.Equal(t, http.StatusForbidden, call(user.ID, "/admin/gmail-breakers").Code)
+	rec = call(operator.ID, "/admin/gmail-breakers")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var breakers []model.GmailBreaker
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &breakers))
+	assert.Len(t, breakers, 1)
+	assert.Equal(t, "ada@example.com", breakers[0].UserEmail)
+	assert.Equal(t, model.BreakerOpen, breakers[0].State)
+	assert.Equal(t, 2, breakers[0].ConsecutiveFailures)
+	assert.Equal(t, int64(2), breakers[0].Requests)
+	assert.True(t, breakers[0].Rejected >= 3)
+}
@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Run `go test ./tests -run Golden -update` after changing body extraction or the email
+// templates on purpose, then review the diff of the golden files
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the Gmail payload and email template tests")
+
+const gmailFixtures = "testdata/gmail"
+
+// fakeGmailServer serves recorded Gmail API messages and records every request it gets
+type fakeGmailServer struct {
+	*httptest.Server
+	messages map[string]json.RawMessage
+
+	mutex    sync.Mutex
+	requests []string // "METHOD path body"
+}
+
+func newFakeGmailServer(t *testing.T, messages map[string]json.RawMessage) *fakeGmailServer {
+	fake := &fakeGmailServer{messages: messages}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (f *fakeGmailServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mutex.Lock()
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+strings.TrimSpace(string(body))))
+	f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		http.Error(w, `{"error":{"code":401,"message":"unauthenticated"}}`, http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.TrimPrefix(r.URL.Path, "/gmail/v1/users/me/")
+	switch {
+	case r.Method == http.MethodGet && path == "messages":
+		ids := make([]string, 0, len(f.messages))
+		for id := range f.messages {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		list := map[string]any{"resultSizeEstimate": len(ids)}
+		var refs []map[string]string
+		for _, id := range ids {
+			refs = append(refs, map[string]string{"id": id, "threadId": "t_" + id})
+		}
+		list["messages"] = refs
+		json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "messages/"):
+		message, ok := f.messages[strings.TrimPrefix(path, "messages/")]
+		if !ok {
+			http.Error(w, `{"error":{"code":404,"message":"Requested entity was not found."}}`, http.StatusNotFound)
+			return
+		}
+		w.Write(message)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/modify"):
+		w.Write([]byte(`{"id":"` + strings.TrimSuffix(strings.TrimPrefix(path, "messages/"), "/modify") + `"}`))
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/trash"):
+		w.Write([]byte(`{"id":"` + strings.TrimSuffix(strings.TrimPrefix(path, "messages/"), "/trash") + `","labelIds":["TRASH"]}`))
+	case r.Method == http.MethodPost && path == "watch":
+		w.Write([]byte(`{"historyId":"1234","expiration":"1704207845000"}`))
+	case r.Method == http.MethodGet && path == "labels":
+		w.Write([]byte(`{"labels":[{"id":"INBOX","name":"INBOX"},{"id":"Label_1","name":"Receipts"}]}`))
+	case r.Method == http.MethodPost && path == "labels":
+		w.Write([]byte(`{"id":"Label_2","name":"Travel"}`))
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "messages/"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+	}
+}
+
+func (f *fakeGmailServer) Requests() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// loadGmailFixtures reads every recorded message in testdata/gmail, keyed by fixture name
+func loadGmailFixtures(t *testing.T) map[string]json.RawMessage {
+	paths, err := filepath.Glob(filepath.Join(gmailFixtures, "*.json"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, paths)
+
+	messages := make(map[string]json.RawMessage)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		messages[strings.TrimSuffix(filepath.Base(path), ".json")] = data
+	}
+	return messages
+}
+
+func newTestGmailClient(t *testing.T, fake *fakeGmailServer) service.MailClient {
+	client, err := gmail.NewGmailClientWithEndpoint("test-token", fake.URL+"/", logger.New())
+	assert.NoError(t, err)
+	return client
+}
+
+// fetchMessages lists the user's newest messages and gets each of them
+func fetchMessages(client service.MailClient, userEmail string, maxResults int64) ([]*model.Email, error) {
+	ctx := context.Background()
+	messageIDs, err := client.List(ctx, userEmail, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	var emails []*model.Email
+	for _, messageID := range messageIDs {
+		email, err := client.Get(ctx, userEmail, messageID)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+func TestGmailPayloadGolden(t *testing.T) {
+	messages := loadGmailFixtures(t)
+	client := newTestGmailClient(t, newFakeGmailServer(t, messages))
+
+	emails, err := fetchMessages(client, "bob@example.com", int64(len(messages)))
+	assert.NoError(t, err)
+	assert.Len(t, emails, len(messages))
+
+	for _, email := range emails {
+		t.Run(email.GmailID, func(t *testing.T) {
+			golden := filepath.Join(gmailFixtures, email.GmailID+".golden.html")
+			if *updateGolden {
+				assert.NoError(t, os.WriteFile(golden, []byte(email.Body), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), email.Body)
+		})
+	}
+}
+
+func TestGmailClientParsesHeadersAndLabels(t *testing.T) {
+	messages := loadGmailFixtures(t)
+	client := newTestGmailClient(t, newFakeGmailServer(t, map[string]json.RawMessage{"html_only": messages["html_only"]}))
+
+	emails, err := fetchMessages(client, "bob@example.com", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, emails, 1) {
+		email := emails[0]
+		assert.Equal(t, "HTML only", email.Subject)
+		assert.Equal(t, "Alice Example <alice@example.com>", email.From)
+		assert.Equal(t, "bob@example.com", email.To)
+		assert.Equal(t, "<html_only@mail.example.com>", email.MessageID)
+		assert.True(t, email.ReceivedAt.Equal(time.Unix(1704207845, 0)))
+		if assert.NotNil(t, email.SentAt) {
+			assert.True(t, email.SentAt.Equal(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)))
+		}
+		assert.True(t, email.Starred)
+		assert.True(t, email.Important)
+		assert.False(t, email.Unread)
+	}
+}
+
+func TestGmailClientLabelRequests(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeGmailServer(t, nil)
+	client := newTestGmailClient(t, fake)
+
+	assert.NoError(t, client.Modify(ctx, "bob@example.com", "msg_1", nil, []string{model.LabelInbox, model.LabelUnread}))
+	assert.NoError(t, client.Modify(ctx, "bob@example.com", "msg_1", []string{model.LabelStarred}, nil))
+	assert.NoError(t, client.Trash(ctx, "bob@example.com", "msg_2"))
+	watch, err := client.Watch(ctx, "bob@example.com", "projects/app/topics/gmail")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), watch.HistoryID)
+	assert.True(t, watch.Expiration.Equal(time.UnixMilli(1704207845000)))
+
+	// Existing labels are matched by name, others are created
+	labelID, err := client.GetOrCreateLabel(ctx, "bob@example.com", "receipts")
+	assert.NoError(t, err)
+	assert.Equal(t, "Label_1", labelID)
+	labelID, err = client.GetOrCreateLabel(ctx, "bob@example.com", "Travel")
+	assert.NoError(t, err)
+	assert.Equal(t, "Label_2", labelID)
+
+	assert.Equal(t, []string{
+		`POST /gmail/v1/users/me/messages/msg_1/modify {"removeLabelIds":["INBOX","UNREAD"]}`,
+		`POST /gmail/v1/users/me/messages/msg_1/modify {"addLabelIds":["STARRED"]}`,
+		`POST /gmail/v1/users/me/messages/msg_2/trash`,
+		`POST /gmail/v1/users/me/watch {"labelIds":["INBOX"],"topicName":"projects/app/topics/gmail"}`,
+		`GET /gmail/v1/users/me/labels`,
+		`GET /gmail/v1/users/me/labels`,
+		`POST /gmail/v1/users/me/labels {"labelListVisibility":"labelShow","messageListVisibility":"show","name":"Travel"}`,
+	}, fake.Requests())
+
+	// API errors are surfaced
+	unauthorized, _ := gmail.NewGmailClientWithEndpoint("wrong-token", fake.URL+"/", logger.New())
+	err = unauthorized.Modify(ctx, "bob@example.com", "msg_1", nil, []string{model.LabelUnread})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, service.ErrGmailUnreachable))
+	assert.True(t, errors.Is(err, service.ErrGmailUnauthorized), "got %v", err)
+}
+
+func TestGmailClientReportsOutages(t *testing.T) {
+	ctx := context.Background()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":503,"message":"backend error"}}`, http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	// Server errors and unreachable servers are outages
+	client, err := gmail.NewGmailClientWithEndpoint("test-token", failing.URL+"/", logger.New())
+	assert.NoError(t, err)
+	err = client.Modify(ctx, "bob@example.com", "msg_1", nil, []string{model.LabelUnread})
+	assert.True(t, errors.Is(err, service.ErrGmailUnreachable), "got %v", err)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	client, err = gmail.NewGmailClientWithEndpoint("test-token", closed.URL+"/", logger.New())
+	assert.NoError(t, err)
+	_, err = client.List(ctx, "bob@example.com", 10)
+	assert.True(t, errors.Is(err, service.ErrGmailUnreachable), "got %v", err)
+
+	// A canceled request isn't one
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = client.Send(canceled, "bob@example.com", []byte("Subject: Hi\r\n\r\nHello"))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, service.ErrGmailUnreachable))
+}
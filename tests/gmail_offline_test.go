@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGmailOfflineMode(t *testing.T) {
+	ctx := context.Background()
+	// Gmail answers until offline is set, and records the changes that reached it
+	var mutex sync.Mutex
+	offline := false
+	var applied []string
+	setOffline := func(value bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		offline = value
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if offline {
+			return nil, fmt.Errorf("failed to list messages: %w: 503 Service Unavailable", service.ErrGmailUnreachable)
+		}
+		return nil, nil
+	}
+	gmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if offline {
+			return fmt.Errorf("failed to modify email: %w: dial tcp: connection refused", service.ErrGmailUnreachable)
+		}
+		applied = append(applied, fmt.Sprint(messageID, " +", add, " -", remove))
+		return nil
+	}
+	container, user, request := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.GmailBreakerFailures = 10 // the outage isn't long enough to pause the user's calls
+	}), withAppOptions(app.WithGmailClient(gmailClient)), withGmailModify())
+	email := model.NewEmail(user.ID, "msg_1", "news@paper.example", "Today's news", "body", time.Now().Add(-time.Hour))
+	email.Unread = true
+	assert.NoError(t, container.EmailRepo.Create(ctx, email))
+
+	call := func(method, path string) *httptest.ResponseRecorder {
+		return serve(container, request(method, "/api/v1"+path, ""))
+	}
+	gmailHealth := func() *model.GmailHealth {
+		rec := call(http.MethodGet, "/me")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var me handler.MeResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &me))
+		return me.Gmail
+	}
+	assert.Equal(t, &model.GmailHealth{Status: model.GmailReachable, Breaker: model.BreakerClosed}, gmailHealth())
+
+	// Syncing while Gmail is down serves the stored emails, flagged as stale
+	setOffline(true)
+	rec := call(http.MethodPost, "/emails/sync")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get(handler.StaleHeader))
+	var synced handler.SyncEmailsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &synced))
+	assert.True(t, synced.Stale)
+
+	rec = call(http.MethodGet, "/emails")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(handler.StaleHeader))
+	assert.Contains(t, rec.Body.String(), email.ID)
+
+	health := gmailHealth()
+	assert.Equal(t, model.GmailUnreachable, health.Status)
+	assert.NotNil(t, health.Since)
+	assert.Contains(t, health.LastError, "503 Service Unavailable")
+
+	// Changes apply in the app right away and wait in the queue for Gmail
+	assert.NoError(t, container.EmailService.PerformBulkAction(ctx, []string{email.ID}, "read", user.ID))
+	assert.NoError(t, container.EmailService.PerformBulkAction(ctx, []string{email.ID}, "archive", user.ID))
+	stored, err := container.EmailRepo.FindByID(ctx, email.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.Unread)
+	assert.True(t, stored.Archived)
+	assert.Equal(t, 2, gmailHealth().QueuedActions)
+
+	// Marking it unread again queues behind them
+	assert.NoError(t, container.EmailService.PerformBulkAction(ctx, []string{email.ID}, "unread", user.ID))
+	assert.Equal(t, 3, gmailHealth().QueuedActions)
+
+	// Nothing is replayed while Gmail stays down
+	container.ReplayJob.RunReplay()
+	assert.Equal(t, 3, gmailHealth().QueuedActions)
+	assert.Empty(t, applied)
+
+	// Once Gmail is back, the queue is replayed in order
+	setOffline(false)
+	container.ReplayJob.RunReplay()
+	// Read, then archived, then unread again
+	assert.Equal(t, []string{"msg_1 +[] -[UNREAD]", "msg_1 +[] -[INBOX UNREAD]", "msg_1 +[UNREAD] -[]"}, applied)
+	assert.Equal(t, &model.GmailHealth{Status: model.GmailReachable, Breaker: model.BreakerClosed}, gmailHealth())
+
+	rec = call(http.MethodGet, "/emails")
+	assert.Empty(t, rec.Header().Get(handler.StaleHeader))
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingUserLookups counts the users looked up by email
+type countingUserLookups struct {
+	repository.UserRepository
+	mutex   sync.Mutex
+	lookups int
+}
+
+func (r *countingUserLookups) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mutex.Lock()
+	r.lookups++
+	r.mutex.Unlock()
+	return r.UserRepository.FindByEmail(ctx, email)
+}
+
+func TestGmailUserClientCachesClients(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeGmailServer(t, map[string]json.RawMessage{})
+	userRepo := &countingUserLookups{UserRepository: memory.NewInMemoryUserRepository()}
+	alice := model.NewUser("google_1", "alice@example.com", "Alice", "test-token", "refresh_token", time.Time{})
+	bob := model.NewUser("google_2", "bob@example.com", "Bob", "test-token", "refresh_token", time.Time{})
+	userRepo.Create(ctx, alice)
+	userRepo.Create(ctx, bob)
+	client := gmail.NewUserClientWithEndpoint(userRepo, 10, fake.URL+"/", logger.New())
+	setToken := func(token string) {
+		alice.AccessToken = token
+		assert.NoError(t, userRepo.Update(ctx, alice))
+	}
+
+	// The user is looked up and their client built once
+	assert.NoError(t, client.Modify(ctx, "alice@example.com", "msg_1", nil, []string{model.LabelInbox, model.LabelUnread}))
+	assert.NoError(t, client.Modify(ctx, "alice@example.com", "msg_1", []string{model.LabelStarred}, nil))
+	assert.Equal(t, 1, userRepo.lookups)
+
+	// New tokens aren't used until the cached client is invalidated
+	setToken("new-token")
+	assert.NoError(t, client.Modify(ctx, "alice@example.com", "msg_1", nil, []string{model.LabelUnread}))
+	client.Invalidate(alice.ID)
+	assert.Error(t, client.Modify(ctx, "alice@example.com", "msg_1", nil, []string{model.LabelUnread}))
+	assert.Equal(t, 2, userRepo.lookups)
+
+	// Only the most recently used users' clients are kept
+	setToken("test-token")
+	client.Invalidate(alice.ID)
+	client.SetCacheSize(1)
+	assert.NoError(t, client.Modify(ctx, "alice@example.com", "msg_1", nil, []string{model.LabelStarred}))
+	assert.NoError(t, client.Modify(ctx, "bob@example.com", "msg_2", nil, []string{model.LabelStarred}))
+	assert.NoError(t, client.Modify(ctx, "bob@example.com", "msg_2", nil, []string{model.LabelStarred}))
+	assert.Equal(t, 4, userRepo.lookups)
+	assert.NoError(t, client.Modify(ctx, "alice@example.com", "msg_1", nil, []string{model.LabelStarred}))
+	assert.Equal(t, 5, userRepo.lookups)
+
+	// A client built with an expired token is built again
+	carol := model.NewUser("google_3", "carol@example.com", "Carol", "test-token", "refresh_token", time.Now().Add(-time.Minute))
+	userRepo.Create(ctx, carol)
+	assert.NoError(t, client.Modify(ctx, "carol@example.com", "msg_3", []string{model.LabelStarred}, nil))
+	assert.NoError(t, client.Modify(ctx, "carol@example.com", "msg_3", []string{model.LabelStarred}, nil))
+	assert.Equal(t, 7, userRepo.lookups)
+
+	_, err := client.List(ctx, "nobody@example.com", 0)
+	assert.Error(t, err)
+}
+
+func TestGmailUserClientRefreshesTokens(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeGmailServer(t, map[string]json.RawMessage{})
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostFormValue("grant_type") != "refresh_token" || r.PostFormValue("client_id") != "client-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+		if r.PostFormValue("refresh_token") != "good-refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"test-token","expires_in":3599,"token_type":"Bearer"}`))
+	}))
+	defer tokens.Close()
+
+	userRepo := memory.NewInMemoryUserRepository()
+	client := gmail.NewUserClientWithEndpoint(userRepo, 10, fake.URL+"/", logger.New())
+	client.UseTokenRefresh(gmail.NewTokenRefresher("client-id", "client-secret", tokens.URL))
+
+	// An expired access token is refreshed and saved before the call
+	alice := model.NewUser("google_1", "alice@example.com", "Alice", "expired-token", "good-refresh", time.Now().Add(-time.Minute))
+	assert.NoError(t, userRepo.Create(ctx, alice))
+	assert.NoError(t, client.Modify(ctx, "alice@example.com", "msg_1", []string{model.LabelStarred}, nil))
+	stored, err := userRepo.FindByID(ctx, alice.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-token", stored.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), stored.TokenExpiry, time.Minute)
+
+	// A token Gmail refuses before it expires is refreshed, and the call retried
+	bob := model.NewUser("google_2", "bob@example.com", "Bob", "revoked-token", "good-refresh", time.Now().Add(time.Hour))
+	assert.NoError(t, userRepo.Create(ctx, bob))
+	assert.NoError(t, client.Modify(ctx, "bob@example.com", "msg_2", nil, []string{model.LabelStarred}))
+
+	// Google refusing the refresh, or no refresh token to try, means signing in again
+	carol := model.NewUser("google_3", "carol@example.com", "Carol", "revoked-token", "revoked-refresh", time.Now().Add(time.Hour))
+	assert.NoError(t, userRepo.Create(ctx, carol))
+	err = client.Modify(ctx, "carol@example.com", "msg_3", nil, []string{model.LabelUnread})
+	assert.ErrorIs(t, err, service.ErrGmailReauthRequired)
+	assert.Contains(t, err.Error(), "Token has been expired or revoked.")
+
+	dave := model.NewUser("google_4", "dave@example.com", "Dave", "revoked-token", "", time.Time{})
+	assert.NoError(t, userRepo.Create(ctx, dave))
+	_, err = client.List(ctx, "dave@example.com", 0)
+	assert.ErrorIs(t, err, service.ErrGmailReauthRequired)
+}
+
+func TestSignInRunsTokenRefreshHooks(t *testing.T) {
+	ctx := context.Background()
+	userRepo := memory.NewInMemoryUserRepository()
+	authService := service.NewAuthService(userRepo, logger.New())
+	var refreshed []string
+	authService.OnTokenRefresh(func(ctx context.Context, user *model.User) {
+		refreshed = append(refreshed, user.AccessToken)
+	})
+
+	_, err := authService.GetOrCreateUser(ctx, "google_1", "alice@example.com", "Alice", "first-token", "refresh_token", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, refreshed)
+	_, err = authService.GetOrCreateUser(ctx, "google_1", "alice@example.com", "Alice", "second-token", "refresh_token", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second-token"}, refreshed)
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListEndpointsNegotiateHTMLPartials(t *testing.T) {
+	container, user, request := newTestApp(t)
+	ctx := context.Background()
+
+	category, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Work related emails")
+	assert.NoError(t, err)
+	email := model.NewEmail(user.ID, "msg_1", "Boss <boss@example.com>", "<script>alert(1)</script> Q3 plan", "Body", time.Now())
+	email.CategoryID = category.ID
+	assert.NoError(t, container.EmailRepo.Create(ctx, email))
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := request(http.MethodGet, path, "")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return serve(container, req)
+	}
+
+	// API clients keep getting JSON
+	rec := get("/api/v1/emails", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+	var emails []*model.Email
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &emails))
+	assert.Len(t, emails, 1)
+
+	// HTMX gets the email list partial, escaped and labelled with the category
+	rec = get("/api/v1/emails/category/"+category.ID, map[string]string{"HX-Request": "true"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rec.Header().Values("Vary"), "HX-Request")
+	body := rec.Body.String()
+	assert.Contains(t, body, `id="email-`+email.ID+`"`)
+	assert.Contains(t, body, "&lt;script&gt;alert(1)&lt;/script&gt; Q3 plan")
+	assert.Contains(t, body, "Boss")
+	assert.Contains(t, body, `class="email-category category-work">Work<`)
+
+	// Browsers preferring HTML get the category list partial; shared categories have no delete icon
+	rec = get("/api/v1/categories", map[string]string{"Accept": "text/html,application/xhtml+xml"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body = rec.Body.String()
+	assert.Contains(t, body, "filterByCategory('"+category.ID+"')")
+	assert.Contains(t, body, "deleteCategory('"+category.ID+"')")
+	shared, err := container.CategoryService.GetAllCategories(ctx, "")
+	assert.NoError(t, err)
+	for _, c := range shared {
+		assert.NotContains(t, body, "deleteCategory('"+c.ID+"')")
+	}
+
+	// An empty listing renders the empty state rather than nothing
+	rec = get("/api/v1/emails/category/missing", map[string]string{"HX-Request": "true"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "No emails found")
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/i18n"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	assert.Equal(t, "Não autorizado", i18n.Translate("pt", "Unauthorized"))
+	assert.Equal(t, "debe tener como máximo 100 caracteres", i18n.Translate("es", "must be at most 100 characters"))
+	assert.Equal(t, "deve ser no máximo 5", i18n.Translate("pt", "must be at most 5"))
+
+	// Messages missing from the catalog, and English, are served as written
+	assert.Equal(t, "Failed to get emails", i18n.Translate("pt", "Failed to get emails"))
+	assert.Equal(t, "Unauthorized", i18n.Translate("en", "Unauthorized"))
+	assert.Equal(t, "Unauthorized", i18n.Translate("", "Unauthorized"))
+
+	assert.Equal(t, "pt", i18n.FromAcceptLanguage("pt-BR,pt;q=0.9,en;q=0.8"))
+	assert.Equal(t, "es", i18n.FromAcceptLanguage("fr-CA, es;q=0.5"))
+	assert.Equal(t, "en", i18n.FromAcceptLanguage("pt;q=0, en"))
+	assert.Equal(t, "", i18n.FromAcceptLanguage("de-DE"))
+	assert.Equal(t, "Portuguese", i18n.LanguageName("pt"))
+}
+
+func TestLocalizedAPIErrors(t *testing.T) {
+	container, user, _ := newTestApp(t)
+
+	call := func(method, path, acceptLanguage string, body any, cookie bool) (int, handler.ErrorResponse) {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/api/v1"+path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", acceptLanguage)
+		if cookie {
+			req.AddCookie(sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+		}
+		rec := serve(container, req)
+		var response handler.ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	// Without a locale of their own, users get the browser's language
+	status, response := call(http.MethodGet, "/reports/missing", "es-ES,es;q=0.9", nil, false)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "No autorizado", response.Error)
+	assert.Equal(t, apierror.CodeUnauthorized, response.Code)
+
+	status, response = call(http.MethodPut, "/settings/locale", "es", map[string]string{"locale": "fr"}, true)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "Solicitud no válida", response.Error)
+	if assert.Len(t, response.Fields, 1) {
+		assert.Equal(t, "locale", response.Fields[0].Field)
+		assert.Equal(t, "debe ser uno de: en, es, pt", response.Fields[0].Message)
+	}
+
+	// Their own locale wins over the browser's
+	status, _ = call(http.MethodPut, "/settings/locale", "es", map[string]string{"locale": "pt"}, true)
+	assert.Equal(t, http.StatusOK, status)
+	status, response = call(http.MethodGet, "/reports/missing", "es", nil, true)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "relatório não encontrado", response.Error)
+	assert.Equal(t, apierror.CodeNotFound, response.Code)
+
+	// Requests with neither a session nor a supported language stay in English
+	status, response = call(http.MethodPut, "/settings/locale", "", map[string]string{"locale": "fr"}, false)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "Unauthorized", response.Error)
+}
+
+func TestSummariesInUserLanguage(t *testing.T) {
+	ctx := context.Background()
+	var languages []string
+	aiClient := ai.NewMockAIClient()
+	aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+		languages = append(languages, language)
+		return "Resumo do email sobre o relatório.", nil
+	}
+	userRepo := memory.NewInMemoryUserRepository()
+	emailService := service.NewEmailService(memory.NewInMemoryEmailRepository(), memory.NewInMemoryCategoryRepository(),
+		userRepo, nil, aiClient, logger.New())
+	category := model.NewCategory("Work", "Work related emails")
+
+	english := model.NewUser("google_123", "en@example.com", "English User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(ctx, english)
+	portuguese := model.NewUser("google_456", "pt@example.com", "Portuguese User", "access_token", "refresh_token", time.Time{})
+	portuguese.Locale = "pt"
+	userRepo.Create(ctx, portuguese)
+
+	// Summaries stay in the email's language until the user picks one
+	email := model.NewEmail(english.ID, "gmail_1", "boss@example.com", "Report", "Please send the report by Friday.", time.Now())
+	assert.NoError(t, emailService.ClassifyAndSummarizeEmail(ctx, email, []*model.Category{category}))
+
+	// A summary in the user's language is kept, though the email is written in another
+	email = model.NewEmail(portuguese.ID, "gmail_2", "boss@example.com", "Report", "Please send the report by Friday.", time.Now())
+	assert.NoError(t, emailService.ClassifyAndSummarizeEmail(ctx, email, []*model.Category{category}))
+	assert.Equal(t, "Resumo do email sobre o relatório.", email.Summary)
+	assert.False(t, email.NeedsReprocessing)
+
+	assert.Equal(t, []string{"", "Portuguese"}, languages)
+}
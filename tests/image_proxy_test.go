@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageProxy(t *testing.T) {
+	ctx := context.Background()
+
+	logo := []byte("\x89PNG fake image")
+	var logoFetches atomic.Int32
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logo.png":
+			logoFetches.Add(1)
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(logo)
+		case "/huge.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte("x"), 6<<20))
+		case "/icon.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer images.Close()
+
+	// The test server listens on loopback, which the default client refuses
+	container, user, request := newTestApp(t, withAppOptions(app.WithFetchClient(images.Client())))
+
+	logoURL := images.URL + "/logo.png"
+	body := `<p>Hello</p>
+		<img src="` + logoURL + `" srcset="` + logoURL + ` 2x" alt="Logo">
+		<img src="https://mailtrack.io/trace/mail/abc.png">
+		<img src="https://cdn.shop.example/open.gif" width="1" height="1">
+		<img src="cid:banner@shop.example">`
+	email := model.NewEmail(user.ID, "gmail_1", "Shop <news@shop.example>", "Newsletter", body, time.Now())
+	assert.NoError(t, container.EmailRepo.Create(ctx, email))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		return serve(container, request(http.MethodGet, "/api/v1"+path, ""))
+	}
+	proxy := func(imageURL string) *httptest.ResponseRecorder {
+		return get("/proxy/image?url=" + url.QueryEscape(imageURL))
+	}
+
+	// Displayed bodies point remote images at the proxy and lose their tracking pixels
+	rec := get("/emails/" + email.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var detail model.EmailDetail
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &detail))
+	rewritten := detail.Email.Body
+	assert.Contains(t, rewritten, `src="/api/v1/proxy/image?url=`+url.QueryEscape(logoURL)+`"`)
+	assert.NotContains(t, rewritten, "srcset")
+	assert.NotContains(t, rewritten, "mailtrack.io")
+	assert.NotContains(t, rewritten, "open.gif")
+	assert.Contains(t, rewritten, `src="cid:banner@shop.example"`)
+	assert.Contains(t, rewritten, "<p>Hello</p>")
+
+	rec = get("/emails/" + email.ID + "/body")
+	var bodyResponse struct {
+		Body string `json:"body"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bodyResponse))
+	assert.Equal(t, rewritten, bodyResponse.Body)
+
+	// The stored body is left as it was synced
+	stored, err := container.EmailRepo.FindByID(ctx, email.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, body, stored.Body)
+
+	// Images are fetched once, then served from the cache
+	for i := 0; i < 2; i++ {
+		rec = proxy(logoURL)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		assert.Equal(t, logo, rec.Body.Bytes())
+	}
+	assert.Equal(t, int32(1), logoFetches.Load())
+
+	assert.Equal(t, http.StatusForbidden, proxy("https://pixel.mailtrack.io/open.png").Code)
+	assert.Equal(t, http.StatusBadGateway, proxy(images.URL+"/huge.png").Code)
+	assert.Equal(t, http.StatusBadGateway, proxy(images.URL+"/icon.svg").Code)
+	assert.Equal(t, http.StatusBadGateway, proxy(images.URL+"/missing.png").Code)
+	assert.Equal(t, http.StatusBadRequest, proxy("javascript:alert(1)").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/proxy/image").Code)
+
+	rec = serve(container, httptest.NewRequest(http.MethodGet, "/api/v1/proxy/image?url="+url.QueryEscape(logoURL), nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Without a replacement client, internal addresses can't be reached through the proxy
+	guarded := service.NewImageProxyService(nil, container.Logger)
+	_, err = guarded.FetchImage(ctx, logoURL)
+	assert.True(t, errors.Is(err, apierror.ErrForbidden), "got %v", err)
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClickTrackingLinks(t *testing.T) {
+	ctx := context.Background()
+
+	// Plays SendGrid handing over to Mailchimp, which redirects to the shop
+	var trackerHits atomic.Int32
+	trackers := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trackerHits.Add(1)
+		switch {
+		case strings.HasSuffix(r.Host, "ct.sendgrid.net") && r.URL.Path == "/ls/click":
+			http.Redirect(w, r, "http://shop.us1.list-manage.com/track/click?id=42", http.StatusFound)
+		case strings.HasSuffix(r.Host, "list-manage.com") && r.URL.Path == "/track/click":
+			http.Redirect(w, r, "https://shop.example/sale?utm_source=email", http.StatusFound)
+		default:
+			w.Write([]byte("no redirect here"))
+		}
+	}))
+	defer trackers.Close()
+	// Every host resolves to the test server
+	fetchClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, trackers.Listener.Addr().String())
+		},
+	}}
+
+	safelink := "https://nam02.safelinks.protection.outlook.com/?url=" +
+		"https%3A%2F%2Fwww.google.com%2Furl%3Fq%3Dhttps%253A%252F%252Fnews.example%252Fstory%26sa%3DD&data=05%7C01&reserved=0"
+	proofpoint := "https://urldefense.proofpoint.com/v2/url?u=https-3A__docs.example_page-3Fid-3D7&d=DwMFaQ&c=x"
+	sendgrid := "http://u123.ct.sendgrid.net/ls/click?upn=abc"
+	dead := "http://u123.ct.sendgrid.net/ls/other"
+	body := `<p>Read <a href="` + safelink + `">the story</a>, <a href="` + proofpoint + `">the doc</a>,
+		<a href="` + sendgrid + `">the sale</a>, <a href="` + dead + `">old link</a> or <a href="https://example.com/about">about us</a>.</p>`
+
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return []*model.Email{model.NewEmail("", "tracked", "Shop <news@shop.example>", "Weekly news", body, time.Now())}, nil
+	}
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient), app.WithFetchClient(fetchClient)))
+
+	_, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+
+	// links maps each link's text to its href and original address
+	links := func(body string) map[string][2]string {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+		assert.NoError(t, err)
+		found := make(map[string][2]string)
+		doc.Find("a").Each(func(i int, link *goquery.Selection) {
+			href, _ := link.Attr("href")
+			original, _ := link.Attr("data-original-href")
+			found[link.Text()] = [2]string{href, original}
+		})
+		return found
+	}
+
+	// Redirectors carrying the destination are unwrapped in the stored body, without any request
+	stored, err := container.EmailRepo.FindByGmailID(ctx, user.ID, "tracked")
+	assert.NoError(t, err)
+	storedLinks := links(stored.Body)
+	assert.Equal(t, [2]string{"https://news.example/story", safelink}, storedLinks["the story"])
+	assert.Equal(t, [2]string{"https://docs.example/page?id=7", proofpoint}, storedLinks["the doc"])
+	assert.Equal(t, [2]string{sendgrid, ""}, storedLinks["the sale"])
+	assert.Equal(t, [2]string{"https://example.com/about", ""}, storedLinks["about us"])
+	assert.Equal(t, int32(0), trackerHits.Load())
+
+	// Click trackers hiding the destination are followed when the email is displayed
+	display := func() map[string][2]string {
+		rec := serve(container, request(http.MethodGet, "/api/v1/emails/"+stored.ID+"/body", ""))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response struct {
+			Body string `json:"body"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return links(response.Body)
+	}
+	displayed := display()
+	assert.Equal(t, [2]string{"https://shop.example/sale?utm_source=email", sendgrid}, displayed["the sale"])
+	assert.Equal(t, [2]string{"https://news.example/story", safelink}, displayed["the story"])
+	// A tracker that doesn't redirect leaves its link as it is
+	assert.Equal(t, [2]string{dead, ""}, displayed["old link"])
+	assert.Equal(t, int32(3), trackerHits.Load())
+
+	// Resolved destinations are cached; only the dead link is tried again
+	display()
+	assert.Equal(t, int32(4), trackerHits.Load())
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalClient(t *testing.T) {
+	ctx := context.Background()
+	client := ai.NewLocalClient(logger.New())
+	categories := []*model.Category{
+		model.NewCategory("Work", "Meetings, projects and messages from colleagues"),
+		model.NewCategory("Shopping", "Orders, receipts and deliveries from online stores"),
+		model.NewCategory("Travel", "Flights, hotels and itineraries"),
+	}
+
+	// Before learning anything, the descriptions decide
+	name, err := client.ClassifyEmail(ctx, "Your order has shipped and the delivery is due Monday", categories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Shopping", name)
+	name, err = client.ClassifyEmail(ctx, "Your flight to Lisbon is confirmed, here is the itinerary", categories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Travel", name)
+
+	// Hints count as much as descriptions
+	categories[2].Hints = "boarding passes, car rentals"
+	name, err = client.ClassifyEmail(ctx, "Your boarding pass", categories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Travel", name)
+
+	// Words the descriptions don't mention are learned from the emails users file
+	invoice := "Invoice for your order from Acme Corp, consulting retainer"
+	name, err = client.ClassifyEmail(ctx, invoice, categories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Shopping", name)
+	client.Learn("Invoice INV-1802 from Acme Corp for the consulting retainer, due in 30 days", categories[0].ID)
+	client.Learn("Acme Corp consulting retainer invoice for March", categories[0].ID)
+	name, err = client.ClassifyEmail(ctx, invoice, categories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Work", name)
+
+	// Summaries are the first sentences, and nothing else can be analyzed
+	summary, err := client.SummarizeEmail(ctx, "Hi Ana. The report is attached! Let me know what you think? Thanks, Bob", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi Ana. The report is attached!", summary)
+	summary, err = client.SummarizeEmail(ctx, strings.Repeat("word ", 200), "")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(summary), 240)
+
+	_, err = client.Analyze(ctx, "task", "content")
+	assert.ErrorIs(t, err, service.ErrAnalysisUnsupported)
+}
+
+func TestClassificationWithoutAIKey(t *testing.T) {
+	ctx := context.Background()
+	gmailClient := gmail.NewMockGmailClient()
+	var synced []*model.Email
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	container, user, request := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AIProvider = ai.ProviderOpenAI
+		cfg.AIChunkTokens = 100
+		cfg.AIMaxChunks = 4
+	}), withAppOptions(app.WithGmailClient(gmailClient)), withConfiguredAI())
+	work, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Meetings, projects and messages from colleagues")
+	assert.NoError(t, err)
+	shopping, err := container.CategoryService.CreateCategory(ctx, user.ID, "Shopping", "Orders, receipts and deliveries from online stores")
+	assert.NoError(t, err)
+
+	syncEmails := func(emails ...*model.Email) {
+		synced = emails
+		_, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+		assert.NoError(t, err)
+	}
+	stored := func(gmailID string) *model.Email {
+		email, err := container.EmailRepo.FindByGmailID(ctx, user.ID, gmailID)
+		assert.NoError(t, err)
+		return email
+	}
+
+	// Emails are classified and summarized without reaching any provider
+	longBody := "<p>Your order has shipped.</p>" + strings.Repeat("<p>Tracking details and delivery options follow.</p>", 20)
+	syncEmails(model.NewEmail("", "order_1", "store@shop.example", "Shipped", longBody, time.Now()),
+		model.NewEmail("", "invoice_1", "billing@acme.example", "Invoice", "<p>Invoice for your order from Acme Corp, consulting retainer.</p>", time.Now()))
+	order := stored("order_1")
+	assert.Equal(t, shopping.ID, order.CategoryID)
+	assert.True(t, strings.HasPrefix(order.Summary, "Your order has shipped."), order.Summary)
+	assert.False(t, order.NeedsReprocessing)
+	assert.Equal(t, shopping.ID, stored("invoice_1").CategoryID)
+
+	// Filing an email by hand teaches the local model
+	rec := serve(container, request(http.MethodPut, "/api/v1/emails/"+stored("invoice_1").ID+"/category", `{"category_id":"`+work.ID+`"}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, work.ID, stored("invoice_1").CategoryID)
+
+	syncEmails(model.NewEmail("", "invoice_2", "billing@acme.example", "Invoice", "<p>Acme Corp invoice for the consulting retainer, order 1182.</p>", time.Now()))
+	assert.Equal(t, work.ID, stored("invoice_2").CategoryID)
+
+	health := container.AIClient.(*ai.FailoverClient).Health()
+	if assert.Len(t, health, 2) {
+		assert.Equal(t, "local", health[1].Name)
+		assert.Contains(t, health[0].LastError, "no API key configured")
+	}
+}
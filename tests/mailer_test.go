@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/emailtemplate"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/mailer"
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer speaks just enough SMTP to take emails, recording what it was told
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mutex    sync.Mutex
+	auth     string // the decoded AUTH PLAIN response
+	from     string
+	to       []string
+	messages [][]byte
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(textproto.NewConn(conn))
+		}
+	}()
+	return server
+}
+
+func (f *fakeSMTPServer) port() int {
+	return f.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTPServer) serve(conn *textproto.Conn) {
+	defer conn.Close()
+	conn.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Fields(line + " x")[0])
+		f.mutex.Lock()
+		switch command {
+		case "EHLO":
+			conn.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+			f.auth = string(decoded)
+			conn.PrintfLine("235 Authenticated")
+		case "MAIL":
+			f.from = line
+			conn.PrintfLine("250 OK")
+		case "RCPT":
+			f.to = append(f.to, line)
+			conn.PrintfLine("250 OK")
+		case "DATA":
+			conn.PrintfLine("354 Go ahead")
+			f.mutex.Unlock()
+			data, err := conn.ReadDotBytes()
+			f.mutex.Lock()
+			if err == nil {
+				f.messages = append(f.messages, data)
+			}
+			conn.PrintfLine("250 Queued")
+		case "QUIT":
+			conn.PrintfLine("221 Bye")
+			f.mutex.Unlock()
+			return
+		default:
+			conn.PrintfLine("250 OK")
+		}
+		f.mutex.Unlock()
+	}
+}
+
+// readAlternatives parses a composed email, returning its headers and its parts by content type
+func readAlternatives(t *testing.T, raw []byte) (mail.Header, map[string]string) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	assert.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		// NextPart decodes quoted-printable and drops the encoding header
+		body, err := io.ReadAll(part)
+		assert.NoError(t, err)
+		parts[part.Header.Get("Content-Type")] = string(body)
+	}
+	return message.Header, parts
+}
+
+func TestSMTPSenderDeliversAlternatives(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sender := mailer.NewSMTPSender("127.0.0.1", server.port(), "mailer", "s3cret", "Jump Challenge <noreply@example.com>")
+	message := &emailtemplate.Message{
+		Subject: "Relatório semanal",
+		HTML:    "<p>" + strings.Repeat("Long line of HTML ", 10) + "= done</p>",
+		Text:    "Plain text",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, sender.Send(ctx, "Ada <ada@example.com>", message))
+	assert.NoError(t, sender.Check(ctx))
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	assert.Equal(t, "\x00mailer\x00s3cret", server.auth)
+	assert.Equal(t, "MAIL FROM:<noreply@example.com>", strings.Fields(server.from)[0]+" "+strings.Fields(server.from)[1])
+	assert.Equal(t, []string{"RCPT TO:<ada@example.com>"}, server.to)
+	if assert.Len(t, server.messages, 1) {
+		header, parts := readAlternatives(t, server.messages[0])
+		decoder := new(mime.WordDecoder)
+		subject, err := decoder.DecodeHeader(header.Get("Subject"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Relatório semanal", subject)
+		assert.Equal(t, `"Jump Challenge" <noreply@example.com>`, header.Get("From"))
+		assert.Equal(t, `"Ada" <ada@example.com>`, header.Get("To"))
+		assert.Equal(t, "auto-generated", header.Get("Auto-Submitted"))
+		assert.True(t, strings.HasSuffix(header.Get("Message-Id"), "@example.com>"))
+		assert.Equal(t, message.Text, parts["text/plain; charset=utf-8"])
+		assert.Equal(t, message.HTML, parts["text/html; charset=utf-8"])
+	}
+
+	// Nobody listening
+	closed := mailer.NewSMTPSender("127.0.0.1", 1, "", "", "noreply@example.com")
+	assert.ErrorContains(t, closed.Send(ctx, "ada@example.com", message), "failed to connect to the SMTP server")
+	assert.Error(t, sender.Send(ctx, "not an address", message))
+}
+
+func TestGmailSenderSendsFromTheRecipient(t *testing.T) {
+	gmailClient := gmail.NewMockGmailClient()
+	var account string
+	var raw []byte
+	gmailClient.SendFunc = func(ctx context.Context, userEmail string, message []byte) error {
+		account, raw = userEmail, message
+		return nil
+	}
+
+	message := &emailtemplate.Message{Subject: "Report", HTML: "<p>Hi</p>", Text: "Hi"}
+	assert.NoError(t, mailer.NewGmailSender(gmailClient).Send(context.Background(), "ada@example.com", message))
+	assert.Equal(t, "ada@example.com", account)
+	header, parts := readAlternatives(t, raw)
+	assert.Equal(t, "<ada@example.com>", header.Get("From"))
+	assert.Equal(t, "<ada@example.com>", header.Get("To"))
+	assert.Equal(t, "Hi", parts["text/plain; charset=utf-8"])
+}
+
+// recordingMailSender keeps the emails it is asked to send
+type recordingMailSender struct {
+	mutex sync.Mutex
+	sent  map[string]*emailtemplate.Message // by recipient
+}
+
+func (r *recordingMailSender) Send(ctx context.Context, to string, message *emailtemplate.Message) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sent[to] = message
+	return nil
+}
+
+func TestWeeklyReportsAreEmailed(t *testing.T) {
+	ctx := context.Background()
+	sender := &recordingMailSender{sent: make(map[string]*emailtemplate.Message)}
+	container, user, _ := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.ReportEmails = true
+	}), withAppOptions(app.WithMailSender(sender)), withUser(func(user *model.User) {
+		user.Email = "ada@example.com"
+		user.Name = "Ada"
+	}))
+	now := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		email := model.NewEmail(user.ID, "msg_"+strconv.Itoa(i), "news@shop.example", "Sale", "Body", now.AddDate(0, 0, -7))
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+	}
+
+	generated, err := container.ReportService.GenerateWeeklyReports(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, generated)
+	if assert.Contains(t, sender.sent, "ada@example.com") {
+		message := sender.sent["ada@example.com"]
+		assert.Equal(t, "Your week in email: Mar 3, 2025 - Mar 9, 2025", message.Subject)
+		assert.Contains(t, message.Text, "Hi Ada")
+		assert.Contains(t, message.Text, "Emails: 3")
+		assert.Contains(t, message.HTML, "http://localhost:8080/app")
+	}
+
+	// A report is only emailed once, when generated
+	sender.sent = make(map[string]*emailtemplate.Message)
+	_, err = container.ReportService.GenerateWeeklyReports(ctx, now)
+	assert.NoError(t, err)
+	assert.Empty(t, sender.sent)
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRepositoryDoesNotShareStoredValues(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+
+	email := model.NewEmail("user_1", "msg_1", "a@example.com", "Original", "body", time.Now())
+	assert.NoError(t, emailRepo.Create(ctx, email))
+
+	// Changing the created value or a found one doesn't reach the repository
+	email.Subject = "Changed after create"
+	found, err := emailRepo.FindByID(ctx, email.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Original", found.Subject)
+
+	found.Subject = "Changed after find"
+	listed, err := emailRepo.FindByUserID(ctx, "user_1", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "Original", listed[0].Subject)
+	assert.NotSame(t, found, listed[0])
+
+	// Update is the way to change it
+	assert.NoError(t, emailRepo.Update(ctx, found))
+	found, err = emailRepo.FindByID(ctx, email.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Changed after find", found.Subject)
+
+	// Trashing sets the stored time without touching copies handed out before
+	assert.NoError(t, emailRepo.Delete(ctx, email.ID))
+	assert.Nil(t, found.DeletedAt)
+	trashed, err := emailRepo.FindDeletedByUserID(ctx, "user_1", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, trashed, 1) {
+		*trashed[0].DeletedAt = time.Time{}
+		again, _ := emailRepo.FindDeletedByUserID(ctx, "user_1", 0)
+		assert.False(t, again[0].DeletedAt.IsZero())
+	}
+
+	preferencesRepo := memory.NewInMemoryNotificationPreferencesRepository()
+	preferences := &model.NotificationPreferences{UserID: "user_1", EventTypes: []string{model.EventEmailSummary}}
+	assert.NoError(t, preferencesRepo.Save(ctx, preferences))
+	preferences.EventTypes[0] = "changed"
+	stored, err := preferencesRepo.FindByUserID(ctx, "user_1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{model.EventEmailSummary}, stored.EventTypes)
+}
+
+// TestConcurrentSyncAndReads runs syncs while other goroutines list, encode and update the same
+// emails, as the sync job and HTTP handlers do; run with -race to catch shared writes
+func TestConcurrentSyncAndReads(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	userRepo.Create(ctx, user)
+	categoryRepo.Create(ctx, model.NewCategory("Work", "Work emails"))
+
+	// Every sync brings two new emails
+	var batch atomic.Int64
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		n := batch.Add(1)
+		return []*model.Email{
+			model.NewEmail(user.ID, fmt.Sprintf("msg_%d_a", n), "a@example.com", "Hello", "Some body text", time.Now()),
+			model.NewEmail(user.ID, fmt.Sprintf("msg_%d_b", n), "b@example.com", "Hi", "Other body text", time.Now()),
+		}, nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, ai.NewMockAIClient(), logger.New())
+
+	// Readers keep going until the syncs are done
+	const rounds = 20
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			emails, err := emailService.GetEmailsByUser(ctx, user.ID, 0)
+			assert.NoError(t, err)
+			_, err = json.Marshal(emails)
+			assert.NoError(t, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			emails, _ := emailService.GetEmailsByUser(ctx, user.ID, 5)
+			var ids []string
+			for _, email := range emails {
+				ids = append(ids, email.ID)
+			}
+			if len(ids) > 0 {
+				assert.NoError(t, emailService.PerformBulkAction(ctx, ids, "read", user.ID))
+			}
+		}
+	}()
+
+	for i := 0; i < rounds; i++ {
+		_, err := emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+		assert.NoError(t, err)
+	}
+	close(done)
+	wg.Wait()
+
+	emails, err := emailService.GetEmailsByUser(ctx, user.ID, 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 2*rounds)
+	for _, email := range emails {
+		assert.NotEmpty(t, email.CategoryID)
+		assert.NotEmpty(t, email.Summary)
+	}
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreferencesQuietHours(t *testing.T) {
+	// 22:00 to 07:00 in Sao Paulo (UTC-3) spans midnight
+	preferences := model.NewNotificationPreferences("user_1", "22:00", "07:00", "America/Sao_Paulo", nil, "")
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 10, hour, minute, 0, 0, time.UTC)
+	}
+	assert.True(t, preferences.InQuietHours(at(6, 0)))   // 03:00 local
+	assert.True(t, preferences.InQuietHours(at(1, 0)))   // 22:00 local
+	assert.False(t, preferences.InQuietHours(at(10, 0))) // 07:00 local
+	assert.False(t, preferences.InQuietHours(at(15, 0))) // 12:00 local
+
+	// Quiet hours hold back new mail but not the progress of a job the user started
+	assert.False(t, preferences.Allows(model.EventNewEmail, at(6, 0)))
+	assert.True(t, preferences.Allows(model.EventBulkJob, at(6, 0)))
+	assert.True(t, preferences.Allows(model.EventNewEmail, at(15, 0)))
+
+	// Event types and minimum priority apply around the clock
+	preferences = model.NewNotificationPreferences("user_1", "", "", "", []string{model.EventNewEmail, model.EventEmailSummary}, model.PriorityNormal)
+	assert.True(t, preferences.Allows(model.EventNewEmail, at(15, 0)))
+	assert.False(t, preferences.Allows(model.EventEmailSummary, at(15, 0)))
+	assert.False(t, preferences.Allows(model.EventBulkJob, at(15, 0)))
+}
+
+func TestSSEManagerHonorsNotificationPreferences(t *testing.T) {
+	ctx := context.Background()
+	notificationService := service.NewNotificationService(memory.NewInMemoryNotificationPreferencesRepository(), logger.New())
+	sseManager := sse.NewSSEManager(logger.New())
+	defer sseManager.Close()
+	sseManager.UseNotificationPreferences(notificationService)
+
+	userID := "test_user_123"
+	clientChannel := sseManager.AddClient(userID)
+
+	// Without saved preferences every event is delivered
+	sseManager.BroadcastToUser(userID, model.EventEmailSummary, map[string]interface{}{"count": 1})
+	assert.Equal(t, model.EventEmailSummary, receiveEventType(t, clientChannel))
+
+	// Quiet hours around the current time only let high priority events through
+	now := time.Now().UTC()
+	_, err := notificationService.UpdatePreferences(ctx, userID, now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"), "UTC", nil, model.PriorityLow)
+	assert.NoError(t, err)
+
+	sseManager.BroadcastEmailToUser(userID, model.NewEmail(userID, "msg_123", "sender@example.com", "Subject", "Body", time.Now()))
+	sseManager.BroadcastToUser(userID, model.EventBulkJob, map[string]interface{}{"id": "job_1"})
+	assert.Equal(t, model.EventBulkJob, receiveEventType(t, clientChannel))
+
+	// Invalid times and zones are rejected
+	_, err = notificationService.UpdatePreferences(ctx, userID, "25:00", "07:00", "Mars/Olympus", nil, "")
+	assert.Error(t, err)
+}
+
+func receiveEventType(t *testing.T, channel chan []byte) string {
+	t.Helper()
+	select {
+	case msg := <-channel:
+		var event map[string]interface{}
+		assert.NoError(t, json.Unmarshal(msg, &event))
+		return event["type"].(string)
+	case <-time.After(time.Second):
+		t.Fatal("Did not receive message within timeout")
+		return ""
+	}
+}
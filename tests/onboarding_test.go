@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnboardingWizard(t *testing.T) {
+	ctx := context.Background()
+	gmailClient := gmail.NewMockGmailClient()
+	var requested int64
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		requested = maxResults
+		var emails []*model.Email
+		for i := 0; i < 6; i++ {
+			email := model.NewEmail("", fmt.Sprintf("msg_%d", i), "news@paper.example", "Today's news", "body", time.Now().Add(-time.Duration(i)*time.Hour))
+			email.Unread = true
+			emails = append(emails, email)
+		}
+		return emails, nil
+	}
+	container, _, _ := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.OnboardingBackfill = 20
+	}), withAppOptions(app.WithGmailClient(gmailClient)))
+
+	// Signing up starts the wizard
+	user, err := container.AuthService.GetOrCreateUser(ctx, "google_1", "ada@example.com", "Ada", "access_token", "refresh_token", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OnboardingCategories, user.OnboardingStep)
+	_, err = container.CategoryService.CreateCategory(ctx, user.ID, "Travel", "Trips")
+	assert.NoError(t, err)
+
+	call := func(method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+		rec := serve(container, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+	complete := func(body string) handler.OnboardingResponse {
+		status, response := call(http.MethodPost, "/onboarding", body)
+		assert.Equal(t, http.StatusOK, status, string(response))
+		var onboarding handler.OnboardingResponse
+		assert.NoError(t, json.Unmarshal(response, &onboarding))
+		return onboarding
+	}
+
+	// The categories step suggests those the user doesn't have yet
+	status, body := call(http.MethodGet, "/onboarding", "")
+	assert.Equal(t, http.StatusOK, status)
+	var onboarding model.Onboarding
+	assert.NoError(t, json.Unmarshal(body, &onboarding))
+	assert.Equal(t, model.OnboardingCategories, onboarding.Step)
+	assert.Equal(t, model.OnboardingSteps, onboarding.Steps)
+	var suggested []string
+	for _, suggestion := range onboarding.SuggestedCategories {
+		suggested = append(suggested, suggestion.Name)
+	}
+	assert.Contains(t, suggested, "Work")
+	assert.NotContains(t, suggested, "Travel")
+
+	// Another step than the current one is a conflict, answered with where the user stands
+	status, body = call(http.MethodPost, "/onboarding", `{"step":"backfill"}`)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, string(body), `"step":"categories"`)
+	status, _ = call(http.MethodPost, "/onboarding", `{"step":"categories","categories":["Work","Pets"]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	response := complete(`{"step":"categories","categories":["work","Travel"]}`)
+	assert.Equal(t, model.OnboardingBackfill, response.Step)
+	assert.Equal(t, 20, response.BackfillEmails)
+	categories, err := container.CategoryService.GetAllCategories(ctx, user.ID)
+	assert.NoError(t, err)
+	var names []string
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	assert.Contains(t, names, "Work")
+
+	// The backfill imports a limited first batch, which the unsubscribe step recommends from
+	response = complete(`{"step":"backfill"}`)
+	assert.Equal(t, int64(20), requested)
+	if assert.NotNil(t, response.Backfill) {
+		assert.Equal(t, 6, response.Backfill.New)
+	}
+	assert.Equal(t, model.OnboardingUnsubscribe, response.Step)
+	if assert.Len(t, response.Recommendations, 1) {
+		assert.Equal(t, "unsubscribe:news@paper.example", response.Recommendations[0].ID)
+	}
+	status, _ = call(http.MethodPost, "/onboarding", `{"step":"backfill"}`)
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Only unsubscribe recommendations are carried out by that step
+	status, _ = call(http.MethodPost, "/onboarding", `{"step":"unsubscribe","recommendation_ids":["archive_stale:x"]}`)
+	assert.Equal(t, http.StatusNotFound, status)
+	response = complete(`{"step":"unsubscribe","recommendation_ids":["unsubscribe:news@paper.example"]}`)
+	assert.Equal(t, model.OnboardingDone, response.Step)
+	if assert.Len(t, response.Jobs, 1) {
+		assert.Equal(t, "unsubscribe", response.Jobs[0].Action)
+	}
+
+	status, body = call(http.MethodGet, "/me", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(body), `"onboarding_step":"done"`)
+	status, _ = call(http.MethodPost, "/onboarding", `{"step":"unsubscribe","skip":true}`)
+	assert.Equal(t, http.StatusConflict, status)
+}
+
+func TestOnboardingSkipsSteps(t *testing.T) {
+	ctx := context.Background()
+	// Users from before the wizard are through it
+	container, existing, _ := newTestApp(t)
+	onboarding, err := container.Onboarding.GetOnboarding(ctx, existing.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OnboardingDone, onboarding.Step)
+
+	user, err := container.AuthService.GetOrCreateUser(ctx, "google_2", "grace@example.com", "Grace", "access_token", "refresh_token", nil)
+	assert.NoError(t, err)
+	for _, step := range model.OnboardingSteps[:len(model.OnboardingSteps)-1] {
+		onboarding, err = container.Onboarding.CompleteStep(ctx, user.ID, step)
+		assert.NoError(t, err)
+		assert.Equal(t, model.NextOnboardingStep(step), onboarding.Step)
+	}
+	assert.Equal(t, model.OnboardingDone, onboarding.Step)
+
+	// Signing in again doesn't restart it
+	user, err = container.AuthService.GetOrCreateUser(ctx, "google_2", "grace@example.com", "Grace", "new_access_token", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OnboardingDone, user.Onboarding())
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPIDocumentCoversVersionedRoutes(t *testing.T) {
+	cfg := &config.Config{
+		Port:          "0",
+		BaseURL:       "http://localhost:8080",
+		SessionSecret: "test-secret",
+		SessionTTL:    time.Hour,
+	}
+
+	container, err := app.New(cfg, app.WithGmailClient(gmail.NewMockGmailClient()), app.WithAIClient(ai.NewMockAIClient()))
+	assert.NoError(t, err)
+	defer container.Stop(context.Background())
+
+	// The spec is public
+	rec := httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var spec struct {
+		OpenAPI    string                                `json:"openapi"`
+		Servers    []struct{ URL string }                `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Equal(t, "/api/v1", spec.Servers[0].URL)
+	assert.Contains(t, spec.Paths["/emails/{id}/body"], "get")
+	assert.Contains(t, spec.Paths["/categories/{id}"], "put")
+	assert.Contains(t, spec.Paths["/emails"], "delete")
+
+	bulk := spec.Components.Schemas["BulkActionRequest"]
+	assert.Contains(t, bulk.Properties, "filter")
+	assert.Equal(t, []string{"action"}, bulk.Required)
+	assert.Contains(t, spec.Components.Schemas, "EmailFilter")
+	assert.Contains(t, spec.Components.Schemas, "ErrorResponse")
+
+	// Versioned and legacy API paths both require a session
+	for _, path := range []string{"/api/v1/emails", "/api/emails"} {
+		rec = httptest.NewRecorder()
+		container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+	}
+
+	rec = httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestOpenAPIDocumentsSSEEvents(t *testing.T) {
+	cfg := &config.Config{
+		Port:          "0",
+		BaseURL:       "http://localhost:8080",
+		SessionSecret: "test-secret",
+		SessionTTL:    time.Hour,
+	}
+
+	container, err := app.New(cfg, app.WithGmailClient(gmail.NewMockGmailClient()), app.WithAIClient(ai.NewMockAIClient()))
+	assert.NoError(t, err)
+	defer container.Stop(context.Background())
+
+	rec := httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	type schema struct {
+		Ref        string             `json:"$ref"`
+		Enum       []any              `json:"enum"`
+		OneOf      []schema           `json:"oneOf"`
+		Properties map[string]*schema `json:"properties"`
+		Required   []string           `json:"required"`
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema schema `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]schema `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+
+	// The stream is any of the registered events
+	stream := spec.Paths["/sse"]["get"].Responses["200"].Content["text/event-stream"].Schema
+	var refs []string
+	for _, event := range stream.OneOf {
+		refs = append(refs, event.Ref)
+	}
+	assert.Contains(t, refs, "#/components/schemas/NewEmailEvent")
+	assert.Contains(t, refs, "#/components/schemas/UnsubscribeManualActionEvent")
+
+	// Each event is an envelope with its type and version, and typed data
+	newEmail := spec.Components.Schemas["NewEmailEvent"]
+	assert.Equal(t, []any{"new_email"}, newEmail.Properties["type"].Enum)
+	assert.Equal(t, []any{float64(1)}, newEmail.Properties["version"].Enum)
+	assert.Equal(t, "#/components/schemas/NewEmail", newEmail.Properties["data"].Ref)
+	assert.ElementsMatch(t, []string{"type", "version", "data", "time"}, newEmail.Required)
+
+	// Payloads embedding a model carry its fields
+	assert.Contains(t, spec.Components.Schemas["NewEmail"].Properties, "subject")
+	assert.Contains(t, spec.Components.Schemas["UnsubscribeResult"].Properties, "manual_action_url")
+	assert.NotContains(t, spec.Components.Schemas["UnsubscribeResult"].Properties, "eventType")
+}
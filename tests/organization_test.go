@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type organizationFixture struct {
+	orgRepo           *memory.InMemoryOrganizationRepository
+	categoryRepo      *memory.InMemoryCategoryRepository
+	emailRepo         *memory.InMemoryEmailRepository
+	orgService        service.OrganizationService
+	categoryService   service.CategoryService
+	automationService service.AutomationService
+	alice, bob        *model.User
+}
+
+func newOrganizationFixture(t *testing.T) *organizationFixture {
+	ctx := context.Background()
+	f := &organizationFixture{
+		orgRepo:      memory.NewInMemoryOrganizationRepository(),
+		categoryRepo: memory.NewInMemoryCategoryRepository(),
+		emailRepo:    memory.NewInMemoryEmailRepository(),
+	}
+	f.categoryRepo.UseOrganizations(f.orgRepo)
+	userRepo := memory.NewInMemoryUserRepository()
+	automationRepo := memory.NewInMemoryAutomationRepository()
+	emailService := service.NewEmailService(f.emailRepo, f.categoryRepo, userRepo, gmail.NewMockGmailClient(), ai.NewMockAIClient(), logger.New())
+
+	f.orgService = service.NewOrganizationService(f.orgRepo, memory.NewInMemoryInvitationRepository(), userRepo, f.categoryRepo, automationRepo, f.emailRepo, logger.New())
+	f.categoryService = service.NewCategoryService(f.categoryRepo, f.orgRepo, logger.New())
+	f.automationService = service.NewAutomationService(automationRepo, f.categoryRepo, f.emailRepo, f.orgRepo, emailService, logger.New())
+
+	f.alice = model.NewUser("google_alice", "alice@example.com", "Alice", "token", "refresh", time.Time{})
+	f.bob = model.NewUser("google_bob", "Bob@Example.com", "Bob", "token", "refresh", time.Time{})
+	f.bob.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	assert.NoError(t, userRepo.Create(ctx, f.alice))
+	assert.NoError(t, userRepo.Create(ctx, f.bob))
+	return f
+}
+
+// join creates alice's organization and has bob accept an invitation to it
+func (f *organizationFixture) join(t *testing.T) {
+	ctx := context.Background()
+	_, err := f.orgService.CreateOrganization(ctx, f.alice.ID, "Acme")
+	assert.NoError(t, err)
+	invitation, err := f.orgService.Invite(ctx, f.alice.ID, "bob@example.com", model.RoleMember)
+	assert.NoError(t, err)
+	_, err = f.orgService.AcceptInvitation(ctx, f.bob.ID, invitation.ID)
+	assert.NoError(t, err)
+}
+
+func TestOrganizationInvitationFlow(t *testing.T) {
+	ctx := context.Background()
+	f := newOrganizationFixture(t)
+
+	_, err := f.orgService.GetOrganization(ctx, f.alice.ID)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	detail, err := f.orgService.CreateOrganization(ctx, f.alice.ID, " Acme ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme", detail.Organization.Name)
+	assert.Equal(t, model.RoleAdmin, detail.Role)
+	assert.Len(t, detail.Members, 1)
+	assert.Equal(t, "alice@example.com", detail.Members[0].Email)
+
+	_, err = f.orgService.Invite(ctx, f.alice.ID, "not an address", model.RoleMember)
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+
+	// Invitations match the invitee's email case-insensitively
+	invitation, err := f.orgService.Invite(ctx, f.alice.ID, "bob@example.com", model.RoleMember)
+	assert.NoError(t, err)
+	received, err := f.orgService.GetPendingInvitations(ctx, f.bob.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "Acme", received[0].OrgName)
+	}
+
+	// Only the invitee can answer an invitation, and only members with the admin role can invite
+	_, err = f.orgService.AcceptInvitation(ctx, f.alice.ID, invitation.ID)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+	detail, err = f.orgService.AcceptInvitation(ctx, f.bob.ID, invitation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleMember, detail.Role)
+	assert.Len(t, detail.Members, 2)
+	_, err = f.orgService.Invite(ctx, f.bob.ID, "carol@example.com", model.RoleMember)
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+
+	pending, err := f.orgService.ListInvitations(ctx, f.alice.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// The last admin hands over the role before leaving
+	assert.True(t, errors.Is(f.orgService.LeaveOrganization(ctx, f.alice.ID), apierror.ErrValidation))
+	_, err = f.orgService.UpdateMemberRole(ctx, f.alice.ID, f.bob.ID, model.RoleAdmin)
+	assert.NoError(t, err)
+	assert.NoError(t, f.orgService.LeaveOrganization(ctx, f.alice.ID))
+
+	// The last member to leave deletes the organization
+	assert.NoError(t, f.orgService.LeaveOrganization(ctx, f.bob.ID))
+	_, err = f.orgRepo.FindByID(ctx, detail.Organization.ID)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+}
+
+func TestTeamCategoriesAreSharedWithMembers(t *testing.T) {
+	ctx := context.Background()
+	f := newOrganizationFixture(t)
+
+	// Team categories need an organization and an admin
+	_, err := f.categoryService.CreateTeamCategory(ctx, f.alice.ID, "Customers", "Emails from customers")
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+	f.join(t)
+	_, err = f.categoryService.CreateTeamCategory(ctx, f.bob.ID, "Vendors", "Emails from vendors")
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+
+	team, err := f.categoryService.CreateTeamCategory(ctx, f.alice.ID, "Customers", "Emails from customers")
+	assert.NoError(t, err)
+	assert.True(t, team.IsTeam())
+
+	categories, err := f.categoryService.GetAllCategories(ctx, f.bob.ID)
+	assert.NoError(t, err)
+	assert.Len(t, categories, 1)
+
+	// Members use team categories but only admins change them
+	_, err = f.categoryService.UpdateCategory(ctx, f.bob.ID, team.ID, "Clients", "")
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+	updated, err := f.categoryService.UpdateCategory(ctx, f.alice.ID, team.ID, "Clients", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Clients", updated.Name)
+
+	// Removed members lose access
+	assert.NoError(t, f.orgService.RemoveMember(ctx, f.alice.ID, f.bob.ID))
+	_, err = f.categoryService.GetCategory(ctx, f.bob.ID, team.ID)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+}
+
+func TestTeamAutomationsRunForEveryMember(t *testing.T) {
+	ctx := context.Background()
+	f := newOrganizationFixture(t)
+	f.join(t)
+
+	team, err := f.categoryService.CreateTeamCategory(ctx, f.alice.ID, "Newsletters", "Bulk mail")
+	assert.NoError(t, err)
+	personal, err := f.categoryService.CreateCategory(ctx, f.alice.ID, "Mine", "Alice only")
+	assert.NoError(t, err)
+
+	// Team automations can't target categories other members can't see
+	_, err = f.automationService.CreateTeamAutomation(ctx, f.alice.ID, personal.ID, "read", "", 0)
+	assert.True(t, errors.Is(err, apierror.ErrValidation))
+	_, err = f.automationService.CreateTeamAutomation(ctx, f.bob.ID, team.ID, "read", "", 0)
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+
+	automation, err := f.automationService.CreateTeamAutomation(ctx, f.alice.ID, team.ID, "read", "", 0)
+	assert.NoError(t, err)
+
+	automations, err := f.automationService.GetAutomations(ctx, f.bob.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, automations, 1) {
+		assert.Equal(t, automation.ID, automations[0].ID)
+	}
+	_, err = f.automationService.UpdateAutomation(ctx, f.bob.ID, automation.ID, team.ID, "star", "", 0, true)
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+
+	// A member's newly classified email gets the team action
+	email := model.NewEmail(f.bob.ID, "msg_1", "news@example.com", "Weekly digest", "Hello", time.Now())
+	email.CategoryID = team.ID
+	email.Unread = true
+	assert.NoError(t, f.emailRepo.Create(ctx, email))
+	f.automationService.ApplyToNewEmail(ctx, email)
+
+	stored, err := f.emailRepo.FindByID(ctx, email.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.Unread)
+}
+
+func TestOrganizationStatsForAdmins(t *testing.T) {
+	ctx := context.Background()
+	f := newOrganizationFixture(t)
+	f.join(t)
+
+	team, err := f.categoryService.CreateTeamCategory(ctx, f.alice.ID, "Customers", "Emails from customers")
+	assert.NoError(t, err)
+	for i, userID := range []string{f.alice.ID, f.bob.ID, f.bob.ID} {
+		email := model.NewEmail(userID, "msg_"+string(rune('a'+i)), "client@example.com", "Order", "Body", time.Now())
+		email.CategoryID = team.ID
+		email.Unread = i > 0
+		assert.NoError(t, f.emailRepo.Create(ctx, email))
+	}
+
+	_, err = f.orgService.GetStats(ctx, f.bob.ID)
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+
+	stats, err := f.orgService.GetStats(ctx, f.alice.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Members)
+	assert.Equal(t, 3, stats.Emails)
+	assert.Equal(t, 2, stats.Unread)
+	assert.Equal(t, 1, stats.TeamCategories)
+	assert.Equal(t, 3, stats.EmailsByCategory["Customers"])
+	if assert.Len(t, stats.EmailsByMember, 2) {
+		assert.Equal(t, 2, stats.EmailsByMember[1].Emails)
+	}
+}
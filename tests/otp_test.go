@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOTPExtraction(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	synced := []*model.Email{
+		model.NewEmail("", "code_after", "Acme <no-reply@acme.example>", "Your Acme verification code",
+			"<p>Hi,</p><p>Your verification code is <b>482 913</b>.</p><p>It expires in 15 minutes.</p><p>© 2024 Acme</p>", now),
+		model.NewEmail("", "code_before", "Social <security@social.example>", "G-771204 is your login code", "<p>Welcome back</p>", now),
+		model.NewEmail("", "code_hours", "Bank <alerts@bank.example>", "Sign-in attempt",
+			"Use the one-time passcode 55310921 to sign in. This code is valid for 2 hours.", now),
+		model.NewEmail("", "no_keyword", "Shop <orders@shop.example>", "Order 123456 shipped", "<p>Order 123456 is on its way</p>", now),
+		model.NewEmail("", "no_code", "Acme <no-reply@acme.example>", "Verification code requested", "<p>Open the app to see it</p>", now),
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	container, user, _ := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient)))
+
+	other := model.NewUser("google_456", "other@example.com", "Other User", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, other)
+
+	// Codes are pushed as their own event during sync
+	events := container.SSEManager.AddClient(user.ID)
+	container.EmailSyncJob.RunSync()
+	codes := make(map[string]string)
+	for len(events) > 0 {
+		var event struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(<-events, &event))
+		if event.Type == model.EventOTP {
+			var otp model.OTP
+			assert.NoError(t, json.Unmarshal(event.Data, &otp))
+			codes[otp.From] = otp.Code
+		}
+	}
+	assert.Equal(t, map[string]string{"Acme": "482913", "Social": "771204", "Bank": "55310921"}, codes)
+
+	stored := func(gmailID string) *model.Email {
+		email, err := container.EmailRepo.FindByGmailID(ctx, user.ID, gmailID)
+		assert.NoError(t, err)
+		return email
+	}
+	get := func(userID, emailID string) (int, model.OTP) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/emails/"+emailID+"/otp", nil)
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		rec := serve(container, req)
+		var otp model.OTP
+		json.Unmarshal(rec.Body.Bytes(), &otp)
+		return rec.Code, otp
+	}
+
+	email := stored("code_after")
+	status, otp := get(user.ID, email.ID)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, email.ID, otp.EmailID)
+	assert.Equal(t, "482913", otp.Code)
+	assert.True(t, otp.ExpiresAt.Equal(now.Add(15*time.Minute)))
+	assert.False(t, otp.Expired)
+
+	_, otp = get(user.ID, stored("code_hours").ID)
+	assert.True(t, otp.ExpiresAt.Equal(now.Add(2*time.Hour)))
+	_, otp = get(user.ID, stored("code_before").ID)
+	assert.True(t, otp.ExpiresAt.Equal(now.Add(10*time.Minute)))
+
+	// Listings carry the expiry but not the code
+	data, err := json.Marshal(email)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"otp_expires_at"`)
+	assert.NotContains(t, string(data), "482913")
+
+	status, _ = get(user.ID, stored("no_keyword").ID)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = get(user.ID, stored("no_code").ID)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = get(other.ID, email.ID)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Code emails go to the trash once past the retention window
+	old := model.NewEmail(user.ID, "old_code", "Acme <no-reply@acme.example>", "Your verification code", "Your verification code is 1234", now.Add(-48*time.Hour))
+	old.OTPCode = "1234"
+	expiresAt := old.ReceivedAt.Add(10 * time.Minute)
+	old.OTPExpiresAt = &expiresAt
+	assert.NoError(t, container.EmailRepo.Create(ctx, old))
+
+	status, otp = get(user.ID, old.ID)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, otp.Expired)
+
+	container.CleanupJob.RunCleanup()
+	trashed := stored("old_code")
+	assert.NotNil(t, trashed.DeletedAt)
+	assert.Nil(t, stored("code_after").DeletedAt)
+	assert.Nil(t, stored("no_keyword").DeletedAt)
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryServiceScopesCategoriesToOwner(t *testing.T) {
+	ctx := context.Background()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	categoryService := service.NewCategoryService(categoryRepo, memory.NewInMemoryOrganizationRepository(), logger.New())
+
+	shared := model.NewCategory("Newsletters", "Shared default")
+	assert.NoError(t, categoryRepo.Create(ctx, shared))
+	alice, err := categoryService.CreateCategory(ctx, "alice", "Work", "Alice's work emails")
+	assert.NoError(t, err)
+	bob, err := categoryService.CreateCategory(ctx, "bob", "Family", "Bob's family emails")
+	assert.NoError(t, err)
+
+	// Each user sees the shared categories and their own
+	categories, err := categoryService.GetAllCategories(ctx, "alice")
+	assert.NoError(t, err)
+	var names []string
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	assert.ElementsMatch(t, []string{"Newsletters", "Work"}, names)
+
+	// Another user's category looks like it does not exist
+	_, err = categoryService.GetCategory(ctx, "alice", bob.ID)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+	_, err = categoryService.UpdateCategory(ctx, "alice", bob.ID, "Hijacked", "")
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+	assert.True(t, errors.Is(categoryService.DeleteCategory(ctx, "alice", bob.ID), apierror.ErrNotFound))
+
+	stored, err := categoryRepo.FindByID(ctx, bob.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Family", stored.Name)
+
+	// Shared categories are readable but not modifiable
+	_, err = categoryService.GetCategory(ctx, "alice", shared.ID)
+	assert.NoError(t, err)
+	_, err = categoryService.UpdateCategory(ctx, "alice", shared.ID, "Renamed", "")
+	assert.True(t, errors.Is(err, apierror.ErrForbidden))
+	assert.True(t, errors.Is(categoryService.DeleteCategory(ctx, "alice", shared.ID), apierror.ErrForbidden))
+
+	// Owners keep full control
+	_, err = categoryService.UpdateCategory(ctx, "alice", alice.ID, "Office", "")
+	assert.NoError(t, err)
+	assert.NoError(t, categoryService.DeleteCategory(ctx, "alice", alice.ID))
+}
+
+func TestEmailServiceScopesEmailsToOwner(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, gmail.NewMockGmailClient(), ai.NewMockAIClient(), logger.New())
+
+	alice := model.NewUser("google_alice", "alice@example.com", "Alice", "access_token", "refresh_token", time.Time{})
+	alice.GrantedScopes = model.ScopeGmailModify
+	bob := model.NewUser("google_bob", "bob@example.com", "Bob", "access_token", "refresh_token", time.Time{})
+	bob.GrantedScopes = model.ScopeGmailModify
+	assert.NoError(t, userRepo.Create(ctx, alice))
+	assert.NoError(t, userRepo.Create(ctx, bob))
+
+	aliceEmail := model.NewEmail(alice.ID, "msg_alice", "a@example.com", "For Alice", "Body", time.Now())
+	aliceEmail.CategoryID = "work"
+	bobEmail := model.NewEmail(bob.ID, "msg_bob", "b@example.com", "For Bob", "Body", time.Now())
+	bobEmail.CategoryID = "work"
+	assert.NoError(t, emailRepo.Create(ctx, aliceEmail))
+	assert.NoError(t, emailRepo.Create(ctx, bobEmail))
+
+	// Category listings only contain the caller's emails
+	emails, err := emailService.GetEmailsByCategory(ctx, alice.ID, "work", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, emails, 1) {
+		assert.Equal(t, aliceEmail.ID, emails[0].ID)
+	}
+
+	// Another user's email cannot be read, modified or deleted
+	_, err = emailService.GetEmail(ctx, alice.ID, bobEmail.ID)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	assert.NoError(t, emailService.PerformBulkAction(ctx, []string{bobEmail.ID}, "local_archive", alice.ID))
+	assert.NoError(t, emailService.DeleteEmails(ctx, []string{bobEmail.ID}, alice.ID))
+
+	stored, err := emailRepo.FindByIDAndUser(ctx, bobEmail.ID, bob.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.LocallyArchived)
+	assert.Nil(t, stored.DeletedAt)
+}
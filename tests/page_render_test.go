@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/push"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagesRenderServerData(t *testing.T) {
+	ctx := context.Background()
+	container, user, request := newTestApp(t, withUser(func(user *model.User) {
+		user.Email = "ada@example.com"
+		user.Name = "Ada Lovelace"
+		user.AccessToken = "secret_access_token"
+		user.RefreshToken = "secret_refresh_token"
+	}))
+	categories, err := container.CategoryService.GetAllCategories(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, categories)
+
+	// Signed in, the page carries the user and their categories but never their tokens
+	rec := serve(container, request(http.MethodGet, "/app", ""))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "<title>Email Organization App</title>")
+	assert.Contains(t, body, `"email":"ada@example.com"`)
+	assert.Contains(t, body, `"name":"Ada Lovelace"`)
+	assert.Contains(t, body, categories[0].Name)
+	assert.NotContains(t, body, "secret_access_token")
+	assert.NotContains(t, body, "secret_refresh_token")
+
+	// Features that aren't set up are left out of the menus
+	assert.Contains(t, body, `"push":false`)
+	assert.NotContains(t, body, `onclick="enablePushNotifications()"`)
+
+	// Signed out, pages render without a user
+	for _, path := range []string{"/", "/app", "/api/docs"} {
+		rec = serve(container, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), `"user":null`, path)
+		assert.Contains(t, rec.Body.String(), "<!DOCTYPE html>", path)
+	}
+}
+
+func TestPagesShowConfiguredFeatures(t *testing.T) {
+	container, _, _ := newTestApp(t, withAppOptions(app.WithPushClient(push.NewMockPushClient())))
+
+	rec := serve(container, httptest.NewRequest(http.MethodGet, "/app", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"push":true`)
+	assert.Contains(t, rec.Body.String(), `onclick="enablePushNotifications()"`)
+	assert.NotContains(t, rec.Body.String(), `onclick="linkTelegram()"`)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/sse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresenceAndConnectionsEndpoints(t *testing.T) {
+	container, user, _ := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AdminEmails = []string{"Ops@example.com"}
+	}))
+
+	ctx := context.Background()
+	operator := model.NewUser("google_456", "ops@example.com", "Operator", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, operator)
+
+	get := func(path string, as *model.User, out any) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(sessionCookie(t, as.ID, time.Now().Add(time.Hour)))
+		rec := serve(container, req)
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+		}
+		return rec.Code
+	}
+
+	var presence sse.Presence
+	assert.Equal(t, http.StatusOK, get("/api/v1/sse/status", user, &presence))
+	assert.False(t, presence.Connected)
+	assert.Empty(t, presence.Connections)
+
+	first := container.SSEManager.AddClient(user.ID)
+	time.Sleep(10 * time.Millisecond)
+	container.SSEManager.AddClient(user.ID)
+	assert.Equal(t, http.StatusOK, get("/api/v1/sse/status", user, &presence))
+	assert.True(t, presence.Connected)
+	if assert.Len(t, presence.Connections, 2) {
+		assert.True(t, presence.Connections[0].ConnectedAt.Before(presence.Connections[1].ConnectedAt))
+		assert.False(t, presence.Connections[0].Slow)
+	}
+
+	// Only operators see everyone's connections
+	var connections handler.ConnectionsResponse
+	assert.Equal(t, http.StatusForbidden, get("/api/v1/admin/connections", user, &connections))
+	assert.Equal(t, http.StatusOK, get("/api/admin/connections", operator, &connections))
+	if assert.Len(t, connections.Users, 1) {
+		assert.Equal(t, user.ID, connections.Users[0].UserID)
+		assert.Len(t, connections.Users[0].Connections, 2)
+	}
+	assert.Equal(t, 2, connections.Stats.Connections)
+
+	container.SSEManager.RemoveClient(user.ID, first)
+	assert.Equal(t, http.StatusOK, get("/api/v1/admin/connections", operator, &connections))
+	assert.Len(t, connections.Users[0].Connections, 1)
+}
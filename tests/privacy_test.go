@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivacyMode(t *testing.T) {
+	ctx := context.Background()
+	var aiCalls []string
+	aiClient := ai.NewMockAIClient()
+	aiClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		aiCalls = append(aiCalls, emailBody)
+		return "Medical", nil
+	}
+	aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+		aiCalls = append(aiCalls, emailBody)
+		return "summary", nil
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	var synced []*model.Email
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient), app.WithAIClient(aiClient)))
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		return serve(container, request(method, "/api/v1"+path, body))
+	}
+	syncEmails := func(emails ...*model.Email) {
+		synced = emails
+		_, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+		assert.NoError(t, err)
+	}
+	stored := func(gmailID string) *model.Email {
+		email, err := container.EmailRepo.FindByGmailID(ctx, user.ID, gmailID)
+		assert.NoError(t, err)
+		return email
+	}
+
+	// Categories are marked sensitive through the category API
+	rec := call(http.MethodPost, "/categories", `{"name":"Medical","description":"Doctors and labs","sensitive":true}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var medical model.Category
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &medical))
+	assert.True(t, medical.Sensitive)
+
+	rec = call(http.MethodGet, "/settings/privacy", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var settings model.PrivacySettings
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &settings))
+	assert.False(t, settings.Enabled)
+
+	// Outside privacy mode, sensitive categories are processed like any other
+	syncEmails(model.NewEmail("", "lab_1", "Lab <results@lab.example>", "Results", "<p>first results</p>", time.Now()))
+	assert.Equal(t, []string{"first results", "first results"}, aiCalls)
+	assert.Equal(t, medical.ID, stored("lab_1").CategoryID)
+	assert.NotEmpty(t, stored("lab_1").Summary)
+
+	rec = call(http.MethodPut, "/settings/privacy", `{"enabled":true}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &settings))
+	assert.True(t, settings.Enabled)
+
+	// The sender's next email is filed with the last one without the AI reading it
+	aiCalls = nil
+	syncEmails(model.NewEmail("", "lab_2", "Lab <results@lab.example>", "Results", "<p>second results</p>", time.Now()))
+	assert.Empty(t, aiCalls)
+	assert.Equal(t, medical.ID, stored("lab_2").CategoryID)
+	assert.Empty(t, stored("lab_2").Summary)
+
+	// New senders are still classified, but not summarized when they land in a sensitive category
+	syncEmails(model.NewEmail("", "clinic_1", "clinic@clinic.example", "Appointment", "<p>appointment</p>", time.Now()))
+	assert.Equal(t, []string{"appointment"}, aiCalls)
+	assert.Equal(t, medical.ID, stored("clinic_1").CategoryID)
+	assert.Empty(t, stored("clinic_1").Summary)
+
+	// Bodies are shown as text only and remote content is blocked
+	email := model.NewEmail(user.ID, "news_1", "news@example.com", "News",
+		`<p>Hello <b>there</b></p><img src="https://cdn.example.com/pixel.png">`, time.Now())
+	assert.NoError(t, container.EmailRepo.Create(ctx, email))
+
+	rec = call(http.MethodGet, "/emails/"+email.ID+"/body", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body handler.EmailBodyResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.True(t, body.TextOnly)
+	assert.NotContains(t, body.Body, "<")
+	assert.Contains(t, body.Body, "Hello there")
+
+	rec = call(http.MethodGet, "/emails/"+email.ID, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var detail model.EmailDetail
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &detail))
+	assert.True(t, detail.TextOnly)
+	assert.NotContains(t, detail.Email.Body, "cdn.example.com")
+
+	rec = call(http.MethodGet, "/proxy/image?url="+url.QueryEscape("https://cdn.example.com/logo.png"), "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Exports keep sensitive emails but not their content
+	exported, err := container.EmailService.ExportEmails(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, exported, 4)
+	for _, email := range exported {
+		if email.CategoryID == medical.ID {
+			assert.Empty(t, email.Body, email.GmailID)
+			assert.Empty(t, email.Summary, email.GmailID)
+			assert.NotEmpty(t, email.Subject, email.GmailID)
+		} else {
+			assert.NotEmpty(t, email.Body, email.GmailID)
+		}
+	}
+
+	// Reprocessing leaves sensitive emails alone too
+	lab := stored("lab_2")
+	lab.NeedsReprocessing = true
+	assert.NoError(t, container.EmailRepo.Update(ctx, lab))
+	aiCalls = nil
+	_, err = container.EmailService.ReprocessEmails(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, aiCalls)
+}
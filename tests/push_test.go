@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/push"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushServiceNotifiesImportantEmails(t *testing.T) {
+	ctx := context.Background()
+	userID := "test_user_123"
+
+	var sent []*model.PushNotification
+	pushClient := push.NewMockPushClient()
+	pushClient.SendFunc = func(ctx context.Context, subscription *model.PushSubscription, notification *model.PushNotification) error {
+		if subscription.Endpoint == "https://push.example.com/expired" {
+			return service.ErrPushSubscriptionGone
+		}
+		sent = append(sent, notification)
+		return nil
+	}
+
+	subscriptionRepo := memory.NewInMemoryPushSubscriptionRepository()
+	notificationService := service.NewNotificationService(memory.NewInMemoryNotificationPreferencesRepository(), logger.New())
+	pushService := service.NewPushService(subscriptionRepo, pushClient, notificationService, logger.New())
+
+	publicKey, err := pushService.PublicKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "mock-vapid-public-key", publicKey)
+
+	_, err = pushService.Subscribe(ctx, userID, "http://push.example.com/plain", "key", "auth")
+	assert.Error(t, err)
+	assert.False(t, pushService.HasSubscriptions(ctx, userID))
+
+	_, err = pushService.Subscribe(ctx, userID, "https://push.example.com/active", "key", "auth")
+	assert.NoError(t, err)
+	_, err = pushService.Subscribe(ctx, userID, "https://push.example.com/expired", "key", "auth")
+	assert.NoError(t, err)
+	assert.True(t, pushService.HasSubscriptions(ctx, userID))
+
+	// Only emails Gmail marked important are pushed
+	email := model.NewEmail(userID, "msg_123", "Boss <boss@example.com>", "Quarterly report", "Body", time.Now())
+	pushService.NotifyNewEmail(ctx, email)
+	assert.Empty(t, sent)
+
+	email.Important = true
+	pushService.NotifyNewEmail(ctx, email)
+	assert.Len(t, sent, 1)
+	assert.Equal(t, model.EventNewEmail, sent[0].Type)
+	assert.Equal(t, "Boss", sent[0].Title)
+	assert.Equal(t, "Quarterly report", sent[0].Body)
+
+	// The subscription the push service reported as gone was dropped
+	subscriptions, err := subscriptionRepo.FindByUserID(ctx, userID)
+	assert.NoError(t, err)
+	assert.Len(t, subscriptions, 1)
+	assert.Equal(t, "https://push.example.com/active", subscriptions[0].Endpoint)
+
+	// Quiet hours hold back new mail but not finished jobs
+	now := time.Now().UTC()
+	_, err = notificationService.UpdatePreferences(ctx, userID, now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"), "UTC", nil, model.PriorityLow)
+	assert.NoError(t, err)
+
+	pushService.NotifyNewEmail(ctx, email)
+	job := model.NewBulkJob(userID, "archive", model.EmailFilter{})
+	job.Status, job.Total, job.Processed = model.BulkJobCompleted, 3, 3
+	pushService.NotifyJobFinished(ctx, job)
+	assert.Len(t, sent, 2)
+	assert.Equal(t, model.EventBulkJob, sent[1].Type)
+
+	assert.NoError(t, pushService.Unsubscribe(ctx, userID, "https://push.example.com/active"))
+	assert.False(t, pushService.HasSubscriptions(ctx, userID))
+}
+
+func TestPushServiceDisabledWithoutClient(t *testing.T) {
+	pushService := service.NewPushService(memory.NewInMemoryPushSubscriptionRepository(), nil,
+		service.NewNotificationService(memory.NewInMemoryNotificationPreferencesRepository(), logger.New()), logger.New())
+
+	_, err := pushService.PublicKey()
+	assert.Error(t, err)
+	_, err = pushService.Subscribe(context.Background(), "test_user_123", "https://push.example.com/active", "key", "auth")
+	assert.Error(t, err)
+}
+
+func TestPushClientEncryptsForSubscription(t *testing.T) {
+	publicKey, privateKey, err := push.GenerateVAPIDKeys()
+	assert.NoError(t, err)
+	pushClient, err := push.NewPushClient(publicKey, privateKey, "mailto:admin@example.com", logger.New())
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, pushClient.PublicKey())
+
+	// The browser side of the subscription
+	browserKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+	subscription := model.NewPushSubscription("test_user_123", "", base64.RawURLEncoding.EncodeToString(browserKey.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(authSecret))
+
+	status := http.StatusCreated
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	subscription.Endpoint = server.URL + "/push/abc"
+
+	notification := &model.PushNotification{Type: model.EventNewEmail, Title: "Boss", Body: "Quarterly report", URL: "/app", Urgency: "high"}
+	assert.NoError(t, pushClient.Send(context.Background(), subscription, notification))
+
+	assert.Equal(t, "aes128gcm", header.Get("Content-Encoding"))
+	assert.Equal(t, "high", header.Get("Urgency"))
+	assert.Equal(t, "86400", header.Get("TTL"))
+	assert.True(t, strings.HasPrefix(header.Get("Authorization"), "vapid t="))
+	assert.True(t, strings.HasSuffix(header.Get("Authorization"), ", k="+publicKey))
+
+	var received model.PushNotification
+	assert.NoError(t, json.Unmarshal(decryptPushBody(t, browserKey, authSecret, body), &received))
+	assert.Equal(t, "Boss", received.Title)
+	assert.Equal(t, "Quarterly report", received.Body)
+
+	// Push services answer 410 once the browser unsubscribed
+	status = http.StatusGone
+	assert.ErrorIs(t, pushClient.Send(context.Background(), subscription, notification), service.ErrPushSubscriptionGone)
+}
+
+// decryptPushBody reverses RFC 8291 the way a browser does
+func decryptPushBody(t *testing.T, browserKey *ecdh.PrivateKey, authSecret, body []byte) []byte {
+	t.Helper()
+	salt, keyIDLength := body[:16], int(body[20])
+	serverPublic, ciphertext := body[21:21+keyIDLength], body[21+keyIDLength:]
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPublic)
+	assert.NoError(t, err)
+	sharedSecret, err := browserKey.ECDH(serverKey)
+	assert.NoError(t, err)
+
+	keyInfo := "WebPush: info\x00" + string(browserKey.PublicKey().Bytes()) + string(serverPublic)
+	ikm, _ := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	contentKey, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(contentKey)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
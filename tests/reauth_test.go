@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/push"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedGrantAsksToSignInAgain(t *testing.T) {
+	ctx := context.Background()
+
+	// Google refuses the refresh of the user's token until they sign in again
+	var mutex sync.Mutex
+	revoked := true
+	syncs := 0
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		syncs++
+		if revoked {
+			return nil, fmt.Errorf("failed to refresh access token: %w: Token has been expired or revoked.", service.ErrGmailReauthRequired)
+		}
+		return nil, nil
+	}
+	var pushed []*model.PushNotification
+	pushClient := push.NewMockPushClient()
+	pushClient.SendFunc = func(ctx context.Context, subscription *model.PushSubscription, notification *model.PushNotification) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		pushed = append(pushed, notification)
+		return nil
+	}
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient), app.WithPushClient(pushClient)),
+		withUser(func(user *model.User) {
+			user.GoogleID = "google_1"
+			user.Email = "ada@example.com"
+			user.Name = "Ada"
+		}), withGmailModify())
+	_, err := container.PushService.Subscribe(ctx, user.ID, "https://push.example.com/ada", "key", "auth")
+	assert.NoError(t, err)
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		return serve(container, request(method, "/api/v1"+path, body))
+	}
+	me := func() handler.MeResponse {
+		rec := call(http.MethodGet, "/me", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var me handler.MeResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &me))
+		return me
+	}
+	assert.Nil(t, me().Banner)
+
+	// The sync that finds the grant revoked marks the user and tells them over SSE and push
+	events := container.SSEManager.AddClient(user.ID)
+	defer container.SSEManager.RemoveClient(user.ID, events)
+	container.EmailSyncJob.RunSync()
+
+	stored, err := container.UserRepo.FindByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.NeedsReauth)
+
+	var prompts []sse.ReauthRequired
+	for len(events) > 0 {
+		var event struct {
+			Type string             `json:"type"`
+			Data sse.ReauthRequired `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(<-events, &event))
+		if event.Type == model.EventReauth {
+			prompts = append(prompts, event.Data)
+		}
+	}
+	if assert.Len(t, prompts, 1) {
+		assert.Equal(t, handler.ReauthURL, prompts[0].URL) // the user had granted modify access
+	}
+	mutex.Lock()
+	if assert.Len(t, pushed, 1) {
+		assert.Equal(t, model.EventReauth, pushed[0].Type)
+	}
+	mutex.Unlock()
+
+	// /me shows the banner, and the user stays signed in to see it
+	current := me()
+	assert.True(t, current.User.NeedsReauth)
+	assert.Equal(t, &handler.Banner{
+		Type:      model.EventReauth,
+		Message:   "Google access was revoked. Sign in again to keep syncing your emails.",
+		ActionURL: handler.ReauthURL,
+	}, current.Banner)
+
+	// The user isn't synced any more, and Gmail actions ask them to sign in again
+	for range 3 {
+		container.EmailSyncJob.RunSync()
+	}
+	mutex.Lock()
+	assert.Equal(t, 1, syncs)
+	mutex.Unlock()
+
+	rec := call(http.MethodPost, "/emails/sync", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var errorResponse handler.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, apierror.CodeReauthRequired, errorResponse.Code)
+	assert.Equal(t, handler.ReauthURL, errorResponse.ReauthURL)
+
+	email := model.NewEmail(user.ID, "msg_1", "news@paper.example", "Today's news", "body", time.Now())
+	assert.NoError(t, container.EmailRepo.Create(ctx, email))
+	err = container.EmailService.PerformBulkAction(ctx, []string{email.ID}, "archive", user.ID)
+	var apiErr *apierror.Error
+	if assert.True(t, errors.As(err, &apiErr), "got %v", err) {
+		assert.Equal(t, apierror.CodeReauthRequired, apiErr.Code)
+	}
+
+	// Signing in again clears the banner and resumes syncing
+	mutex.Lock()
+	revoked = false
+	mutex.Unlock()
+	_, err = container.AuthService.GetOrCreateUser(ctx, "google_1", "ada@example.com", "Ada", "new_access_token", "new_refresh_token", nil)
+	assert.NoError(t, err)
+	current = me()
+	assert.False(t, current.User.NeedsReauth)
+	assert.Nil(t, current.Banner)
+	assert.Equal(t, model.BreakerClosed, current.Gmail.Breaker)
+
+	container.EmailSyncJob.RunSync()
+	mutex.Lock()
+	assert.Equal(t, 2, syncs)
+	mutex.Unlock()
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendationsAPI(t *testing.T) {
+	ctx := context.Background()
+	container, user, request := newTestApp(t, withGmailModify())
+
+	promotions, err := container.CategoryService.CreateCategory(ctx, user.ID, "Promotions", "Deals")
+	assert.NoError(t, err)
+	work, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Work emails")
+	assert.NoError(t, err)
+
+	count := 0
+	addEmails := func(n int, from string, age time.Duration, categoryID string, unread bool) []*model.Email {
+		var emails []*model.Email
+		for range n {
+			count++
+			email := model.NewEmail(user.ID, fmt.Sprintf("msg_%d", count), from, "Subject", "body", time.Now().Add(-age-time.Duration(count)*time.Minute))
+			email.CategoryID = categoryID
+			email.Unread = unread
+			assert.NoError(t, container.EmailRepo.Create(ctx, email))
+			emails = append(emails, email)
+		}
+		return emails
+	}
+	stale := addEmails(12, "deals@shop.example", 40*24*time.Hour, promotions.ID, true)
+	addEmails(3, "deals@shop.example", 2*24*time.Hour, promotions.ID, true) // too recent to archive
+	addEmails(1, "deals@shop.example", 2*24*time.Hour, promotions.ID, false)
+	addEmails(9, "boss@work.example", 40*24*time.Hour, work.ID, true) // too few to bother
+	addEmails(1, "boss@work.example", time.Hour, work.ID, false)
+	news := addEmails(6, "news@paper.example", time.Hour, "", true)
+	addEmails(5, "friend@mail.example", time.Hour, "", true)
+	addEmails(1, "friend@mail.example", time.Hour, "", false)
+
+	call := func(method, path string) (int, []byte) {
+		rec := serve(container, request(method, "/api/v1"+path, ""))
+		return rec.Code, rec.Body.Bytes()
+	}
+	list := func() []model.Recommendation {
+		status, body := call(http.MethodGet, "/recommendations")
+		assert.Equal(t, http.StatusOK, status)
+		var recommendations []model.Recommendation
+		assert.NoError(t, json.Unmarshal(body, &recommendations))
+		return recommendations
+	}
+
+	// The largest cleanups come first; senders with an email read aren't ignored
+	recommendations := list()
+	if assert.Len(t, recommendations, 2) {
+		archive := recommendations[0]
+		assert.Equal(t, "archive_stale:"+promotions.ID, archive.ID)
+		assert.Equal(t, "archive", archive.Action)
+		assert.Equal(t, 12, archive.Count)
+		assert.Equal(t, "You have 12 unread Promotions emails older than 30 days. Archive them?", archive.Message)
+
+		unsubscribe := recommendations[1]
+		assert.Equal(t, "unsubscribe:news@paper.example", unsubscribe.ID)
+		assert.Equal(t, "unsubscribe", unsubscribe.Action)
+		assert.Equal(t, 6, unsubscribe.Count)
+		// Only the latest email is unsubscribed through
+		matched, err := container.EmailService.ListEmails(ctx, user.ID, unsubscribe.Filter, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{news[0].ID}, emailIDs(matched))
+	}
+
+	// Applying one queues its bulk job
+	status, body := call(http.MethodPost, "/recommendations/archive_stale:"+promotions.ID+"/apply")
+	assert.Equal(t, http.StatusAccepted, status)
+	var job model.BulkJob
+	assert.NoError(t, json.Unmarshal(body, &job))
+	assert.Equal(t, "archive", job.Action)
+	container.BulkJobs.RunPending()
+	finished, err := container.BulkJobs.GetJob(user.ID, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.BulkJobCompleted, finished.Status)
+	assert.Equal(t, 12, finished.Processed)
+	stored, err := container.EmailRepo.FindByID(ctx, stale[0].ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.Archived)
+
+	// Done recommendations are gone and can't be applied again
+	recommendations = list()
+	if assert.Len(t, recommendations, 1) {
+		assert.Equal(t, "unsubscribe:news@paper.example", recommendations[0].ID)
+	}
+	status, _ = call(http.MethodPost, "/recommendations/archive_stale:"+promotions.ID+"/apply")
+	assert.Equal(t, http.StatusNotFound, status)
+}
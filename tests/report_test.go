@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeeklyReports(t *testing.T) {
+	ctx := context.Background()
+	container, user, _ := newTestApp(t)
+
+	other := model.NewUser("google_456", "other@example.com", "Other User", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, other)
+
+	categories, err := container.CategoryService.GetAllCategories(ctx, user.ID)
+	assert.NoError(t, err)
+	category := categories[0]
+
+	// Wednesday, in the week after the one reported on
+	now := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	week := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	store := func(from string, receivedAt time.Time, prepare func(email *model.Email)) *model.Email {
+		email := model.NewEmail(user.ID, fmt.Sprint("gmail_", receivedAt.UnixNano(), from), from, "Subject", "<p>body</p>", receivedAt)
+		if prepare != nil {
+			prepare(email)
+		}
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		return email
+	}
+	for i := 0; i < 3; i++ {
+		store("News <news@shop.example>", week.Add(time.Duration(i+1)*time.Hour), func(email *model.Email) {
+			email.CategoryID = category.ID
+			email.Summary = "A sale"
+			email.Archived = true
+		})
+	}
+	store("Amy <amy@example.com>", week.AddDate(0, 0, 2), nil)
+	trashed := store("Bob <bob@example.com>", week.AddDate(0, 0, 6), nil)
+	assert.NoError(t, container.EmailRepo.Delete(ctx, trashed.ID))
+
+	// Unsubscribing during the week counts, whenever the email itself arrived
+	unsubscribedAt := week.AddDate(0, 0, 4)
+	store("Promo <promo@deals.example>", week.AddDate(0, 0, -10), func(email *model.Email) {
+		email.UnsubscribedAt = &unsubscribedAt
+	})
+	// Outside the week
+	store("Amy <amy@example.com>", week.AddDate(0, 0, 7), nil)
+
+	container.ReportJob.RunReports(now)
+
+	get := func(userID, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1"+path, nil)
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		return serve(container, req)
+	}
+	list := func(userID string) []*model.InboxReport {
+		rec := get(userID, "/reports")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var reports []*model.InboxReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &reports))
+		return reports
+	}
+
+	reports := list(user.ID)
+	if !assert.Len(t, reports, 1) {
+		return
+	}
+	report := reports[0]
+	assert.True(t, report.PeriodStart.Equal(week))
+	assert.True(t, report.PeriodEnd.Equal(week.AddDate(0, 0, 7)))
+
+	stats := report.Stats
+	assert.Equal(t, 5, stats.TotalEmails)
+	assert.Equal(t, []model.CategoryVolume{
+		{CategoryID: category.ID, Name: category.Name, Count: 3},
+		{CategoryID: "", Name: "Uncategorized", Count: 2},
+	}, stats.Categories)
+	assert.Equal(t, []model.SenderVolume{
+		{Address: "news@shop.example", Name: "News", Count: 3},
+		{Address: "amy@example.com", Name: "Amy", Count: 1},
+		{Address: "bob@example.com", Name: "Bob", Count: 1},
+	}, stats.TopSenders)
+	assert.Equal(t, 3, stats.Summarized)
+	assert.Equal(t, 3, stats.Archived)
+	assert.Equal(t, 1, stats.Unsubscribes)
+	// 3 summaries at 45s, 3 archived emails at 10s and an unsubscribe at 5 minutes
+	assert.Equal(t, 7, stats.TimeSavedMinutes)
+
+	rec := get(user.ID, "/reports/"+report.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var fetched model.InboxReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &fetched))
+	assert.Equal(t, stats, fetched.Stats)
+
+	// Users without emails still get an empty report, but never someone else's
+	otherReports := list(other.ID)
+	if assert.Len(t, otherReports, 1) {
+		assert.Equal(t, 0, otherReports[0].Stats.TotalEmails)
+		assert.NotEqual(t, report.ID, otherReports[0].ID)
+	}
+	assert.Equal(t, http.StatusNotFound, get(other.ID, "/reports/"+report.ID).Code)
+
+	// Running again in the same week generates nothing new
+	container.ReportJob.RunReports(now.Add(24 * time.Hour))
+	assert.Len(t, list(user.ID), 1)
+
+	assert.Equal(t, http.StatusBadRequest, get(user.ID, "/reports?limit=-1").Code)
+}
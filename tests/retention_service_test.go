@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionServicePrunesBodiesThenRecords(t *testing.T) {
+	retentionRepo := memory.NewInMemoryRetentionPolicyRepository()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	retentionService := service.NewRetentionService(retentionRepo, emailRepo, logger.New())
+	ctx := context.Background()
+	now := time.Now()
+
+	recent := model.NewEmail("user_1", "msg_1", "a@example.com", "Recent", "Body", now)
+	aging := model.NewEmail("user_1", "msg_2", "a@example.com", "Aging", "Body", now.AddDate(0, 0, -10))
+	expired := model.NewEmail("user_1", "msg_3", "a@example.com", "Expired", "Body", now.AddDate(0, 0, -40))
+	otherUser := model.NewEmail("user_2", "msg_4", "b@example.com", "Other", "Body", now.AddDate(0, 0, -40))
+	for _, email := range []*model.Email{recent, aging, expired, otherUser} {
+		assert.NoError(t, emailRepo.Create(ctx, email))
+	}
+
+	// Without a saved policy nothing is pruned
+	policy, err := retentionService.GetPolicy(ctx, "user_1")
+	assert.NoError(t, err)
+	assert.False(t, policy.IsEnabled())
+
+	_, err = retentionService.UpdatePolicy(ctx, "user_1", 7, 30, 0)
+	assert.NoError(t, err)
+
+	stats, err := retentionService.EnforcePolicies(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.BodiesPruned)
+	assert.Equal(t, 1, stats.EmailsDeleted)
+
+	stored, err := emailRepo.FindByID(ctx, aging.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.BodyPruned)
+	assert.Empty(t, stored.Body)
+
+	stored, err = emailRepo.FindByID(ctx, recent.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.BodyPruned)
+
+	_, err = emailRepo.FindByID(ctx, expired.ID)
+	assert.Error(t, err)
+
+	// Other users are untouched
+	_, err = emailRepo.FindByID(ctx, otherUser.ID)
+	assert.NoError(t, err)
+
+	userStats := retentionService.GetStats("user_1")
+	assert.Equal(t, 2, userStats.BodiesPruned)
+	assert.Equal(t, 1, userStats.EmailsDeleted)
+}
+
+func TestRetentionServiceKeepsNewestPerCategory(t *testing.T) {
+	retentionRepo := memory.NewInMemoryRetentionPolicyRepository()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	retentionService := service.NewRetentionService(retentionRepo, emailRepo, logger.New())
+	ctx := context.Background()
+	now := time.Now()
+
+	var newsletters []*model.Email
+	for i := 0; i < 3; i++ {
+		email := model.NewEmail("user_1", "news_"+string(rune('a'+i)), "a@example.com", "News", "Body", now.Add(-time.Duration(i)*time.Hour))
+		email.CategoryID = "newsletters"
+		newsletters = append(newsletters, email)
+		assert.NoError(t, emailRepo.Create(ctx, email))
+	}
+	receipt := model.NewEmail("user_1", "receipt", "b@example.com", "Receipt", "Body", now.Add(-5*time.Hour))
+	receipt.CategoryID = "receipts"
+	assert.NoError(t, emailRepo.Create(ctx, receipt))
+
+	_, err := retentionService.UpdatePolicy(ctx, "user_1", 0, 0, 2)
+	assert.NoError(t, err)
+
+	stats, err := retentionService.EnforcePolicies(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.EmailsDeleted)
+
+	// The oldest newsletter goes; the lone receipt stays
+	_, err = emailRepo.FindByID(ctx, newsletters[2].ID)
+	assert.Error(t, err)
+	_, err = emailRepo.FindByID(ctx, receipt.ID)
+	assert.NoError(t, err)
+}
+
+func TestRetentionServiceRejectsNegativeLimits(t *testing.T) {
+	retentionService := service.NewRetentionService(memory.NewInMemoryRetentionPolicyRepository(), memory.NewInMemoryEmailRepository(), logger.New())
+
+	_, err := retentionService.UpdatePolicy(context.Background(), "user_1", -1, 0, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassificationConfidence(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeAIServer(t, openAIAnswer("Shopping\n35"), openAIAnswer("work"), openAIAnswer("Work\nConfidence: 120%"))
+	client := ai.NewAIClientWithBaseURL(ai.ProviderOpenAI, "sk-test", "", fake.URL, logger.New())
+
+	// The model rates its answer on a second line
+	category, confidence, err := client.ClassifyEmailWithConfidence(ctx, "Can you send the report?", aiTestCategories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Shopping", category)
+	assert.Equal(t, 0.35, confidence)
+	sent, _ := json.Marshal(fake.lastRequest(t).body)
+	assert.Contains(t, string(sent), "how confident you are")
+
+	// Without a rating the answer is taken as certain, and ratings are capped
+	category, confidence, err = client.ClassifyEmailWithConfidence(ctx, "Can you send the report?", aiTestCategories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Work", category)
+	assert.Equal(t, 1.0, confidence)
+	_, confidence, err = client.ClassifyEmailWithConfidence(ctx, "Can you send the report?", aiTestCategories)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, confidence)
+
+	// The local model is less sure of emails its categories' words don't tell apart
+	local := ai.NewLocalClient(logger.New())
+	categories := []*model.Category{
+		model.NewCategory("Work", "Meetings, projects and messages from colleagues"),
+		model.NewCategory("Shopping", "Orders, receipts and deliveries from online stores"),
+	}
+	category, clear, err := local.ClassifyEmailWithConfidence(ctx, "Your order and receipt, the delivery is due Monday", categories)
+	assert.NoError(t, err)
+	assert.Equal(t, "Shopping", category)
+	_, unclear, err := local.ClassifyEmailWithConfidence(ctx, "Your order for the project meeting", categories)
+	assert.NoError(t, err)
+	assert.Greater(t, clear, unclear)
+	assert.LessOrEqual(t, clear, 1.0)
+	assert.GreaterOrEqual(t, unclear, 0.5)
+}
+
+func TestReviewQueue(t *testing.T) {
+	ctx := context.Background()
+	mockAI := ai.NewMockAIClient()
+	mockAI.ClassifyEmailWithConfidenceFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, float64, error) {
+		if strings.Contains(emailBody, "report") {
+			return "Work", 0.9, nil
+		}
+		return "Shopping", 0.3, nil
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return []*model.Email{
+			model.NewEmail("", "sure", "boss@example.com", "Report", "<p>Can you send the report?</p>", time.Now()),
+			model.NewEmail("", "unsure", "someone@example.com", "Hello", "<p>Are you coming on Friday?</p>", time.Now()),
+		}, nil
+	}
+	container, user, build := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.ReviewConfidence = 60
+	}), withAppOptions(app.WithGmailClient(gmailClient), app.WithAIClient(mockAI)))
+	work, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Colleagues")
+	assert.NoError(t, err)
+	shopping, err := container.CategoryService.CreateCategory(ctx, user.ID, "Shopping", "Orders")
+	assert.NoError(t, err)
+
+	var learned []*model.Email
+	container.EmailService.OnCategorized(func(ctx context.Context, email *model.Email) {
+		learned = append(learned, email)
+	})
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		return serve(container, build(method, "/api/v1"+path, body))
+	}
+
+	_, err = container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 2})
+	assert.NoError(t, err)
+
+	// A confident classification is filed, the other waits with its suggestion
+	sure, err := container.EmailRepo.FindByGmailID(ctx, user.ID, "sure")
+	assert.NoError(t, err)
+	assert.Equal(t, work.ID, sure.CategoryID)
+	assert.False(t, sure.InReview())
+	assert.Equal(t, 0.9, sure.Confidence)
+	unsure, err := container.EmailRepo.FindByGmailID(ctx, user.ID, "unsure")
+	assert.NoError(t, err)
+	assert.Empty(t, unsure.CategoryID)
+	assert.Equal(t, shopping.ID, unsure.ReviewCategoryID)
+	assert.Equal(t, 0.3, unsure.Confidence)
+
+	rec := request(http.MethodGet, "/review", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var queue []*model.Email
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queue))
+	if assert.Len(t, queue, 1) {
+		assert.Equal(t, unsure.ID, queue[0].ID)
+		assert.Equal(t, shopping.ID, queue[0].ReviewCategoryID)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/review/"+unsure.ID+"/resolve", `{}`).Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/review/"+sure.ID+"/resolve", `{"category_id":"`+work.ID+`"}`).Code)
+
+	// Resolving files the email under the user's pick and teaches the classifiers
+	rec = request(http.MethodPost, "/review/"+unsure.ID+"/resolve", `{"category_id":"`+work.ID+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resolved model.Email
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resolved))
+	assert.Equal(t, work.ID, resolved.CategoryID)
+	assert.Empty(t, resolved.ReviewCategoryID)
+	if assert.Len(t, learned, 1) {
+		assert.Equal(t, unsure.ID, learned[0].ID)
+		assert.Equal(t, work.ID, learned[0].CategoryID)
+	}
+
+	queue, err = container.EmailService.GetReviewQueue(ctx, user.ID, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/review/"+unsure.ID+"/resolve", `{"category_id":"`+work.ID+`"}`).Code)
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedViewsAPI(t *testing.T) {
+	ctx := context.Background()
+	container, user, _ := newTestApp(t)
+
+	other := model.NewUser("google_456", "other@example.com", "Other User", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, other)
+
+	receipts, err := container.CategoryService.CreateCategory(ctx, user.ID, "Receipts", "Orders and invoices")
+	assert.NoError(t, err)
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, subject := range []string{"Invoice for March", "Invoice for February", "Lunch on Friday?"} {
+		email := model.NewEmail(user.ID, "msg_"+subject, "shop@example.com", subject, "<p>body</p>", base.Add(-time.Duration(i)*24*time.Hour))
+		email.Unread = true
+		if i < 2 {
+			email.CategoryID = receipts.ID
+		}
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+	}
+
+	call := func(userID, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		return serve(container, req)
+	}
+
+	rec := call(user.ID, http.MethodPost, "/views", `{"name":"March invoices","filter":{"category_id":"`+receipts.ID+`","query":"invoice","after":"2024-03-01T00:00:00Z","unread":true}}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var view model.SavedView
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &view))
+	assert.Equal(t, "March invoices", view.Name)
+	assert.Equal(t, receipts.ID, view.Filter.CategoryID)
+
+	viewEmails := func(userID, viewID string) (int, []*model.Email) {
+		rec := call(userID, http.MethodGet, "/views/"+viewID+"/emails", "")
+		var emails []*model.Email
+		json.Unmarshal(rec.Body.Bytes(), &emails)
+		return rec.Code, emails
+	}
+	status, emails := viewEmails(user.ID, view.ID)
+	assert.Equal(t, http.StatusOK, status)
+	if assert.Len(t, emails, 1) {
+		assert.Equal(t, "Invoice for March", emails[0].Subject)
+		assert.Empty(t, emails[0].Body)
+	}
+
+	// Replacing the filter changes what the view lists
+	rec = call(user.ID, http.MethodPut, "/views/"+view.ID, `{"name":"Invoices","filter":{"query":"INVOICE"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, emails = viewEmails(user.ID, view.ID)
+	assert.Len(t, emails, 2)
+
+	rec = call(user.ID, http.MethodGet, "/views", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var views []*model.SavedView
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &views))
+	if assert.Len(t, views, 1) {
+		assert.Equal(t, "Invoices", views[0].Name)
+	}
+
+	// Names are unique per user regardless of case
+	rec = call(user.ID, http.MethodPost, "/views", `{"name":"invoices","filter":{}}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = call(other.ID, http.MethodPost, "/views", `{"name":"Invoices","filter":{}}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	// Filters are validated
+	var body struct {
+		Code   string                `json:"code"`
+		Fields []apierror.FieldError `json:"fields"`
+	}
+	rec = call(user.ID, http.MethodPost, "/views", `{"name":"Broken","filter":{"category_id":"missing","after":"2024-03-02T00:00:00Z","before":"2024-03-01T00:00:00Z","archive":"never"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, apierror.CodeValidation, body.Code)
+	assert.Equal(t, []apierror.FieldError{{Field: "filter.archive", Message: "must be one of: only, all"}}, body.Fields)
+	rec = call(user.ID, http.MethodPost, "/views", `{"name":"Broken","filter":{"category_id":"missing","after":"2024-03-02T00:00:00Z","before":"2024-03-01T00:00:00Z"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "filter.category_id", Message: "does not exist"},
+		{Field: "filter.before", Message: "must be later than after"},
+	}, body.Fields)
+
+	// Other users can't see, list through or delete the view
+	status, _ = viewEmails(other.ID, view.ID)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, http.StatusNotFound, call(other.ID, http.MethodGet, "/views/"+view.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(other.ID, http.MethodDelete, "/views/"+view.ID, "").Code)
+
+	assert.Equal(t, http.StatusNoContent, call(user.ID, http.MethodDelete, "/views/"+view.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(user.ID, http.MethodGet, "/views/"+view.ID, "").Code)
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityEvents(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	footer := strings.Repeat("<p>Our spring sale is on, with new deals every day.</p>", 12) + "<p>Forgot your password? Reset it here.</p>"
+	synced := []*model.Email{
+		model.NewEmail("", "reset", "GitHub <noreply@github.com>", "[GitHub] Please reset your password", "<p>We heard that you lost your password.</p>", now.Add(-4*time.Hour)),
+		model.NewEmail("", "sign_in", "Google <no-reply@accounts.google.com>", "Security alert", "<p>A new sign-in on Mac</p><p>We noticed a new sign-in to your account.</p>", now.Add(-3*time.Hour)),
+		model.NewEmail("", "change", "Bank <alerts@bank.example>", "Account update", "Your password was changed on March 3.", now.Add(-2*time.Hour)),
+		model.NewEmail("", "suspicious", "Social <security@social.example>", "Unusual login attempt blocked", "<p>Someone tried to log in from Lagos.</p>", now.Add(-time.Hour)),
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	container, user, _ := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient)))
+
+	other := model.NewUser("google_456", "other@example.com", "Other User", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, other)
+
+	// Each new security email is pushed as an alert during sync
+	events := container.SSEManager.AddClient(user.ID)
+	container.EmailSyncJob.RunSync()
+	alerts := make(map[string]string)
+	for len(events) > 0 {
+		var event struct {
+			Type string              `json:"type"`
+			Data model.SecurityEvent `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(<-events, &event))
+		if event.Type == model.EventSecurity {
+			alerts[event.Data.Subject] = event.Data.Kind
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"[GitHub] Please reset your password": model.SecurityPasswordReset,
+		"Security alert":                      model.SecurityNewSignIn,
+		"Account update":                      model.SecurityAccountChange,
+		"Unusual login attempt blocked":       model.SecuritySuspicious,
+	}, alerts)
+
+	// Password mentions in a newsletter's footer or a bounce don't count
+	synced = []*model.Email{
+		model.NewEmail("", "newsletter", "Shop <news@shop.example>", "Spring sale", footer, now),
+		model.NewEmail("", "bounce", "MAILER-DAEMON@example.com", "Mail delivery failed", "<p>Password reset link could not be delivered</p>", now),
+	}
+	_, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+
+	list := func(userID, query string) (int, []model.SecurityEvent) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/security-events"+query, nil)
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		rec := serve(container, req)
+		var events []model.SecurityEvent
+		json.Unmarshal(rec.Body.Bytes(), &events)
+		return rec.Code, events
+	}
+
+	status, listed := list(user.ID, "")
+	assert.Equal(t, http.StatusOK, status)
+	var kinds []string
+	for _, event := range listed {
+		kinds = append(kinds, event.Kind)
+	}
+	assert.Equal(t, []string{model.SecuritySuspicious, model.SecurityAccountChange, model.SecurityNewSignIn, model.SecurityPasswordReset}, kinds)
+	assert.Equal(t, "GitHub", listed[3].From)
+	assert.True(t, listed[3].ReceivedAt.Equal(now.Add(-4*time.Hour)))
+
+	// The flag doesn't depend on the category the email was filed under
+	email, err := container.EmailRepo.FindByID(ctx, listed[0].EmailID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SecuritySuspicious, email.SecurityFlag)
+	assert.NotEmpty(t, email.CategoryID)
+
+	_, listed = list(user.ID, "?limit=2")
+	assert.Len(t, listed, 2)
+
+	// Trashed emails drop out of the listing
+	assert.NoError(t, container.EmailRepo.Delete(ctx, email.ID))
+	_, listed = list(user.ID, "")
+	assert.Len(t, listed, 3)
+
+	status, listed = list(other.ID, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, listed)
+
+	status, _ = list(user.ID, "?limit=-1")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/cli"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoogleOAuthCheck(t *testing.T) {
+	ctx := context.Background()
+	answer := `{"error":"invalid_grant","error_description":"Malformed auth code."}`
+	var form map[string]string
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = map[string]string{"client_id": r.PostForm.Get("client_id"), "redirect_uri": r.PostForm.Get("redirect_uri")}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(answer))
+	}))
+	defer fake.Close()
+	check := func(clientID string) (string, error) {
+		return service.GoogleOAuthCheck(clientID, "secret", "http://localhost:8080/auth/google/callback", fake.URL, fake.Client())(ctx)
+	}
+
+	// Google only gets as far as rejecting the code with credentials it accepts
+	message, err := check("123.apps.googleusercontent.com")
+	assert.NoError(t, err)
+	assert.Contains(t, message, "accepted")
+	assert.Equal(t, map[string]string{"client_id": "123.apps.googleusercontent.com", "redirect_uri": "http://localhost:8080/auth/google/callback"}, form)
+
+	answer = `{"error":"invalid_client","error_description":"The OAuth client was not found."}`
+	_, err = check("123.apps.googleusercontent.com")
+	assert.ErrorContains(t, err, "The OAuth client was not found")
+
+	answer = `{"error":"redirect_uri_mismatch"}`
+	_, err = check("123.apps.googleusercontent.com")
+	assert.ErrorContains(t, err, "add http://localhost:8080/auth/google/callback to the OAuth client's authorized redirect URIs")
+
+	// Client IDs that can't be right aren't sent
+	form = nil
+	_, err = check("123")
+	assert.ErrorContains(t, err, "apps.googleusercontent.com")
+	_, err = check("")
+	assert.Error(t, err)
+	assert.Nil(t, form)
+}
+
+func TestSelfCheckReport(t *testing.T) {
+	ctx := context.Background()
+	mockAI := ai.NewMockAIClient()
+	mockAI.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		return "", errors.New("401 invalid api key")
+	}
+	checks := service.NewSelfCheckService(logger.New())
+	checks.AddCheck("ai", service.AICheck(mockAI))
+	checks.AddCheck("slow", func(ctx context.Context) (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		return "answered", nil
+	})
+
+	report := checks.Run(ctx)
+	assert.False(t, report.OK)
+	if assert.Len(t, report.Checks, 2) {
+		assert.Equal(t, "ai", report.Checks[0].Name)
+		assert.False(t, report.Checks[0].OK)
+		assert.Contains(t, report.Checks[0].Message, "check AI_PROVIDER, AI_API_KEY and DEFAULT_MODEL: 401 invalid api key")
+		assert.Equal(t, "slow", report.Checks[1].Name)
+		assert.True(t, report.Checks[1].OK)
+		assert.GreaterOrEqual(t, report.Checks[1].DurationMS, int64(10))
+	}
+
+	mockAI.ClassifyEmailFunc = nil
+	assert.True(t, checks.Run(ctx).OK)
+}
+
+func TestSelfCheckEndpoint(t *testing.T) {
+	ctx := context.Background()
+	container, _, _ := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AdminEmails = []string{"ops@example.com"}
+	}))
+
+	operator := model.NewUser("google_1", "ops@example.com", "Ops", "access_token", "refresh_token", time.Time{})
+	member := model.NewUser("google_2", "member@example.com", "Member", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, operator)
+	container.UserRepo.Create(ctx, member)
+	request := func(user *model.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/selfcheck", nil)
+		req.AddCookie(sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+		return serve(container, req)
+	}
+
+	// Without Google credentials the check fails, telling what to set
+	rec := request(operator)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var report model.SelfCheckReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.False(t, report.OK)
+	results := make(map[string]*model.SelfCheck)
+	for _, check := range report.Checks {
+		results[check.Name] = check
+	}
+	if assert.Contains(t, results, "google_oauth") && assert.Contains(t, results, "database") {
+		assert.False(t, results["google_oauth"].OK)
+		assert.Contains(t, results["google_oauth"].Message, "GOOGLE_CLIENT_ID")
+		assert.True(t, results["database"].OK)
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(member).Code)
+}
+
+func TestCLICheck(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "")
+	t.Setenv("AI_API_KEY", "")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_REPLICA_URL", "")
+	var out, errOut bytes.Buffer
+
+	assert.Equal(t, 1, cli.Run([]string{"--check"}, &out, &errOut))
+	assert.Contains(t, out.String(), "GOOGLE_CLIENT_SECRET is required")
+	assert.Contains(t, out.String(), "no AI_API_KEY")
+	assert.Contains(t, errOut.String(), "2 of 4 checks failed")
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSenderHistoryAPI(t *testing.T) {
+	ctx := context.Background()
+	unsubscribePage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body><p>You have been removed from the list.</p></body></html>"))
+	}))
+	defer unsubscribePage.Close()
+	aiClient := ai.NewMockAIClient()
+	aiClient.AnalyzeFunc = func(ctx context.Context, task, content string) (string, error) {
+		return "CONFIRMED", nil
+	}
+	container, user, request := newTestApp(t, withAppOptions(app.WithAIClient(aiClient)), withUser(func(user *model.User) {
+		user.BlockSender("news@example.com")
+	}))
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	addEmail := func(gmailID, from string, day int, edit func(*model.Email)) *model.Email {
+		email := model.NewEmail(user.ID, gmailID, from, "Issue "+gmailID, "<p>Read online</p>", base.AddDate(0, 0, day))
+		email.Summary = "Summary of " + gmailID
+		if edit != nil {
+			edit(email)
+		}
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		return email
+	}
+	addEmail("news_3", "The News <News@Example.com>", 3, func(e *model.Email) {
+		e.Unread = true
+		e.Body = `<a href="` + unsubscribePage.URL + `/unsubscribe">Unsubscribe</a>`
+	})
+	addEmail("news_1", "News <news@example.com>", 1, func(e *model.Email) { e.Archived = true })
+	addEmail("news_2", "News <news@example.com>", 2, func(e *model.Email) { e.LocallyArchived, e.Unread = true, true })
+	trashed := addEmail("news_0", "News <news@example.com>", 0, nil)
+	assert.NoError(t, container.EmailRepo.Delete(ctx, trashed.ID))
+	addEmail("other", "shop@example.com", 1, nil)
+
+	// A successful unsubscribe is recorded on the email it went through
+	latest, err := container.EmailRepo.FindByGmailID(ctx, user.ID, "news_3")
+	assert.NoError(t, err)
+	assert.NoError(t, container.UnsubscribeService.UnsubscribeEmails(ctx, []string{latest.ID}, user.ID))
+
+	get := func(address string) (int, model.SenderHistory) {
+		rec := serve(container, request(http.MethodGet, "/api/v1/senders/"+address+"/history", ""))
+		var history model.SenderHistory
+		json.Unmarshal(rec.Body.Bytes(), &history)
+		return rec.Code, history
+	}
+
+	status, history := get("News%40Example.com")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "news@example.com", history.Address)
+	assert.Equal(t, "The News", history.Name)
+	assert.True(t, history.Blocked)
+
+	var subjects []string
+	for _, email := range history.Emails {
+		subjects = append(subjects, email.Subject)
+		assert.Empty(t, email.Body)
+	}
+	assert.Equal(t, []string{"Issue news_0", "Issue news_1", "Issue news_2", "Issue news_3"}, subjects)
+	assert.Equal(t, "Summary of news_0", history.Emails[0].Summary)
+
+	stats := history.Stats
+	assert.Equal(t, 4, stats.Total)
+	assert.Equal(t, 2, stats.Unread)
+	assert.Equal(t, 2, stats.Archived)
+	assert.Equal(t, 1, stats.Deleted)
+	assert.Equal(t, 1, stats.Unsubscribed)
+	if assert.NotNil(t, stats.FirstReceivedAt) && assert.NotNil(t, stats.LastReceivedAt) {
+		assert.True(t, stats.FirstReceivedAt.Equal(base))
+		assert.True(t, stats.LastReceivedAt.Equal(base.AddDate(0, 0, 3)))
+	}
+
+	// Unknown senders have an empty history, and addresses are checked
+	status, history = get("nobody@example.com")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, history.Emails)
+	assert.False(t, history.Blocked)
+	status, _ = get("not-an-address")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestSenderProfileAPI(t *testing.T) {
+	ctx := context.Background()
+	var prompts []string
+	aiClient := ai.NewMockAIClient()
+	aiClient.AnalyzeFunc = func(ctx context.Context, task, content string) (string, error) {
+		prompts = append(prompts, task+"\n"+content)
+		return " weekly marketing newsletter, ~1 email/week, rarely important\n", nil
+	}
+	container, user, request := newTestApp(t, withAppOptions(app.WithAIClient(aiClient)))
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	addEmail := func(gmailID string, week int) {
+		email := model.NewEmail(user.ID, gmailID, "Shop <deals@shop.example>", "Sale "+gmailID, "<p>Deals</p>", base.AddDate(0, 0, 7*week))
+		email.Summary = "Discounts on " + gmailID
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+	}
+	for week := range 4 {
+		addEmail(fmt.Sprintf("deal_%d", week), week)
+	}
+
+	generate := func(path string) (int, model.SenderProfile) {
+		rec := serve(container, request(http.MethodPost, "/api/v1/senders/"+path, ""))
+		var profile model.SenderProfile
+		json.Unmarshal(rec.Body.Bytes(), &profile)
+		return rec.Code, profile
+	}
+
+	status, profile := generate("deals@shop.example/profile")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "deals@shop.example", profile.Address)
+	assert.Equal(t, "weekly marketing newsletter, ~1 email/week, rarely important", profile.Description)
+	assert.Equal(t, 4, profile.EmailCount)
+	if assert.Len(t, prompts, 1) {
+		assert.Contains(t, prompts[0], "Shop <deals@shop.example>")
+		assert.Contains(t, prompts[0], "about 1.3 per week")
+		assert.Contains(t, prompts[0], "Sale deal_3 | Discounts on deal_3")
+	}
+
+	// The profile is cached until the sender's emails change
+	status, _ = generate("deals@shop.example/profile")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, prompts, 1)
+	status, _ = generate("deals@shop.example/profile?refresh=true")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, prompts, 2)
+	addEmail("deal_4", 4)
+	_, profile = generate("deals@shop.example/profile")
+	assert.Len(t, prompts, 3)
+	assert.Equal(t, 5, profile.EmailCount)
+
+	// Sender histories carry the cached profile
+	rec := serve(container, request(http.MethodGet, "/api/v1/senders/deals@shop.example/history", ""))
+	var history model.SenderHistory
+	json.Unmarshal(rec.Body.Bytes(), &history)
+	if assert.NotNil(t, history.Profile) {
+		assert.Equal(t, profile.Description, history.Profile.Description)
+	}
+
+	status, _ = generate("nobody@example.com/profile")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Len(t, prompts, 3)
+}
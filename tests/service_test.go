@@ -3,6 +3,8 @@ package tests
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -34,7 +36,7 @@ func TestEmailServiceSyncEmails(t *testing.T) {
 	categoryRepo.Create(context.Background(), category)
 
 	// Mock Gmail client to return a sample email
-	mockGmailClient.SyncEmailsFunc = func(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
 		email := model.NewEmail(user.ID, "msg_123", "sender@example.com", "Test Subject", "Test body content", time.Now())
 		return []*model.Email{email}, nil
 	}
@@ -43,7 +45,7 @@ func TestEmailServiceSyncEmails(t *testing.T) {
 	mockAIClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
 		return "Work", nil
 	}
-	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody string) (string, error) {
+	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
 		return "Summary of the email", nil
 	}
 
@@ -51,13 +53,13 @@ func TestEmailServiceSyncEmails(t *testing.T) {
 	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, mockAIClient, appLogger)
 
 	// Execute
-	err := emailService.SyncEmails(context.Background(), user.ID, 3, "")
+	_, err := emailService.SyncEmails(context.Background(), user.ID, service.SyncOptions{MaxResults: 3})
 
 	// Verify
 	assert.NoError(t, err)
 
 	// Check that the email was saved
-	emails, err := emailRepo.FindByUserID(context.Background(), user.ID)
+	emails, err := emailRepo.FindByUserID(context.Background(), user.ID, 0)
 	assert.NoError(t, err)
 	assert.Len(t, emails, 1)
 	assert.Equal(t, category.ID, emails[0].CategoryID)
@@ -87,7 +89,7 @@ func TestEmailServiceClassifyAndSummarizeEmail(t *testing.T) {
 	mockAIClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
 		return "Work", nil
 	}
-	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody string) (string, error) {
+	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
 		return "Summary of the email", nil
 	}
 
@@ -147,8 +149,9 @@ func TestEmailServicePerformBulkAction(t *testing.T) {
 	mockAIClient := ai.NewMockAIClient()
 	appLogger := logger.New()
 
-	// Create a sample user
+	// Create a sample user that granted modify access
 	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
 	userRepo.Create(context.Background(), user)
 
 	// Create sample emails
@@ -158,7 +161,7 @@ func TestEmailServicePerformBulkAction(t *testing.T) {
 	emailRepo.Create(context.Background(), email2)
 
 	// Mock Gmail client
-	mockGmailClient.ArchiveEmailFunc = func(ctx context.Context, userEmail, messageID string) error {
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
 		return nil
 	}
 
@@ -172,3 +175,268 @@ func TestEmailServicePerformBulkAction(t *testing.T) {
 	// Verify
 	assert.NoError(t, err)
 }
+
+func TestEmailServiceLocalArchiveLeavesGmailUntouched(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+	appLogger := logger.New()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(context.Background(), user)
+
+	email := model.NewEmail(user.ID, "msg_123", "sender@example.com", "Test Subject", "Test body content", time.Now())
+	emailRepo.Create(context.Background(), email)
+
+	gmailCalled := false
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		gmailCalled = true
+		return nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, mockGmailClient, ai.NewMockAIClient(), appLogger)
+
+	err := emailService.PerformBulkAction(context.Background(), []string{email.ID}, "local_archive", user.ID)
+	assert.NoError(t, err)
+	assert.False(t, gmailCalled)
+
+	stored, err := emailRepo.FindByID(context.Background(), email.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.LocallyArchived)
+	assert.False(t, stored.Archived)
+
+	// Hidden from the default listing until unarchived
+	emails, err := emailService.ListEmails(context.Background(), user.ID, model.EmailFilter{}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 0)
+
+	err = emailService.PerformBulkAction(context.Background(), []string{email.ID}, "local_unarchive", user.ID)
+	assert.NoError(t, err)
+
+	emails, err = emailService.ListEmails(context.Background(), user.ID, model.EmailFilter{}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 1)
+}
+
+func TestEmailServiceGmailActionsRequireModifyScope(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+	appLogger := logger.New()
+
+	// A read-only user, as created by the default login
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly
+	userRepo.Create(context.Background(), user)
+
+	email := model.NewEmail(user.ID, "msg_123", "sender@example.com", "Test Subject", "Test body content", time.Now())
+	emailRepo.Create(context.Background(), email)
+
+	gmailCalled := false
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		gmailCalled = true
+		return nil
+	}
+	mockGmailClient.DeleteFunc = func(ctx context.Context, userEmail string, messageIDs []string) error {
+		gmailCalled = true
+		return nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, mockGmailClient, ai.NewMockAIClient(), appLogger)
+
+	err := emailService.PerformBulkAction(context.Background(), []string{email.ID}, "archive", user.ID)
+	assert.ErrorIs(t, err, service.ErrGmailModifyScopeRequired)
+
+	err = emailService.DeleteEmails(context.Background(), []string{email.ID}, user.ID)
+	assert.ErrorIs(t, err, service.ErrGmailModifyScopeRequired)
+	assert.False(t, gmailCalled)
+
+	// Local-only actions still work without modify access
+	err = emailService.PerformBulkAction(context.Background(), []string{email.ID}, "local_archive", user.ID)
+	assert.NoError(t, err)
+}
+
+func TestEmailServiceReclassifyCategory(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockAIClient := ai.NewMockAIClient()
+	appLogger := logger.New()
+
+	workCategory := model.NewCategory("Work", "Work related emails")
+	otherCategory := model.NewCategory("Other", "Other emails")
+	categoryRepo.Create(context.Background(), workCategory)
+	categoryRepo.Create(context.Background(), otherCategory)
+
+	email := model.NewEmail("user_1", "msg_1", "sender@example.com", "Subject", "Body", time.Now())
+	email.CategoryID = otherCategory.ID
+	emailRepo.Create(context.Background(), email)
+
+	// Emails without a body can't be reclassified
+	pruned := model.NewEmail("user_1", "msg_2", "sender@example.com", "Subject", "", time.Now())
+	pruned.CategoryID = otherCategory.ID
+	pruned.BodyPruned = true
+	emailRepo.Create(context.Background(), pruned)
+
+	mockAIClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		return "Work", nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, gmail.NewMockGmailClient(), mockAIClient, appLogger)
+
+	updated, err := emailService.ReclassifyCategory(context.Background(), otherCategory.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	stored, _ := emailRepo.FindByID(context.Background(), email.ID)
+	assert.Equal(t, workCategory.ID, stored.CategoryID)
+
+	stored, _ = emailRepo.FindByID(context.Background(), pruned.ID)
+	assert.Equal(t, otherCategory.ID, stored.CategoryID)
+}
+
+func TestEmailServiceGetEmailDetail(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	emailService := service.NewEmailService(emailRepo, categoryRepo, memory.NewInMemoryUserRepository(), gmail.NewMockGmailClient(), ai.NewMockAIClient(), logger.New())
+
+	category := model.NewCategory("Newsletters", "Mailing lists")
+	assert.NoError(t, categoryRepo.Create(ctx, category))
+
+	now := time.Now()
+	first := model.NewEmail("user_1", "msg_1", "News <news@example.com>", "Issue 2", `<a href="https://example.com/unsubscribe?u=1">Unsubscribe</a>`, now)
+	second := model.NewEmail("user_1", "msg_2", "news@example.com", "Issue 1", "Plain body", now.Add(-time.Hour))
+	other := model.NewEmail("user_2", "msg_3", "news@example.com", "Not yours", "Body", now)
+	for _, email := range []*model.Email{first, second, other} {
+		email.CategoryID = category.ID
+		assert.NoError(t, emailRepo.Create(ctx, email))
+	}
+
+	detail, err := emailService.GetEmailDetail(ctx, "user_1", first.ID, model.EmailFilter{CategoryID: category.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, detail.Email.ID)
+	assert.Equal(t, "Newsletters", detail.Category.Name)
+	assert.Equal(t, 2, detail.SenderEmailCount)
+	assert.True(t, detail.CanUnsubscribe)
+	assert.Empty(t, detail.PreviousID)
+	assert.Equal(t, second.ID, detail.NextID)
+
+	detail, err = emailService.GetEmailDetail(ctx, "user_1", second.ID, model.EmailFilter{})
+	assert.NoError(t, err)
+	assert.False(t, detail.CanUnsubscribe)
+	assert.Equal(t, first.ID, detail.PreviousID)
+	assert.Empty(t, detail.NextID)
+
+	// Other users' emails are not found
+	_, err = emailService.GetEmailDetail(ctx, "user_1", other.ID, model.EmailFilter{})
+	assert.Error(t, err)
+}
+
+func TestEmailServiceUnreadAndStarActions(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	userRepo.Create(ctx, user)
+
+	email := model.NewEmail(user.ID, "msg_123", "sender@example.com", "Subject", "Body", time.Now())
+	emailRepo.Create(ctx, email)
+
+	var calls []string
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		calls = append(calls, fmt.Sprint(messageID, " +", add, " -", remove))
+		return nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, memory.NewInMemoryCategoryRepository(), userRepo, mockGmailClient, ai.NewMockAIClient(), logger.New())
+
+	assert.NoError(t, emailService.PerformBulkAction(ctx, []string{email.ID}, "unread", user.ID))
+	assert.NoError(t, emailService.PerformBulkAction(ctx, []string{email.ID}, "star", user.ID))
+	stored, _ := emailRepo.FindByID(ctx, email.ID)
+	assert.True(t, stored.Unread)
+	assert.True(t, stored.Starred)
+
+	assert.NoError(t, emailService.PerformBulkAction(ctx, []string{email.ID}, "unstar", user.ID))
+	stored, _ = emailRepo.FindByID(ctx, email.ID)
+	assert.False(t, stored.Starred)
+	assert.Equal(t, []string{"msg_123 +[UNREAD] -[]", "msg_123 +[STARRED] -[]", "msg_123 +[] -[STARRED]"}, calls)
+
+	// A failed Gmail call leaves the local state untouched
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		return errors.New("gmail unavailable")
+	}
+	assert.NoError(t, emailService.PerformBulkAction(ctx, []string{email.ID}, "star", user.ID))
+	stored, _ = emailRepo.FindByID(ctx, email.ID)
+	assert.False(t, stored.Starred)
+
+	// Like the other Gmail actions these need modify access
+	user.GrantedScopes = model.ScopeGmailReadonly
+	userRepo.Update(ctx, user)
+	assert.ErrorIs(t, emailService.PerformBulkAction(ctx, []string{email.ID}, "star", user.ID), service.ErrGmailModifyScopeRequired)
+}
+
+func TestEmailServiceReportSpamBlocksSender(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	userRepo.Create(ctx, user)
+
+	spam := model.NewEmail(user.ID, "msg_spam", "Prize Desk <Winner@Spam.example>", "You won", "Body", time.Now())
+	other := model.NewEmail(user.ID, "msg_other", "friend@example.com", "Hi", "Body", time.Now())
+	emailRepo.Create(ctx, spam)
+	emailRepo.Create(ctx, other)
+
+	var reported []string
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		if slices.Contains(add, model.LabelSpam) {
+			reported = append(reported, messageID)
+		}
+		return nil
+	}
+
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	categoryRepo.Create(ctx, model.NewCategory("Personal", "Personal emails"))
+
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, ai.NewMockAIClient(), logger.New())
+
+	// Reporting without blocking only moves the email out of the inbox
+	assert.NoError(t, emailService.ReportSpam(ctx, []string{other.ID}, user.ID, false))
+	stored, _ := userRepo.FindByID(ctx, user.ID)
+	assert.Empty(t, stored.BlockedSenders)
+
+	assert.NoError(t, emailService.ReportSpam(ctx, []string{spam.ID}, user.ID, true))
+	assert.Equal(t, []string{"msg_other", "msg_spam"}, reported)
+
+	stored, _ = userRepo.FindByID(ctx, user.ID)
+	assert.True(t, stored.IsSenderBlocked("winner@spam.example"))
+	reportedEmail, _ := emailRepo.FindByID(ctx, spam.ID)
+	assert.True(t, reportedEmail.Archived)
+	assert.NotNil(t, reportedEmail.DeletedAt)
+
+	// Later syncs skip the blocked sender
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return []*model.Email{
+			model.NewEmail(user.ID, "msg_spam_2", "winner@spam.example", "You won again", "Body", time.Now()),
+			model.NewEmail(user.ID, "msg_new", "friend@example.com", "Lunch?", "Body", time.Now()),
+		}, nil
+	}
+	_, err := emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+	_, err = emailRepo.FindByGmailID(ctx, user.ID, "msg_spam_2")
+	assert.Error(t, err)
+	_, err = emailRepo.FindByGmailID(ctx, user.ID, "msg_new")
+	assert.NoError(t, err)
+
+	// Spam reports change Gmail, so they need modify access
+	stored.GrantedScopes = model.ScopeGmailReadonly
+	userRepo.Update(ctx, stored)
+	assert.ErrorIs(t, emailService.ReportSpam(ctx, []string{other.ID}, user.ID, true), service.ErrGmailModifyScopeRequired)
+}
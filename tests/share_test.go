@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareServiceSignsAndRevokesLinks(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	shareRepo := memory.NewInMemoryEmailShareRepository()
+	shareService := service.NewShareService(shareRepo, emailRepo, memory.NewInMemoryCategoryRepository(), "secret", "https://mail.example.com/", logger.New())
+
+	email := model.NewEmail("alice", "msg_1", "Boss <boss@example.com>", "Quarterly report",
+		`<html><head><style>p{}</style></head><body><p>Numbers are <b>up</b></p><script>alert(1)</script></body></html>`, time.Now())
+	email.Summary = "Revenue grew this quarter"
+	emailRepo.Create(ctx, email)
+
+	// Only the owner can share
+	_, err := shareService.CreateShare(ctx, "bob", email.ID, 0, false)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	share, err := shareService.CreateShare(ctx, "alice", email.ID, 0, false)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(service.DefaultShareExpiry), share.ExpiresAt, time.Minute)
+	assert.True(t, strings.HasPrefix(share.URL, "https://mail.example.com/share/"+share.ID+"."))
+	token := strings.TrimPrefix(share.URL, "https://mail.example.com/share/")
+
+	shared, err := shareService.OpenShare(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "Quarterly report", shared.Subject)
+	assert.Equal(t, "Revenue grew this quarter", shared.Summary)
+	assert.Empty(t, shared.Body)
+
+	// A share ID without the right signature does not open
+	_, err = shareService.OpenShare(ctx, share.ID+".forged")
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	// Opting in shows the body as plain text
+	withBody, err := shareService.CreateShare(ctx, "alice", email.ID, time.Hour, true)
+	assert.NoError(t, err)
+	shared, err = shareService.OpenShare(ctx, strings.TrimPrefix(withBody.URL, "https://mail.example.com/share/"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Numbers are up", shared.Body)
+
+	// Revoked and expired links stop working
+	assert.True(t, errors.Is(shareService.RevokeShare(ctx, "bob", email.ID, share.ID), apierror.ErrNotFound))
+	assert.NoError(t, shareService.RevokeShare(ctx, "alice", email.ID, share.ID))
+	_, err = shareService.OpenShare(ctx, token)
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	withBody.ExpiresAt = time.Now().Add(-time.Minute)
+	shareRepo.Update(ctx, withBody)
+	_, err = shareService.OpenShare(ctx, strings.TrimPrefix(withBody.URL, "https://mail.example.com/share/"))
+	assert.True(t, errors.Is(err, apierror.ErrNotFound))
+
+	shares, err := shareService.GetShares(ctx, "alice", email.ID)
+	assert.NoError(t, err)
+	assert.Len(t, shares, 2)
+}
+
+func TestSharePageIsPublic(t *testing.T) {
+	container, user, request := newTestApp(t)
+
+	email := model.NewEmail(user.ID, "msg_1", "boss@example.com", "<Quarterly> report", "Secret body", time.Now())
+	email.Summary = "Revenue grew"
+	container.EmailRepo.Create(context.Background(), email)
+
+	rec := serve(container, request(http.MethodPost, "/api/v1/emails/"+email.ID+"/share", `{"expires_in_hours":24}`))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var share model.EmailShare
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &share))
+	shareURL, err := url.Parse(share.URL)
+	assert.NoError(t, err)
+
+	// The page opens without a session and escapes email content
+	rec = httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, shareURL.Path, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), "&lt;Quarterly&gt; report")
+	assert.Contains(t, rec.Body.String(), "Revenue grew")
+	assert.NotContains(t, rec.Body.String(), "Secret body")
+
+	rec = httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/share/bogus", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Link unavailable")
+}
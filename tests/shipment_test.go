@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTrackingClient struct {
+	statuses map[string]string
+	calls    []string
+}
+
+func (f *fakeTrackingClient) Track(ctx context.Context, carrier, trackingNumber string) (string, error) {
+	f.calls = append(f.calls, trackingNumber)
+	return f.statuses[trackingNumber], nil
+}
+
+func TestShipmentTracking(t *testing.T) {
+	ctx := context.Background()
+	aiClient := ai.NewMockAIClient()
+	aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+		return "summary", nil
+	}
+	aiClient.AnalyzeFunc = func(ctx context.Context, task, content string) (string, error) {
+		if !strings.Contains(task, "Find the package tracking number") {
+			return "", nil
+		}
+		if strings.Contains(content, "AB 123 456 789 CD") {
+			return "CARRIER: other\nTRACKING: AB 123 456 789 CD", nil
+		}
+		return "NONE", nil
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	var synced []*model.Email
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	tracking := &fakeTrackingClient{statuses: map[string]string{}}
+	container, user, _ := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient), app.WithAIClient(aiClient), app.WithTrackingClient(tracking)))
+
+	other := model.NewUser("google_456", "other@example.com", "Other User", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, other)
+
+	syncEmails := func(emails ...*model.Email) {
+		synced = emails
+		_, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+		assert.NoError(t, err)
+	}
+	list := func(userID string) map[string]*model.Shipment {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/shipments", nil)
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		rec := serve(container, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var shipments []*model.Shipment
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shipments))
+		byNumber := make(map[string]*model.Shipment)
+		for _, shipment := range shipments {
+			byNumber[shipment.TrackingNumber] = shipment
+		}
+		return byNumber
+	}
+	delivered := func(events chan []byte) []string {
+		var numbers []string
+		for len(events) > 0 {
+			var event struct {
+				Type string         `json:"type"`
+				Data model.Shipment `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(<-events, &event))
+			if event.Type == model.EventShipment {
+				numbers = append(numbers, event.Data.TrackingNumber)
+			}
+		}
+		return numbers
+	}
+
+	events := container.SSEManager.AddClient(user.ID)
+	syncEmails(
+		model.NewEmail("", "ups", "Shop <orders@shop.example>", "Your order has shipped", "<p>Track it with UPS: 1Z999AA10123456784</p>", time.Now()),
+		model.NewEmail("", "usps", "Store <orders@store.example>", "Shipping confirmation", "USPS tracking number: 9400100000000000000000", time.Now()),
+		model.NewEmail("", "fedex", "Outlet <orders@outlet.example>", "Your package is out for delivery", "FedEx tracking 123456789012", time.Now()),
+		model.NewEmail("", "other", "Market <orders@market.example>", "Your order is on its way", "Tracking: AB 123 456 789 CD", time.Now()),
+		model.NewEmail("", "no_number", "Shop <orders@shop.example>", "Your order 1234567890 has shipped", "Thanks for shopping", time.Now()),
+		model.NewEmail("", "not_shipping", "Bank <alerts@bank.example>", "Statement ready", "Account 123456789012 statement", time.Now()),
+	)
+
+	shipments := list(user.ID)
+	assert.Len(t, shipments, 4)
+	if ups := shipments["1Z999AA10123456784"]; assert.NotNil(t, ups) {
+		assert.Equal(t, model.CarrierUPS, ups.Carrier)
+		assert.Equal(t, model.ShipmentInTransit, ups.Status)
+		assert.Equal(t, "https://www.ups.com/track?tracknum=1Z999AA10123456784", ups.TrackingURL)
+	}
+	if usps := shipments["9400100000000000000000"]; assert.NotNil(t, usps) {
+		assert.Equal(t, model.CarrierUSPS, usps.Carrier)
+	}
+	if fedex := shipments["123456789012"]; assert.NotNil(t, fedex) {
+		assert.Equal(t, model.CarrierFedEx, fedex.Carrier)
+		assert.Equal(t, model.ShipmentOutForDelivery, fedex.Status)
+	}
+	// Numbers none of the formats match are read by the AI
+	if found := shipments["AB123456789CD"]; assert.NotNil(t, found) {
+		assert.Equal(t, "other", found.Carrier)
+		assert.Empty(t, found.TrackingURL)
+	}
+	assert.Empty(t, delivered(events))
+	assert.Empty(t, list(other.ID))
+
+	// A later notice moves the package forward and announces the delivery
+	notice := model.NewEmail("", "ups_delivered", "Shop <orders@shop.example>", "Delivered: your order", "Your package 1Z999AA10123456784 was delivered", time.Now())
+	syncEmails(notice)
+	ups := list(user.ID)["1Z999AA10123456784"]
+	assert.Equal(t, model.ShipmentDelivered, ups.Status)
+	assert.NotNil(t, ups.DeliveredAt)
+	stored, err := container.EmailRepo.FindByGmailID(ctx, user.ID, "ups_delivered")
+	assert.NoError(t, err)
+	assert.Equal(t, stored.ID, ups.EmailID)
+	assert.Equal(t, []string{"1Z999AA10123456784"}, delivered(events))
+
+	// Delivered packages aren't polled, the others are updated from the carrier
+	tracking.statuses["9400100000000000000000"] = model.ShipmentDelivered
+	tracking.statuses["123456789012"] = model.ShipmentOutForDelivery
+	container.TrackingJob.RunTracking()
+	assert.ElementsMatch(t, []string{"9400100000000000000000", "123456789012", "AB123456789CD"}, tracking.calls)
+	assert.Equal(t, []string{"9400100000000000000000"}, delivered(events))
+
+	shipments = list(user.ID)
+	assert.Equal(t, model.ShipmentDelivered, shipments["9400100000000000000000"].Status)
+	assert.NotNil(t, shipments["9400100000000000000000"].CheckedAt)
+	assert.Equal(t, model.ShipmentOutForDelivery, shipments["123456789012"].Status)
+	assert.Equal(t, model.ShipmentInTransit, shipments["AB123456789CD"].Status)
+}
@@ -3,13 +3,18 @@ package tests
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"jump-challenge/internal/config"
 	"jump-challenge/internal/gmail"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
 	"jump-challenge/internal/repository/memory"
 	"jump-challenge/internal/service"
 	"jump-challenge/internal/sse"
@@ -75,7 +80,7 @@ func TestEmailSyncJob(t *testing.T) {
 	assert.NoError(t, err)
 	
 	// Mock Gmail client to return a sample email
-	mockGmailClient.SyncEmailsFunc = func(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
 		email := model.NewEmail(user.ID, "msg_after_123", "sender@example.com", "Test Subject After", "Test body content", time.Now())
 		return []*model.Email{email}, nil
 	}
@@ -84,7 +89,7 @@ func TestEmailSyncJob(t *testing.T) {
 	mockAIClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
 		return "Work", nil
 	}
-	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody string) (string, error) {
+	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
 		return "Summary of the email", nil
 	}
 
@@ -99,7 +104,7 @@ func TestEmailSyncJob(t *testing.T) {
 	clientChannel := sseManager.AddClient(user.ID)
 	
 	// Create the email sync job
-	job := sse.NewEmailSyncJob(emailService, userRepo, sseManager, appLogger)
+	job := sse.NewEmailSyncJob(emailService, userRepo, sseManager, 0, 3, appLogger)
 	
 	// Test that it has the correct default interval
 	assert.Equal(t, 30*time.Second, job.GetInterval())
@@ -119,7 +124,7 @@ func TestEmailSyncJob(t *testing.T) {
 	}
 	
 	// Verify that email was saved to repository
-	emails, err := emailRepo.FindByUserID(context.Background(), user.ID)
+	emails, err := emailRepo.FindByUserID(context.Background(), user.ID, 0)
 	assert.NoError(t, err)
 	assert.Len(t, emails, 1)
 	assert.Equal(t, "Summary of the email", emails[0].Summary)
@@ -128,7 +133,7 @@ func TestEmailSyncJob(t *testing.T) {
 // MockAIClient is a mock implementation of AIClient for testing
 type MockAIClient struct {
 	ClassifyEmailFunc   func(ctx context.Context, emailBody string, categories []*model.Category) (string, error)
-	SummarizeEmailFunc  func(ctx context.Context, emailBody string) (string, error)
+	SummarizeEmailFunc  func(ctx context.Context, emailBody, language string) (string, error)
 }
 
 func (m *MockAIClient) ClassifyEmail(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
@@ -138,13 +143,17 @@ func (m *MockAIClient) ClassifyEmail(ctx context.Context, emailBody string, cate
 	return "", nil
 }
 
-func (m *MockAIClient) SummarizeEmail(ctx context.Context, emailBody string) (string, error) {
+func (m *MockAIClient) SummarizeEmail(ctx context.Context, emailBody, language string) (string, error) {
 	if m.SummarizeEmailFunc != nil {
-		return m.SummarizeEmailFunc(ctx, emailBody)
+		return m.SummarizeEmailFunc(ctx, emailBody, language)
 	}
 	return "", nil
 }
 
+func (m *MockAIClient) Analyze(ctx context.Context, task, content string) (string, error) {
+	return "", nil
+}
+
 func TestUserRepositoryFindAll(t *testing.T) {
 	userRepo := memory.NewInMemoryUserRepository()
 	
@@ -170,4 +179,201 @@ func TestUserRepositoryFindAll(t *testing.T) {
 	}
 	assert.True(t, userIDs[user1.ID])
 	assert.True(t, userIDs[user2.ID])
-}
\ No newline at end of file
+}
+func TestSSEManagerKeepsEventsForDisconnectedUsers(t *testing.T) {
+	sseManager := sse.NewSSEManager(logger.New())
+	defer sseManager.Close()
+	sseManager.UseOfflineQueue(memory.NewInMemoryPendingEventRepository(), 2, time.Hour)
+
+	userID := "test_user_123"
+	assert.False(t, sseManager.AwaitsReplay(context.Background(), userID))
+
+	// While connected events go straight to the connection
+	channel := sseManager.AddClient(userID)
+	assert.True(t, sseManager.AwaitsReplay(context.Background(), userID))
+	sseManager.BroadcastToUser(userID, model.EventOTP, "123456")
+	assert.Len(t, channel, 1)
+	sseManager.RemoveClient(userID, channel)
+
+	// A user who just left still gets what follows, the latest events when there are too many
+	assert.True(t, sseManager.AwaitsReplay(context.Background(), userID))
+	for _, code := range []string{"111111", "222222", "333333"} {
+		sseManager.BroadcastToUser(userID, model.EventOTP, code)
+	}
+
+	pending := sseManager.TakePending(userID)
+	if assert.Len(t, pending, 2) {
+		var event map[string]interface{}
+		assert.NoError(t, json.Unmarshal(pending[0], &event))
+		assert.Equal(t, model.EventOTP, event["type"])
+		assert.Equal(t, "222222", event["data"])
+		assert.NoError(t, json.Unmarshal(pending[1], &event))
+		assert.Equal(t, "333333", event["data"])
+	}
+	assert.Empty(t, sseManager.TakePending(userID))
+}
+
+// stalledPendingEvents holds every event being kept until release is closed
+type stalledPendingEvents struct {
+	repository.PendingEventRepository
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r *stalledPendingEvents) Enqueue(ctx context.Context, event *model.PendingEvent, limit int) error {
+	r.entered <- struct{}{}
+	<-r.release
+	return r.PendingEventRepository.Enqueue(ctx, event, limit)
+}
+
+func TestSSEManagerKeepsEventsWithoutBlockingOtherUsers(t *testing.T) {
+	sseManager := sse.NewSSEManager(logger.New())
+	defer sseManager.Close()
+	queue := &stalledPendingEvents{
+		PendingEventRepository: memory.NewInMemoryPendingEventRepository(),
+		entered:                make(chan struct{}, 2),
+		release:                make(chan struct{}),
+	}
+	sseManager.UseOfflineQueue(queue, 10, time.Hour)
+
+	// An event kept for an offline user is stuck in a slow store
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sseManager.BroadcastToUser("user_offline", model.EventOTP, "111111")
+	}()
+	<-queue.entered
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sseManager.BroadcastToUser("user_offline", model.EventOTP, "222222")
+	}()
+
+	// Meanwhile others connect, receive their events and leave
+	channel := sseManager.AddClient("user_online")
+	sseManager.BroadcastToUser("user_online", model.EventOTP, "333333")
+	assert.Len(t, channel, 1)
+	sseManager.RemoveClient("user_online", channel)
+
+	// The offline user's events are kept in the order they were broadcast
+	close(queue.release)
+	wg.Wait()
+	pending := sseManager.TakePending("user_offline")
+	if assert.Len(t, pending, 2) {
+		assert.Contains(t, string(pending[0]), "111111")
+		assert.Contains(t, string(pending[1]), "222222")
+	}
+}
+
+func TestSSEReplaysEventsKeptWhileOffline(t *testing.T) {
+	container, user, request := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.SSEQueueSize = 10
+	}))
+
+	email := model.NewEmail(user.ID, "msg_1", "news@shop.example", "Sale", "Body", time.Now())
+	container.SSEManager.BroadcastEmailToUser(user.ID, email)
+
+	// The stream ends when the request is cancelled, after it had time to send what was kept
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rec := serve(container, request(http.MethodGet, "/api/v1/sse", "").WithContext(ctx))
+
+	var types []string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		data, found := strings.CutPrefix(line, "data: ")
+		if !found {
+			continue
+		}
+		var event map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(data), &event))
+		types = append(types, event["type"].(string))
+	}
+	assert.Equal(t, []string{"connection", model.EventNewEmail}, types)
+	assert.Empty(t, container.SSEManager.TakePending(user.ID))
+}
+
+func TestSSEManagerDropsEventsForSlowClients(t *testing.T) {
+	sseManager := sse.NewSSEManager(logger.New())
+	defer sseManager.Close()
+
+	// Nobody reads the connection; broadcasting must not wait for it
+	userID := "test_user_123"
+	slow := sseManager.AddClient(userID)
+	start := time.Now()
+	for i := 0; i < 115; i++ {
+		sseManager.BroadcastToUser(userID, model.EventOTP, i)
+	}
+	assert.Less(t, time.Since(start), time.Second)
+
+	next := func() map[string]interface{} {
+		select {
+		case msg := <-slow:
+			var event map[string]interface{}
+			assert.NoError(t, json.Unmarshal(msg, &event))
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("event was not delivered")
+			return nil
+		}
+	}
+
+	// It gets what fits, is told how many it missed, then gets the latest in order
+	var values []int
+	missed := 0
+	for len(values) == 0 || values[len(values)-1] != 114 {
+		event := next()
+		if event["type"] == model.EventDropped {
+			assert.Zero(t, missed, "missed events are told once")
+			missed = int(event["data"].(map[string]interface{})["count"].(float64))
+			continue
+		}
+		values = append(values, int(event["data"].(float64)))
+	}
+	assert.Positive(t, missed)
+	assert.Equal(t, 115, len(values)+missed)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, values[:10])
+	assert.IsIncreasing(t, values)
+
+	stats := sseManager.Stats()
+	assert.Equal(t, 1, stats.Connections)
+	assert.Equal(t, int64(len(values)), stats.Sent)
+	assert.Equal(t, int64(missed), stats.Dropped)
+	assert.Equal(t, int64(1), stats.SlowClients)
+
+	// Caught up, events go straight through again
+	sseManager.BroadcastToUser(userID, model.EventOTP, 115)
+	assert.Equal(t, float64(115), next()["data"])
+}
+
+func TestSSEEventsAreVersionedEnvelopes(t *testing.T) {
+	// Every published event type is registered
+	for _, event := range []sse.Event{
+		sse.Connected{}, sse.NewEmail{}, sse.VIPEmail{}, sse.SyncProgress{}, sse.JobCompleted{},
+		sse.OTPReceived{}, sse.SecurityAlert{}, sse.ShipmentDelivered{},
+		sse.UnsubscribeIneffective(nil), sse.UnsubscribeManualAction(nil), sse.EventsDropped{},
+	} {
+		schema, ok := sse.LookupSchema(event.EventType())
+		assert.True(t, ok, event.EventType())
+		assert.Positive(t, schema.Version, event.EventType())
+		assert.NotEmpty(t, schema.Description, event.EventType())
+	}
+
+	// Payloads wrapping a model are encoded as the model itself
+	attempt := &model.UnsubscribeAttempt{ID: "attempt_1", ManualActionURL: "https://example.com/unsubscribe"}
+	encoded, err := sse.Encode(sse.UnsubscribeManualAction(attempt))
+	assert.NoError(t, err)
+
+	var envelope struct {
+		Type    string                 `json:"type"`
+		Version int                    `json:"version"`
+		Data    map[string]interface{} `json:"data"`
+		Time    int64                  `json:"time"`
+	}
+	assert.NoError(t, json.Unmarshal(encoded, &envelope))
+	assert.Equal(t, model.EventUnsubscribeManualAction, envelope.Type)
+	assert.Equal(t, 1, envelope.Version)
+	assert.Equal(t, "attempt_1", envelope.Data["id"])
+	assert.Equal(t, "https://example.com/unsubscribe", envelope.Data["manual_action_url"])
+	assert.InDelta(t, time.Now().Unix(), envelope.Time, 5)
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/static"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticAssetsAreFingerprinted(t *testing.T) {
+	assets, err := static.NewAssets(fstest.MapFS{
+		"app.js":       {Data: []byte("console.log('v1')")},
+		"img/logo.png": {Data: []byte("png")},
+	})
+	assert.NoError(t, err)
+
+	path := assets.Path("app.js")
+	assert.Regexp(t, `^/static/app\.[0-9a-f]{10}\.js$`, path)
+	assert.Regexp(t, `^/static/img/logo\.[0-9a-f]{10}\.png$`, assets.Path("img/logo.png"))
+	assert.Equal(t, "/static/missing.js", assets.Path("missing.js"))
+
+	// A changed file gets another name
+	changed, err := static.NewAssets(fstest.MapFS{"app.js": {Data: []byte("console.log('v2')")}})
+	assert.NoError(t, err)
+	assert.NotEqual(t, path, changed.Path("app.js"))
+}
+
+func TestStaticAssetCacheHeaders(t *testing.T) {
+	cfg := &config.Config{
+		Port:          "0",
+		BaseURL:       "http://localhost:8080",
+		SessionSecret: "test-secret",
+		SessionTTL:    time.Hour,
+	}
+	container, err := app.New(cfg, app.WithGmailClient(gmail.NewMockGmailClient()), app.WithAIClient(ai.NewMockAIClient()))
+	assert.NoError(t, err)
+	defer container.Stop(context.Background())
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		container.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Pages link the fingerprinted favicon, the service worker keeps its URL
+	rec := get("/app")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	favicon := regexp.MustCompile(`href="(/static/favicon\.[0-9a-f]{10}\.svg)"`).FindStringSubmatch(rec.Body.String())
+	if !assert.Len(t, favicon, 2) {
+		return
+	}
+	assert.NotContains(t, rec.Body.String(), `href="/static/favicon.svg"`)
+	assert.Contains(t, rec.Body.String(), `register('/static/sw.js')`)
+
+	rec = get(favicon[1])
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+
+	// Plain names are revalidated
+	rec = get("/static/sw.js")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, no-cache", rec.Header().Get("Cache-Control"))
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotModified, get("/static/sw.js", "If-None-Match", etag).Code)
+
+	assert.Equal(t, http.StatusNotFound, get("/static/favicon.0000000000.svg").Code)
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/loadtest"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runLoadTest(opts loadtest.Options) (*loadtest.Report, error) {
+	return loadtest.Run(context.Background(), memory.NewInMemoryUserRepository(), memory.NewInMemoryCategoryRepository(),
+		memory.NewInMemoryEmailRepository(), opts, logger.NewWithWriter(io.Discard))
+}
+
+func TestLoadTestRun(t *testing.T) {
+	report, err := runLoadTest(loadtest.Options{Emails: 120, BatchSize: 50})
+	assert.NoError(t, err)
+	assert.Equal(t, 120, report.Emails)
+	assert.Equal(t, 3, report.Syncs)
+	// Each sync creates its emails in one batch, archived in Gmail before
+	assert.Equal(t, int64(3), report.Writes)
+	assert.Greater(t, report.EmailsPerSec, 0.0)
+	assert.Greater(t, report.Allocs, uint64(0))
+
+	_, err = runLoadTest(loadtest.Options{})
+	assert.Error(t, err)
+}
+
+// BenchmarkSyncPipeline syncs b.N synthetic emails through classification, summarization and
+// storage; run with `make bench` and compare emails/s and allocs/op between changes
+func BenchmarkSyncPipeline(b *testing.B) {
+	for _, batch := range []int{10, 50, 200} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			b.ReportAllocs()
+			report, err := runLoadTest(loadtest.Options{Emails: b.N, BatchSize: batch})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(report.EmailsPerSec, "emails/s")
+			b.ReportMetric(report.WritesPerSec, "writes/s")
+		})
+	}
+}
+
+// BenchmarkSyncPipelineSlowAI shows how much the concurrent processing hides AI latency
+func BenchmarkSyncPipelineSlowAI(b *testing.B) {
+	b.ReportAllocs()
+	report, err := runLoadTest(loadtest.Options{Emails: b.N, BatchSize: 50, AILatency: time.Millisecond})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(report.EmailsPerSec, "emails/s")
+}
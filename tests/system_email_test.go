@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemFlagFromHeaders(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"failed recipients", map[string]string{"x-failed-recipients": "bob@example.com"}, model.SystemFlagBounce},
+		{"delivery status report", map[string]string{"content-type": `multipart/report; report-type=delivery-status; boundary="b"`}, model.SystemFlagBounce},
+		{"read receipt report", map[string]string{"content-type": "multipart/report; report-type=disposition-notification"}, ""},
+		{"auto-replied", map[string]string{"auto-submitted": "Auto-Replied"}, model.SystemFlagAutoReply},
+		{"auto-generated notification", map[string]string{"auto-submitted": "auto-generated"}, ""},
+		{"x-autoreply", map[string]string{"x-autoreply": "yes"}, model.SystemFlagAutoReply},
+		{"precedence", map[string]string{"precedence": "auto_reply"}, model.SystemFlagAutoReply},
+		{"bulk precedence", map[string]string{"precedence": "bulk"}, ""},
+		{"none", nil, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, model.SystemFlagFromHeaders(tc.headers))
+		})
+	}
+}
+
+func TestDetectSystemFlag(t *testing.T) {
+	cases := []struct {
+		from, subject, want string
+	}{
+		{"Mail Delivery Subsystem <MAILER-DAEMON@googlemail.com>", "Delivery Status Notification (Failure)", model.SystemFlagBounce},
+		{"postmaster@example.com", "Message not delivered", model.SystemFlagBounce},
+		{"it@example.com", "Undeliverable: Quarterly report", model.SystemFlagBounce},
+		{"jane@example.com", "Automatic reply: Quarterly report", model.SystemFlagAutoReply},
+		{"jane@example.com", "Out of Office until Monday", model.SystemFlagAutoReply},
+		{"jane@example.com", "Re: out of office plans", ""},
+		{"news@example.com", "Weekly digest", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.subject, func(t *testing.T) {
+			email := model.NewEmail("user", "gmail", tc.from, tc.subject, "body", time.Now())
+			assert.Equal(t, tc.want != "", email.DetectSystemFlag())
+			assert.Equal(t, tc.want, email.SystemFlag)
+		})
+	}
+
+	// Header detection wins over the content
+	email := model.NewEmail("user", "gmail", "jane@example.com", "Out of office", "body", time.Now())
+	email.SystemFlag = model.SystemFlagBounce
+	assert.True(t, email.DetectSystemFlag())
+	assert.Equal(t, model.SystemFlagBounce, email.SystemFlag)
+}
+
+func TestGmailClientFlagsSystemEmails(t *testing.T) {
+	message := `{"id":"bounce","threadId":"t_bounce","labelIds":["INBOX"],"internalDate":"1704207845000","payload":{
+		"mimeType":"text/plain","headers":[
+			{"name":"From","value":"Mail Delivery System <mailer@mx.example.com>"},
+			{"name":"Subject","value":"Your message could not be sent"},
+			{"name":"X-Failed-Recipients","value":"nobody@example.com"}],
+		"body":{"size":5,"data":"aGVsbG8"}}}`
+	client := newTestGmailClient(t, newFakeGmailServer(t, map[string]json.RawMessage{"bounce": json.RawMessage(message)}))
+
+	emails, err := fetchMessages(client, "bob@example.com", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, emails, 1) {
+		assert.Equal(t, model.SystemFlagBounce, emails[0].SystemFlag)
+	}
+}
+
+func TestSystemEmailSync(t *testing.T) {
+	ctx := context.Background()
+	var aiCalls []string
+	aiClient := ai.NewMockAIClient()
+	aiClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
+		aiCalls = append(aiCalls, emailBody)
+		return categories[0].Name, nil
+	}
+	aiClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
+		aiCalls = append(aiCalls, emailBody)
+		return "summary", nil
+	}
+	gmailClient := gmail.NewMockGmailClient()
+	var synced []*model.Email
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient), app.WithAIClient(aiClient)))
+
+	call := func(method, body string) (int, model.SystemEmailSettings) {
+		rec := serve(container, request(method, "/api/v1/settings/system-emails", body))
+		var settings model.SystemEmailSettings
+		json.Unmarshal(rec.Body.Bytes(), &settings)
+		return rec.Code, settings
+	}
+	syncEmails := func(emails ...*model.Email) {
+		synced = emails
+		_, err := container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+		assert.NoError(t, err)
+	}
+	stored := func(gmailID string) *model.Email {
+		email, err := container.EmailRepo.FindByGmailID(ctx, user.ID, gmailID)
+		assert.NoError(t, err)
+		return email
+	}
+
+	status, settings := call(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, settings.AutoArchive)
+
+	// Bounces and auto-replies are stored without AI processing
+	bounce := model.NewEmail("", "bounce_1", "MAILER-DAEMON@example.com", "Mail delivery failed", "<p>bounce</p>", time.Now())
+	reply := model.NewEmail("", "reply_1", "jane@example.com", "Automatic reply: Lunch", "<p>away</p>", time.Now())
+	flagged := model.NewEmail("", "flagged_1", "jane@example.com", "Lunch?", "<p>flagged</p>", time.Now())
+	flagged.SystemFlag = model.SystemFlagAutoReply
+	regular := model.NewEmail("", "regular_1", "jane@example.com", "Lunch", "<p>regular</p>", time.Now())
+	syncEmails(bounce, reply, flagged, regular)
+
+	assert.Equal(t, []string{"regular", "regular"}, aiCalls)
+	for gmailID, flag := range map[string]string{"bounce_1": model.SystemFlagBounce, "reply_1": model.SystemFlagAutoReply, "flagged_1": model.SystemFlagAutoReply} {
+		email := stored(gmailID)
+		assert.Equal(t, flag, email.SystemFlag, gmailID)
+		assert.Empty(t, email.CategoryID, gmailID)
+		assert.Empty(t, email.Summary, gmailID)
+		assert.False(t, email.LocallyArchived, gmailID)
+	}
+	assert.Empty(t, stored("regular_1").SystemFlag)
+	assert.NotEmpty(t, stored("regular_1").CategoryID)
+
+	// With auto-archive on, new system emails are put out of the way
+	status, settings = call(http.MethodPut, `{"auto_archive":true}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, settings.AutoArchive)
+	_, settings = call(http.MethodGet, "")
+	assert.True(t, settings.AutoArchive)
+
+	syncEmails(model.NewEmail("", "bounce_2", "postmaster@example.com", "Returned mail: see transcript", "<p>bounce</p>", time.Now()),
+		model.NewEmail("", "regular_2", "jane@example.com", "Dinner", "<p>regular</p>", time.Now()))
+	assert.True(t, stored("bounce_2").LocallyArchived)
+	assert.False(t, stored("regular_2").LocallyArchived)
+	assert.False(t, stored("bounce_1").LocallyArchived)
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/sse"
+	"jump-challenge/internal/telegram"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramBotLinksChatAndTriagesEmails(t *testing.T) {
+	ctx := context.Background()
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	mockGmailClient := gmail.NewMockGmailClient()
+	mockAIClient := ai.NewMockAIClient()
+	telegramClient := telegram.NewMockTelegramClient()
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	userRepo.Create(ctx, user)
+	work := model.NewCategory("Work", "Work emails")
+	categoryRepo.Create(ctx, work)
+
+	var archived []string
+	mockGmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		archived = append(archived, messageID)
+		return nil
+	}
+
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, mockAIClient, logger.New())
+	categoryService := service.NewCategoryService(categoryRepo, memory.NewInMemoryOrganizationRepository(), logger.New())
+	unsubscribeService := service.NewUnsubscribeService(emailRepo, userRepo, mockGmailClient, mockAIClient, logger.New())
+	notificationService := service.NewNotificationService(memory.NewInMemoryNotificationPreferencesRepository(), logger.New())
+	telegramService := service.NewTelegramService(memory.NewInMemoryTelegramLinkRepository(), telegramClient, "inbox_bot",
+		emailService, categoryService, unsubscribeService, notificationService, logger.New())
+
+	const chatID int64 = 4242
+
+	// Unknown chats are told how to link
+	telegramService.HandleMessage(ctx, &model.TelegramMessage{ChatID: chatID, Text: "/archive"})
+	assert.Contains(t, telegramClient.LastMessage(chatID), "not linked")
+
+	code, err := telegramService.CreateLinkCode(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://t.me/inbox_bot?start="+code.Code, code.URL)
+
+	telegramService.HandleMessage(ctx, &model.TelegramMessage{ChatID: chatID, Text: "/start " + code.Code})
+	assert.Contains(t, telegramClient.LastMessage(chatID), "linked")
+	assert.True(t, telegramService.IsLinked(ctx, user.ID))
+
+	// Codes only work once
+	telegramService.HandleMessage(ctx, &model.TelegramMessage{ChatID: 7, Text: "/start " + code.Code})
+	assert.Contains(t, telegramClient.LastMessage(7), "invalid or expired")
+
+	// Only important emails are sent to the chat
+	plain := model.NewEmail(user.ID, "msg_plain", "news@example.com", "Newsletter", "Body", time.Now())
+	emailRepo.Create(ctx, plain)
+	telegramService.NotifyNewEmail(ctx, plain)
+	assert.Len(t, telegramClient.Sent[chatID], 2)
+
+	first := model.NewEmail(user.ID, "msg_first", "Boss <boss@example.com>", "Quarterly report", "Body", time.Now())
+	first.Important = true
+	first.Summary = "The report is due Friday"
+	emailRepo.Create(ctx, first)
+	telegramService.NotifyNewEmail(ctx, first)
+	notification := telegramClient.LastMessage(chatID)
+	assert.Contains(t, notification, "Boss")
+	assert.Contains(t, notification, "The report is due Friday")
+
+	second := model.NewEmail(user.ID, "msg_second", "Client <client@example.com>", "Contract", "Body", time.Now())
+	second.Important = true
+	emailRepo.Create(ctx, second)
+	telegramService.NotifyNewEmail(ctx, second)
+
+	// Replying to a notification targets that email, otherwise the latest one
+	telegramService.HandleMessage(ctx, &model.TelegramMessage{ChatID: chatID, Text: "/categorize work", ReplyToText: notification})
+	assert.Equal(t, "Moved to Work.", telegramClient.LastMessage(chatID))
+	stored, _ := emailRepo.FindByID(ctx, first.ID)
+	assert.Equal(t, work.ID, stored.CategoryID)
+
+	telegramService.HandleMessage(ctx, &model.TelegramMessage{ChatID: chatID, Text: "/archive@inbox_bot"})
+	assert.Equal(t, "Archived.", telegramClient.LastMessage(chatID))
+	assert.Equal(t, []string{"msg_second"}, archived)
+
+	telegramService.HandleMessage(ctx, &model.TelegramMessage{ChatID: chatID, Text: "/categorize Nope"})
+	assert.True(t, strings.HasPrefix(telegramClient.LastMessage(chatID), "Failed:"))
+
+	telegramService.HandleMessage(ctx, &model.TelegramMessage{ChatID: chatID, Text: "/stop"})
+	assert.False(t, telegramService.IsLinked(ctx, user.ID))
+}
+
+func TestTelegramBotJobAdvancesOffset(t *testing.T) {
+	telegramClient := telegram.NewMockTelegramClient()
+	var offsets []int64
+	telegramClient.GetUpdatesFunc = func(ctx context.Context, offset int64, timeout time.Duration) ([]*model.TelegramMessage, error) {
+		offsets = append(offsets, offset)
+		return []*model.TelegramMessage{{UpdateID: 10, ChatID: 1, Text: "/help"}, {UpdateID: 11}}, nil
+	}
+
+	telegramService := service.NewTelegramService(memory.NewInMemoryTelegramLinkRepository(), telegramClient, "",
+		nil, nil, nil, nil, logger.New())
+	job := sse.NewTelegramBotJob(telegramClient, telegramService, logger.New())
+
+	assert.NoError(t, job.Poll())
+	assert.NoError(t, job.Poll())
+	assert.Equal(t, []int64{0, 12}, offsets)
+	// Updates without a chat message are skipped
+	assert.Len(t, telegramClient.Sent[1], 2)
+}
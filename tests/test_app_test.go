@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+)
+
+// testAppSetup is what newTestApp builds the container from and signs in
+type testAppSetup struct {
+	cfg          *config.Config
+	options      []app.Option
+	user         *model.User
+	configuredAI bool
+}
+
+// testAppOption customizes the app newTestApp builds
+type testAppOption func(*testAppSetup)
+
+// withConfig changes the config the container is built from
+func withConfig(configure func(cfg *config.Config)) testAppOption {
+	return func(setup *testAppSetup) {
+		configure(setup.cfg)
+	}
+}
+
+// withAppOptions adds container options; they come after the mock Gmail and AI clients, so they
+// can replace them
+func withAppOptions(options ...app.Option) testAppOption {
+	return func(setup *testAppSetup) {
+		setup.options = append(setup.options, options...)
+	}
+}
+
+// withConfiguredAI leaves out the mock AI client, so the container builds the one the config
+// describes
+func withConfiguredAI() testAppOption {
+	return func(setup *testAppSetup) {
+		setup.configuredAI = true
+	}
+}
+
+// withUser changes the signed-in user before it is stored
+func withUser(configure func(user *model.User)) testAppOption {
+	return func(setup *testAppSetup) {
+		configure(setup.user)
+	}
+}
+
+// withGmailModify grants the user the Gmail scopes actions on their mailbox need
+func withGmailModify() testAppOption {
+	return withUser(func(user *model.User) {
+		user.GrantedScopes = model.ScopeGmailReadonly + " " + model.ScopeGmailModify
+	})
+}
+
+// requestBuilder builds a request signed in as the test user; a body is sent as JSON
+type requestBuilder func(method, target, body string) *http.Request
+
+// newTestApp builds the application with mock Gmail and AI clients, in memory, and a user signed
+// in for an hour; the container is stopped when the test ends
+func newTestApp(t *testing.T, opts ...testAppOption) (*app.Container, *model.User, requestBuilder) {
+	setup := &testAppSetup{
+		cfg: &config.Config{
+			Port:          "0",
+			BaseURL:       "http://localhost:8080",
+			SessionSecret: "test-secret",
+			SessionTTL:    time.Hour,
+		},
+		user: model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{}),
+	}
+	for _, opt := range opts {
+		opt(setup)
+	}
+
+	options := []app.Option{app.WithGmailClient(gmail.NewMockGmailClient())}
+	if !setup.configuredAI {
+		options = append(options, app.WithAIClient(ai.NewMockAIClient()))
+	}
+	container, err := app.New(setup.cfg, append(options, setup.options...)...)
+	if err != nil {
+		t.Fatalf("failed to build the app: %v", err)
+	}
+	t.Cleanup(func() { container.Stop(context.Background()) })
+	if err := container.UserRepo.Create(context.Background(), setup.user); err != nil {
+		t.Fatalf("failed to create the test user: %v", err)
+	}
+
+	cookie := sessionCookie(t, setup.user.ID, time.Now().Add(time.Hour))
+	request := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.AddCookie(cookie)
+		return req
+	}
+	return container, setup.user, request
+}
+
+// serve runs the request through the app's routes
+func serve(container *app.Container, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	container.Echo.ServeHTTP(rec, req)
+	return rec
+}
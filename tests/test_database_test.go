@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// testDatabase is the postgres the repository tests share: TEST_DATABASE_URL when it is set,
+// otherwise a throwaway container started on first use
+var testDatabase struct {
+	once        sync.Once
+	url         string
+	unavailable string
+	err         error
+	pool        *dockertest.Pool
+	container   *dockertest.Resource
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testDatabase.container != nil {
+		testDatabase.pool.Purge(testDatabase.container)
+	}
+	os.Exit(code)
+}
+
+// lookupTestDatabase returns the URL of a postgres the test may wipe, or "" when Docker is not
+// available to start one
+func lookupTestDatabase(t *testing.T) string {
+	testDatabase.once.Do(startTestDatabase)
+	if testDatabase.err != nil {
+		t.Fatalf("failed to start the test database: %v", testDatabase.err)
+	}
+	return testDatabase.url
+}
+
+// testDatabaseURL is lookupTestDatabase for tests that only run against postgres, skipping them
+// when Docker is not available
+func testDatabaseURL(t *testing.T) string {
+	databaseURL := lookupTestDatabase(t)
+	if databaseURL == "" {
+		t.Skipf("no test database: %s", testDatabase.unavailable)
+	}
+	return databaseURL
+}
+
+func startTestDatabase() {
+	if databaseURL := os.Getenv("TEST_DATABASE_URL"); databaseURL != "" {
+		testDatabase.url = databaseURL
+		return
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err == nil {
+		err = pool.Client.Ping()
+	}
+	if err != nil {
+		testDatabase.unavailable = fmt.Sprintf("docker is not available: %v", err)
+		return
+	}
+	pool.MaxWait = time.Minute
+
+	container, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "16-alpine",
+		Env:        []string{"POSTGRES_PASSWORD=postgres"},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		testDatabase.err = fmt.Errorf("failed to run postgres: %w", err)
+		return
+	}
+	testDatabase.pool = pool
+	testDatabase.container = container
+	// Docker removes it even if the tests never get to purge it
+	container.Expire(600)
+
+	databaseURL := fmt.Sprintf("postgres://postgres:postgres@%s/postgres?sslmode=disable", container.GetHostPort("5432/tcp"))
+	err = pool.Retry(func() error {
+		db, err := sql.Open("postgres", databaseURL)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.Ping()
+	})
+	if err != nil {
+		testDatabase.err = fmt.Errorf("postgres did not come up: %w", err)
+		return
+	}
+	testDatabase.url = databaseURL
+}
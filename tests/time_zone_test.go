@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeZoneSettings(t *testing.T) {
+	ctx := context.Background()
+	container, user, request := newTestApp(t)
+
+	call := func(method string, body any) (int, handler.TimeZoneSettings) {
+		payload, _ := json.Marshal(body)
+		rec := serve(container, request(method, "/api/v1/settings/time-zone", string(payload)))
+		var settings handler.TimeZoneSettings
+		json.Unmarshal(rec.Body.Bytes(), &settings)
+		return rec.Code, settings
+	}
+
+	// The zone reported by the browser at login only fills in a missing one
+	assert.NoError(t, container.AuthService.DetectTimeZone(ctx, user.ID, "Not/AZone"))
+	assert.NoError(t, container.AuthService.DetectTimeZone(ctx, user.ID, "Europe/Lisbon"))
+	assert.NoError(t, container.AuthService.DetectTimeZone(ctx, user.ID, "Asia/Tokyo"))
+	status, settings := call(http.MethodGet, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Europe/Lisbon", settings.TimeZone)
+
+	// Settings override it, and only accept IANA zones
+	status, _ = call(http.MethodPut, map[string]string{"time_zone": "Mars/Olympus"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(http.MethodPut, map[string]string{"time_zone": "Local"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, settings = call(http.MethodPut, map[string]string{"time_zone": "America/Sao_Paulo"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "America/Sao_Paulo", settings.TimeZone)
+
+	stored, err := container.UserRepo.FindByID(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "America/Sao_Paulo", stored.TimeZone)
+}
+
+func TestWeeklyReportsFollowUserTimeZone(t *testing.T) {
+	ctx := context.Background()
+	container, utc, _ := newTestApp(t, withUser(func(user *model.User) {
+		user.Email = "utc@example.com"
+		user.Name = "UTC User"
+	}))
+	auckland := model.NewUser("google_456", "nz@example.com", "NZ User", "access_token", "refresh_token", time.Time{})
+	auckland.TimeZone = "Pacific/Auckland"
+	container.UserRepo.Create(ctx, auckland)
+
+	// Sunday noon in UTC is already 01:00 on Monday in Auckland (UTC+13), so the week before
+	// has ended there but not in UTC
+	now := time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC)
+	_, err := container.ReportService.GenerateWeeklyReports(ctx, now)
+	assert.NoError(t, err)
+
+	reports, err := container.ReportService.GetReports(ctx, utc.ID, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 1) {
+		assert.True(t, reports[0].PeriodStart.Equal(time.Date(2025, 2, 24, 0, 0, 0, 0, time.UTC)))
+	}
+
+	reports, err = container.ReportService.GetReports(ctx, auckland.ID, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, reports, 1) {
+		// Monday 3 March 00:00 in Auckland
+		assert.True(t, reports[0].PeriodStart.Equal(time.Date(2025, 3, 2, 11, 0, 0, 0, time.UTC)))
+		assert.True(t, reports[0].PeriodEnd.Equal(time.Date(2025, 3, 9, 11, 0, 0, 0, time.UTC)))
+	}
+}
+
+func TestCleanupScheduleFollowsLocalDays(t *testing.T) {
+	policy := model.NewCleanupPolicy("user_1", "Social", "category_1", nil, 14, "archive", model.CleanupDaily)
+	assert.True(t, policy.IsDue(time.Now()))
+
+	// 23:00 on Sunday in Auckland
+	lastRun := time.Date(2025, 3, 9, 10, 0, 0, 0, time.UTC)
+	policy.LastRunAt = &lastRun
+	now := lastRun.Add(2 * time.Hour)
+
+	assert.False(t, policy.IsDue(now))
+	assert.True(t, policy.IsDue(now.In(model.LoadLocation("Pacific/Auckland"))))
+
+	// A new local week has started in Auckland too
+	policy.Schedule = model.CleanupWeekly
+	assert.False(t, policy.IsDue(now))
+	assert.True(t, policy.IsDue(now.In(model.LoadLocation("Pacific/Auckland"))))
+}
+
+func TestQuietHoursDefaultToUserTimeZone(t *testing.T) {
+	ctx := context.Background()
+	userRepo := memory.NewInMemoryUserRepository()
+	notificationService := service.NewNotificationService(memory.NewInMemoryNotificationPreferencesRepository(), logger.New())
+	notificationService.UseUserTimeZones(userRepo)
+
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.TimeZone = "Asia/Kolkata"
+	userRepo.Create(ctx, user)
+
+	// Quiet for the two hours around now in Kolkata (UTC+5:30), which UTC is well outside of
+	local := time.Now().In(model.LoadLocation(user.TimeZone))
+	start := local.Add(-time.Hour).Format("15:04")
+	end := local.Add(time.Hour).Format("15:04")
+	_, err := notificationService.UpdatePreferences(ctx, user.ID, start, end, "", nil, "")
+	assert.NoError(t, err)
+	assert.False(t, notificationService.ShouldNotify(ctx, user.ID, model.EventNewEmail))
+
+	// A zone set on the preferences themselves still wins
+	_, err = notificationService.UpdatePreferences(ctx, user.ID, start, end, "UTC", nil, "")
+	assert.NoError(t, err)
+	assert.True(t, notificationService.ShouldNotify(ctx, user.ID, model.EventNewEmail))
+}
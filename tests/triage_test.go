@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriageAPI(t *testing.T) {
+	ctx := context.Background()
+	container, user, _ := newTestApp(t, withGmailModify())
+
+	other := model.NewUser("google_456", "other@example.com", "Other User", "access_token", "refresh_token", time.Time{})
+	container.UserRepo.Create(ctx, other)
+
+	receipts, err := container.CategoryService.CreateCategory(ctx, user.ID, "Receipts", "Orders and invoices")
+	assert.NoError(t, err)
+	_, err = container.AutomationService.CreateAutomation(ctx, user.ID, receipts.ID, "archive", "", 0)
+	assert.NoError(t, err)
+
+	base := time.Now().Add(-24 * time.Hour)
+	addEmail := func(gmailID, from string, age time.Duration, edit func(*model.Email)) *model.Email {
+		email := model.NewEmail(user.ID, gmailID, from, "Subject "+gmailID, "<p>body</p>", base.Add(-age))
+		email.Unread = true
+		if edit != nil {
+			edit(email)
+		}
+		assert.NoError(t, container.EmailRepo.Create(ctx, email))
+		return email
+	}
+	// The user already put two of the newsletter's emails away
+	for _, gmailID := range []string{"news_1", "news_2"} {
+		earlier := addEmail(gmailID, "News <news@example.com>", 10*time.Hour, nil)
+		assert.NoError(t, container.EmailRepo.MarkTriaged(ctx, earlier.ID, "local_archive", time.Now()))
+	}
+	addEmail("gone", "shop@example.com", 9*time.Hour, func(e *model.Email) { e.Archived = true })
+	receipt := addEmail("receipt", "shop@example.com", 4*time.Hour, func(e *model.Email) { e.CategoryID = receipts.ID })
+	newsletter := addEmail("news_3", "News <news@example.com>", 3*time.Hour, nil)
+	important := addEmail("boss", "boss@example.com", 2*time.Hour, func(e *model.Email) { e.Important = true })
+	plain := addEmail("friend", "friend@example.com", time.Hour, nil)
+
+	call := func(userID, method, path, body string) (int, model.TriageItem) {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(sessionCookie(t, userID, time.Now().Add(time.Hour)))
+		rec := serve(container, req)
+		var item model.TriageItem
+		json.Unmarshal(rec.Body.Bytes(), &item)
+		return rec.Code, item
+	}
+	assertNext := func(item model.TriageItem, email *model.Email, action, reason string, remaining int) {
+		t.Helper()
+		if assert.NotNil(t, item.Email) && assert.NotNil(t, item.Suggestion) {
+			assert.Equal(t, email.ID, item.Email.ID)
+			assert.Empty(t, item.Email.Body)
+			assert.Equal(t, model.TriageSuggestion{Action: action, Reason: reason}, *item.Suggestion)
+		}
+		assert.Equal(t, remaining, item.Remaining)
+	}
+
+	// Oldest first, skipping archived and already triaged emails
+	status, item := call(user.ID, http.MethodGet, "/triage/next", "")
+	assert.Equal(t, http.StatusOK, status)
+	assertNext(item, receipt, "archive", model.TriageReasonAutomation, 4)
+
+	status, item = call(user.ID, http.MethodPost, "/triage/"+receipt.ID+"/decision", `{"action":"archive"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assertNext(item, newsletter, "local_archive", model.TriageReasonSender, 3)
+
+	stored, err := container.EmailRepo.FindByID(ctx, receipt.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.Archived)
+	assert.Equal(t, "archive", stored.TriageAction)
+	assert.NotNil(t, stored.TriagedAt)
+
+	// A decision is made once
+	status, _ = call(user.ID, http.MethodPost, "/triage/"+receipt.ID+"/decision", `{"action":"keep"}`)
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = call(user.ID, http.MethodPost, "/triage/"+newsletter.ID+"/decision", `{"action":"unsubscribe"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(other.ID, http.MethodPost, "/triage/"+newsletter.ID+"/decision", `{"action":"keep"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Keeping an email only takes it off the queue
+	_, item = call(user.ID, http.MethodPost, "/triage/"+newsletter.ID+"/decision", `{"action":"keep"}`)
+	assertNext(item, important, "star", model.TriageReasonImportant, 2)
+	stored, err = container.EmailRepo.FindByID(ctx, newsletter.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.Unread)
+	assert.False(t, stored.LocallyArchived)
+
+	_, item = call(user.ID, http.MethodPost, "/triage/"+important.ID+"/decision", `{"action":"star"}`)
+	assertNext(item, plain, "archive", model.TriageReasonDefault, 1)
+	_, item = call(user.ID, http.MethodPost, "/triage/"+plain.ID+"/decision", `{"action":"delete"}`)
+	assert.Nil(t, item.Email)
+	assert.Equal(t, 0, item.Remaining)
+
+	// A later sync keeps the decisions
+	resynced := model.NewEmail(user.ID, "boss", "boss@example.com", "Subject boss", "<p>body</p>", base.Add(-2*time.Hour))
+	assert.NoError(t, container.EmailRepo.Create(ctx, resynced))
+	status, item = call(user.ID, http.MethodGet, "/triage/next", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, item.Email)
+}
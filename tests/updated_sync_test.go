@@ -2,21 +2,27 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 
 	"jump-challenge/internal/ai"
+	"jump-challenge/internal/app"
 	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
 	"jump-challenge/internal/logger"
 	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository"
 	"jump-challenge/internal/repository/memory"
 	"jump-challenge/internal/service"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestEmailServiceSyncEmailsWithNewEmails(t *testing.T) {
+func TestEmailServiceSyncEmailsResult(t *testing.T) {
 	// Setup
 	emailRepo := memory.NewInMemoryEmailRepository()
 	categoryRepo := memory.NewInMemoryCategoryRepository()
@@ -34,7 +40,7 @@ func TestEmailServiceSyncEmailsWithNewEmails(t *testing.T) {
 	categoryRepo.Create(context.Background(), category)
 
 	// Mock Gmail client to return sample emails
-	mockGmailClient.SyncEmailsFunc = func(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
 		email1 := model.NewEmail("", "msg_123", "sender@example.com", "Test Subject 1", "Test body content 1", time.Now())
 		email2 := model.NewEmail("", "msg_456", "sender@example.com", "Test Subject 2", "Test body content 2", time.Now())
 		email3 := model.NewEmail("", "msg_789", "sender@example.com", "Test Subject 3", "Test body content 3", time.Now())
@@ -45,7 +51,7 @@ func TestEmailServiceSyncEmailsWithNewEmails(t *testing.T) {
 	mockAIClient.ClassifyEmailFunc = func(ctx context.Context, emailBody string, categories []*model.Category) (string, error) {
 		return "Work", nil
 	}
-	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody string) (string, error) {
+	mockAIClient.SummarizeEmailFunc = func(ctx context.Context, emailBody, language string) (string, error) {
 		return "Summary of the email", nil
 	}
 
@@ -53,42 +59,45 @@ func TestEmailServiceSyncEmailsWithNewEmails(t *testing.T) {
 	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, mockAIClient, appLogger)
 
 	// Execute - first sync
-	fetchedEmails, newEmails, err := emailService.SyncEmailsWithNewEmails(context.Background(), user.ID, 3, "")
+	result, err := emailService.SyncEmails(context.Background(), user.ID, service.SyncOptions{MaxResults: 3})
 
 	// Verify
 	assert.NoError(t, err)
-	assert.Equal(t, 3, len(fetchedEmails)) // Should have fetched 3 emails
-	assert.Equal(t, 3, len(newEmails))     // Should have processed 3 new emails
+	assert.Equal(t, 3, len(result.Fetched)) // Should have fetched 3 emails
+	assert.Equal(t, 3, len(result.New))     // Should have processed 3 new emails
 
 	// Check that the emails were saved
-	emails, err := emailRepo.FindByUserID(context.Background(), user.ID)
+	emails, err := emailRepo.FindByUserID(context.Background(), user.ID, 0)
 	assert.NoError(t, err)
 	assert.Len(t, emails, 3)
 
 	// Execute - second sync with same emails (should process 0 new emails)
-	newFetchedEmails, newNewEmails, err := emailService.SyncEmailsWithNewEmails(context.Background(), user.ID, 3, "")
+	result, err = emailService.SyncEmails(context.Background(), user.ID, service.SyncOptions{MaxResults: 3})
 
 	// Verify - no new emails should be processed
 	assert.NoError(t, err)
-	assert.Equal(t, 3, len(newFetchedEmails)) // Should have fetched the same 3 emails
-	assert.Equal(t, 0, len(newNewEmails))     // Should have processed 0 new emails
+	assert.Equal(t, 3, len(result.Fetched)) // Should have fetched the same 3 emails
+	assert.Equal(t, 0, len(result.New))     // Should have processed 0 new emails
+	assert.Empty(t, result.Updated)         // Nothing changed in Gmail
+	assert.Equal(t, 3, result.Skipped)
 
 	// Execute - third sync with different emails
-	mockGmailClient.SyncEmailsFunc = func(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
 		email1 := model.NewEmail("", "msg_123", "sender@example.com", "Test Subject 1", "Test body content 1", time.Now()) // Same as before
 		email4 := model.NewEmail("", "msg_ABC", "sender@example.com", "Test Subject 4", "Test body content 4", time.Now()) // New
 		return []*model.Email{email1, email4}, nil
 	}
 
-	finalFetchedEmails, finalNewEmails, err := emailService.SyncEmailsWithNewEmails(context.Background(), user.ID, 3, "")
+	result, err = emailService.SyncEmails(context.Background(), user.ID, service.SyncOptions{MaxResults: 3})
 
 	// Verify - only 1 new email should be processed
 	assert.NoError(t, err)
-	assert.Equal(t, 2, len(finalFetchedEmails)) // Should have fetched 2 emails
-	assert.Equal(t, 1, len(finalNewEmails))     // Should have processed 1 new email (the other already existed)
+	assert.Equal(t, 2, len(result.Fetched)) // Should have fetched 2 emails
+	assert.Equal(t, 1, len(result.New))     // Should have processed 1 new email (the other already existed)
+	assert.Equal(t, 1, result.Skipped)
 }
 
-func TestEmailServiceSyncEmailsWithNewEmailsError(t *testing.T) {
+func TestEmailServiceSyncEmailsError(t *testing.T) {
 	// Setup
 	emailRepo := memory.NewInMemoryEmailRepository()
 	categoryRepo := memory.NewInMemoryCategoryRepository()
@@ -102,7 +111,7 @@ func TestEmailServiceSyncEmailsWithNewEmailsError(t *testing.T) {
 	userRepo.Create(context.Background(), user)
 
 	// Mock Gmail client to return a sample email
-	mockGmailClient.SyncEmailsFunc = func(ctx context.Context, userEmail string, maxResults int64, afterEmailID string) ([]*model.Email, error) {
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
 		email := model.NewEmail("", "msg_123", "sender@example.com", "Test Subject", "Test body content", time.Now())
 		return []*model.Email{email}, nil
 	}
@@ -116,9 +125,203 @@ func TestEmailServiceSyncEmailsWithNewEmailsError(t *testing.T) {
 	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, mockAIClient, appLogger)
 
 	// Execute
-	_, _, err := emailService.SyncEmailsWithNewEmails(context.Background(), user.ID, 3, "")
+	_, err := emailService.SyncEmails(context.Background(), user.ID, service.SyncOptions{MaxResults: 3})
 
 	// Verify
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "classification error")
 }
+
+func TestSyncStoresPreviewSnippet(t *testing.T) {
+	emailRepo := memory.NewInMemoryEmailRepository()
+	userRepo := memory.NewInMemoryUserRepository()
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	userRepo.Create(context.Background(), user)
+
+	mockGmailClient := gmail.NewMockGmailClient()
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		html := model.NewEmail("", "msg_html", "sender@example.com", "Newsletter",
+			`<html><head><style>p { color: red }</style></head><body><p>Big   news</p><p>this week</p><img src="x.png"></body></html>`, time.Now())
+		// Gmail's own snippet is kept
+		withSnippet := model.NewEmail("", "msg_snippet", "sender@example.com", "Hello", "<p>Body</p>", time.Now())
+		withSnippet.SetSnippet("From Gmail")
+		return []*model.Email{html, withSnippet}, nil
+	}
+
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	categoryRepo.Create(context.Background(), model.NewCategory("Work", "Work related emails"))
+
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, ai.NewMockAIClient(), logger.New())
+	emailService.SetMaxBodyBytes(10)
+	_, err := emailService.SyncEmails(context.Background(), user.ID, service.SyncOptions{MaxResults: 2})
+	assert.NoError(t, err)
+
+	emails, err := emailRepo.FindByUserID(context.Background(), user.ID, 0)
+	assert.NoError(t, err)
+	snippets := make(map[string]string)
+	for _, email := range emails {
+		snippets[email.GmailID] = email.WithoutBody().Snippet
+	}
+	// Made before the body is truncated to the storage limit
+	assert.Equal(t, map[string]string{"msg_html": "Big news this week", "msg_snippet": "From Gmail"}, snippets)
+}
+
+// countingEmailWrites counts the emails created and updated through the repository, the
+// batches they were created in, and the reads syncing looks stored emails up with
+type countingEmailWrites struct {
+	repository.EmailRepository
+	mutex    sync.Mutex
+	creates  int
+	batches  int
+	updates  int
+	lookups  int // FindByGmailIDs calls
+	listings int // FindByUserID calls
+}
+
+func (r *countingEmailWrites) Create(ctx context.Context, email *model.Email) error {
+	r.mutex.Lock()
+	r.creates++
+	r.mutex.Unlock()
+	return r.EmailRepository.Create(ctx, email)
+}
+
+func (r *countingEmailWrites) CreateBatch(ctx context.Context, emails []*model.Email) error {
+	r.mutex.Lock()
+	r.creates += len(emails)
+	r.batches++
+	r.mutex.Unlock()
+	return r.EmailRepository.CreateBatch(ctx, emails)
+}
+
+func (r *countingEmailWrites) Update(ctx context.Context, email *model.Email) error {
+	r.mutex.Lock()
+	r.updates++
+	r.mutex.Unlock()
+	return r.EmailRepository.Update(ctx, email)
+}
+
+func (r *countingEmailWrites) FindByGmailIDs(ctx context.Context, userID string, gmailIDs []string) ([]*model.Email, error) {
+	r.mutex.Lock()
+	r.lookups++
+	r.mutex.Unlock()
+	return r.EmailRepository.FindByGmailIDs(ctx, userID, gmailIDs)
+}
+
+func (r *countingEmailWrites) FindByUserID(ctx context.Context, userID string, limit int) ([]*model.Email, error) {
+	r.mutex.Lock()
+	r.listings++
+	r.mutex.Unlock()
+	return r.EmailRepository.FindByUserID(ctx, userID, limit)
+}
+
+func TestSyncSavesEachEmailOnce(t *testing.T) {
+	ctx := context.Background()
+	emailRepo := &countingEmailWrites{EmailRepository: memory.NewInMemoryEmailRepository()}
+	userRepo := memory.NewInMemoryUserRepository()
+	user := model.NewUser("google_123", "test@example.com", "Test User", "access_token", "refresh_token", time.Time{})
+	user.GrantedScopes = model.ScopeGmailModify
+	userRepo.Create(ctx, user)
+	categoryRepo := memory.NewInMemoryCategoryRepository()
+	work := model.NewCategory("Work", "Work related emails")
+	personal := model.NewCategory("Personal", "Friends and family")
+	categoryRepo.Create(ctx, work)
+	categoryRepo.Create(ctx, personal)
+
+	unread := false
+	mockGmailClient := gmail.NewMockGmailClient()
+	mockGmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		first := model.NewEmail("", "msg_1", "sender@example.com", "First", "First body", time.Now())
+		first.Unread = unread
+		second := model.NewEmail("", "msg_2", "sender@example.com", "Second", "Second body", time.Now())
+		return []*model.Email{first, second}, nil
+	}
+	emailService := service.NewEmailService(emailRepo, categoryRepo, userRepo, mockGmailClient, ai.NewMockAIClient(), logger.New())
+
+	// New emails are archived in Gmail before their only save, together
+	result, err := emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 2})
+	assert.NoError(t, err)
+	assert.Len(t, result.New, 2)
+	assert.Equal(t, 2, emailRepo.creates)
+	assert.Equal(t, 1, emailRepo.batches)
+	assert.Zero(t, emailRepo.updates)
+	first, err := emailRepo.FindByGmailID(ctx, user.ID, "msg_1")
+	assert.NoError(t, err)
+	assert.True(t, first.Archived)
+	assert.Equal(t, work.ID, first.CategoryID)
+
+	// Syncing again saves only what Gmail changed, keeping the user's classification
+	_, err = emailService.CategorizeEmail(ctx, user.ID, first.ID, personal.ID)
+	assert.NoError(t, err)
+	emailRepo.creates, emailRepo.updates = 0, 0
+	unread = true
+	result, err = emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 2})
+	assert.NoError(t, err)
+	assert.Empty(t, result.New)
+	if assert.Len(t, result.Updated, 1) {
+		assert.Equal(t, "msg_1", result.Updated[0].GmailID)
+	}
+	assert.Equal(t, 1, result.Skipped)
+	assert.Zero(t, emailRepo.creates)
+	assert.Equal(t, 1, emailRepo.updates)
+	first, err = emailRepo.FindByGmailID(ctx, user.ID, "msg_1")
+	assert.NoError(t, err)
+	assert.True(t, first.Unread)
+	assert.Equal(t, personal.ID, first.CategoryID)
+
+	_, err = emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, emailRepo.updates)
+
+	// Trashed emails aren't imported again
+	second, err := emailRepo.FindByGmailID(ctx, user.ID, "msg_2")
+	assert.NoError(t, err)
+	assert.NoError(t, emailRepo.Delete(ctx, second.ID))
+	result, err = emailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 2})
+	assert.NoError(t, err)
+	assert.Empty(t, result.New)
+	assert.Equal(t, 2, result.Skipped)
+	assert.Zero(t, emailRepo.creates)
+	second, err = emailRepo.FindByGmailID(ctx, user.ID, "msg_2")
+	assert.NoError(t, err)
+	assert.NotNil(t, second.DeletedAt)
+
+	// Each sync looked the fetched emails up at once, without loading the mailbox
+	assert.Equal(t, 4, emailRepo.lookups)
+	assert.Zero(t, emailRepo.listings)
+}
+
+func TestSyncEndpointReportsResult(t *testing.T) {
+	ctx := context.Background()
+	gmailClient := gmail.NewMockGmailClient()
+	var requested int64
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		requested = maxResults
+		return []*model.Email{
+			model.NewEmail("", "msg_1", "sender@example.com", "First", "First body", time.Now()),
+			model.NewEmail("", "msg_2", "sender@example.com", "Second", "Second body", time.Now()),
+		}, nil
+	}
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient)))
+	_, err := container.CategoryService.CreateCategory(ctx, user.ID, "Work", "Colleagues")
+	assert.NoError(t, err)
+
+	syncNow := func() handler.SyncEmailsResponse {
+		rec := serve(container, request(http.MethodPost, "/api/v1/emails/sync?max_results=2", ""))
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var response handler.SyncEmailsResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response
+	}
+
+	response := syncNow()
+	assert.Equal(t, int64(2), requested)
+	assert.Equal(t, 2, response.Fetched)
+	assert.Equal(t, 2, response.New)
+	assert.Zero(t, response.Skipped)
+
+	response = syncNow()
+	assert.Equal(t, 2, response.Fetched)
+	assert.Zero(t, response.New)
+	assert.Zero(t, response.Updated)
+	assert.Equal(t, 2, response.Skipped)
+}
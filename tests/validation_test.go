@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/validation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationReportsFieldErrors(t *testing.T) {
+	req := handler.BulkActionRequest{
+		EmailIDs: []string{"email_1", ""},
+		Filter:   &model.EmailFilter{Archive: "bogus"},
+	}
+
+	fields, err := validation.Struct(&req)
+	assert.NoError(t, err)
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "email_ids[1]", Message: "is required"},
+		{Field: "filter.archive", Message: "must be one of: only, all"},
+		{Field: "action", Message: "is required"},
+	}, fields)
+
+	req = handler.BulkActionRequest{EmailIDs: []string{"email_1"}, Action: "archive"}
+	fields, err = validation.Struct(&req)
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	retention := handler.RetentionRequest{RetentionDays: -1}
+	fields, err = validation.Struct(retention)
+	assert.NoError(t, err)
+	assert.Equal(t, []apierror.FieldError{{Field: "retention_days", Message: "must be at least 0"}}, fields)
+}
+
+// mistypedRequest has a rule the validator doesn't know, and one it can't apply
+type mistypedRequest struct {
+	Name    string `json:"name" validate:"required,maxlen=10"`
+	Enabled bool   `json:"enabled" validate:"min=1"`
+	Nested  struct {
+		Tags string `json:"tags" validate:"dive,required"`
+	} `json:"nested"`
+}
+
+func TestValidationRejectsInvalidTags(t *testing.T) {
+	// Every invalid tag of the type and the ones it nests is reported at once
+	err := validation.Check(handler.BulkActionRequest{}, &mistypedRequest{}, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `mistypedRequest.Name: unknown rule "maxlen"`)
+		assert.Contains(t, err.Error(), "mistypedRequest.Enabled: min on a bool")
+		assert.Contains(t, err.Error(), ".Tags: dive on a string")
+	}
+
+	// Validating such a type fails instead of panicking
+	assert.NotPanics(t, func() {
+		_, err = validation.Struct(&mistypedRequest{Name: "ada"})
+	})
+	assert.Error(t, err)
+	assert.Error(t, validation.New().Validate(&mistypedRequest{Name: "ada"}))
+}
+
+func TestHandlersRejectInvalidRequests(t *testing.T) {
+	container, _, request := newTestApp(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		field  string
+	}{
+		{http.MethodPost, "/api/v1/emails/sync?max_results=-1", "", "max_results"},
+		{http.MethodGet, "/api/v1/emails?archived=maybe", "", "archived"},
+		{http.MethodPost, "/api/v1/categories", `{"description":"no name"}`, "name"},
+		{http.MethodPost, "/api/v1/emails/bulk-action", `{"email_ids":["a"],"action":"explode"}`, "action"},
+		{http.MethodPost, "/api/v1/emails/bulk-action", `{"email_ids":["a"],"action":"move"}`, "label"},
+		{http.MethodPost, "/api/v1/emails/classify", `{"subject":"hi"}`, "body"},
+		{http.MethodPut, "/api/v1/retention", `{"body_retention_days":-5}`, "body_retention_days"},
+		{http.MethodPost, "/api/v1/automations", `{"category_id":"c","action":"explode"}`, "action"},
+		{http.MethodPost, "/api/v1/automations", `{"category_id":"missing","action":"archive"}`, "category_id"},
+		{http.MethodPut, "/api/v1/settings/notifications", `{"event_types":["pager"]}`, "event_types[0]"},
+		{http.MethodPut, "/api/v1/settings/notifications", `{"quiet_hours_start":"9pm","quiet_hours_end":"07:00"}`, "quiet_hours_start"},
+		{http.MethodPost, "/api/v1/push/subscriptions", `{"endpoint":"https://push.example.com/abc","keys":{"auth":"secret"}}`, "keys.p256dh"},
+		{http.MethodDelete, "/api/v1/push/subscriptions", `{}`, "endpoint"},
+		{http.MethodPost, "/api/v1/emails/some-id/share", `{"expires_in_hours":1000}`, "expires_in_hours"},
+		{http.MethodPost, "/api/v1/organization", `{}`, "name"},
+		{http.MethodPost, "/api/v1/organization/invitations", `{"email":"bob@example.com","role":"owner"}`, "role"},
+	}
+
+	for _, tt := range tests {
+		rec := serve(container, request(tt.method, tt.path, tt.body))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, tt.path)
+
+		var body handler.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), tt.path)
+		assert.Equal(t, apierror.CodeValidation, body.Code, tt.path)
+		if assert.Len(t, body.Fields, 1, tt.path) {
+			assert.Equal(t, tt.field, body.Fields[0].Field, tt.path)
+		}
+	}
+
+	// Malformed JSON is rejected before validation
+	rec := serve(container, request(http.MethodPost, "/api/v1/categories", `{"name":`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/push"
+	"jump-challenge/internal/service"
+	"jump-challenge/internal/telegram"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVIPSenders(t *testing.T) {
+	ctx := context.Background()
+	gmailClient := gmail.NewMockGmailClient()
+	var synced []*model.Email
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	var mutex sync.Mutex
+	var archived []string
+	gmailClient.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		archived = append(archived, messageID)
+		return nil
+	}
+	var pushed []*model.PushNotification
+	pushClient := push.NewMockPushClient()
+	pushClient.SendFunc = func(ctx context.Context, subscription *model.PushSubscription, notification *model.PushNotification) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		pushed = append(pushed, notification)
+		return nil
+	}
+	telegramClient := telegram.NewMockTelegramClient()
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient), app.WithPushClient(pushClient), app.WithTelegramClient(telegramClient)), withGmailModify())
+
+	_, err := container.PushService.Subscribe(ctx, user.ID, "https://push.example.com/vip", "key", "auth")
+	assert.NoError(t, err)
+	assert.NoError(t, container.TelegramRepo.Save(ctx, model.NewTelegramLink(user.ID, 42)))
+
+	// Every new email lands in the first category, which an automation archives locally
+	categories, err := container.CategoryService.GetAllCategories(ctx, user.ID)
+	assert.NoError(t, err)
+	_, err = container.AutomationService.CreateAutomation(ctx, user.ID, categories[0].ID, "local_archive", "", 0)
+	assert.NoError(t, err)
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		return serve(container, request(method, "/api/v1"+path, body))
+	}
+	stored := func(gmailID string) *model.Email {
+		email, err := container.EmailRepo.FindByGmailID(ctx, user.ID, gmailID)
+		assert.NoError(t, err)
+		return email
+	}
+	vipEmails := func() []string {
+		rec := call(http.MethodGet, "/emails/vip", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var emails []*model.Email
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &emails))
+		var gmailIDs []string
+		for _, email := range emails {
+			gmailIDs = append(gmailIDs, email.GmailID)
+		}
+		return gmailIDs
+	}
+
+	// Emails stored before the sender is marked become urgent too
+	synced = []*model.Email{model.NewEmail("", "before", "Boss <Boss@Company.example>", "Quarterly plan", "<p>plan</p>", time.Now().Add(-time.Hour))}
+	_, err = container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, stored("before").Priority)
+
+	rec := call(http.MethodPost, "/vip-senders", `{"address":" BOSS@company.example ","name":"Boss"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var vip model.VIPSender
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vip))
+	assert.Equal(t, "boss@company.example", vip.Address)
+	assert.Equal(t, model.EmailPriorityUrgent, stored("before").Priority)
+	assert.Equal(t, []string{"before"}, vipEmails())
+
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/vip-senders", `{"address":"boss"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/vip-senders", `{"name":"Nobody"}`).Code)
+
+	rec = call(http.MethodGet, "/vip-senders", "")
+	var vips []model.VIPSender
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vips))
+	if assert.Len(t, vips, 1) {
+		assert.Equal(t, "Boss", vips[0].Name)
+	}
+
+	// New VIP emails skip archiving and automations and are notified right away
+	archived, pushed = nil, nil
+	events := container.SSEManager.AddClient(user.ID)
+	synced = []*model.Email{
+		model.NewEmail("", "vip_new", "Boss <boss@company.example>", "Call me", "<p>urgent</p>", time.Now()),
+		model.NewEmail("", "regular", "News <news@shop.example>", "Sale", "<p>sale</p>", time.Now()),
+	}
+	container.EmailSyncJob.RunSync()
+
+	var vipEvents []string
+	for len(events) > 0 {
+		var event struct {
+			Type string      `json:"type"`
+			Data model.Email `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(<-events, &event))
+		if event.Type == model.EventVIPEmail {
+			vipEvents = append(vipEvents, event.Data.GmailID)
+		}
+	}
+	assert.Equal(t, []string{"vip_new"}, vipEvents)
+
+	assert.Equal(t, []string{"regular"}, archived)
+	assert.False(t, stored("vip_new").LocallyArchived)
+	assert.True(t, stored("regular").LocallyArchived)
+	assert.Equal(t, model.EmailPriorityUrgent, stored("vip_new").Priority)
+	assert.Empty(t, stored("regular").Priority)
+
+	if assert.Len(t, pushed, 1) {
+		assert.Equal(t, model.EventVIPEmail, pushed[0].Type)
+		assert.Equal(t, "Call me", pushed[0].Body)
+	}
+	assert.Contains(t, telegramClient.LastMessage(42), "VIP email from Boss")
+	assert.Equal(t, []string{"vip_new", "before"}, vipEmails())
+
+	// Removing the sender takes their emails out of the VIP listing
+	rec = call(http.MethodDelete, "/vip-senders/"+url.PathEscape("boss@company.example"), "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, vipEmails())
+	assert.Empty(t, stored("vip_new").Priority)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodDelete, "/vip-senders/boss@company.example", "").Code)
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/repository/memory"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookDelivery is a request a test webhook received
+type webhookDelivery struct {
+	signature string
+	body      []byte
+}
+
+// webhookReceiver records the requests posted to a TLS test server
+func webhookReceiver(t *testing.T) (*httptest.Server, func() []webhookDelivery) {
+	var mutex sync.Mutex
+	var deliveries []webhookDelivery
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		deliveries = append(deliveries, webhookDelivery{signature: r.Header.Get(service.WebhookSignatureHeader), body: body})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []webhookDelivery {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]webhookDelivery(nil), deliveries...)
+	}
+}
+
+func TestWebhookPostsSignedVIPEmails(t *testing.T) {
+	ctx := context.Background()
+	server, deliveries := webhookReceiver(t)
+	gmailClient := gmail.NewMockGmailClient()
+	var synced []*model.Email
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		return synced, nil
+	}
+	// The test server listens on loopback, which the default client refuses
+	container, user, request := newTestApp(t, withAppOptions(app.WithGmailClient(gmailClient), app.WithFetchClient(server.Client())))
+	_, err := container.EmailService.AddVIPSender(ctx, user.ID, "boss@company.example", "Boss")
+	assert.NoError(t, err)
+
+	call := func(method, body string) *httptest.ResponseRecorder {
+		return serve(container, request(method, "/api/v1/webhook", body))
+	}
+
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "").Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, `{"url":"http://hooks.example.com/vip"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, `{}`).Code)
+
+	rec := call(http.MethodPut, `{"url":"`+server.URL+`/vip"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var webhook model.Webhook
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &webhook))
+	assert.Equal(t, server.URL+"/vip", webhook.URL)
+	assert.Len(t, webhook.Secret, 64)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "").Code)
+
+	// Only the VIP email is posted, as the vip_email event without its body
+	synced = []*model.Email{
+		model.NewEmail("", "vip_new", "Boss <boss@company.example>", "Call me", "<p>urgent</p>", time.Now()),
+		model.NewEmail("", "regular", "News <news@shop.example>", "Sale", "<p>sale</p>", time.Now()),
+	}
+	_, err = container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+
+	received := deliveries()
+	if assert.Len(t, received, 1) {
+		var event struct {
+			Type    string      `json:"type"`
+			Version int         `json:"version"`
+			Data    model.Email `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(received[0].body, &event))
+		assert.Equal(t, model.EventVIPEmail, event.Type)
+		assert.Equal(t, 1, event.Version)
+		assert.Equal(t, "vip_new", event.Data.GmailID)
+		assert.Empty(t, event.Data.Body)
+
+		// The signature is an HMAC of the timestamp and the body, keyed with the secret
+		timestamp, signature, _ := strings.Cut(strings.TrimPrefix(received[0].signature, "t="), ",v1=")
+		mac := hmac.New(sha256.New, []byte(webhook.Secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(received[0].body)
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), signature)
+	}
+
+	// Nothing is posted once the webhook is removed
+	assert.Equal(t, http.StatusNoContent, call(http.MethodDelete, "").Code)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "").Code)
+	synced = []*model.Email{model.NewEmail("", "vip_later", "Boss <boss@company.example>", "Again", "<p>urgent</p>", time.Now())}
+	_, err = container.EmailService.SyncEmails(ctx, user.ID, service.SyncOptions{MaxResults: 10})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries(), 1)
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	server, deliveries := webhookReceiver(t)
+	notifications := service.NewNotificationService(memory.NewInMemoryNotificationPreferencesRepository(), logger.New())
+	webhooks := service.NewWebhookService(memory.NewInMemoryWebhookRepository(), nil, notifications, logger.New())
+
+	_, err := webhooks.SetWebhook(ctx, "user_1", server.URL+"/vip")
+	assert.NoError(t, err)
+	email := model.NewEmail("user_1", "vip_new", "boss@company.example", "Call me", "<p>urgent</p>", time.Now())
+	email.Priority = model.EmailPriorityUrgent
+	webhooks.NotifyNewEmail(ctx, email)
+
+	assert.Empty(t, deliveries())
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"jump-challenge/internal/apierror"
+	"jump-challenge/internal/app"
+	"jump-challenge/internal/config"
+	"jump-challenge/internal/gmail"
+	"jump-challenge/internal/handler"
+	"jump-challenge/internal/logger"
+	"jump-challenge/internal/model"
+	"jump-challenge/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serviceAccountKey returns the JSON key of a service account whose tokens come from tokenURL
+func serviceAccountKey(t *testing.T, tokenURL string) []byte {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	assert.NoError(t, err)
+
+	key, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_id":      "1234567890",
+		"client_email":   "sync@jump-challenge.iam.gserviceaccount.com",
+		"private_key_id": "key_1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURL,
+	})
+	assert.NoError(t, err)
+	return key
+}
+
+func TestDelegatedClientImpersonatesDomainUsers(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeGmailServer(t, map[string]json.RawMessage{})
+
+	// Google delegates access to Ada's mailbox, refuses Bob's and fails for Carol's
+	var mutex sync.Mutex
+	var subjects []string
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		parts := strings.Split(r.PostFormValue("assertion"), ".")
+		var claims struct {
+			Subject string `json:"sub"`
+			Scope   string `json:"scope"`
+		}
+		if len(parts) == 3 {
+			payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			json.Unmarshal(payload, &claims)
+		}
+		mutex.Lock()
+		subjects = append(subjects, claims.Subject)
+		mutex.Unlock()
+
+		switch claims.Subject {
+		case "ada@example.com":
+			assert.Equal(t, model.ScopeGmailReadonly+" "+model.ScopeGmailModify, claims.Scope)
+			w.Write([]byte(`{"access_token":"test-token","expires_in":3600,"token_type":"Bearer"}`))
+		case "carol@example.com":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized_client","error_description":"Client is unauthorized to retrieve access tokens using this method."}`))
+		}
+	}))
+	defer tokens.Close()
+
+	// Addresses outside the domain use the users' own tokens
+	var fallbackCalls []string
+	fallback := gmail.NewMockGmailClient()
+	fallback.ModifyFunc = func(ctx context.Context, userEmail, messageID string, add, remove []string) error {
+		fallbackCalls = append(fallbackCalls, userEmail)
+		return nil
+	}
+
+	client, err := gmail.NewDelegatedClientWithEndpoint(serviceAccountKey(t, tokens.URL), "example.com", 10, fake.URL+"/", fallback, logger.New())
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890", client.ClientID())
+
+	// The service account's token for the user is fetched once and reused
+	assert.NoError(t, client.Modify(ctx, "ada@example.com", "msg_1", []string{model.LabelStarred}, nil))
+	assert.NoError(t, client.Modify(ctx, "Ada@Example.com", "msg_1", nil, []string{model.LabelStarred}))
+	assert.Len(t, fake.Requests(), 2)
+
+	// A refused delegation isn't fixed by the user signing in again
+	err = client.Modify(ctx, "bob@example.com", "msg_2", nil, []string{model.LabelUnread})
+	assert.ErrorIs(t, err, service.ErrGmailUnauthorized)
+	assert.NotErrorIs(t, err, service.ErrGmailReauthRequired)
+	assert.Contains(t, err.Error(), "unauthorized_client")
+
+	_, err = client.List(ctx, "carol@example.com", 0)
+	assert.ErrorIs(t, err, service.ErrGmailUnreachable)
+
+	assert.NoError(t, client.Modify(ctx, "dave@elsewhere.example", "msg_3", []string{model.LabelStarred}, nil))
+	assert.Equal(t, []string{"dave@elsewhere.example"}, fallbackCalls)
+
+	mutex.Lock()
+	assert.Equal(t, []string{"ada@example.com", "bob@example.com", "carol@example.com"}, subjects)
+	mutex.Unlock()
+}
+
+func TestWorkspaceMailboxes(t *testing.T) {
+	ctx := context.Background()
+	// The domain's admin delegated access to every mailbox but Bob's
+	var mutex sync.Mutex
+	synced := map[string]int{}
+	gmailClient := gmail.NewMockGmailClient()
+	gmailClient.MessagesFunc = func(ctx context.Context, userEmail string, maxResults int64) ([]*model.Email, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if userEmail == "bob@example.com" {
+			return nil, service.ErrGmailUnauthorized
+		}
+		synced[userEmail]++
+		return nil, nil
+	}
+	container, operator, _ := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AdminEmails = []string{"ops@example.com"}
+		cfg.GmailAuth = config.GmailAuthDelegation
+		cfg.WorkspaceDomain = "example.com"
+	}), withAppOptions(app.WithGmailClient(gmailClient)), withUser(func(user *model.User) {
+		user.GoogleID = "google_1"
+		user.Email = "ops@example.com"
+		user.Name = "Ops"
+	}))
+	member := model.NewUser("google_2", "eve@example.com", "Eve", "access_token", "refresh_token", time.Time{})
+	assert.NoError(t, container.UserRepo.Create(ctx, member))
+
+	call := func(user *model.User, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(sessionCookie(t, user.ID, time.Now().Add(time.Hour)))
+		return serve(container, req)
+	}
+	errorCode := func(rec *httptest.ResponseRecorder) string {
+		var errorResponse handler.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+		return errorResponse.Code
+	}
+
+	// Only operators manage the domain's mailboxes
+	assert.Equal(t, http.StatusForbidden, call(member, http.MethodGet, "/admin/workspace", "").Code)
+	assert.Equal(t, http.StatusForbidden, call(member, http.MethodPost, "/admin/workspace/mailboxes", `{"email":"ada@example.com"}`).Code)
+
+	// Mailboxes are designated once the service account read them
+	rec := call(operator, http.MethodPost, "/admin/workspace/mailboxes", `{"email":"Ada@Example.com","name":"Ada"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var ada model.WorkspaceMailbox
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ada))
+	assert.Equal(t, "ada@example.com", ada.Email)
+	assert.False(t, ada.SignedIn)
+
+	rec = call(operator, http.MethodPost, "/admin/workspace/mailboxes", `{"email":"bob@example.com"}`)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, apierror.CodeUpstream, errorCode(rec))
+	rec = call(operator, http.MethodPost, "/admin/workspace/mailboxes", `{"email":"carol@elsewhere.example"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, apierror.CodeValidation, errorCode(rec))
+
+	rec = call(operator, http.MethodGet, "/admin/workspace", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var connection model.WorkspaceConnection
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &connection))
+	assert.Equal(t, "example.com", connection.Domain)
+	assert.Equal(t, model.WorkspaceScopes, connection.Scopes)
+	if assert.Len(t, connection.Mailboxes, 1) {
+		assert.Equal(t, ada.UserID, connection.Mailboxes[0].UserID)
+	}
+
+	// The sync job syncs designated mailboxes though nobody watches them
+	container.EmailSyncJob.RunSync()
+	mutex.Lock()
+	assert.Equal(t, map[string]int{"ada@example.com": 2}, synced) // once more than the check
+	mutex.Unlock()
+
+	// Ada's first sign-in takes her mailbox over, keeping the access the domain delegates
+	user, err := container.AuthService.GetOrCreateUser(ctx, "google_3", "ada@example.com", "Ada Lovelace", "access_token", "refresh_token", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ada.UserID, user.ID)
+	user, err = container.AuthService.SetGrantedScopes(ctx, user.ID, []string{model.ScopeGmailReadonly})
+	assert.NoError(t, err)
+	assert.True(t, user.HasScope(model.ScopeGmailModify))
+	assert.Equal(t, model.OnboardingCategories, user.Onboarding())
+
+	rec = call(user, http.MethodGet, "/me", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var me handler.MeResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &me))
+	assert.True(t, me.User.CanModify)
+
+	// Removing the mailbox stops syncing it once nobody watches it
+	assert.Equal(t, http.StatusNoContent, call(operator, http.MethodDelete, "/admin/workspace/mailboxes/ada@example.com", "").Code)
+	assert.Equal(t, http.StatusNotFound, call(operator, http.MethodDelete, "/admin/workspace/mailboxes/ada@example.com", "").Code)
+	container.EmailSyncJob.RunSync()
+	mutex.Lock()
+	assert.Equal(t, 2, synced["ada@example.com"])
+	mutex.Unlock()
+}
+
+func TestWorkspaceMailboxesNeedDelegation(t *testing.T) {
+	container, _, request := newTestApp(t, withConfig(func(cfg *config.Config) {
+		cfg.AdminEmails = []string{"ops@example.com"}
+	}), withUser(func(user *model.User) {
+		user.Email = "ops@example.com"
+	}))
+
+	rec := serve(container, request(http.MethodGet, "/api/v1/admin/workspace", ""))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}